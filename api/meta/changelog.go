@@ -0,0 +1,75 @@
+package meta
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ChangeType 变更类型
+type ChangeType int
+
+const (
+	ChangeCreate   ChangeType = iota + 1 // 创建文件或目录
+	ChangeModify                         // 修改元数据或内容
+	ChangeDelete                         // 删除
+	ChangeRename                         // 重命名，OldPath 为原路径
+	ChangeAttr                           // 只修改权限、所有者或时间等属性，内容不变
+	ChangeExchange                       // 交换 Path 和 OldPath 上的条目
+)
+
+// String 返回变更类型名称
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeCreate:
+		return "create"
+	case ChangeModify:
+		return "modify"
+	case ChangeDelete:
+		return "delete"
+	case ChangeRename:
+		return "rename"
+	case ChangeAttr:
+		return "attr"
+	case ChangeExchange:
+		return "exchange"
+	default:
+		return fmt.Sprintf("ChangeType(%d)", int(t))
+	}
+}
+
+// ChangeEvent 变更日志中的一条事件
+type ChangeEvent struct {
+	Seq     uint64     `json:"seq"`
+	Type    ChangeType `json:"type"`
+	Path    string     `json:"path"`
+	OldPath string     `json:"old_path,omitempty"`
+	Inode   uint64     `json:"inode"`
+	IsDir   bool       `json:"is_dir"`
+	User    string     `json:"user,omitempty"` // 发起变更的主体，未认证的内部操作为空
+	Time    time.Time  `json:"time"`
+	// 启用混合逻辑时钟时事件的 HLC 时间戳，跨节点合并日志时按其排序
+	HLC *HLCTimestamp `json:"hlc,omitempty"`
+}
+
+// InSubtree 判断事件是否发生在目录 dir 下，recursive 为 false 时只匹配直接子项
+func (e *ChangeEvent) InSubtree(dir string, recursive bool) bool {
+	dir = CleanPath(dir)
+	match := func(p string) bool {
+		if p == "" || p == dir {
+			return false
+		}
+		if !recursive {
+			return path.Dir(p) == dir
+		}
+		return dir == "/" || strings.HasPrefix(p, dir+"/")
+	}
+	return match(e.Path) || match(e.OldPath)
+}
+
+// HasPrefix 判断事件的路径或原路径是否为 prefix 本身或位于其下，按路径分量匹配
+func (e *ChangeEvent) HasPrefix(prefix string) bool {
+	prefix = CleanPath(prefix)
+	return e.Path != "" && isWithin(e.Path, prefix) || e.OldPath != "" && isWithin(e.OldPath, prefix)
+}
@@ -0,0 +1,59 @@
+package meta
+
+import (
+	"fmt"
+	"os"
+)
+
+// CompressionDefault 目录默认属性中的压缩设置
+type CompressionDefault int
+
+const (
+	CompressionUnset CompressionDefault = iota // 不改变子项的压缩设置
+	CompressionOn                              // 子项压缩保存
+	CompressionOff                             // 子项不压缩
+)
+
+// DirDefaults 目录的默认属性，创建子项时自动应用
+//
+// 语义类似 setgid 目录加默认 ACL：新建的文件和子目录在创建时取得这些属性，
+// 子目录同时继承默认属性本身，因此设置一次即可覆盖之后在整棵子树中创建的条目。
+// 修改默认属性不影响已经存在的子项。
+type DirDefaults struct {
+	// Mode 子项权限位，非 0 时替代创建请求中的权限
+	Mode os.FileMode `json:"mode,omitempty"`
+	// Owner 子项所有者，为空时不设置
+	Owner string `json:"owner,omitempty"`
+	// Group 子项所属组，为空时不设置
+	Group string `json:"group,omitempty"`
+	// SetGID 子项继承目录的组，优先于 Group
+	SetGID bool `json:"setgid,omitempty"`
+	// StorageClass 子项存储类别，为空时不设置
+	StorageClass string `json:"storage_class,omitempty"`
+	// Compression 子项压缩设置
+	Compression CompressionDefault `json:"compression,omitempty"`
+	// ProjectID 子项所属项目，非 0 时设置，用于项目配额
+	ProjectID uint32 `json:"project_id,omitempty"`
+}
+
+// Clone 返回默认属性的副本，nil 时返回 nil
+func (d *DirDefaults) Clone() *DirDefaults {
+	if d == nil {
+		return nil
+	}
+	c := *d
+	return &c
+}
+
+// Validate 校验默认属性
+func (d *DirDefaults) Validate() error {
+	if d.Mode&^os.ModePerm != 0 {
+		return fmt.Errorf("default mode must only contain permission bits: %v", d.Mode)
+	}
+	switch d.Compression {
+	case CompressionUnset, CompressionOn, CompressionOff:
+	default:
+		return fmt.Errorf("invalid default compression: %d", d.Compression)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package meta
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirDefaultsProtoRoundTrip(t *testing.T) {
+	dir := &Metadata{Name: "d", Type: TypeDirectory, Mode: 0755 | os.ModeDir, Defaults: &DirDefaults{
+		Mode: 0750, Owner: "svc", Group: "ops", SetGID: true, StorageClass: "ssd", Compression: CompressionOff,
+	}}
+	data, err := dir.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, dir.Defaults, decoded.Defaults)
+
+	// 空的默认属性与未设置可以区分
+	dir.Defaults = &DirDefaults{}
+	data, err = dir.MarshalBinary()
+	require.NoError(t, err)
+	decoded = &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.NotNil(t, decoded.Defaults)
+
+	clone := dir.Clone()
+	clone.Defaults.Owner = "changed"
+	assert.Empty(t, dir.Defaults.Owner)
+}
@@ -0,0 +1,7 @@
+// Package meta 定义 cpfs 元数据的公开类型：文件元数据、数据块与区间、条带布局、变更事件，
+// 以及与 api/proto 下 proto 定义一致的 protobuf 编解码。
+//
+// 该包属于独立发布的 cpfs/api 模块，按语义化版本打 api/vX.Y.Z 标签：同一主版本内只新增类型、字段和函数，
+// 不删除或修改已有的导出标识符，编码的字段编号不复用；不兼容的修改发布新的主版本。
+// 元数据服务的实现位于 cpfs 模块的 internal/ 下，不属于公开 API。
+package meta
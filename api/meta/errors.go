@@ -0,0 +1,35 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrExist 路径已存在，可用 errors.Is 判断
+var ErrExist = errors.New("already exists")
+
+// ErrNotEmpty 目录非空，可用 errors.Is 判断
+var ErrNotEmpty = errors.New("directory not empty")
+
+// ErrBusy 路径是绑定点，不能被删除或重命名，可用 errors.Is 判断
+var ErrBusy = errors.New("resource busy")
+
+// ErrVersionConflict 版本冲突，可用 errors.Is 判断
+var ErrVersionConflict = errors.New("version conflict")
+
+// VersionConflictError 更新时携带的版本与存储中的当前版本不一致
+type VersionConflictError struct {
+	Path     string // 文件路径
+	Expected uint64 // 调用方期望的版本
+	Actual   uint64 // 存储中的当前版本
+}
+
+// Error 实现 error
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict on %s: expected %d, actual %d", e.Path, e.Expected, e.Actual)
+}
+
+// Is 使 errors.Is(err, ErrVersionConflict) 成立
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
@@ -0,0 +1,215 @@
+package meta
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// blockIDLength 顺序分配的块ID长度（16 位十六进制序号）
+const blockIDLength = 16
+
+// Extent 连续分配的数据块区间
+//
+// 区间内的块按偏移连续排列，除最后一块外大小均为 BlockSize，块ID为从 StartID 开始的连续序号，
+// 并且存放在同一组数据服务器上。一个区间可以替代成千上万条逐块记录。
+type Extent struct {
+	Offset    int64    `json:"offset"`     // 区间在文件内的起始偏移
+	Length    int64    `json:"length"`     // 区间总长度
+	BlockSize int64    `json:"block_size"` // 区间内的块大小
+	StartID   uint64   `json:"start_id"`   // 第一个块的序号
+	Locations []string `json:"locations"`  // 数据服务器位置
+}
+
+// FormatBlockID 返回顺序分配的块序号对应的块ID
+func FormatBlockID(seq uint64) string {
+	return fmt.Sprintf("%016x", seq)
+}
+
+// ParseBlockID 解析顺序分配的块ID，非该格式的ID返回 false
+func ParseBlockID(id string) (uint64, bool) {
+	if len(id) != blockIDLength {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(id, 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
+
+// End 返回区间结束偏移
+func (e *Extent) End() int64 {
+	return e.Offset + e.Length
+}
+
+// BlockCount 返回区间包含的块数量
+func (e *Extent) BlockCount() int {
+	return int((e.Length + e.BlockSize - 1) / e.BlockSize)
+}
+
+// full 判断区间的最后一块是否为完整块，只有完整时才能继续追加
+func (e *Extent) full() bool {
+	return e.Length%e.BlockSize == 0
+}
+
+// Block 返回区间内第 i 个块
+func (e *Extent) Block(i int) Block {
+	offset := e.Offset + int64(i)*e.BlockSize
+	return Block{
+		ID:        FormatBlockID(e.StartID + uint64(i)),
+		Size:      min(e.BlockSize, e.End()-offset),
+		Offset:    offset,
+		Locations: append([]string(nil), e.Locations...),
+	}
+}
+
+// Blocks 将区间展开为逐块记录
+func (e *Extent) Blocks() []Block {
+	blocks := make([]Block, e.BlockCount())
+	for i := range blocks {
+		blocks[i] = e.Block(i)
+	}
+	return blocks
+}
+
+// canAppend 判断数据块能否追加到区间末尾
+func (e *Extent) canAppend(b *Block, seq uint64) bool {
+	return e.full() &&
+		b.Offset == e.End() &&
+		b.Size > 0 && b.Size <= e.BlockSize &&
+		seq == e.StartID+uint64(e.BlockCount()) &&
+		slices.Equal(b.Locations, e.Locations)
+}
+
+// canMerge 判断两个相邻区间能否合并
+func (e *Extent) canMerge(next *Extent) bool {
+	return e.full() &&
+		next.Offset == e.End() &&
+		next.BlockSize == e.BlockSize &&
+		next.StartID == e.StartID+uint64(e.BlockCount()) &&
+		slices.Equal(next.Locations, e.Locations)
+}
+
+// BuildExtents 将逐块记录中连续分配的部分合并为区间
+//
+// 只有ID为顺序序号、偏移连续、大小一致、位置相同且没有校验和的块会被合并，
+// 其余块原样返回，结果按偏移排序。单个块不单独形成区间。
+func BuildExtents(blocks []Block) ([]Extent, []Block) {
+	sorted := append([]Block(nil), blocks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	var extents []Extent
+	var rest, run []Block
+	var cur *Extent
+	flush := func() {
+		if cur != nil && cur.BlockCount() >= 2 {
+			extents = append(extents, *cur)
+		} else {
+			rest = append(rest, run...)
+		}
+		cur = nil
+		run = run[:0]
+	}
+
+	for i := range sorted {
+		b := &sorted[i]
+		seq, ok := ParseBlockID(b.ID)
+		if !ok || b.Checksum != "" || b.Size <= 0 {
+			flush()
+			rest = append(rest, *b)
+			continue
+		}
+		if cur != nil && cur.canAppend(b, seq) {
+			cur.Length += b.Size
+			run = append(run, *b)
+			continue
+		}
+		flush()
+		cur = &Extent{
+			Offset:    b.Offset,
+			Length:    b.Size,
+			BlockSize: b.Size,
+			StartID:   seq,
+			Locations: b.Locations,
+		}
+		run = append(run, *b)
+	}
+	flush()
+
+	return extents, rest
+}
+
+// mergeExtents 排序并合并相邻的区间
+func mergeExtents(extents []Extent) []Extent {
+	if len(extents) < 2 {
+		return extents
+	}
+	sort.SliceStable(extents, func(i, j int) bool {
+		return extents[i].Offset < extents[j].Offset
+	})
+
+	merged := extents[:1]
+	for _, e := range extents[1:] {
+		last := &merged[len(merged)-1]
+		if last.canMerge(&e) {
+			last.Length += e.Length
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// NormalizeBlocks 将逐块记录中连续分配的部分并入区间，剩余块按偏移排序
+func (m *Metadata) NormalizeBlocks() {
+	if len(m.Blocks) == 0 {
+		return
+	}
+	extents, rest := BuildExtents(m.Blocks)
+	m.Extents = mergeExtents(append(m.Extents, extents...))
+	m.Blocks = rest
+}
+
+// ExtentBlocks 返回按偏移排序的区间列表中与 [offset, end) 相交的块，end 小于 0 表示直到文件末尾
+func ExtentBlocks(extents []Extent, offset, end int64) []Block {
+	first := sort.Search(len(extents), func(i int) bool {
+		return extents[i].End() > offset
+	})
+
+	var result []Block
+	for i := first; i < len(extents); i++ {
+		e := &extents[i]
+		if end >= 0 && e.Offset >= end {
+			break
+		}
+		start := 0
+		if offset > e.Offset {
+			start = int((offset - e.Offset) / e.BlockSize)
+		}
+		for j := start; j < e.BlockCount(); j++ {
+			b := e.Block(j)
+			if end >= 0 && b.Offset >= end {
+				break
+			}
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// LocateBlock 返回包含指定偏移的数据块，区间部分使用二分查找
+func (m *Metadata) LocateBlock(offset int64) (Block, bool) {
+	if blocks := ExtentBlocks(m.Extents, offset, offset+1); len(blocks) > 0 {
+		return blocks[0], true
+	}
+	for i := range m.Blocks {
+		if m.Blocks[i].Overlaps(offset, offset+1) {
+			return m.Blocks[i], true
+		}
+	}
+	return Block{}, false
+}
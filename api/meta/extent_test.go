@@ -0,0 +1,114 @@
+package meta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequentialBlocks 生成从 seq 开始、大小为 size 的 n 个连续块
+func sequentialBlocks(seq uint64, offset, size int64, n int, locations ...string) []Block {
+	blocks := make([]Block, n)
+	for i := range blocks {
+		blocks[i] = Block{
+			ID:        FormatBlockID(seq + uint64(i)),
+			Size:      size,
+			Offset:    offset + int64(i)*size,
+			Locations: locations,
+		}
+	}
+	return blocks
+}
+
+func TestBlockID(t *testing.T) {
+	id := FormatBlockID(255)
+	assert.Equal(t, "00000000000000ff", id)
+	seq, ok := ParseBlockID(id)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(255), seq)
+
+	_, ok = ParseBlockID("blk-1")
+	assert.False(t, ok)
+	_, ok = ParseBlockID("zzzzzzzzzzzzzzzz")
+	assert.False(t, ok)
+}
+
+func TestBuildExtents(t *testing.T) {
+	var blocks []Block
+	blocks = append(blocks, sequentialBlocks(10, 0, 100, 4, "ds1")...)
+	// 最后一块不完整
+	blocks = append(blocks, Block{ID: FormatBlockID(14), Size: 50, Offset: 400, Locations: []string{"ds1"}})
+	// 位置不同，开始新的区间
+	blocks = append(blocks, sequentialBlocks(15, 450, 100, 3, "ds2")...)
+	// 带校验和或非顺序ID的块保持逐块记录
+	blocks = append(blocks,
+		Block{ID: FormatBlockID(18), Size: 100, Offset: 750, Checksum: "abc", Locations: []string{"ds2"}},
+		Block{ID: "custom", Size: 100, Offset: 850},
+	)
+	// 乱序输入
+	blocks[0], blocks[6] = blocks[6], blocks[0]
+
+	extents, rest := BuildExtents(blocks)
+	require.Len(t, extents, 2)
+	assert.Equal(t, Extent{Offset: 0, Length: 450, BlockSize: 100, StartID: 10, Locations: []string{"ds1"}}, extents[0])
+	assert.Equal(t, Extent{Offset: 450, Length: 300, BlockSize: 100, StartID: 15, Locations: []string{"ds2"}}, extents[1])
+	require.Len(t, rest, 2)
+	assert.Equal(t, "abc", rest[0].Checksum)
+	assert.Equal(t, "custom", rest[1].ID)
+
+	assert.Equal(t, 5, extents[0].BlockCount())
+	expanded := extents[0].Blocks()
+	assert.Equal(t, blocks[6].ID, expanded[0].ID)
+	assert.Equal(t, int64(50), expanded[4].Size)
+}
+
+func TestMetadataNormalizeAndLocate(t *testing.T) {
+	meta := &Metadata{Blocks: sequentialBlocks(0, 0, 100, 10, "ds1")}
+	meta.NormalizeBlocks()
+	require.Len(t, meta.Extents, 1)
+	assert.Empty(t, meta.Blocks)
+
+	// 追加的连续块合并到已有区间
+	meta.Blocks = sequentialBlocks(10, 1000, 100, 5, "ds1")
+	meta.Blocks = append(meta.Blocks, Block{ID: "tail", Size: 10, Offset: 1500})
+	meta.NormalizeBlocks()
+	require.Len(t, meta.Extents, 1)
+	assert.Equal(t, int64(1500), meta.Extents[0].Length)
+	require.Len(t, meta.Blocks, 1)
+
+	b, ok := meta.LocateBlock(1234)
+	require.True(t, ok)
+	assert.Equal(t, FormatBlockID(12), b.ID)
+	assert.Equal(t, int64(1200), b.Offset)
+
+	b, ok = meta.LocateBlock(1505)
+	require.True(t, ok)
+	assert.Equal(t, "tail", b.ID)
+
+	_, ok = meta.LocateBlock(1510)
+	assert.False(t, ok)
+
+	data, err := meta.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, meta.Extents, decoded.Extents)
+}
+
+func BenchmarkLocateBlockExtents(b *testing.B) {
+	meta := &Metadata{Blocks: sequentialBlocks(0, 0, 1<<20, 100000, "ds1")}
+	meta.NormalizeBlocks()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		meta.LocateBlock(int64(i%100000) << 20)
+	}
+}
+
+func BenchmarkLocateBlockLinear(b *testing.B) {
+	meta := &Metadata{Blocks: sequentialBlocks(0, 0, 1<<20, 100000, "ds1")}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		meta.LocateBlock(int64(i%100000) << 20)
+	}
+}
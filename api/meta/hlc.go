@@ -0,0 +1,51 @@
+package meta
+
+import (
+	"fmt"
+	"time"
+)
+
+// HLCTimestamp 混合逻辑时钟时间戳
+//
+// Wall 为物理时间部分（Unix 纳秒），Logical 在物理时间相同或回拨时区分先后。
+// 时间戳先按 Wall 再按 Logical 全序比较，并保持因果顺序：节点收到带时间戳的消息后生成的时间戳总是更大。
+type HLCTimestamp struct {
+	Wall    int64  `json:"wall"`
+	Logical uint32 `json:"logical"`
+}
+
+// Compare 返回 -1、0 或 1，表示 t 早于、等于或晚于 other
+func (t HLCTimestamp) Compare(other HLCTimestamp) int {
+	switch {
+	case t.Wall < other.Wall:
+		return -1
+	case t.Wall > other.Wall:
+		return 1
+	case t.Logical < other.Logical:
+		return -1
+	case t.Logical > other.Logical:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Before 判断 t 是否早于 other
+func (t HLCTimestamp) Before(other HLCTimestamp) bool {
+	return t.Compare(other) < 0
+}
+
+// IsZero 判断时间戳是否未设置
+func (t HLCTimestamp) IsZero() bool {
+	return t == HLCTimestamp{}
+}
+
+// Time 返回时间戳的物理时间部分
+func (t HLCTimestamp) Time() time.Time {
+	return time.Unix(0, t.Wall)
+}
+
+// String 返回 wall.logical 格式
+func (t HLCTimestamp) String() string {
+	return fmt.Sprintf("%d.%d", t.Wall, t.Logical)
+}
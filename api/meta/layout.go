@@ -0,0 +1,59 @@
+package meta
+
+import "fmt"
+
+// StripeLayout 文件的条带布局，语义与 Lustre 的 stripe_count、stripe_size、stripe_offset 相同
+//
+// 文件按 StripeSize 切分为条带单元，第 i 个单元写入第 (StartIndex+i) % StripeCount 个条带目标，
+// 客户端据此将大文件的写入并行分散到多个数据服务器。
+type StripeLayout struct {
+	StripeCount int   `json:"stripe_count"` // 条带宽度，即文件数据分布的目标数
+	StripeSize  int64 `json:"stripe_size"`  // 每个条带单元的字节数
+	StartIndex  int   `json:"start_index"`  // 第一个条带单元所在的目标序号
+}
+
+// StripeChunk 一次读写中落在单个条带单元内的部分
+type StripeChunk struct {
+	Target int   // 条带目标序号，范围 [0, StripeCount)
+	Offset int64 // 文件内偏移
+	Length int64
+}
+
+// Validate 校验布局
+func (l *StripeLayout) Validate() error {
+	if l.StripeCount < 1 {
+		return fmt.Errorf("stripe count must be at least 1: %d", l.StripeCount)
+	}
+	if l.StripeSize <= 0 {
+		return fmt.Errorf("stripe size must be positive: %d", l.StripeSize)
+	}
+	if l.StartIndex < 0 || l.StartIndex >= l.StripeCount {
+		return fmt.Errorf("stripe start index %d out of range [0, %d)", l.StartIndex, l.StripeCount)
+	}
+	return nil
+}
+
+// Clone 返回布局的拷贝，nil 返回 nil
+func (l *StripeLayout) Clone() *StripeLayout {
+	if l == nil {
+		return nil
+	}
+	clone := *l
+	return &clone
+}
+
+// Target 返回偏移所在条带单元的目标序号
+func (l *StripeLayout) Target(offset int64) int {
+	return int((int64(l.StartIndex) + offset/l.StripeSize) % int64(l.StripeCount))
+}
+
+// Chunks 将 [offset, offset+length) 按条带单元拆分，客户端写入时每一段发往对应的目标
+func (l *StripeLayout) Chunks(offset, length int64) []StripeChunk {
+	var chunks []StripeChunk
+	for end := offset + length; offset < end; {
+		n := min(end, (offset/l.StripeSize+1)*l.StripeSize) - offset
+		chunks = append(chunks, StripeChunk{Target: l.Target(offset), Offset: offset, Length: n})
+		offset += n
+	}
+	return chunks
+}
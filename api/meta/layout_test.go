@@ -0,0 +1,36 @@
+package meta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripeLayoutChunks(t *testing.T) {
+	l := &StripeLayout{StripeCount: 3, StripeSize: 4, StartIndex: 2}
+	assert.Equal(t, []StripeChunk{
+		{Target: 2, Offset: 2, Length: 2},
+		{Target: 0, Offset: 4, Length: 4},
+		{Target: 1, Offset: 8, Length: 4},
+		{Target: 2, Offset: 12, Length: 1},
+	}, l.Chunks(2, 11))
+	assert.Empty(t, l.Chunks(5, 0))
+
+	for _, invalid := range []StripeLayout{
+		{StripeCount: 0, StripeSize: 4},
+		{StripeCount: 2, StripeSize: 0},
+		{StripeCount: 2, StripeSize: 4, StartIndex: 2},
+	} {
+		assert.Error(t, invalid.Validate(), "%+v", invalid)
+	}
+}
+
+func TestStripeLayoutProtoRoundTrip(t *testing.T) {
+	f := &Metadata{Name: "f", Layout: &StripeLayout{StripeCount: 8, StripeSize: 4 << 20, StartIndex: 3}}
+	data, err := f.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, f.Layout, decoded.Layout)
+}
@@ -0,0 +1,23 @@
+package meta
+
+import "time"
+
+// PageOptions 分页查询选项
+type PageOptions struct {
+	// Token 上一页返回的续传令牌，为空时从头开始
+	Token string
+	// Limit 本页最多返回的条目数，为 0 时不限制
+	Limit int
+	// Budget 本次调用的时间预算，为 0 时使用服务端的默认预算；ctx 设置了截止时间时不超过剩余时间的一半
+	Budget time.Duration
+}
+
+// Page 分页查询结果
+type Page struct {
+	Entries []*Metadata `json:"entries"`
+	Paths   []string    `json:"paths"` // 与 Entries 一一对应的完整路径
+	// NextToken 非空时还有未处理的条目，传回 PageOptions.Token 继续
+	NextToken string `json:"next_token,omitempty"`
+	// Truncated 本页因时间预算耗尽提前返回，条目数可能少于 Limit
+	Truncated bool `json:"truncated,omitempty"`
+}
@@ -0,0 +1,444 @@
+package meta
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// api/proto/cpfs/meta/v1/metadata.proto 中的字段编号
+const (
+	fieldMetaInode        protowire.Number = 1
+	fieldMetaName         protowire.Number = 2
+	fieldMetaType         protowire.Number = 3
+	fieldMetaSize         protowire.Number = 4
+	fieldMetaMode         protowire.Number = 5
+	fieldMetaBlocks       protowire.Number = 6
+	fieldMetaLinks        protowire.Number = 7
+	fieldMetaOwner        protowire.Number = 8
+	fieldMetaGroup        protowire.Number = 9
+	fieldMetaCreateTime   protowire.Number = 10
+	fieldMetaModifyTime   protowire.Number = 11
+	fieldMetaAccessTime   protowire.Number = 12
+	fieldMetaVersion      protowire.Number = 13
+	fieldMetaPlacement    protowire.Number = 14
+	fieldMetaBlockCount   protowire.Number = 15
+	fieldMetaExtents      protowire.Number = 16
+	fieldMetaInlineData   protowire.Number = 17
+	fieldMetaStorageClass protowire.Number = 18
+	fieldMetaCompression  protowire.Number = 19
+	fieldMetaDefaults     protowire.Number = 20
+	fieldMetaProjectID    protowire.Number = 21
+	fieldMetaPin          protowire.Number = 22
+	fieldMetaWriter       protowire.Number = 23
+	fieldMetaLayout       protowire.Number = 24
+	fieldMetaTarget       protowire.Number = 25
+	fieldMetaQuotaBytes   protowire.Number = 26
+	fieldMetaQuotaFiles   protowire.Number = 27
+	fieldMetaHLCWall      protowire.Number = 28
+	fieldMetaHLCLogical   protowire.Number = 29
+
+	fieldDefaultsMode         protowire.Number = 1
+	fieldDefaultsOwner        protowire.Number = 2
+	fieldDefaultsGroup        protowire.Number = 3
+	fieldDefaultsSetGID       protowire.Number = 4
+	fieldDefaultsStorageClass protowire.Number = 5
+	fieldDefaultsCompression  protowire.Number = 6
+	fieldDefaultsProjectID    protowire.Number = 7
+
+	fieldPinNodes protowire.Number = 1
+	fieldPinMedia protowire.Number = 2
+
+	fieldLayoutStripeCount protowire.Number = 1
+	fieldLayoutStripeSize  protowire.Number = 2
+	fieldLayoutStartIndex  protowire.Number = 3
+
+	fieldBlockID        protowire.Number = 1
+	fieldBlockSize      protowire.Number = 2
+	fieldBlockOffset    protowire.Number = 3
+	fieldBlockChecksum  protowire.Number = 4
+	fieldBlockLocations protowire.Number = 5
+
+	fieldExtentOffset    protowire.Number = 1
+	fieldExtentLength    protowire.Number = 2
+	fieldExtentBlockSize protowire.Number = 3
+	fieldExtentStartID   protowire.Number = 4
+	fieldExtentLocations protowire.Number = 5
+)
+
+// appendVarint 追加非零的 varint 字段，零值按 proto3 规则省略
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendString 追加非空的字符串字段
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// appendBool 追加为 true 的布尔字段
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarint(b, num, 1)
+}
+
+// timeToProto 将时间转换为 Unix 纳秒，零值时间编码为 0
+func timeToProto(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// timeFromProto 将 Unix 纳秒还原为时间
+func timeFromProto(v uint64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(v))
+}
+
+// AppendProto 按 metadata.proto 编码数据块并追加到 buf
+func (b *Block) AppendProto(buf []byte) []byte {
+	buf = appendString(buf, fieldBlockID, b.ID)
+	buf = appendVarint(buf, fieldBlockSize, uint64(b.Size))
+	buf = appendVarint(buf, fieldBlockOffset, uint64(b.Offset))
+	buf = appendString(buf, fieldBlockChecksum, b.Checksum)
+	for _, location := range b.Locations {
+		buf = protowire.AppendTag(buf, fieldBlockLocations, protowire.BytesType)
+		buf = protowire.AppendString(buf, location)
+	}
+	return buf
+}
+
+// AppendProto 按 metadata.proto 编码数据块区间并追加到 b
+func (e *Extent) AppendProto(b []byte) []byte {
+	b = appendVarint(b, fieldExtentOffset, uint64(e.Offset))
+	b = appendVarint(b, fieldExtentLength, uint64(e.Length))
+	b = appendVarint(b, fieldExtentBlockSize, uint64(e.BlockSize))
+	b = appendVarint(b, fieldExtentStartID, e.StartID)
+	for _, location := range e.Locations {
+		b = protowire.AppendTag(b, fieldExtentLocations, protowire.BytesType)
+		b = protowire.AppendString(b, location)
+	}
+	return b
+}
+
+// AppendProto 按 metadata.proto 编码元数据并追加到 b
+func (m *Metadata) AppendProto(b []byte) []byte {
+	b = appendVarint(b, fieldMetaInode, m.Inode)
+	b = appendString(b, fieldMetaName, m.Name)
+	b = appendVarint(b, fieldMetaType, uint64(m.Type))
+	b = appendVarint(b, fieldMetaSize, uint64(m.Size))
+	b = appendVarint(b, fieldMetaMode, uint64(uint32(m.Mode)))
+	for i := range m.Blocks {
+		b = protowire.AppendTag(b, fieldMetaBlocks, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Blocks[i].AppendProto(nil))
+	}
+	b = appendVarint(b, fieldMetaLinks, uint64(m.Links))
+	b = appendString(b, fieldMetaOwner, m.Owner)
+	b = appendString(b, fieldMetaGroup, m.Group)
+	b = appendVarint(b, fieldMetaCreateTime, timeToProto(m.CreateTime))
+	b = appendVarint(b, fieldMetaModifyTime, timeToProto(m.ModifyTime))
+	b = appendVarint(b, fieldMetaAccessTime, timeToProto(m.AccessTime))
+	b = appendVarint(b, fieldMetaVersion, m.Version)
+	b = appendString(b, fieldMetaPlacement, m.Placement)
+	b = appendVarint(b, fieldMetaBlockCount, uint64(m.BlockCount))
+	for i := range m.Extents {
+		b = protowire.AppendTag(b, fieldMetaExtents, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Extents[i].AppendProto(nil))
+	}
+	if len(m.InlineData) > 0 {
+		b = protowire.AppendTag(b, fieldMetaInlineData, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.InlineData)
+	}
+	b = appendString(b, fieldMetaStorageClass, m.StorageClass)
+	b = appendBool(b, fieldMetaCompression, m.Compression)
+	if m.Defaults != nil {
+		b = protowire.AppendTag(b, fieldMetaDefaults, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Defaults.AppendProto(nil))
+	}
+	b = appendVarint(b, fieldMetaProjectID, uint64(m.ProjectID))
+	if m.Pin != nil {
+		b = protowire.AppendTag(b, fieldMetaPin, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Pin.AppendProto(nil))
+	}
+	b = appendString(b, fieldMetaWriter, m.Writer)
+	if m.Layout != nil {
+		b = protowire.AppendTag(b, fieldMetaLayout, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Layout.AppendProto(nil))
+	}
+	b = appendString(b, fieldMetaTarget, m.Target)
+	b = appendVarint(b, fieldMetaQuotaBytes, uint64(m.QuotaBytes))
+	b = appendVarint(b, fieldMetaQuotaFiles, uint64(m.QuotaFiles))
+	if m.HLC != nil {
+		b = appendVarint(b, fieldMetaHLCWall, uint64(m.HLC.Wall))
+		b = appendVarint(b, fieldMetaHLCLogical, uint64(m.HLC.Logical))
+	}
+	return b
+}
+
+// MarshalBinary 实现 encoding.BinaryMarshaler，输出 protobuf 编码
+func (m *Metadata) MarshalBinary() ([]byte, error) {
+	return m.AppendProto(nil), nil
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，解析 protobuf 编码，忽略未知字段
+func (m *Metadata) UnmarshalBinary(data []byte) error {
+	*m = Metadata{}
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+		switch {
+		case typ == protowire.VarintType && num == fieldMetaInode:
+			m.Inode = v
+		case typ == protowire.VarintType && num == fieldMetaType:
+			m.Type = FileType(v)
+		case typ == protowire.VarintType && num == fieldMetaSize:
+			m.Size = int64(v)
+		case typ == protowire.VarintType && num == fieldMetaMode:
+			m.Mode = os.FileMode(uint32(v))
+		case typ == protowire.VarintType && num == fieldMetaLinks:
+			m.Links = int(int64(v))
+		case typ == protowire.VarintType && num == fieldMetaCreateTime:
+			m.CreateTime = timeFromProto(v)
+		case typ == protowire.VarintType && num == fieldMetaModifyTime:
+			m.ModifyTime = timeFromProto(v)
+		case typ == protowire.VarintType && num == fieldMetaAccessTime:
+			m.AccessTime = timeFromProto(v)
+		case typ == protowire.VarintType && num == fieldMetaVersion:
+			m.Version = v
+		case typ == protowire.VarintType && num == fieldMetaBlockCount:
+			m.BlockCount = int(v)
+		case typ == protowire.BytesType && num == fieldMetaName:
+			m.Name = string(raw)
+		case typ == protowire.BytesType && num == fieldMetaOwner:
+			m.Owner = string(raw)
+		case typ == protowire.BytesType && num == fieldMetaGroup:
+			m.Group = string(raw)
+		case typ == protowire.BytesType && num == fieldMetaPlacement:
+			m.Placement = string(raw)
+		case typ == protowire.BytesType && num == fieldMetaInlineData:
+			m.InlineData = append([]byte(nil), raw...)
+		case typ == protowire.BytesType && num == fieldMetaStorageClass:
+			m.StorageClass = string(raw)
+		case typ == protowire.VarintType && num == fieldMetaCompression:
+			m.Compression = v != 0
+		case typ == protowire.BytesType && num == fieldMetaDefaults:
+			m.Defaults = &DirDefaults{}
+			if err := m.Defaults.UnmarshalBinary(raw); err != nil {
+				return err
+			}
+		case typ == protowire.VarintType && num == fieldMetaProjectID:
+			m.ProjectID = uint32(v)
+		case typ == protowire.BytesType && num == fieldMetaLayout:
+			m.Layout = &StripeLayout{}
+			if err := m.Layout.UnmarshalBinary(raw); err != nil {
+				return err
+			}
+		case typ == protowire.BytesType && num == fieldMetaWriter:
+			m.Writer = string(raw)
+		case typ == protowire.BytesType && num == fieldMetaTarget:
+			m.Target = string(raw)
+		case typ == protowire.VarintType && num == fieldMetaQuotaBytes:
+			m.QuotaBytes = int64(v)
+		case typ == protowire.VarintType && num == fieldMetaQuotaFiles:
+			m.QuotaFiles = int64(v)
+		case typ == protowire.VarintType && num == fieldMetaHLCWall:
+			if m.HLC == nil {
+				m.HLC = &HLCTimestamp{}
+			}
+			m.HLC.Wall = int64(v)
+		case typ == protowire.VarintType && num == fieldMetaHLCLogical:
+			if m.HLC == nil {
+				m.HLC = &HLCTimestamp{}
+			}
+			m.HLC.Logical = uint32(v)
+		case typ == protowire.BytesType && num == fieldMetaPin:
+			m.Pin = &FilePin{}
+			if err := m.Pin.UnmarshalBinary(raw); err != nil {
+				return err
+			}
+		case typ == protowire.BytesType && num == fieldMetaBlocks:
+			var block Block
+			if err := block.UnmarshalBinary(raw); err != nil {
+				return err
+			}
+			m.Blocks = append(m.Blocks, block)
+		case typ == protowire.BytesType && num == fieldMetaExtents:
+			var extent Extent
+			if err := extent.UnmarshalBinary(raw); err != nil {
+				return err
+			}
+			m.Extents = append(m.Extents, extent)
+		}
+		return nil
+	})
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，解析数据块区间的 protobuf 编码，忽略未知字段
+func (e *Extent) UnmarshalBinary(data []byte) error {
+	*e = Extent{}
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+		switch {
+		case typ == protowire.VarintType && num == fieldExtentOffset:
+			e.Offset = int64(v)
+		case typ == protowire.VarintType && num == fieldExtentLength:
+			e.Length = int64(v)
+		case typ == protowire.VarintType && num == fieldExtentBlockSize:
+			e.BlockSize = int64(v)
+		case typ == protowire.VarintType && num == fieldExtentStartID:
+			e.StartID = v
+		case typ == protowire.BytesType && num == fieldExtentLocations:
+			e.Locations = append(e.Locations, string(raw))
+		}
+		return nil
+	})
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，解析数据块的 protobuf 编码，忽略未知字段
+func (b *Block) UnmarshalBinary(data []byte) error {
+	*b = Block{}
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+		switch {
+		case typ == protowire.BytesType && num == fieldBlockID:
+			b.ID = string(raw)
+		case typ == protowire.VarintType && num == fieldBlockSize:
+			b.Size = int64(v)
+		case typ == protowire.VarintType && num == fieldBlockOffset:
+			b.Offset = int64(v)
+		case typ == protowire.BytesType && num == fieldBlockChecksum:
+			b.Checksum = string(raw)
+		case typ == protowire.BytesType && num == fieldBlockLocations:
+			b.Locations = append(b.Locations, string(raw))
+		}
+		return nil
+	})
+}
+
+// AppendProto 按 metadata.proto 编码目录默认属性并追加到 b
+func (d *DirDefaults) AppendProto(b []byte) []byte {
+	b = appendVarint(b, fieldDefaultsMode, uint64(uint32(d.Mode)))
+	b = appendString(b, fieldDefaultsOwner, d.Owner)
+	b = appendString(b, fieldDefaultsGroup, d.Group)
+	b = appendBool(b, fieldDefaultsSetGID, d.SetGID)
+	b = appendString(b, fieldDefaultsStorageClass, d.StorageClass)
+	b = appendVarint(b, fieldDefaultsCompression, uint64(d.Compression))
+	b = appendVarint(b, fieldDefaultsProjectID, uint64(d.ProjectID))
+	return b
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，解析目录默认属性的 protobuf 编码，忽略未知字段
+func (d *DirDefaults) UnmarshalBinary(data []byte) error {
+	*d = DirDefaults{}
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+		switch {
+		case typ == protowire.VarintType && num == fieldDefaultsMode:
+			d.Mode = os.FileMode(uint32(v))
+		case typ == protowire.BytesType && num == fieldDefaultsOwner:
+			d.Owner = string(raw)
+		case typ == protowire.BytesType && num == fieldDefaultsGroup:
+			d.Group = string(raw)
+		case typ == protowire.VarintType && num == fieldDefaultsSetGID:
+			d.SetGID = v != 0
+		case typ == protowire.BytesType && num == fieldDefaultsStorageClass:
+			d.StorageClass = string(raw)
+		case typ == protowire.VarintType && num == fieldDefaultsCompression:
+			d.Compression = CompressionDefault(v)
+		case typ == protowire.VarintType && num == fieldDefaultsProjectID:
+			d.ProjectID = uint32(v)
+		}
+		return nil
+	})
+}
+
+// AppendProto 按 metadata.proto 编码文件固定位置并追加到 b
+func (p *FilePin) AppendProto(b []byte) []byte {
+	for _, node := range p.Nodes {
+		b = protowire.AppendTag(b, fieldPinNodes, protowire.BytesType)
+		b = protowire.AppendString(b, node)
+	}
+	b = appendString(b, fieldPinMedia, p.Media)
+	return b
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，解析文件固定位置的 protobuf 编码，忽略未知字段
+func (p *FilePin) UnmarshalBinary(data []byte) error {
+	*p = FilePin{}
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+		switch {
+		case typ == protowire.BytesType && num == fieldPinNodes:
+			p.Nodes = append(p.Nodes, string(raw))
+		case typ == protowire.BytesType && num == fieldPinMedia:
+			p.Media = string(raw)
+		}
+		return nil
+	})
+}
+
+// AppendProto 按 metadata.proto 编码条带布局并追加到 b
+func (l *StripeLayout) AppendProto(b []byte) []byte {
+	b = appendVarint(b, fieldLayoutStripeCount, uint64(l.StripeCount))
+	b = appendVarint(b, fieldLayoutStripeSize, uint64(l.StripeSize))
+	b = appendVarint(b, fieldLayoutStartIndex, uint64(l.StartIndex))
+	return b
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，解析条带布局的 protobuf 编码，忽略未知字段
+func (l *StripeLayout) UnmarshalBinary(data []byte) error {
+	*l = StripeLayout{}
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+		switch {
+		case typ == protowire.VarintType && num == fieldLayoutStripeCount:
+			l.StripeCount = int(v)
+		case typ == protowire.VarintType && num == fieldLayoutStripeSize:
+			l.StripeSize = int64(v)
+		case typ == protowire.VarintType && num == fieldLayoutStartIndex:
+			l.StartIndex = int(v)
+		}
+		return nil
+	})
+}
+
+// consumeFields 逐个解析字段，varint 字段传入 v，长度前缀字段传入 raw，其他类型跳过
+func consumeFields(data []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var v uint64
+		var raw []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			raw, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf field %d: %v", num, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if typ == protowire.VarintType || typ == protowire.BytesType {
+			if err := fn(num, typ, v, raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package meta
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func sampleMetadata() *Metadata {
+	now := time.Unix(1700000000, 123456789)
+	return &Metadata{
+		Inode:      42,
+		Name:       "file.dat",
+		Type:       TypeRegular,
+		Size:       8192,
+		Mode:       0644,
+		Links:      1,
+		Owner:      "alice",
+		Group:      "staff",
+		CreateTime: now,
+		ModifyTime: now.Add(time.Second),
+		AccessTime: now.Add(time.Minute),
+		Version:    3,
+		Placement:  "zone=a",
+
+		StorageClass: "archive",
+		Compression:  true,
+		Blocks: []Block{
+			{ID: "b1", Size: 4096, Offset: 0, Checksum: "c1", Locations: []string{"ds1", "ds2"}},
+			{ID: "b2", Size: 4096, Offset: 4096},
+		},
+	}
+}
+
+func TestMetadataProtoRoundTrip(t *testing.T) {
+	original := sampleMetadata()
+
+	data, err := original.MarshalBinary()
+	require.NoError(t, err)
+
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, original.Blocks, decoded.Blocks)
+	assert.True(t, original.CreateTime.Equal(decoded.CreateTime))
+	decoded.CreateTime, decoded.ModifyTime, decoded.AccessTime = original.CreateTime, original.ModifyTime, original.AccessTime
+	assert.Equal(t, original, decoded)
+
+	// protobuf 编码明显小于 JSON
+	jsonData, err := json.Marshal(original)
+	require.NoError(t, err)
+	assert.Less(t, len(data), len(jsonData)/2)
+
+	// 零值与目录模式
+	dir := &Metadata{Name: "d", Type: TypeDirectory, Mode: 0755 | os.ModeDir}
+	data, err = dir.MarshalBinary()
+	require.NoError(t, err)
+	decoded = &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, dir, decoded)
+}
+
+func TestMetadataProtoUnknownFields(t *testing.T) {
+	data, err := sampleMetadata().MarshalBinary()
+	require.NoError(t, err)
+
+	// 新版本增加的字段被忽略
+	data = protowire.AppendTag(data, 99, protowire.Fixed64Type)
+	data = protowire.AppendFixed64(data, 7)
+	data = protowire.AppendTag(data, 100, protowire.BytesType)
+	data = protowire.AppendString(data, "future")
+
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, "file.dat", decoded.Name)
+
+	assert.Error(t, decoded.UnmarshalBinary([]byte{0x12, 0x05, 'a'}))
+}
@@ -0,0 +1,42 @@
+package meta
+
+import "fmt"
+
+// FilePin 管理员为文件指定的固定放置位置
+//
+// 被固定文件的数据块只能放置在满足条件的数据服务器上，再平衡和分层迁移不得移动这些数据，
+// 用于对延迟敏感的数据集，例如固定到 NVMe 节点。
+type FilePin struct {
+	// Nodes 允许放置数据的节点ID，为空时不限制节点
+	Nodes []string `json:"nodes,omitempty"`
+	// Media 要求节点的 media 标签等于该值，例如 nvme，为空时不限制介质
+	Media string `json:"media,omitempty"`
+}
+
+// Clone 返回固定位置的深拷贝，nil 返回 nil
+func (p *FilePin) Clone() *FilePin {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	clone.Nodes = append([]string(nil), p.Nodes...)
+	return &clone
+}
+
+// Validate 校验固定位置
+func (p *FilePin) Validate() error {
+	if len(p.Nodes) == 0 && p.Media == "" {
+		return fmt.Errorf("pin must name nodes or a media type")
+	}
+	for _, node := range p.Nodes {
+		if node == "" {
+			return fmt.Errorf("pin node id must not be empty")
+		}
+	}
+	return nil
+}
+
+// Pinned 判断条目是否被固定，再平衡和分层迁移必须跳过被固定的文件
+func (m *Metadata) Pinned() bool {
+	return m.Pin != nil
+}
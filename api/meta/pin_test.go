@@ -0,0 +1,24 @@
+package meta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePinProtoRoundTrip(t *testing.T) {
+	f := &Metadata{Name: "f", Type: TypeRegular, Pin: &FilePin{Nodes: []string{"d1", "d3"}, Media: "nvme"}}
+	data, err := f.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, f.Pin, decoded.Pin)
+
+	f.Pin = nil
+	data, err = f.MarshalBinary()
+	require.NoError(t, err)
+	decoded = &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Nil(t, decoded.Pin)
+}
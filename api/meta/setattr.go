@@ -0,0 +1,26 @@
+package meta
+
+import (
+	"os"
+	"time"
+)
+
+// SetAttrMask SetAttr 要修改的属性，对应 FUSE setattr 的 valid 位
+type SetAttrMask uint32
+
+const (
+	SetAttrMode       SetAttrMask = 1 << iota // 权限位，chmod
+	SetAttrOwner                              // 所有者，chown
+	SetAttrGroup                              // 组，chown/chgrp
+	SetAttrAccessTime                         // 访问时间，utimes
+	SetAttrModifyTime                         // 修改时间，utimes
+)
+
+// Attrs SetAttr 的属性值，只有 mask 中指定的字段生效
+type Attrs struct {
+	Mode       os.FileMode
+	Owner      string
+	Group      string
+	AccessTime time.Time
+	ModifyTime time.Time
+}
@@ -0,0 +1,132 @@
+package meta
+
+import (
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileType 定义文件类型
+type FileType int
+
+const (
+	TypeRegular   FileType = iota // 普通文件
+	TypeDirectory                 // 目录
+	TypeSymlink                   // 符号链接
+	TypeBind                      // 绑定点，Target 为被绑定的源目录
+)
+
+// Metadata 文件元数据
+type Metadata struct {
+	Inode      uint64      `json:"inode"`       // Inode号
+	Name       string      `json:"name"`        // 文件名
+	Type       FileType    `json:"type"`        // 文件类型
+	Size       int64       `json:"size"`        // 文件大小
+	Mode       os.FileMode `json:"mode"`        // 文件权限
+	Blocks     []Block     `json:"blocks"`      // 无法合并为区间的数据块列表
+	Extents    []Extent    `json:"extents"`     // 连续分配的数据块区间
+	Links      int         `json:"links"`       // 硬链接数
+	Owner      string      `json:"owner"`       // 所有者
+	Group      string      `json:"group"`       // 组
+	CreateTime time.Time   `json:"create_time"` // 创建时间
+	ModifyTime time.Time   `json:"modify_time"` // 修改时间
+	AccessTime time.Time   `json:"access_time"` // 访问时间
+	Version    uint64      `json:"version"`     // 版本号
+	Placement  string      `json:"placement"`   // 放置约束表达式，仅对目录有效
+	BlockCount int         `json:"block_count"` // 块映射拆分保存时的数据块总数，此时 Blocks 为空
+	InlineData []byte      `json:"inline_data"` // 小文件内联保存的数据，此时没有数据块
+
+	StorageClass string        `json:"storage_class,omitempty"` // 存储类别
+	Compression  bool          `json:"compression,omitempty"`   // 数据是否压缩保存
+	Defaults     *DirDefaults  `json:"defaults,omitempty"`      // 子项继承的默认属性，仅对目录有效
+	ProjectID    uint32        `json:"project_id,omitempty"`    // 所属项目，用于项目配额，0 表示不属于任何项目
+	Pin          *FilePin      `json:"pin,omitempty"`           // 管理员指定的固定放置位置，仅对文件有效
+	Writer       string        `json:"writer,omitempty"`        // 正在写入文件的客户端，关闭或恢复后清除
+	Layout       *StripeLayout `json:"layout,omitempty"`        // 条带布局，仅对文件有效，为 nil 时由放置策略决定
+	Target       string        `json:"target,omitempty"`        // 符号链接指向的路径或绑定点的源目录
+	QuotaBytes   int64         `json:"quota_bytes,omitempty"`   // 目录配额：子树内普通文件大小之和的上限，0 表示不限制
+	QuotaFiles   int64         `json:"quota_files,omitempty"`   // 目录配额：子树内条目数量的上限，0 表示不限制
+	HLC          *HLCTimestamp `json:"hlc,omitempty"`           // 写入该版本时的混合逻辑时钟时间戳，未启用 HLC 时为 nil
+}
+
+// Block 数据块信息
+type Block struct {
+	ID        string   `json:"id"`        // 块ID
+	Size      int64    `json:"size"`      // 块大小
+	Offset    int64    `json:"offset"`    // 文件内偏移
+	Checksum  string   `json:"checksum"`  // 校验和
+	Locations []string `json:"locations"` // 数据服务器位置
+}
+
+// End 返回数据块结束偏移
+func (b *Block) End() int64 {
+	return b.Offset + b.Size
+}
+
+// Overlaps 判断数据块是否与 [offset, end) 相交，end 小于 0 表示直到文件末尾
+func (b *Block) Overlaps(offset, end int64) bool {
+	return b.End() > offset && (end < 0 || b.Offset < end)
+}
+
+// Clone 返回元数据的深拷贝
+func (m *Metadata) Clone() *Metadata {
+	clone := *m
+	if m.Blocks != nil {
+		clone.Blocks = make([]Block, len(m.Blocks))
+		for i, block := range m.Blocks {
+			clone.Blocks[i] = block
+			clone.Blocks[i].Locations = append([]string(nil), block.Locations...)
+		}
+	}
+	if m.Extents != nil {
+		clone.Extents = make([]Extent, len(m.Extents))
+		for i, extent := range m.Extents {
+			clone.Extents[i] = extent
+			clone.Extents[i].Locations = append([]string(nil), extent.Locations...)
+		}
+	}
+	if m.InlineData != nil {
+		clone.InlineData = append([]byte(nil), m.InlineData...)
+	}
+	clone.Defaults = m.Defaults.Clone()
+	clone.Pin = m.Pin.Clone()
+	clone.Layout = m.Layout.Clone()
+	if m.HLC != nil {
+		ts := *m.HLC
+		clone.HLC = &ts
+	}
+	return &clone
+}
+
+// CleanPath 将路径标准化为服务端使用的形式：以 / 开头、使用正斜杠、不含 . 和 .. 以及重复的斜杠
+func CleanPath(p string) string {
+	// 替换所有反斜杠为正斜杠
+	p = strings.ReplaceAll(p, "\\", "/")
+
+	// 删除开头的 "./"
+	p = strings.TrimPrefix(p, "./")
+
+	// 确保路径以 / 开头
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	// 使用 path.Clean 处理 . 和 .. 以及重复的斜杠
+	p = path.Clean(p)
+
+	// 确保返回至少是根目录
+	if p == "." || p == "" {
+		return "/"
+	}
+
+	return p
+}
+
+// isWithin 判断标准化路径 p 是否为 root 本身或位于其下
+func isWithin(p, root string) bool {
+	if root == "/" || p == root {
+		return true
+	}
+	return len(p) > len(root) && p[:len(root)] == root && p[len(root)] == '/'
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DeviceAuthorization 设备授权流程的授权信息
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// TokenResponse 令牌端点响应
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// oauthErrorBody 令牌端点错误响应体
+type oauthErrorBody struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// DeviceFlow 实现 OAuth2 设备授权流程（RFC 8628），供命令行工具获取令牌
+type DeviceFlow struct {
+	config   OIDCConfig
+	metadata providerMetadata
+}
+
+// NewDeviceFlow 创建设备授权流程客户端
+func NewDeviceFlow(ctx context.Context, config OIDCConfig) (*DeviceFlow, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := discoverProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("provider does not support device authorization")
+	}
+
+	return &DeviceFlow{config: config, metadata: metadata}, nil
+}
+
+// Start 发起设备授权，返回需要展示给用户的验证地址和用户码
+func (f *DeviceFlow) Start(ctx context.Context, scopes ...string) (*DeviceAuthorization, error) {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "groups"}
+	}
+
+	form := url.Values{
+		"client_id": {f.config.ClientID},
+		"scope":     {strings.Join(scopes, " ")},
+	}
+
+	var auth DeviceAuthorization
+	if err := f.post(ctx, f.metadata.DeviceAuthorizationEndpoint, form, &auth); err != nil {
+		return nil, err
+	}
+	if auth.Interval <= 0 {
+		auth.Interval = 5
+	}
+	return &auth, nil
+}
+
+// Poll 轮询令牌端点直到用户完成授权、授权过期或 ctx 被取消
+func (f *DeviceFlow) Poll(ctx context.Context, auth *DeviceAuthorization) (*TokenResponse, error) {
+	interval := time.Duration(auth.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {auth.DeviceCode},
+		"client_id":   {f.config.ClientID},
+	}
+
+	for {
+		if auth.ExpiresIn > 0 && time.Now().After(deadline) {
+			return nil, fmt.Errorf("device authorization expired")
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		var token TokenResponse
+		err := f.post(ctx, f.metadata.TokenEndpoint, form, &token)
+		if err == nil {
+			return &token, nil
+		}
+
+		tokenErr, ok := err.(*tokenErrorResponse)
+		if !ok {
+			return nil, err
+		}
+		switch tokenErr.code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += time.Second * 5
+		default:
+			return nil, err
+		}
+	}
+}
+
+// tokenErrorResponse 令牌端点返回的 OAuth2 错误
+type tokenErrorResponse struct {
+	code        string
+	description string
+}
+
+func (e *tokenErrorResponse) Error() string {
+	if e.description != "" {
+		return fmt.Sprintf("oauth2 error %s: %s", e.code, e.description)
+	}
+	return "oauth2 error " + e.code
+}
+
+// post 提交表单并解析 JSON 响应
+func (f *DeviceFlow) post(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e oauthErrorBody
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+		}
+		return &tokenErrorResponse{code: e.Error, description: e.ErrorDescription}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
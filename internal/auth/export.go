@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"cpfs/internal/config"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Export 元数据服务器对外导出的子树
+type Export struct {
+	Path     string   // 导出的路径前缀
+	Users    []string // 允许挂载的用户
+	Groups   []string // 允许挂载的组，Users 和 Groups 都为空时允许所有已认证主体
+	ReadOnly bool     // 是否只读导出
+}
+
+// Allows 判断主体是否可以挂载该导出
+func (e *Export) Allows(p *Principal) bool {
+	if len(e.Users) == 0 && len(e.Groups) == 0 {
+		return true
+	}
+	for _, u := range e.Users {
+		if u == p.User {
+			return true
+		}
+	}
+	for _, g := range e.Groups {
+		if p.InGroup(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains 判断绝对路径是否位于导出子树内
+func (e *Export) contains(p string) bool {
+	return e.Path == "/" || p == e.Path || strings.HasPrefix(p, e.Path+"/")
+}
+
+// ExportTable 导出表，按路径前缀最长匹配
+type ExportTable struct {
+	exports []Export
+	limits  SessionLimits
+}
+
+// NewExportTable 创建导出表，exports 为空时导出整个命名空间且不限制主体
+func NewExportTable(exports []Export) (*ExportTable, error) {
+	if len(exports) == 0 {
+		exports = []Export{{Path: "/"}}
+	}
+	table := &ExportTable{}
+	seen := make(map[string]bool)
+	for _, e := range exports {
+		if !strings.HasPrefix(e.Path, "/") {
+			return nil, fmt.Errorf("export path must be absolute: %q", e.Path)
+		}
+		e.Path = path.Clean(e.Path)
+		if seen[e.Path] {
+			return nil, fmt.Errorf("duplicate export: %s", e.Path)
+		}
+		seen[e.Path] = true
+		table.exports = append(table.exports, e)
+	}
+	sort.Slice(table.exports, func(i, j int) bool {
+		return len(table.exports[i].Path) > len(table.exports[j].Path)
+	})
+	return table, nil
+}
+
+// NewExportTableFromConfig 根据服务器配置创建导出表
+func NewExportTableFromConfig(configs []config.ExportConfig) (*ExportTable, error) {
+	exports := make([]Export, 0, len(configs))
+	for _, c := range configs {
+		exports = append(exports, Export{
+			Path:     c.Path,
+			Users:    c.Users,
+			Groups:   c.Groups,
+			ReadOnly: c.ReadOnly,
+		})
+	}
+	return NewExportTable(exports)
+}
+
+// Session 挂载会话，客户端路径相对于会话根目录解析
+type Session struct {
+	Principal *Principal
+	Export    *Export
+	Root      string // 挂载根目录的绝对路径，位于导出子树内
+
+	usage   sessionUsage
+	handles handleTable
+}
+
+// Setup 为主体建立挂载 mountPath 的会话
+//
+// 选择包含 mountPath 的最长导出，主体不在允许列表中时返回 PermissionDenied，
+// 没有导出包含 mountPath 时返回 NotFound，避免泄露导出外路径的存在性。
+func (t *ExportTable) Setup(p *Principal, mountPath string) (*Session, error) {
+	if p == nil {
+		return nil, status.Error(codes.Unauthenticated, "missing principal")
+	}
+	root := path.Clean("/" + mountPath)
+	for i := range t.exports {
+		e := &t.exports[i]
+		if !e.contains(root) {
+			continue
+		}
+		if !e.Allows(p) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to mount %s", p.User, root)
+		}
+		session := &Session{Principal: p, Export: e, Root: root}
+		session.usage.limits = t.limits
+		return session, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "no export for %s", root)
+}
+
+// Resolve 将客户端路径解析为绝对路径，".." 不能越过会话根目录
+func (s *Session) Resolve(p string) string {
+	return path.Join(s.Root, path.Clean("/"+p))
+}
+
+// CheckWrite 只读导出的会话返回 PermissionDenied
+func (s *Session) CheckWrite() error {
+	if s.Export.ReadOnly {
+		return status.Errorf(codes.PermissionDenied, "export %s is read-only", s.Export.Path)
+	}
+	return nil
+}
+
+// CheckPath 校验绝对路径位于会话根目录内
+func (s *Session) CheckPath(p string) error {
+	p = path.Clean("/" + p)
+	if s.Root != "/" && p != s.Root && !strings.HasPrefix(p, s.Root+"/") {
+		return status.Errorf(codes.NotFound, "no such file: %s", p)
+	}
+	return nil
+}
+
+type sessionKey struct{}
+
+// WithSession 将挂载会话写入上下文
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFromContext 从上下文获取挂载会话
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok && s != nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"cpfs/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExportTableSetup(t *testing.T) {
+	table, err := NewExportTable([]Export{
+		{Path: "/projects", Groups: []string{"staff"}, ReadOnly: true},
+		{Path: "/projects/teamA/", Users: []string{"alice"}},
+	})
+	require.NoError(t, err)
+
+	alice := &Principal{User: "alice"}
+	bob := &Principal{User: "bob", Groups: []string{"staff"}}
+
+	session, err := table.Setup(alice, "/projects/teamA/src")
+	require.NoError(t, err)
+	assert.Equal(t, "/projects/teamA", session.Export.Path)
+	assert.Equal(t, "/projects/teamA/src", session.Root)
+	assert.NoError(t, session.CheckWrite())
+
+	_, err = table.Setup(bob, "/projects/teamA")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	session, err = table.Setup(bob, "/projects/teamB")
+	require.NoError(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(session.CheckWrite()))
+
+	_, err = table.Setup(alice, "/projects")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = table.Setup(bob, "/home")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = table.Setup(bob, "/projectsX")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = table.Setup(nil, "/projects")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestSessionConfinement(t *testing.T) {
+	table, err := NewExportTable([]Export{{Path: "/projects/teamA"}})
+	require.NoError(t, err)
+
+	session, err := table.Setup(&Principal{User: "alice"}, "/projects/teamA")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/projects/teamA", session.Resolve("/"))
+	assert.Equal(t, "/projects/teamA/x", session.Resolve("x"))
+	assert.Equal(t, "/projects/teamA/etc", session.Resolve("../../../etc"))
+
+	assert.NoError(t, session.CheckPath("/projects/teamA/x"))
+	assert.Equal(t, codes.NotFound, status.Code(session.CheckPath("/projects/teamB")))
+	assert.Equal(t, codes.NotFound, status.Code(session.CheckPath("/projects/teamA/../teamB")))
+
+	ctx := WithSession(context.Background(), session)
+	got, ok := SessionFromContext(ctx)
+	require.True(t, ok)
+	assert.Same(t, session, got)
+}
+
+func TestExportTableConfig(t *testing.T) {
+	table, err := NewExportTableFromConfig(nil)
+	require.NoError(t, err)
+	session, err := table.Setup(&Principal{User: "anyone"}, "/any/where")
+	require.NoError(t, err)
+	assert.Equal(t, "/", session.Export.Path)
+
+	table, err = NewExportTableFromConfig([]config.ExportConfig{{Path: "/data", ReadOnly: true}})
+	require.NoError(t, err)
+	session, err = table.Setup(&Principal{User: "anyone"}, "/data")
+	require.NoError(t, err)
+	assert.True(t, session.Export.ReadOnly)
+
+	_, err = NewExportTable([]Export{{Path: "relative"}})
+	assert.Error(t, err)
+	_, err = NewExportTable([]Export{{Path: "/a"}, {Path: "/a/"}})
+	assert.Error(t, err)
+}
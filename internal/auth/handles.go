@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FileCloser 释放元数据服务上 inode 的打开引用，meta.MemoryStore 实现该接口
+type FileCloser interface {
+	Close(ctx context.Context, inode uint64) error
+}
+
+// handle 会话中的一个打开句柄
+type handle struct {
+	inode   uint64
+	release func()
+}
+
+// handleTable 会话的打开句柄表
+type handleTable struct {
+	mu     sync.Mutex
+	nextID uint64
+	byID   map[uint64]handle
+}
+
+// AddHandle 登记一个已在元数据服务上打开的 inode，占用一个 ResourceOpenFiles，返回句柄 ID
+//
+// 超出 MaxOpenFiles 时返回 *LimitExceededError，调用方需要自行关闭已打开的 inode。
+func (s *Session) AddHandle(inode uint64) (uint64, error) {
+	release, err := s.Acquire(ResourceOpenFiles)
+	if err != nil {
+		return 0, err
+	}
+
+	s.handles.mu.Lock()
+	defer s.handles.mu.Unlock()
+	if s.handles.byID == nil {
+		s.handles.byID = make(map[uint64]handle)
+	}
+	s.handles.nextID++
+	s.handles.byID[s.handles.nextID] = handle{inode: inode, release: release}
+	return s.handles.nextID, nil
+}
+
+// CloseHandle 关闭句柄并释放它在元数据服务上的打开引用
+func (s *Session) CloseHandle(ctx context.Context, files FileCloser, id uint64) error {
+	s.handles.mu.Lock()
+	h, ok := s.handles.byID[id]
+	delete(s.handles.byID, id)
+	s.handles.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown file handle: %d", id)
+	}
+
+	h.release()
+	return files.Close(ctx, h.inode)
+}
+
+// CloseAll 关闭会话的全部句柄，在客户端断开或会话过期时调用
+//
+// 否则已删除但仍被该会话打开的文件的数据块永远不会被回收。遇到错误时继续关闭其余句柄，返回全部错误。
+func (s *Session) CloseAll(ctx context.Context, files FileCloser) error {
+	s.handles.mu.Lock()
+	ids := make([]uint64, 0, len(s.handles.byID))
+	for id := range s.handles.byID {
+		ids = append(ids, id)
+	}
+	s.handles.mu.Unlock()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var errs []error
+	for _, id := range ids {
+		if err := s.CloseHandle(ctx, files, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HandleInode 返回句柄对应的 inode
+func (s *Session) HandleInode(id uint64) (uint64, bool) {
+	s.handles.mu.Lock()
+	defer s.handles.mu.Unlock()
+	h, ok := s.handles.byID[id]
+	return h.inode, ok
+}
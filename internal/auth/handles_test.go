@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFiles 记录每个 inode 的关闭次数
+type fakeFiles struct {
+	closed map[uint64]int
+	fail   error
+}
+
+func (f *fakeFiles) Close(ctx context.Context, inode uint64) error {
+	f.closed[inode]++
+	return f.fail
+}
+
+func TestSessionHandles(t *testing.T) {
+	ctx := context.Background()
+	files := &fakeFiles{closed: make(map[uint64]int)}
+	session := newLimitedSession(t, SessionLimits{MaxOpenFiles: 2})
+
+	id1, err := session.AddHandle(10)
+	require.NoError(t, err)
+	id2, err := session.AddHandle(10)
+	require.NoError(t, err)
+	assert.NotEqual(t, id1, id2)
+	inode, ok := session.HandleInode(id2)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), inode)
+
+	// 句柄占用打开文件配额
+	_, err = session.AddHandle(11)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	require.NoError(t, session.CloseHandle(ctx, files, id1))
+	assert.Equal(t, 1, files.closed[10])
+	assert.Equal(t, 1, session.Usage(ResourceOpenFiles))
+	assert.Error(t, session.CloseHandle(ctx, files, id1))
+	assert.Equal(t, 1, files.closed[10])
+}
+
+func TestSessionCloseAll(t *testing.T) {
+	ctx := context.Background()
+	files := &fakeFiles{closed: make(map[uint64]int)}
+	session := newLimitedSession(t, SessionLimits{})
+
+	for _, inode := range []uint64{1, 2, 2} {
+		_, err := session.AddHandle(inode)
+		require.NoError(t, err)
+	}
+	require.NoError(t, session.CloseAll(ctx, files))
+	assert.Equal(t, map[uint64]int{1: 1, 2: 2}, files.closed)
+	assert.Zero(t, session.Usage(ResourceOpenFiles))
+
+	// 元数据服务出错时仍然关闭全部句柄
+	files.fail = errors.New("unavailable")
+	for _, inode := range []uint64{3, 4} {
+		_, err := session.AddHandle(inode)
+		require.NoError(t, err)
+	}
+	assert.Error(t, session.CloseAll(ctx, files))
+	assert.Zero(t, session.Usage(ResourceOpenFiles))
+	_, ok := session.HandleInode(1)
+	assert.False(t, ok)
+}
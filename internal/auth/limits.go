@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Resource 会话占用的资源类型
+type Resource int
+
+const (
+	ResourceOpenFiles Resource = iota // 打开的文件句柄
+	ResourceLocks                     // 持有的锁
+	ResourceWatches                   // 目录变更订阅
+	ResourceInflight                  // 进行中的 RPC
+	resourceCount
+)
+
+// String 返回资源名称
+func (r Resource) String() string {
+	switch r {
+	case ResourceOpenFiles:
+		return "open_files"
+	case ResourceLocks:
+		return "locks"
+	case ResourceWatches:
+		return "watches"
+	case ResourceInflight:
+		return "inflight_rpcs"
+	default:
+		return fmt.Sprintf("Resource(%d)", int(r))
+	}
+}
+
+// SessionLimits 单个会话的资源上限，为 0 表示不限制
+type SessionLimits struct {
+	MaxOpenFiles int
+	MaxLocks     int
+	MaxWatches   int
+	MaxInflight  int
+}
+
+// limit 返回资源上限
+func (l SessionLimits) limit(r Resource) int {
+	switch r {
+	case ResourceOpenFiles:
+		return l.MaxOpenFiles
+	case ResourceLocks:
+		return l.MaxLocks
+	case ResourceWatches:
+		return l.MaxWatches
+	case ResourceInflight:
+		return l.MaxInflight
+	default:
+		return 0
+	}
+}
+
+// ErrLimitExceeded 会话资源超出上限
+var ErrLimitExceeded = errors.New("session limit exceeded")
+
+// LimitExceededError 会话资源超出上限的详细错误
+type LimitExceededError struct {
+	User     string
+	Resource Resource
+	Limit    int
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("session limit exceeded for %s: %s limit is %d", e.User, e.Resource, e.Limit)
+}
+
+// Is 使 errors.Is(err, ErrLimitExceeded) 成立
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// GRPCStatus 将错误映射为 codes.ResourceExhausted
+func (e *LimitExceededError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Error())
+}
+
+// sessionUsage 会话资源占用计数
+type sessionUsage struct {
+	mu     sync.Mutex
+	limits SessionLimits
+	used   [resourceCount]int
+}
+
+// SetLimits 设置之后建立的会话的资源上限
+func (t *ExportTable) SetLimits(limits SessionLimits) {
+	t.limits = limits
+}
+
+// Acquire 占用一个资源，超出上限时返回 *LimitExceededError；成功时返回释放函数，重复调用只释放一次
+func (s *Session) Acquire(r Resource) (release func(), err error) {
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+
+	if limit := s.usage.limits.limit(r); limit > 0 && s.usage.used[r] >= limit {
+		return nil, &LimitExceededError{User: s.Principal.User, Resource: r, Limit: limit}
+	}
+	s.usage.used[r]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.usage.mu.Lock()
+			s.usage.used[r]--
+			s.usage.mu.Unlock()
+		})
+	}, nil
+}
+
+// Usage 返回会话当前占用的资源数量
+func (s *Session) Usage(r Resource) int {
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	return s.usage.used[r]
+}
+
+// LimitUnaryInterceptor 返回按会话限制进行中 RPC 数量的一元拦截器，上下文中没有会话的请求不受限制
+func LimitUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		session, ok := SessionFromContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+		release, err := session.Acquire(ResourceInflight)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// LimitStreamInterceptor 返回按会话限制进行中流数量的流拦截器
+func LimitStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		session, ok := SessionFromContext(ss.Context())
+		if !ok {
+			return handler(srv, ss)
+		}
+		release, err := session.Acquire(ResourceInflight)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newLimitedSession(t *testing.T, limits SessionLimits) *Session {
+	table, err := NewExportTable(nil)
+	require.NoError(t, err)
+	table.SetLimits(limits)
+	session, err := table.Setup(&Principal{User: "alice"}, "/")
+	require.NoError(t, err)
+	return session
+}
+
+func TestSessionAcquire(t *testing.T) {
+	session := newLimitedSession(t, SessionLimits{MaxOpenFiles: 2})
+
+	release1, err := session.Acquire(ResourceOpenFiles)
+	require.NoError(t, err)
+	_, err = session.Acquire(ResourceOpenFiles)
+	require.NoError(t, err)
+
+	_, err = session.Acquire(ResourceOpenFiles)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	var limitErr *LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, ResourceOpenFiles, limitErr.Resource)
+	assert.Equal(t, 2, limitErr.Limit)
+
+	release1()
+	release1()
+	assert.Equal(t, 1, session.Usage(ResourceOpenFiles))
+	_, err = session.Acquire(ResourceOpenFiles)
+	assert.NoError(t, err)
+
+	// 未设置上限的资源不受限制
+	for i := 0; i < 100; i++ {
+		_, err := session.Acquire(ResourceLocks)
+		require.NoError(t, err)
+	}
+}
+
+func TestLimitUnaryInterceptor(t *testing.T) {
+	session := newLimitedSession(t, SessionLimits{MaxInflight: 1})
+	ctx := WithSession(context.Background(), session)
+	interceptor := LimitUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/m"}
+
+	var nested error
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, 1, session.Usage(ResourceInflight))
+		_, nested = interceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(nested))
+	assert.Equal(t, 0, session.Usage(ResourceInflight))
+
+	_, err = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,423 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig OIDC 提供方配置
+type OIDCConfig struct {
+	// Issuer 签发者地址，用于发现配置并校验 iss
+	Issuer string
+	// ClientID 客户端ID，用于校验 aud
+	ClientID string
+	// UsernameClaim 映射为用户名的声明，默认 sub；preferred_username 等声明可由用户修改，
+	// 只有提供方保证其唯一且不可变时才应配置，缺失时使用 sub
+	UsernameClaim string
+	// GroupsClaim 映射为组的声明，默认 groups
+	GroupsClaim string
+	// ClockSkew 校验时间时允许的时钟偏差
+	ClockSkew time.Duration
+	// KeyRefreshInterval 遇到未知 kid 时两次刷新 JWKS 的最小间隔，默认 30 秒
+	KeyRefreshInterval time.Duration
+	// HTTPClient 访问提供方使用的 HTTP 客户端
+	HTTPClient *http.Client
+}
+
+// withDefaults 校验配置并填充默认值
+func (c OIDCConfig) withDefaults() (OIDCConfig, error) {
+	if c.Issuer == "" || c.ClientID == "" {
+		return c, fmt.Errorf("issuer and client id are required")
+	}
+	if c.UsernameClaim == "" {
+		c.UsernameClaim = "sub"
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+	if c.ClockSkew == 0 {
+		c.ClockSkew = time.Minute
+	}
+	if c.KeyRefreshInterval == 0 {
+		c.KeyRefreshInterval = time.Second * 30
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: time.Second * 10}
+	}
+	return c, nil
+}
+
+// providerMetadata OIDC 发现文档
+type providerMetadata struct {
+	Issuer                      string `json:"issuer"`
+	JWKSURI                     string `json:"jwks_uri"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// jsonWebKey JWKS 中的单个密钥
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCVerifier 校验 OIDC ID Token 并映射为 cpfs 主体
+type OIDCVerifier struct {
+	config   OIDCConfig
+	metadata providerMetadata
+	mu       sync.RWMutex
+	keys     map[string]crypto.PublicKey
+	now      func() time.Time
+
+	refreshMu   sync.Mutex
+	refreshing  *keyRefresh // 进行中的刷新，并发的未知 kid 共用一次请求
+	lastRefresh time.Time   // 上一次开始刷新的时间
+}
+
+// keyRefresh 一次进行中的 JWKS 刷新
+type keyRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewOIDCVerifier 创建 OIDC 校验器，通过发现文档获取 JWKS 地址
+func NewOIDCVerifier(ctx context.Context, config OIDCConfig) (*OIDCVerifier, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &OIDCVerifier{
+		config: config,
+		keys:   make(map[string]crypto.PublicKey),
+		now:    time.Now,
+	}
+
+	metadata, err := discoverProvider(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	v.metadata = metadata
+
+	v.lastRefresh = v.now()
+	if err := v.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// discoverProvider 获取并校验 OIDC 发现文档
+func discoverProvider(ctx context.Context, config OIDCConfig) (providerMetadata, error) {
+	var metadata providerMetadata
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, config.HTTPClient, discoveryURL, &metadata); err != nil {
+		return metadata, fmt.Errorf("failed to discover provider: %v", err)
+	}
+	if metadata.Issuer != config.Issuer {
+		return metadata, fmt.Errorf("issuer mismatch: expected %s, got %s", config.Issuer, metadata.Issuer)
+	}
+	return metadata, nil
+}
+
+// getJSON 获取并解析 JSON 文档
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// refreshKeys 重新获取 JWKS
+func (v *OIDCVerifier) refreshKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, v.config.HTTPClient, v.metadata.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch jwks: %v", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range jwks.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			// 跳过不支持的密钥类型
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.mu.Unlock()
+	return nil
+}
+
+// publicKey 将 JWK 转换为公钥
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+// refreshKeysLimited 为未知 kid 刷新 JWKS
+//
+// 并发的调用共用同一次请求；距上一次刷新不足 KeyRefreshInterval 时直接返回，
+// 避免携带伪造 kid 的令牌不断触发对提供方的请求。
+func (v *OIDCVerifier) refreshKeysLimited(ctx context.Context) error {
+	v.refreshMu.Lock()
+	if call := v.refreshing; call != nil {
+		v.refreshMu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if v.now().Sub(v.lastRefresh) < v.config.KeyRefreshInterval {
+		v.refreshMu.Unlock()
+		return nil
+	}
+	call := &keyRefresh{done: make(chan struct{})}
+	v.refreshing = call
+	v.lastRefresh = v.now()
+	v.refreshMu.Unlock()
+
+	// 其他调用方在等待这次请求，不随发起者的 ctx 取消，超时由 HTTPClient 控制
+	call.err = v.refreshKeys(context.WithoutCancel(ctx))
+
+	v.refreshMu.Lock()
+	v.refreshing = nil
+	v.refreshMu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// key 按 kid 获取公钥，未知 kid 时刷新 JWKS 以支持密钥轮换
+func (v *OIDCVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	v.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if err := v.refreshKeysLimited(ctx); err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %s", kid)
+}
+
+// Verify 校验 ID Token 的签名、签发者、受众和有效期，并映射为主体
+func (v *OIDCVerifier) Verify(ctx context.Context, rawToken string) (*Principal, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %v", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %v", err)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return v.principal(claims)
+}
+
+// verifySignature 按算法校验签名
+func verifySignature(alg string, key crypto.PublicKey, digest, signature []byte) error {
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key type does not match algorithm %s", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest, signature); err != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("key type does not match algorithm %s", alg)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid token signature")
+		}
+	default:
+		return fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+	return nil
+}
+
+// validateClaims 校验 iss、aud、azp、exp、nbf
+func (v *OIDCVerifier) validateClaims(claims map[string]interface{}) error {
+	if iss, _ := claims["iss"].(string); iss != v.config.Issuer {
+		return fmt.Errorf("invalid issuer: %s", iss)
+	}
+
+	audienceOK := false
+	audiences := 0
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = 1
+		audienceOK = aud == v.config.ClientID
+	case []interface{}:
+		audiences = len(aud)
+		for _, a := range aud {
+			if s, _ := a.(string); s == v.config.ClientID {
+				audienceOK = true
+				break
+			}
+		}
+	}
+	if !audienceOK {
+		return fmt.Errorf("token audience does not include %s", v.config.ClientID)
+	}
+
+	// 令牌有多个受众时必须由本客户端申请，出现 azp 时也必须是本客户端
+	azp, hasAZP := claims["azp"].(string)
+	if (audiences > 1 || hasAZP) && azp != v.config.ClientID {
+		return fmt.Errorf("token authorized party %q is not %s", azp, v.config.ClientID)
+	}
+
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.config.ClockSkew)) {
+		return fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.config.ClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("token not yet valid")
+	}
+
+	return nil
+}
+
+// principal 将声明映射为主体
+func (v *OIDCVerifier) principal(claims map[string]interface{}) (*Principal, error) {
+	user, _ := claims[v.config.UsernameClaim].(string)
+	if user == "" {
+		user, _ = claims["sub"].(string)
+	}
+	if user == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	p := &Principal{User: user}
+	switch groups := claims[v.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				p.Groups = append(p.Groups, s)
+			}
+		}
+	case string:
+		p.Groups = []string{groups}
+	}
+
+	return p, nil
+}
+
+// decodeSegment 解码 JWT 中 base64url 编码的 JSON 段
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// testProvider 模拟 OIDC 提供方
+type testProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	polls  int32
+	jwks   int32 // JWKS 请求次数
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                        p.server.URL,
+			"jwks_uri":                      p.server.URL + "/jwks",
+			"token_endpoint":                p.server.URL + "/token",
+			"device_authorization_endpoint": p.server.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&p.jwks, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(DeviceAuthorization{
+			DeviceCode:      "dev-code",
+			UserCode:        "ABCD-EFGH",
+			VerificationURI: p.server.URL + "/activate",
+			ExpiresIn:       60,
+			Interval:        1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&p.polls, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{IDToken: "id-token", TokenType: "Bearer"})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// sign 签发测试令牌
+func (p *testProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	return p.signWithKid(t, "k1", claims)
+}
+
+// signWithKid 用指定 kid 签发测试令牌
+func (p *testProvider) signWithKid(t *testing.T, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (p *testProvider) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                p.server.URL,
+		"aud":                "cpfs",
+		"sub":                "u-123",
+		"preferred_username": "alice",
+		"groups":             []string{"admins", "team-a"},
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier, err := NewOIDCVerifier(ctx, OIDCConfig{Issuer: provider.server.URL, ClientID: "cpfs"})
+	require.NoError(t, err)
+
+	principal, err := verifier.Verify(ctx, provider.sign(t, provider.claims()))
+	require.NoError(t, err)
+	assert.Equal(t, "u-123", principal.User)
+	assert.True(t, principal.InGroup("team-a"))
+
+	// 受众不匹配
+	claims := provider.claims()
+	claims["aud"] = []string{"other"}
+	_, err = verifier.Verify(ctx, provider.sign(t, claims))
+	assert.Error(t, err)
+
+	// 多个受众时 azp 必须是本客户端
+	claims = provider.claims()
+	claims["aud"] = []string{"cpfs", "other"}
+	_, err = verifier.Verify(ctx, provider.sign(t, claims))
+	assert.Error(t, err)
+	claims["azp"] = "other"
+	_, err = verifier.Verify(ctx, provider.sign(t, claims))
+	assert.Error(t, err)
+	claims["azp"] = "cpfs"
+	_, err = verifier.Verify(ctx, provider.sign(t, claims))
+	assert.NoError(t, err)
+
+	// 已过期
+	claims = provider.claims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	_, err = verifier.Verify(ctx, provider.sign(t, claims))
+	assert.Error(t, err)
+
+	// 签发者不匹配
+	claims = provider.claims()
+	claims["iss"] = "https://evil.example.com"
+	_, err = verifier.Verify(ctx, provider.sign(t, claims))
+	assert.Error(t, err)
+
+	// 篡改签名
+	token := provider.sign(t, provider.claims())
+	tampered := provider.sign(t, map[string]interface{}{"sub": "mallory"})
+	_, err = verifier.Verify(ctx, token[:len(token)-10]+tampered[len(tampered)-10:])
+	assert.Error(t, err)
+
+	_, err = verifier.Verify(ctx, "not-a-token")
+	assert.Error(t, err)
+}
+
+func TestOIDCVerifierUsernameClaim(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier, err := NewOIDCVerifier(ctx, OIDCConfig{
+		Issuer:        provider.server.URL,
+		ClientID:      "cpfs",
+		UsernameClaim: "preferred_username",
+	})
+	require.NoError(t, err)
+
+	principal, err := verifier.Verify(ctx, provider.sign(t, provider.claims()))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.User)
+
+	// 声明缺失时回退到 sub
+	claims := provider.claims()
+	delete(claims, "preferred_username")
+	principal, err = verifier.Verify(ctx, provider.sign(t, claims))
+	require.NoError(t, err)
+	assert.Equal(t, "u-123", principal.User)
+}
+
+func TestOIDCVerifierKeyRefreshLimit(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier, err := NewOIDCVerifier(ctx, OIDCConfig{Issuer: provider.server.URL, ClientID: "cpfs"})
+	require.NoError(t, err)
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+	assert.Equal(t, int32(1), atomic.LoadInt32(&provider.jwks))
+
+	// 刚刷新过，未知 kid 不再请求提供方
+	token := provider.signWithKid(t, "unknown", provider.claims())
+	_, err = verifier.Verify(ctx, token)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&provider.jwks))
+
+	// 超过最小间隔后并发的未知 kid 只刷新一次
+	now = now.Add(time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.Verify(ctx, token)
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&provider.jwks))
+
+	// 已知 kid 不受影响
+	_, err = verifier.Verify(ctx, provider.sign(t, provider.claims()))
+	assert.NoError(t, err)
+}
+
+func TestDeviceFlow(t *testing.T) {
+	provider := newTestProvider(t)
+	ctx := context.Background()
+
+	flow, err := NewDeviceFlow(ctx, OIDCConfig{Issuer: provider.server.URL, ClientID: "cpfs"})
+	require.NoError(t, err)
+
+	auth, err := flow.Start(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD-EFGH", auth.UserCode)
+
+	token, err := flow.Poll(ctx, auth)
+	require.NoError(t, err)
+	assert.Equal(t, "id-token", token.IDToken)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&provider.polls))
+}
+
+// staticVerifier 测试用令牌校验器
+type staticVerifier struct{}
+
+func (staticVerifier) Verify(ctx context.Context, rawToken string) (*Principal, error) {
+	if rawToken != "good" {
+		return nil, assert.AnError
+	}
+	return &Principal{User: "alice"}, nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(staticVerifier{})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		p, ok := PrincipalFromContext(ctx)
+		require.True(t, ok)
+		return p.User, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer good"))
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", resp)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer bad"))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Error(t, err)
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"cpfs/internal/principal"
+)
+
+// Principal 已认证的 cpfs 主体
+type Principal = principal.Principal
+
+// WithPrincipal 将主体写入上下文
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return principal.WithPrincipal(ctx, p)
+}
+
+// PrincipalFromContext 从上下文获取主体
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	return principal.FromContext(ctx)
+}
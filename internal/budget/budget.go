@@ -0,0 +1,212 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// DefaultLowWatermark 超出预算时回收到总预算的该比例以下，避免频繁触发回收
+const DefaultLowWatermark = 0.9
+
+// Consumer 向预算管理器登记的内存使用方（元数据缓存、块缓存、预读缓冲、日志等）
+//
+// Usage 和 Evict 由管理器在自己的锁内调用，实现方不能在持有自身锁时回调管理器。
+type Consumer interface {
+	// Usage 返回当前占用的字节数
+	Usage() int64
+	// Evict 尝试释放至少 bytes 字节，返回实际释放的字节数
+	Evict(bytes int64) int64
+}
+
+// consumer 已登记的使用方
+type consumer struct {
+	name     string
+	c        Consumer
+	priority int
+	evicted  *metrics.Counter
+}
+
+// Manager 跨子系统的内存预算管理器
+//
+// 各缓存独立增长，由管理器统一统计总占用；总占用超过预算时按优先级从低到高依次要求使用方回收，
+// 直到回到低水位以下。
+type Manager struct {
+	mu           sync.Mutex
+	limit        int64
+	lowWatermark float64
+	consumers    map[string]*consumer
+	registry     *metrics.Registry
+	log          logger.Logger
+}
+
+// NewManager 创建总预算为 limit 字节的管理器，limit 小于等于 0 表示不限制；registry 为 nil 时使用 metrics.Default
+func NewManager(limit int64, registry *metrics.Registry) *Manager {
+	if registry == nil {
+		registry = metrics.Default
+	}
+	m := &Manager{
+		limit:        limit,
+		lowWatermark: DefaultLowWatermark,
+		consumers:    make(map[string]*consumer),
+		registry:     registry,
+		log:          logger.Default(),
+	}
+	registry.GaugeFunc("cpfs_memory_budget_bytes", "Total memory budget shared by caches.", nil, func() float64 {
+		return float64(m.limit)
+	})
+	registry.GaugeFunc("cpfs_memory_used_bytes", "Memory used by all registered caches.", nil, func() float64 {
+		return float64(m.Usage())
+	})
+	return m
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (m *Manager) SetLogger(l logger.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.log = logger.OrDefault(l)
+}
+
+// Register 登记使用方，priority 越小越先被回收
+func (m *Manager) Register(name string, c Consumer, priority int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.consumers[name]; exists {
+		return fmt.Errorf("memory consumer already registered: %s", name)
+	}
+	labels := metrics.Labels{"consumer": name}
+	m.consumers[name] = &consumer{
+		name:     name,
+		c:        c,
+		priority: priority,
+		evicted:  m.registry.Counter("cpfs_memory_evicted_bytes_total", "Bytes released by budget-driven eviction.", labels),
+	}
+	m.registry.GaugeFunc("cpfs_memory_consumer_bytes", "Memory used by a registered cache.", labels, func() float64 {
+		return float64(c.Usage())
+	})
+	return nil
+}
+
+// Unregister 注销使用方
+func (m *Manager) Unregister(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.consumers, name)
+}
+
+// Limit 返回总预算
+func (m *Manager) Limit() int64 {
+	return m.limit
+}
+
+// Usage 返回所有使用方的总占用
+func (m *Manager) Usage() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage()
+}
+
+// usage 统计总占用，调用方需持有 mu
+func (m *Manager) usage() int64 {
+	var total int64
+	for _, c := range m.consumers {
+		total += c.c.Usage()
+	}
+	return total
+}
+
+// Available 返回预算剩余字节数，不限制时返回 -1
+func (m *Manager) Available() int64 {
+	if m.limit <= 0 {
+		return -1
+	}
+	return max(m.limit-m.Usage(), 0)
+}
+
+// Enforce 总占用超过预算时协调回收，返回释放的字节数
+//
+// 使用方按优先级从低到高、同优先级按占用从大到小依次回收，直到总占用降到低水位以下。
+func (m *Manager) Enforce() int64 {
+	if m.limit <= 0 {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := m.usage()
+	if total <= m.limit {
+		return 0
+	}
+	target := total - int64(float64(m.limit)*m.lowWatermark)
+
+	type candidate struct {
+		*consumer
+		usage int64
+	}
+	candidates := make([]candidate, 0, len(m.consumers))
+	for _, c := range m.consumers {
+		candidates = append(candidates, candidate{c, c.c.Usage()})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority < candidates[j].priority
+		}
+		if candidates[i].usage != candidates[j].usage {
+			return candidates[i].usage > candidates[j].usage
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var freed int64
+	for _, c := range candidates {
+		if freed >= target {
+			break
+		}
+		if c.usage <= 0 {
+			continue
+		}
+		n := c.c.Evict(min(target-freed, c.usage))
+		if n > 0 {
+			c.evicted.Add(uint64(n))
+			freed += n
+		}
+	}
+
+	m.log.Info("Enforced memory budget",
+		zap.Int64("limit", m.limit),
+		zap.Int64("usage", total),
+		zap.Int64("freed", freed),
+	)
+	if freed < total-m.limit {
+		m.log.Warn("Memory budget still exceeded after eviction",
+			zap.Int64("limit", m.limit),
+			zap.Int64("usage", total-freed),
+		)
+	}
+	return freed
+}
+
+// Run 按 interval 周期执行 Enforce，直到 ctx 取消
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Enforce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package budget
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsumer 按请求字节数释放的测试使用方，minimum 为不可回收的部分
+type fakeConsumer struct {
+	mu      sync.Mutex
+	used    int64
+	minimum int64
+	calls   int
+}
+
+func (c *fakeConsumer) Usage() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used
+}
+
+func (c *fakeConsumer) Evict(n int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	freed := min(n, c.used-c.minimum)
+	c.used -= freed
+	return freed
+}
+
+func TestManagerEnforce(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewManager(1000, registry)
+
+	readAhead := &fakeConsumer{used: 300}
+	metadata := &fakeConsumer{used: 500}
+	blocks := &fakeConsumer{used: 400}
+	require.NoError(t, m.Register("readahead", readAhead, 0))
+	require.NoError(t, m.Register("metadata", metadata, 10))
+	require.NoError(t, m.Register("blocks", blocks, 5))
+	assert.Error(t, m.Register("blocks", blocks, 5))
+
+	assert.Equal(t, int64(1200), m.Usage())
+	assert.Equal(t, int64(0), m.Available())
+
+	// 需要回收到 900：先回收优先级最低的 readahead，再回收 blocks
+	freed := m.Enforce()
+	assert.Equal(t, int64(300), freed)
+	assert.Equal(t, int64(0), readAhead.Usage())
+	assert.Equal(t, int64(400), blocks.Usage())
+	assert.Equal(t, 0, metadata.calls)
+	assert.Equal(t, int64(900), m.Usage())
+
+	// 低于预算时不回收
+	assert.Equal(t, int64(0), m.Enforce())
+
+	blocks.used = 700
+	readAhead.used = 100
+	readAhead.minimum = 100
+	freed = m.Enforce()
+	assert.Equal(t, int64(400), freed)
+	assert.Equal(t, int64(300), blocks.Usage())
+	assert.Equal(t, int64(100), readAhead.Usage())
+
+	var out bytes.Buffer
+	require.NoError(t, registry.WritePrometheus(&out))
+	assert.Contains(t, out.String(), `cpfs_memory_evicted_bytes_total{consumer="blocks"} 400`)
+	assert.Contains(t, out.String(), `cpfs_memory_consumer_bytes{consumer="metadata"} 500`)
+
+	m.Unregister("metadata")
+	assert.Equal(t, int64(400), m.Usage())
+}
+
+func TestManagerUnlimited(t *testing.T) {
+	m := NewManager(0, metrics.NewRegistry())
+	c := &fakeConsumer{used: 1 << 40}
+	require.NoError(t, m.Register("cache", c, 0))
+	assert.Equal(t, int64(0), m.Enforce())
+	assert.Equal(t, int64(-1), m.Available())
+	assert.Equal(t, 0, c.calls)
+}
+
+func TestManagerRun(t *testing.T) {
+	m := NewManager(100, metrics.NewRegistry())
+	c := &fakeConsumer{used: 200}
+	require.NoError(t, m.Register("cache", c, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return c.Usage() <= 90 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
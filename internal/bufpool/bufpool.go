@@ -0,0 +1,93 @@
+package bufpool
+
+import (
+	"bytes"
+	"sync"
+
+	"cpfs/internal/metrics"
+)
+
+// DefaultMaxRetained 默认可回收缓冲区的最大容量，超过的缓冲区直接丢弃以免长期占用内存
+const DefaultMaxRetained = 4 << 20
+
+// Pool 基于 sync.Pool 的 bytes.Buffer 复用池
+type Pool struct {
+	pool        sync.Pool
+	maxRetained int
+
+	gets     metrics.Counter
+	puts     metrics.Counter
+	allocs   metrics.Counter
+	discards metrics.Counter
+}
+
+// Stats 缓冲池统计
+type Stats struct {
+	Gets     uint64 // 获取次数
+	Puts     uint64 // 成功归还次数
+	Allocs   uint64 // 池为空时新分配的次数
+	Discards uint64 // 因容量过大被丢弃的次数
+}
+
+// Default 默认的全局缓冲池
+var Default = New(DefaultMaxRetained)
+
+// New 创建缓冲池，maxRetained 为可回收缓冲区的最大容量
+func New(maxRetained int) *Pool {
+	if maxRetained <= 0 {
+		maxRetained = DefaultMaxRetained
+	}
+	p := &Pool{maxRetained: maxRetained}
+	p.pool.New = func() any {
+		p.allocs.Inc()
+		return new(bytes.Buffer)
+	}
+	return p
+}
+
+// Get 获取一个已清空的缓冲区
+func (p *Pool) Get() *bytes.Buffer {
+	p.gets.Inc()
+	buf := p.pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// Put 归还缓冲区，调用后不得再使用 buf 及其返回过的切片
+func (p *Pool) Put(buf *bytes.Buffer) {
+	if buf == nil {
+		return
+	}
+	if buf.Cap() > p.maxRetained {
+		p.discards.Inc()
+		return
+	}
+	p.puts.Inc()
+	p.pool.Put(buf)
+}
+
+// Stats 返回缓冲池统计
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Gets:     p.gets.Value(),
+		Puts:     p.puts.Value(),
+		Allocs:   p.allocs.Value(),
+		Discards: p.discards.Value(),
+	}
+}
+
+// RegisterMetrics 将缓冲池统计注册到指标注册表
+func (p *Pool) RegisterMetrics(registry *metrics.Registry, labels metrics.Labels) {
+	registry.CounterFunc("cpfs_bufpool_gets_total", "Number of buffers taken from the pool.", labels, func() float64 {
+		return float64(p.gets.Value())
+	})
+	registry.CounterFunc("cpfs_bufpool_puts_total", "Number of buffers returned to the pool.", labels, func() float64 {
+		return float64(p.puts.Value())
+	})
+	registry.CounterFunc("cpfs_bufpool_allocs_total", "Number of buffers allocated because the pool was empty.", labels, func() float64 {
+		return float64(p.allocs.Value())
+	})
+	registry.CounterFunc("cpfs_bufpool_discards_total", "Number of oversized buffers dropped instead of pooled.", labels, func() float64 {
+		return float64(p.discards.Value())
+	})
+}
@@ -0,0 +1,69 @@
+package bufpool
+
+import (
+	"bytes"
+	"testing"
+
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	p := New(1024)
+
+	buf := p.Get()
+	buf.WriteString("hello")
+	p.Put(buf)
+
+	// 取回的缓冲区已被清空
+	buf = p.Get()
+	assert.Equal(t, 0, buf.Len())
+	p.Put(buf)
+
+	// 超过上限的缓冲区不回收
+	large := p.Get()
+	large.Grow(4096)
+	p.Put(large)
+	p.Put(nil)
+
+	stats := p.Stats()
+	assert.Equal(t, uint64(3), stats.Gets)
+	assert.Equal(t, uint64(2), stats.Puts)
+	assert.Equal(t, uint64(1), stats.Discards)
+	assert.GreaterOrEqual(t, stats.Allocs, uint64(1))
+}
+
+func TestPoolRegisterMetrics(t *testing.T) {
+	p := New(0)
+	p.Put(p.Get())
+
+	registry := metrics.NewRegistry()
+	p.RegisterMetrics(registry, metrics.Labels{"pool": "test"})
+
+	var out bytes.Buffer
+	require.NoError(t, registry.WritePrometheus(&out))
+	assert.Contains(t, out.String(), "cpfs_bufpool_gets_total{pool=\"test\"} 1\n")
+	assert.Contains(t, out.String(), "cpfs_bufpool_puts_total{pool=\"test\"} 1\n")
+}
+
+func BenchmarkPool(b *testing.B) {
+	p := New(0)
+	data := bytes.Repeat([]byte("x"), 64<<10)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := p.Get()
+		buf.Write(data)
+		p.Put(buf)
+	}
+}
+
+func BenchmarkNoPool(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 64<<10)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		buf.Write(data)
+	}
+}
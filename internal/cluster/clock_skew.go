@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultMaxClockSkew 节点间时钟偏差的默认告警阈值
+//
+// 租约、快照调度和日志排序假设各节点时钟大致同步，偏差超过该值时这些假设可能不成立。
+const DefaultMaxClockSkew = 500 * time.Millisecond
+
+// ClockSkew 与某个节点的时钟偏差测量结果
+type ClockSkew struct {
+	Node     string        `json:"node"`
+	Address  string        `json:"address"`
+	Skew     time.Duration `json:"skew"` // 对端时钟减本地时钟，正值表示对端时钟较快
+	RTT      time.Duration `json:"rtt"`  // 测量时的往返时间，偏差的误差不超过其一半
+	Measured time.Time     `json:"measured"`
+	Exceeded bool          `json:"exceeded"` // 偏差超过阈值
+}
+
+// ClockSkewEvent 时钟偏差越过阈值或恢复正常时的事件
+type ClockSkewEvent struct {
+	ClockSkew
+	Threshold time.Duration `json:"threshold"`
+}
+
+// OnClockSkew 注册时钟偏差事件回调，偏差超过阈值和恢复正常时各调用一次
+func (m *Membership) OnClockSkew(fn func(ClockSkewEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.skewListeners = append(m.skewListeners, fn)
+}
+
+// ClockSkews 返回最近一次测得的各节点时钟偏差，按节点ID排序
+func (m *Membership) ClockSkews() []ClockSkew {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]ClockSkew, 0, len(m.skews))
+	for _, skew := range m.skews {
+		out = append(out, skew)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Node < out[j].Node })
+	return out
+}
+
+// estimateSkew 按 NTP 的方式估计偏差：假设对端在往返的中点读取时钟
+func estimateSkew(sent, received, remote time.Time) (skew, rtt time.Duration) {
+	rtt = received.Sub(sent)
+	return remote.Sub(sent.Add(rtt / 2)), rtt
+}
+
+// measureSkew 根据一次 gossip 往返中对端自身记录携带的时钟更新与该节点的偏差
+func (m *Membership) measureSkew(address string, sent, received time.Time, digest []Member) {
+	var peer *Member
+	for i := range digest {
+		if digest[i].Address == address && digest[i].ID != m.config.NodeID {
+			peer = &digest[i]
+			break
+		}
+	}
+	if peer == nil || peer.Clock.IsZero() {
+		return
+	}
+	skew, rtt := estimateSkew(sent, received, peer.Clock)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	current := ClockSkew{
+		Node:     peer.ID,
+		Address:  address,
+		Skew:     skew,
+		RTT:      rtt,
+		Measured: received,
+		Exceeded: abs > m.config.MaxClockSkew,
+	}
+
+	m.mu.Lock()
+	previous := m.skews[peer.ID]
+	m.skews[peer.ID] = current
+	listeners := m.skewListeners
+	m.mu.Unlock()
+
+	if current.Exceeded == previous.Exceeded {
+		return
+	}
+	if current.Exceeded {
+		m.log.Warn("Clock skew with cluster member exceeds threshold",
+			zap.String("node", current.Node),
+			zap.Duration("skew", current.Skew),
+			zap.Duration("rtt", current.RTT),
+			zap.Duration("threshold", m.config.MaxClockSkew),
+		)
+	} else {
+		m.log.Info("Clock skew with cluster member is back within threshold",
+			zap.String("node", current.Node),
+			zap.Duration("skew", current.Skew),
+		)
+	}
+	event := ClockSkewEvent{ClockSkew: current, Threshold: m.config.MaxClockSkew}
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
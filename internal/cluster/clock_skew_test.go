@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateSkew(t *testing.T) {
+	sent := time.Unix(1000, 0)
+	skew, rtt := estimateSkew(sent, sent.Add(20*time.Millisecond), sent.Add(time.Second+10*time.Millisecond))
+	assert.Equal(t, time.Second, skew)
+	assert.Equal(t, 20*time.Millisecond, rtt)
+}
+
+func TestMembershipClockSkew(t *testing.T) {
+	transport := &localTransport{nodes: make(map[string]*Membership)}
+	a := newTestMembership(t, "data-1", "a:1", transport, "b:1")
+	b := newTestMembership(t, "data-2", "b:1", transport)
+	transport.nodes["a:1"] = a
+	transport.nodes["b:1"] = b
+
+	var events []ClockSkewEvent
+	a.OnClockSkew(func(e ClockSkewEvent) {
+		events = append(events, e)
+	})
+
+	// 对端时钟快 2 秒
+	b.now = func() time.Time { return time.Now().Add(2 * time.Second) }
+	a.gossipRound(context.Background())
+	require.Len(t, events, 1)
+	assert.Equal(t, "data-2", events[0].Node)
+	assert.True(t, events[0].Exceeded)
+	assert.InDelta(t, float64(2*time.Second), float64(events[0].Skew), float64(100*time.Millisecond))
+	assert.Equal(t, DefaultMaxClockSkew, events[0].Threshold)
+
+	// 持续超限不重复通知
+	a.gossipRound(context.Background())
+	assert.Len(t, events, 1)
+
+	b.now = time.Now
+	a.gossipRound(context.Background())
+	require.Len(t, events, 2)
+	assert.False(t, events[1].Exceeded)
+
+	skews := a.ClockSkews()
+	require.Len(t, skews, 1)
+	assert.Equal(t, "b:1", skews[0].Address)
+	assert.Less(t, skews[0].Skew.Abs(), DefaultMaxClockSkew)
+
+	// 间接获知的节点时钟不用于测量
+	member, ok := a.Get("data-2")
+	require.True(t, ok)
+	assert.True(t, member.Clock.IsZero())
+}
@@ -0,0 +1,169 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// HintMode 分配提示的放置方式
+type HintMode int
+
+const (
+	// HintCollocate 同组文件的数据块尽量放在相同的节点上
+	HintCollocate HintMode = iota + 1
+	// HintSpread 同组文件的数据块尽量分散到不同节点，提高并行读取带宽
+	HintSpread
+)
+
+// String 返回放置方式名称
+func (m HintMode) String() string {
+	switch m {
+	case HintCollocate:
+		return "collocate"
+	case HintSpread:
+		return "spread"
+	default:
+		return fmt.Sprintf("HintMode(%d)", int(m))
+	}
+}
+
+// ParseHintMode 解析放置方式名称
+func ParseHintMode(s string) (HintMode, error) {
+	switch s {
+	case "collocate":
+		return HintCollocate, nil
+	case "spread":
+		return HintSpread, nil
+	default:
+		return 0, fmt.Errorf("unknown allocation hint mode: %q", s)
+	}
+}
+
+// AllocationHint 一组文件的放置提示，例如同一数据集的各个分片
+type AllocationHint struct {
+	Group string
+	Mode  HintMode
+}
+
+// hintGroup 一个提示组的放置方式和各节点已分配的数据块数
+type hintGroup struct {
+	mode   HintMode
+	paths  map[string]bool
+	placed map[string]int
+}
+
+// AllocationHints 按提示组调整数据块的节点选择
+//
+// 提示只影响满足放置约束的节点之间的排序，不会使分配失败；提示保存在内存中，
+// 元数据服务器重启后需要重新设置，已经放置的数据块不会迁移。
+type AllocationHints struct {
+	mu     sync.Mutex
+	paths  map[string]string // 文件路径 -> 提示组
+	groups map[string]*hintGroup
+}
+
+// NewAllocationHints 创建分配提示表
+func NewAllocationHints() *AllocationHints {
+	return &AllocationHints{
+		paths:  make(map[string]string),
+		groups: make(map[string]*hintGroup),
+	}
+}
+
+// SetHint 将文件加入提示组，文件原来所属的组被替换；同一组的放置方式必须一致
+func (h *AllocationHints) SetHint(paths []string, hint AllocationHint) error {
+	if hint.Group == "" {
+		return fmt.Errorf("allocation hint group is required")
+	}
+	if hint.Mode != HintCollocate && hint.Mode != HintSpread {
+		return fmt.Errorf("invalid allocation hint mode: %v", hint.Mode)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g, ok := h.groups[hint.Group]
+	if ok && g.mode != hint.Mode {
+		return fmt.Errorf("allocation hint group %s already uses mode %s", hint.Group, g.mode)
+	}
+	if !ok {
+		g = &hintGroup{mode: hint.Mode, paths: make(map[string]bool), placed: make(map[string]int)}
+		h.groups[hint.Group] = g
+	}
+	for _, p := range paths {
+		h.removeLocked(p)
+		h.paths[p] = hint.Group
+		g.paths[p] = true
+	}
+	return nil
+}
+
+// ClearHint 将文件移出所属的提示组，组内没有文件时删除该组
+func (h *AllocationHints) ClearHint(paths []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, p := range paths {
+		h.removeLocked(p)
+	}
+}
+
+// removeLocked 将文件移出提示组，调用方需持有锁
+func (h *AllocationHints) removeLocked(p string) {
+	group, ok := h.paths[p]
+	if !ok {
+		return
+	}
+	delete(h.paths, p)
+	g := h.groups[group]
+	delete(g.paths, p)
+	if len(g.paths) == 0 {
+		delete(h.groups, group)
+	}
+}
+
+// Hint 返回文件所属的提示组
+func (h *AllocationHints) Hint(p string) (AllocationHint, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	group, ok := h.paths[p]
+	if !ok {
+		return AllocationHint{}, false
+	}
+	return AllocationHint{Group: group, Mode: h.groups[group].mode}, true
+}
+
+// SelectNodes 为文件 p 的一个数据块选择 count 个节点并记录到其提示组
+//
+// 没有提示的文件与 SelectNodes 相同。集中放置时优先选择组内已放置数据块最多的节点，
+// 分散放置时优先选择最少的节点，放置数相同时按容量使用率排序。
+func (h *AllocationHints) SelectNodes(members []Member, constraint *Constraint, count int, p string) ([]Member, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	group, ok := h.paths[p]
+	if !ok {
+		return SelectNodes(members, constraint, count)
+	}
+	g := h.groups[group]
+
+	candidates, err := eligibleNodes(members, constraint, count)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		pi, pj := g.placed[candidates[i].ID], g.placed[candidates[j].ID]
+		if pi != pj {
+			if g.mode == HintCollocate {
+				return pi > pj
+			}
+			return pi < pj
+		}
+		return lessLoaded(candidates[i], candidates[j])
+	})
+
+	selected := candidates[:count]
+	for _, member := range selected {
+		g.placed[member.ID]++
+	}
+	return selected, nil
+}
@@ -0,0 +1,65 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func memberIDs(members []Member) []string {
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func TestAllocationHints(t *testing.T) {
+	members := []Member{
+		{ID: "d1", State: StateAlive, Load: NodeLoad{Capacity: 100, Used: 10}},
+		{ID: "d2", State: StateAlive, Load: NodeLoad{Capacity: 100, Used: 20}},
+		{ID: "d3", State: StateAlive, Load: NodeLoad{Capacity: 100, Used: 30}},
+		{ID: "d4", State: StateAlive, Load: NodeLoad{Capacity: 100, Used: 40}},
+	}
+	hints := NewAllocationHints()
+	require.NoError(t, hints.SetHint([]string{"/ds/shard-0", "/ds/shard-1"}, AllocationHint{Group: "ds", Mode: HintSpread}))
+	require.NoError(t, hints.SetHint([]string{"/join/a", "/join/b"}, AllocationHint{Group: "join", Mode: HintCollocate}))
+
+	// 分散放置：第二个分片避开第一个分片使用的节点
+	selected, err := hints.SelectNodes(members, nil, 2, "/ds/shard-0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d1", "d2"}, memberIDs(selected))
+	selected, err = hints.SelectNodes(members, nil, 2, "/ds/shard-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d3", "d4"}, memberIDs(selected))
+
+	// 集中放置：跟随组内已有的节点，即使它们负载更高
+	members[3].Load.Used = 5
+	selected, err = hints.SelectNodes(members, nil, 1, "/join/a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d4"}, memberIDs(selected))
+	members[3].Load.Used = 90
+	selected, err = hints.SelectNodes(members, nil, 1, "/join/b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d4"}, memberIDs(selected))
+
+	selected, err = hints.SelectNodes(members, nil, 1, "/other")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d1"}, memberIDs(selected), "files without a hint use plain selection")
+
+	_, err = hints.SelectNodes(members, nil, -1, "/ds/shard-0")
+	assert.Error(t, err)
+
+	hint, ok := hints.Hint("/join/a")
+	assert.True(t, ok)
+	assert.Equal(t, AllocationHint{Group: "join", Mode: HintCollocate}, hint)
+	hints.ClearHint([]string{"/join/a", "/join/b"})
+	_, ok = hints.Hint("/join/a")
+	assert.False(t, ok)
+
+	assert.Error(t, hints.SetHint([]string{"/x"}, AllocationHint{Group: "ds", Mode: HintCollocate}))
+	assert.Error(t, hints.SetHint([]string{"/x"}, AllocationHint{Mode: HintSpread}))
+	_, err = ParseHintMode("random")
+	assert.Error(t, err)
+}
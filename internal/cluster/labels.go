@@ -0,0 +1,251 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Labels 节点标签，例如 ssd=true、zone=z1
+type Labels map[string]string
+
+// Constraint 放置约束表达式
+//
+// 支持的语法：
+//
+//	expr   := term { "||" term }
+//	term   := factor { "&&" factor }
+//	factor := "!" factor | "(" expr ")" | key [ ("=" | "==" | "!=") value ]
+//
+// 仅给出 key 时表示要求节点存在该标签。
+type Constraint struct {
+	source string
+	root   constraintNode
+}
+
+// constraintNode 约束表达式语法树节点
+type constraintNode interface {
+	eval(labels Labels) bool
+}
+
+type andNode struct{ left, right constraintNode }
+type orNode struct{ left, right constraintNode }
+type notNode struct{ operand constraintNode }
+
+type matchNode struct {
+	key    string
+	value  string
+	negate bool
+}
+
+type existsNode struct{ key string }
+
+func (n andNode) eval(labels Labels) bool { return n.left.eval(labels) && n.right.eval(labels) }
+func (n orNode) eval(labels Labels) bool  { return n.left.eval(labels) || n.right.eval(labels) }
+func (n notNode) eval(labels Labels) bool { return !n.operand.eval(labels) }
+
+func (n matchNode) eval(labels Labels) bool {
+	value, ok := labels[n.key]
+	if n.negate {
+		return !ok || value != n.value
+	}
+	return ok && value == n.value
+}
+
+func (n existsNode) eval(labels Labels) bool {
+	_, ok := labels[n.key]
+	return ok
+}
+
+// ParseConstraint 解析放置约束表达式，空表达式匹配所有节点
+func ParseConstraint(expr string) (*Constraint, error) {
+	c := &Constraint{source: expr}
+	if strings.TrimSpace(expr) == "" {
+		return c, nil
+	}
+
+	tokens, err := tokenizeConstraint(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &constraintParser{tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token %q in constraint: %s", p.tokens[p.pos], expr)
+	}
+
+	c.root = root
+	return c, nil
+}
+
+// Matches 判断标签是否满足约束
+func (c *Constraint) Matches(labels Labels) bool {
+	if c == nil || c.root == nil {
+		return true
+	}
+	return c.root.eval(labels)
+}
+
+// String 返回原始表达式
+func (c *Constraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.source
+}
+
+// tokenizeConstraint 将表达式拆分为词法单元
+func tokenizeConstraint(expr string) ([]string, error) {
+	var tokens []string
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+		case r == '&' || r == '|' || r == '=':
+			if i+1 < len(runes) && runes[i+1] == r {
+				tokens = append(tokens, string(runes[i:i+2]))
+				i += 2
+			} else if r == '=' {
+				tokens = append(tokens, "=")
+				i++
+			} else {
+				return nil, fmt.Errorf("unexpected character %q in constraint: %s", r, expr)
+			}
+		case r == '!':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, "!=")
+				i += 2
+			} else {
+				tokens = append(tokens, "!")
+				i++
+			}
+		case isLabelRune(r):
+			start := i
+			for i < len(runes) && isLabelRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			return nil, fmt.Errorf("unexpected character %q in constraint: %s", r, expr)
+		}
+	}
+
+	return tokens, nil
+}
+
+// isLabelRune 判断字符是否可以出现在标签键或值中
+func isLabelRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-./:", r)
+}
+
+// constraintParser 递归下降解析器
+type constraintParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *constraintParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *constraintParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *constraintParser) parseExpr() (constraintNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *constraintParser) parseTerm() (constraintNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *constraintParser) parseFactor() (constraintNode, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of constraint")
+	case token == "!":
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	case token == "(":
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis in constraint")
+		}
+		return node, nil
+	case !isLabelToken(token):
+		return nil, fmt.Errorf("expected label key, got %q", token)
+	}
+
+	key := token
+	switch op := p.peek(); op {
+	case "=", "==", "!=":
+		p.next()
+		value := p.next()
+		if !isLabelToken(value) {
+			return nil, fmt.Errorf("expected value for label %q, got %q", key, value)
+		}
+		return matchNode{key: key, value: value, negate: op == "!="}, nil
+	default:
+		return existsNode{key: key}, nil
+	}
+}
+
+// isLabelToken 判断词法单元是否为标签键或值
+func isLabelToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, r := range token {
+		if !isLabelRune(r) {
+			return false
+		}
+	}
+	return true
+}
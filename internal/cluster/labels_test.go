@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintMatches(t *testing.T) {
+	labels := Labels{"ssd": "true", "zone": "z1", "gpu-rack": "a"}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"", true},
+		{"ssd=true", true},
+		{"ssd==false", false},
+		{"ssd=true && zone!=z3", true},
+		{"ssd=true && zone!=z1", false},
+		{"zone=z3 || gpu-rack=a", true},
+		{"!(zone=z1)", false},
+		{"hdd", false},
+		{"gpu-rack && !hdd", true},
+		{"missing!=x", true},
+		{"(zone=z2 || zone=z1) && ssd=true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseConstraint(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c.Matches(labels))
+		})
+	}
+}
+
+func TestConstraintParseErrors(t *testing.T) {
+	for _, expr := range []string{"ssd=", "ssd & zone", "(ssd", "ssd=true &&", "zone=z1)", "a=#"} {
+		_, err := ParseConstraint(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	members := []Member{
+		{ID: "d1", State: StateAlive, Labels: Labels{"ssd": "true", "zone": "z1"}, Load: NodeLoad{Capacity: 100, Used: 80}},
+		{ID: "d2", State: StateAlive, Labels: Labels{"ssd": "true", "zone": "z3"}, Load: NodeLoad{Capacity: 100, Used: 10}},
+		{ID: "d3", State: StateAlive, Labels: Labels{"ssd": "true", "zone": "z2"}, Load: NodeLoad{Capacity: 100, Used: 20}},
+		{ID: "d4", State: StateDead, Labels: Labels{"ssd": "true", "zone": "z2"}},
+		{ID: "d5", State: StateAlive, Labels: Labels{"zone": "z1"}},
+	}
+
+	c, err := ParseConstraint("ssd=true && zone!=z3")
+	require.NoError(t, err)
+
+	selected, err := SelectNodes(members, c, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "d3", selected[0].ID)
+	assert.Equal(t, "d1", selected[1].ID)
+
+	_, err = SelectNodes(members, c, 3)
+	assert.Error(t, err)
+
+	_, err = SelectNodes(members, c, -1)
+	assert.Error(t, err)
+}
+
+func TestLocations(t *testing.T) {
+	// 成员地址来自各节点 gossip 的通告地址
+	selected := []Member{
+		{ID: "d2", Address: "[2001:db8::2]:9000"},
+		{ID: "d1", Address: "data-1.cpfs.svc:9000"},
+	}
+	assert.Equal(t, []string{"[2001:db8::2]:9000", "data-1.cpfs.svc:9000"}, Locations(selected))
+}
+
+func TestSelectPinnedNodes(t *testing.T) {
+	members := []Member{
+		{ID: "d1", State: StateAlive, Labels: Labels{MediaLabel: "nvme"}, Load: NodeLoad{Capacity: 100, Used: 50}},
+		{ID: "d2", State: StateAlive, Labels: Labels{MediaLabel: "hdd"}},
+		{ID: "d3", State: StateAlive, Labels: Labels{MediaLabel: "nvme"}, Load: NodeLoad{Capacity: 100, Used: 10}},
+		{ID: "d4", State: StateDead, Labels: Labels{MediaLabel: "nvme"}},
+	}
+
+	selected, err := SelectPinnedNodes(members, nil, "nvme", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d3", "d1"}, []string{selected[0].ID, selected[1].ID})
+
+	selected, err = SelectPinnedNodes(members, []string{"d1", "d2"}, "", 2)
+	require.NoError(t, err)
+	assert.Len(t, selected, 2)
+
+	_, err = SelectPinnedNodes(members, []string{"d1", "d2", "d4"}, "nvme", 2)
+	assert.Error(t, err, "only d1 is alive, pinned and on nvme")
+}
+
+// staticPlacement 按路径返回固定放置约束的解析器
+type staticPlacement map[string]string
+
+func (s staticPlacement) EffectivePlacement(ctx context.Context, p string) (string, error) {
+	expr, ok := s[p]
+	if !ok {
+		return "", fmt.Errorf("file not found: %s", p)
+	}
+	return expr, nil
+}
+
+func TestPlacer(t *testing.T) {
+	ctx := context.Background()
+	members := []Member{
+		{ID: "d1", State: StateAlive, Labels: Labels{"ssd": "true"}, Load: NodeLoad{Capacity: 100, Used: 50}},
+		{ID: "d2", State: StateAlive, Labels: Labels{"ssd": "true"}, Load: NodeLoad{Capacity: 100, Used: 10}},
+		{ID: "d3", State: StateAlive},
+	}
+	resolver := staticPlacement{"/fast/a": "ssd=true", "/plain/b": "", "/bad/c": "ssd=("}
+
+	placer := NewPlacer(resolver, nil)
+	selected, err := placer.Select(ctx, members, "/fast/a", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d2", "d1"}, memberIDs(selected))
+	_, err = placer.Select(ctx, members, "/fast/a", 3)
+	assert.Error(t, err)
+	selected, err = placer.Select(ctx, members, "/plain/b", 3)
+	require.NoError(t, err)
+	assert.Len(t, selected, 3)
+	_, err = placer.Select(ctx, members, "/bad/c", 1)
+	assert.Error(t, err)
+	_, err = placer.Select(ctx, members, "/missing", 1)
+	assert.Error(t, err)
+
+	// 设置了分配提示时在约束内按提示选择
+	hints := NewAllocationHints()
+	require.NoError(t, hints.SetHint([]string{"/fast/a"}, AllocationHint{Group: "g", Mode: HintSpread}))
+	placer = NewPlacer(resolver, hints)
+	selected, err = placer.Select(ctx, members, "/fast/a", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d2"}, memberIDs(selected))
+	selected, err = placer.Select(ctx, members, "/fast/a", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d1"}, memberIDs(selected))
+}
@@ -0,0 +1,435 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// NodeState 定义节点状态
+type NodeState int
+
+const (
+	StateAlive   NodeState = iota // 存活
+	StateSuspect                  // 疑似故障
+	StateDead                     // 已故障
+)
+
+// String 返回节点状态名称
+func (s NodeState) String() string {
+	switch s {
+	case StateAlive:
+		return "alive"
+	case StateSuspect:
+		return "suspect"
+	case StateDead:
+		return "dead"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// NodeLoad 节点负载信息
+type NodeLoad struct {
+	Capacity      int64 `json:"capacity"`       // 总容量
+	Used          int64 `json:"used"`           // 已用容量
+	ActiveStreams int   `json:"active_streams"` // 活跃数据流数量
+
+	Disks []DiskLoad `json:"disks,omitempty"` // 各磁盘的容量与健康状态
+}
+
+// DiskLoad 单块磁盘的容量与健康状态
+type DiskLoad struct {
+	Path     string `json:"path"`     // 挂载路径
+	Capacity int64  `json:"capacity"` // 总容量
+	Used     int64  `json:"used"`     // 已用容量
+	Healthy  bool   `json:"healthy"`  // 最近一次检查是否正常
+}
+
+// Member 集群成员信息
+type Member struct {
+	ID          string    `json:"id"`          // 节点ID
+	Address     string    `json:"address"`     // 节点地址
+	Role        string    `json:"role"`        // 节点角色：meta 或 data
+	State       NodeState `json:"state"`       // 节点状态
+	Incarnation uint64    `json:"incarnation"` // 化身号，由节点自身递增用于反驳怀疑
+	Heartbeat   uint64    `json:"heartbeat"`   // 心跳计数，节点每轮 gossip 递增，表明记录来自仍在运行的节点
+	Load        NodeLoad  `json:"load"`        // 负载信息
+	Labels      Labels    `json:"labels"`      // 节点标签
+	Clock       time.Time `json:"clock"`       // 节点发出摘要时的本地时钟，只对摘要发送方自身的记录有意义
+	LastSeen    time.Time `json:"-"`           // 本地最后一次收到更新的时间
+}
+
+// Transport 定义 gossip 消息传输接口，基于 gRPC 的实现为 network.GossipTransport
+type Transport interface {
+	// PushPull 将本地成员摘要发送给对端并返回对端的摘要
+	PushPull(ctx context.Context, address string, digest []Member) ([]Member, error)
+}
+
+// MembershipConfig gossip 成员管理配置
+type MembershipConfig struct {
+	// 本节点ID
+	NodeID string
+	// 本节点地址
+	Address string
+	// 本节点角色：RoleMeta 或 RoleData
+	Role string
+	// 本节点标签
+	Labels Labels
+	// 种子节点地址
+	Seeds []string
+	// 每轮 gossip 的对端数量
+	Fanout int
+	// gossip 间隔
+	GossipInterval time.Duration
+	// 超过该时间未收到更新则标记为疑似故障
+	SuspectTimeout time.Duration
+	// 疑似故障超过该时间则标记为故障
+	DeadTimeout time.Duration
+	// 已故障超过该时间则从成员表中移除，为 0 时取 DeadTimeout 的 10 倍；
+	// 移除前故障记录继续随 gossip 传播，使其他节点也能获知
+	ReapTimeout time.Duration
+	// 与其他节点的时钟偏差超过该值时告警，为 0 时使用 DefaultMaxClockSkew
+	MaxClockSkew time.Duration
+	// 日志，为 nil 时使用全局日志
+	Logger logger.Logger
+}
+
+// MembershipConfigFromServer 根据服务器配置创建成员管理配置，未启用 gossip 时 ok 为 false
+//
+// 本节点地址取对外通告地址，未配置时使用监听地址。
+// GossipInterval 与 MaxClockSkew 以毫秒配置；故障判定超时取默认值，即 gossip 间隔的倍数。
+func MembershipConfigFromServer(cfg *config.ServerConfig) (mc MembershipConfig, ok bool) {
+	if !cfg.GossipEnabled {
+		return MembershipConfig{}, false
+	}
+	return MembershipConfig{
+		NodeID:         cfg.ServerID,
+		Address:        cfg.AdvertisedAddress(),
+		Role:           cfg.ServerType,
+		Seeds:          cfg.GossipSeeds,
+		Fanout:         cfg.GossipFanout,
+		GossipInterval: time.Duration(cfg.GossipInterval) * time.Millisecond,
+		MaxClockSkew:   time.Duration(cfg.MaxClockSkew) * time.Millisecond,
+	}, true
+}
+
+// Membership 基于 gossip 的集群成员管理
+type Membership struct {
+	config    MembershipConfig
+	transport Transport
+	mu        sync.RWMutex
+	members   map[string]*Member
+	listeners []func(Member)
+	log       logger.Logger
+
+	// 拓扑版本，任何成员记录变化时递增并关闭 changed 通知等待者
+	version uint64
+	changed chan struct{}
+
+	// 通过 gossip 往返测得的各节点时钟偏差，以及偏差事件订阅者
+	skews         map[string]ClockSkew
+	skewListeners []func(ClockSkewEvent)
+	now           func() time.Time
+}
+
+// NewMembership 创建新的成员管理实例
+func NewMembership(config MembershipConfig, transport Transport) (*Membership, error) {
+	if config.NodeID == "" {
+		return nil, fmt.Errorf("node id is required")
+	}
+	if config.Fanout <= 0 {
+		config.Fanout = 3
+	}
+	if config.GossipInterval <= 0 {
+		config.GossipInterval = time.Second
+	}
+	if config.SuspectTimeout <= 0 {
+		config.SuspectTimeout = config.GossipInterval * 5
+	}
+	if config.DeadTimeout <= 0 {
+		config.DeadTimeout = config.SuspectTimeout * 2
+	}
+	if config.ReapTimeout <= 0 {
+		config.ReapTimeout = config.DeadTimeout * 10
+	}
+	if config.MaxClockSkew <= 0 {
+		config.MaxClockSkew = DefaultMaxClockSkew
+	}
+
+	m := &Membership{
+		config:    config,
+		transport: transport,
+		members:   make(map[string]*Member),
+		log:       logger.OrDefault(config.Logger),
+		version:   1,
+		changed:   make(chan struct{}),
+		skews:     make(map[string]ClockSkew),
+		now:       time.Now,
+	}
+
+	m.members[config.NodeID] = &Member{
+		ID:          config.NodeID,
+		Address:     config.Address,
+		Role:        config.Role,
+		State:       StateAlive,
+		Incarnation: 1,
+		Labels:      config.Labels,
+		LastSeen:    m.now(),
+	}
+
+	return m, nil
+}
+
+// OnChange 注册成员状态变化回调，供放置管理器等订阅
+func (m *Membership) OnChange(fn func(Member)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// UpdateLoad 更新本节点负载并随下一轮 gossip 传播
+func (m *Membership) UpdateLoad(load NodeLoad) {
+	m.mu.Lock()
+	self := m.members[m.config.NodeID]
+	self.Load = load
+	self.Incarnation++
+	self.LastSeen = m.now()
+	m.bump()
+	m.mu.Unlock()
+}
+
+// bump 递增拓扑版本并唤醒等待者，调用方需持有写锁
+func (m *Membership) bump() {
+	m.version++
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// Members 返回所有成员的快照
+func (m *Membership) Members() []Member {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		result = append(result, *member)
+	}
+	return result
+}
+
+// Get 获取指定成员
+func (m *Membership) Get(id string) (Member, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	member, ok := m.members[id]
+	if !ok {
+		return Member{}, false
+	}
+	return *member, true
+}
+
+// Merge 合并对端的成员摘要
+//
+// 化身号较大的记录获胜；化身号相同时状态更差的记录获胜。
+// 化身号与状态都相同、心跳计数更大的存活记录只刷新最后收到更新的时间，空闲的节点因此不会被误判为疑似故障。
+// 关于本节点的怀疑通过递增化身号进行反驳。
+// 未知节点的故障记录不会加入成员表，已移除的故障节点因此不会被其他节点的摘要重新带回。
+func (m *Membership) Merge(digest []Member) {
+	var changed []Member
+	updated := false
+
+	m.mu.Lock()
+	now := m.now()
+	for _, remote := range digest {
+		if remote.ID == m.config.NodeID {
+			self := m.members[remote.ID]
+			if remote.State != StateAlive && remote.Incarnation >= self.Incarnation {
+				self.Incarnation = remote.Incarnation + 1
+				updated = true
+			}
+			continue
+		}
+
+		local, exists := m.members[remote.ID]
+		if !exists {
+			if remote.State == StateDead {
+				continue
+			}
+			member := remote
+			member.Clock = time.Time{}
+			member.LastSeen = now
+			m.members[remote.ID] = &member
+			changed = append(changed, member)
+			updated = true
+			continue
+		}
+
+		if remote.Incarnation > local.Incarnation ||
+			(remote.Incarnation == local.Incarnation && remote.State > local.State) {
+			stateChanged := local.State != remote.State
+			local.Address = remote.Address
+			local.Role = remote.Role
+			local.State = remote.State
+			local.Incarnation = remote.Incarnation
+			local.Heartbeat = remote.Heartbeat
+			local.Load = remote.Load
+			local.Labels = remote.Labels
+			local.LastSeen = now
+			updated = true
+			if stateChanged {
+				changed = append(changed, *local)
+			}
+		} else if remote.Incarnation == local.Incarnation && remote.State == StateAlive &&
+			local.State == StateAlive && remote.Heartbeat > local.Heartbeat {
+			local.Heartbeat = remote.Heartbeat
+			local.LastSeen = now
+		}
+	}
+	if updated {
+		m.bump()
+	}
+	listeners := m.listeners
+	m.mu.Unlock()
+
+	m.notify(listeners, changed)
+}
+
+// Digest 返回用于 gossip 的本地成员摘要，本节点的记录带有当前时钟供对端测量偏差
+func (m *Membership) Digest() []Member {
+	members := m.Members()
+	now := m.now()
+	for i := range members {
+		if members[i].ID == m.config.NodeID {
+			members[i].Clock = now
+		}
+	}
+	return members
+}
+
+// CheckFailures 根据超时检测故障节点，并移除故障时间超过 ReapTimeout 的节点
+func (m *Membership) CheckFailures(now time.Time) {
+	var changed []Member
+	reaped := false
+
+	m.mu.Lock()
+	for id, member := range m.members {
+		if id == m.config.NodeID {
+			continue
+		}
+
+		elapsed := now.Sub(member.LastSeen)
+		switch {
+		case member.State == StateDead:
+			if elapsed > m.config.SuspectTimeout+m.config.DeadTimeout+m.config.ReapTimeout {
+				delete(m.members, id)
+				delete(m.skews, id)
+				reaped = true
+			}
+		case member.State == StateAlive && elapsed > m.config.SuspectTimeout:
+			member.State = StateSuspect
+			changed = append(changed, *member)
+		case member.State == StateSuspect && elapsed > m.config.SuspectTimeout+m.config.DeadTimeout:
+			member.State = StateDead
+			changed = append(changed, *member)
+		}
+	}
+	if len(changed) > 0 || reaped {
+		m.bump()
+	}
+	listeners := m.listeners
+	m.mu.Unlock()
+
+	m.notify(listeners, changed)
+}
+
+// Run 运行 gossip 循环直到 ctx 被取消
+func (m *Membership) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.GossipInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.gossipRound(ctx)
+			m.CheckFailures(m.now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// gossipRound 执行一轮 gossip，随机选择对端交换摘要
+func (m *Membership) gossipRound(ctx context.Context) {
+	if m.transport == nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.members[m.config.NodeID].Heartbeat++
+	m.mu.Unlock()
+
+	for _, address := range m.pickPeers() {
+		sent := m.now()
+		remote, err := m.transport.PushPull(ctx, address, m.Digest())
+		if err != nil {
+			m.log.Debug("Gossip exchange failed",
+				zap.String("peer", address),
+				zap.Error(err),
+			)
+			continue
+		}
+		m.measureSkew(address, sent, m.now(), remote)
+		m.Merge(remote)
+	}
+}
+
+// pickPeers 随机选择本轮 gossip 的对端地址
+func (m *Membership) pickPeers() []string {
+	m.mu.RLock()
+	var candidates []string
+	for id, member := range m.members {
+		if id != m.config.NodeID && member.State != StateDead && member.Address != "" {
+			candidates = append(candidates, member.Address)
+		}
+	}
+	m.mu.RUnlock()
+
+	// 尚未发现其他节点时使用种子节点
+	if len(candidates) == 0 {
+		for _, seed := range m.config.Seeds {
+			if seed != m.config.Address {
+				candidates = append(candidates, seed)
+			}
+		}
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if len(candidates) > m.config.Fanout {
+		candidates = candidates[:m.config.Fanout]
+	}
+	return candidates
+}
+
+// notify 通知成员状态变化
+func (m *Membership) notify(listeners []func(Member), changed []Member) {
+	for _, member := range changed {
+		m.log.Info("Cluster member state changed",
+			zap.String("node", member.ID),
+			zap.String("state", member.State.String()),
+		)
+		for _, fn := range listeners {
+			fn(member)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localTransport 在进程内直接交换摘要的测试传输层
+type localTransport struct {
+	nodes map[string]*Membership
+}
+
+func (t *localTransport) PushPull(ctx context.Context, address string, digest []Member) ([]Member, error) {
+	peer := t.nodes[address]
+	peer.Merge(digest)
+	return peer.Digest(), nil
+}
+
+func newTestMembership(t *testing.T, id, address string, transport Transport, seeds ...string) *Membership {
+	m, err := NewMembership(MembershipConfig{
+		NodeID:         id,
+		Address:        address,
+		Seeds:          seeds,
+		GossipInterval: time.Millisecond * 10,
+		SuspectTimeout: time.Millisecond * 50,
+		DeadTimeout:    time.Millisecond * 50,
+	}, transport)
+	require.NoError(t, err)
+	return m
+}
+
+func TestMembershipGossip(t *testing.T) {
+	transport := &localTransport{nodes: make(map[string]*Membership)}
+	a := newTestMembership(t, "data-1", "a:1", transport, "b:1")
+	b := newTestMembership(t, "data-2", "b:1", transport, "c:1")
+	c := newTestMembership(t, "data-3", "c:1", transport)
+	transport.nodes["a:1"] = a
+	transport.nodes["b:1"] = b
+	transport.nodes["c:1"] = c
+
+	b.gossipRound(context.Background())
+	a.gossipRound(context.Background())
+
+	// a 通过 b 间接获知 c
+	assert.Len(t, a.Members(), 3)
+	member, ok := a.Get("data-3")
+	require.True(t, ok)
+	assert.Equal(t, StateAlive, member.State)
+
+	// 负载信息随 gossip 传播
+	c.UpdateLoad(NodeLoad{Capacity: 100, Used: 40})
+	a.Merge(c.Digest())
+	member, _ = a.Get("data-3")
+	assert.Equal(t, int64(40), member.Load.Used)
+}
+
+func TestMembershipFailureDetection(t *testing.T) {
+	m := newTestMembership(t, "data-1", "a:1", nil)
+	m.Merge([]Member{{ID: "data-2", Address: "b:1", State: StateAlive, Incarnation: 1}})
+
+	var events []Member
+	m.OnChange(func(member Member) {
+		events = append(events, member)
+	})
+
+	now := time.Now()
+	m.CheckFailures(now.Add(time.Millisecond * 60))
+	member, _ := m.Get("data-2")
+	assert.Equal(t, StateSuspect, member.State)
+
+	m.CheckFailures(now.Add(time.Millisecond * 200))
+	member, _ = m.Get("data-2")
+	assert.Equal(t, StateDead, member.State)
+	assert.Len(t, events, 2)
+
+	// 更高的化身号使节点恢复存活
+	m.Merge([]Member{{ID: "data-2", Address: "b:1", State: StateAlive, Incarnation: 2}})
+	member, _ = m.Get("data-2")
+	assert.Equal(t, StateAlive, member.State)
+}
+
+func TestMembershipReapDead(t *testing.T) {
+	m := newTestMembership(t, "data-1", "a:1", nil)
+	clock := time.Now()
+	m.now = func() time.Time { return clock }
+	m.Merge([]Member{{ID: "data-2", Address: "b:1", State: StateAlive, Incarnation: 1}})
+
+	m.CheckFailures(clock.Add(time.Millisecond * 60))
+	m.CheckFailures(clock.Add(time.Millisecond * 200))
+	member, _ := m.Get("data-2")
+	assert.Equal(t, StateDead, member.State)
+
+	// 故障记录在 ReapTimeout 内保留，随后移除并递增拓扑版本
+	m.CheckFailures(clock.Add(time.Millisecond * 500))
+	_, ok := m.Get("data-2")
+	assert.True(t, ok)
+
+	version := m.version
+	m.CheckFailures(clock.Add(time.Millisecond*100 + m.config.ReapTimeout + time.Millisecond))
+	_, ok = m.Get("data-2")
+	assert.False(t, ok)
+	assert.Greater(t, m.version, version)
+
+	// 其他节点仍持有的故障记录不会把已移除的节点带回
+	m.Merge([]Member{{ID: "data-2", Address: "b:1", State: StateDead, Incarnation: 1}})
+	_, ok = m.Get("data-2")
+	assert.False(t, ok)
+
+	// 节点重新加入时以存活记录出现
+	m.Merge([]Member{{ID: "data-2", Address: "b:1", State: StateAlive, Incarnation: 2}})
+	member, ok = m.Get("data-2")
+	require.True(t, ok)
+	assert.Equal(t, StateAlive, member.State)
+}
+
+func TestMembershipUpdateLoadUsesClock(t *testing.T) {
+	m := newTestMembership(t, "data-1", "a:1", nil)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return clock }
+
+	m.UpdateLoad(NodeLoad{Capacity: 100, Used: 10})
+	self, _ := m.Get("data-1")
+	assert.Equal(t, clock, self.LastSeen)
+	assert.Equal(t, int64(10), self.Load.Used)
+}
+
+func TestMembershipRefuteSuspicion(t *testing.T) {
+	m := newTestMembership(t, "data-1", "a:1", nil)
+	m.Merge([]Member{{ID: "data-1", State: StateSuspect, Incarnation: 1}})
+
+	self, _ := m.Get("data-1")
+	assert.Equal(t, StateAlive, self.State)
+	assert.Equal(t, uint64(2), self.Incarnation)
+	// 反驳改变了本节点的记录，拓扑版本随之递增
+	assert.Equal(t, uint64(2), m.version)
+}
+
+func TestMembershipIdleNodeStaysAlive(t *testing.T) {
+	transport := &localTransport{nodes: make(map[string]*Membership)}
+	a := newTestMembership(t, "data-1", "a:1", transport, "b:1")
+	b := newTestMembership(t, "data-2", "b:1", transport, "a:1")
+	transport.nodes["a:1"] = a
+	transport.nodes["b:1"] = b
+	clock := time.Now()
+	a.now = func() time.Time { return clock }
+	b.now = func() time.Time { return clock }
+
+	// 两个节点都没有负载变化，跨越多个疑似超时后仍通过心跳保持存活
+	for round := 0; round < 50; round++ {
+		clock = clock.Add(a.config.GossipInterval)
+		a.gossipRound(context.Background())
+		b.gossipRound(context.Background())
+		a.CheckFailures(clock)
+		b.CheckFailures(clock)
+	}
+	member, ok := a.Get("data-2")
+	require.True(t, ok)
+	assert.Equal(t, StateAlive, member.State)
+	assert.Equal(t, uint64(1), member.Incarnation)
+	member, _ = b.Get("data-1")
+	assert.Equal(t, StateAlive, member.State)
+
+	// 停止 gossip 的节点在超时后被怀疑
+	for round := 0; round < 10; round++ {
+		clock = clock.Add(a.config.GossipInterval)
+		a.CheckFailures(clock)
+	}
+	member, _ = a.Get("data-2")
+	assert.Equal(t, StateSuspect, member.State)
+}
+
+func TestMembershipConfigFromServer(t *testing.T) {
+	_, ok := MembershipConfigFromServer(&config.ServerConfig{ServerID: "data-1"})
+	assert.False(t, ok)
+
+	mc, ok := MembershipConfigFromServer(&config.ServerConfig{
+		ServerID:       "data-1",
+		ServerType:     RoleData,
+		ListenAddress:  "10.0.0.1:9000",
+		GossipEnabled:  true,
+		GossipSeeds:    []string{"10.0.0.2:9000"},
+		GossipFanout:   2,
+		GossipInterval: 200,
+		MaxClockSkew:   100,
+	})
+	require.True(t, ok)
+	assert.Equal(t, MembershipConfig{
+		NodeID:         "data-1",
+		Address:        "10.0.0.1:9000",
+		Role:           RoleData,
+		Seeds:          []string{"10.0.0.2:9000"},
+		Fanout:         2,
+		GossipInterval: 200 * time.Millisecond,
+		MaxClockSkew:   100 * time.Millisecond,
+	}, mc)
+
+	// 配置了通告地址时 gossip 通告该地址而非通配监听地址
+	mc, ok = MembershipConfigFromServer(&config.ServerConfig{
+		ServerID:         "data-1",
+		ListenAddress:    "[::]:9000",
+		AdvertiseAddress: "data-1.cpfs.svc",
+		GossipEnabled:    true,
+	})
+	require.True(t, ok)
+	assert.Equal(t, "data-1.cpfs.svc:9000", mc.Address)
+}
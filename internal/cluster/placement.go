@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// SelectNodes 按放置约束从存活节点中选择 count 个节点
+//
+// 满足约束的节点按容量使用率从低到高排序，使用率相同时按节点ID排序。
+func SelectNodes(members []Member, constraint *Constraint, count int) ([]Member, error) {
+	candidates, err := eligibleNodes(members, constraint, count)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return lessLoaded(candidates[i], candidates[j])
+	})
+	return candidates[:count], nil
+}
+
+// PlacementResolver 返回路径生效的放置约束表达式，meta.MemoryStore 满足该接口
+type PlacementResolver interface {
+	EffectivePlacement(ctx context.Context, p string) (string, error)
+}
+
+// Placer 按文件所在目录的放置约束为其数据块选择节点
+type Placer struct {
+	resolver PlacementResolver
+	hints    *AllocationHints
+}
+
+// NewPlacer 创建放置器，hints 为 nil 时不使用分配提示
+func NewPlacer(resolver PlacementResolver, hints *AllocationHints) *Placer {
+	return &Placer{resolver: resolver, hints: hints}
+}
+
+// Select 为文件 p 的一个数据块选择 count 个节点
+//
+// 约束取文件生效的放置约束（最近一个设置了放置约束的祖先目录），没有约束时匹配所有节点。
+func (pl *Placer) Select(ctx context.Context, members []Member, p string, count int) ([]Member, error) {
+	expr, err := pl.resolver.EffectivePlacement(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve placement of %s: %v", p, err)
+	}
+	constraint, err := ParseConstraint(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid placement constraint for %s: %v", p, err)
+	}
+	if pl.hints != nil {
+		return pl.hints.SelectNodes(members, constraint, count, p)
+	}
+	return SelectNodes(members, constraint, count)
+}
+
+// Locations 返回所选节点的通告地址，按选择顺序写入 Block.Locations
+func Locations(selected []Member) []string {
+	locations := make([]string, len(selected))
+	for i, member := range selected {
+		locations[i] = member.Address
+	}
+	return locations
+}
+
+// eligibleNodes 返回满足放置约束的存活节点，count 为负数或节点数量不足 count 时返回错误
+func eligibleNodes(members []Member, constraint *Constraint, count int) ([]Member, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("invalid node count: %d", count)
+	}
+
+	var candidates []Member
+	for _, member := range members {
+		if member.State != StateAlive {
+			continue
+		}
+		if !constraint.Matches(member.Labels) {
+			continue
+		}
+		candidates = append(candidates, member)
+	}
+
+	if len(candidates) < count {
+		return nil, fmt.Errorf("not enough nodes match constraint %q: need %d, have %d",
+			constraint.String(), count, len(candidates))
+	}
+	return candidates, nil
+}
+
+// lessLoaded 按容量使用率比较节点，使用率相同时按节点ID比较
+func lessLoaded(a, b Member) bool {
+	ua, ub := utilization(a.Load), utilization(b.Load)
+	if ua != ub {
+		return ua < ub
+	}
+	return a.ID < b.ID
+}
+
+// utilization 计算节点容量使用率
+func utilization(load NodeLoad) float64 {
+	if load.Capacity <= 0 {
+		return 0
+	}
+	return float64(load.Used) / float64(load.Capacity)
+}
+
+// MediaLabel 节点存储介质的标签键，例如 media=nvme
+const MediaLabel = "media"
+
+// SelectPinnedNodes 为被固定的文件选择 count 个节点
+//
+// nodes 非空时只考虑这些节点，media 非空时要求节点的 MediaLabel 标签等于该值，其余规则与 SelectNodes 相同。
+func SelectPinnedNodes(members []Member, nodes []string, media string, count int) ([]Member, error) {
+	candidates := members
+	if len(nodes) > 0 {
+		allowed := make(map[string]bool, len(nodes))
+		for _, id := range nodes {
+			allowed[id] = true
+		}
+		candidates = nil
+		for _, member := range members {
+			if allowed[member.ID] {
+				candidates = append(candidates, member)
+			}
+		}
+	}
+
+	constraint := &Constraint{}
+	if media != "" {
+		constraint = &Constraint{
+			source: MediaLabel + "=" + media,
+			root:   matchNode{key: MediaLabel, value: media},
+		}
+	}
+	return SelectNodes(candidates, constraint, count)
+}
@@ -0,0 +1,63 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+)
+
+const (
+	// RoleMeta 元数据服务器
+	RoleMeta = "meta"
+	// RoleData 数据服务器
+	RoleData = "data"
+)
+
+// Topology 带版本号的集群视图
+//
+// 版本号在任何成员的地址、状态、标签或负载变化时递增，消费者据此判断视图是否过期；
+// 成员状态即健康状况，负载中包含容量与各磁盘信息。
+type Topology struct {
+	Version     uint64   `json:"version"`
+	MetaServers []Member `json:"meta_servers"`
+	DataServers []Member `json:"data_servers"` // 未声明角色的节点也视为数据服务器
+}
+
+// Topology 返回当前集群视图，以及在视图下次变化时关闭的通道
+func (m *Membership) Topology() (Topology, <-chan struct{}) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	topo := Topology{Version: m.version, MetaServers: []Member{}, DataServers: []Member{}}
+	for _, member := range m.members {
+		if member.Role == RoleMeta {
+			topo.MetaServers = append(topo.MetaServers, *member)
+		} else {
+			topo.DataServers = append(topo.DataServers, *member)
+		}
+	}
+	sortMembers(topo.MetaServers)
+	sortMembers(topo.DataServers)
+	return topo, m.changed
+}
+
+// WaitTopology 阻塞直到视图版本大于 version，返回新的视图
+func (m *Membership) WaitTopology(ctx context.Context, version uint64) (Topology, error) {
+	for {
+		topo, changed := m.Topology()
+		if topo.Version > version {
+			return topo, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Topology{}, ctx.Err()
+		}
+	}
+}
+
+// sortMembers 按节点ID排序，使视图输出稳定
+func sortMembers(members []Member) {
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+}
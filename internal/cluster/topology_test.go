@@ -0,0 +1,65 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyVersioning(t *testing.T) {
+	m, err := NewMembership(MembershipConfig{NodeID: "meta-1", Address: "10.0.0.1:7000", Role: RoleMeta}, nil)
+	require.NoError(t, err)
+
+	topo, changed := m.Topology()
+	assert.Equal(t, uint64(1), topo.Version)
+	require.Len(t, topo.MetaServers, 1)
+	assert.Empty(t, topo.DataServers)
+
+	m.Merge([]Member{
+		{ID: "data-2", Address: "10.0.0.3:7000", Role: RoleData, State: StateAlive, Incarnation: 1},
+		{ID: "data-1", Address: "10.0.0.2:7000", Role: RoleData, State: StateAlive, Incarnation: 1,
+			Load: NodeLoad{Capacity: 100, Disks: []DiskLoad{{Path: "/disk0", Capacity: 100, Healthy: true}}}},
+	})
+	select {
+	case <-changed:
+	default:
+		t.Fatal("change channel not closed")
+	}
+
+	topo, _ = m.Topology()
+	assert.Equal(t, uint64(2), topo.Version)
+	require.Len(t, topo.DataServers, 2)
+	assert.Equal(t, "data-1", topo.DataServers[0].ID)
+	assert.Equal(t, "/disk0", topo.DataServers[0].Load.Disks[0].Path)
+
+	// 旧的摘要不改变视图
+	m.Merge([]Member{{ID: "data-1", Role: RoleData, State: StateAlive, Incarnation: 1}})
+	topo, _ = m.Topology()
+	assert.Equal(t, uint64(2), topo.Version)
+
+	m.UpdateLoad(NodeLoad{Capacity: 10})
+	topo, _ = m.Topology()
+	assert.Equal(t, uint64(3), topo.Version)
+}
+
+func TestWaitTopology(t *testing.T) {
+	m, err := NewMembership(MembershipConfig{NodeID: "meta-1", Role: RoleMeta}, nil)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.Merge([]Member{{ID: "data-1", State: StateAlive, Incarnation: 1}})
+	}()
+	topo, err := m.WaitTopology(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), topo.Version)
+	require.Len(t, topo.DataServers, 1, "members without a role are data servers")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = m.WaitTopology(ctx, topo.Version)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
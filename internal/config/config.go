@@ -1,6 +1,9 @@
 package config
 
 import (
+	"net"
+	"strings"
+
 	"github.com/spf13/viper"
 )
 
@@ -12,6 +15,12 @@ type ServerConfig struct {
 	ListenAddress string `mapstructure:"listen_address"`
 	DataDir       string `mapstructure:"data_dir"`
 
+	// 对外通告地址，写入成员信息与 Block.Locations，为空时使用监听地址
+	AdvertiseAddress string `mapstructure:"advertise_address"`
+
+	// 节点标签，用于放置约束匹配，例如 ssd: "true"
+	NodeLabels map[string]string `mapstructure:"node_labels"`
+
 	// 元数据服务器配置
 	MetaServers []string `mapstructure:"meta_servers"`
 
@@ -26,9 +35,137 @@ type ServerConfig struct {
 	HeartbeatInterval int `mapstructure:"heartbeat_interval"`
 	FailureTimeout    int `mapstructure:"failure_timeout"`
 
+	// Gossip 成员管理配置（可选，替代中心化注册）
+	GossipEnabled  bool     `mapstructure:"gossip_enabled"`
+	GossipSeeds    []string `mapstructure:"gossip_seeds"`
+	GossipFanout   int      `mapstructure:"gossip_fanout"`
+	GossipInterval int      `mapstructure:"gossip_interval"` // 毫秒
+
+	// 与其他节点时钟偏差的告警阈值（毫秒），偏差随 gossip 往返测量，为 0 时使用默认值
+	MaxClockSkew int `mapstructure:"max_clock_skew"`
+	// 为变更日志事件和元数据版本分配混合逻辑时钟（HLC）时间戳，跨节点按其而非墙钟排序
+	HLCJournal bool `mapstructure:"hlc_journal"`
+
+	// 变更日志保留策略：超过最长保留时间（秒）或事件数上限的事件被压缩，为 0 时不按该条件压缩；
+	// 恢复窗口（秒）内的事件、消费组尚未确认的事件和最早的快照之后的事件总是保留
+	ChangelogMaxAge        int `mapstructure:"changelog_max_age"`
+	ChangelogMaxEvents     int `mapstructure:"changelog_max_events"`
+	ChangelogRestoreWindow int `mapstructure:"changelog_restore_window"`
+
+	// ACME 自动证书配置
+	ACMEHosts        []string `mapstructure:"acme_hosts"`
+	ACMEEmail        string   `mapstructure:"acme_email"`
+	ACMEDirectoryURL string   `mapstructure:"acme_directory_url"`
+
+	// OIDC 认证配置
+	OIDCIssuer   string `mapstructure:"oidc_issuer"`
+	OIDCClientID string `mapstructure:"oidc_client_id"`
+
+	// 慢客户端检测：单次发送最长阻塞时间（毫秒）与窗口内最大阻塞比例，为 0 时不检查
+	SlowClientMaxStall        int     `mapstructure:"slow_client_max_stall"`
+	SlowClientMaxBlockedRatio float64 `mapstructure:"slow_client_max_blocked_ratio"`
+
+	// 数据服务器按客户端限制读写流带宽（字节/秒），为 0 时不限制；
+	// ClientBandwidthOverrides 按用户名覆盖默认上限
+	ClientBandwidthLimit     int64            `mapstructure:"client_bandwidth_limit"`
+	ClientBandwidthBurst     int64            `mapstructure:"client_bandwidth_burst"`
+	ClientBandwidthOverrides map[string]int64 `mapstructure:"client_bandwidth_overrides"`
+
+	// 导出配置，为空时不限制挂载路径
+	Exports []ExportConfig `mapstructure:"exports"`
+
+	// 单个客户端会话的资源上限，为 0 表示不限制
+	SessionMaxOpenFiles int `mapstructure:"session_max_open_files"`
+	SessionMaxLocks     int `mapstructure:"session_max_locks"`
+	SessionMaxWatches   int `mapstructure:"session_max_watches"`
+	SessionMaxInflight  int `mapstructure:"session_max_inflight"`
+
 	// 缓存配置
 	CacheSize int64 `mapstructure:"cache_size"`
 	CacheTTL  int   `mapstructure:"cache_ttl"`
+
+	// 各子系统缓存共享的内存预算（字节），为 0 时不限制
+	MemoryLimit int64 `mapstructure:"memory_limit"`
+
+	// 维护窗口：窗口内巡检、重平衡、GC 等后台作业全速运行，窗口外按
+	// MaintenanceOutsideFactor 降速，为 0 时暂停；未配置窗口时不限制
+	MaintenanceWindows       []MaintenanceWindowConfig `mapstructure:"maintenance_windows"`
+	MaintenanceOutsideFactor float64                   `mapstructure:"maintenance_outside_factor"`
+
+	// 服务等级目标，服务端据此计算错误预算消耗速率并导出为指标
+	SLOs []SLOConfig `mapstructure:"slos"`
+
+	// 按目录配置的定时快照策略
+	SnapshotPolicies []SnapshotPolicyConfig `mapstructure:"snapshot_policies"`
+
+	// 准入策略：PolicyDir 下的 WebAssembly 模块在创建、删除、重命名等操作前求值；
+	// 单次求值超时（毫秒），为 0 时使用默认值；PolicyFailOpen 为 true 时模块出错放行
+	PolicyDir      string `mapstructure:"policy_dir"`
+	PolicyTimeout  int    `mapstructure:"policy_timeout"`
+	PolicyFailOpen bool   `mapstructure:"policy_fail_open"`
+
+	// 变更事件投递目标，按路径前缀和事件类型过滤后至少一次地发布
+	EventSinks []EventSinkConfig `mapstructure:"event_sinks"`
+}
+
+// EventSinkConfig 定义一个变更事件投递目标
+type EventSinkConfig struct {
+	Name     string   `mapstructure:"name"`
+	Type     string   `mapstructure:"type"`     // webhook、nats 或 kafka
+	URL      string   `mapstructure:"url"`      // Webhook 地址或 NATS 服务器地址 host:port
+	Subject  string   `mapstructure:"subject"`  // NATS 主题或 Kafka 主题
+	Prefixes []string `mapstructure:"prefixes"` // 只投递这些路径前缀下的事件，为空表示全部
+	Types    []string `mapstructure:"types"`    // 只投递这些类型的事件，例如 create、delete，为空表示全部
+}
+
+// SnapshotPolicyConfig 定义一个目录的定时快照策略
+type SnapshotPolicyConfig struct {
+	Path     string `mapstructure:"path"`
+	Interval string `mapstructure:"interval"` // 快照间隔：hourly、daily、weekly 或 Go 时长如 "30m"
+	Keep     int    `mapstructure:"keep"`     // 保留的最近快照数量，为 0 时不自动删除
+}
+
+// SLOConfig 定义一个服务等级目标
+type SLOConfig struct {
+	Name             string   `mapstructure:"name"`
+	Methods          []string `mapstructure:"methods"`           // 计入的 gRPC 方法名后缀，为空表示全部方法
+	Objective        float64  `mapstructure:"objective"`         // 达标请求的目标比例，例如 0.999
+	LatencyThreshold float64  `mapstructure:"latency_threshold"` // 延迟阈值（毫秒），超过即不达标；为 0 时按请求是否出错判断可用性
+	WindowDays       int      `mapstructure:"window_days"`       // 错误预算的统计周期（天），为 0 时为 30 天
+}
+
+// MaintenanceWindowConfig 定义一个按周重复的维护窗口
+type MaintenanceWindowConfig struct {
+	Days     string `mapstructure:"days"`     // 星期，例如 "mon-fri"、"sat,sun"，为空或 "*" 表示每天
+	Start    string `mapstructure:"start"`    // 开始时间 HH:MM
+	End      string `mapstructure:"end"`      // 结束时间 HH:MM，不晚于开始时间时表示跨越午夜
+	Timezone string `mapstructure:"timezone"` // IANA 时区名，为空时使用 UTC
+}
+
+// ExportConfig 定义一个导出子树
+type ExportConfig struct {
+	Path     string   `mapstructure:"path"`
+	Users    []string `mapstructure:"users"`
+	Groups   []string `mapstructure:"groups"`
+	ReadOnly bool     `mapstructure:"read_only"`
+}
+
+// AdvertisedAddress 返回对外通告地址，未配置 AdvertiseAddress 时使用监听地址
+//
+// 通告地址未包含端口时沿用监听地址的端口，IPv6 字面量可写作 [::1] 或 ::1。
+func (c *ServerConfig) AdvertisedAddress() string {
+	if c.AdvertiseAddress == "" {
+		return c.ListenAddress
+	}
+	if _, _, err := net.SplitHostPort(c.AdvertiseAddress); err == nil {
+		return c.AdvertiseAddress
+	}
+	_, port, err := net.SplitHostPort(c.ListenAddress)
+	if err != nil {
+		return c.AdvertiseAddress
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(c.AdvertiseAddress, "["), "]")
+	return net.JoinHostPort(host, port)
 }
 
 // LoadConfig 加载配置文件
@@ -16,3 +16,17 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, "meta-1", config.ServerID)
 	assert.Equal(t, "meta", config.ServerType)
 }
+
+func TestAdvertisedAddress(t *testing.T) {
+	config := &ServerConfig{ListenAddress: "[::]:9000"}
+	assert.Equal(t, "[::]:9000", config.AdvertisedAddress())
+
+	config.AdvertiseAddress = "node-1.example.com:19000"
+	assert.Equal(t, "node-1.example.com:19000", config.AdvertisedAddress())
+
+	// 未包含端口时沿用监听端口
+	config.AdvertiseAddress = "10.0.0.5"
+	assert.Equal(t, "10.0.0.5:9000", config.AdvertisedAddress())
+	config.AdvertiseAddress = "[2001:db8::5]"
+	assert.Equal(t, "[2001:db8::5]:9000", config.AdvertisedAddress())
+}
@@ -0,0 +1,106 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// KeySize 数据密钥和主密钥长度（AES-256）
+const KeySize = 32
+
+// KeyProvider 定义主密钥/KMS 接口，用于包装和解包区域数据密钥
+type KeyProvider interface {
+	// Wrap 使用主密钥加密数据密钥
+	Wrap(dataKey []byte) ([]byte, error)
+	// Unwrap 使用主密钥解密数据密钥
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// LocalKeyProvider 使用本地主密钥的密钥提供者
+type LocalKeyProvider struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKeyProvider 创建本地主密钥提供者
+func NewLocalKeyProvider(masterKey []byte) (*LocalKeyProvider, error) {
+	if len(masterKey) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(masterKey))
+	}
+
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKeyProvider{aead: aead}, nil
+}
+
+// Wrap 实现 KeyProvider
+func (p *LocalKeyProvider) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(p.aead, dataKey, nil)
+}
+
+// Unwrap 实现 KeyProvider
+func (p *LocalKeyProvider) Unwrap(wrapped []byte) ([]byte, error) {
+	return open(p.aead, wrapped, nil)
+}
+
+// GenerateKey 生成随机密钥
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return key, nil
+}
+
+// EncryptBlock 使用区域数据密钥加密数据块，块ID作为附加数据防止块被替换
+func EncryptBlock(dataKey []byte, blockID string, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, plaintext, []byte(blockID))
+}
+
+// DecryptBlock 使用区域数据密钥解密数据块
+func DecryptBlock(dataKey []byte, blockID string, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, ciphertext, []byte(blockID))
+}
+
+// newAEAD 创建 AES-GCM 实例
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal 加密数据，输出格式为 nonce || ciphertext
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open 解密 seal 输出的数据
+func open(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %v", err)
+	}
+	return plaintext, nil
+}
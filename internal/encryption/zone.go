@@ -0,0 +1,295 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+
+	"go.uber.org/zap"
+)
+
+// zoneKeyPrefix 区域信息在存储中的键前缀
+const zoneKeyPrefix = "/encryption/zones/"
+
+// zoneKeyWipePasses 销毁区域时覆写包装密钥的次数
+const zoneKeyWipePasses = 3
+
+// ErrZoneDestroyed 路径所在加密区域的数据密钥已被销毁，可用 errors.Is 判断
+var ErrZoneDestroyed = errors.New("encryption zone destroyed")
+
+// secureDeleter 支持覆写后删除的存储，例如 meta.FileStorage
+type secureDeleter interface {
+	SecureDelete(ctx context.Context, key string, passes int) error
+}
+
+// Zone 加密区域
+//
+// 区域被销毁后保留不含密钥的记录，区域内的路径不会被误当作未加密路径。
+type Zone struct {
+	Path        string    `json:"path"`                   // 区域根目录
+	WrappedKey  []byte    `json:"wrapped_key,omitempty"`  // 主密钥包装后的数据密钥
+	CreateTime  time.Time `json:"create_time"`            // 创建时间
+	Destroyed   bool      `json:"destroyed,omitempty"`    // 数据密钥是否已销毁
+	DestroyTime time.Time `json:"destroy_time,omitempty"` // 销毁时间
+}
+
+// Namespace 创建区域时检查根目录的元数据命名空间，meta.MemoryStore 满足该接口
+type Namespace interface {
+	Get(ctx context.Context, p string) (*meta.Metadata, error)
+	List(ctx context.Context, p string) ([]*meta.Metadata, error)
+}
+
+// ZoneManager 管理加密区域及其数据密钥
+type ZoneManager struct {
+	storage   meta.Storage
+	provider  KeyProvider
+	namespace Namespace
+	mu        sync.RWMutex
+	zones     map[string]*Zone
+	log       logger.Logger
+}
+
+// NewZoneManager 创建加密区域管理器并从存储加载已有区域，namespace 用于检查新区域的根目录
+func NewZoneManager(ctx context.Context, storage meta.Storage, provider KeyProvider, namespace Namespace) (*ZoneManager, error) {
+	m := &ZoneManager{
+		storage:   storage,
+		provider:  provider,
+		namespace: namespace,
+		zones:     make(map[string]*Zone),
+		log:       logger.Default(),
+	}
+
+	keys, err := storage.List(ctx, zoneKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list zones: %v", err)
+	}
+	for _, key := range keys {
+		data, err := storage.Load(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load zone %s: %v", key, err)
+		}
+		zone := &Zone{}
+		if err := json.Unmarshal(data, zone); err != nil {
+			return nil, fmt.Errorf("failed to decode zone %s: %v", key, err)
+		}
+		m.zones[zone.Path] = zone
+	}
+
+	return m, nil
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (m *ZoneManager) SetLogger(l logger.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.log = logger.OrDefault(l)
+}
+
+// zoneKey 返回区域在存储中的键
+func zoneKey(zonePath string) string {
+	return zoneKeyPrefix + base64.RawURLEncoding.EncodeToString([]byte(zonePath))
+}
+
+// cleanZonePath 规范化区域路径
+func cleanZonePath(p string) string {
+	return path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+}
+
+// isWithin 判断 p 是否位于目录 dir 之下（含 dir 本身）
+func isWithin(p, dir string) bool {
+	return dir == "/" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// CreateZone 将目录标记为加密区域并生成独立的数据密钥，不允许嵌套区域
+//
+// 与 HDFS 的加密区域相同，根目录必须已经存在且为空：已有文件的数据块是明文，不能事后纳入区域。
+func (m *ZoneManager) CreateZone(ctx context.Context, zonePath string) (*Zone, error) {
+	zonePath = cleanZonePath(zonePath)
+	// 命名空间的写入在持有元数据锁时查询区域，检查须在加锁之前完成
+	if err := m.checkEmptyDir(ctx, zonePath); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for existing := range m.zones {
+		if isWithin(zonePath, existing) || isWithin(existing, zonePath) {
+			return nil, fmt.Errorf("encryption zone overlaps existing zone: %s", existing)
+		}
+	}
+
+	dataKey, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := m.provider.Wrap(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap zone key: %v", err)
+	}
+
+	zone := &Zone{
+		Path:       zonePath,
+		WrappedKey: wrapped,
+		CreateTime: time.Now(),
+	}
+	data, err := json.Marshal(zone)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.storage.Save(ctx, zoneKey(zonePath), data); err != nil {
+		return nil, fmt.Errorf("failed to save zone: %v", err)
+	}
+	if err := m.storage.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to persist zone: %v", err)
+	}
+
+	m.zones[zonePath] = zone
+	m.log.Info("Created encryption zone",
+		zap.String("path", zonePath),
+	)
+	return zone, nil
+}
+
+// checkEmptyDir 检查区域根目录存在、是目录且为空
+func (m *ZoneManager) checkEmptyDir(ctx context.Context, zonePath string) error {
+	dir, err := m.namespace.Get(ctx, zonePath)
+	if err != nil {
+		return fmt.Errorf("encryption zone root %s: %v", zonePath, err)
+	}
+	if dir.Type != meta.TypeDirectory {
+		return fmt.Errorf("encryption zone root is not a directory: %s", zonePath)
+	}
+	entries, err := m.namespace.List(ctx, zonePath)
+	if err != nil {
+		return fmt.Errorf("failed to list encryption zone root %s: %v", zonePath, err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("encryption zone root %s: %w", zonePath, meta.ErrNotEmpty)
+	}
+	return nil
+}
+
+// ZoneFor 返回路径所属的加密区域
+func (m *ZoneManager) ZoneFor(p string) (*Zone, bool) {
+	p = cleanZonePath(p)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for zonePath, zone := range m.zones {
+		if isWithin(p, zonePath) {
+			return zone, true
+		}
+	}
+	return nil, false
+}
+
+// ZoneRoot 返回路径所属区域的根目录，不在任何区域内时返回空字符串
+func (m *ZoneManager) ZoneRoot(p string) string {
+	if zone, ok := m.ZoneFor(p); ok {
+		return zone.Path
+	}
+	return ""
+}
+
+// ContainsZone 判断目录 dir 的子树中（不含 dir 本身）是否有加密区域的根目录，包括已销毁的区域，可用作 meta.ZoneLookup
+func (m *ZoneManager) ContainsZone(dir string) bool {
+	dir = cleanZonePath(dir)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for zonePath := range m.zones {
+		if zonePath != dir && isWithin(zonePath, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// DataKey 返回路径所属区域的数据密钥
+//
+// 路径不在任何区域内时返回 nil，所在区域已被销毁时返回 ErrZoneDestroyed。
+func (m *ZoneManager) DataKey(p string) ([]byte, error) {
+	zone, ok := m.ZoneFor(p)
+	if !ok {
+		return nil, nil
+	}
+	if zone.Destroyed {
+		return nil, fmt.Errorf("%w: %s", ErrZoneDestroyed, zone.Path)
+	}
+	return m.provider.Unwrap(zone.WrappedKey)
+}
+
+// Zones 返回所有加密区域，包括已销毁的区域
+func (m *ZoneManager) Zones() []Zone {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]Zone, 0, len(m.zones))
+	for _, zone := range m.zones {
+		result = append(result, *zone)
+	}
+	return result
+}
+
+// DestroyZone 销毁区域数据密钥（加密粉碎），区域内所有数据块将无法再被解密
+//
+// 包装密钥在存储中被覆写后删除，存储不支持安全删除时返回错误。
+func (m *ZoneManager) DestroyZone(ctx context.Context, zonePath string) error {
+	zonePath = cleanZonePath(zonePath)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zone, ok := m.zones[zonePath]
+	if !ok {
+		return fmt.Errorf("encryption zone not found: %s", zonePath)
+	}
+	if zone.Destroyed {
+		return fmt.Errorf("%w: %s", ErrZoneDestroyed, zonePath)
+	}
+
+	// 普通删除只移除目录项，磁盘上的包装密钥仍可恢复，必须覆写后删除
+	wiper, ok := m.storage.(secureDeleter)
+	if !ok {
+		return fmt.Errorf("storage does not support secure deletion of zone keys")
+	}
+	if err := wiper.SecureDelete(ctx, zoneKey(zonePath), zoneKeyWipePasses); err != nil {
+		return fmt.Errorf("failed to wipe zone key: %v", err)
+	}
+
+	// 保留不含密钥的区域记录，重启后区域内的路径仍然返回 ErrZoneDestroyed
+	destroyed := &Zone{
+		Path:        zonePath,
+		CreateTime:  zone.CreateTime,
+		Destroyed:   true,
+		DestroyTime: time.Now(),
+	}
+	data, err := json.Marshal(destroyed)
+	if err != nil {
+		return err
+	}
+	if err := m.storage.Save(ctx, zoneKey(zonePath), data); err != nil {
+		return fmt.Errorf("failed to save destroyed zone: %v", err)
+	}
+	if err := m.storage.Sync(); err != nil {
+		return fmt.Errorf("failed to persist zone deletion: %v", err)
+	}
+
+	m.zones[zonePath] = destroyed
+	m.log.Warn("Destroyed encryption zone key",
+		zap.String("path", zonePath),
+	)
+	return nil
+}
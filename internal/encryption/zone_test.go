@@ -0,0 +1,223 @@
+package encryption
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T, dir string) *meta.FileStorage {
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{
+		RootDir:      dir,
+		SyncInterval: time.Second,
+		FileMode:     0600,
+	})
+	require.NoError(t, err)
+	return storage
+}
+
+// newTestNamespace 返回已创建 dirs 中各目录的元数据存储
+func newTestNamespace(t *testing.T, dirs ...string) *meta.MemoryStore {
+	store := meta.NewMemoryStore()
+	for _, dir := range dirs {
+		require.NoError(t, store.Mkdir(context.Background(), dir, 0755))
+	}
+	return store
+}
+
+func TestEncryptBlock(t *testing.T) {
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	ciphertext, err := EncryptBlock(key, "blk-1", []byte("secret data"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "secret data")
+
+	plaintext, err := DecryptBlock(key, "blk-1", ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret data"), plaintext)
+
+	// 块ID不匹配时解密失败
+	_, err = DecryptBlock(key, "blk-2", ciphertext)
+	assert.Error(t, err)
+
+	otherKey, err := GenerateKey()
+	require.NoError(t, err)
+	_, err = DecryptBlock(otherKey, "blk-1", ciphertext)
+	assert.Error(t, err)
+}
+
+func TestZoneManager(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	masterKey, err := GenerateKey()
+	require.NoError(t, err)
+	provider, err := NewLocalKeyProvider(masterKey)
+	require.NoError(t, err)
+
+	storage := newTestStorage(t, dir)
+	namespace := newTestNamespace(t, "/secure", "/secure/a", "/secure/b")
+	manager, err := NewZoneManager(ctx, storage, provider, namespace)
+	require.NoError(t, err)
+
+	_, err = manager.CreateZone(ctx, "/secure/a")
+	require.NoError(t, err)
+	_, err = manager.CreateZone(ctx, "/secure/b")
+	require.NoError(t, err)
+
+	// 不允许嵌套区域
+	require.NoError(t, namespace.Mkdir(ctx, "/secure/a/inner", 0755))
+	_, err = manager.CreateZone(ctx, "/secure/a/inner")
+	assert.Error(t, err)
+	_, err = manager.CreateZone(ctx, "/secure")
+	assert.Error(t, err)
+
+	keyA, err := manager.DataKey("/secure/a/file.bin")
+	require.NoError(t, err)
+	keyB, err := manager.DataKey("/secure/b/file.bin")
+	require.NoError(t, err)
+	assert.Len(t, keyA, KeySize)
+	assert.NotEqual(t, keyA, keyB)
+
+	key, err := manager.DataKey("/plain/file.bin")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+	_, ok := manager.ZoneFor("/secure/ab")
+	assert.False(t, ok)
+
+	// 重启后从存储恢复区域
+	require.NoError(t, storage.Close())
+	storage = newTestStorage(t, dir)
+	manager, err = NewZoneManager(ctx, storage, provider, namespace)
+	require.NoError(t, err)
+	assert.Len(t, manager.Zones(), 2)
+
+	reloaded, err := manager.DataKey("/secure/a/file.bin")
+	require.NoError(t, err)
+	assert.Equal(t, keyA, reloaded)
+
+	// 加密粉碎
+	require.NoError(t, manager.DestroyZone(ctx, "/secure/a"))
+	zone, ok := manager.ZoneFor("/secure/a/file.bin")
+	require.True(t, ok)
+	assert.True(t, zone.Destroyed)
+	assert.Empty(t, zone.WrappedKey)
+	assert.ErrorIs(t, manager.DestroyZone(ctx, "/secure/a"), ErrZoneDestroyed)
+
+	// 已销毁区域内的路径不会被当作未加密路径，重启后同样如此
+	_, err = manager.DataKey("/secure/a/file.bin")
+	assert.ErrorIs(t, err, ErrZoneDestroyed)
+	require.NoError(t, storage.Close())
+	storage = newTestStorage(t, dir)
+	defer storage.Close()
+	manager, err = NewZoneManager(ctx, storage, provider, namespace)
+	require.NoError(t, err)
+	_, err = manager.DataKey("/secure/a/file.bin")
+	assert.ErrorIs(t, err, ErrZoneDestroyed)
+	keyB2, err := manager.DataKey("/secure/b/file.bin")
+	require.NoError(t, err)
+	assert.Equal(t, keyB, keyB2)
+}
+
+func TestZoneManagerRejectsCrossZoneRename(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := GenerateKey()
+	require.NoError(t, err)
+	provider, err := NewLocalKeyProvider(masterKey)
+	require.NoError(t, err)
+	storage := newTestStorage(t, t.TempDir())
+	defer storage.Close()
+	store := newTestNamespace(t, "/secure")
+	manager, err := NewZoneManager(ctx, storage, provider, store)
+	require.NoError(t, err)
+	_, err = manager.CreateZone(ctx, "/secure")
+	require.NoError(t, err)
+
+	store.SetZoneLookup(manager)
+	_, err = store.Create(ctx, "/secure/file", 0644)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, store.Rename(ctx, "/secure/file", "/file"), meta.ErrCrossZone)
+	assert.ErrorIs(t, store.Link(ctx, "/secure/file", "/file"), meta.ErrCrossZone)
+	assert.NoError(t, store.Rename(ctx, "/secure/file", "/secure/renamed"))
+}
+
+func TestZoneManagerProtectsZoneRoots(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := GenerateKey()
+	require.NoError(t, err)
+	provider, err := NewLocalKeyProvider(masterKey)
+	require.NoError(t, err)
+	storage := newTestStorage(t, t.TempDir())
+	defer storage.Close()
+
+	store := newTestNamespace(t, "/a", "/a/z", "/other")
+	manager, err := NewZoneManager(ctx, storage, provider, store)
+	require.NoError(t, err)
+
+	// 根目录必须已经存在、是目录且为空
+	_, err = manager.CreateZone(ctx, "/missing")
+	assert.Error(t, err)
+	_, err = store.Create(ctx, "/other/plain.txt", 0644)
+	require.NoError(t, err)
+	_, err = manager.CreateZone(ctx, "/other")
+	assert.ErrorIs(t, err, meta.ErrNotEmpty)
+	_, err = manager.CreateZone(ctx, "/other/plain.txt")
+	assert.Error(t, err)
+	assert.Empty(t, manager.Zones())
+
+	_, err = manager.CreateZone(ctx, "/a/z")
+	require.NoError(t, err)
+	store.SetZoneLookup(manager)
+	_, err = store.Create(ctx, "/a/z/f", 0644)
+	require.NoError(t, err)
+
+	// 移动或删除包含区域根目录的目录会让区域内的文件脱离区域
+	assert.ErrorIs(t, store.Rename(ctx, "/a", "/b"), meta.ErrContainsZone)
+	assert.ErrorIs(t, store.RenameExchange(ctx, "/a", "/other"), meta.ErrContainsZone)
+	assert.ErrorIs(t, store.DeleteAll(ctx, "/a"), meta.ErrContainsZone)
+	_, err = store.DeleteTree(ctx, "/a", meta.DeleteTreeOptions{})
+	assert.ErrorIs(t, err, meta.ErrContainsZone)
+	assert.Equal(t, "/a/z", manager.ZoneRoot("/a/z/f"))
+
+	// 区域根目录本身随区域一起删除
+	require.NoError(t, store.DeleteAll(ctx, "/a/z"))
+	assert.False(t, manager.ContainsZone("/a/z"))
+	assert.True(t, manager.ContainsZone("/"))
+}
+
+// plainStorage 隐藏 FileStorage 的 SecureDelete，模拟不支持安全删除的存储
+type plainStorage struct {
+	meta.Storage
+}
+
+func TestDestroyZoneRequiresSecureDelete(t *testing.T) {
+	ctx := context.Background()
+	masterKey, err := GenerateKey()
+	require.NoError(t, err)
+	provider, err := NewLocalKeyProvider(masterKey)
+	require.NoError(t, err)
+
+	storage := newTestStorage(t, t.TempDir())
+	defer storage.Close()
+	manager, err := NewZoneManager(ctx, plainStorage{storage}, provider, newTestNamespace(t, "/secure"))
+	require.NoError(t, err)
+	_, err = manager.CreateZone(ctx, "/secure")
+	require.NoError(t, err)
+
+	// 无法覆写包装密钥时拒绝销毁，区域保持可用
+	assert.Error(t, manager.DestroyZone(ctx, "/secure"))
+	key, err := manager.DataKey("/secure/file.bin")
+	require.NoError(t, err)
+	assert.Len(t, key, KeySize)
+}
+
+func TestLocalKeyProviderInvalidKey(t *testing.T) {
+	_, err := NewLocalKeyProvider([]byte("short"))
+	assert.Error(t, err)
+}
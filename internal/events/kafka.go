@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"strconv"
+
+	"cpfs/internal/meta"
+)
+
+// KafkaRecord 发布到 Kafka 的一条记录
+type KafkaRecord struct {
+	Topic string
+	Key   []byte // 事件的 inode，同一文件的事件进入同一分区以保持顺序
+	Value []byte // 事件的 JSON 编码
+}
+
+// KafkaProducer 同步写入 Kafka 的客户端，返回 nil 表示全部记录已被 broker 按 acks 设置确认
+//
+// cpfs 不内置 Kafka 客户端，由嵌入程序用所选的客户端库实现，生产者应开启 acks=all。
+type KafkaProducer interface {
+	Produce(ctx context.Context, records []KafkaRecord) error
+}
+
+// KafkaSink 把每个事件作为一条记录写入 Kafka 主题
+type KafkaSink struct {
+	producer KafkaProducer
+	topic    string
+}
+
+// NewKafkaSink 创建写入 topic 的 Kafka 目标
+func NewKafkaSink(producer KafkaProducer, topic string) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic}
+}
+
+// Publish 实现 Sink
+func (k *KafkaSink) Publish(ctx context.Context, events []meta.ChangeEvent) error {
+	records := make([]KafkaRecord, 0, len(events))
+	for _, e := range events {
+		value, err := Encode(e)
+		if err != nil {
+			return err
+		}
+		records = append(records, KafkaRecord{
+			Topic: k.topic,
+			Key:   []byte(strconv.FormatUint(e.Inode, 10)),
+			Value: value,
+		})
+	}
+	return k.producer.Produce(ctx, records)
+}
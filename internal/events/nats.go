@@ -0,0 +1,140 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/meta"
+)
+
+// defaultNATSTimeout 连接与一次发布的默认超时
+const defaultNATSTimeout = 10 * time.Second
+
+// NATSSink 用 NATS 文本协议把每个事件作为一条消息发布到主题
+//
+// 每批事件发布后发送 PING 并等待 PONG，收到 PONG 说明服务器已处理之前的全部 PUB。
+// 核心 NATS 不持久化消息，需要持久化时主题应由 JetStream 流捕获。连接出错后在下一次发布时重连。
+type NATSSink struct {
+	addr    string
+	subject string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewNATSSink 创建发布到 addr 上 subject 主题的 NATS 目标，addr 可以带 nats:// 前缀
+func NewNATSSink(addr, subject string) *NATSSink {
+	return &NATSSink{addr: strings.TrimPrefix(addr, "nats://"), subject: subject}
+}
+
+// Publish 实现 Sink
+func (n *NATSSink) Publish(ctx context.Context, events []meta.ChangeEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.connect(ctx); err != nil {
+		return err
+	}
+	if err := n.publish(ctx, events); err != nil {
+		n.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// Close 关闭连接
+func (n *NATSSink) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.closeLocked()
+}
+
+// closeLocked 关闭连接，调用方需持有锁
+func (n *NATSSink) closeLocked() error {
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn, n.r = nil, nil
+	return err
+}
+
+// deadline 返回本次操作的截止时间
+func deadline(ctx context.Context) time.Time {
+	if d, ok := ctx.Deadline(); ok {
+		return d
+	}
+	return time.Now().Add(defaultNATSTimeout)
+}
+
+// connect 建立连接并完成握手，已连接时直接返回，调用方需持有锁
+func (n *NATSSink) connect(ctx context.Context) error {
+	if n.conn != nil {
+		return nil
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nats: %v", err)
+	}
+	conn.SetDeadline(deadline(ctx))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to read nats info: %v", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected nats greeting: %q", strings.TrimSpace(line))
+	}
+	if _, err := conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"cpfs\"}\r\n")); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send nats connect: %v", err)
+	}
+	n.conn, n.r = conn, r
+	return nil
+}
+
+// publish 发送一批 PUB 并等待 PONG 确认，调用方需持有锁
+func (n *NATSSink) publish(ctx context.Context, events []meta.ChangeEvent) error {
+	n.conn.SetDeadline(deadline(ctx))
+	w := bufio.NewWriter(n.conn)
+	for _, e := range events {
+		msg, err := Encode(e)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "PUB %s %d\r\n", n.subject, len(msg))
+		w.Write(msg)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("PING\r\n")
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to publish to nats: %v", err)
+	}
+
+	for {
+		line, err := n.r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read nats reply: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to answer nats ping: %v", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNATS 接受连接，记录 PUB 的主题和消息，reject 为 true 时对 PING 回复 -ERR
+func fakeNATS(t *testing.T, reject bool) (addr string, published <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	msgs := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch {
+					case len(fields) == 3 && fields[0] == "PUB":
+						var size int
+						fmt.Sscan(fields[2], &size)
+						payload := make([]byte, size+2)
+						if _, err := io.ReadFull(r, payload); err != nil {
+							return
+						}
+						msgs <- fields[1] + " " + string(payload[:size])
+					case len(fields) == 1 && fields[0] == "PING":
+						if reject {
+							fmt.Fprintf(conn, "-ERR 'Permissions Violation'\r\n")
+						} else {
+							fmt.Fprintf(conn, "PING\r\nPONG\r\n")
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return "nats://" + ln.Addr().String(), msgs
+}
+
+func TestNATSSink(t *testing.T) {
+	addr, published := fakeNATS(t, false)
+	sink := NewNATSSink(addr, "cpfs.events")
+	defer sink.Close()
+
+	events := []meta.ChangeEvent{{Seq: 1, Type: meta.ChangeCreate, Path: "/a"}, {Seq: 2, Type: meta.ChangeDelete, Path: "/a"}}
+	require.NoError(t, sink.Publish(context.Background(), events))
+	first := <-published
+	assert.True(t, strings.HasPrefix(first, "cpfs.events {"), first)
+	assert.Contains(t, first, `"type":"create"`)
+	assert.Contains(t, <-published, `"type":"delete"`)
+
+	// 复用连接
+	require.NoError(t, sink.Publish(context.Background(), events[:1]))
+	<-published
+
+	rejecting, _ := fakeNATS(t, true)
+	bad := NewNATSSink(rejecting, "cpfs.events")
+	defer bad.Close()
+	err := bad.Publish(context.Background(), events)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Permissions Violation")
+
+	unreachable := NewNATSSink("127.0.0.1:1", "cpfs.events")
+	assert.Error(t, unreachable.Publish(context.Background(), events))
+}
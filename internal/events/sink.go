@@ -0,0 +1,339 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBatchSize 一次发布的最大事件数
+	DefaultBatchSize = 100
+	// DefaultMinBackoff 发布失败后的初始重试间隔
+	DefaultMinBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff 发布失败后的最大重试间隔
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Sink 变更事件的投递目标
+//
+// Publish 返回 nil 表示整批事件已被目标确认接收；返回错误时整批重试，因此目标可能收到重复事件，
+// 消费者应按 Seq 去重。
+type Sink interface {
+	Publish(ctx context.Context, events []meta.ChangeEvent) error
+}
+
+// Message 事件的 JSON 编码，各目标发布的消息体相同
+type Message struct {
+	Seq     uint64    `json:"seq"`
+	Type    string    `json:"type"`
+	Path    string    `json:"path"`
+	OldPath string    `json:"old_path,omitempty"`
+	Inode   uint64    `json:"inode"`
+	IsDir   bool      `json:"is_dir"`
+	User    string    `json:"user,omitempty"`
+	Time    time.Time `json:"time"`
+	// 变更日志启用 HLC 时的时间戳，消费者合并多个元数据服务器的事件时按其排序
+	HLC *meta.HLCTimestamp `json:"hlc,omitempty"`
+}
+
+// Encode 返回事件的 JSON 编码
+func Encode(e meta.ChangeEvent) ([]byte, error) {
+	body, err := json.Marshal(Message{
+		Seq:     e.Seq,
+		Type:    e.Type.String(),
+		Path:    e.Path,
+		OldPath: e.OldPath,
+		Inode:   e.Inode,
+		IsDir:   e.IsDir,
+		User:    e.User,
+		Time:    e.Time,
+		HLC:     e.HLC,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode change event: %v", err)
+	}
+	return body, nil
+}
+
+// Filter 事件过滤条件，为空的条件不限制
+type Filter struct {
+	Prefixes []string          // 路径前缀，事件的 Path 或 OldPath 位于任一前缀下即匹配
+	Types    []meta.ChangeType // 事件类型
+}
+
+// Match 判断事件是否满足过滤条件
+func (f Filter) Match(e *meta.ChangeEvent) bool {
+	if len(f.Types) > 0 {
+		matched := false
+		for _, t := range f.Types {
+			if e.Type == t {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(f.Prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range f.Prefixes {
+		if e.HasPrefix(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChangeType 按名称解析事件类型
+func parseChangeType(name string) (meta.ChangeType, error) {
+	for t := meta.ChangeCreate; t <= meta.ChangeExchange; t++ {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown change type: %q", name)
+}
+
+// Options 投递选项
+type Options struct {
+	// Filter 只投递匹配的事件
+	Filter Filter
+	// FromSeq 开始投递的事件序号，为 0 时从启动之后的新事件开始
+	FromSeq uint64
+	// BatchSize 一次发布的最大事件数，为 0 时使用 DefaultBatchSize
+	BatchSize int
+	// MinBackoff、MaxBackoff 发布失败后的重试间隔范围，为 0 时使用默认值
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Groups 不为 nil 时游标保存在名为 Group 的消费组中，重启后从已确认的位置继续，FromSeq 只在首次创建组时生效
+	Groups *meta.ConsumerGroups
+	Group  string
+}
+
+// Dispatcher 从变更日志读取事件并至少一次地发布到一个目标
+//
+// 事件按序号顺序发布，发布失败时按指数退避重试同一批事件，成功后才推进游标，
+// 因此目标不会漏收事件。未设置 Options.Groups 时游标只保存在内存中，重启后需要用 Acked()+1 作为 FromSeq 才能不丢事件；
+// 设置后每批发布成功都会持久化确认位置，崩溃重启最多重复投递最后一批。
+// 落后超过变更日志容量时无法保证投递，Run 返回 ErrChangelogTruncated。
+type Dispatcher struct {
+	name      string
+	changelog *meta.Changelog
+	sink      Sink
+	opts      Options
+	published *metrics.Counter
+	failed    *metrics.Counter
+	sleep     func(ctx context.Context, d time.Duration) error
+
+	mu    sync.Mutex
+	acked uint64
+	log   logger.Logger
+}
+
+// NewDispatcher 创建名为 name 的投递器，registry 为 nil 时使用 metrics.Default
+//
+// 设置 Options.Groups 时创建或加载消费组，失败时返回错误。
+func NewDispatcher(name string, changelog *meta.Changelog, sink Sink, opts Options, registry *metrics.Registry) (*Dispatcher, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = DefaultMinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.Groups != nil {
+		if opts.Group == "" {
+			opts.Group = name
+		}
+		if err := opts.Groups.Create(context.Background(), opts.Group, opts.FromSeq); err != nil {
+			return nil, fmt.Errorf("failed to create consumer group for event sink %s: %v", name, err)
+		}
+		acked, err := opts.Groups.Offset(opts.Group)
+		if err != nil {
+			return nil, err
+		}
+		opts.FromSeq = acked + 1
+	}
+	if opts.FromSeq == 0 {
+		opts.FromSeq = changelog.LastSeq() + 1
+	}
+	if registry == nil {
+		registry = metrics.Default
+	}
+	labels := metrics.Labels{"sink": name}
+	d := &Dispatcher{
+		name:      name,
+		changelog: changelog,
+		sink:      sink,
+		opts:      opts,
+		published: registry.Counter("cpfs_event_sink_published_total", "Change events published to event sinks.", labels),
+		failed:    registry.Counter("cpfs_event_sink_failures_total", "Failed event sink publish attempts.", labels),
+		sleep:     sleepContext,
+		acked:     opts.FromSeq - 1,
+		log:       logger.Default(),
+	}
+	registry.GaugeFunc("cpfs_event_sink_lag", "Change events not yet published to the event sink.", labels, func() float64 {
+		return float64(changelog.LastSeq() - d.Acked())
+	})
+	return d, nil
+}
+
+// sleepContext 等待 d 或 ctx 取消
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (d *Dispatcher) SetLogger(l logger.Logger) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = logger.OrDefault(l)
+}
+
+// Acked 返回已确认投递的最后一个事件序号，过滤掉的事件也计为已确认
+func (d *Dispatcher) Acked() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.acked
+}
+
+// ack 推进游标，设置了消费组时先持久化确认位置
+func (d *Dispatcher) ack(ctx context.Context, seq uint64) error {
+	if d.opts.Groups != nil {
+		if err := d.opts.Groups.Ack(ctx, d.opts.Group, seq); err != nil {
+			return err
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.acked = seq
+	return nil
+}
+
+// Run 持续投递事件，阻塞到 ctx 被取消或变更日志已淘汰未投递的事件
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for {
+		events, wait, err := d.changelog.Since(d.Acked() + 1)
+		if err != nil {
+			d.mu.Lock()
+			log := d.log
+			d.mu.Unlock()
+			log.Error("Event sink fell behind the changelog", zap.String("sink", d.name), zap.Error(err))
+			return err
+		}
+		for len(events) > 0 {
+			n := len(events)
+			if n > d.opts.BatchSize {
+				n = d.opts.BatchSize
+			}
+			if err := d.deliver(ctx, events[:n]); err != nil {
+				return err
+			}
+			events = events[n:]
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// deliver 发布一批事件中匹配的部分，失败时退避重试，直到成功或 ctx 被取消
+func (d *Dispatcher) deliver(ctx context.Context, batch []meta.ChangeEvent) error {
+	last := batch[len(batch)-1].Seq
+	matched := make([]meta.ChangeEvent, 0, len(batch))
+	for i := range batch {
+		if d.opts.Filter.Match(&batch[i]) {
+			matched = append(matched, batch[i])
+		}
+	}
+	if len(matched) == 0 {
+		return d.ack(ctx, last)
+	}
+
+	backoff := d.opts.MinBackoff
+	for {
+		err := d.sink.Publish(ctx, matched)
+		if err == nil {
+			d.published.Add(uint64(len(matched)))
+			return d.ack(ctx, last)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		d.failed.Inc()
+		d.mu.Lock()
+		log := d.log
+		d.mu.Unlock()
+		log.Warn("Failed to publish change events, retrying",
+			zap.String("sink", d.name),
+			zap.Uint64("first_seq", matched[0].Seq),
+			zap.Int("events", len(matched)),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		if err := d.sleep(ctx, backoff); err != nil {
+			return err
+		}
+		if backoff *= 2; backoff > d.opts.MaxBackoff {
+			backoff = d.opts.MaxBackoff
+		}
+	}
+}
+
+// NewSinkFromConfig 按配置创建 webhook 或 nats 目标以及对应的过滤条件
+//
+// kafka 目标需要 Kafka 客户端，由调用方用 NewKafkaSink 包装后创建，这里返回错误。
+func NewSinkFromConfig(cfg config.EventSinkConfig) (Sink, Filter, error) {
+	filter := Filter{Prefixes: cfg.Prefixes}
+	for _, name := range cfg.Types {
+		t, err := parseChangeType(name)
+		if err != nil {
+			return nil, Filter{}, fmt.Errorf("event sink %s: %v", cfg.Name, err)
+		}
+		filter.Types = append(filter.Types, t)
+	}
+
+	switch cfg.Type {
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, Filter{}, fmt.Errorf("event sink %s: url is required", cfg.Name)
+		}
+		return NewWebhookSink(cfg.URL), filter, nil
+	case "nats":
+		if cfg.URL == "" || cfg.Subject == "" {
+			return nil, Filter{}, fmt.Errorf("event sink %s: url and subject are required", cfg.Name)
+		}
+		return NewNATSSink(cfg.URL, cfg.Subject), filter, nil
+	case "kafka":
+		return nil, Filter{}, fmt.Errorf("event sink %s: %w", cfg.Name, ErrNoKafkaClient)
+	default:
+		return nil, Filter{}, fmt.Errorf("event sink %s: unknown type %q", cfg.Name, cfg.Type)
+	}
+}
+
+// ErrNoKafkaClient 配置了 kafka 目标但没有内置 Kafka 客户端
+var ErrNoKafkaClient = errors.New("kafka sinks require a producer supplied through NewKafkaSink")
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySink 前 failures 次发布失败，之后记录收到的事件
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	got      []meta.ChangeEvent
+	notify   chan struct{}
+}
+
+func (s *flakySink) Publish(ctx context.Context, events []meta.ChangeEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	s.got = append(s.got, events...)
+	s.notify <- struct{}{}
+	return nil
+}
+
+func (s *flakySink) paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var paths []string
+	for _, e := range s.got {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
+
+func TestDispatcherRetriesAndFilters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changelog := meta.NewChangelog(16)
+	changelog.Append(meta.ChangeEvent{Type: meta.ChangeCreate, Path: "/old"})
+
+	sink := &flakySink{failures: 2, notify: make(chan struct{}, 16)}
+	d, err := NewDispatcher("test", changelog, sink, Options{
+		Filter: Filter{Prefixes: []string{"/data"}, Types: []meta.ChangeType{meta.ChangeCreate, meta.ChangeRename}},
+	}, metrics.NewRegistry())
+	require.NoError(t, err)
+	d.sleep = func(ctx context.Context, _ time.Duration) error { return ctx.Err() }
+	assert.Equal(t, uint64(1), d.Acked())
+
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	changelog.Append(meta.ChangeEvent{Type: meta.ChangeCreate, Path: "/data/a"})
+	changelog.Append(meta.ChangeEvent{Type: meta.ChangeModify, Path: "/data/a"})
+	changelog.Append(meta.ChangeEvent{Type: meta.ChangeCreate, Path: "/other"})
+	changelog.Append(meta.ChangeEvent{Type: meta.ChangeRename, Path: "/data/b", OldPath: "/other"})
+
+	deadline := time.After(time.Second)
+	for len(sink.paths()) < 2 {
+		select {
+		case <-sink.notify:
+		case <-deadline:
+			t.Fatalf("events not delivered: %v", sink.paths())
+		}
+	}
+	assert.Equal(t, []string{"/data/a", "/data/b"}, sink.paths())
+	assert.GreaterOrEqual(t, sink.attempts, 3)
+	require.Eventually(t, func() bool { return d.Acked() == 5 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDispatcherTruncated(t *testing.T) {
+	changelog := meta.NewChangelog(2)
+	for i := 0; i < 4; i++ {
+		changelog.Append(meta.ChangeEvent{Type: meta.ChangeCreate, Path: "/f"})
+	}
+	d, err := NewDispatcher("test", changelog, &flakySink{notify: make(chan struct{}, 4)}, Options{FromSeq: 1}, metrics.NewRegistry())
+	require.NoError(t, err)
+	assert.ErrorIs(t, d.Run(context.Background()), meta.ErrChangelogTruncated)
+}
+
+func TestDispatcherConsumerGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{RootDir: t.TempDir(), SyncInterval: time.Second, FileMode: 0600})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	changelog := meta.NewChangelog(16)
+	groups, err := meta.NewConsumerGroups(ctx, changelog, storage)
+	require.NoError(t, err)
+	changelog.Append(meta.ChangeEvent{Type: meta.ChangeCreate, Path: "/a"})
+	changelog.Append(meta.ChangeEvent{Type: meta.ChangeCreate, Path: "/b"})
+
+	sink := &flakySink{notify: make(chan struct{}, 16)}
+	d, err := NewDispatcher("mirror", changelog, sink, Options{FromSeq: 1, Groups: groups}, metrics.NewRegistry())
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+	require.Eventually(t, func() bool { return d.Acked() == 2 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	// 确认位置已持久化，重新创建的投递器从其后继续，FromSeq 不再生效
+	offset, err := groups.Offset("mirror")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), offset)
+	d, err = NewDispatcher("mirror", changelog, sink, Options{FromSeq: 1, Groups: groups}, metrics.NewRegistry())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), d.Acked())
+	assert.Equal(t, []string{"/a", "/b"}, sink.paths())
+}
+
+// recordingProducer 记录写入的 Kafka 记录
+type recordingProducer struct {
+	records []KafkaRecord
+}
+
+func (p *recordingProducer) Produce(ctx context.Context, records []KafkaRecord) error {
+	p.records = append(p.records, records...)
+	return nil
+}
+
+func TestKafkaSinkAndConfig(t *testing.T) {
+	producer := &recordingProducer{}
+	sink := NewKafkaSink(producer, "cpfs-events")
+	require.NoError(t, sink.Publish(context.Background(), []meta.ChangeEvent{{Seq: 7, Type: meta.ChangeDelete, Path: "/f", Inode: 42}}))
+	require.Len(t, producer.records, 1)
+	assert.Equal(t, "cpfs-events", producer.records[0].Topic)
+	assert.Equal(t, "42", string(producer.records[0].Key))
+	var msg Message
+	require.NoError(t, json.Unmarshal(producer.records[0].Value, &msg))
+	assert.Equal(t, Message{Seq: 7, Type: "delete", Path: "/f", Inode: 42}, msg)
+
+	_, filter, err := NewSinkFromConfig(config.EventSinkConfig{Name: "hook", Type: "webhook", URL: "http://localhost/", Types: []string{"create", "exchange"}})
+	require.NoError(t, err)
+	assert.Equal(t, []meta.ChangeType{meta.ChangeCreate, meta.ChangeExchange}, filter.Types)
+
+	for _, cfg := range []config.EventSinkConfig{
+		{Name: "a", Type: "webhook"},
+		{Name: "b", Type: "nats", URL: "localhost:4222"},
+		{Name: "c", Type: "smtp"},
+		{Name: "d", Type: "webhook", URL: "http://localhost/", Types: []string{"chmod"}},
+	} {
+		_, _, err := NewSinkFromConfig(cfg)
+		assert.Error(t, err, cfg.Name)
+	}
+	_, _, err = NewSinkFromConfig(config.EventSinkConfig{Name: "k", Type: "kafka", Subject: "t"})
+	assert.ErrorIs(t, err, ErrNoKafkaClient)
+}
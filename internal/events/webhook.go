@@ -0,0 +1,64 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cpfs/internal/meta"
+)
+
+// WebhookSink 把一批事件以 JSON 数组 POST 到 URL，2xx 响应视为确认
+type WebhookSink struct {
+	// HTTPClient 发送请求使用的客户端，为 nil 时使用 10 秒超时的客户端
+	HTTPClient *http.Client
+
+	url string
+}
+
+// NewWebhookSink 创建 Webhook 目标
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		url:        url,
+	}
+}
+
+// Publish 实现 Sink
+func (w *WebhookSink) Publish(ctx context.Context, events []meta.ChangeEvent) error {
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, e := range events {
+		msg, err := Encode(e)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(msg)
+	}
+	body.WriteByte(']')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var got []Message
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var batch []Message
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		got = append(got, batch...)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+	events := []meta.ChangeEvent{
+		{Seq: 1, Type: meta.ChangeCreate, Path: "/a"},
+		{Seq: 2, Type: meta.ChangeRename, Path: "/b", OldPath: "/a"},
+	}
+	require.NoError(t, sink.Publish(context.Background(), events))
+	require.Len(t, got, 2)
+	assert.Equal(t, "rename", got[1].Type)
+	assert.Equal(t, "/a", got[1].OldPath)
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, sink.Publish(context.Background(), events))
+}
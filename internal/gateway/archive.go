@@ -0,0 +1,332 @@
+package gateway
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"cpfs/internal/meta"
+)
+
+// DefaultArchiveParallelism 打包时默认同时读取的数据块数
+const DefaultArchiveParallelism = 8
+
+// ArchiveFormat 打包格式
+type ArchiveFormat string
+
+const (
+	ArchiveTar ArchiveFormat = "tar"
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// ParseArchiveFormat 解析打包格式，空字符串表示 tar
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch ArchiveFormat(s) {
+	case "", ArchiveTar:
+		return ArchiveTar, nil
+	case ArchiveZip:
+		return ArchiveZip, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format: %q", s)
+	}
+}
+
+// TreeSource 打包读取的元数据
+type TreeSource interface {
+	BlockRanger
+	Get(ctx context.Context, path string) (*meta.Metadata, error)
+	List(ctx context.Context, path string) ([]*meta.Metadata, error)
+}
+
+// ArchiveOptions ArchiveTree 的选项
+type ArchiveOptions struct {
+	// Format 打包格式，为空时使用 tar
+	Format ArchiveFormat
+	// Parallelism 同时读取的数据块数，为 0 时使用 DefaultArchiveParallelism
+	Parallelism int
+}
+
+// archiveEntry 打包中的一个条目
+type archiveEntry struct {
+	name string // 打包内的相对路径，目录以 / 结尾
+	path string // 文件系统中的路径
+	meta *meta.Metadata
+}
+
+// blockFetch 一个数据块的并行读取结果
+type blockFetch struct {
+	block meta.Block
+	done  chan struct{}
+	data  []byte
+	err   error
+}
+
+// archiveWriter tar 与 zip 的公共写入接口
+type archiveWriter interface {
+	writeHeader(e *archiveEntry) (io.Writer, error)
+	Close() error
+}
+
+type tarArchive struct{ *tar.Writer }
+
+func (a tarArchive) writeHeader(e *archiveEntry) (io.Writer, error) {
+	hdr := &tar.Header{
+		Name:    e.name,
+		Mode:    int64(e.meta.Mode.Perm()),
+		ModTime: e.meta.ModifyTime,
+		Uname:   e.meta.Owner,
+		Gname:   e.meta.Group,
+		Format:  tar.FormatPAX,
+	}
+	if e.meta.Type == meta.TypeDirectory {
+		hdr.Typeflag = tar.TypeDir
+	} else {
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = e.meta.Size
+	}
+	if err := a.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	return a.Writer, nil
+}
+
+type zipArchive struct{ *zip.Writer }
+
+func (a zipArchive) writeHeader(e *archiveEntry) (io.Writer, error) {
+	hdr := &zip.FileHeader{Name: e.name, Modified: e.meta.ModifyTime, Method: zip.Store}
+	hdr.SetMode(e.meta.Mode)
+	return a.CreateHeader(hdr)
+}
+
+// collectArchive 按深度优先、名称有序收集子树中的目录和普通文件，符号链接被跳过
+func collectArchive(ctx context.Context, source TreeSource, dir, prefix string, out []archiveEntry) ([]archiveEntry, error) {
+	children, err := source.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+	for _, child := range children {
+		p := path.Join(dir, child.Name)
+		name := prefix + child.Name
+		switch child.Type {
+		case meta.TypeDirectory:
+			out = append(out, archiveEntry{name: name + "/", path: p, meta: child})
+			if out, err = collectArchive(ctx, source, p, name+"/", out); err != nil {
+				return nil, err
+			}
+		case meta.TypeRegular:
+			out = append(out, archiveEntry{name: name, path: p, meta: child})
+		}
+	}
+	return out, nil
+}
+
+// readReplica 依次尝试数据块的各个副本，返回第一个成功读取的内容
+func readReplica(ctx context.Context, reader meta.BlockReader, block meta.Block) ([]byte, error) {
+	if len(block.Locations) == 0 {
+		return nil, fmt.Errorf("block %s has no replicas", block.ID)
+	}
+	var lastErr error
+	for _, location := range block.Locations {
+		data, err := reader.ReadBlock(ctx, location, block)
+		if err == nil && int64(len(data)) == block.Size {
+			return data, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("short read from %s: %d of %d bytes", location, len(data), block.Size)
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to read block %s: %v", block.ID, lastErr)
+}
+
+// ArchiveTree 将 root 子树打包写入 w
+//
+// 数据块按打包顺序排队并由最多 Parallelism 个读取并行获取，读取可以跨越文件边界提前进行，
+// 写入仍严格按顺序，因此输出可以直接流式返回给客户端。文件中没有数据块覆盖的空洞按零填充。
+func ArchiveTree(ctx context.Context, w io.Writer, source TreeSource, reader meta.BlockReader, root string, opts ArchiveOptions) error {
+	format, err := ParseArchiveFormat(string(opts.Format))
+	if err != nil {
+		return err
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = DefaultArchiveParallelism
+	}
+
+	rootMeta, err := source.Get(ctx, root)
+	if err != nil {
+		return err
+	}
+	var entries []archiveEntry
+	if rootMeta.Type == meta.TypeDirectory {
+		if entries, err = collectArchive(ctx, source, root, "", nil); err != nil {
+			return err
+		}
+	} else {
+		entries = []archiveEntry{{name: path.Base(root), path: root, meta: rootMeta}}
+	}
+
+	// 每个文件的数据块按偏移排序
+	blocks := make([][]meta.Block, len(entries))
+	for i, e := range entries {
+		if e.meta.Type != meta.TypeRegular || len(e.meta.InlineData) > 0 || e.meta.Size == 0 {
+			continue
+		}
+		if blocks[i], err = source.GetBlockRange(ctx, e.path, 0, 0); err != nil {
+			return err
+		}
+		sort.Slice(blocks[i], func(a, b int) bool {
+			return blocks[i][a].Offset < blocks[i][b].Offset
+		})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	queue := make(chan *blockFetch, opts.Parallelism)
+	go func() {
+		defer close(queue)
+		sem := make(chan struct{}, opts.Parallelism)
+		for _, fileBlocks := range blocks {
+			for _, block := range fileBlocks {
+				f := &blockFetch{block: block, done: make(chan struct{})}
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				go func() {
+					defer func() { <-sem }()
+					f.data, f.err = readReplica(ctx, reader, f.block)
+					close(f.done)
+				}()
+				select {
+				case queue <- f:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var archive archiveWriter
+	if format == ArchiveZip {
+		archive = zipArchive{zip.NewWriter(w)}
+	} else {
+		archive = tarArchive{tar.NewWriter(w)}
+	}
+	for i, e := range entries {
+		out, err := archive.writeHeader(&e)
+		if err != nil {
+			return fmt.Errorf("failed to write archive header for %s: %v", e.path, err)
+		}
+		if e.meta.Type != meta.TypeRegular {
+			continue
+		}
+		if len(e.meta.InlineData) > 0 {
+			if _, err := out.Write(e.meta.InlineData); err != nil {
+				return err
+			}
+			continue
+		}
+		written := int64(0)
+		for range blocks[i] {
+			var f *blockFetch
+			select {
+			case f = <-queue:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if f.err != nil {
+				return fmt.Errorf("%s: %v", e.path, f.err)
+			}
+			if err := writeZeros(out, f.block.Offset-written); err != nil {
+				return err
+			}
+			data := f.data
+			if end := f.block.Offset + int64(len(data)); end > e.meta.Size {
+				data = data[:max(e.meta.Size-f.block.Offset, 0)]
+			}
+			if _, err := out.Write(data); err != nil {
+				return err
+			}
+			written = max(written, f.block.Offset+int64(len(data)))
+		}
+		if err := writeZeros(out, e.meta.Size-written); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// writeZeros 写入 n 个零字节，n 小于等于 0 时不写入
+func writeZeros(w io.Writer, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := io.CopyN(w, zeroReader{}, n)
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// ArchiveHandler 返回以打包文件下载子树的 HTTP 处理器
+//
+// GET ?path=/dir&format=tar|zip。响应开始写出后出错只能中断连接，客户端会看到不完整的打包文件。
+func ArchiveHandler(source TreeSource, reader meta.BlockReader, opts ArchiveOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		root := r.URL.Query().Get("path")
+		if root == "" {
+			http.Error(w, "path is required", http.StatusBadRequest)
+			return
+		}
+		format, err := ParseArchiveFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := source.Get(r.Context(), root); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		name := path.Base(root)
+		if name == "/" || name == "." {
+			name = "root"
+		}
+		contentType := "application/x-tar"
+		if format == ArchiveZip {
+			contentType = "application/zip"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", strings.ReplaceAll(name, `"`, "_")+"."+string(format)))
+
+		opts := opts
+		opts.Format = format
+		if err := ArchiveTree(r.Context(), w, source, reader, root, opts); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockData 按块ID返回内容的测试读取器，d0 上的副本总是读取失败
+type blockData map[string][]byte
+
+func (b blockData) ReadBlock(ctx context.Context, location string, block meta.Block) ([]byte, error) {
+	data, ok := b[block.ID]
+	if !ok || location == "d0" {
+		return nil, errors.New("unavailable")
+	}
+	return data, nil
+}
+
+// archiveFixture 创建 /data 子树：a 由两个块和一个空洞组成，sub/b 内联保存
+func archiveFixture(t *testing.T) (*meta.MemoryStore, blockData) {
+	store := meta.NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/data", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/data/sub", 0750))
+
+	a, err := store.Create(ctx, "/data/a", 0644)
+	require.NoError(t, err)
+	a.Size = 12
+	a.Blocks = []meta.Block{
+		{ID: "a2", Offset: 6, Size: 3, Locations: []string{"d0", "d2"}},
+		{ID: "a1", Offset: 0, Size: 4, Locations: []string{"d1"}},
+	}
+	require.NoError(t, store.Update(ctx, "/data/a", a))
+	_, err = store.CreateWithData(ctx, "/data/sub/b", 0600, []byte("inline"))
+	require.NoError(t, err)
+
+	return store, blockData{"a1": []byte("abcd"), "a2": []byte("xyz")}
+}
+
+func TestArchiveTreeTar(t *testing.T) {
+	store, data := archiveFixture(t)
+	var buf bytes.Buffer
+	require.NoError(t, ArchiveTree(context.Background(), &buf, store, data, "/data", ArchiveOptions{Parallelism: 1}))
+
+	files := map[string]string{}
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(content)
+	}
+	assert.Equal(t, []string{"a", "sub/", "sub/b"}, names)
+	assert.Equal(t, "abcd\x00\x00xyz\x00\x00\x00", files["a"])
+	assert.Equal(t, "inline", files["sub/b"])
+
+	delete(data, "a2")
+	err := ArchiveTree(context.Background(), io.Discard, store, data, "/data", ArchiveOptions{})
+	assert.ErrorContains(t, err, "failed to read block a2")
+}
+
+func TestArchiveHandlerZip(t *testing.T) {
+	store, data := archiveFixture(t)
+	srv := httptest.NewServer(ArchiveHandler(store, data, ArchiveOptions{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?path=/data/sub&format=zip")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="sub.zip"`, resp.Header.Get("Content-Disposition"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 1)
+	assert.Equal(t, "b", zr.File[0].Name)
+	rc, err := zr.File[0].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "inline", string(content))
+
+	for query, code := range map[string]int{
+		"":                       http.StatusBadRequest,
+		"?path=/data&format=rar": http.StatusBadRequest,
+		"?path=/missing":         http.StatusNotFound,
+	} {
+		resp, err := http.Get(srv.URL + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, code, resp.StatusCode, query)
+	}
+}
@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"cpfs/internal/meta"
+)
+
+// ETag 返回元数据对应的强 ETag，由版本令牌加引号构成
+func ETag(m *meta.Metadata) string {
+	return `"` + meta.VersionToken(m) + `"`
+}
+
+// SetValidators 在响应中设置 ETag 和 Last-Modified
+func SetValidators(w http.ResponseWriter, m *meta.Metadata) {
+	w.Header().Set("ETag", ETag(m))
+	if !m.ModifyTime.IsZero() {
+		w.Header().Set("Last-Modified", m.ModifyTime.UTC().Format(http.TimeFormat))
+	}
+}
+
+// etagMatches 判断 If-Match/If-None-Match 头中的 ETag 列表是否包含 etag
+//
+// weak 为 true 时使用弱比较（忽略 W/ 前缀），用于 If-None-Match。
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPreconditions 按 RFC 7232 的顺序评估条件请求头
+//
+// 返回 0 表示继续处理请求，否则返回应直接响应的状态码（304 或 412）。
+// m 为 nil 表示目标不存在，此时 If-Match 失败而 If-None-Match: * 成立。
+func CheckPreconditions(r *http.Request, m *meta.Metadata) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if header := r.Header.Get("If-Match"); header != "" {
+		if m == nil || !etagMatches(header, ETag(m), false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if header := r.Header.Get("If-Unmodified-Since"); header != "" && m != nil {
+		if since, err := http.ParseTime(header); err == nil && modifiedAfter(m.ModifyTime, since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if m != nil && etagMatches(header, ETag(m), true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if header := r.Header.Get("If-Modified-Since"); header != "" && safe && m != nil {
+		if since, err := http.ParseTime(header); err == nil && !modifiedAfter(m.ModifyTime, since) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// modifiedAfter 以 HTTP 日期的秒级精度比较修改时间
+func modifiedAfter(modified, since time.Time) bool {
+	return modified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	m := &meta.Metadata{Inode: 10, Version: 2, ModifyTime: modified}
+	etag := ETag(m)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		target  *meta.Metadata
+		want    int
+	}{
+		{"no conditions", http.MethodGet, nil, m, 0},
+		{"if-match hit", http.MethodPut, map[string]string{"If-Match": etag}, m, 0},
+		{"if-match list", http.MethodPut, map[string]string{"If-Match": `"x", ` + etag}, m, 0},
+		{"if-match miss", http.MethodPut, map[string]string{"If-Match": `"a-1"`}, m, http.StatusPreconditionFailed},
+		{"if-match weak", http.MethodPut, map[string]string{"If-Match": "W/" + etag}, m, http.StatusPreconditionFailed},
+		{"if-match missing target", http.MethodPut, map[string]string{"If-Match": "*"}, nil, http.StatusPreconditionFailed},
+		{"if-none-match get", http.MethodGet, map[string]string{"If-None-Match": "W/" + etag}, m, http.StatusNotModified},
+		{"if-none-match put", http.MethodPut, map[string]string{"If-None-Match": "*"}, m, http.StatusPreconditionFailed},
+		{"if-none-match create", http.MethodPut, map[string]string{"If-None-Match": "*"}, nil, 0},
+		{"if-none-match miss", http.MethodGet, map[string]string{"If-None-Match": `"a-1"`}, m, 0},
+		{"if-modified-since unchanged", http.MethodGet, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, m, http.StatusNotModified},
+		{"if-modified-since changed", http.MethodGet, map[string]string{"If-Modified-Since": before}, m, 0},
+		{"if-modified-since ignored with etag", http.MethodGet, map[string]string{"If-Modified-Since": after, "If-None-Match": `"a-1"`}, m, 0},
+		{"if-unmodified-since fail", http.MethodPut, map[string]string{"If-Unmodified-Since": before}, m, http.StatusPreconditionFailed},
+		{"if-unmodified-since ok", http.MethodPut, map[string]string{"If-Unmodified-Since": after}, m, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/obj", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, CheckPreconditions(r, tt.target))
+		})
+	}
+}
+
+func TestSetValidators(t *testing.T) {
+	m := &meta.Metadata{Inode: 1, Version: 1, ModifyTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	w := httptest.NewRecorder()
+	SetValidators(w, m)
+	assert.Equal(t, `"1-1"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", w.Header().Get("Last-Modified"))
+}
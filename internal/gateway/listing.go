@@ -0,0 +1,282 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"cpfs/internal/meta"
+)
+
+const (
+	// DefaultMaxKeys 未指定 max-keys 时每页返回的条目数，与 S3 相同
+	DefaultMaxKeys = 1000
+	// MaxKeysLimit max-keys 的上限
+	MaxKeysLimit = 1000
+)
+
+// Lister 分页列举使用的元数据操作，由 meta.MemoryStore 实现
+//
+// 续传令牌编码上一页最后处理的路径，结果按路径排序，因此翻页期间的插入和删除
+// 不会让其余条目重复或遗漏。
+type Lister interface {
+	Get(ctx context.Context, path string) (*meta.Metadata, error)
+	ListPage(ctx context.Context, path string, opts meta.PageOptions) (*meta.Page, error)
+	Search(ctx context.Context, root string, opts meta.SearchOptions) (*meta.Page, error)
+}
+
+// parseMaxKeys 解析 max-keys 参数，为空时使用 DefaultMaxKeys，超过 MaxKeysLimit 时截断
+func parseMaxKeys(s string) (int, error) {
+	if s == "" {
+		return DefaultMaxKeys, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid max-keys: %q", s)
+	}
+	return min(n, MaxKeysLimit), nil
+}
+
+// ListEntry REST 列举结果中的一个条目
+type ListEntry struct {
+	Path       string        `json:"path"`
+	Name       string        `json:"name"`
+	Type       meta.FileType `json:"type"`
+	Size       int64         `json:"size"`
+	ModifyTime time.Time     `json:"modify_time"`
+	ETag       string        `json:"etag"`
+}
+
+// ListResult REST 列举结果
+type ListResult struct {
+	Entries []ListEntry `json:"entries"`
+	// NextContinuationToken 非空时传回 continuation-token 获取下一页
+	NextContinuationToken string `json:"next_continuation_token,omitempty"`
+	// IsTruncated 还有下一页；本页可能因服务端时间预算提前返回，条目数少于 max-keys
+	IsTruncated bool `json:"is_truncated"`
+}
+
+// ListHandler 返回分页列举目录的 REST 处理器
+//
+// GET ?path=/dir&max-keys=&continuation-token=；recursive=true 时列举整个子树，
+// 可用 name 指定名称的通配模式。
+func ListHandler(store Lister) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		dir := query.Get("path")
+		if dir == "" {
+			http.Error(w, "path is required", http.StatusBadRequest)
+			return
+		}
+		maxKeys, err := parseMaxKeys(query.Get("max-keys"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := query.Get("name")
+		if _, err := path.Match(name, ""); err != nil {
+			http.Error(w, fmt.Sprintf("invalid name pattern: %q", name), http.StatusBadRequest)
+			return
+		}
+
+		result := &ListResult{Entries: []ListEntry{}}
+		if maxKeys > 0 {
+			opts := meta.PageOptions{Token: query.Get("continuation-token"), Limit: maxKeys}
+			var page *meta.Page
+			if query.Get("recursive") == "true" {
+				page, err = store.Search(r.Context(), dir, meta.SearchOptions{PageOptions: opts, Name: name})
+			} else {
+				page, err = store.ListPage(r.Context(), dir, opts)
+			}
+			if err != nil {
+				status := http.StatusNotFound
+				if errors.Is(err, meta.ErrInvalidPageToken) {
+					status = http.StatusBadRequest
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+			for i, m := range page.Entries {
+				result.Entries = append(result.Entries, ListEntry{
+					Path: page.Paths[i], Name: m.Name, Type: m.Type, Size: m.Size, ModifyTime: m.ModifyTime, ETag: ETag(m),
+				})
+			}
+			result.NextContinuationToken = page.NextToken
+			result.IsTruncated = page.NextToken != ""
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	})
+}
+
+// ListObjectsV2Request S3 ListObjectsV2 请求参数
+type ListObjectsV2Request struct {
+	Prefix            string
+	Delimiter         string // 只支持 "/" 或空
+	MaxKeys           int
+	ContinuationToken string
+}
+
+// S3Object ListObjectsV2 结果中的对象
+type S3Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+	StorageClass string    `xml:"StorageClass"`
+}
+
+// S3CommonPrefix 使用分隔符时合并的公共前缀
+type S3CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// ListBucketResult S3 ListObjectsV2 响应
+type ListBucketResult struct {
+	XMLName               xml.Name         `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	Delimiter             string           `xml:"Delimiter,omitempty"`
+	MaxKeys               int              `xml:"MaxKeys"`
+	KeyCount              int              `xml:"KeyCount"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	Contents              []S3Object       `xml:"Contents"`
+	CommonPrefixes        []S3CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// ListObjectsV2 以 S3 语义分页列举 bucketRoot 下的对象，对象键为相对 bucketRoot 的路径
+//
+// 没有分隔符时递归列举前缀下的全部文件；分隔符为 "/" 时只列举前缀所在目录，子目录作为公共前缀返回。
+// 前缀所在目录不存在时返回空结果。
+func ListObjectsV2(ctx context.Context, store Lister, bucketRoot string, req ListObjectsV2Request) (*ListBucketResult, error) {
+	if req.Delimiter != "" && req.Delimiter != "/" {
+		return nil, fmt.Errorf("unsupported delimiter: %q", req.Delimiter)
+	}
+	root := path.Clean("/" + bucketRoot)
+	result := &ListBucketResult{
+		Name:              path.Base(root),
+		Prefix:            req.Prefix,
+		Delimiter:         req.Delimiter,
+		MaxKeys:           req.MaxKeys,
+		ContinuationToken: req.ContinuationToken,
+	}
+
+	// 前缀中最后一个 "/" 之前的部分是目录，之后的部分按名称前缀过滤
+	dirKey := ""
+	if i := strings.LastIndex(req.Prefix, "/"); i >= 0 {
+		dirKey = req.Prefix[:i]
+	}
+	dir := path.Join(root, dirKey)
+	if req.MaxKeys == 0 {
+		return result, nil
+	}
+	if md, err := store.Get(ctx, dir); err != nil || md.Type != meta.TypeDirectory {
+		return result, nil
+	}
+
+	opts := meta.PageOptions{Token: req.ContinuationToken, Limit: req.MaxKeys}
+	var page *meta.Page
+	var err error
+	if req.Delimiter == "" {
+		page, err = store.Search(ctx, dir, meta.SearchOptions{PageOptions: opts, Types: []meta.FileType{meta.TypeRegular}})
+	} else {
+		page, err = store.ListPage(ctx, dir, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range page.Entries {
+		key := strings.TrimPrefix(strings.TrimPrefix(page.Paths[i], root), "/")
+		if !strings.HasPrefix(key, req.Prefix) {
+			continue
+		}
+		switch m.Type {
+		case meta.TypeDirectory:
+			result.CommonPrefixes = append(result.CommonPrefixes, S3CommonPrefix{Prefix: key + "/"})
+		case meta.TypeRegular:
+			class := m.StorageClass
+			if class == "" {
+				class = "STANDARD"
+			}
+			result.Contents = append(result.Contents, S3Object{
+				Key: key, LastModified: m.ModifyTime.UTC(), ETag: ETag(m), Size: m.Size, StorageClass: class,
+			})
+		}
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+	result.NextContinuationToken = page.NextToken
+	result.IsTruncated = page.NextToken != ""
+	return result, nil
+}
+
+// s3Error S3 错误响应
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// writeS3Error 写出 S3 格式的错误响应
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(s3Error{Code: code, Message: message})
+}
+
+// ListObjectsV2Handler 返回 S3 ListObjectsV2 的 HTTP 处理器
+//
+// GET ?list-type=2&prefix=&delimiter=&max-keys=&continuation-token=，列举 bucketRoot 下的对象。
+func ListObjectsV2Handler(store Lister, bucketRoot string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "method not allowed")
+			return
+		}
+		query := r.URL.Query()
+		maxKeys, err := parseMaxKeys(query.Get("max-keys"))
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+			return
+		}
+		delimiter := query.Get("delimiter")
+		if delimiter != "" && delimiter != "/" {
+			writeS3Error(w, http.StatusBadRequest, "InvalidArgument", fmt.Sprintf("unsupported delimiter: %q", delimiter))
+			return
+		}
+		result, err := ListObjectsV2(r.Context(), store, bucketRoot, ListObjectsV2Request{
+			Prefix:            query.Get("prefix"),
+			Delimiter:         delimiter,
+			MaxKeys:           maxKeys,
+			ContinuationToken: query.Get("continuation-token"),
+		})
+		if err != nil {
+			if errors.Is(err, meta.ErrInvalidPageToken) {
+				writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "The continuation token provided is incorrect")
+				return
+			}
+			writeS3Error(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(xml.Header))
+		_ = xml.NewEncoder(w).Encode(result)
+	})
+}
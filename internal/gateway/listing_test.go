@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBucket 创建 /bucket 下的测试对象
+func newBucket(t *testing.T) *meta.MemoryStore {
+	ctx := context.Background()
+	store := meta.NewMemoryStore()
+	for _, dir := range []string{"/bucket", "/bucket/logs", "/bucket/logs/2024"} {
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+	}
+	for _, p := range []string{"/bucket/a.txt", "/bucket/b.txt", "/bucket/logs/app.log", "/bucket/logs/2024/jan.log"} {
+		_, err := store.Create(ctx, p, 0644)
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func TestListObjectsV2(t *testing.T) {
+	ctx := context.Background()
+	store := newBucket(t)
+
+	// 没有分隔符时递归列举，按键排序并分页
+	var keys []string
+	req := ListObjectsV2Request{MaxKeys: 2}
+	for {
+		result, err := ListObjectsV2(ctx, store, "/bucket", req)
+		require.NoError(t, err)
+		for _, obj := range result.Contents {
+			keys = append(keys, obj.Key)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		req.ContinuationToken = result.NextContinuationToken
+	}
+	assert.Equal(t, []string{"a.txt", "b.txt", "logs/2024/jan.log", "logs/app.log"}, keys)
+
+	result, err := ListObjectsV2(ctx, store, "/bucket", ListObjectsV2Request{Delimiter: "/", MaxKeys: 100})
+	require.NoError(t, err)
+	require.Len(t, result.Contents, 2)
+	assert.Equal(t, "STANDARD", result.Contents[0].StorageClass)
+	assert.Equal(t, []S3CommonPrefix{{Prefix: "logs/"}}, result.CommonPrefixes)
+	assert.Equal(t, 3, result.KeyCount)
+	assert.False(t, result.IsTruncated)
+
+	result, err = ListObjectsV2(ctx, store, "/bucket", ListObjectsV2Request{Prefix: "logs/a", Delimiter: "/", MaxKeys: 100})
+	require.NoError(t, err)
+	require.Len(t, result.Contents, 1)
+	assert.Equal(t, "logs/app.log", result.Contents[0].Key)
+	assert.Empty(t, result.CommonPrefixes)
+
+	result, err = ListObjectsV2(ctx, store, "/bucket", ListObjectsV2Request{Prefix: "missing/", MaxKeys: 100})
+	require.NoError(t, err)
+	assert.Zero(t, result.KeyCount)
+
+	_, err = ListObjectsV2(ctx, store, "/bucket", ListObjectsV2Request{Delimiter: "|", MaxKeys: 100})
+	assert.Error(t, err)
+}
+
+func TestListObjectsV2StableAcrossPages(t *testing.T) {
+	ctx := context.Background()
+	store := newBucket(t)
+
+	first, err := ListObjectsV2(ctx, store, "/bucket", ListObjectsV2Request{MaxKeys: 2})
+	require.NoError(t, err)
+	require.True(t, first.IsTruncated)
+
+	// 翻页之间的插入和删除不影响其余对象
+	require.NoError(t, store.Delete(ctx, "/bucket/logs/app.log"))
+	_, err = store.Create(ctx, "/bucket/0-early.txt", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/bucket/logs/b.log", 0644)
+	require.NoError(t, err)
+
+	second, err := ListObjectsV2(ctx, store, "/bucket", ListObjectsV2Request{MaxKeys: 10, ContinuationToken: first.NextContinuationToken})
+	require.NoError(t, err)
+	var keys []string
+	for _, obj := range second.Contents {
+		keys = append(keys, obj.Key)
+	}
+	assert.Equal(t, []string{"logs/2024/jan.log", "logs/b.log"}, keys)
+}
+
+func TestListObjectsV2Handler(t *testing.T) {
+	server := httptest.NewServer(ListObjectsV2Handler(newBucket(t), "/bucket"))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?list-type=2&max-keys=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var result ListBucketResult
+	require.NoError(t, xml.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "bucket", result.Name)
+	assert.True(t, result.IsTruncated)
+	require.Len(t, result.Contents, 1)
+	assert.Equal(t, "a.txt", result.Contents[0].Key)
+
+	for _, query := range []string{"max-keys=x", "delimiter=%7C", "continuation-token=!!"} {
+		resp, err := http.Get(server.URL + "?list-type=2&" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	server := httptest.NewServer(ListHandler(newBucket(t)))
+	defer server.Close()
+
+	get := func(query url.Values) (int, *ListResult) {
+		resp, err := http.Get(server.URL + "?" + query.Encode())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		var result ListResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, &result
+	}
+
+	var names []string
+	query := url.Values{"path": {"/bucket"}, "max-keys": {"2"}}
+	for {
+		code, result := get(query)
+		require.Equal(t, http.StatusOK, code)
+		for _, e := range result.Entries {
+			names = append(names, e.Name)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	assert.Equal(t, []string{"a.txt", "b.txt", "logs"}, names)
+
+	code, result := get(url.Values{"path": {"/bucket"}, "recursive": {"true"}, "name": {"*.log"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, result.Entries, 2)
+	assert.Equal(t, "/bucket/logs/2024/jan.log", result.Entries[0].Path)
+
+	code, _ = get(url.Values{"path": {"/missing"}})
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = get(url.Values{"path": {"/bucket"}, "continuation-token": {"!!"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get(url.Values{"path": {"/bucket"}, "name": {"["}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get(url.Values{})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
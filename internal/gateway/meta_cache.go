@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultFallbackTTL 没有失效订阅时缓存条目的过期时间
+	DefaultFallbackTTL = 5 * time.Second
+	// DefaultCallbackTTL 失效订阅正常时缓存条目的过期时间
+	DefaultCallbackTTL = 5 * time.Minute
+	// DefaultResubscribeInterval 订阅失败或中断后重新订阅的间隔
+	DefaultResubscribeInterval = time.Second
+)
+
+// Subscribe 订阅元数据变更，用于接收失效通知
+type Subscribe func(ctx context.Context) (*meta.Watcher, error)
+
+// MetaCacheOptions 网关元数据缓存配置
+type MetaCacheOptions struct {
+	// Size 缓存容量（字节），为 0 时不限制
+	Size int64
+	// FallbackTTL 没有失效订阅时的过期时间，决定其他客户端的修改最多多久不可见，为 0 时使用 DefaultFallbackTTL
+	FallbackTTL time.Duration
+	// CallbackTTL 失效订阅正常时的过期时间，为 0 时使用 DefaultCallbackTTL
+	CallbackTTL time.Duration
+	// ResubscribeInterval 订阅失败或中断后的重试间隔，为 0 时使用 DefaultResubscribeInterval
+	ResubscribeInterval time.Duration
+}
+
+// MetaCache 网关本地的元数据缓存
+//
+// S3、REST 和 WebDAV 网关的 HEAD 和条件请求通过它读取元数据，避免每次都访问元数据服务。
+// Run 订阅变更日志并按事件使条目失效，订阅正常时条目使用较长的 CallbackTTL；
+// 订阅不可用时退回较短的 FallbackTTL，修改最多在 FallbackTTL 内不可见。
+type MetaCache struct {
+	cache *meta.MetadataCache
+	opts  MetaCacheOptions
+
+	mu         sync.Mutex
+	subscribed bool
+	log        logger.Logger
+}
+
+// NewMetaCache 在 backend 之上创建网关元数据缓存，初始使用 FallbackTTL
+func NewMetaCache(backend meta.CacheBackend, opts MetaCacheOptions) *MetaCache {
+	if opts.FallbackTTL <= 0 {
+		opts.FallbackTTL = DefaultFallbackTTL
+	}
+	if opts.CallbackTTL <= 0 {
+		opts.CallbackTTL = DefaultCallbackTTL
+	}
+	if opts.ResubscribeInterval <= 0 {
+		opts.ResubscribeInterval = DefaultResubscribeInterval
+	}
+	return &MetaCache{
+		cache: meta.NewMetadataCache(backend, meta.CacheConfig{Size: opts.Size, TTL: opts.FallbackTTL}),
+		opts:  opts,
+		log:   logger.Default(),
+	}
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (c *MetaCache) SetLogger(l logger.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.log = logger.OrDefault(l)
+}
+
+// Get 获取元数据副本，优先从缓存返回
+func (c *MetaCache) Get(ctx context.Context, p string) (*meta.Metadata, error) {
+	return c.cache.Get(ctx, p)
+}
+
+// Subscribed 返回失效订阅当前是否正常
+func (c *MetaCache) Subscribed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscribed
+}
+
+// Stats 返回缓存运行统计
+func (c *MetaCache) Stats() meta.CacheStats {
+	return c.cache.Stats()
+}
+
+// Handle 按一条变更事件使缓存条目失效
+//
+// 目录的删除、重命名和交换使整个子树失效，其余事件只使涉及的路径失效。
+func (c *MetaCache) Handle(e meta.ChangeEvent) {
+	switch {
+	case e.Type == meta.ChangeRename || e.Type == meta.ChangeExchange:
+		c.cache.InvalidateTree(e.OldPath)
+		c.cache.InvalidateTree(e.Path)
+	case e.IsDir && e.Type == meta.ChangeDelete:
+		c.cache.InvalidateTree(e.Path)
+	default:
+		c.cache.Invalidate(e.Path)
+	}
+}
+
+// Run 订阅变更并处理失效通知，阻塞到 ctx 被取消
+//
+// 每次订阅成功都先清空缓存，因为订阅建立之前的修改没有收到通知；订阅中断时同样清空缓存
+// 并退回 FallbackTTL，然后按 ResubscribeInterval 重试。
+func (c *MetaCache) Run(ctx context.Context, subscribe Subscribe) {
+	for {
+		watcher, err := subscribe(ctx)
+		if err == nil {
+			c.setSubscribed(true)
+			for e := range watcher.Events {
+				c.Handle(e)
+			}
+			err = watcher.Err()
+			c.setSubscribed(false)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.mu.Lock()
+		log := c.log
+		c.mu.Unlock()
+		log.Warn("Metadata invalidation unavailable, falling back to TTL",
+			zap.Duration("ttl", c.opts.FallbackTTL), zap.Error(err))
+
+		select {
+		case <-time.After(c.opts.ResubscribeInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// setSubscribed 切换订阅状态，调整 TTL 并清空可能已过时的条目
+func (c *MetaCache) setSubscribed(ok bool) {
+	c.mu.Lock()
+	c.subscribed = ok
+	c.mu.Unlock()
+
+	if ok {
+		c.cache.SetTTL(c.opts.CallbackTTL)
+	} else {
+		c.cache.SetTTL(c.opts.FallbackTTL)
+	}
+	c.cache.InvalidateAll()
+}
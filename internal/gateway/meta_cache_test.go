@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaCacheInvalidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := newBucket(t)
+	cache := NewMetaCache(store, MetaCacheOptions{})
+
+	go cache.Run(ctx, func(ctx context.Context) (*meta.Watcher, error) {
+		return store.Changelog().Watch(ctx, "/", true, 0)
+	})
+	require.Eventually(t, cache.Subscribed, time.Second, 10*time.Millisecond)
+
+	m, err := cache.Get(ctx, "/bucket/a.txt")
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "/bucket/logs/app.log")
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.Stats().Entries)
+
+	// 绕过缓存的修改通过变更通知失效
+	m.Size = 42
+	require.NoError(t, store.Update(ctx, "/bucket/a.txt", m))
+	assert.Eventually(t, func() bool {
+		m, err := cache.Get(ctx, "/bucket/a.txt")
+		return err == nil && m.Size == 42
+	}, time.Second, 10*time.Millisecond)
+
+	// 目录重命名使整个子树失效
+	require.NoError(t, store.Rename(ctx, "/bucket/logs", "/bucket/old"))
+	assert.Eventually(t, func() bool {
+		_, err := cache.Get(ctx, "/bucket/logs/app.log")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMetaCacheFallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := newBucket(t)
+	cache := NewMetaCache(store, MetaCacheOptions{ResubscribeInterval: time.Millisecond})
+
+	attempts := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Run(ctx, func(ctx context.Context) (*meta.Watcher, error) {
+			select {
+			case attempts <- struct{}{}:
+			default:
+			}
+			return nil, errors.New("meta server unavailable")
+		})
+	}()
+
+	// 订阅失败时持续重试，缓存仍按较短的 TTL 工作
+	<-attempts
+	<-attempts
+	assert.False(t, cache.Subscribed())
+	_, err := cache.Get(ctx, "/bucket/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, 1, cache.Stats().Entries)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,408 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// multipartKeyPrefix 分段上传会话在存储中的键前缀
+	multipartKeyPrefix = "/multipart/"
+	// MaxPartNumber 最大分段编号
+	MaxPartNumber = 10000
+	// DefaultMinPartSize 除最后一段外每段的最小大小
+	DefaultMinPartSize = 5 << 20
+	// DefaultUploadTTL 未完成会话的默认保留时间
+	DefaultUploadTTL = 7 * 24 * time.Hour
+)
+
+// ObjectStore 分段上传完成时写入对象元数据所需的存储接口
+type ObjectStore interface {
+	CreateWithOptions(ctx context.Context, path string, mode os.FileMode, opts meta.CreateOptions) (*meta.Metadata, bool, error)
+	Update(ctx context.Context, path string, meta *meta.Metadata) error
+	GetBlockRange(ctx context.Context, path string, offset, length int64) ([]meta.Block, error)
+	Delete(ctx context.Context, path string) error
+}
+
+// Part 已上传的分段，块偏移相对于分段起始位置
+type Part struct {
+	Number   int          `json:"number"`
+	ETag     string       `json:"etag"`
+	Size     int64        `json:"size"`
+	Blocks   []meta.Block `json:"blocks"`
+	Uploaded time.Time    `json:"uploaded"`
+}
+
+// Upload 分段上传会话
+type Upload struct {
+	ID        string        `json:"id"`
+	Path      string        `json:"path"`
+	Initiated time.Time     `json:"initiated"`
+	Parts     map[int]*Part `json:"parts"`
+	// Replaced 被重新上传的分段覆盖的数据块，完成或取消上传时一并回收
+	Replaced []meta.Block `json:"replaced,omitempty"`
+}
+
+// CompletedPart 完成上传时客户端提交的分段清单项
+type CompletedPart struct {
+	Number int
+	ETag   string
+}
+
+// MultipartOptions 分段上传配置
+type MultipartOptions struct {
+	MinPartSize int64         // 除最后一段外每段的最小大小，为 0 时使用默认值
+	TTL         time.Duration // 未完成会话的保留时间，为 0 时使用默认值
+	Mode        os.FileMode   // 新建对象的权限
+	Logger      logger.Logger // 日志，为 nil 时使用全局日志
+}
+
+// MultipartManager 管理分段上传会话
+//
+// 分段数据由调用方直接写入数据服务器，这里只记录每段对应的数据块；
+// 完成上传时按分段顺序重排块偏移并一次性写入对象元数据，不复制任何数据。
+type MultipartManager struct {
+	storage meta.Storage
+	objects ObjectStore
+	options MultipartOptions
+	mu      sync.Mutex
+	log     logger.Logger
+}
+
+// NewMultipartManager 创建分段上传管理器
+func NewMultipartManager(storage meta.Storage, objects ObjectStore, options MultipartOptions) *MultipartManager {
+	if options.MinPartSize <= 0 {
+		options.MinPartSize = DefaultMinPartSize
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultUploadTTL
+	}
+	if options.Mode == 0 {
+		options.Mode = 0644
+	}
+	return &MultipartManager{
+		storage: storage,
+		objects: objects,
+		options: options,
+		log:     logger.OrDefault(options.Logger),
+	}
+}
+
+// uploadKey 返回会话在存储中的键
+func uploadKey(id string) string {
+	return multipartKeyPrefix + id
+}
+
+// newUploadID 生成随机会话ID
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate upload id: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// partETag 根据分段的数据块计算 ETag
+func partETag(blocks []meta.Block) string {
+	h := sha256.New()
+	for _, b := range blocks {
+		fmt.Fprintf(h, "%s|%d|%d|%s\n", b.ID, b.Offset, b.Size, b.Checksum)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// load 从存储加载会话，调用方需持有 mu
+func (m *MultipartManager) load(ctx context.Context, id string) (*Upload, error) {
+	data, err := m.storage.Load(ctx, uploadKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("upload not found: %s", id)
+	}
+	upload := &Upload{}
+	if err := json.Unmarshal(data, upload); err != nil {
+		return nil, fmt.Errorf("failed to decode upload %s: %v", id, err)
+	}
+	if upload.Parts == nil {
+		upload.Parts = make(map[int]*Part)
+	}
+	return upload, nil
+}
+
+// save 持久化会话，调用方需持有 mu
+func (m *MultipartManager) save(ctx context.Context, upload *Upload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	if err := m.storage.Save(ctx, uploadKey(upload.ID), data); err != nil {
+		return fmt.Errorf("failed to save upload: %v", err)
+	}
+	return nil
+}
+
+// Initiate 创建分段上传会话
+func (m *MultipartManager) Initiate(ctx context.Context, path string) (*Upload, error) {
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload := &Upload{
+		ID:        id,
+		Path:      path,
+		Initiated: time.Now(),
+		Parts:     make(map[int]*Part),
+	}
+	if err := m.save(ctx, upload); err != nil {
+		return nil, err
+	}
+
+	m.log.Info("Initiated multipart upload",
+		zap.String("upload_id", id),
+		zap.String("path", path),
+	)
+	return upload, nil
+}
+
+// UploadPart 记录一个分段对应的数据块并返回分段 ETag，相同编号的分段会被覆盖
+//
+// 被覆盖的分段中不再引用的数据块记入会话，在完成、取消或过期时随其他未使用的数据块一起返回。
+func (m *MultipartManager) UploadPart(ctx context.Context, id string, number int, blocks []meta.Block) (string, error) {
+	if number < 1 || number > MaxPartNumber {
+		return "", fmt.Errorf("invalid part number: %d", number)
+	}
+
+	var size int64
+	for _, b := range blocks {
+		if b.Offset != size {
+			return "", fmt.Errorf("part %d blocks are not contiguous at offset %d", number, size)
+		}
+		size += b.Size
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, err := m.load(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	part := &Part{
+		Number:   number,
+		ETag:     partETag(blocks),
+		Size:     size,
+		Blocks:   blocks,
+		Uploaded: time.Now(),
+	}
+	if previous, ok := upload.Parts[number]; ok {
+		upload.Replaced = append(upload.Replaced, unreferenced(previous.Blocks, blocks)...)
+	}
+	upload.Parts[number] = part
+	if err := m.save(ctx, upload); err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
+
+// unreferenced 返回 blocks 中ID不在 keep 中的数据块
+func unreferenced(blocks, keep []meta.Block) []meta.Block {
+	kept := make(map[string]bool, len(keep))
+	for _, b := range keep {
+		kept[b.ID] = true
+	}
+	var result []meta.Block
+	for _, b := range blocks {
+		if !kept[b.ID] {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// Complete 按清单拼接分段并写入对象元数据，返回对象元数据和需要回收的数据块
+//
+// 需要回收的数据块包括清单之外的分段、被重新上传覆盖的分段以及被替换的同名对象原有的数据块。
+// 写入对象元数据失败时删除本次新建的对象，已有对象保持不变。
+func (m *MultipartManager) Complete(ctx context.Context, id string, parts []CompletedPart) (*meta.Metadata, []meta.Block, error) {
+	if len(parts) == 0 {
+		return nil, nil, fmt.Errorf("no parts to complete")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, err := m.load(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var blocks []meta.Block
+	var offset int64
+	used := make(map[int]bool, len(parts))
+	for i, cp := range parts {
+		if i > 0 && cp.Number <= parts[i-1].Number {
+			return nil, nil, fmt.Errorf("parts must be in ascending order")
+		}
+		part, ok := upload.Parts[cp.Number]
+		if !ok || strings.Trim(cp.ETag, `"`) != strings.Trim(part.ETag, `"`) {
+			return nil, nil, fmt.Errorf("invalid part: %d", cp.Number)
+		}
+		if i < len(parts)-1 && part.Size < m.options.MinPartSize {
+			return nil, nil, fmt.Errorf("part %d is smaller than the minimum part size", cp.Number)
+		}
+
+		for _, b := range part.Blocks {
+			b.Offset += offset
+			blocks = append(blocks, b)
+		}
+		offset += part.Size
+		used[cp.Number] = true
+	}
+
+	obj, created, err := m.objects.CreateWithOptions(ctx, upload.Path, m.options.Mode, meta.CreateOptions{Parents: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create object: %v", err)
+	}
+	var replaced []meta.Block
+	if !created {
+		if replaced, err = m.objects.GetBlockRange(ctx, upload.Path, 0, 0); err != nil {
+			return nil, nil, fmt.Errorf("failed to read existing object: %v", err)
+		}
+	}
+	obj.Blocks = blocks
+	obj.Extents = nil
+	obj.BlockCount = 0
+	obj.InlineData = nil
+	obj.Size = offset
+	if err := m.objects.Update(ctx, upload.Path, obj); err != nil {
+		if created {
+			if delErr := m.objects.Delete(ctx, upload.Path); delErr != nil {
+				m.log.Error("Failed to remove object after failed multipart completion",
+					zap.String("upload_id", id),
+					zap.String("path", upload.Path),
+					zap.Error(delErr),
+				)
+			}
+		}
+		return nil, nil, fmt.Errorf("failed to update object: %v", err)
+	}
+
+	orphaned := unreferenced(replaced, blocks)
+	for number, part := range upload.Parts {
+		if !used[number] {
+			orphaned = append(orphaned, part.Blocks...)
+		}
+	}
+	orphaned = append(orphaned, upload.Replaced...)
+	if err := m.storage.Delete(ctx, uploadKey(id)); err != nil {
+		return nil, nil, fmt.Errorf("failed to delete upload: %v", err)
+	}
+
+	m.log.Info("Completed multipart upload",
+		zap.String("upload_id", id),
+		zap.String("path", upload.Path),
+		zap.Int("parts", len(parts)),
+		zap.Int64("size", offset),
+	)
+	return obj, orphaned, nil
+}
+
+// Abort 取消上传会话，返回已上传分段的数据块以便回收
+func (m *MultipartManager) Abort(ctx context.Context, id string) ([]meta.Block, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	upload, err := m.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.storage.Delete(ctx, uploadKey(id)); err != nil {
+		return nil, fmt.Errorf("failed to delete upload: %v", err)
+	}
+	return upload.blocks(), nil
+}
+
+// blocks 返回会话中全部分段以及被覆盖分段的数据块
+func (u *Upload) blocks() []meta.Block {
+	numbers := make([]int, 0, len(u.Parts))
+	for number := range u.Parts {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	var blocks []meta.Block
+	for _, number := range numbers {
+		blocks = append(blocks, u.Parts[number].Blocks...)
+	}
+	return append(blocks, u.Replaced...)
+}
+
+// ListUploads 返回所有未完成的上传会话
+func (m *MultipartManager) ListUploads(ctx context.Context) ([]*Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys, err := m.storage.List(ctx, multipartKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %v", err)
+	}
+
+	uploads := make([]*Upload, 0, len(keys))
+	for _, key := range keys {
+		upload, err := m.load(ctx, strings.TrimPrefix(key, multipartKeyPrefix))
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+	return uploads, nil
+}
+
+// ExpireStale 删除发起时间早于 now-TTL 的会话，返回被删除的会话ID和需要回收的数据块
+func (m *MultipartManager) ExpireStale(ctx context.Context, now time.Time) ([]string, []meta.Block, error) {
+	uploads, err := m.ListUploads(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var expired []string
+	var blocks []meta.Block
+	for _, upload := range uploads {
+		if now.Sub(upload.Initiated) < m.options.TTL {
+			continue
+		}
+		abandoned, err := m.Abort(ctx, upload.ID)
+		if err != nil {
+			return expired, blocks, err
+		}
+		expired = append(expired, upload.ID)
+		blocks = append(blocks, abandoned...)
+	}
+
+	if len(expired) > 0 {
+		m.log.Info("Expired stale multipart uploads",
+			zap.Strings("upload_ids", expired),
+		)
+	}
+	return expired, blocks, nil
+}
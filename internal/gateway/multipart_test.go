@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMultipart(t *testing.T, options MultipartOptions) (*MultipartManager, *meta.FileStorage, *meta.MemoryStore) {
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{
+		RootDir:      t.TempDir(),
+		SyncInterval: time.Second,
+		FileMode:     0600,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+
+	store := meta.NewMemoryStore()
+	require.NoError(t, store.Mkdir(context.Background(), "/bucket", 0755))
+	return NewMultipartManager(storage, store, options), storage, store
+}
+
+func partBlocks(prefix string, sizes ...int64) []meta.Block {
+	var blocks []meta.Block
+	var offset int64
+	for i, size := range sizes {
+		blocks = append(blocks, meta.Block{
+			ID:        prefix + string(rune('a'+i)),
+			Size:      size,
+			Offset:    offset,
+			Checksum:  "sum",
+			Locations: []string{"ds1"},
+		})
+		offset += size
+	}
+	return blocks
+}
+
+func TestMultipartComplete(t *testing.T) {
+	ctx := context.Background()
+	mgr, storage, store := newTestMultipart(t, MultipartOptions{MinPartSize: 100})
+
+	upload, err := mgr.Initiate(ctx, "/bucket/obj")
+	require.NoError(t, err)
+
+	etag1, err := mgr.UploadPart(ctx, upload.ID, 1, partBlocks("p1", 60, 60))
+	require.NoError(t, err)
+	etag2, err := mgr.UploadPart(ctx, upload.ID, 2, partBlocks("p2", 30))
+	require.NoError(t, err)
+	_, err = mgr.UploadPart(ctx, upload.ID, 3, partBlocks("p3", 10))
+	require.NoError(t, err)
+
+	obj, orphaned, err := mgr.Complete(ctx, upload.ID, []CompletedPart{
+		{Number: 1, ETag: etag1},
+		{Number: 2, ETag: etag2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), obj.Size)
+	assert.Equal(t, partBlocks("p3", 10), orphaned)
+
+	stored, err := store.Get(ctx, "/bucket/obj")
+	require.NoError(t, err)
+	require.Len(t, stored.Blocks, 3)
+	assert.Equal(t, "p2a", stored.Blocks[2].ID)
+	assert.Equal(t, int64(120), stored.Blocks[2].Offset)
+
+	keys, err := storage.List(ctx, multipartKeyPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestMultipartCompleteValidation(t *testing.T) {
+	ctx := context.Background()
+	mgr, _, _ := newTestMultipart(t, MultipartOptions{MinPartSize: 100})
+
+	upload, err := mgr.Initiate(ctx, "/bucket/obj")
+	require.NoError(t, err)
+
+	_, err = mgr.UploadPart(ctx, upload.ID, 0, nil)
+	assert.Error(t, err)
+	_, err = mgr.UploadPart(ctx, upload.ID, 1, []meta.Block{{ID: "x", Offset: 5, Size: 10}})
+	assert.Error(t, err)
+
+	etag1, err := mgr.UploadPart(ctx, upload.ID, 1, partBlocks("p1", 50))
+	require.NoError(t, err)
+	etag2, err := mgr.UploadPart(ctx, upload.ID, 2, partBlocks("p2", 50))
+	require.NoError(t, err)
+
+	_, _, err = mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 1, ETag: etag1}, {Number: 2, ETag: etag2}})
+	assert.Error(t, err, "part below minimum size")
+	_, _, err = mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 2, ETag: etag2}, {Number: 1, ETag: etag1}})
+	assert.Error(t, err, "parts out of order")
+	_, _, err = mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 1, ETag: `"bogus"`}})
+	assert.Error(t, err, "etag mismatch")
+
+	obj, _, err := mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 2, ETag: etag2}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), obj.Size)
+
+	_, _, err = mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 2, ETag: etag2}})
+	assert.Error(t, err, "upload already completed")
+}
+
+func TestMultipartAbortAndExpire(t *testing.T) {
+	ctx := context.Background()
+	mgr, _, _ := newTestMultipart(t, MultipartOptions{TTL: time.Hour})
+
+	first, err := mgr.Initiate(ctx, "/bucket/a")
+	require.NoError(t, err)
+	_, err = mgr.UploadPart(ctx, first.ID, 2, partBlocks("a2", 10))
+	require.NoError(t, err)
+	_, err = mgr.UploadPart(ctx, first.ID, 1, partBlocks("a1", 10))
+	require.NoError(t, err)
+
+	blocks, err := mgr.Abort(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, append(partBlocks("a1", 10), partBlocks("a2", 10)...), blocks)
+	_, err = mgr.Abort(ctx, first.ID)
+	assert.Error(t, err)
+
+	second, err := mgr.Initiate(ctx, "/bucket/b")
+	require.NoError(t, err)
+	_, err = mgr.UploadPart(ctx, second.ID, 1, partBlocks("b1", 10))
+	require.NoError(t, err)
+
+	expired, _, err := mgr.ExpireStale(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	expired, blocks, err = mgr.ExpireStale(ctx, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{second.ID}, expired)
+	assert.Equal(t, partBlocks("b1", 10), blocks)
+
+	uploads, err := mgr.ListUploads(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, uploads)
+}
+
+func TestMultipartReplacedParts(t *testing.T) {
+	ctx := context.Background()
+	mgr, _, _ := newTestMultipart(t, MultipartOptions{MinPartSize: 100})
+
+	upload, err := mgr.Initiate(ctx, "/bucket/obj")
+	require.NoError(t, err)
+	_, err = mgr.UploadPart(ctx, upload.ID, 1, partBlocks("old", 60, 60))
+	require.NoError(t, err)
+	// 重试时沿用第一个数据块，只有第二个数据块被替换
+	retry := partBlocks("old", 60)
+	retry = append(retry, meta.Block{ID: "newb", Size: 60, Offset: 60, Checksum: "sum", Locations: []string{"ds1"}})
+	etag, err := mgr.UploadPart(ctx, upload.ID, 1, retry)
+	require.NoError(t, err)
+
+	_, orphaned, err := mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 1, ETag: etag}})
+	require.NoError(t, err)
+	require.Len(t, orphaned, 1)
+	assert.Equal(t, "oldb", orphaned[0].ID)
+
+	upload, err = mgr.Initiate(ctx, "/bucket/other")
+	require.NoError(t, err)
+	_, err = mgr.UploadPart(ctx, upload.ID, 1, partBlocks("x", 10))
+	require.NoError(t, err)
+	_, err = mgr.UploadPart(ctx, upload.ID, 1, partBlocks("y", 10))
+	require.NoError(t, err)
+	blocks, err := mgr.Abort(ctx, upload.ID)
+	require.NoError(t, err)
+	assert.Equal(t, append(partBlocks("y", 10), partBlocks("x", 10)...), blocks)
+}
+
+func TestMultipartCompleteReplacesObject(t *testing.T) {
+	ctx := context.Background()
+	mgr, _, store := newTestMultipart(t, MultipartOptions{MinPartSize: 100})
+
+	existing, _, err := store.CreateWithOptions(ctx, "/bucket/obj", 0644, meta.CreateOptions{})
+	require.NoError(t, err)
+	existing.Blocks = partBlocks("prev", 40)
+	existing.Size = 40
+	require.NoError(t, store.Update(ctx, "/bucket/obj", existing))
+
+	upload, err := mgr.Initiate(ctx, "/bucket/obj")
+	require.NoError(t, err)
+	etag, err := mgr.UploadPart(ctx, upload.ID, 1, partBlocks("p1", 10))
+	require.NoError(t, err)
+
+	obj, orphaned, err := mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 1, ETag: etag}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), obj.Size)
+	require.Len(t, orphaned, 1)
+	assert.Equal(t, "preva", orphaned[0].ID)
+}
+
+// failingUpdateStore 写入对象元数据总是失败的对象存储
+type failingUpdateStore struct {
+	*meta.MemoryStore
+}
+
+func (s failingUpdateStore) Update(ctx context.Context, path string, metadata *meta.Metadata) error {
+	return assert.AnError
+}
+
+func TestMultipartCompleteRollback(t *testing.T) {
+	ctx := context.Background()
+	_, storage, store := newTestMultipart(t, MultipartOptions{})
+	mgr := NewMultipartManager(storage, failingUpdateStore{store}, MultipartOptions{MinPartSize: 100})
+
+	upload, err := mgr.Initiate(ctx, "/bucket/obj")
+	require.NoError(t, err)
+	etag, err := mgr.UploadPart(ctx, upload.ID, 1, partBlocks("p1", 10))
+	require.NoError(t, err)
+
+	_, _, err = mgr.Complete(ctx, upload.ID, []CompletedPart{{Number: 1, ETag: etag}})
+	require.Error(t, err)
+	_, err = store.Get(ctx, "/bucket/obj")
+	assert.Error(t, err, "newly created object should be removed")
+
+	// 会话仍然保留，数据块可以通过取消上传回收
+	blocks, err := mgr.Abort(ctx, upload.ID)
+	require.NoError(t, err)
+	assert.Equal(t, partBlocks("p1", 10), blocks)
+}
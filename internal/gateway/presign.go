@@ -0,0 +1,220 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SigV4 预签名相关常量
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4Terminator  = "aws4_request"
+	sigV4DateFormat  = "20060102T150405Z"
+	sigV4ScopeFormat = "20060102"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+
+	// MaxPresignExpiry 预签名URL的最长有效期
+	MaxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// 预签名查询参数名
+const (
+	amzAlgorithm     = "X-Amz-Algorithm"
+	amzCredential    = "X-Amz-Credential"
+	amzDate          = "X-Amz-Date"
+	amzExpires       = "X-Amz-Expires"
+	amzSignedHeaders = "X-Amz-SignedHeaders"
+	amzSignature     = "X-Amz-Signature"
+)
+
+var (
+	// ErrPresignExpired 预签名URL已过期
+	ErrPresignExpired = errors.New("presigned url expired")
+	// ErrSignatureMismatch 签名校验失败
+	ErrSignatureMismatch = errors.New("signature does not match")
+)
+
+// CredentialStore 根据访问密钥ID查找私有密钥
+type CredentialStore interface {
+	SecretKey(accessKey string) (string, bool)
+}
+
+// StaticCredentials 固定的访问密钥表，键为访问密钥ID，值为私有密钥
+type StaticCredentials map[string]string
+
+// SecretKey 实现 CredentialStore
+func (c StaticCredentials) SecretKey(accessKey string) (string, bool) {
+	secret, ok := c[accessKey]
+	return secret, ok
+}
+
+// Presigner 生成和校验 SigV4 预签名URL
+type Presigner struct {
+	Region      string
+	Service     string
+	Credentials CredentialStore
+	Now         func() time.Time // 测试时替换当前时间，为 nil 时使用 time.Now
+}
+
+// NewPresigner 创建 S3 服务的预签名器
+func NewPresigner(region string, credentials CredentialStore) *Presigner {
+	return &Presigner{
+		Region:      region,
+		Service:     "s3",
+		Credentials: credentials,
+	}
+}
+
+// now 返回当前时间
+func (p *Presigner) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+// Presign 为对象生成有效期为 expires 的预签名URL，只签名 host 头，负载不参与签名
+func (p *Presigner) Presign(method, endpoint, objectPath, accessKey string, expires time.Duration) (string, error) {
+	if expires <= 0 || expires > MaxPresignExpiry {
+		return "", fmt.Errorf("invalid expiry: %v", expires)
+	}
+	secret, ok := p.Credentials.SecretKey(accessKey)
+	if !ok {
+		return "", fmt.Errorf("unknown access key: %s", accessKey)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint: %v", err)
+	}
+	u.Path = "/" + strings.TrimPrefix(objectPath, "/")
+
+	now := p.now().UTC()
+	query := url.Values{}
+	query.Set(amzAlgorithm, sigV4Algorithm)
+	query.Set(amzCredential, accessKey+"/"+p.scope(now))
+	query.Set(amzDate, now.Format(sigV4DateFormat))
+	query.Set(amzExpires, strconv.Itoa(int(expires/time.Second)))
+	query.Set(amzSignedHeaders, "host")
+
+	signature := p.signature(secret, now, method, u.Host, u.Path, query)
+	u.RawQuery = canonicalQuery(query) + "&" + amzSignature + "=" + signature
+	return u.String(), nil
+}
+
+// Verify 校验请求携带的预签名参数，成功时返回访问密钥ID
+func (p *Presigner) Verify(r *http.Request) (string, error) {
+	query := r.URL.Query()
+	if query.Get(amzAlgorithm) != sigV4Algorithm {
+		return "", fmt.Errorf("unsupported signature algorithm: %q", query.Get(amzAlgorithm))
+	}
+	if !strings.EqualFold(query.Get(amzSignedHeaders), "host") {
+		return "", fmt.Errorf("unsupported signed headers: %q", query.Get(amzSignedHeaders))
+	}
+
+	signed, err := time.Parse(sigV4DateFormat, query.Get(amzDate))
+	if err != nil {
+		return "", fmt.Errorf("invalid %s: %v", amzDate, err)
+	}
+	seconds, err := strconv.Atoi(query.Get(amzExpires))
+	if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > MaxPresignExpiry {
+		return "", fmt.Errorf("invalid %s: %q", amzExpires, query.Get(amzExpires))
+	}
+	if p.now().After(signed.Add(time.Duration(seconds) * time.Second)) {
+		return "", ErrPresignExpired
+	}
+
+	accessKey, scope, ok := strings.Cut(query.Get(amzCredential), "/")
+	if !ok || scope != p.scope(signed) {
+		return "", fmt.Errorf("invalid credential scope: %q", query.Get(amzCredential))
+	}
+	secret, ok := p.Credentials.SecretKey(accessKey)
+	if !ok {
+		return "", fmt.Errorf("unknown access key: %s", accessKey)
+	}
+
+	provided := query.Get(amzSignature)
+	query.Del(amzSignature)
+	expected := p.signature(secret, signed, r.Method, r.Host, r.URL.Path, query)
+	if !hmac.Equal([]byte(provided), []byte(expected)) {
+		return "", ErrSignatureMismatch
+	}
+	return accessKey, nil
+}
+
+// scope 返回签名范围 date/region/service/aws4_request
+func (p *Presigner) scope(t time.Time) string {
+	return strings.Join([]string{t.Format(sigV4ScopeFormat), p.Region, p.Service, sigV4Terminator}, "/")
+}
+
+// signature 计算预签名请求的签名，path 为解码后的请求路径
+func (p *Presigner) signature(secret string, t time.Time, method, host, path string, query url.Values) string {
+	canonicalRequest := strings.Join([]string{
+		method,
+		sigV4Escape(path, true),
+		canonicalQuery(query),
+		"host:" + strings.TrimSpace(host) + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	digest := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		t.Format(sigV4DateFormat),
+		p.scope(t),
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secret), t.Format(sigV4ScopeFormat))
+	key = hmacSHA256(key, p.Region)
+	key = hmacSHA256(key, p.Service)
+	key = hmacSHA256(key, sigV4Terminator)
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hmacSHA256 计算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery 按 SigV4 规则排序并编码查询参数
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, sigV4Escape(key, false)+"="+sigV4Escape(value, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// sigV4Escape 对除非保留字符外的所有字节进行百分号编码，keepSlash 为 true 时保留 '/'
+func sigV4Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cpfs/internal/meta"
+)
+
+// ErrRangeNotSatisfiable 请求范围超出对象大小，对应 416
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// BlockRanger 按字节范围读取块映射
+type BlockRanger interface {
+	GetBlockRange(ctx context.Context, path string, offset, length int64) ([]meta.Block, error)
+}
+
+// ParseRange 解析单个字节范围的 Range 头，返回起始偏移和长度
+//
+// 支持 bytes=a-b、bytes=a- 和 bytes=-n 三种形式，不支持多范围。
+// header 为空时 ok 为 false，表示读取整个对象。
+func ParseRange(header string, size int64) (offset, length int64, ok bool, err error) {
+	if header == "" {
+		return 0, size, false, nil
+	}
+
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, fmt.Errorf("unsupported range: %q", header)
+	}
+	startStr, endStr, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, fmt.Errorf("invalid range: %q", header)
+	}
+
+	if startStr == "" {
+		// 后缀范围：最后 n 个字节
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, fmt.Errorf("invalid range: %q", header)
+		}
+		if size == 0 {
+			return 0, 0, false, ErrRangeNotSatisfiable
+		}
+		n = min(n, size)
+		return size - n, n, true, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, fmt.Errorf("invalid range: %q", header)
+	}
+	if start >= size {
+		return 0, 0, false, ErrRangeNotSatisfiable
+	}
+
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, fmt.Errorf("invalid range: %q", header)
+		}
+		end = min(end, size-1)
+	}
+	return start, end - start + 1, true, nil
+}
+
+// ContentRange 返回 Content-Range 响应头的值
+func ContentRange(offset, length, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size)
+}
+
+// RangeRead 一次范围读取的解析结果
+type RangeRead struct {
+	Offset  int64        // 起始偏移
+	Length  int64        // 读取长度
+	Partial bool         // 是否为部分读取（206）
+	Blocks  []meta.Block // 覆盖该范围的数据块
+}
+
+// ResolveRange 将请求的 Range 头转换为块范围读取
+//
+// 范围不可满足时返回 ErrRangeNotSatisfiable，调用方应响应 416 并设置 Content-Range: bytes */size。
+func ResolveRange(ctx context.Context, store BlockRanger, r *http.Request, p string, m *meta.Metadata) (*RangeRead, error) {
+	offset, length, partial, err := ParseRange(r.Header.Get("Range"), m.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	read := &RangeRead{Offset: offset, Length: length, Partial: partial}
+	if length == 0 {
+		return read, nil
+	}
+	read.Blocks, err = store.GetBlockRange(ctx, p, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return read, nil
+}
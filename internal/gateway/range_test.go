@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		offset  int64
+		length  int64
+		partial bool
+		err     bool
+	}{
+		{"", 0, 1000, false, false},
+		{"bytes=0-99", 0, 100, true, false},
+		{"bytes=900-", 900, 100, true, false},
+		{"bytes=990-2000", 990, 10, true, false},
+		{"bytes=-10", 990, 10, true, false},
+		{"bytes=-5000", 0, 1000, true, false},
+		{"bytes=1000-", 0, 0, false, true},
+		{"bytes=5-1", 0, 0, false, true},
+		{"bytes=0-1,5-6", 0, 0, false, true},
+		{"items=0-1", 0, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		offset, length, partial, err := ParseRange(tt.header, 1000)
+		if tt.err {
+			assert.Error(t, err, tt.header)
+			continue
+		}
+		require.NoError(t, err, tt.header)
+		assert.Equal(t, tt.offset, offset, tt.header)
+		assert.Equal(t, tt.length, length, tt.header)
+		assert.Equal(t, tt.partial, partial, tt.header)
+	}
+
+	_, _, _, err := ParseRange("bytes=1000-", 1000)
+	assert.ErrorIs(t, err, ErrRangeNotSatisfiable)
+	assert.Equal(t, "bytes 0-99/1000", ContentRange(0, 100, 1000))
+}
+
+func TestResolveRange(t *testing.T) {
+	store := meta.NewMemoryStore()
+	ctx := context.Background()
+
+	m, err := store.Create(ctx, "/obj", 0644)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		m.Blocks = append(m.Blocks, meta.Block{ID: "b" + string(rune('0'+i)), Size: 100, Offset: int64(i) * 100})
+	}
+	m.Size = 400
+	require.NoError(t, store.Update(ctx, "/obj", m))
+
+	r := httptest.NewRequest("GET", "/obj", nil)
+	r.Header.Set("Range", "bytes=150-250")
+	read, err := ResolveRange(ctx, store, r, "/obj", m)
+	require.NoError(t, err)
+	assert.True(t, read.Partial)
+	assert.Equal(t, int64(150), read.Offset)
+	assert.Equal(t, int64(101), read.Length)
+	require.Len(t, read.Blocks, 2)
+	assert.Equal(t, "b1", read.Blocks[0].ID)
+	assert.Equal(t, "b2", read.Blocks[1].ID)
+
+	r.Header.Set("Range", "bytes=400-")
+	_, err = ResolveRange(ctx, store, r, "/obj", m)
+	assert.ErrorIs(t, err, ErrRangeNotSatisfiable)
+}
@@ -0,0 +1,383 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// jobKeyPrefix 作业记录在存储中的键前缀
+	jobKeyPrefix = "/jobs/"
+	// DefaultProgressInterval 运行中作业进度写回存储的最小间隔
+	DefaultProgressInterval = time.Second
+)
+
+// ErrJobNotFound 作业不存在
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobFinished 作业已结束，无法取消
+var ErrJobFinished = errors.New("job already finished")
+
+// State 作业状态
+type State string
+
+const (
+	StateRunning   State = "running"   // 运行中
+	StateSucceeded State = "succeeded" // 成功结束
+	StateFailed    State = "failed"    // 失败结束
+	StateCanceled  State = "canceled"  // 被取消
+)
+
+// Finished 判断状态是否为终态
+func (s State) Finished() bool {
+	return s != StateRunning
+}
+
+// Progress 作业进度，Total 为 0 表示总量未知
+type Progress struct {
+	Done    int64  `json:"done"`
+	Total   int64  `json:"total"`
+	Message string `json:"message,omitempty"`
+}
+
+// Job 作业记录
+type Job struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`
+	Params   map[string]string `json:"params,omitempty"`
+	User     string            `json:"user,omitempty"`
+	State    State             `json:"state"`
+	Progress Progress          `json:"progress"`
+	Error    string            `json:"error,omitempty"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+}
+
+// clone 返回作业记录的副本
+func (j *Job) clone() *Job {
+	c := *j
+	if j.Params != nil {
+		c.Params = make(map[string]string, len(j.Params))
+		for k, v := range j.Params {
+			c.Params[k] = v
+		}
+	}
+	return &c
+}
+
+// Func 作业执行函数，应在 ctx 取消时尽快返回，并通过 report 上报进度
+type Func func(ctx context.Context, report func(Progress)) error
+
+// running 运行中的作业
+type running struct {
+	job    *Job
+	cancel context.CancelFunc
+	done   chan struct{}
+	saved  time.Time
+}
+
+// Manager 长时间运行作业的管理器
+//
+// 重平衡、子树删除、导入、预热和迁移等后台任务通过 Submit 提交，
+// 作业记录以 JSON 持久化到存储，进程重启后仍可查询历史。
+type Manager struct {
+	storage meta.Storage
+	mu      sync.Mutex
+	running map[string]*running
+
+	// ProgressInterval 运行中作业进度写回存储的最小间隔
+	ProgressInterval time.Duration
+	now              func() time.Time
+	log              logger.Logger
+}
+
+// NewManager 创建作业管理器
+func NewManager(storage meta.Storage) *Manager {
+	return &Manager{
+		storage:          storage,
+		running:          make(map[string]*running),
+		ProgressInterval: DefaultProgressInterval,
+		now:              time.Now,
+		log:              logger.Default(),
+	}
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (m *Manager) SetLogger(l logger.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.log = logger.OrDefault(l)
+}
+
+// jobKey 返回作业在存储中的键
+func jobKey(id string) string {
+	return jobKeyPrefix + id
+}
+
+// newJobID 生成随机作业ID
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// save 持久化作业记录
+func (m *Manager) save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := m.storage.Save(ctx, jobKey(job.ID), data); err != nil {
+		return fmt.Errorf("failed to save job %s: %v", job.ID, err)
+	}
+	return nil
+}
+
+// load 从存储加载作业记录
+func (m *Manager) load(ctx context.Context, id string) (*Job, error) {
+	data, err := m.storage.Load(ctx, jobKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %v", id, err)
+	}
+	return job, nil
+}
+
+// Submit 提交并立即在后台运行作业，提交者取自上下文中的主体
+//
+// 作业在独立的上下文中运行，不随 ctx 取消，只能通过 Cancel 取消。
+func (m *Manager) Submit(ctx context.Context, typ string, params map[string]string, fn Func) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		ID:      id,
+		Type:    typ,
+		Params:  params,
+		State:   StateRunning,
+		Started: m.now(),
+	}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		job.User = principal.User
+	}
+	if err := m.save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+	r := &running{job: job, cancel: cancel, done: make(chan struct{}), saved: job.Started}
+	m.mu.Lock()
+	m.running[id] = r
+	// 启动作业前取副本，之后 r.job 只能在 m.mu 下访问
+	submitted := job.clone()
+	m.mu.Unlock()
+
+	m.log.Info("Submitted job",
+		zap.String("job_id", id),
+		zap.String("type", typ),
+		zap.String("user", submitted.User),
+	)
+	go m.run(jobCtx, r, fn)
+	return submitted, nil
+}
+
+// run 执行作业并记录结果
+func (m *Manager) run(ctx context.Context, r *running, fn Func) {
+	defer close(r.done)
+
+	report := func(p Progress) {
+		m.mu.Lock()
+		r.job.Progress = p
+		now := m.now()
+		var snapshot *Job
+		if now.Sub(r.saved) >= m.ProgressInterval {
+			r.saved = now
+			snapshot = r.job.clone()
+		}
+		m.mu.Unlock()
+		if snapshot != nil {
+			if err := m.save(context.Background(), snapshot); err != nil {
+				m.log.Warn("Failed to save job progress", zap.String("job_id", snapshot.ID), zap.Error(err))
+			}
+		}
+	}
+	err := fn(ctx, report)
+
+	m.mu.Lock()
+	job := r.job
+	job.Finished = m.now()
+	switch {
+	case err == nil:
+		job.State = StateSucceeded
+	case ctx.Err() != nil:
+		job.State = StateCanceled
+		job.Error = err.Error()
+	default:
+		job.State = StateFailed
+		job.Error = err.Error()
+	}
+	snapshot := job.clone()
+	delete(m.running, job.ID)
+	m.mu.Unlock()
+	r.cancel()
+
+	if err := m.save(context.Background(), snapshot); err != nil {
+		m.log.Error("Failed to save job result", zap.String("job_id", snapshot.ID), zap.Error(err))
+	}
+	m.log.Info("Job finished",
+		zap.String("job_id", snapshot.ID),
+		zap.String("type", snapshot.Type),
+		zap.String("state", string(snapshot.State)),
+		zap.Duration("elapsed", snapshot.Finished.Sub(snapshot.Started)),
+	)
+}
+
+// Get 返回作业记录，运行中的作业返回最新进度
+func (m *Manager) Get(ctx context.Context, id string) (*Job, error) {
+	m.mu.Lock()
+	if r, ok := m.running[id]; ok {
+		job := r.job.clone()
+		m.mu.Unlock()
+		return job, nil
+	}
+	m.mu.Unlock()
+	return m.load(ctx, id)
+}
+
+// List 返回全部作业记录，按开始时间排序
+func (m *Manager) List(ctx context.Context) ([]*Job, error) {
+	keys, err := m.storage.List(ctx, jobKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %v", err)
+	}
+	jobs := make([]*Job, 0, len(keys))
+	for _, key := range keys {
+		job, err := m.Get(ctx, strings.TrimPrefix(key, jobKeyPrefix))
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Started.Before(jobs[j].Started)
+	})
+	return jobs, nil
+}
+
+// Cancel 取消运行中的作业，不等待其结束
+func (m *Manager) Cancel(ctx context.Context, id string) error {
+	m.mu.Lock()
+	r, ok := m.running[id]
+	m.mu.Unlock()
+	if ok {
+		r.cancel()
+		m.log.Info("Canceled job", zap.String("job_id", id))
+		return nil
+	}
+	if _, err := m.load(ctx, id); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrJobFinished, id)
+}
+
+// Wait 等待作业结束并返回最终记录
+func (m *Manager) Wait(ctx context.Context, id string) (*Job, error) {
+	m.mu.Lock()
+	r, ok := m.running[id]
+	m.mu.Unlock()
+	if ok {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-r.done:
+		}
+	}
+	return m.load(ctx, id)
+}
+
+// Recover 将上次进程退出时仍处于运行状态的作业标记为失败，应在启动时调用
+func (m *Manager) Recover(ctx context.Context) (int, error) {
+	jobs, err := m.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	recovered := 0
+	for _, job := range jobs {
+		m.mu.Lock()
+		_, live := m.running[job.ID]
+		m.mu.Unlock()
+		if live || job.State.Finished() {
+			continue
+		}
+		job.State = StateFailed
+		job.Error = "interrupted by restart"
+		job.Finished = m.now()
+		if err := m.save(ctx, job); err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+	if recovered > 0 {
+		m.log.Warn("Marked interrupted jobs as failed", zap.Int("jobs", recovered))
+	}
+	return recovered, nil
+}
+
+// Prune 删除结束时间早于 before 的作业记录，返回删除数量
+func (m *Manager) Prune(ctx context.Context, before time.Time) (int, error) {
+	jobs, err := m.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for _, job := range jobs {
+		if !job.State.Finished() || !job.Finished.Before(before) {
+			continue
+		}
+		if err := m.storage.Delete(ctx, jobKey(job.ID)); err != nil {
+			return pruned, fmt.Errorf("failed to delete job %s: %v", job.ID, err)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// TreeDeleter 支持服务端子树删除的元数据存储
+type TreeDeleter interface {
+	DeleteTree(ctx context.Context, path string, opts meta.DeleteTreeOptions) (meta.DeleteTreeProgress, error)
+}
+
+// DeleteTreeJob 返回执行子树删除的作业函数，进度按已删除条目数上报
+func DeleteTreeJob(store TreeDeleter, path string, opts meta.DeleteTreeOptions) Func {
+	return func(ctx context.Context, report func(Progress)) error {
+		progress := opts.Progress
+		opts.Progress = func(p meta.DeleteTreeProgress) {
+			report(Progress{Done: int64(p.Deleted), Total: int64(p.Total)})
+			if progress != nil {
+				progress(p)
+			}
+		}
+		_, err := store.DeleteTree(ctx, path, opts)
+		return err
+	}
+}
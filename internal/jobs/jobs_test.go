@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T) *meta.FileStorage {
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{
+		RootDir:      t.TempDir(),
+		SyncInterval: time.Second,
+		FileMode:     0600,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	storage := newTestStorage(t)
+	manager := NewManager(storage)
+	manager.ProgressInterval = 0
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{User: "alice"})
+
+	release := make(chan struct{})
+	job, err := manager.Submit(ctx, "warmup", map[string]string{"path": "/data"}, func(ctx context.Context, report func(Progress)) error {
+		report(Progress{Done: 1, Total: 2})
+		<-release
+		report(Progress{Done: 2, Total: 2})
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, job.State)
+	assert.Equal(t, "alice", job.User)
+
+	require.Eventually(t, func() bool {
+		got, err := manager.Get(ctx, job.ID)
+		return err == nil && got.Progress.Done == 1
+	}, time.Second, time.Millisecond)
+	assert.ErrorIs(t, manager.Cancel(ctx, "missing"), ErrJobNotFound)
+
+	close(release)
+	final, err := manager.Wait(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateSucceeded, final.State)
+	assert.Equal(t, int64(2), final.Progress.Done)
+	assert.False(t, final.Finished.IsZero())
+	assert.ErrorIs(t, manager.Cancel(ctx, job.ID), ErrJobFinished)
+
+	// 历史在新的管理器实例中仍然可见
+	reopened := NewManager(storage)
+	list, err := reopened.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "/data", list[0].Params["path"])
+
+	pruned, err := reopened.Prune(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	list, err = reopened.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestManagerCancelAndFailure(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(newTestStorage(t))
+
+	job, err := manager.Submit(ctx, "rebalance", nil, func(ctx context.Context, report func(Progress)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+	require.NoError(t, manager.Cancel(ctx, job.ID))
+	final, err := manager.Wait(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateCanceled, final.State)
+
+	job, err = manager.Submit(ctx, "import", nil, func(ctx context.Context, report func(Progress)) error {
+		return errors.New("source unavailable")
+	})
+	require.NoError(t, err)
+	final, err = manager.Wait(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, final.State)
+	assert.Equal(t, "source unavailable", final.Error)
+}
+
+func TestManagerSubmitReturnsSubmittedState(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(newTestStorage(t))
+	manager.ProgressInterval = 0
+
+	// 作业在 Submit 返回前就报告进度并结束，返回的仍是提交时的状态
+	for i := 0; i < 20; i++ {
+		job, err := manager.Submit(ctx, "scan", nil, func(ctx context.Context, report func(Progress)) error {
+			report(Progress{Done: 1, Total: 1})
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, StateRunning, job.State)
+		assert.Zero(t, job.Progress.Done)
+		final, err := manager.Wait(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StateSucceeded, final.State)
+	}
+}
+
+func TestManagerRecover(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+	manager := NewManager(storage)
+	require.NoError(t, manager.save(ctx, &Job{ID: "stale", Type: "scrub", State: StateRunning, Started: time.Now()}))
+
+	recovered, err := manager.Recover(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+	job, err := manager.Get(ctx, "stale")
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, job.State)
+}
+
+func TestDeleteTreeJob(t *testing.T) {
+	ctx := context.Background()
+	store := meta.NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/tmp", 0755))
+	for _, name := range []string{"/tmp/a", "/tmp/b", "/tmp/c"} {
+		_, err := store.Create(ctx, name, 0644)
+		require.NoError(t, err)
+	}
+
+	manager := NewManager(newTestStorage(t))
+	job, err := manager.Submit(ctx, "delete-tree", map[string]string{"path": "/tmp"},
+		DeleteTreeJob(store, "/tmp", meta.DeleteTreeOptions{BatchSize: 1}))
+	require.NoError(t, err)
+	final, err := manager.Wait(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateSucceeded, final.State)
+	assert.Equal(t, Progress{Done: 4, Total: 4}, final.Progress)
+	_, err = store.Get(ctx, "/tmp")
+	assert.Error(t, err)
+}
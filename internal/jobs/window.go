@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cpfs/internal/config"
+)
+
+// weekdays 星期缩写到 time.Weekday 的映射
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window 按周重复的维护窗口
+type Window struct {
+	Days     [7]bool        // 按 time.Weekday 索引，表示窗口开始的星期
+	Start    time.Duration  // 开始时间距午夜的偏移
+	End      time.Duration  // 结束时间距午夜的偏移，不大于 Start 时跨越午夜
+	Location *time.Location // 时区
+}
+
+// parseDays 解析 "mon-fri"、"sat,sun"、"*" 形式的星期描述
+func parseDays(spec string) ([7]bool, error) {
+	var days [7]bool
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" || spec == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, ok := weekdays[from]
+		if !ok {
+			return days, fmt.Errorf("invalid weekday: %q", from)
+		}
+		end := start
+		if isRange {
+			if end, ok = weekdays[to]; !ok {
+				return days, fmt.Errorf("invalid weekday: %q", to)
+			}
+		}
+		// 允许 "fri-mon" 这样跨越周末的范围
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseClock 解析 HH:MM 形式的时刻
+func parseClock(spec string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(spec))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %v", spec, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseWindow 解析维护窗口配置
+func ParseWindow(cfg config.MaintenanceWindowConfig) (Window, error) {
+	var w Window
+	var err error
+	if w.Days, err = parseDays(cfg.Days); err != nil {
+		return w, err
+	}
+	if w.Start, err = parseClock(cfg.Start); err != nil {
+		return w, err
+	}
+	if w.End, err = parseClock(cfg.End); err != nil {
+		return w, err
+	}
+	w.Location = time.UTC
+	if cfg.Timezone != "" {
+		if w.Location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return w, fmt.Errorf("invalid timezone %q: %v", cfg.Timezone, err)
+		}
+	}
+	return w, nil
+}
+
+// midnight 返回 t 所在日期的零点
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Contains 判断时刻是否落在窗口内，跨越午夜的窗口归属于开始的那一天
+func (w Window) Contains(t time.Time) bool {
+	local := t.In(w.Location)
+	offset := local.Sub(midnight(local))
+	today := local.Weekday()
+	if w.Start < w.End {
+		return w.Days[today] && offset >= w.Start && offset < w.End
+	}
+	yesterday := (today + 6) % 7
+	return (w.Days[today] && offset >= w.Start) || (w.Days[yesterday] && offset < w.End)
+}
+
+// nextStart 返回严格晚于 t 的下一次窗口开始时刻
+func (w Window) nextStart(t time.Time) time.Time {
+	local := t.In(w.Location)
+	day := midnight(local)
+	for i := 0; i <= 7; i++ {
+		d := day.AddDate(0, 0, i)
+		start := d.Add(w.Start)
+		if w.Days[d.Weekday()] && start.After(t) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// Schedule 后台作业的维护计划
+//
+// 未配置窗口时始终全速运行；配置后窗口内全速运行，窗口外按 OutsideFactor 降速，为 0 时暂停。
+type Schedule struct {
+	Windows       []Window
+	OutsideFactor float64
+	now           func() time.Time
+}
+
+// NewSchedule 创建维护计划
+func NewSchedule(windows []Window, outsideFactor float64) *Schedule {
+	return &Schedule{Windows: windows, OutsideFactor: outsideFactor, now: time.Now}
+}
+
+// NewScheduleFromConfig 根据服务器配置创建维护计划
+func NewScheduleFromConfig(cfg *config.ServerConfig) (*Schedule, error) {
+	if cfg.MaintenanceOutsideFactor < 0 || cfg.MaintenanceOutsideFactor > 1 {
+		return nil, fmt.Errorf("maintenance_outside_factor must be between 0 and 1: %v", cfg.MaintenanceOutsideFactor)
+	}
+	windows := make([]Window, 0, len(cfg.MaintenanceWindows))
+	for i, wc := range cfg.MaintenanceWindows {
+		w, err := ParseWindow(wc)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %d: %v", i, err)
+		}
+		windows = append(windows, w)
+	}
+	return NewSchedule(windows, cfg.MaintenanceOutsideFactor), nil
+}
+
+// InWindow 判断时刻是否落在任一维护窗口内，未配置窗口时始终为 true
+func (s *Schedule) InWindow(t time.Time) bool {
+	if len(s.Windows) == 0 {
+		return true
+	}
+	for _, w := range s.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Factor 返回时刻 t 后台作业的速率系数，1 为全速，0 为暂停
+func (s *Schedule) Factor(t time.Time) float64 {
+	if s.InWindow(t) {
+		return 1
+	}
+	return s.OutsideFactor
+}
+
+// Rate 按当前时刻的速率系数缩放基准速率
+func (s *Schedule) Rate(base float64) float64 {
+	return base * s.Factor(s.now())
+}
+
+// NextWindow 返回晚于 t 的下一个维护窗口开始时刻，未配置窗口时返回零值
+func (s *Schedule) NextWindow(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range s.Windows {
+		start := w.nextStart(t)
+		if !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next
+}
+
+// Wait 在作业被暂停时阻塞到下一个维护窗口开始，供作业在批次之间调用
+func (s *Schedule) Wait(ctx context.Context) error {
+	for {
+		now := s.now()
+		if s.Factor(now) > 0 {
+			return nil
+		}
+		timer := time.NewTimer(s.NextWindow(now).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWindow(t *testing.T) {
+	w, err := ParseWindow(config.MaintenanceWindowConfig{Days: "fri-mon", Start: "22:00", End: "06:00"})
+	require.NoError(t, err)
+	assert.Equal(t, [7]bool{true, true, false, false, false, true, true}, w.Days)
+	assert.Equal(t, 22*time.Hour, w.Start)
+	assert.Equal(t, 6*time.Hour, w.End)
+
+	w, err = ParseWindow(config.MaintenanceWindowConfig{Start: "01:30", End: "02:00", Timezone: "Asia/Shanghai"})
+	require.NoError(t, err)
+	assert.Equal(t, [7]bool{true, true, true, true, true, true, true}, w.Days)
+	assert.Equal(t, "Asia/Shanghai", w.Location.String())
+
+	for _, bad := range []config.MaintenanceWindowConfig{
+		{Days: "funday", Start: "01:00", End: "02:00"},
+		{Start: "25:00", End: "02:00"},
+		{Start: "01:00", End: "02:00", Timezone: "Mars/Base"},
+	} {
+		_, err := ParseWindow(bad)
+		assert.Error(t, err, "%+v", bad)
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 周一至周五 22:00 到次日 06:00
+	w, err := ParseWindow(config.MaintenanceWindowConfig{Days: "mon-fri", Start: "22:00", End: "06:00"})
+	require.NoError(t, err)
+
+	at := func(day, hour int) time.Time {
+		// 2024-01-01 是周一
+		return time.Date(2024, 1, day, hour, 0, 0, 0, time.UTC)
+	}
+	assert.True(t, w.Contains(at(1, 23)), "monday night")
+	assert.True(t, w.Contains(at(2, 5)), "tuesday early morning belongs to monday")
+	assert.False(t, w.Contains(at(2, 12)))
+	assert.True(t, w.Contains(at(6, 3)), "saturday early morning belongs to friday")
+	assert.False(t, w.Contains(at(6, 23)), "saturday night")
+	assert.False(t, w.Contains(at(1, 3)), "monday early morning belongs to sunday")
+}
+
+func TestSchedule(t *testing.T) {
+	schedule, err := NewScheduleFromConfig(&config.ServerConfig{
+		MaintenanceWindows: []config.MaintenanceWindowConfig{
+			{Days: "sat,sun", Start: "00:00", End: "00:00"},
+			{Days: "*", Start: "02:00", End: "04:00"},
+		},
+		MaintenanceOutsideFactor: 0.25,
+	})
+	require.NoError(t, err)
+
+	monday := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 0.25, schedule.Factor(monday))
+	assert.Equal(t, 1.0, schedule.Factor(monday.Add(15*time.Hour)))
+	assert.Equal(t, 1.0, schedule.Factor(time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, monday.Add(14*time.Hour), schedule.NextWindow(monday))
+
+	schedule.now = func() time.Time { return monday }
+	assert.Equal(t, 25.0, schedule.Rate(100))
+
+	unlimited, err := NewScheduleFromConfig(&config.ServerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, unlimited.Factor(monday))
+
+	_, err = NewScheduleFromConfig(&config.ServerConfig{MaintenanceOutsideFactor: 2})
+	assert.Error(t, err)
+}
+
+func TestScheduleWait(t *testing.T) {
+	now := time.Date(2024, 1, 1, 1, 59, 59, 950_000_000, time.UTC)
+	schedule := NewSchedule([]Window{{Days: [7]bool{true, true, true, true, true, true, true}, Start: 2 * time.Hour, End: 3 * time.Hour, Location: time.UTC}}, 0)
+	calls := 0
+	schedule.now = func() time.Time {
+		calls++
+		if calls > 1 {
+			return now.Add(time.Second)
+		}
+		return now
+	}
+	require.NoError(t, schedule.Wait(context.Background()))
+
+	schedule.now = func() time.Time { return now.Add(-time.Hour) }
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, schedule.Wait(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// BackendZap 使用 zap 编码器输出
+	BackendZap = "zap"
+	// BackendSlog 通过 log/slog 的 Handler 输出
+	BackendSlog = "slog"
+)
+
+// LoggerConfig 日志配置
+type LoggerConfig struct {
+	// Level 日志级别：debug、info（默认）、warn、error
+	Level string
+	// Backend 输出后端：zap（默认）或 slog
+	Backend string
+	// Format 输出格式：json（默认）或 text
+	Format string
+	// Output 日志输出目标，例如测试中的内存缓冲、syslog 或 journald 写入器，为 nil 时输出到标准输出
+	Output io.Writer
+	// File 额外写入的日志文件，为空时不写文件
+	File string
+	// Handler slog 后端使用的处理器，设置后忽略 Format、Output 和 File
+	Handler slog.Handler
+}
+
+// parseLevel 解析日志级别
+func parseLevel(level string) (zapcore.Level, error) {
+	if level == "" {
+		return zapcore.InfoLevel, nil
+	}
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return l, fmt.Errorf("invalid log level: %q", level)
+	}
+	return l, nil
+}
+
+// output 返回配置的输出目标，需要写文件时同时写入 Output 和文件
+func (c *LoggerConfig) output() (io.Writer, error) {
+	out := c.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	if c.File == "" {
+		return out, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.File), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %v", err)
+	}
+	return io.MultiWriter(out, f), nil
+}
+
+// encoderConfig 与 InitLogger 保持一致的编码配置
+func encoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// New 按配置创建 zap.Logger，slog 后端通过桥接 Core 输出到 slog.Handler
+func New(cfg LoggerConfig) (*zap.Logger, error) {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	var core zapcore.Core
+	switch cfg.Backend {
+	case "", BackendZap:
+		out, err := cfg.output()
+		if err != nil {
+			return nil, err
+		}
+		var encoder zapcore.Encoder
+		switch cfg.Format {
+		case "", "json":
+			encoder = zapcore.NewJSONEncoder(encoderConfig())
+		case "text":
+			encoder = zapcore.NewConsoleEncoder(encoderConfig())
+		default:
+			return nil, fmt.Errorf("invalid log format: %q", cfg.Format)
+		}
+		core = zapcore.NewCore(encoder, zapcore.AddSync(out), level)
+	case BackendSlog:
+		handler := cfg.Handler
+		if handler == nil {
+			out, err := cfg.output()
+			if err != nil {
+				return nil, err
+			}
+			opts := &slog.HandlerOptions{Level: slogLevel(level)}
+			switch cfg.Format {
+			case "", "json":
+				handler = slog.NewJSONHandler(out, opts)
+			case "text":
+				handler = slog.NewTextHandler(out, opts)
+			default:
+				return nil, fmt.Errorf("invalid log format: %q", cfg.Format)
+			}
+		}
+		core = &slogCore{handler: handler, level: level}
+	default:
+		return nil, fmt.Errorf("invalid log backend: %q", cfg.Backend)
+	}
+
+	return zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1)), nil
+}
+
+// Init 按配置创建日志并设置为全局日志
+func Init(cfg LoggerConfig) error {
+	l, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	Log = l
+	return nil
+}
+
+// slogLevel 将 zap 级别转换为 slog 级别
+func slogLevel(l zapcore.Level) slog.Level {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case l == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case l == zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// slogCore 将 zap 日志条目转发到 slog.Handler 的 zapcore.Core
+type slogCore struct {
+	handler slog.Handler
+	level   zapcore.Level
+}
+
+// Enabled 实现 zapcore.LevelEnabler
+func (c *slogCore) Enabled(l zapcore.Level) bool {
+	return l >= c.level && c.handler.Enabled(context.Background(), slogLevel(l))
+}
+
+// With 实现 zapcore.Core
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	return &slogCore{handler: c.handler.WithAttrs(attrs(fields)), level: c.level}
+}
+
+// Check 实现 zapcore.Core
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 实现 zapcore.Core
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(ent.Time, slogLevel(ent.Level), ent.Message, 0)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+	r.AddAttrs(attrs(fields)...)
+	return c.handler.Handle(context.Background(), r)
+}
+
+// Sync 实现 zapcore.Core
+func (c *slogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewZapToWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(LoggerConfig{Level: "warn", Output: &buf})
+	require.NoError(t, err)
+
+	l.Info("skipped")
+	l.Warn("captured", zap.String("path", "/a"))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "captured", entry["msg"])
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "/a", entry["path"])
+}
+
+func TestNewSlogBackend(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(LoggerConfig{Backend: BackendSlog, Format: "text", Output: &buf})
+	require.NoError(t, err)
+
+	l.Debug("skipped")
+	l.With(zap.String("node", "meta-1")).Info("started", zap.Int("port", 50051))
+	out := buf.String()
+	assert.NotContains(t, out, "skipped")
+	assert.Contains(t, out, "level=INFO msg=started node=meta-1 port=50051")
+}
+
+func TestInitWithFile(t *testing.T) {
+	saved := Log
+	defer func() { Log = saved }()
+
+	var buf bytes.Buffer
+	file := filepath.Join(t.TempDir(), "logs", "cpfs.log")
+	require.NoError(t, Init(LoggerConfig{Level: "debug", Format: "text", Output: &buf, File: file}))
+	Debug("to both")
+
+	assert.Contains(t, buf.String(), "to both")
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "to both")
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, cfg := range []LoggerConfig{
+		{Level: "loud"},
+		{Backend: "logrus"},
+		{Format: "xml"},
+		{Backend: BackendSlog, Format: "xml"},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
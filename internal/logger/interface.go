@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger 各子系统使用的日志接口
+//
+// 嵌入 cpfs 的宿主程序可以通过构造参数注入自己的实现，避免覆盖宿主的全局日志配置。
+// *zap.Logger 直接满足该接口；未注入时使用 Default，即包级全局日志。
+type Logger interface {
+	Debug(msg string, fields ...zap.Field)
+	Info(msg string, fields ...zap.Field)
+	Warn(msg string, fields ...zap.Field)
+	Error(msg string, fields ...zap.Field)
+}
+
+// global 转发到包级全局日志的实现，InitLogger 之后的替换对已注入的子系统同样生效
+type global struct{}
+
+func (global) Debug(msg string, fields ...zap.Field) { Debug(msg, fields...) }
+func (global) Info(msg string, fields ...zap.Field)  { Info(msg, fields...) }
+func (global) Warn(msg string, fields ...zap.Field)  { Warn(msg, fields...) }
+func (global) Error(msg string, fields ...zap.Field) { Error(msg, fields...) }
+
+// Default 返回转发到包级全局日志的 Logger
+func Default() Logger {
+	return global{}
+}
+
+// OrDefault 在 l 为 nil 时返回 Default，供构造函数处理未注入的情况
+func OrDefault(l Logger) Logger {
+	if l == nil {
+		return Default()
+	}
+	return l
+}
+
+// Nop 返回丢弃所有日志的 Logger
+func Nop() Logger {
+	return zap.NewNop()
+}
+
+// slogLogger 将 zap 字段转换为 slog 属性输出的适配器
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// FromSlog 返回输出到 slog.Logger 的 Logger
+func FromSlog(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+// attrs 按原顺序将 zap 字段转换为 slog 属性
+func attrs(fields []zap.Field) []slog.Attr {
+	out := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for k, v := range enc.Fields {
+			out = append(out, slog.Any(k, v))
+		}
+	}
+	return out
+}
+
+func (s *slogLogger) log(level slog.Level, msg string, fields []zap.Field) {
+	ctx := context.Background()
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	s.l.LogAttrs(ctx, level, msg, attrs(fields)...)
+}
+
+func (s *slogLogger) Debug(msg string, fields ...zap.Field) { s.log(slog.LevelDebug, msg, fields) }
+func (s *slogLogger) Info(msg string, fields ...zap.Field)  { s.log(slog.LevelInfo, msg, fields) }
+func (s *slogLogger) Warn(msg string, fields ...zap.Field)  { s.log(slog.LevelWarn, msg, fields) }
+func (s *slogLogger) Error(msg string, fields ...zap.Field) { s.log(slog.LevelError, msg, fields) }
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := FromSlog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Debug("hidden")
+	l.Info("hello", zap.String("path", "/a"), zap.Int("n", 3), zap.Error(errors.New("boom")))
+	l.Error("failed")
+
+	out := buf.String()
+	assert.NotContains(t, out, "hidden")
+	assert.Contains(t, out, "level=INFO msg=hello path=/a n=3 error=boom")
+	assert.Contains(t, out, "level=ERROR msg=failed")
+}
+
+func TestDefaultForwardsToGlobal(t *testing.T) {
+	saved := Log
+	defer func() { Log = saved }()
+
+	core, logs := observer.New(zap.DebugLevel)
+	l := Default()
+	// 注入后再替换全局日志，已注入的 Default 仍然生效
+	Log = zap.New(core)
+	l.Warn("warned", zap.String("k", "v"))
+
+	entries := logs.All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "warned", entries[0].Message)
+		assert.Equal(t, "v", entries[0].ContextMap()["k"])
+	}
+
+	assert.Equal(t, Default(), OrDefault(nil))
+	nop := Nop()
+	assert.Same(t, nop, OrDefault(nop))
+	nop.Info("dropped")
+}
@@ -0,0 +1,196 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+)
+
+// Bind 将目录 source 绑定到 mountPoint，通过 mountPoint 可以访问 source 下的全部内容
+//
+// 绑定点是命名空间中一个类型为 TypeBind 的条目，Target 记录源目录。与符号链接不同，绑定对
+// 访问者透明：经过绑定点的路径在服务端被换成源目录下的路径，Get、List、Create 等操作直接作用于
+// 源目录中的条目，不需要客户端解析。绑定点本身不能被删除或重命名，只能用 Unbind 解除。
+// 变更日志、配额和管理操作使用源目录下的真实路径。
+func (s *MemoryStore) Bind(ctx context.Context, source, mountPoint string) error {
+	unlock, err := s.lockFenced(ctx, mountPoint)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	src := s.locate(normalizePath(source))
+	dst := s.locateEntry(normalizePath(mountPoint))
+	srcMeta, exists := s.tree.get(src)
+	if !exists {
+		return fmt.Errorf("directory not found: %s", src)
+	}
+	if srcMeta.Type != TypeDirectory {
+		return fmt.Errorf("bind source is not a directory: %s", src)
+	}
+	if dst == "/" {
+		return fmt.Errorf("cannot bind over root directory")
+	}
+	if isWithin(dst, src) {
+		return fmt.Errorf("cannot bind %s inside itself: %s", src, dst)
+	}
+	parent := path.Dir(dst)
+	parentMeta, exists := s.tree.get(parent)
+	if !exists {
+		return fmt.Errorf("parent directory not found: %s", parent)
+	}
+	if parentMeta.Type != TypeDirectory {
+		return fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+	if _, exists := s.tree.get(dst); exists {
+		return fmt.Errorf("file %w: %s", ErrExist, dst)
+	}
+
+	inode, err := s.nextInode(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	meta := &Metadata{
+		Inode:      inode,
+		Name:       s.interner.Intern(path.Base(dst)),
+		Type:       TypeBind,
+		Mode:       srcMeta.Mode,
+		Links:      1,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+		Target:     src,
+	}
+	if err := s.chargeQuota(dst, nil, meta); err != nil {
+		return err
+	}
+	s.stamp(meta)
+	s.setEntry(dst, meta)
+	s.bindPoints++
+	s.recordChange(ctx, ChangeCreate, dst, meta)
+	return nil
+}
+
+// Unbind 解除绑定点，源目录不受影响
+func (s *MemoryStore) Unbind(ctx context.Context, mountPoint string) error {
+	unlock, err := s.lockFenced(ctx, mountPoint)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dst := s.locateEntry(normalizePath(mountPoint))
+	meta, exists := s.tree.get(dst)
+	if !exists {
+		return fmt.Errorf("file not found: %s", dst)
+	}
+	if meta.Type != TypeBind {
+		return fmt.Errorf("path is not a bind point: %s", dst)
+	}
+	if _, err := s.unlink(ctx, dst, meta); err != nil {
+		return err
+	}
+	s.recordChange(ctx, ChangeDelete, dst, meta)
+	return nil
+}
+
+// Bindings 返回全部绑定点及其源目录
+func (s *MemoryStore) Bindings(ctx context.Context) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bindings := make(map[string]string, s.bindPoints)
+	if s.bindPoints == 0 {
+		return bindings
+	}
+	for p, m := range s.tree.all() {
+		if m.Type == TypeBind {
+			bindings[p] = m.Target
+		}
+	}
+	return bindings
+}
+
+// locate 将经过绑定点的路径换成源目录下的真实路径，最后一个分量是绑定点时同样替换，调用方需持有锁
+//
+// 没有绑定点时直接返回。绑定点链最多替换 MaxSymlinkDepth 次，之后原样返回剩余路径。
+func (s *MemoryStore) locate(filePath string) string {
+	if s.bindPoints == 0 {
+		return filePath
+	}
+	for range MaxSymlinkDepth {
+		rewritten, ok := s.rebind(filePath)
+		if !ok {
+			break
+		}
+		filePath = rewritten
+	}
+	return filePath
+}
+
+// locateEntry 只替换父目录中的绑定点，返回条目本身的真实路径，用于作用于绑定点条目的操作，调用方需持有锁
+func (s *MemoryStore) locateEntry(filePath string) string {
+	if s.bindPoints == 0 || filePath == "/" {
+		return filePath
+	}
+	return path.Join(s.locate(path.Dir(filePath)), path.Base(filePath))
+}
+
+// rebind 从根开始查找路径中的第一个绑定点并换成源目录，没有绑定点时返回 false，调用方需持有锁
+func (s *MemoryStore) rebind(filePath string) (string, bool) {
+	for i := 1; i <= len(filePath); i++ {
+		if i < len(filePath) && filePath[i] != '/' {
+			continue
+		}
+		prefix := filePath[:i]
+		meta, exists := s.tree.get(prefix)
+		if !exists {
+			return "", false
+		}
+		if meta.Type == TypeBind {
+			return meta.Target + filePath[i:], true
+		}
+	}
+	return "", false
+}
+
+// unlocatePaths 将真实路径换回调用方经过绑定点看到的路径，located 为 requested 对应的真实路径
+func unlocatePaths(paths []string, requested, located string) {
+	if requested == located {
+		return
+	}
+	for i, p := range paths {
+		if isWithin(p, located) {
+			paths[i] = path.Join(requested, p[len(located):])
+		}
+	}
+}
+
+// retargetBinds 源目录被重命名后更新指向其子树的绑定点，调用方需持有写锁
+func (s *MemoryStore) retargetBinds(from, to string) {
+	if s.bindPoints == 0 {
+		return
+	}
+	for p, m := range s.tree.all() {
+		if m.Type == TypeBind && isWithin(m.Target, from) {
+			updated := m.Clone()
+			updated.Target = to + m.Target[len(from):]
+			updated.Version++
+			s.setEntry(p, updated)
+		}
+	}
+}
+
+// countBindPoints 统计绑定点数量
+func countBindPoints(data map[string]*Metadata) int {
+	n := 0
+	for _, m := range data {
+		if m.Type == TypeBind {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBind(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	for _, dir := range []string{"/datasets", "/datasets/imagenet", "/projects", "/projects/a"} {
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+	}
+	_, err := store.Create(ctx, "/datasets/imagenet/train.tar", 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Bind(ctx, "/datasets/imagenet", "/projects/a/imagenet"))
+	assert.Equal(t, map[string]string{"/projects/a/imagenet": "/datasets/imagenet"}, store.Bindings(ctx))
+
+	// 经过绑定点的访问作用于源目录
+	m, err := store.Get(ctx, "/projects/a/imagenet/train.tar")
+	require.NoError(t, err)
+	assert.Equal(t, "train.tar", m.Name)
+	dir, err := store.Get(ctx, "/projects/a/imagenet")
+	require.NoError(t, err)
+	assert.Equal(t, TypeDirectory, dir.Type)
+	_, err = store.Create(ctx, "/projects/a/imagenet/val.tar", 0644)
+	require.NoError(t, err)
+	_, err = store.Get(ctx, "/datasets/imagenet/val.tar")
+	assert.NoError(t, err)
+	entries, err := store.List(ctx, "/projects/a/imagenet")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// 分页结果使用调用方看到的路径
+	page, err := store.ListPage(ctx, "/projects/a/imagenet", PageOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/projects/a/imagenet/train.tar", "/projects/a/imagenet/val.tar"}, page.Paths)
+
+	resolved, err := store.ResolvePath(ctx, "/projects/a/imagenet/train.tar")
+	require.NoError(t, err)
+	assert.Equal(t, "/datasets/imagenet/train.tar", resolved)
+
+	// 绑定点不能被删除或重命名
+	assert.ErrorIs(t, store.Delete(ctx, "/projects/a/imagenet"), ErrBusy)
+	assert.ErrorIs(t, store.Rename(ctx, "/projects/a/imagenet", "/projects/a/x"), ErrBusy)
+	assert.ErrorIs(t, store.DeleteAll(ctx, "/projects/a/imagenet"), ErrBusy)
+
+	// 源目录重命名后绑定随之更新
+	require.NoError(t, store.Rename(ctx, "/datasets/imagenet", "/datasets/imagenet-2024"))
+	_, err = store.Get(ctx, "/projects/a/imagenet/train.tar")
+	assert.NoError(t, err)
+
+	require.NoError(t, store.Unbind(ctx, "/projects/a/imagenet"))
+	_, err = store.Get(ctx, "/projects/a/imagenet/train.tar")
+	assert.Error(t, err)
+	_, err = store.Get(ctx, "/datasets/imagenet-2024/train.tar")
+	assert.NoError(t, err)
+	assert.Empty(t, store.Bindings(ctx))
+}
+
+func TestBindValidation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/src", 0755))
+	_, err := store.Create(ctx, "/file", 0644)
+	require.NoError(t, err)
+
+	assert.Error(t, store.Bind(ctx, "/missing", "/m"))
+	assert.Error(t, store.Bind(ctx, "/file", "/m"))
+	assert.Error(t, store.Bind(ctx, "/src", "/src/inner"))
+	assert.ErrorIs(t, store.Bind(ctx, "/src", "/file"), ErrExist)
+	assert.Error(t, store.Unbind(ctx, "/src"))
+}
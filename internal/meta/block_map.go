@@ -0,0 +1,225 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	api "cpfs/api/meta"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// InlineBlockLimit 数据块数量不超过该值时块列表随元数据内联保存
+	InlineBlockLimit = 1024
+	// BlockSegmentSize 拆分后每个块映射段包含的数据块数量
+	BlockSegmentSize = 4096
+)
+
+// blockSegmentKeyPrefix 块映射段在存储中的键前缀
+const blockSegmentKeyPrefix = "/blockmap/"
+
+// fieldSegmentBlocks BlockSegment 消息中块列表的字段编号
+const fieldSegmentBlocks protowire.Number = 1
+
+// blockSegment 大文件块映射中的一段，覆盖文件内 [start, end) 字节范围
+type blockSegment struct {
+	start  int64
+	end    int64
+	key    string  // 持久化到块映射存储时的键
+	blocks []Block // 未持久化时常驻内存，否则为 nil 并按需加载
+}
+
+// blockSegmentKey 返回块映射段在存储中的键
+func blockSegmentKey(inode uint64, index int) string {
+	return fmt.Sprintf("%s%d/%d", blockSegmentKeyPrefix, inode, index)
+}
+
+// splitBlocks 将按偏移排序的块列表拆分为块映射段
+func splitBlocks(blocks []Block) []*blockSegment {
+	segments := make([]*blockSegment, 0, (len(blocks)+BlockSegmentSize-1)/BlockSegmentSize)
+	for i := 0; i < len(blocks); i += BlockSegmentSize {
+		chunk := blocks[i:min(i+BlockSegmentSize, len(blocks))]
+		seg := &blockSegment{
+			start:  chunk[0].Offset,
+			blocks: chunk,
+		}
+		for j := range chunk {
+			seg.end = max(seg.end, chunk[j].End())
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// encodeBlockSegment 按 metadata.proto 中的 BlockSegment 编码块列表
+func encodeBlockSegment(blocks []Block) []byte {
+	var b []byte
+	for i := range blocks {
+		b = protowire.AppendTag(b, fieldSegmentBlocks, protowire.BytesType)
+		b = protowire.AppendBytes(b, blocks[i].AppendProto(nil))
+	}
+	return b
+}
+
+// decodeBlockSegment 解析 BlockSegment 编码
+func decodeBlockSegment(data []byte) ([]Block, error) {
+	var blocks []Block
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+		if typ == protowire.BytesType && num == fieldSegmentBlocks {
+			var block Block
+			if err := block.UnmarshalBinary(raw); err != nil {
+				return err
+			}
+			blocks = append(blocks, block)
+		}
+		return nil
+	})
+	return blocks, err
+}
+
+// SetBlockStorage 设置大文件块映射段的持久化存储
+//
+// 设置后拆分出的块映射段写入该存储并从内存释放，GetBlockRange 按需加载。
+// 应在写入大文件之前调用。
+func (s *MemoryStore) SetBlockStorage(storage Storage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blockStorage = storage
+}
+
+// storeBlockMap 保存文件块映射，调用方需持有写锁
+//
+// meta.Blocks 为 nil 且文件已有拆分的块映射时保持原块映射不变；
+// 否则先将连续分配的块合并为区间，剩余块数量超过 InlineBlockLimit 时拆分为段保存，
+// 元数据中只保留 BlockCount。
+func (s *MemoryStore) storeBlockMap(ctx context.Context, meta *Metadata) error {
+	if meta.Blocks == nil && meta.BlockCount > 0 {
+		if _, ok := s.segments[meta.Inode]; ok {
+			return nil
+		}
+	}
+
+	if err := s.dropBlockMap(ctx, meta.Inode); err != nil {
+		return err
+	}
+	meta.NormalizeBlocks()
+	meta.BlockCount = 0
+	if len(meta.Blocks) <= InlineBlockLimit {
+		return nil
+	}
+
+	blocks := append([]Block(nil), meta.Blocks...)
+	sortBlocks(blocks)
+	segments := splitBlocks(blocks)
+	if s.blockStorage != nil {
+		for i, seg := range segments {
+			seg.key = blockSegmentKey(meta.Inode, i)
+			if err := s.blockStorage.Save(ctx, seg.key, encodeBlockSegment(seg.blocks)); err != nil {
+				return fmt.Errorf("failed to save block map segment: %v", err)
+			}
+			seg.blocks = nil
+		}
+	}
+
+	s.segments[meta.Inode] = segments
+	meta.BlockCount = len(blocks)
+	meta.Blocks = nil
+	return nil
+}
+
+// dropBlockMap 删除 inode 拆分的块映射，调用方需持有写锁
+func (s *MemoryStore) dropBlockMap(ctx context.Context, inode uint64) error {
+	segments, ok := s.segments[inode]
+	if !ok {
+		return nil
+	}
+	for _, seg := range segments {
+		if seg.key == "" {
+			continue
+		}
+		if err := s.blockStorage.Delete(ctx, seg.key); err != nil {
+			return fmt.Errorf("failed to delete block map segment: %v", err)
+		}
+	}
+	delete(s.segments, inode)
+	return nil
+}
+
+// loadSegment 返回块映射段的数据块，必要时从存储加载
+func (s *MemoryStore) loadSegment(ctx context.Context, seg *blockSegment) ([]Block, error) {
+	if seg.key == "" {
+		return seg.blocks, nil
+	}
+	data, err := s.blockStorage.Load(ctx, seg.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load block map segment: %v", err)
+	}
+	blocks, err := decodeBlockSegment(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode block map segment %s: %v", seg.key, err)
+	}
+	return blocks, nil
+}
+
+// GetBlockRange 返回与文件 [offset, offset+length) 范围相交的数据块，length 小于等于 0 表示直到文件末尾
+//
+// 对于拆分了块映射的大文件，只加载覆盖该范围的块映射段。
+func (s *MemoryStore) GetBlockRange(ctx context.Context, p string, offset, length int64) ([]Block, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d", offset)
+	}
+	end := int64(-1)
+	if length > 0 {
+		end = offset + length
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filePath := normalizePath(p)
+	meta, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+
+	result := api.ExtentBlocks(meta.Extents, offset, end)
+	segments, ok := s.segments[meta.Inode]
+	if !ok {
+		for i := range meta.Blocks {
+			if meta.Blocks[i].Overlaps(offset, end) {
+				result = append(result, meta.Blocks[i])
+			}
+		}
+		sortBlocks(result)
+		return result, nil
+	}
+
+	first := sort.Search(len(segments), func(i int) bool {
+		return segments[i].end > offset
+	})
+	for _, seg := range segments[first:] {
+		if end >= 0 && seg.start >= end {
+			break
+		}
+		blocks, err := s.loadSegment(ctx, seg)
+		if err != nil {
+			return nil, err
+		}
+		for i := range blocks {
+			if blocks[i].Overlaps(offset, end) {
+				result = append(result, blocks[i])
+			}
+		}
+	}
+	sortBlocks(result)
+	return result, nil
+}
+
+// sortBlocks 按偏移排序数据块
+func sortBlocks(blocks []Block) {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].Offset < blocks[j].Offset
+	})
+}
@@ -0,0 +1,122 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeBlocks 生成 n 个连续的 1MiB 数据块
+func makeBlocks(n int) []Block {
+	blocks := make([]Block, n)
+	for i := range blocks {
+		blocks[i] = Block{
+			ID:        fmt.Sprintf("blk-%d", i),
+			Size:      1 << 20,
+			Offset:    int64(i) << 20,
+			Locations: []string{"ds1", "ds2"},
+		}
+	}
+	return blocks
+}
+
+func testBlockRange(t *testing.T, store *MemoryStore) {
+	ctx := context.Background()
+	meta, err := store.Create(ctx, "/huge", 0644)
+	require.NoError(t, err)
+
+	const total = BlockSegmentSize*2 + 100
+	meta.Blocks = makeBlocks(total)
+	meta.Size = int64(total) << 20
+	require.NoError(t, store.Update(ctx, "/huge", meta))
+
+	// 核心元数据不再携带块列表
+	got, err := store.Get(ctx, "/huge")
+	require.NoError(t, err)
+	assert.Nil(t, got.Blocks)
+	assert.Equal(t, total, got.BlockCount)
+
+	blocks, err := store.GetBlockRange(ctx, "/huge", int64(BlockSegmentSize)<<20-1, 2)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, fmt.Sprintf("blk-%d", BlockSegmentSize-1), blocks[0].ID)
+	assert.Equal(t, fmt.Sprintf("blk-%d", BlockSegmentSize), blocks[1].ID)
+	assert.Equal(t, []string{"ds1", "ds2"}, blocks[1].Locations)
+
+	blocks, err = store.GetBlockRange(ctx, "/huge", int64(total-10)<<20, 0)
+	require.NoError(t, err)
+	assert.Len(t, blocks, 10)
+
+	blocks, err = store.GetBlockRange(ctx, "/huge", int64(total)<<20, 0)
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+
+	// 只修改属性时保留原块映射
+	got.Mode = 0600
+	require.NoError(t, store.Update(ctx, "/huge", got))
+	blocks, err = store.GetBlockRange(ctx, "/huge", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, blocks, total)
+
+	// 块列表缩小后恢复内联保存
+	got.Blocks = makeBlocks(3)
+	require.NoError(t, store.Update(ctx, "/huge", got))
+	got, err = store.Get(ctx, "/huge")
+	require.NoError(t, err)
+	assert.Len(t, got.Blocks, 3)
+	assert.Equal(t, 0, got.BlockCount)
+	blocks, err = store.GetBlockRange(ctx, "/huge", 1<<20, 1)
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "blk-1", blocks[0].ID)
+
+	_, err = store.GetBlockRange(ctx, "/missing", 0, 0)
+	assert.Error(t, err)
+	_, err = store.GetBlockRange(ctx, "/huge", -1, 0)
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreGetBlockRange(t *testing.T) {
+	testBlockRange(t, NewMemoryStore())
+}
+
+func TestMemoryStoreBlockStorage(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	storage, err := NewFileStorage(&StorageConfig{
+		RootDir:      tempDir,
+		SyncInterval: time.Hour,
+		FileMode:     0644,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	store := NewMemoryStore()
+	store.SetBlockStorage(storage)
+	testBlockRange(t, store)
+
+	// 恢复内联保存后块映射段被删除
+	keys, err := storage.List(context.Background(), blockSegmentKeyPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	ctx := context.Background()
+	meta, err := store.Create(ctx, "/big", 0644)
+	require.NoError(t, err)
+	meta.Blocks = makeBlocks(InlineBlockLimit + 1)
+	require.NoError(t, store.Update(ctx, "/big", meta))
+	keys, err = storage.List(ctx, blockSegmentKeyPrefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	require.NoError(t, store.Delete(ctx, "/big"))
+	keys, err = storage.List(ctx, blockSegmentKeyPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
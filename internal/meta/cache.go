@@ -0,0 +1,366 @@
+package meta
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/metrics"
+)
+
+const (
+	// DefaultCacheTTL 元数据缓存默认过期时间
+	DefaultCacheTTL = 5 * time.Minute
+	// DefaultCacheHotHits 条目在一个 TTL 周期内被命中该次数后视为热点并延长 TTL
+	DefaultCacheHotHits = 4
+	// cacheEntryOverhead 缓存条目的固定开销估计
+	cacheEntryOverhead = 256
+	// dirDefaultsOverhead 目录默认属性的固定开销估计
+	dirDefaultsOverhead = 64
+)
+
+// CacheBackend 元数据缓存下层的存储
+type CacheBackend interface {
+	Create(ctx context.Context, path string, mode os.FileMode) (*Metadata, error)
+	Get(ctx context.Context, path string) (*Metadata, error)
+	Update(ctx context.Context, path string, meta *Metadata) error
+	Delete(ctx context.Context, path string) error
+	List(ctx context.Context, path string) ([]*Metadata, error)
+	Mkdir(ctx context.Context, path string, mode os.FileMode) error
+}
+
+// CacheConfig 元数据缓存配置
+type CacheConfig struct {
+	// Size 缓存容量（字节），为 0 时不限制
+	Size int64
+	// TTL 条目的初始过期时间，为 0 时使用 DefaultCacheTTL
+	TTL time.Duration
+	// MaxTTL 热点条目延长后的最长过期时间，为 0 时为 4 倍 TTL
+	MaxTTL time.Duration
+	// HotHits 触发 TTL 延长的命中次数，为 0 时使用 DefaultCacheHotHits
+	HotHits int
+	// Metrics 指标注册表，为 nil 时使用实例私有的注册表
+	Metrics *metrics.Registry
+}
+
+// CacheStats 元数据缓存运行统计
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	HitRatio    float64
+	Entries     int
+	Bytes       int64
+	Expirations uint64 // 因过期淘汰的条目数
+	Extensions  uint64 // 热点条目 TTL 延长次数
+	Evictions   uint64 // 因容量或内存预算淘汰的条目数
+}
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	path    string
+	meta    *Metadata
+	size    int64
+	ttl     time.Duration
+	expires time.Time
+	hits    int // 当前 TTL 周期内的命中次数
+}
+
+// MetadataCache 带 TTL 的元数据读缓存
+//
+// Get 命中未过期条目时直接返回副本；同一条目在一个 TTL 周期内命中 HotHits 次后 TTL 翻倍，
+// 最长不超过 MaxTTL，冷条目则按初始 TTL 过期。经由缓存的写操作使对应条目失效，
+// 绕过缓存的修改最多在 TTL 内不可见。
+type MetadataCache struct {
+	backend CacheBackend
+	config  CacheConfig
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // 最近使用的条目在前
+	bytes   int64
+	gen     uint64 // 每次失效时递增，防止未命中期间的并发修改被旧值覆盖
+
+	hits        *metrics.Counter
+	misses      *metrics.Counter
+	expirations *metrics.Counter
+	extensions  *metrics.Counter
+	evictions   *metrics.Counter
+}
+
+// NewMetadataCache 在 backend 之上创建元数据缓存
+func NewMetadataCache(backend CacheBackend, config CacheConfig) *MetadataCache {
+	if config.TTL <= 0 {
+		config.TTL = DefaultCacheTTL
+	}
+	if config.MaxTTL < config.TTL {
+		config.MaxTTL = 4 * config.TTL
+	}
+	if config.HotHits <= 0 {
+		config.HotHits = DefaultCacheHotHits
+	}
+	registry := config.Metrics
+	if registry == nil {
+		registry = metrics.NewRegistry()
+	}
+
+	c := &MetadataCache{
+		backend:     backend,
+		config:      config,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		hits:        registry.Counter("cpfs_meta_cache_hits_total", "Number of metadata lookups served from the cache.", nil),
+		misses:      registry.Counter("cpfs_meta_cache_misses_total", "Number of metadata lookups that went to the store.", nil),
+		expirations: registry.Counter("cpfs_meta_cache_expirations_total", "Number of cache entries dropped after their TTL.", nil),
+		extensions:  registry.Counter("cpfs_meta_cache_ttl_extensions_total", "Number of TTL extensions granted to hot entries.", nil),
+		evictions:   registry.Counter("cpfs_meta_cache_evictions_total", "Number of cache entries evicted for capacity.", nil),
+	}
+	registry.GaugeFunc("cpfs_meta_cache_bytes", "Estimated bytes held in the metadata cache.", nil, func() float64 {
+		return float64(c.Usage())
+	})
+	return c
+}
+
+// CacheConfigFromServer 根据服务器配置的 CacheSize（字节）和 CacheTTL（秒）创建缓存配置
+func CacheConfigFromServer(cfg *config.ServerConfig) CacheConfig {
+	return CacheConfig{
+		Size: cfg.CacheSize,
+		TTL:  time.Duration(cfg.CacheTTL) * time.Second,
+	}
+}
+
+// metadataSize 估算元数据占用的内存
+func metadataSize(m *Metadata) int64 {
+	size := int64(cacheEntryOverhead + len(m.Name) + len(m.Owner) + len(m.Group) + len(m.Placement))
+	size += int64(len(m.Blocks)) * 96
+	size += int64(len(m.Extents)) * 80
+	size += int64(len(m.InlineData)) + int64(len(m.StorageClass)) + int64(len(m.Writer)) + int64(len(m.Target))
+	if m.Defaults != nil {
+		size += dirDefaultsOverhead + int64(len(m.Defaults.Owner)+len(m.Defaults.Group)+len(m.Defaults.StorageClass))
+	}
+	if m.Layout != nil {
+		size += 32
+	}
+	if m.Pin != nil {
+		size += dirDefaultsOverhead + int64(len(m.Pin.Media))
+		for _, node := range m.Pin.Nodes {
+			size += int64(len(node))
+		}
+	}
+	return size
+}
+
+// Get 获取元数据副本，优先从缓存返回
+func (c *MetadataCache) Get(ctx context.Context, p string) (*Metadata, error) {
+	filePath := normalizePath(p)
+	now := c.now()
+
+	c.mu.Lock()
+	if elem, ok := c.entries[filePath]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if now.Before(entry.expires) {
+			entry.hits++
+			if entry.hits >= c.config.HotHits && entry.ttl < c.config.MaxTTL {
+				entry.ttl = min(entry.ttl*2, c.config.MaxTTL)
+				entry.expires = now.Add(entry.ttl)
+				entry.hits = 0
+				c.extensions.Inc()
+			}
+			c.lru.MoveToFront(elem)
+			meta := entry.meta.Clone()
+			c.mu.Unlock()
+			c.hits.Inc()
+			return meta, nil
+		}
+		c.remove(elem)
+		c.expirations.Inc()
+	}
+	gen := c.gen
+	c.mu.Unlock()
+	c.misses.Inc()
+
+	meta, err := c.backend.Get(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	c.put(filePath, meta.Clone(), now, gen)
+	return meta, nil
+}
+
+// put 写入缓存条目并按容量淘汰最久未使用的条目，gen 与读取前不一致时放弃写入
+func (c *MetadataCache) put(p string, meta *Metadata, now time.Time, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if elem, ok := c.entries[p]; ok {
+		c.remove(elem)
+	}
+	entry := &cacheEntry{
+		path:    p,
+		meta:    meta,
+		size:    metadataSize(meta),
+		ttl:     c.config.TTL,
+		expires: now.Add(c.config.TTL),
+	}
+	c.entries[p] = c.lru.PushFront(entry)
+	c.bytes += entry.size
+
+	if c.config.Size > 0 {
+		c.evictBytes(c.bytes - c.config.Size)
+	}
+}
+
+// remove 删除条目，调用方需持有 mu
+func (c *MetadataCache) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.path)
+	c.bytes -= entry.size
+}
+
+// evictBytes 从最久未使用的条目开始淘汰至少 n 字节，调用方需持有 mu
+func (c *MetadataCache) evictBytes(n int64) int64 {
+	var freed int64
+	for freed < n {
+		elem := c.lru.Back()
+		if elem == nil {
+			break
+		}
+		freed += elem.Value.(*cacheEntry).size
+		c.remove(elem)
+		c.evictions.Inc()
+	}
+	return freed
+}
+
+// Invalidate 使路径对应的缓存条目失效
+func (c *MetadataCache) Invalidate(p string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if elem, ok := c.entries[normalizePath(p)]; ok {
+		c.remove(elem)
+	}
+}
+
+// InvalidateTree 使路径及其子树中的全部缓存条目失效，用于目录的重命名和删除
+func (c *MetadataCache) InvalidateTree(p string) {
+	root := normalizePath(p)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for filePath, elem := range c.entries {
+		if isWithin(filePath, root) {
+			c.remove(elem)
+		}
+	}
+}
+
+// InvalidateAll 清空缓存
+func (c *MetadataCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+// SetTTL 调整新条目的初始 TTL，热点条目的最长 TTL 随之设为 4 倍，已缓存的条目保持原过期时间
+func (c *MetadataCache) SetTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.TTL = ttl
+	c.config.MaxTTL = 4 * ttl
+}
+
+// Create 创建文件
+func (c *MetadataCache) Create(ctx context.Context, p string, mode os.FileMode) (*Metadata, error) {
+	c.Invalidate(p)
+	return c.backend.Create(ctx, p, mode)
+}
+
+// Update 更新元数据并使缓存条目失效
+func (c *MetadataCache) Update(ctx context.Context, p string, meta *Metadata) error {
+	defer c.Invalidate(p)
+	return c.backend.Update(ctx, p, meta)
+}
+
+// Delete 删除文件并使缓存条目失效
+func (c *MetadataCache) Delete(ctx context.Context, p string) error {
+	defer c.Invalidate(p)
+	return c.backend.Delete(ctx, p)
+}
+
+// List 列出目录内容，不经过缓存
+func (c *MetadataCache) List(ctx context.Context, p string) ([]*Metadata, error) {
+	return c.backend.List(ctx, p)
+}
+
+// Mkdir 创建目录
+func (c *MetadataCache) Mkdir(ctx context.Context, p string, mode os.FileMode) error {
+	c.Invalidate(p)
+	return c.backend.Mkdir(ctx, p, mode)
+}
+
+// Usage 实现 budget.Consumer，返回缓存估算占用字节数
+func (c *MetadataCache) Usage() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// Evict 实现 budget.Consumer，先淘汰已过期条目，再按最久未使用淘汰
+func (c *MetadataCache) Evict(n int64) int64 {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var freed int64
+	for elem := c.lru.Back(); elem != nil && freed < n; {
+		prev := elem.Prev()
+		if entry := elem.Value.(*cacheEntry); !now.Before(entry.expires) {
+			freed += entry.size
+			c.remove(elem)
+			c.expirations.Inc()
+		}
+		elem = prev
+	}
+	if freed < n {
+		freed += c.evictBytes(n - freed)
+	}
+	return freed
+}
+
+// Stats 返回缓存运行统计
+func (c *MetadataCache) Stats() CacheStats {
+	stats := CacheStats{
+		Hits:        c.hits.Value(),
+		Misses:      c.misses.Value(),
+		Expirations: c.expirations.Value(),
+		Extensions:  c.extensions.Value(),
+		Evictions:   c.evictions.Value(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+
+	c.mu.Lock()
+	stats.Entries = len(c.entries)
+	stats.Bytes = c.bytes
+	c.mu.Unlock()
+
+	return stats
+}
@@ -0,0 +1,176 @@
+package meta
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cpfs/internal/budget"
+	"cpfs/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ budget.Consumer = (*MetadataCache)(nil)
+
+// countingBackend 统计 Get 调用次数的测试存储
+type countingBackend struct {
+	*MemoryStore
+	gets atomic.Int64
+}
+
+func (b *countingBackend) Get(ctx context.Context, p string) (*Metadata, error) {
+	b.gets.Add(1)
+	return b.MemoryStore.Get(ctx, p)
+}
+
+// newTestCache 创建使用可控时钟的缓存
+func newTestCache(t *testing.T, config CacheConfig) (*MetadataCache, *countingBackend, *time.Time) {
+	backend := &countingBackend{MemoryStore: NewMemoryStore()}
+	cache := NewMetadataCache(backend, config)
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+	return cache, backend, &now
+}
+
+func TestMetadataCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	cache, backend, now := newTestCache(t, CacheConfig{TTL: time.Minute, HotHits: 100})
+
+	_, err := cache.Create(ctx, "/a", 0644)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		m, err := cache.Get(ctx, "/a")
+		require.NoError(t, err)
+		assert.Equal(t, "a", m.Name)
+	}
+	assert.Equal(t, int64(1), backend.gets.Load())
+
+	// 返回副本，修改不影响缓存
+	m, _ := cache.Get(ctx, "a")
+	m.Size = 99
+	m, _ = cache.Get(ctx, "/a")
+	assert.Equal(t, int64(0), m.Size)
+
+	*now = now.Add(time.Minute)
+	_, err = cache.Get(ctx, "/a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), backend.gets.Load())
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(4), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Expirations)
+	assert.InDelta(t, 4.0/6.0, stats.HitRatio, 1e-9)
+	assert.Equal(t, 1, stats.Entries)
+}
+
+func TestMetadataCacheAdaptiveTTL(t *testing.T) {
+	ctx := context.Background()
+	cache, backend, now := newTestCache(t, CacheConfig{TTL: time.Minute, MaxTTL: 3 * time.Minute, HotHits: 2})
+
+	_, err := cache.Create(ctx, "/hot", 0644)
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "/hot")
+	require.NoError(t, err)
+
+	// 两次命中后 TTL 翻倍为 2 分钟，再两次命中达到上限 3 分钟
+	for i := 0; i < 4; i++ {
+		_, err = cache.Get(ctx, "/hot")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(2), cache.Stats().Extensions)
+
+	*now = now.Add(2*time.Minute + 30*time.Second)
+	_, err = cache.Get(ctx, "/hot")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), backend.gets.Load())
+
+	*now = now.Add(3 * time.Minute)
+	_, err = cache.Get(ctx, "/hot")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), backend.gets.Load())
+}
+
+func TestMetadataCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	cache, backend, _ := newTestCache(t, CacheConfig{})
+
+	_, err := cache.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	m, err := cache.Get(ctx, "/f")
+	require.NoError(t, err)
+
+	m.Size = 10
+	require.NoError(t, cache.Update(ctx, "/f", m))
+	m, err = cache.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), m.Size)
+	assert.Equal(t, int64(2), backend.gets.Load())
+
+	require.NoError(t, cache.Delete(ctx, "/f"))
+	_, err = cache.Get(ctx, "/f")
+	assert.Error(t, err)
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestMetadataCacheCapacity(t *testing.T) {
+	ctx := context.Background()
+	cache, _, _ := newTestCache(t, CacheConfig{Size: 3 * (cacheEntryOverhead + 1)})
+
+	for _, name := range []string{"/a", "/b", "/c", "/d"} {
+		_, err := cache.Create(ctx, name, 0644)
+		require.NoError(t, err)
+		_, err = cache.Get(ctx, name)
+		require.NoError(t, err)
+	}
+	stats := cache.Stats()
+	assert.Equal(t, 3, stats.Entries)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, stats.Bytes, cache.Usage())
+
+	freed := cache.Evict(1)
+	assert.Equal(t, int64(cacheEntryOverhead+1), freed)
+	assert.Equal(t, 2, cache.Stats().Entries)
+}
+
+func TestCacheConfigFromServer(t *testing.T) {
+	cfg := CacheConfigFromServer(&config.ServerConfig{CacheSize: 1 << 30, CacheTTL: 300})
+	assert.Equal(t, int64(1<<30), cfg.Size)
+	assert.Equal(t, 5*time.Minute, cfg.TTL)
+}
+
+func TestMetadataCacheInvalidateTree(t *testing.T) {
+	ctx := context.Background()
+	cache, backend, now := newTestCache(t, CacheConfig{})
+	require.NoError(t, backend.Mkdir(ctx, "/d", 0755))
+	for _, p := range []string{"/d/a", "/db"} {
+		_, err := backend.Create(ctx, p, 0644)
+		require.NoError(t, err)
+	}
+	for _, p := range []string{"/d", "/d/a", "/db"} {
+		_, err := cache.Get(ctx, p)
+		require.NoError(t, err)
+	}
+
+	// 前缀相同的兄弟路径不受影响
+	cache.InvalidateTree("/d")
+	assert.Equal(t, 1, cache.Stats().Entries)
+
+	cache.InvalidateAll()
+	assert.Equal(t, 0, cache.Stats().Entries)
+	assert.Zero(t, cache.Usage())
+
+	// 缩短 TTL 后新条目按新 TTL 过期
+	cache.SetTTL(time.Second)
+	_, err := cache.Get(ctx, "/db")
+	require.NoError(t, err)
+	*now = now.Add(2 * time.Second)
+	gets := backend.gets.Load()
+	_, err = cache.Get(ctx, "/db")
+	require.NoError(t, err)
+	assert.Equal(t, gets+1, backend.gets.Load())
+}
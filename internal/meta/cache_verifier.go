@@ -0,0 +1,137 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// VerifyReport 缓存一致性校验报告
+type VerifyReport struct {
+	Checked   int      // 已校验的键数量
+	Divergent []string // 缓存与磁盘内容不一致的键
+	Missing   []string // 缓存中存在但磁盘上已被删除的键
+	Reloaded  bool     // 是否已按磁盘内容修正缓存
+}
+
+// Consistent 判断校验是否未发现问题
+func (r *VerifyReport) Consistent() bool {
+	return len(r.Divergent) == 0 && len(r.Missing) == 0
+}
+
+// VerifyCache 抽样校验缓存与磁盘内容是否一致
+//
+// 仅校验已同步（非脏）的键；sampleSize 为 0 时校验全部键。
+// reload 为 true 时以磁盘内容修正缓存，否则仅报告差异。
+func (fs *FileStorage) VerifyCache(ctx context.Context, sampleSize int, reload bool) (*VerifyReport, error) {
+	fs.mu.RLock()
+	candidates := make([]string, 0, len(fs.cache))
+	for key := range fs.cache {
+		if !fs.dirty[key] {
+			candidates = append(candidates, key)
+		}
+	}
+	fs.mu.RUnlock()
+
+	if sampleSize > 0 && sampleSize < len(candidates) {
+		rand.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+		candidates = candidates[:sampleSize]
+	}
+
+	report := &VerifyReport{Reloaded: reload}
+	for _, key := range candidates {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		path, err := fs.keyToPath(strings.TrimPrefix(key, "/"))
+		if err != nil {
+			continue
+		}
+
+		// 先在锁外读取，发现差异后再在锁内确认，避免与并发同步产生误报
+		disk, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return report, err
+		}
+		fs.mu.RLock()
+		cached, ok := fs.cache[key]
+		dirty := fs.dirty[key]
+		fs.mu.RUnlock()
+		if !ok || dirty {
+			continue
+		}
+		report.Checked++
+		if err == nil && bytes.Equal(cached, disk) {
+			continue
+		}
+
+		fs.mu.Lock()
+		fs.confirmDivergence(key, path, report, reload)
+		fs.mu.Unlock()
+	}
+
+	return report, nil
+}
+
+// confirmDivergence 在持有写锁时重新比较缓存与磁盘，调用方需持有 fs.mu
+func (fs *FileStorage) confirmDivergence(key, path string, report *VerifyReport, reload bool) {
+	cached, ok := fs.cache[key]
+	if !ok || fs.dirty[key] {
+		return
+	}
+
+	disk, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		report.Missing = append(report.Missing, key)
+		if reload {
+			delete(fs.cache, key)
+		}
+	case err != nil:
+		fs.log.Warn("Failed to read file during cache verification",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	case !bytes.Equal(cached, disk):
+		report.Divergent = append(report.Divergent, key)
+		if reload {
+			fs.cache[key] = disk
+		}
+	}
+}
+
+// verifyLoop 后台缓存一致性校验循环
+func (fs *FileStorage) verifyLoop() {
+	ticker := time.NewTicker(fs.config.VerifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := fs.VerifyCache(context.Background(), fs.config.VerifySampleSize, fs.config.VerifyReload)
+			if err != nil {
+				fs.log.Error("Failed to verify storage cache",
+					zap.Error(err),
+				)
+				continue
+			}
+			if !report.Consistent() {
+				fs.log.Warn("Storage cache diverged from disk",
+					zap.Strings("divergent", report.Divergent),
+					zap.Strings("missing", report.Missing),
+					zap.Bool("reloaded", report.Reloaded),
+				)
+			}
+		case <-fs.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cpfs/internal/principal"
+)
+
+// DefaultChangelogCapacity 变更日志默认保留的事件数量
+const DefaultChangelogCapacity = 65536
+
+// ErrChangelogTruncated 请求的起始序号已被变更日志淘汰
+var ErrChangelogTruncated = errors.New("changelog truncated")
+
+// Changelog 按顺序记录命名空间变更的有界日志
+//
+// 每个事件分配单调递增的序号，超出容量后最早的事件被淘汰。
+// 订阅者通过序号追赶，落后超过容量时收到 ErrChangelogTruncated。
+type Changelog struct {
+	mu       sync.Mutex
+	events   []ChangeEvent // 环形缓冲区，未满时按需增长
+	capacity int
+	first    uint64            // 保留的最早事件序号
+	base     uint64            // 环形缓冲区第一个槽位对应的事件序号
+	next     uint64            // 下一个事件序号
+	notify   chan struct{}     // 追加事件时关闭并替换，用于唤醒等待者
+	hlc      *HLC              // 为 nil 时事件只带墙钟时间
+	sink     func(ChangeEvent) // 追加事件后在锁外调用，由消费组用于持久化未确认的事件
+}
+
+// NewChangelog 创建保留 capacity 个事件的变更日志
+func NewChangelog(capacity int) *Changelog {
+	if capacity <= 0 {
+		capacity = DefaultChangelogCapacity
+	}
+	return &Changelog{
+		capacity: capacity,
+		first:    1,
+		base:     1,
+		next:     1,
+		notify:   make(chan struct{}),
+	}
+}
+
+// Append 追加事件并返回分配的序号
+func (c *Changelog) Append(e ChangeEvent) uint64 {
+	c.mu.Lock()
+	e.Seq = c.next
+	if c.hlc != nil && e.HLC == nil {
+		ts := c.hlc.Now()
+		e.HLC = &ts
+		if e.Time.IsZero() {
+			e.Time = ts.Time()
+		}
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if len(c.events) < c.capacity {
+		c.events = append(c.events, e)
+	} else {
+		c.events[c.slot(e.Seq)] = e
+	}
+	// 缓冲区写满后覆盖最早的槽位；压缩可能已经把 first 推得更靠后
+	if e.Seq >= c.first+uint64(c.capacity) {
+		c.first = e.Seq - uint64(c.capacity) + 1
+	}
+	c.next++
+
+	close(c.notify)
+	c.notify = make(chan struct{})
+	sink := c.sink
+	c.mu.Unlock()
+
+	if sink != nil {
+		sink(e)
+	}
+	return e.Seq
+}
+
+// slot 返回序号 seq 的事件在环形缓冲区中的位置，调用方需持有锁
+func (c *Changelog) slot(seq uint64) int {
+	return int((seq - c.base) % uint64(c.capacity))
+}
+
+// resumeAfter 使之后的事件序号从 seq+1 开始，用于重启后序号不回退到已被消费者确认的位置
+//
+// 只能在追加任何事件之前调用。
+func (c *Changelog) resumeAfter(seq uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.next > seq {
+		return nil
+	}
+	if len(c.events) > 0 {
+		return fmt.Errorf("changelog already has events up to %d, below consumer offset %d", c.next-1, seq)
+	}
+	c.first, c.base, c.next = seq+1, seq+1, seq+1
+	return nil
+}
+
+// restore 把重启前持久化的事件放回空日志，之后的事件序号从最后一个恢复的事件之后继续
+//
+// events 按序号升序排列；序号不连续时只恢复最后一段连续的事件，超出容量时只恢复最新的事件。
+// 只能在追加任何事件之前调用。
+func (c *Changelog) restore(events []ChangeEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	start := len(events) - 1
+	for start > 0 && events[start-1].Seq+1 == events[start].Seq {
+		start--
+	}
+	events = events[start:]
+	if len(events) > c.capacity {
+		events = events[len(events)-c.capacity:]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.events) > 0 {
+		return fmt.Errorf("changelog already has events up to %d", c.next-1)
+	}
+	if events[0].Seq < c.next {
+		return fmt.Errorf("cannot restore changelog events from %d, next sequence is %d", events[0].Seq, c.next)
+	}
+	c.events = append(c.events, events...)
+	c.first, c.base = events[0].Seq, events[0].Seq
+	c.next = events[len(events)-1].Seq + 1
+	return nil
+}
+
+// setSink 设置追加事件后的回调，为 nil 时取消
+func (c *Changelog) setSink(sink func(ChangeEvent)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sink = sink
+}
+
+// SetHLC 为之后追加的事件分配混合逻辑时钟时间戳，clock 为 nil 时停用
+//
+// 墙钟在节点间存在偏差或回拨时不能反映事件的先后，HLC 时间戳在同一节点内单调递增，
+// 并通过 HLC.Update 合并其他节点的时间戳保持因果顺序。
+func (c *Changelog) SetHLC(clock *HLC) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hlc = clock
+}
+
+// LastSeq 返回最后一个事件的序号，没有事件时返回 0
+func (c *Changelog) LastSeq() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.next - 1
+}
+
+// Since 返回序号不小于 seq 的事件，以及下一次追加事件时关闭的通知通道
+func (c *Changelog) Since(seq uint64) ([]ChangeEvent, <-chan struct{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seq < c.first {
+		return nil, nil, fmt.Errorf("%w: requested %d, oldest %d", ErrChangelogTruncated, seq, c.first)
+	}
+	var events []ChangeEvent
+	for s := seq; s < c.next; s++ {
+		events = append(events, c.events[c.slot(s)])
+	}
+	return events, c.notify, nil
+}
+
+// History 返回与路径相关的事件（Path 或 OldPath 等于 p），按时间倒序，最多 limit 条，limit 小于等于 0 表示不限制
+func (c *Changelog) History(p string, limit int) []ChangeEvent {
+	p = normalizePath(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var events []ChangeEvent
+	for s := c.next - 1; s >= c.first && s > 0; s-- {
+		e := c.events[c.slot(s)]
+		if e.Path != p && e.OldPath != p {
+			continue
+		}
+		events = append(events, e)
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+	}
+	return events
+}
+
+// Watcher 目录变更订阅
+type Watcher struct {
+	// Events 按序号递增投递的事件，订阅结束后关闭
+	Events <-chan ChangeEvent
+	err    error
+}
+
+// Err 返回订阅结束的原因，只在 Events 关闭后有效
+func (w *Watcher) Err() error {
+	return w.err
+}
+
+// Watch 订阅目录下的变更事件，直到 ctx 取消或订阅者落后超过日志容量
+//
+// fromSeq 为 0 表示只接收订阅之后的新事件，否则从该序号开始回放。
+func (c *Changelog) Watch(ctx context.Context, dir string, recursive bool, fromSeq uint64) (*Watcher, error) {
+	return c.watch(ctx, fromSeq, func(e *ChangeEvent) bool { return e.InSubtree(dir, recursive) })
+}
+
+// WatchPrefix 订阅路径前缀下的变更事件，前缀本身的变更也被投递，fromSeq 的含义与 Watch 相同
+func (c *Changelog) WatchPrefix(ctx context.Context, prefix string, fromSeq uint64) (*Watcher, error) {
+	return c.watch(ctx, fromSeq, func(e *ChangeEvent) bool { return e.HasPrefix(prefix) })
+}
+
+// watch 订阅满足 match 的变更事件
+func (c *Changelog) watch(ctx context.Context, fromSeq uint64, match func(*ChangeEvent) bool) (*Watcher, error) {
+	if fromSeq == 0 {
+		fromSeq = c.LastSeq() + 1
+	}
+	if _, _, err := c.Since(fromSeq); err != nil {
+		return nil, err
+	}
+
+	events := make(chan ChangeEvent, 64)
+	w := &Watcher{Events: events}
+	go func() {
+		defer close(events)
+		cursor := fromSeq
+		for {
+			batch, wait, err := c.Since(cursor)
+			if err != nil {
+				w.err = err
+				return
+			}
+			for i := range batch {
+				cursor = batch[i].Seq + 1
+				if !match(&batch[i]) {
+					continue
+				}
+				select {
+				case events <- batch[i]:
+				case <-ctx.Done():
+					w.err = ctx.Err()
+					return
+				}
+			}
+			select {
+			case <-wait:
+			case <-ctx.Done():
+				w.err = ctx.Err()
+				return
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Changelog 返回存储的变更日志
+func (s *MemoryStore) Changelog() *Changelog {
+	return s.changelog
+}
+
+// Watch 订阅路径前缀下的创建、删除、重命名和属性修改事件，只接收订阅之后的新事件
+//
+// 经过绑定点的前缀被替换为源目录，投递的事件使用源目录中的路径。
+func (s *MemoryStore) Watch(ctx context.Context, prefix string) (*Watcher, error) {
+	s.mu.Lock()
+	prefix = s.locate(normalizePath(prefix))
+	s.mu.Unlock()
+	return s.changelog.WatchPrefix(ctx, prefix, 0)
+}
+
+// newChangeEvent 构造变更事件，填入发起变更的主体和条目信息
+func newChangeEvent(ctx context.Context, typ ChangeType, p string, meta *Metadata) ChangeEvent {
+	e := ChangeEvent{Type: typ, Path: p}
+	if who, ok := principal.FromContext(ctx); ok {
+		e.User = who.User
+	}
+	if meta != nil {
+		e.Inode = meta.Inode
+		e.IsDir = meta.Type == TypeDirectory
+	}
+	return e
+}
+
+// recordChange 记录元数据变更，调用方需持有写锁以保证事件顺序与修改顺序一致
+func (s *MemoryStore) recordChange(ctx context.Context, typ ChangeType, p string, meta *Metadata) {
+	if changesResolution(typ, meta) {
+		s.dentries.reset()
+	}
+	s.changelog.Append(newChangeEvent(ctx, typ, p, meta))
+}
+
+// recordRename 记录重命名，目录的后代随之移动，只记录一条事件，调用方需持有写锁
+func (s *MemoryStore) recordRename(ctx context.Context, oldPath, newPath string, meta *Metadata) {
+	e := newChangeEvent(ctx, ChangeRename, newPath, meta)
+	e.OldPath = oldPath
+	s.dentries.reset()
+	s.changelog.Append(e)
+}
@@ -0,0 +1,363 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// consumerKeyPrefix 消费组偏移量的存储键前缀
+	consumerKeyPrefix = "/changelog/consumers/"
+	// pendingEventKeyPrefix 尚未被全部消费组确认的事件的存储键前缀
+	pendingEventKeyPrefix = "/changelog/events/"
+)
+
+// ErrConsumerNotFound 消费组不存在，可用 errors.Is 判断
+var ErrConsumerNotFound = errors.New("changelog consumer not found")
+
+// ConsumerOffset 消费组的确认位置
+type ConsumerOffset struct {
+	Name  string `json:"name"`
+	Acked uint64 `json:"acked"` // 已确认处理的最后一个事件序号
+	Lag   uint64 `json:"lag"`   // 尚未确认的事件数
+}
+
+// consumerState 持久化的消费组状态
+type consumerState struct {
+	Acked uint64 `json:"acked"`
+}
+
+// ConsumerGroups 变更日志的命名消费组，确认位置通过 Storage 持久化
+//
+// 索引器、镜像等消费者按组名读取确认位置之后的事件，处理完成后确认最后一个序号；
+// 确认在写入并同步存储后才生效，崩溃重启后从持久化的位置继续，不会跳过未确认的事件。
+// 结合事件序号做幂等处理（序号不大于已确认位置的事件直接丢弃），消费者即可做到恰好一次。
+//
+// 变更日志本身保存在内存中。存在消费组时，每个追加的事件也写入存储，
+// 所有消费组都确认后删除，因此重启后最慢的消费组确认位置之后的事件仍可读取。
+// 追加事件时只把事件放入队列，由后台批量写入存储（Ack 和 Delete 会先写入队列中的事件），
+// 因此元数据写入不会等待存储。事件随存储的下一次同步落盘（Ack 会同步存储），
+// 同步之前崩溃丢失的事件在重启后读取时返回 ErrChangelogTruncated。
+type ConsumerGroups struct {
+	mu        sync.Mutex
+	changelog *Changelog
+	storage   Storage
+	acked     map[string]uint64
+	trimmed   uint64 // 存储中不大于该序号的事件已删除
+	log       logger.Logger
+
+	queueMu  sync.Mutex
+	queue    []ChangeEvent // 等待写入存储的事件
+	flushing bool          // 后台写入是否在进行
+}
+
+// NewConsumerGroups 从存储加载消费组的确认位置和未确认的事件
+//
+// 加载后变更日志包含最慢的消费组确认位置之后、重启前持久化的事件，序号从其后继续；
+// 没有这样的事件时序号从最大的确认位置之后继续，已确认的序号不会被新事件复用。
+// 应在变更日志追加任何事件之前调用。
+func NewConsumerGroups(ctx context.Context, changelog *Changelog, storage Storage) (*ConsumerGroups, error) {
+	g := &ConsumerGroups{changelog: changelog, storage: storage, acked: make(map[string]uint64), log: logger.Default()}
+	keys, err := storage.List(ctx, consumerKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changelog consumers: %v", err)
+	}
+	var highest uint64
+	for _, key := range keys {
+		data, err := storage.Load(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load changelog consumer %s: %v", key, err)
+		}
+		var state consumerState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("failed to decode changelog consumer %s: %v", key, err)
+		}
+		name := strings.TrimPrefix(normalizePath(key), consumerKeyPrefix)
+		g.acked[name] = state.Acked
+		highest = max(highest, state.Acked)
+	}
+	if err := g.restorePending(ctx); err != nil {
+		return nil, err
+	}
+	if err := changelog.resumeAfter(highest); err != nil {
+		return nil, err
+	}
+	changelog.setSink(g.persist)
+	return g, nil
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (g *ConsumerGroups) SetLogger(l logger.Logger) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.log = logger.OrDefault(l)
+}
+
+// pendingEventKey 返回事件在存储中的键，序号补零使键按序号排序
+func pendingEventKey(seq uint64) string {
+	return fmt.Sprintf("%s%020d", pendingEventKeyPrefix, seq)
+}
+
+// slowest 返回最慢的消费组的确认位置，没有消费组时 ok 为 false，调用方需持有锁
+func (g *ConsumerGroups) slowest() (uint64, bool) {
+	if len(g.acked) == 0 {
+		return 0, false
+	}
+	slowest := ^uint64(0)
+	for _, acked := range g.acked {
+		slowest = min(slowest, acked)
+	}
+	return slowest, true
+}
+
+// restorePending 把持久化的未确认事件放回变更日志，删除已被全部消费组确认的事件
+func (g *ConsumerGroups) restorePending(ctx context.Context) error {
+	keys, err := g.storage.List(ctx, pendingEventKeyPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list pending changelog events: %v", err)
+	}
+	slowest, ok := g.slowest()
+	var events []ChangeEvent
+	for _, key := range keys {
+		data, err := g.storage.Load(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to load changelog event %s: %v", key, err)
+		}
+		var e ChangeEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("failed to decode changelog event %s: %v", key, err)
+		}
+		if !ok || e.Seq <= slowest {
+			if err := g.storage.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete changelog event %s: %v", key, err)
+			}
+			continue
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	g.trimmed = slowest
+	return g.changelog.restore(events)
+}
+
+// persist 把新追加的事件放入写入队列，由变更日志在追加后调用
+//
+// 变更日志在元数据存储的写锁内追加事件，这里不访问存储也不获取 g.mu，
+// 避免元数据写入等待正在同步存储的 Ack。
+func (g *ConsumerGroups) persist(e ChangeEvent) {
+	g.queueMu.Lock()
+	g.queue = append(g.queue, e)
+	start := !g.flushing
+	g.flushing = true
+	g.queueMu.Unlock()
+	if start {
+		go g.flushQueue()
+	}
+}
+
+// flushQueue 在后台把队列中的事件写入存储，队列为空时退出
+func (g *ConsumerGroups) flushQueue() {
+	for {
+		g.mu.Lock()
+		g.writeQueued(context.Background())
+		g.mu.Unlock()
+
+		g.queueMu.Lock()
+		if len(g.queue) == 0 {
+			g.flushing = false
+			g.queueMu.Unlock()
+			return
+		}
+		g.queueMu.Unlock()
+	}
+}
+
+// writeQueued 在存在消费组时把队列中的事件写入存储，调用方需持有锁
+//
+// 已被全部消费组确认或在第一个消费组创建之前追加的事件直接丢弃。
+func (g *ConsumerGroups) writeQueued(ctx context.Context) {
+	g.queueMu.Lock()
+	events := g.queue
+	g.queue = nil
+	g.queueMu.Unlock()
+	if _, ok := g.slowest(); !ok {
+		return
+	}
+
+	for _, e := range events {
+		if e.Seq <= g.trimmed {
+			continue
+		}
+		data, err := json.Marshal(e)
+		if err == nil {
+			err = g.storage.Save(ctx, pendingEventKey(e.Seq), data)
+		}
+		if err != nil {
+			g.log.Warn("Failed to persist changelog event", zap.Uint64("seq", e.Seq), zap.Error(err))
+		}
+	}
+}
+
+// trim 删除已被全部消费组确认的持久化事件，调用方需持有锁
+func (g *ConsumerGroups) trim(ctx context.Context) {
+	slowest, ok := g.slowest()
+	if !ok {
+		slowest = g.changelog.LastSeq()
+	}
+	for seq := g.trimmed + 1; seq <= slowest; seq++ {
+		if err := g.storage.Delete(ctx, pendingEventKey(seq)); err != nil {
+			g.log.Warn("Failed to delete acknowledged changelog event", zap.Uint64("seq", seq), zap.Error(err))
+			return
+		}
+		g.trimmed = seq
+	}
+}
+
+// validConsumerName 检查组名可以作为存储键的一段
+func validConsumerName(name string) error {
+	if name == "" || strings.ContainsAny(name, "/\\") || name == "." || name == ".." {
+		return fmt.Errorf("invalid changelog consumer name: %q", name)
+	}
+	return nil
+}
+
+// save 写入并同步消费组的确认位置
+func (g *ConsumerGroups) save(ctx context.Context, name string, acked uint64) error {
+	data, err := json.Marshal(consumerState{Acked: acked})
+	if err != nil {
+		return fmt.Errorf("failed to encode changelog consumer %s: %v", name, err)
+	}
+	if err := g.storage.Save(ctx, consumerKeyPrefix+name, data); err != nil {
+		return fmt.Errorf("failed to save changelog consumer %s: %v", name, err)
+	}
+	if err := g.storage.Sync(); err != nil {
+		return fmt.Errorf("failed to sync changelog consumer %s: %v", name, err)
+	}
+	return nil
+}
+
+// Create 创建消费组，从序号 fromSeq 开始消费，fromSeq 为 0 时从之后的新事件开始；组已存在时不做修改
+func (g *ConsumerGroups) Create(ctx context.Context, name string, fromSeq uint64) error {
+	if err := validConsumerName(name); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, exists := g.acked[name]; exists {
+		return nil
+	}
+	g.writeQueued(ctx)
+	last := g.changelog.LastSeq()
+	acked := last
+	if fromSeq > 0 {
+		acked = fromSeq - 1
+	}
+	if err := g.save(ctx, name, acked); err != nil {
+		return err
+	}
+	// 没有消费组时事件不写入存储，之前的事件无需删除
+	if len(g.acked) == 0 {
+		g.trimmed = max(g.trimmed, last)
+	}
+	g.acked[name] = acked
+	return nil
+}
+
+// Delete 删除消费组
+func (g *ConsumerGroups) Delete(ctx context.Context, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, exists := g.acked[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrConsumerNotFound, name)
+	}
+	g.writeQueued(ctx)
+	if err := g.storage.Delete(ctx, consumerKeyPrefix+name); err != nil {
+		return fmt.Errorf("failed to delete changelog consumer %s: %v", name, err)
+	}
+	delete(g.acked, name)
+	g.trim(ctx)
+	return nil
+}
+
+// Offset 返回消费组已确认的最后一个事件序号
+func (g *ConsumerGroups) Offset(name string) (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	acked, exists := g.acked[name]
+	if !exists {
+		return 0, fmt.Errorf("%w: %s", ErrConsumerNotFound, name)
+	}
+	return acked, nil
+}
+
+// List 返回全部消费组的确认位置与积压，按组名排序
+func (g *ConsumerGroups) List() []ConsumerOffset {
+	last := g.changelog.LastSeq()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]ConsumerOffset, 0, len(g.acked))
+	for name, acked := range g.acked {
+		offset := ConsumerOffset{Name: name, Acked: acked}
+		if last > acked {
+			offset.Lag = last - acked
+		}
+		out = append(out, offset)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Fetch 返回消费组确认位置之后最多 limit 个事件（limit 为 0 时不限制），以及下一次追加事件时关闭的通知通道
+//
+// 未确认的事件每次 Fetch 都会重新返回。确认位置之后的事件已被日志淘汰时返回 ErrChangelogTruncated。
+func (g *ConsumerGroups) Fetch(name string, limit int) ([]ChangeEvent, <-chan struct{}, error) {
+	acked, err := g.Offset(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, wait, err := g.changelog.Since(acked + 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, wait, nil
+}
+
+// Ack 确认消费组已处理到序号 seq（含），持久化后返回
+//
+// 不大于当前确认位置的序号视为重复确认并直接返回，因此消费者可以安全地重试确认；
+// 确认尚未产生的序号返回错误。
+func (g *ConsumerGroups) Ack(ctx context.Context, name string, seq uint64) error {
+	last := g.changelog.LastSeq()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	acked, exists := g.acked[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConsumerNotFound, name)
+	}
+	if seq <= acked {
+		return nil
+	}
+	if seq > last {
+		return fmt.Errorf("cannot acknowledge event %d, the latest event is %d", seq, last)
+	}
+	// 未确认的事件与确认位置在同一次同步中落盘
+	g.writeQueued(ctx)
+	if err := g.save(ctx, name, seq); err != nil {
+		return err
+	}
+	g.acked[name] = seq
+	g.trim(ctx)
+	return nil
+}
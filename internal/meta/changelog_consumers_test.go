@@ -0,0 +1,200 @@
+package meta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumerGroupsFetchAck(t *testing.T) {
+	ctx := context.Background()
+	storage := newAllocStorage(t)
+	c := NewChangelog(16)
+	groups, err := NewConsumerGroups(ctx, c, storage)
+	require.NoError(t, err)
+
+	c.Append(ChangeEvent{Type: ChangeCreate, Path: "/old"})
+	require.NoError(t, groups.Create(ctx, "indexer", 0))
+	require.NoError(t, groups.Create(ctx, "mirror", 1))
+	require.Error(t, groups.Create(ctx, "a/b", 0))
+
+	for _, p := range []string{"/a", "/b", "/c"} {
+		c.Append(ChangeEvent{Type: ChangeCreate, Path: p})
+	}
+
+	// 从最新位置创建的组只看到之后的事件，指定起点的组从该序号开始
+	events, _, err := groups.Fetch("indexer", 2)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "/a", events[0].Path)
+	events, _, err = groups.Fetch("mirror", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, "/old", events[0].Path)
+
+	// 未确认的事件再次返回，重复确认是幂等的
+	require.NoError(t, groups.Ack(ctx, "indexer", 3))
+	require.NoError(t, groups.Ack(ctx, "indexer", 2))
+	events, _, err = groups.Fetch("indexer", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "/c", events[0].Path)
+
+	assert.Error(t, groups.Ack(ctx, "indexer", 9))
+	assert.ErrorIs(t, groups.Ack(ctx, "missing", 1), ErrConsumerNotFound)
+
+	assert.Equal(t, []ConsumerOffset{
+		{Name: "indexer", Acked: 3, Lag: 1},
+		{Name: "mirror", Acked: 0, Lag: 4},
+	}, groups.List())
+
+	require.NoError(t, groups.Delete(ctx, "mirror"))
+	_, err = groups.Offset("mirror")
+	assert.ErrorIs(t, err, ErrConsumerNotFound)
+}
+
+func TestConsumerGroupsResumeAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	storage := newAllocStorage(t)
+	c := NewChangelog(16)
+	groups, err := NewConsumerGroups(ctx, c, storage)
+	require.NoError(t, err)
+	require.NoError(t, groups.Create(ctx, "indexer", 0))
+	for _, p := range []string{"/a", "/b", "/c"} {
+		c.Append(ChangeEvent{Type: ChangeCreate, Path: p})
+	}
+	require.NoError(t, groups.Ack(ctx, "indexer", 2))
+
+	// 重启后未确认的事件从存储恢复，序号在其后继续，已确认的序号不会被复用
+	restarted := NewChangelog(16)
+	groups, err = NewConsumerGroups(ctx, restarted, storage)
+	require.NoError(t, err)
+	offset, err := groups.Offset("indexer")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), offset)
+	assert.Equal(t, uint64(3), restarted.LastSeq())
+
+	seq := restarted.Append(ChangeEvent{Type: ChangeCreate, Path: "/d"})
+	assert.Equal(t, uint64(4), seq)
+	events, _, err := groups.Fetch("indexer", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "/c", events[0].Path)
+	assert.Equal(t, uint64(3), events[0].Seq)
+	assert.Equal(t, "/d", events[1].Path)
+
+	// 已有事件的日志不能再回退序号
+	assert.NoError(t, restarted.resumeAfter(2))
+	assert.Error(t, restarted.resumeAfter(10))
+}
+
+func TestConsumerGroupsPersistUpToSlowest(t *testing.T) {
+	ctx := context.Background()
+	storage := newAllocStorage(t)
+	c := NewChangelog(16)
+	groups, err := NewConsumerGroups(ctx, c, storage)
+	require.NoError(t, err)
+
+	// 没有消费组时事件不写入存储
+	c.Append(ChangeEvent{Type: ChangeCreate, Path: "/before"})
+	keys, err := storage.List(ctx, pendingEventKeyPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, groups.Create(ctx, "fast", 0))
+	require.NoError(t, groups.Create(ctx, "slow", 0))
+	for _, p := range []string{"/a", "/b", "/c", "/d"} {
+		c.Append(ChangeEvent{Type: ChangeCreate, Path: p})
+	}
+	require.NoError(t, groups.Ack(ctx, "fast", 5))
+	require.NoError(t, groups.Ack(ctx, "slow", 3))
+
+	// 只保留最慢的消费组确认位置之后的事件
+	keys, err = storage.List(ctx, pendingEventKeyPrefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	restarted := NewChangelog(16)
+	groups, err = NewConsumerGroups(ctx, restarted, storage)
+	require.NoError(t, err)
+	events, _, err := groups.Fetch("slow", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "/c", events[0].Path)
+	assert.Equal(t, "/d", events[1].Path)
+	events, _, err = groups.Fetch("fast", 0)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, uint64(6), restarted.Append(ChangeEvent{Type: ChangeCreate, Path: "/e"}))
+
+	// 删除最后一个消费组后持久化的事件全部删除
+	require.NoError(t, groups.Delete(ctx, "slow"))
+	keys, err = storage.List(ctx, pendingEventKeyPrefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+	require.NoError(t, groups.Delete(ctx, "fast"))
+	keys, err = storage.List(ctx, pendingEventKeyPrefix)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestConsumerGroupsPersistAsync(t *testing.T) {
+	ctx := context.Background()
+	storage := newAllocStorage(t)
+	c := NewChangelog(16)
+	groups, err := NewConsumerGroups(ctx, c, storage)
+	require.NoError(t, err)
+	require.NoError(t, groups.Create(ctx, "indexer", 0))
+
+	// 消费组的锁被占用（例如 Ack 正在同步存储）时追加事件不会阻塞
+	groups.mu.Lock()
+	appended := make(chan uint64)
+	go func() { appended <- c.Append(ChangeEvent{Type: ChangeCreate, Path: "/a"}) }()
+	select {
+	case seq := <-appended:
+		assert.Equal(t, uint64(1), seq)
+	case <-time.After(time.Second):
+		t.Fatal("append blocked on consumer groups")
+	}
+	groups.mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		keys, err := storage.List(ctx, pendingEventKeyPrefix)
+		return err == nil && len(keys) == 1
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestChangelogRestore(t *testing.T) {
+	c := NewChangelog(2)
+	// 序号不连续时只恢复最后一段，超出容量时只保留最新的事件
+	require.NoError(t, c.restore([]ChangeEvent{
+		{Seq: 3, Path: "/a"}, {Seq: 5, Path: "/b"}, {Seq: 6, Path: "/c"}, {Seq: 7, Path: "/d"},
+	}))
+	_, _, err := c.Since(5)
+	assert.ErrorIs(t, err, ErrChangelogTruncated)
+	events, _, err := c.Since(6)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "/c", events[0].Path)
+	assert.Equal(t, uint64(8), c.Append(ChangeEvent{Path: "/e"}))
+	assert.Error(t, c.restore([]ChangeEvent{{Seq: 9}}))
+}
+
+func TestChangelogRingAfterResume(t *testing.T) {
+	c := NewChangelog(2)
+	require.NoError(t, c.resumeAfter(5))
+	for _, p := range []string{"/a", "/b", "/c"} {
+		c.Append(ChangeEvent{Type: ChangeCreate, Path: p})
+	}
+	events, _, err := c.Since(7)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "/b", events[0].Path)
+	assert.Equal(t, uint64(8), events[1].Seq)
+	_, _, err = c.Since(6)
+	assert.ErrorIs(t, err, ErrChangelogTruncated)
+	assert.Len(t, c.History("/c", 0), 1)
+}
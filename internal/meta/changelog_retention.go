@@ -0,0 +1,272 @@
+package meta
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/logger"
+	"cpfs/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// DefaultCompactInterval 变更日志压缩的默认检查间隔
+const DefaultCompactInterval = time.Minute
+
+// ChangelogRetention 变更日志的保留策略
+//
+// 事件超过 MaxAge 或保留数量超过 MaxEvents 时可以被压缩；RestoreWindow 内的事件总是保留，
+// 用于时间点恢复时重放。三者为 0 时不按该条件压缩。容量仍是内存上限：写满后最早的事件被覆盖，与保留策略无关。
+type ChangelogRetention struct {
+	MaxAge        time.Duration
+	MaxEvents     int
+	RestoreWindow time.Duration
+}
+
+// ChangelogRetentionFromServer 根据服务器配置的 ChangelogMaxAge、ChangelogRestoreWindow（秒）和 ChangelogMaxEvents 创建保留策略
+func ChangelogRetentionFromServer(cfg *config.ServerConfig) ChangelogRetention {
+	return ChangelogRetention{
+		MaxAge:        time.Duration(cfg.ChangelogMaxAge) * time.Second,
+		MaxEvents:     cfg.ChangelogMaxEvents,
+		RestoreWindow: time.Duration(cfg.ChangelogRestoreWindow) * time.Second,
+	}
+}
+
+// RetentionHold 阻止变更日志压缩的保留方，例如消费组和快照
+type RetentionHold interface {
+	// RetainFrom 返回必须保留的最早事件序号，没有需要保留的事件时 ok 为 false
+	RetainFrom(c *Changelog) (seq uint64, ok bool)
+}
+
+// TimeHold 按时间保留事件的保留方：返回的时间之后的事件必须保留，例如最早的快照的创建时间
+type TimeHold func() (time.Time, bool)
+
+// RetainFrom 实现 RetentionHold
+func (h TimeHold) RetainFrom(c *Changelog) (uint64, bool) {
+	t, ok := h()
+	if !ok {
+		return 0, false
+	}
+	return c.seqAt(t), true
+}
+
+// RetainFrom 实现 RetentionHold：最慢的消费组尚未确认的第一个事件
+func (g *ConsumerGroups) RetainFrom(c *Changelog) (uint64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	slowest, ok := g.slowest()
+	if !ok {
+		return 0, false
+	}
+	return slowest + 1, true
+}
+
+// seqAt 返回时间不早于 t 的第一个保留事件的序号，全部事件都早于 t 时返回下一个事件序号
+//
+// 按事件时间二分查找，墙钟回拨造成的乱序可能使结果偏离几个相邻事件。
+func (c *Changelog) seqAt(t time.Time) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := int(c.next - c.first)
+	i := sort.Search(n, func(i int) bool {
+		return !c.events[c.slot(c.first+uint64(i))].Time.Before(t)
+	})
+	return c.first + uint64(i)
+}
+
+// compact 丢弃序号不大于 upTo 的事件，返回丢弃的数量
+func (c *Changelog) compact(upTo uint64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if upTo >= c.next {
+		upTo = c.next - 1
+	}
+	dropped := 0
+	for ; c.first <= upTo; c.first++ {
+		// 清空槽位，释放事件引用的数据
+		c.events[c.slot(c.first)] = ChangeEvent{}
+		dropped++
+	}
+	return dropped
+}
+
+// retained 返回保留的最早事件序号、事件数和最早事件的时间
+func (c *Changelog) retained() (first uint64, count int, oldest time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count = int(c.next - c.first)
+	if count > 0 {
+		oldest = c.events[c.slot(c.first)].Time
+	}
+	return c.first, count, oldest
+}
+
+// CompactResult 一次压缩的结果
+type CompactResult struct {
+	Eligible  uint64 // 按保留策略可以压缩到的序号（含），0 表示没有
+	Floor     uint64 // 保留方和恢复窗口要求保留的最早序号，0 表示没有限制
+	HeldBy    string // 限制压缩位置的保留方，没有被限制时为空
+	Compacted int    // 实际丢弃的事件数
+}
+
+// ChangelogCompactor 按保留策略压缩变更日志，不丢弃任何保留方仍需要的事件
+//
+// 压缩位置取保留策略允许的位置与最早的保留点之前一个序号中的较小者：最慢的消费组尚未确认的事件、
+// 最早的快照之后的事件以及恢复窗口内的事件都不会被压缩。保留方阻止压缩时事件继续累积直到容量上限，
+// 被阻止的事件数导出为指标以便发现停滞的消费者。
+type ChangelogCompactor struct {
+	changelog *Changelog
+	policy    ChangelogRetention
+	now       func() time.Time
+
+	compacted *metrics.Counter
+	blocked   *metrics.Gauge
+
+	mu    sync.Mutex
+	holds map[string]RetentionHold
+	log   logger.Logger
+}
+
+// NewChangelogCompactor 创建变更日志压缩器，registry 为 nil 时使用 metrics.Default
+func NewChangelogCompactor(changelog *Changelog, policy ChangelogRetention, registry *metrics.Registry) *ChangelogCompactor {
+	if registry == nil {
+		registry = metrics.Default
+	}
+	c := &ChangelogCompactor{
+		changelog: changelog,
+		policy:    policy,
+		now:       time.Now,
+		compacted: registry.Counter("cpfs_changelog_compacted_events_total", "Changelog events dropped by retention compaction.", nil),
+		blocked:   registry.Gauge("cpfs_changelog_compaction_blocked_events", "Changelog events past retention that are kept for a consumer, snapshot or restore window.", nil),
+		holds:     make(map[string]RetentionHold),
+		log:       logger.Default(),
+	}
+	registry.GaugeFunc("cpfs_changelog_retained_events", "Changelog events currently retained.", nil, func() float64 {
+		_, count, _ := changelog.retained()
+		return float64(count)
+	})
+	registry.GaugeFunc("cpfs_changelog_oldest_event_age_seconds", "Age of the oldest retained changelog event.", nil, func() float64 {
+		_, count, oldest := changelog.retained()
+		if count == 0 {
+			return 0
+		}
+		return c.now().Sub(oldest).Seconds()
+	})
+	return c
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (c *ChangelogCompactor) SetLogger(l logger.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.log = logger.OrDefault(l)
+}
+
+// AddHold 注册名为 name 的保留方，同名的保留方被替换
+func (c *ChangelogCompactor) AddHold(name string, hold RetentionHold) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.holds[name] = hold
+}
+
+// RemoveHold 移除保留方
+func (c *ChangelogCompactor) RemoveHold(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.holds, name)
+}
+
+// eligible 返回保留策略允许压缩到的序号（含），0 表示没有可压缩的事件
+func (c *ChangelogCompactor) eligible(now time.Time) uint64 {
+	first, count, _ := c.changelog.retained()
+	if count == 0 {
+		return 0
+	}
+	var upTo uint64
+	if c.policy.MaxEvents > 0 && count > c.policy.MaxEvents {
+		upTo = first + uint64(count-c.policy.MaxEvents) - 1
+	}
+	if c.policy.MaxAge > 0 {
+		if seq := c.changelog.seqAt(now.Add(-c.policy.MaxAge)); seq > first && seq-1 > upTo {
+			upTo = seq - 1
+		}
+	}
+	return upTo
+}
+
+// floor 返回必须保留的最早序号以及限制它的保留方，0 表示没有限制
+func (c *ChangelogCompactor) floor(now time.Time) (uint64, string) {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.holds))
+	holds := make(map[string]RetentionHold, len(c.holds))
+	for name, hold := range c.holds {
+		names = append(names, name)
+		holds[name] = hold
+	}
+	c.mu.Unlock()
+	sort.Strings(names)
+
+	var floor uint64
+	var heldBy string
+	if c.policy.RestoreWindow > 0 {
+		floor, heldBy = c.changelog.seqAt(now.Add(-c.policy.RestoreWindow)), "restore_window"
+	}
+	for _, name := range names {
+		seq, ok := holds[name].RetainFrom(c.changelog)
+		if ok && (floor == 0 || seq < floor) {
+			floor, heldBy = seq, name
+		}
+	}
+	return floor, heldBy
+}
+
+// Compact 按保留策略压缩一次变更日志
+func (c *ChangelogCompactor) Compact() CompactResult {
+	now := c.now()
+	result := CompactResult{Eligible: c.eligible(now)}
+	result.Floor, result.HeldBy = c.floor(now)
+
+	upTo := result.Eligible
+	if result.Floor > 0 && upTo >= result.Floor {
+		upTo = result.Floor - 1
+		c.blocked.Set(float64(result.Eligible - upTo))
+	} else {
+		result.HeldBy = ""
+		c.blocked.Set(0)
+	}
+	if upTo > 0 {
+		result.Compacted = c.changelog.compact(upTo)
+	}
+	if result.Compacted > 0 {
+		c.compacted.Add(uint64(result.Compacted))
+		c.mu.Lock()
+		log := c.log
+		c.mu.Unlock()
+		log.Debug("Compacted changelog",
+			zap.Int("events", result.Compacted),
+			zap.Uint64("up_to", upTo),
+			zap.String("held_by", result.HeldBy),
+		)
+	}
+	return result
+}
+
+// Run 每隔 interval 压缩一次，为 0 时使用 DefaultCompactInterval；阻塞到 ctx 被取消
+func (c *ChangelogCompactor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCompactInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Compact()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
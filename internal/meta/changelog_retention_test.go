@@ -0,0 +1,115 @@
+package meta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendAt 追加一个指定时间的事件
+func appendAt(c *Changelog, p string, at time.Time) uint64 {
+	return c.Append(ChangeEvent{Type: ChangeCreate, Path: p, Time: at})
+}
+
+func TestChangelogCompactorPolicy(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChangelog(16)
+	for i := 0; i < 10; i++ {
+		appendAt(c, "/f", start.Add(time.Duration(i)*time.Minute))
+	}
+	now := start.Add(10 * time.Minute)
+
+	registry := metrics.NewRegistry()
+	compactor := NewChangelogCompactor(c, ChangelogRetention{MaxEvents: 8, MaxAge: 5*time.Minute + 30*time.Second}, registry)
+	compactor.now = func() time.Time { return now }
+
+	// 超过 5 分 30 秒的事件是前 5 个，比数量上限要求的 2 个更多
+	result := compactor.Compact()
+	assert.Equal(t, CompactResult{Eligible: 5, Compacted: 5}, result)
+	_, _, err := c.Since(5)
+	assert.ErrorIs(t, err, ErrChangelogTruncated)
+	events, _, err := c.Since(6)
+	require.NoError(t, err)
+	assert.Len(t, events, 5)
+	assert.Equal(t, uint64(5), compactor.compacted.Value())
+
+	// 再次压缩没有新的可压缩事件
+	assert.Equal(t, CompactResult{}, compactor.Compact())
+}
+
+func TestChangelogCompactorHolds(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChangelog(32)
+	groups, err := NewConsumerGroups(ctx, c, newAllocStorage(t))
+	require.NoError(t, err)
+	require.NoError(t, groups.Create(ctx, "indexer", 1))
+	for i := 0; i < 10; i++ {
+		appendAt(c, "/f", start.Add(time.Duration(i)*time.Minute))
+	}
+	now := start.Add(time.Hour)
+
+	compactor := NewChangelogCompactor(c, ChangelogRetention{MaxAge: time.Minute}, metrics.NewRegistry())
+	compactor.now = func() time.Time { return now }
+	compactor.AddHold("consumers", groups)
+	snapshotAt, haveSnapshot := start.Add(6*time.Minute), true
+	compactor.AddHold("snapshots", TimeHold(func() (time.Time, bool) { return snapshotAt, haveSnapshot }))
+
+	// 消费组尚未确认任何事件，全部保留
+	result := compactor.Compact()
+	assert.Equal(t, CompactResult{Eligible: 10, Floor: 1, HeldBy: "consumers"}, result)
+	assert.Equal(t, float64(10), compactor.blocked.Value())
+
+	// 消费组确认后，压缩停在最早的快照之前
+	require.NoError(t, groups.Ack(ctx, "indexer", 9))
+	result = compactor.Compact()
+	assert.Equal(t, CompactResult{Eligible: 10, Floor: 7, HeldBy: "snapshots", Compacted: 6}, result)
+	events, _, err := groups.Fetch("indexer", 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	// 快照删除后只受消费组限制，未确认的事件仍保留
+	haveSnapshot = false
+	result = compactor.Compact()
+	assert.Equal(t, CompactResult{Eligible: 10, Floor: 10, HeldBy: "consumers", Compacted: 3}, result)
+	events, _, err = groups.Fetch("indexer", 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	compactor.RemoveHold("consumers")
+	result = compactor.Compact()
+	assert.Equal(t, 1, result.Compacted)
+	assert.Equal(t, float64(0), compactor.blocked.Value())
+}
+
+func TestChangelogCompactorRestoreWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewChangelog(16)
+	for i := 0; i < 6; i++ {
+		appendAt(c, "/f", start.Add(time.Duration(i)*time.Hour))
+	}
+	compactor := NewChangelogCompactor(c, ChangelogRetention{MaxEvents: 1, RestoreWindow: 3 * time.Hour}, metrics.NewRegistry())
+	compactor.now = func() time.Time { return start.Add(6 * time.Hour) }
+
+	// 恢复窗口内的最后 3 个事件保留，即使超过数量上限
+	result := compactor.Compact()
+	assert.Equal(t, CompactResult{Eligible: 5, Floor: 4, HeldBy: "restore_window", Compacted: 3}, result)
+	events, _, err := c.Since(4)
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+
+	// 容量淘汰与压缩后的起点衔接
+	for i := 0; i < 16; i++ {
+		appendAt(c, "/g", start.Add(7*time.Hour))
+	}
+	_, _, err = c.Since(6)
+	assert.ErrorIs(t, err, ErrChangelogTruncated)
+	events, _, err = c.Since(7)
+	require.NoError(t, err)
+	assert.Len(t, events, 16)
+}
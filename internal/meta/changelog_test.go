@@ -0,0 +1,185 @@
+package meta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangelogRetention(t *testing.T) {
+	c := NewChangelog(3)
+	assert.Equal(t, uint64(0), c.LastSeq())
+
+	for _, p := range []string{"/a", "/b", "/c", "/d"} {
+		c.Append(ChangeEvent{Type: ChangeCreate, Path: p})
+	}
+	assert.Equal(t, uint64(4), c.LastSeq())
+
+	events, _, err := c.Since(2)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "/b", events[0].Path)
+	assert.Equal(t, uint64(4), events[2].Seq)
+
+	_, _, err = c.Since(1)
+	assert.ErrorIs(t, err, ErrChangelogTruncated)
+
+	events, _, err = c.Since(5)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestChangeEventInSubtree(t *testing.T) {
+	tests := []struct {
+		event     ChangeEvent
+		dir       string
+		recursive bool
+		want      bool
+	}{
+		{ChangeEvent{Path: "/a/b"}, "/a", false, true},
+		{ChangeEvent{Path: "/a/b/c"}, "/a", false, false},
+		{ChangeEvent{Path: "/a/b/c"}, "/a", true, true},
+		{ChangeEvent{Path: "/a"}, "/a", true, false},
+		{ChangeEvent{Path: "/ab/c"}, "/a", true, false},
+		{ChangeEvent{Path: "/x", OldPath: "/a/x"}, "/a", false, true},
+		{ChangeEvent{Path: "/a"}, "/", false, true},
+		{ChangeEvent{Path: "/a/b"}, "/", true, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.event.InSubtree(tt.dir, tt.recursive), "%+v in %s", tt.event, tt.dir)
+	}
+}
+
+func TestMemoryStoreWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/src", 0755))
+
+	watcher, err := store.Changelog().Watch(ctx, "/src", true, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Mkdir(ctx, "/src/pkg", 0755))
+	_, err = store.Create(ctx, "/other", 0644)
+	require.NoError(t, err)
+	file, err := store.Create(ctx, "/src/pkg/main.go", 0644)
+	require.NoError(t, err)
+	file.Size = 10
+	require.NoError(t, store.Update(ctx, "/src/pkg/main.go", file))
+	require.NoError(t, store.Delete(ctx, "/src/pkg/main.go"))
+
+	want := []struct {
+		typ  ChangeType
+		path string
+	}{
+		{ChangeCreate, "/src/pkg"},
+		{ChangeCreate, "/src/pkg/main.go"},
+		{ChangeModify, "/src/pkg/main.go"},
+		{ChangeDelete, "/src/pkg/main.go"},
+	}
+	for _, w := range want {
+		select {
+		case e := <-watcher.Events:
+			assert.Equal(t, w.typ, e.Type)
+			assert.Equal(t, w.path, e.Path)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s %s", w.typ, w.path)
+		}
+	}
+
+	cancel()
+	for range watcher.Events {
+	}
+	assert.ErrorIs(t, watcher.Err(), context.Canceled)
+}
+
+func TestChangeEventHasPrefix(t *testing.T) {
+	tests := []struct {
+		event  ChangeEvent
+		prefix string
+		want   bool
+	}{
+		{ChangeEvent{Path: "/a"}, "/a", true},
+		{ChangeEvent{Path: "/a/b/c"}, "/a", true},
+		{ChangeEvent{Path: "/ab"}, "/a", false},
+		{ChangeEvent{Path: "/x", OldPath: "/a/x"}, "/a/", true},
+		{ChangeEvent{Path: "/x"}, "/", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.event.HasPrefix(tt.prefix), "%+v under %s", tt.event, tt.prefix)
+	}
+}
+
+func TestMemoryStoreWatchPrefix(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore()
+	_, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/fx", 0644)
+	require.NoError(t, err)
+
+	watcher, err := store.Watch(ctx, "/f")
+	require.NoError(t, err)
+
+	_, err = store.SetAttr(ctx, "/fx", Attrs{Mode: 0600}, SetAttrMode)
+	require.NoError(t, err)
+	_, err = store.SetAttr(ctx, "/f", Attrs{Mode: 0600}, SetAttrMode)
+	require.NoError(t, err)
+	require.NoError(t, store.Rename(ctx, "/f", "/g"))
+
+	for _, want := range []ChangeType{ChangeAttr, ChangeRename} {
+		select {
+		case e := <-watcher.Events:
+			assert.Equal(t, want, e.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+	assert.Equal(t, "attr", ChangeAttr.String())
+}
+
+func TestChangelogWatchReplayAndTruncation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := NewChangelog(2)
+	c.Append(ChangeEvent{Type: ChangeCreate, Path: "/a"})
+	c.Append(ChangeEvent{Type: ChangeCreate, Path: "/b"})
+
+	watcher, err := c.Watch(ctx, "/", false, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "/a", (<-watcher.Events).Path)
+	assert.Equal(t, "/b", (<-watcher.Events).Path)
+
+	c.Append(ChangeEvent{Type: ChangeCreate, Path: "/c"})
+	_, err = c.Watch(ctx, "/", false, 1)
+	assert.ErrorIs(t, err, ErrChangelogTruncated)
+}
+
+func TestChangelogHistory(t *testing.T) {
+	c := NewChangelog(4)
+	c.Append(ChangeEvent{Type: ChangeCreate, Path: "/a"})
+	c.Append(ChangeEvent{Type: ChangeCreate, Path: "/b"})
+	c.Append(ChangeEvent{Type: ChangeRename, OldPath: "/a", Path: "/c"})
+	c.Append(ChangeEvent{Type: ChangeModify, Path: "/c"})
+	c.Append(ChangeEvent{Type: ChangeDelete, Path: "/c"})
+
+	history := c.History("/c", 0)
+	require.Len(t, history, 3)
+	assert.Equal(t, ChangeDelete, history[0].Type)
+	assert.Equal(t, ChangeRename, history[2].Type)
+
+	// /a 的创建事件已被淘汰，只剩重命名
+	history = c.History("/a", 0)
+	require.Len(t, history, 1)
+	assert.Equal(t, ChangeRename, history[0].Type)
+
+	assert.Len(t, c.History("/c", 2), 2)
+	assert.Empty(t, NewChangelog(4).History("/a", 0))
+}
@@ -0,0 +1,74 @@
+package meta
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"sync"
+
+	"cpfs/internal/bufpool"
+)
+
+// compressMagic 压缩数据的格式头，没有该头的数据按未压缩处理以兼容旧文件
+var compressMagic = []byte("CPZ\x01")
+
+// storageBuffers 存储热路径使用的缓冲池
+var storageBuffers = bufpool.New(bufpool.DefaultMaxRetained)
+
+// flateWriters 复用的 flate 压缩器，每个压缩器内部持有较大的状态表
+var flateWriters = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// flateReaders 复用的 flate 解压器
+var flateReaders = sync.Pool{
+	New: func() any {
+		return flate.NewReader(nil)
+	},
+}
+
+// compressData 压缩数据，返回带格式头的新切片
+func compressData(data []byte) ([]byte, error) {
+	buf := storageBuffers.Get()
+	defer storageBuffers.Put(buf)
+
+	buf.Write(compressMagic)
+	w := flateWriters.Get().(*flate.Writer)
+	defer flateWriters.Put(w)
+	w.Reset(buf)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %v", err)
+	}
+
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// decompressData 解压 compressData 的输出，没有格式头的数据原样返回
+func decompressData(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, compressMagic) {
+		return data, nil
+	}
+
+	buf := storageBuffers.Get()
+	defer storageBuffers.Put(buf)
+
+	r := flateReaders.Get().(io.ReadCloser)
+	defer flateReaders.Put(r)
+	if err := r.(flate.Resetter).Reset(bytes.NewReader(data[len(compressMagic):]), nil); err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %v", err)
+	}
+
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("failed to decompress data: %v", err)
+	}
+
+	return bytes.Clone(buf.Bytes()), nil
+}
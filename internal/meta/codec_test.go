@@ -0,0 +1,138 @@
+package meta
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompressData 测试压缩编解码
+func TestCompressData(t *testing.T) {
+	data := bytes.Repeat([]byte("metadata "), 1000)
+
+	compressed, err := compressData(data)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(compressed, compressMagic))
+	assert.Less(t, len(compressed), len(data))
+
+	decompressed, err := decompressData(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+
+	// 没有格式头的旧数据原样返回
+	legacy, err := decompressData([]byte("plain"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("plain"), legacy)
+
+	_, err = decompressData(append(append([]byte(nil), compressMagic...), 0xff, 0xff))
+	assert.Error(t, err)
+}
+
+// TestFileStorageCompression 测试启用压缩后的读写
+func TestFileStorageCompression(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	config := &StorageConfig{
+		RootDir:           tempDir,
+		SyncInterval:      time.Hour,
+		FileMode:          0644,
+		EnableCompression: true,
+	}
+	storage, err := NewFileStorage(config)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("abc"), 1000)
+	require.NoError(t, storage.Save(ctx, "/c", data))
+
+	loaded, err := storage.Load(ctx, "/c")
+	require.NoError(t, err)
+	assert.Equal(t, data, loaded)
+	require.NoError(t, storage.Close())
+
+	// 磁盘上保存压缩后的内容
+	path, _ := storage.keyToPath("c")
+	disk, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Less(t, len(disk), len(data))
+
+	reopened, err := NewFileStorage(config)
+	require.NoError(t, err)
+	defer reopened.Close()
+	loaded, err = reopened.Load(ctx, "/c")
+	require.NoError(t, err)
+	assert.Equal(t, data, loaded)
+}
+
+// BenchmarkCompressData 使用缓冲池和复用压缩器的压缩
+func BenchmarkCompressData(b *testing.B) {
+	data := bytes.Repeat([]byte("metadata "), 4096)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := compressData(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCompressDataUnpooled 每次新建缓冲区和压缩器的压缩，作为对照
+func BenchmarkCompressDataUnpooled(b *testing.B) {
+	data := bytes.Repeat([]byte("metadata "), 4096)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		buf.Write(compressMagic)
+		w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+		w.Write(data)
+		w.Close()
+		_ = bytes.Clone(buf.Bytes())
+	}
+}
+
+// BenchmarkDecompressData 使用缓冲池和复用解压器的解压
+func BenchmarkDecompressData(b *testing.B) {
+	compressed, err := compressData(bytes.Repeat([]byte("metadata "), 4096))
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := decompressData(compressed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFileStorageSaveLoad 启用压缩时的 Save/Load 热路径
+func BenchmarkFileStorageSaveLoad(b *testing.B) {
+	tempDir := setupTestDir(b)
+	defer os.RemoveAll(tempDir)
+
+	storage, err := NewFileStorage(&StorageConfig{
+		RootDir:           tempDir,
+		SyncInterval:      time.Hour,
+		FileMode:          0644,
+		EnableCompression: true,
+	})
+	require.NoError(b, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("metadata "), 1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := storage.Save(ctx, "/bench", data); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := storage.Load(ctx, "/bench"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,695 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// arenaRef 字符串在字节区中的位置，字节区上限为 4GiB
+type arenaRef struct {
+	off uint32
+	len uint32
+}
+
+// compactEntry 紧凑元数据记录，不含任何指针，GC 无需扫描
+type compactEntry struct {
+	hash        uint64
+	next        int32 // 同一哈希链上的下一条记录，-1 表示结束
+	used        bool
+	typ         FileType
+	path        arenaRef
+	name        arenaRef
+	owner       arenaRef
+	group       arenaRef
+	placement   arenaRef
+	inline      arenaRef
+	class       arenaRef
+	defaults    arenaRef // 目录默认属性的 protobuf 编码
+	hasDefaults bool
+	pin         arenaRef // 文件固定位置的 protobuf 编码
+	hasPin      bool
+	writer      arenaRef
+	target      arenaRef
+	layout      StripeLayout // 条带布局，StripeCount 为 0 表示未设置
+	compression bool
+	project     uint32
+	quotaBytes  int64
+	quotaFiles  int64
+	hlc         HLCTimestamp
+	inode       uint64
+	size        int64
+	mode        os.FileMode
+	links       int32
+	createTime  int64
+	modifyTime  int64
+	accessTime  int64
+	version     uint64
+	blockStart  uint32
+	blockCount  uint32
+	extentStart uint32
+	extentCount uint32
+}
+
+// compactBlock 紧凑数据块记录，Locations 以 \x00 分隔存放在字节区
+type compactBlock struct {
+	id        arenaRef
+	checksum  arenaRef
+	locations arenaRef
+	size      int64
+	offset    int64
+}
+
+// compactExtent 紧凑数据块区间记录
+type compactExtent struct {
+	locations arenaRef
+	offset    int64
+	length    int64
+	blockSize int64
+	startID   uint64
+}
+
+// CompactStore 面向超大命名空间的紧凑内存元数据存储
+//
+// 所有记录存放在连续的切片中，字符串存放在共享字节区并以偏移引用，
+// 索引以路径哈希为键，整个存储只有少量大对象，避免逐条目的堆分配和 GC 扫描开销。
+// Get 和 List 返回的是元数据副本，修改后需调用 Update 写回。
+type CompactStore struct {
+	mu      sync.RWMutex
+	entries []compactEntry
+	blocks  []compactBlock
+	extents []compactExtent
+	arena   []byte
+	index   map[uint64]int32
+	shared  map[uint64]arenaRef // 驻留字符串的哈希到字节区位置
+	free    []int32
+	inodes  uint64
+	live    int
+	garbage int // 字节区和块区中已失效的字节数
+	log     logger.Logger
+}
+
+// SetLogger 设置日志，应在使用存储之前调用
+func (s *CompactStore) SetLogger(l logger.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = logger.OrDefault(l)
+}
+
+// compactThreshold 失效数据超过该比例时触发整理
+const compactThreshold = 0.5
+
+// NewCompactStore 创建新的紧凑内存存储
+func NewCompactStore() *CompactStore {
+	s := &CompactStore{
+		index:  make(map[uint64]int32),
+		shared: make(map[uint64]arenaRef),
+		log:    logger.Default(),
+	}
+
+	now := time.Now()
+	s.insert("/", &Metadata{
+		Inode:      s.nextInode(),
+		Name:       "/",
+		Type:       TypeDirectory,
+		Mode:       0755,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+	})
+
+	return s
+}
+
+func (s *CompactStore) nextInode() uint64 {
+	s.inodes++
+	return s.inodes
+}
+
+// hashPath 计算路径哈希
+func hashPath(p string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(p))
+	return h.Sum64()
+}
+
+// putString 将字符串写入字节区
+func (s *CompactStore) putString(v string) arenaRef {
+	if v == "" {
+		return arenaRef{}
+	}
+	ref := arenaRef{off: uint32(len(s.arena)), len: uint32(len(v))}
+	s.arena = append(s.arena, v...)
+	return ref
+}
+
+// putShared 将重复率高的字符串驻留到字节区，相同内容的字符串共享同一位置
+//
+// 驻留表以哈希为键且不含指针，哈希冲突时退化为普通写入。驻留字符串在整理前不会被回收。
+func (s *CompactStore) putShared(v string) arenaRef {
+	if v == "" {
+		return arenaRef{}
+	}
+	h := hashPath(v)
+	if ref, ok := s.shared[h]; ok {
+		if string(s.bytesOf(ref)) == v {
+			return ref
+		}
+		return s.putString(v)
+	}
+	ref := s.putString(v)
+	s.shared[h] = ref
+	return ref
+}
+
+// bytesOf 返回字节区中的原始字节，不产生拷贝
+func (s *CompactStore) bytesOf(ref arenaRef) []byte {
+	return s.arena[ref.off : ref.off+ref.len]
+}
+
+// stringOf 返回字节区中的字符串
+func (s *CompactStore) stringOf(ref arenaRef) string {
+	if ref.len == 0 {
+		return ""
+	}
+	return string(s.bytesOf(ref))
+}
+
+// lookup 查找路径对应的记录下标
+func (s *CompactStore) lookup(p string) (int32, bool) {
+	idx, ok := s.index[hashPath(p)]
+	for ok && idx >= 0 {
+		if string(s.bytesOf(s.entries[idx].path)) == p {
+			return idx, true
+		}
+		idx = s.entries[idx].next
+	}
+	return -1, false
+}
+
+// encode 将元数据写入记录，字符串和数据块追加到字节区和块区
+func (s *CompactStore) encode(e *compactEntry, meta *Metadata) {
+	e.typ = meta.Type
+	e.name = s.putShared(meta.Name)
+	e.owner = s.putShared(meta.Owner)
+	e.group = s.putShared(meta.Group)
+	e.placement = s.putShared(meta.Placement)
+	e.inline = s.putString(string(meta.InlineData))
+	e.class = s.putShared(meta.StorageClass)
+	e.writer = s.putShared(meta.Writer)
+	e.target = s.putString(meta.Target)
+	e.layout = StripeLayout{}
+	if meta.Layout != nil {
+		e.layout = *meta.Layout
+	}
+	e.compression = meta.Compression
+	e.project = meta.ProjectID
+	e.quotaBytes = meta.QuotaBytes
+	e.quotaFiles = meta.QuotaFiles
+	e.hlc = HLCTimestamp{}
+	if meta.HLC != nil {
+		e.hlc = *meta.HLC
+	}
+	e.defaults = arenaRef{}
+	e.hasDefaults = meta.Defaults != nil
+	if e.hasDefaults {
+		e.defaults = s.putString(string(meta.Defaults.AppendProto(nil)))
+	}
+	e.pin = arenaRef{}
+	e.hasPin = meta.Pin != nil
+	if e.hasPin {
+		e.pin = s.putString(string(meta.Pin.AppendProto(nil)))
+	}
+	e.inode = meta.Inode
+	e.size = meta.Size
+	e.mode = meta.Mode
+	e.links = int32(meta.Links)
+	e.createTime = meta.CreateTime.UnixNano()
+	e.modifyTime = meta.ModifyTime.UnixNano()
+	e.accessTime = meta.AccessTime.UnixNano()
+	e.version = meta.Version
+
+	e.blockStart = uint32(len(s.blocks))
+	e.blockCount = uint32(len(meta.Blocks))
+	for _, block := range meta.Blocks {
+		s.blocks = append(s.blocks, compactBlock{
+			id:        s.putString(block.ID),
+			checksum:  s.putString(block.Checksum),
+			locations: s.putShared(strings.Join(block.Locations, "\x00")),
+			size:      block.Size,
+			offset:    block.Offset,
+		})
+	}
+
+	e.extentStart = uint32(len(s.extents))
+	e.extentCount = uint32(len(meta.Extents))
+	for _, extent := range meta.Extents {
+		s.extents = append(s.extents, compactExtent{
+			locations: s.putShared(strings.Join(extent.Locations, "\x00")),
+			offset:    extent.Offset,
+			length:    extent.Length,
+			blockSize: extent.BlockSize,
+			startID:   extent.StartID,
+		})
+	}
+}
+
+// decode 将记录还原为元数据副本
+func (s *CompactStore) decode(e *compactEntry) *Metadata {
+	meta := &Metadata{
+		Inode:      e.inode,
+		Name:       s.stringOf(e.name),
+		Type:       e.typ,
+		Size:       e.size,
+		Mode:       e.mode,
+		Links:      int(e.links),
+		Owner:      s.stringOf(e.owner),
+		Group:      s.stringOf(e.group),
+		CreateTime: time.Unix(0, e.createTime),
+		ModifyTime: time.Unix(0, e.modifyTime),
+		AccessTime: time.Unix(0, e.accessTime),
+		Version:    e.version,
+		Placement:  s.stringOf(e.placement),
+
+		StorageClass: s.stringOf(e.class),
+		Compression:  e.compression,
+		ProjectID:    e.project,
+		Writer:       s.stringOf(e.writer),
+		Target:       s.stringOf(e.target),
+		QuotaBytes:   e.quotaBytes,
+		QuotaFiles:   e.quotaFiles,
+	}
+	if !e.hlc.IsZero() {
+		ts := e.hlc
+		meta.HLC = &ts
+	}
+	if e.inline.len > 0 {
+		meta.InlineData = append([]byte(nil), s.bytesOf(e.inline)...)
+	}
+	if e.hasDefaults {
+		meta.Defaults = &DirDefaults{}
+		// 编码由 encode 写入，不会解析失败
+		_ = meta.Defaults.UnmarshalBinary(s.bytesOf(e.defaults))
+	}
+	if e.layout.StripeCount > 0 {
+		meta.Layout = e.layout.Clone()
+	}
+	if e.hasPin {
+		meta.Pin = &FilePin{}
+		_ = meta.Pin.UnmarshalBinary(s.bytesOf(e.pin))
+	}
+	if e.blockCount > 0 {
+		meta.Blocks = make([]Block, e.blockCount)
+		for i := range meta.Blocks {
+			b := &s.blocks[e.blockStart+uint32(i)]
+			meta.Blocks[i] = Block{
+				ID:       s.stringOf(b.id),
+				Size:     b.size,
+				Offset:   b.offset,
+				Checksum: s.stringOf(b.checksum),
+			}
+			if b.locations.len > 0 {
+				meta.Blocks[i].Locations = strings.Split(s.stringOf(b.locations), "\x00")
+			}
+		}
+	}
+	if e.extentCount > 0 {
+		meta.Extents = make([]Extent, e.extentCount)
+		for i := range meta.Extents {
+			x := &s.extents[e.extentStart+uint32(i)]
+			meta.Extents[i] = Extent{
+				Offset:    x.offset,
+				Length:    x.length,
+				BlockSize: x.blockSize,
+				StartID:   x.startID,
+			}
+			if x.locations.len > 0 {
+				meta.Extents[i].Locations = strings.Split(s.stringOf(x.locations), "\x00")
+			}
+		}
+	}
+	return meta
+}
+
+// release 统计记录占用的数据为失效数据
+//
+// 驻留字符串可能仍被其他记录引用，这里按上限估算，只会让整理提前发生。
+func (s *CompactStore) release(e *compactEntry) {
+	s.garbage += int(e.name.len + e.owner.len + e.group.len + e.placement.len + e.inline.len + e.class.len + e.defaults.len + e.pin.len + e.writer.len + e.target.len)
+	for i := uint32(0); i < e.blockCount; i++ {
+		b := &s.blocks[e.blockStart+i]
+		s.garbage += int(b.id.len+b.checksum.len+b.locations.len) + compactBlockSize
+	}
+	for i := uint32(0); i < e.extentCount; i++ {
+		s.garbage += int(s.extents[e.extentStart+i].locations.len) + compactExtentSize
+	}
+}
+
+// compactBlockSize 单个块记录的字节数，用于估算失效数据
+const compactBlockSize = int(unsafe.Sizeof(compactBlock{}))
+
+// compactExtentSize 单个区间记录的字节数，用于估算失效数据
+const compactExtentSize = int(unsafe.Sizeof(compactExtent{}))
+
+// insert 插入新记录
+func (s *CompactStore) insert(p string, meta *Metadata) {
+	var idx int32
+	if n := len(s.free); n > 0 {
+		idx = s.free[n-1]
+		s.free = s.free[:n-1]
+	} else {
+		s.entries = append(s.entries, compactEntry{})
+		idx = int32(len(s.entries) - 1)
+	}
+
+	e := &s.entries[idx]
+	*e = compactEntry{used: true, hash: hashPath(p), path: s.putString(p)}
+	s.encode(e, meta)
+
+	if head, ok := s.index[e.hash]; ok {
+		e.next = head
+	} else {
+		e.next = -1
+	}
+	s.index[e.hash] = idx
+	s.live++
+}
+
+// remove 删除记录并从哈希链中摘除
+func (s *CompactStore) remove(idx int32) {
+	e := &s.entries[idx]
+	if head := s.index[e.hash]; head == idx {
+		if e.next >= 0 {
+			s.index[e.hash] = e.next
+		} else {
+			delete(s.index, e.hash)
+		}
+	} else {
+		for prev := head; prev >= 0; prev = s.entries[prev].next {
+			if s.entries[prev].next == idx {
+				s.entries[prev].next = e.next
+				break
+			}
+		}
+	}
+
+	s.release(e)
+	s.garbage += int(e.path.len)
+	*e = compactEntry{next: -1}
+	s.free = append(s.free, idx)
+	s.live--
+	s.maybeCompact()
+}
+
+// footprint 返回字节区、块区和区间区的总字节数
+func (s *CompactStore) footprint() int {
+	return len(s.arena) + len(s.blocks)*compactBlockSize + len(s.extents)*compactExtentSize
+}
+
+// maybeCompact 失效数据过多时重建字节区、块区和区间区
+func (s *CompactStore) maybeCompact() {
+	if float64(s.garbage) < compactThreshold*float64(s.footprint()) {
+		return
+	}
+
+	oldArena, oldBlocks, oldExtents := s.arena, s.blocks, s.extents
+	s.arena = make([]byte, 0, len(oldArena)-min(s.garbage, len(oldArena)))
+	s.blocks = make([]compactBlock, 0, len(oldBlocks))
+	s.extents = make([]compactExtent, 0, len(oldExtents))
+	move := func(ref arenaRef) arenaRef {
+		if ref.len == 0 {
+			return arenaRef{}
+		}
+		out := arenaRef{off: uint32(len(s.arena)), len: ref.len}
+		s.arena = append(s.arena, oldArena[ref.off:ref.off+ref.len]...)
+		return out
+	}
+	s.shared = make(map[uint64]arenaRef, len(s.shared))
+	moveShared := func(ref arenaRef) arenaRef {
+		if ref.len == 0 {
+			return arenaRef{}
+		}
+		return s.putShared(string(oldArena[ref.off : ref.off+ref.len]))
+	}
+
+	for i := range s.entries {
+		e := &s.entries[i]
+		if !e.used {
+			continue
+		}
+		e.path = move(e.path)
+		e.name = moveShared(e.name)
+		e.owner = moveShared(e.owner)
+		e.group = moveShared(e.group)
+		e.placement = moveShared(e.placement)
+		e.inline = move(e.inline)
+		e.class = moveShared(e.class)
+		e.defaults = move(e.defaults)
+		e.pin = move(e.pin)
+		e.writer = moveShared(e.writer)
+		e.target = move(e.target)
+		start := uint32(len(s.blocks))
+		for j := uint32(0); j < e.blockCount; j++ {
+			b := oldBlocks[e.blockStart+j]
+			b.id = move(b.id)
+			b.checksum = move(b.checksum)
+			b.locations = moveShared(b.locations)
+			s.blocks = append(s.blocks, b)
+		}
+		e.blockStart = start
+		start = uint32(len(s.extents))
+		for j := uint32(0); j < e.extentCount; j++ {
+			x := oldExtents[e.extentStart+j]
+			x.locations = moveShared(x.locations)
+			s.extents = append(s.extents, x)
+		}
+		e.extentStart = start
+	}
+	s.garbage = 0
+
+	s.log.Debug("Compacted metadata arena",
+		zap.Int("entries", s.live),
+		zap.Int("arena_bytes", len(s.arena)),
+		zap.Int("blocks", len(s.blocks)),
+	)
+}
+
+// parentDir 返回父目录记录，确保其存在且为目录
+func (s *CompactStore) parentDir(p string) error {
+	parent := path.Dir(p)
+	idx, exists := s.lookup(parent)
+	if !exists {
+		return fmt.Errorf("parent directory not found: %s", parent)
+	}
+	if s.entries[idx].typ != TypeDirectory {
+		return fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+	return nil
+}
+
+// Len 返回条目数量
+func (s *CompactStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.live
+}
+
+// Create 创建新文件
+func (s *CompactStore) Create(ctx context.Context, p string, mode os.FileMode) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(normalizePath(p), mode, nil)
+}
+
+// create 创建新文件，data 非空时内联保存，调用方需持有写锁
+func (s *CompactStore) create(filePath string, mode os.FileMode, data []byte) (*Metadata, error) {
+	if err := s.parentDir(filePath); err != nil {
+		return nil, err
+	}
+	if _, exists := s.lookup(filePath); exists {
+		return nil, fmt.Errorf("file %w: %s", ErrExist, filePath)
+	}
+
+	now := time.Now()
+	meta := &Metadata{
+		Inode:      s.nextInode(),
+		Name:       path.Base(filePath),
+		Type:       TypeRegular,
+		Mode:       mode,
+		Links:      1,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+	}
+	if len(data) > 0 {
+		meta.InlineData = append([]byte(nil), data...)
+		meta.Size = int64(len(data))
+	}
+	s.applyParentDefaults(filePath, meta)
+	s.insert(filePath, meta)
+	s.log.Info("Created new file",
+		zap.String("path", filePath),
+		zap.Uint64("inode", meta.Inode),
+	)
+
+	return meta, nil
+}
+
+// Get 获取文件元数据副本
+func (s *CompactStore) Get(ctx context.Context, p string) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := normalizePath(p)
+	idx, exists := s.lookup(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+
+	e := &s.entries[idx]
+	e.accessTime = time.Now().UnixNano()
+	return s.decode(e), nil
+}
+
+// Update 更新文件元数据，版本语义与 MemoryStore.Update 相同
+func (s *CompactStore) Update(ctx context.Context, p string, meta *Metadata) error {
+	return s.update(ctx, p, meta, meta.Version, meta.Version != 0)
+}
+
+// UpdateIf 仅当存储中的当前版本等于 expectedVersion 时更新文件元数据
+func (s *CompactStore) UpdateIf(ctx context.Context, p string, meta *Metadata, expectedVersion uint64) error {
+	return s.update(ctx, p, meta, expectedVersion, true)
+}
+
+// update 执行更新，check 为 true 时进行版本比较
+func (s *CompactStore) update(ctx context.Context, p string, meta *Metadata, expectedVersion uint64, check bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := normalizePath(p)
+	idx, exists := s.lookup(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	e := &s.entries[idx]
+	if check && e.version != expectedVersion {
+		return &VersionConflictError{Path: filePath, Expected: expectedVersion, Actual: e.version}
+	}
+	current := &Metadata{}
+	if e.layout.StripeCount > 0 {
+		current.Layout = &e.layout
+	}
+	if err := checkLayoutUpdate(filePath, current, meta); err != nil {
+		return err
+	}
+	if err := checkPlacement(filePath, meta); err != nil {
+		return err
+	}
+
+	meta.ModifyTime = time.Now()
+	meta.Version = e.version + 1
+	meta.NormalizeBlocks()
+
+	s.release(e)
+	s.encode(e, meta)
+	s.maybeCompact()
+
+	return nil
+}
+
+// Delete 删除文件
+func (s *CompactStore) Delete(ctx context.Context, p string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := normalizePath(p)
+	idx, exists := s.lookup(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+
+	s.remove(idx)
+	return nil
+}
+
+// List 列出目录内容
+func (s *CompactStore) List(ctx context.Context, p string) ([]*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dirPath := normalizePath(p)
+	idx, exists := s.lookup(dirPath)
+	if !exists {
+		return nil, fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if s.entries[idx].typ != TypeDirectory {
+		return nil, fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+
+	prefix := dirPath + "/"
+	if dirPath == "/" {
+		prefix = "/"
+	}
+
+	var results []*Metadata
+	for i := range s.entries {
+		e := &s.entries[i]
+		if !e.used {
+			continue
+		}
+		entryPath := s.bytesOf(e.path)
+		if len(entryPath) <= len(prefix) || string(entryPath[:len(prefix)]) != prefix {
+			continue
+		}
+		if strings.IndexByte(string(entryPath[len(prefix):]), '/') >= 0 {
+			continue
+		}
+		results = append(results, s.decode(e))
+	}
+
+	return results, nil
+}
+
+// Mkdir 创建目录
+func (s *CompactStore) Mkdir(ctx context.Context, p string, mode os.FileMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirPath := normalizePath(p)
+	if err := s.parentDir(dirPath); err != nil {
+		return err
+	}
+	if _, exists := s.lookup(dirPath); exists {
+		return fmt.Errorf("directory %w: %s", ErrExist, dirPath)
+	}
+
+	now := time.Now()
+	meta := &Metadata{
+		Inode:      s.nextInode(),
+		Name:       path.Base(dirPath),
+		Type:       TypeDirectory,
+		Mode:       mode | os.ModeDir,
+		Links:      1,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+	}
+	s.applyParentDefaults(dirPath, meta)
+	s.insert(dirPath, meta)
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactStore(t *testing.T) {
+	store := NewCompactStore()
+	ctx := context.Background()
+
+	require.NoError(t, store.Mkdir(ctx, "/dir", 0755))
+	assert.Error(t, store.Mkdir(ctx, "/dir", 0755))
+	assert.Error(t, store.Mkdir(ctx, "/missing/sub", 0755))
+
+	meta, err := store.Create(ctx, "/dir/file", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/dir/file", 0644)
+	assert.Error(t, err)
+	_, err = store.Create(ctx, "/dir/file/child", 0644)
+	assert.Error(t, err)
+
+	meta.Size = 4096
+	meta.Owner = "alice"
+	meta.Blocks = []Block{
+		{ID: "b1", Size: 2048, Offset: 0, Checksum: "c1", Locations: []string{"ds1", "ds2"}},
+		{ID: "b2", Size: 2048, Offset: 2048},
+	}
+	require.NoError(t, store.Update(ctx, "/dir/file", meta))
+
+	got, err := store.Get(ctx, "/dir/file")
+	require.NoError(t, err)
+	assert.Equal(t, "file", got.Name)
+	assert.Equal(t, int64(4096), got.Size)
+	assert.Equal(t, "alice", got.Owner)
+	assert.Equal(t, uint64(2), got.Version)
+	assert.Equal(t, meta.Blocks, got.Blocks)
+	assert.Equal(t, meta.ModifyTime.UnixNano(), got.ModifyTime.UnixNano())
+
+	// 返回的是副本
+	got.Size = 1
+	again, err := store.Get(ctx, "/dir/file")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4096), again.Size)
+
+	_, err = store.Create(ctx, "/dir/other", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Mkdir(ctx, "/dir/sub", 0755))
+	_, err = store.Create(ctx, "/dir/sub/nested", 0644)
+	require.NoError(t, err)
+
+	entries, err := store.List(ctx, "/dir")
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	assert.ElementsMatch(t, []string{"file", "other", "sub"}, names)
+
+	rootEntries, err := store.List(ctx, "/")
+	require.NoError(t, err)
+	assert.Len(t, rootEntries, 1)
+
+	_, err = store.List(ctx, "/dir/file")
+	assert.Error(t, err)
+
+	require.NoError(t, store.Delete(ctx, "/dir/other"))
+	assert.Error(t, store.Delete(ctx, "/dir/other"))
+	_, err = store.Get(ctx, "/dir/other")
+	assert.Error(t, err)
+	assert.Equal(t, 5, store.Len())
+}
+
+func TestCompactStoreCompaction(t *testing.T) {
+	store := NewCompactStore()
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, store.Mkdir(ctx, fmt.Sprintf("/d%d", i), 0755))
+	}
+	for i := 0; i < 100; i += 2 {
+		require.NoError(t, store.Delete(ctx, fmt.Sprintf("/d%d", i)))
+	}
+	for i := 1; i < 100; i += 2 {
+		meta, err := store.Get(ctx, fmt.Sprintf("/d%d", i))
+		require.NoError(t, err)
+		meta.Owner = fmt.Sprintf("owner-%d", i)
+		require.NoError(t, store.Update(ctx, fmt.Sprintf("/d%d", i), meta))
+	}
+
+	store.mu.RLock()
+	assert.Less(t, float64(store.garbage), compactThreshold*float64(store.footprint()))
+	store.mu.RUnlock()
+
+	// 整理后数据保持正确，空闲记录可被复用
+	for i := 1; i < 100; i += 2 {
+		meta, err := store.Get(ctx, fmt.Sprintf("/d%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("d%d", i), meta.Name)
+		assert.Equal(t, fmt.Sprintf("owner-%d", i), meta.Owner)
+	}
+	require.NoError(t, store.Mkdir(ctx, "/reused", 0755))
+	store.mu.RLock()
+	assert.Equal(t, 101, len(store.entries))
+	store.mu.RUnlock()
+	assert.Equal(t, 52, store.Len())
+}
+
+// namespaceStore 基准测试使用的最小存储接口
+type namespaceStore interface {
+	Mkdir(ctx context.Context, path string, mode os.FileMode) error
+}
+
+// benchmarkNamespace 构建宽命名空间并报告堆占用和完整 GC 耗时
+func benchmarkNamespace(b *testing.B, newStore func() namespaceStore) {
+	const entries = 200000
+	ctx := context.Background()
+
+	for i := 0; i < b.N; i++ {
+		store := newStore()
+		for d := 0; d < entries/1000; d++ {
+			dir := fmt.Sprintf("/dir%d", d)
+			require.NoError(b, store.Mkdir(ctx, dir, 0755))
+			for f := 0; f < 1000; f++ {
+				require.NoError(b, store.Mkdir(ctx, fmt.Sprintf("%s/entry%d", dir, f), 0755))
+			}
+		}
+
+		runtime.GC()
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		start := time.Now()
+		runtime.GC()
+		gcTime := time.Since(start)
+
+		b.ReportMetric(float64(stats.HeapAlloc)/(1<<20), "heap-MB")
+		b.ReportMetric(float64(gcTime.Microseconds()), "gc-us")
+		runtime.KeepAlive(store)
+	}
+}
+
+// BenchmarkNamespaceMemoryStore 基于指针映射的内存存储
+func BenchmarkNamespaceMemoryStore(b *testing.B) {
+	benchmarkNamespace(b, func() namespaceStore { return NewMemoryStore() })
+}
+
+// BenchmarkNamespaceCompactStore 紧凑内存存储
+func BenchmarkNamespaceCompactStore(b *testing.B) {
+	benchmarkNamespace(b, func() namespaceStore { return NewCompactStore() })
+}
@@ -0,0 +1,128 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReadConsistency 读请求的一致性级别
+type ReadConsistency int
+
+const (
+	// ReadSerializable 直接读取本地副本，开销最小，故障切换后可能读到旧数据（默认）
+	ReadSerializable ReadConsistency = iota
+	// ReadLinearizable 读取前通过 read-index 确认领导者身份并等待本地应用到提交点，保证读到最新写入
+	ReadLinearizable
+)
+
+// String 返回一致性级别名称
+func (c ReadConsistency) String() string {
+	switch c {
+	case ReadSerializable:
+		return "serializable"
+	case ReadLinearizable:
+		return "linearizable"
+	default:
+		return fmt.Sprintf("ReadConsistency(%d)", int(c))
+	}
+}
+
+// ParseReadConsistency 解析一致性级别名称，空字符串表示默认级别
+func ParseReadConsistency(s string) (ReadConsistency, error) {
+	switch s {
+	case "", "serializable":
+		return ReadSerializable, nil
+	case "linearizable":
+		return ReadLinearizable, nil
+	default:
+		return 0, fmt.Errorf("unknown read consistency: %q", s)
+	}
+}
+
+type readConsistencyKey struct{}
+
+// WithReadConsistency 在上下文中指定读一致性级别
+func WithReadConsistency(ctx context.Context, c ReadConsistency) context.Context {
+	return context.WithValue(ctx, readConsistencyKey{}, c)
+}
+
+// ReadConsistencyFromContext 返回上下文中的读一致性级别，未指定时为 ReadSerializable
+func ReadConsistencyFromContext(ctx context.Context) ReadConsistency {
+	c, _ := ctx.Value(readConsistencyKey{}).(ReadConsistency)
+	return c
+}
+
+// ReadIndexer 复制层提供的线性一致读屏障
+//
+// Raft 实现中 ReadIndex 记录当前提交索引并通过一轮心跳确认仍是领导者（或在租约有效期内直接返回），
+// WaitApplied 等待本地状态机应用到该索引。
+type ReadIndexer interface {
+	ReadIndex(ctx context.Context) (uint64, error)
+	WaitApplied(ctx context.Context, index uint64) error
+}
+
+// LocalReadIndexer 单节点部署的读屏障，本地副本即是唯一副本，无需等待
+type LocalReadIndexer struct {
+	Changelog *Changelog
+}
+
+// ReadIndex 返回本地最新的变更序号
+func (l LocalReadIndexer) ReadIndex(ctx context.Context) (uint64, error) {
+	return l.Changelog.LastSeq(), ctx.Err()
+}
+
+// WaitApplied 本地写入在返回前已应用，直接返回
+func (l LocalReadIndexer) WaitApplied(ctx context.Context, index uint64) error {
+	return ctx.Err()
+}
+
+// ReadBackend 一致性读所需的存储读接口
+type ReadBackend interface {
+	Get(ctx context.Context, path string) (*Metadata, error)
+	List(ctx context.Context, path string) ([]*Metadata, error)
+}
+
+// ConsistentReader 按上下文中的一致性级别执行读请求
+type ConsistentReader struct {
+	backend ReadBackend
+	indexer ReadIndexer
+}
+
+// NewConsistentReader 创建一致性读取器
+func NewConsistentReader(backend ReadBackend, indexer ReadIndexer) *ConsistentReader {
+	return &ConsistentReader{
+		backend: backend,
+		indexer: indexer,
+	}
+}
+
+// barrier 线性一致读在读取前等待本地副本追上提交点
+func (r *ConsistentReader) barrier(ctx context.Context) error {
+	if ReadConsistencyFromContext(ctx) != ReadLinearizable {
+		return nil
+	}
+	index, err := r.indexer.ReadIndex(ctx)
+	if err != nil {
+		return fmt.Errorf("read index failed: %v", err)
+	}
+	if err := r.indexer.WaitApplied(ctx, index); err != nil {
+		return fmt.Errorf("failed to wait for index %d: %v", index, err)
+	}
+	return nil
+}
+
+// Get 获取文件元数据
+func (r *ConsistentReader) Get(ctx context.Context, p string) (*Metadata, error) {
+	if err := r.barrier(ctx); err != nil {
+		return nil, err
+	}
+	return r.backend.Get(ctx, p)
+}
+
+// List 列出目录内容
+func (r *ConsistentReader) List(ctx context.Context, p string) ([]*Metadata, error) {
+	if err := r.barrier(ctx); err != nil {
+		return nil, err
+	}
+	return r.backend.List(ctx, p)
+}
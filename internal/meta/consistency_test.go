@@ -0,0 +1,80 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIndexer 记录调用的测试读屏障
+type fakeIndexer struct {
+	index   uint64
+	err     error
+	waited  []uint64
+	indexed int
+}
+
+func (f *fakeIndexer) ReadIndex(ctx context.Context) (uint64, error) {
+	f.indexed++
+	return f.index, f.err
+}
+
+func (f *fakeIndexer) WaitApplied(ctx context.Context, index uint64) error {
+	f.waited = append(f.waited, index)
+	return nil
+}
+
+func TestParseReadConsistency(t *testing.T) {
+	for _, c := range []ReadConsistency{ReadSerializable, ReadLinearizable} {
+		parsed, err := ParseReadConsistency(c.String())
+		require.NoError(t, err)
+		assert.Equal(t, c, parsed)
+	}
+	c, err := ParseReadConsistency("")
+	require.NoError(t, err)
+	assert.Equal(t, ReadSerializable, c)
+	_, err = ParseReadConsistency("eventual")
+	assert.Error(t, err)
+}
+
+func TestConsistentReader(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+
+	indexer := &fakeIndexer{index: 42}
+	reader := NewConsistentReader(store, indexer)
+
+	// 默认级别不经过读屏障
+	_, err = reader.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, 0, indexer.indexed)
+
+	linear := WithReadConsistency(ctx, ReadLinearizable)
+	_, err = reader.Get(linear, "/f")
+	require.NoError(t, err)
+	_, err = reader.List(linear, "/")
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{42, 42}, indexer.waited)
+
+	indexer.err = errors.New("not leader")
+	_, err = reader.Get(linear, "/f")
+	assert.ErrorContains(t, err, "not leader")
+}
+
+func TestLocalReadIndexer(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	indexer := LocalReadIndexer{Changelog: store.Changelog()}
+
+	_, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	index, err := indexer.ReadIndex(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, store.Changelog().LastSeq(), index)
+	assert.NoError(t, indexer.WaitApplied(ctx, index))
+}
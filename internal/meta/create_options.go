@@ -0,0 +1,114 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+)
+
+// defaultParentMode 自动创建父目录时的默认权限
+const defaultParentMode os.FileMode = 0755
+
+// CreateOptions CreateWithOptions 的行为选项
+type CreateOptions struct {
+	// Exclusive 文件已存在时返回 ErrExist（O_EXCL），否则返回已有文件的元数据
+	Exclusive bool
+	// Parents 自动创建缺失的父目录
+	Parents bool
+	// ParentMode 自动创建的父目录权限，为 0 时使用 0755
+	ParentMode os.FileMode
+}
+
+// MkdirOptions MkdirWithOptions 的行为选项
+type MkdirOptions struct {
+	// ExistOK 目录已存在时返回已有目录的元数据而不是 ErrExist
+	ExistOK bool
+	// Parents 自动创建缺失的父目录，父目录使用相同权限
+	Parents bool
+}
+
+// mkdirParents 逐级创建 dir 及其缺失的祖先目录，调用方需持有写锁
+func (s *MemoryStore) mkdirParents(ctx context.Context, dir string, mode os.FileMode) error {
+	if existing, exists := s.tree.get(dir); exists {
+		if existing.Type != TypeDirectory {
+			return fmt.Errorf("parent path is not a directory: %s", dir)
+		}
+		return nil
+	}
+	if err := s.mkdirParents(ctx, path.Dir(dir), mode); err != nil {
+		return err
+	}
+	_, err := s.mkdir(ctx, dir, mode)
+	return err
+}
+
+// CreateWithOptions 在一次加锁内完成存在性检查和创建，返回元数据以及是否新建
+//
+// 非独占模式下路径已存在且是普通文件时返回已有元数据，避免网关先 Get 再 Create 的竞争。
+func (s *MemoryStore) CreateWithOptions(ctx context.Context, p string, mode os.FileMode, opts CreateOptions) (*Metadata, bool, error) {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	filePath := s.locateEntry(normalizePath(p))
+	if existing, exists := s.tree.get(filePath); exists {
+		if opts.Exclusive {
+			return nil, false, fmt.Errorf("file %w: %s", ErrExist, filePath)
+		}
+		if existing.Type != TypeRegular {
+			return nil, false, fmt.Errorf("path is not a regular file: %s", filePath)
+		}
+		return existing.Clone(), false, nil
+	}
+
+	if opts.Parents {
+		parentMode := opts.ParentMode
+		if parentMode == 0 {
+			parentMode = defaultParentMode
+		}
+		if err := s.mkdirParents(ctx, path.Dir(filePath), parentMode); err != nil {
+			return nil, false, err
+		}
+	}
+
+	meta, err := s.create(ctx, filePath, mode, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return meta.Clone(), true, nil
+}
+
+// MkdirWithOptions 创建目录并返回其元数据，ExistOK 时已有目录视为成功
+func (s *MemoryStore) MkdirWithOptions(ctx context.Context, p string, mode os.FileMode, opts MkdirOptions) (*Metadata, error) {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	dirPath := s.locate(normalizePath(p))
+	if existing, exists := s.tree.get(dirPath); exists {
+		if existing.Type != TypeDirectory {
+			return nil, fmt.Errorf("path is not a directory: %s", dirPath)
+		}
+		if !opts.ExistOK {
+			return nil, fmt.Errorf("directory %w: %s", ErrExist, dirPath)
+		}
+		return existing.Clone(), nil
+	}
+
+	if opts.Parents {
+		if err := s.mkdirParents(ctx, path.Dir(dirPath), mode); err != nil {
+			return nil, err
+		}
+	}
+
+	meta, err := s.mkdir(ctx, dirPath, mode)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Clone(), nil
+}
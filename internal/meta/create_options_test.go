@@ -0,0 +1,93 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWithOptions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	_, _, err := store.CreateWithOptions(ctx, "/a/b/file", 0644, CreateOptions{})
+	assert.Error(t, err, "missing parents without Parents")
+
+	meta, created, err := store.CreateWithOptions(ctx, "/a/b/file", 0644, CreateOptions{Parents: true})
+	require.NoError(t, err)
+	assert.True(t, created)
+	parent, err := store.Get(ctx, "/a/b")
+	require.NoError(t, err)
+	assert.Equal(t, TypeDirectory, parent.Type)
+
+	existing, created, err := store.CreateWithOptions(ctx, "/a/b/file", 0600, CreateOptions{})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, meta.Inode, existing.Inode)
+
+	_, _, err = store.CreateWithOptions(ctx, "/a/b/file", 0644, CreateOptions{Exclusive: true})
+	assert.True(t, errors.Is(err, ErrExist))
+	_, err = store.Create(ctx, "/a/b/file", 0644)
+	assert.True(t, errors.Is(err, ErrExist))
+
+	_, _, err = store.CreateWithOptions(ctx, "/a/b", 0644, CreateOptions{})
+	assert.Error(t, err, "directory is not a regular file")
+
+	_, _, err = store.CreateWithOptions(ctx, "/a/b/file/x", 0644, CreateOptions{Parents: true})
+	assert.Error(t, err, "parent is a file")
+}
+
+func TestMkdirWithOptions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	dir, err := store.MkdirWithOptions(ctx, "/x/y/z", 0750, MkdirOptions{Parents: true})
+	require.NoError(t, err)
+	assert.Equal(t, TypeDirectory, dir.Type)
+
+	_, err = store.MkdirWithOptions(ctx, "/x/y/z", 0750, MkdirOptions{})
+	assert.True(t, errors.Is(err, ErrExist))
+	err = store.Mkdir(ctx, "/x/y/z", 0750)
+	assert.True(t, errors.Is(err, ErrExist))
+
+	existing, err := store.MkdirWithOptions(ctx, "/x/y/z", 0700, MkdirOptions{ExistOK: true})
+	require.NoError(t, err)
+	assert.Equal(t, dir.Inode, existing.Inode)
+
+	_, err = store.Create(ctx, "/x/file", 0644)
+	require.NoError(t, err)
+	_, err = store.MkdirWithOptions(ctx, "/x/file", 0755, MkdirOptions{ExistOK: true})
+	assert.Error(t, err)
+}
+
+func TestCreateWithOptionsConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	inodes := make(map[uint64]int)
+	created := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meta, ok, err := store.CreateWithOptions(ctx, "/shared/obj", 0644, CreateOptions{Parents: true})
+			require.NoError(t, err)
+			mu.Lock()
+			inodes[meta.Inode]++
+			if ok {
+				created++
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, inodes, 1)
+	assert.Equal(t, 1, created)
+}
@@ -0,0 +1,194 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultDeleteBatchSize DeleteTree 每批删除的默认条目数
+const DefaultDeleteBatchSize = 1000
+
+// BlockCollector 接收被删除文件的数据块，由块回收器异步清理数据服务器上的数据
+type BlockCollector interface {
+	ScheduleBlocks(ctx context.Context, blocks []Block) error
+}
+
+// DeleteTreeOptions DeleteTree 的批量与限速选项
+type DeleteTreeOptions struct {
+	// BatchSize 每批在一次加锁内删除的条目数，为 0 时使用 DefaultDeleteBatchSize
+	BatchSize int
+	// Rate 每秒最多删除的条目数，为 0 时不限速
+	Rate float64
+	// Collector 每批删除后接收该批文件的数据块，为 nil 时不调度回收
+	Collector BlockCollector
+	// Progress 每批删除后回调当前进度
+	Progress func(DeleteTreeProgress)
+}
+
+// DeleteTreeProgress 子树删除进度
+type DeleteTreeProgress struct {
+	Total   int  // 已发现的条目总数，删除期间新建的条目会使其增加
+	Deleted int  // 已删除的条目数
+	Blocks  int  // 已调度回收的数据块数
+	Done    bool // 子树已全部删除
+}
+
+// subtreePaths 返回 root 及其全部后代路径，按逆字典序排列使后代先于祖先
+func (s *MemoryStore) subtreePaths(root string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.tree.get(root); !exists {
+		return nil
+	}
+	paths := append([]string{root}, s.tree.descendants(root)...)
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths
+}
+
+// fileBlocks 返回文件的全部数据块，包括区间展开的块和拆分保存的块映射段，调用方需持有锁
+func (s *MemoryStore) fileBlocks(ctx context.Context, meta *Metadata) ([]Block, error) {
+	var blocks []Block
+	for i := range meta.Extents {
+		blocks = append(blocks, meta.Extents[i].Blocks()...)
+	}
+	blocks = append(blocks, meta.Blocks...)
+	for _, seg := range s.segments[meta.Inode] {
+		segBlocks, err := s.loadSegment(ctx, seg)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, segBlocks...)
+	}
+	return blocks, nil
+}
+
+// deleteBatch 在一次加锁内删除一批路径，已不存在的路径被跳过，返回删除数和被删文件的数据块
+func (s *MemoryStore) deleteBatch(ctx context.Context, paths []string) (int, []Block, error) {
+	unlock, err := s.lockFenced(ctx, paths...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer unlock()
+
+	deleted := 0
+	var blocks []Block
+	for _, p := range paths {
+		meta, exists := s.tree.get(p)
+		if !exists {
+			continue
+		}
+		var fileBlocks []Block
+		if meta.Type == TypeRegular {
+			var err error
+			if fileBlocks, err = s.fileBlocks(ctx, meta); err != nil {
+				return deleted, blocks, err
+			}
+		}
+		released, err := s.unlink(ctx, p, meta)
+		if err != nil {
+			return deleted, blocks, err
+		}
+		if released {
+			blocks = append(blocks, fileBlocks...)
+		}
+		s.recordChange(ctx, ChangeDelete, p, meta)
+		deleted++
+	}
+	return deleted, blocks, nil
+}
+
+// throttle 按 rate 限速，使从 start 起删除 deleted 个条目的耗时不少于 deleted/rate 秒
+func throttle(ctx context.Context, start time.Time, deleted int, rate float64) error {
+	if rate <= 0 {
+		return nil
+	}
+	wait := time.Duration(float64(deleted)/rate*float64(time.Second)) - time.Since(start)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// DeleteTree 在服务端分批删除路径及其全部后代
+//
+// 每批在一次加锁内删除，后代先于祖先删除，因此中途取消或失败时剩余部分仍是完整的子树，
+// 重新调用即可继续。删除期间在子树内新建的条目会在下一轮被发现并删除。
+func (s *MemoryStore) DeleteTree(ctx context.Context, p string, opts DeleteTreeOptions) (DeleteTreeProgress, error) {
+	root := normalizePath(p)
+	if root == "/" {
+		return DeleteTreeProgress{}, fmt.Errorf("cannot delete root directory")
+	}
+	s.mu.RLock()
+	err := s.checkNoZoneBelow(root)
+	s.mu.RUnlock()
+	if err != nil {
+		return DeleteTreeProgress{}, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultDeleteBatchSize
+	}
+
+	var progress DeleteTreeProgress
+	start := time.Now()
+	for round := 0; ; round++ {
+		paths := s.subtreePaths(root)
+		if len(paths) == 0 {
+			if round == 0 {
+				return progress, fmt.Errorf("file not found: %s", root)
+			}
+			break
+		}
+		progress.Total += len(paths)
+
+		for i := 0; i < len(paths); i += opts.BatchSize {
+			if err := ctx.Err(); err != nil {
+				return progress, err
+			}
+			batch := paths[i:min(i+opts.BatchSize, len(paths))]
+			deleted, blocks, err := s.deleteBatch(ctx, batch)
+			progress.Deleted += deleted
+			// 已经从元数据中删除的文件必须调度回收，即使本批中途失败
+			if len(blocks) > 0 && opts.Collector != nil {
+				if gcErr := opts.Collector.ScheduleBlocks(ctx, blocks); gcErr != nil {
+					return progress, fmt.Errorf("failed to schedule block collection: %v", gcErr)
+				}
+				progress.Blocks += len(blocks)
+			}
+			if err != nil {
+				return progress, err
+			}
+			// 被并发删除而跳过的条目不再计入总数
+			progress.Total -= len(batch) - deleted
+			if opts.Progress != nil {
+				opts.Progress(progress)
+			}
+			if err := throttle(ctx, start, progress.Deleted, opts.Rate); err != nil {
+				return progress, err
+			}
+		}
+	}
+
+	progress.Done = true
+	if opts.Progress != nil {
+		opts.Progress(progress)
+	}
+	s.log.Info("Deleted tree",
+		zap.String("path", root),
+		zap.Int("entries", progress.Deleted),
+		zap.Int("blocks", progress.Blocks),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+	return progress, nil
+}
@@ -0,0 +1,96 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingCollector 记录调度回收的数据块
+type recordingCollector struct {
+	blocks []Block
+}
+
+func (c *recordingCollector) ScheduleBlocks(ctx context.Context, blocks []Block) error {
+	c.blocks = append(c.blocks, blocks...)
+	return nil
+}
+
+// buildTree 创建 /tree 下 dirs 个目录，每个目录 files 个带一个数据块的文件
+func buildTree(t *testing.T, store *MemoryStore, dirs, files int) {
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/tree", 0755))
+	for d := 0; d < dirs; d++ {
+		dir := fmt.Sprintf("/tree/d%d", d)
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+		for f := 0; f < files; f++ {
+			p := fmt.Sprintf("%s/f%d", dir, f)
+			meta, err := store.Create(ctx, p, 0644)
+			require.NoError(t, err)
+			meta.Blocks = []Block{{ID: p, Size: 10}}
+			meta.Size = 10
+			require.NoError(t, store.Update(ctx, p, meta))
+		}
+	}
+}
+
+func TestDeleteTree(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	buildTree(t, store, 3, 10)
+	_, err := store.Create(ctx, "/treehouse", 0644)
+	require.NoError(t, err)
+
+	collector := &recordingCollector{}
+	var reports []DeleteTreeProgress
+	progress, err := store.DeleteTree(ctx, "/tree", DeleteTreeOptions{
+		BatchSize: 7,
+		Collector: collector,
+		Progress:  func(p DeleteTreeProgress) { reports = append(reports, p) },
+	})
+	require.NoError(t, err)
+
+	assert.True(t, progress.Done)
+	assert.Equal(t, 34, progress.Deleted)
+	assert.Equal(t, 34, progress.Total)
+	assert.Equal(t, 30, progress.Blocks)
+	assert.Len(t, collector.blocks, 30)
+	require.NotEmpty(t, reports)
+	for i := 1; i < len(reports); i++ {
+		assert.GreaterOrEqual(t, reports[i].Deleted, reports[i-1].Deleted)
+	}
+	assert.True(t, reports[len(reports)-1].Done)
+
+	_, err = store.Get(ctx, "/tree")
+	assert.Error(t, err)
+	_, err = store.Get(ctx, "/treehouse")
+	assert.NoError(t, err, "sibling with the same prefix must survive")
+
+	_, err = store.DeleteTree(ctx, "/tree", DeleteTreeOptions{})
+	assert.Error(t, err)
+	_, err = store.DeleteTree(ctx, "/", DeleteTreeOptions{})
+	assert.Error(t, err)
+}
+
+func TestDeleteTreeThrottleAndCancel(t *testing.T) {
+	store := NewMemoryStore()
+	buildTree(t, store, 1, 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	progress, err := store.DeleteTree(ctx, "/tree", DeleteTreeOptions{BatchSize: 5, Rate: 100})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, progress.Done)
+	assert.Less(t, progress.Deleted, 22)
+
+	// 剩余部分仍是完整子树，可以继续删除
+	_, err = store.Get(context.Background(), "/tree")
+	require.NoError(t, err)
+	progress, err = store.DeleteTree(context.Background(), "/tree", DeleteTreeOptions{})
+	require.NoError(t, err)
+	assert.True(t, progress.Done)
+}
@@ -0,0 +1,82 @@
+package meta
+
+import (
+	"sync"
+
+	"cpfs/internal/metrics"
+)
+
+// DefaultDentryCacheSize 路径解析缓存的默认条目数
+const DefaultDentryCacheSize = 1 << 16
+
+// dentryCache 服务端路径解析缓存，记录路径跟随符号链接后的最终路径
+//
+// 只缓存成功的解析。新建普通文件或目录不会改变已成功解析的结果，因此只有删除、重命名
+// 以及符号链接的新建和修改使缓存整体失效；条目数达到上限时同样整体清空。
+type dentryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]string
+
+	hits   metrics.Counter
+	misses metrics.Counter
+}
+
+// newDentryCache 创建路径解析缓存
+func newDentryCache(size int) *dentryCache {
+	return &dentryCache{size: size, entries: make(map[string]string)}
+}
+
+// get 返回路径的缓存解析结果
+func (c *dentryCache) get(p string) (string, bool) {
+	c.mu.Lock()
+	resolved, ok := c.entries[p]
+	c.mu.Unlock()
+	if ok {
+		c.hits.Inc()
+	} else {
+		c.misses.Inc()
+	}
+	return resolved, ok
+}
+
+// put 缓存路径的解析结果
+func (c *dentryCache) put(p, resolved string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		c.entries = make(map[string]string)
+	}
+	c.entries[p] = resolved
+}
+
+// reset 清空缓存
+func (c *dentryCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) > 0 {
+		c.entries = make(map[string]string)
+	}
+}
+
+// changesResolution 判断变更是否可能改变已缓存的解析结果
+func changesResolution(typ ChangeType, meta *Metadata) bool {
+	switch typ {
+	case ChangeDelete, ChangeRename, ChangeExchange:
+		return true
+	case ChangeCreate, ChangeModify:
+		// 副本同步和 Update 可能改写符号链接的目标
+		return meta.Type == TypeSymlink
+	}
+	return false
+}
+
+// RegisterDentryMetrics 将路径解析缓存的命中统计注册到指标注册表
+func (s *MemoryStore) RegisterDentryMetrics(registry *metrics.Registry) {
+	registry.CounterFunc("cpfs_meta_dentry_cache_hits_total", "Path resolutions served from the dentry cache.", nil, func() float64 {
+		return float64(s.dentries.hits.Value())
+	})
+	registry.CounterFunc("cpfs_meta_dentry_cache_misses_total", "Path resolutions that walked the namespace.", nil, func() float64 {
+		return float64(s.dentries.misses.Value())
+	})
+}
@@ -0,0 +1,66 @@
+package meta
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDentryCache(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/data", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/data/v1", 0755))
+	_, err := store.Create(ctx, "/data/v1/f", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Symlink(ctx, "v1", "/data/current"))
+
+	registry := metrics.NewRegistry()
+	store.RegisterDentryMetrics(registry)
+
+	for range 2 {
+		resolved, err := store.ResolvePath(ctx, "/data/current/f")
+		require.NoError(t, err)
+		assert.Equal(t, "/data/v1/f", resolved)
+	}
+	assert.Equal(t, uint64(1), store.dentries.hits.Value())
+
+	// 新建普通文件不影响缓存
+	_, err = store.Create(ctx, "/data/v1/g", 0644)
+	require.NoError(t, err)
+	assert.Len(t, store.dentries.entries, 1)
+
+	// 切换链接后不会返回过时的结果
+	require.NoError(t, store.Mkdir(ctx, "/data/v2", 0755))
+	_, err = store.Create(ctx, "/data/v2/f", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(ctx, "/data/current"))
+	require.NoError(t, store.Symlink(ctx, "v2", "/data/current"))
+	resolved, err := store.ResolvePath(ctx, "/data/current/f")
+	require.NoError(t, err)
+	assert.Equal(t, "/data/v2/f", resolved)
+
+	require.NoError(t, store.Rename(ctx, "/data/v2", "/data/v3"))
+	_, err = store.ResolvePath(ctx, "/data/current/f")
+	assert.Error(t, err)
+}
+
+func TestSymlinkCycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Symlink(ctx, "/b", "/a"))
+	require.NoError(t, store.Symlink(ctx, "/a", "/b"))
+
+	_, err := store.ResolvePath(ctx, "/a")
+	var loop *SymlinkLoopError
+	require.ErrorAs(t, err, &loop)
+	assert.True(t, loop.Cycle)
+	assert.Less(t, loop.Followed, MaxSymlinkDepth)
+	assert.ErrorIs(t, err, ErrSymlinkLoop)
+	assert.ErrorIs(t, err, syscall.ELOOP)
+}
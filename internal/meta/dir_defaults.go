@@ -0,0 +1,124 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// applyDirDefaults 将父目录的默认属性 d 应用到新建的子项
+func applyDirDefaults(d *DirDefaults, parent, child *Metadata) {
+	if d == nil {
+		return
+	}
+	if d.Mode != 0 {
+		child.Mode = child.Mode&os.ModeType | d.Mode.Perm()
+	}
+	if d.Owner != "" {
+		child.Owner = d.Owner
+	}
+	if d.SetGID {
+		child.Group = parent.Group
+	} else if d.Group != "" {
+		child.Group = d.Group
+	}
+	if d.StorageClass != "" {
+		child.StorageClass = d.StorageClass
+	}
+	switch d.Compression {
+	case CompressionOn:
+		child.Compression = true
+	case CompressionOff:
+		child.Compression = false
+	}
+	if d.ProjectID != 0 {
+		child.ProjectID = d.ProjectID
+	}
+	if child.Type == TypeDirectory {
+		child.Defaults = d.Clone()
+	}
+}
+
+// SetDirDefaults 设置目录的默认属性，defaults 为 nil 时清除
+func (s *MemoryStore) SetDirDefaults(ctx context.Context, p string, defaults *DirDefaults) error {
+	if defaults != nil {
+		if err := defaults.Validate(); err != nil {
+			return err
+		}
+	}
+
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dirPath := normalizePath(p)
+	dir, exists := s.tree.get(dirPath)
+	if !exists {
+		return fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if dir.Type != TypeDirectory {
+		return fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+
+	updated := dir.Clone()
+	updated.Defaults = defaults.Clone()
+	updated.ModifyTime = time.Now()
+	updated.Version++
+	s.put(dirPath, updated)
+	if dirPath == "/" {
+		s.root = updated
+	}
+	s.recordChange(ctx, ChangeModify, dirPath, updated)
+	return nil
+}
+
+// applyParentDefaults 将父目录的默认属性应用到新建条目，调用方需持有写锁
+func (s *MemoryStore) applyParentDefaults(p string, child *Metadata) {
+	if parent, ok := s.tree.get(path.Dir(p)); ok {
+		applyDirDefaults(parent.Defaults, parent, child)
+		internMetadata(s.interner, child)
+	}
+}
+
+// SetDirDefaults 设置目录的默认属性，defaults 为 nil 时清除
+func (s *CompactStore) SetDirDefaults(ctx context.Context, p string, defaults *DirDefaults) error {
+	if defaults != nil {
+		if err := defaults.Validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirPath := normalizePath(p)
+	idx, exists := s.lookup(dirPath)
+	if !exists {
+		return fmt.Errorf("directory not found: %s", dirPath)
+	}
+	e := &s.entries[idx]
+	if e.typ != TypeDirectory {
+		return fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+
+	dir := s.decode(e)
+	dir.Defaults = defaults.Clone()
+	dir.ModifyTime = time.Now()
+	dir.Version++
+	s.release(e)
+	s.encode(e, dir)
+	s.maybeCompact()
+	return nil
+}
+
+// applyParentDefaults 将父目录的默认属性应用到新建条目，调用方需持有写锁
+func (s *CompactStore) applyParentDefaults(p string, child *Metadata) {
+	if idx, ok := s.lookup(path.Dir(p)); ok {
+		parent := s.decode(&s.entries[idx])
+		applyDirDefaults(parent.Defaults, parent, child)
+	}
+}
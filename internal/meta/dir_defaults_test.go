@@ -0,0 +1,93 @@
+package meta
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dirDefaultsStore MemoryStore 与 CompactStore 共有的操作
+type dirDefaultsStore interface {
+	Create(ctx context.Context, path string, mode os.FileMode) (*Metadata, error)
+	Get(ctx context.Context, path string) (*Metadata, error)
+	Update(ctx context.Context, path string, meta *Metadata) error
+	Mkdir(ctx context.Context, path string, mode os.FileMode) error
+	SetDirDefaults(ctx context.Context, path string, defaults *DirDefaults) error
+}
+
+func TestSetDirDefaults(t *testing.T) {
+	for name, store := range map[string]dirDefaultsStore{
+		"memory":  NewMemoryStore(),
+		"compact": NewCompactStore(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, store.Mkdir(ctx, "/team", 0755))
+			team, err := store.Get(ctx, "/team")
+			require.NoError(t, err)
+			team.Group = "analysts"
+			require.NoError(t, store.Update(ctx, "/team", team))
+
+			require.NoError(t, store.SetDirDefaults(ctx, "/team", &DirDefaults{
+				Mode:         0640,
+				Owner:        "svc",
+				Group:        "ignored",
+				SetGID:       true,
+				StorageClass: "archive",
+				Compression:  CompressionOn,
+			}))
+
+			file, err := store.Create(ctx, "/team/report.csv", 0666)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0640), file.Mode)
+			assert.Equal(t, "svc", file.Owner)
+			assert.Equal(t, "analysts", file.Group, "setgid takes the directory group")
+			assert.Equal(t, "archive", file.StorageClass)
+			assert.True(t, file.Compression)
+			assert.Nil(t, file.Defaults)
+
+			// 子目录继承默认属性本身，孙子项同样生效
+			require.NoError(t, store.Mkdir(ctx, "/team/raw", 0777))
+			raw, err := store.Get(ctx, "/team/raw")
+			require.NoError(t, err)
+			assert.Equal(t, os.ModeDir|0640, raw.Mode)
+			require.NotNil(t, raw.Defaults)
+			assert.Equal(t, "archive", raw.Defaults.StorageClass)
+			nested, err := store.Create(ctx, "/team/raw/a.bin", 0600)
+			require.NoError(t, err)
+			assert.Equal(t, "analysts", nested.Group)
+			assert.True(t, nested.Compression)
+
+			// 清除后只影响之后创建的条目
+			require.NoError(t, store.SetDirDefaults(ctx, "/team", nil))
+			plain, err := store.Create(ctx, "/team/plain.txt", 0644)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0644), plain.Mode)
+			assert.Empty(t, plain.StorageClass)
+			file, err = store.Get(ctx, "/team/report.csv")
+			require.NoError(t, err)
+			assert.Equal(t, "archive", file.StorageClass)
+
+			assert.Error(t, store.SetDirDefaults(ctx, "/team/plain.txt", &DirDefaults{}))
+			assert.Error(t, store.SetDirDefaults(ctx, "/missing", &DirDefaults{}))
+			assert.Error(t, store.SetDirDefaults(ctx, "/team", &DirDefaults{Mode: os.ModeDir}))
+			assert.Error(t, store.SetDirDefaults(ctx, "/team", &DirDefaults{Compression: 7}))
+		})
+	}
+}
+
+func TestDirDefaultsCompressionOff(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/logs", 0755))
+	require.NoError(t, store.SetDirDefaults(ctx, "/logs", &DirDefaults{Compression: CompressionOn}))
+	require.NoError(t, store.Mkdir(ctx, "/logs/raw", 0755))
+	require.NoError(t, store.SetDirDefaults(ctx, "/logs/raw", &DirDefaults{Compression: CompressionOff}))
+
+	file, err := store.Create(ctx, "/logs/raw/x", 0644)
+	require.NoError(t, err)
+	assert.False(t, file.Compression)
+}
@@ -0,0 +1,257 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// DirQuotaExceededError 操作会使目录子树的用量超过目录配额
+type DirQuotaExceededError struct {
+	Path     string // 设置配额的目录
+	Resource string // 超限的资源：bytes 或 files
+	Limit    int64  // 上限
+	Usage    int64  // 操作前的用量
+}
+
+// Error 实现 error
+func (e *DirQuotaExceededError) Error() string {
+	return fmt.Sprintf("directory %s quota exceeded: %s limit is %d, usage is %d", e.Path, e.Resource, e.Limit, e.Usage)
+}
+
+// Is 使 errors.Is(err, ErrQuotaExceeded) 成立
+func (e *DirQuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// DirQuotaUsage 目录的配额上限与子树用量
+type DirQuotaUsage struct {
+	Path     string     `json:"path"`
+	MaxBytes int64      `json:"max_bytes"` // 为 0 表示不限制
+	MaxFiles int64      `json:"max_files"` // 为 0 表示不限制
+	Usage    QuotaUsage `json:"usage"`
+}
+
+// hasDirQuota 判断条目是否设置了目录配额
+func hasDirQuota(m *Metadata) bool {
+	return m.QuotaBytes > 0 || m.QuotaFiles > 0
+}
+
+// dirUsageOf 返回条目计入所在目录配额的用量
+//
+// 与项目配额不同，目录配额按路径统计：硬链接在每个所在目录中各计一次。
+func dirUsageOf(m *Metadata) QuotaUsage {
+	if m == nil {
+		return QuotaUsage{}
+	}
+	u := QuotaUsage{Files: 1}
+	if m.Type == TypeRegular {
+		u.Bytes = m.Size
+	}
+	return u
+}
+
+// SetDirQuota 设置目录配额，限制子树内普通文件大小之和与条目数量（不含目录本身），均为 0 时删除配额
+//
+// 上限可以低于当前用量，此时只拒绝使用量增加的操作。目录配额与项目配额同时生效，
+// 嵌套目录的配额各自检查。
+func (s *MemoryStore) SetDirQuota(ctx context.Context, p string, maxBytes, maxFiles int64) error {
+	if maxBytes < 0 || maxFiles < 0 {
+		return fmt.Errorf("quota limits must not be negative: bytes %d, files %d", maxBytes, maxFiles)
+	}
+
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dirPath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(dirPath)
+	if !exists {
+		return fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if current.Type != TypeDirectory {
+		return fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+	if current.QuotaBytes == maxBytes && current.QuotaFiles == maxFiles {
+		return nil
+	}
+
+	updated := current.Clone()
+	updated.QuotaBytes = maxBytes
+	updated.QuotaFiles = maxFiles
+	updated.Version++
+	s.put(dirPath, updated)
+	if dirPath == "/" {
+		s.root = updated
+	}
+	if !hasDirQuota(updated) {
+		delete(s.dirUsage, updated.Inode)
+	}
+	s.recordChange(ctx, ChangeModify, dirPath, updated)
+	return nil
+}
+
+// GetQuotaUsage 返回目录的配额上限与子树用量，未设置配额的目录也可以查询
+func (s *MemoryStore) GetQuotaUsage(ctx context.Context, p string) (*DirQuotaUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirPath := s.locate(normalizePath(p))
+	dir, exists := s.tree.get(dirPath)
+	if !exists {
+		return nil, fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if dir.Type != TypeDirectory {
+		return nil, fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+	return &DirQuotaUsage{
+		Path:     dirPath,
+		MaxBytes: dir.QuotaBytes,
+		MaxFiles: dir.QuotaFiles,
+		Usage:    s.dirUsageFor(dirPath, dir),
+	}, nil
+}
+
+// subtreeUsage 统计目录下全部后代的用量，调用方需持有锁
+func (s *MemoryStore) subtreeUsage(dirPath string) QuotaUsage {
+	var usage QuotaUsage
+	for _, p := range s.tree.descendants(dirPath) {
+		u := dirUsageOf(s.tree.entry(p))
+		usage.Bytes += u.Bytes
+		usage.Files += u.Files
+	}
+	return usage
+}
+
+// dirUsageFor 返回目录的子树用量，设置了配额的目录缓存统计结果，调用方需持有写锁
+func (s *MemoryStore) dirUsageFor(dirPath string, dir *Metadata) QuotaUsage {
+	if usage, ok := s.dirUsage[dir.Inode]; ok {
+		return usage
+	}
+	usage := s.subtreeUsage(dirPath)
+	if hasDirQuota(dir) {
+		if s.dirUsage == nil {
+			s.dirUsage = make(map[uint64]QuotaUsage)
+		}
+		s.dirUsage[dir.Inode] = usage
+	}
+	return usage
+}
+
+// invalidateDirUsage 丢弃缓存的目录用量，在子树整体移动或批量修改后调用，调用方需持有写锁
+func (s *MemoryStore) invalidateDirUsage() {
+	s.dirUsage = nil
+}
+
+// quotaDirs 返回 filePath 的祖先中设置了目录配额的目录，调用方需持有锁
+func (s *MemoryStore) quotaDirs(filePath string) []string {
+	var dirs []string
+	for p := filePath; p != "/"; {
+		p = path.Dir(p)
+		if m, ok := s.tree.get(p); ok && hasDirQuota(m) {
+			dirs = append(dirs, p)
+		}
+	}
+	return dirs
+}
+
+// usageDelta 返回条目从 old 改为 new 的用量变化
+func usageDelta(old, new *Metadata) QuotaUsage {
+	delta := dirUsageOf(new)
+	prev := dirUsageOf(old)
+	delta.Bytes -= prev.Bytes
+	delta.Files -= prev.Files
+	return delta
+}
+
+// checkDirQuota 检查把 paths 上的条目从 old 改为 new 是否超出所在目录的配额，只有用量增加时才会拒绝，调用方需持有写锁
+func (s *MemoryStore) checkDirQuota(paths []string, old, new *Metadata) error {
+	delta := usageDelta(old, new)
+	if delta.Bytes <= 0 && delta.Files <= 0 {
+		return nil
+	}
+	// 同一目录下的多个硬链接各计一次
+	pending := make(map[string]QuotaUsage)
+	for _, p := range paths {
+		for _, dirPath := range s.quotaDirs(p) {
+			u := pending[dirPath]
+			u.Bytes += delta.Bytes
+			u.Files += delta.Files
+			pending[dirPath] = u
+		}
+	}
+	for dirPath, d := range pending {
+		if err := s.exceedsDirQuota(dirPath, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceedsDirQuota 检查目录用量增加 delta 后是否超过配额，调用方需持有写锁
+func (s *MemoryStore) exceedsDirQuota(dirPath string, delta QuotaUsage) error {
+	dir := s.tree.entry(dirPath)
+	usage := s.dirUsageFor(dirPath, dir)
+	if delta.Files > 0 && dir.QuotaFiles > 0 && usage.Files+delta.Files > dir.QuotaFiles {
+		return &DirQuotaExceededError{Path: dirPath, Resource: "files", Limit: dir.QuotaFiles, Usage: usage.Files}
+	}
+	if delta.Bytes > 0 && dir.QuotaBytes > 0 && usage.Bytes+delta.Bytes > dir.QuotaBytes {
+		return &DirQuotaExceededError{Path: dirPath, Resource: "bytes", Limit: dir.QuotaBytes, Usage: usage.Bytes}
+	}
+	return nil
+}
+
+// chargeDirQuota 把 paths 上的条目从 old 改为 new 的用量变化计入已缓存的目录用量，调用方需持有写锁
+//
+// 未缓存的目录在下次查询时按当时的条目统计，因此只能在条目修改前检查、修改后计入。
+func (s *MemoryStore) chargeDirQuota(paths []string, old, new *Metadata) {
+	if len(s.dirUsage) == 0 {
+		return
+	}
+	delta := usageDelta(old, new)
+	if delta == (QuotaUsage{}) {
+		return
+	}
+	for _, p := range paths {
+		for _, dirPath := range s.quotaDirs(p) {
+			inode := s.tree.entry(dirPath).Inode
+			if usage, ok := s.dirUsage[inode]; ok {
+				usage.Bytes += delta.Bytes
+				usage.Files += delta.Files
+				s.dirUsage[inode] = usage
+			}
+		}
+	}
+}
+
+// checkDirQuotaMove 检查把 from 上用量为 moved 的子树移动到 to、替换用量为 displaced 的子树后是否超出 to 所在目录的配额，调用方需持有写锁
+//
+// 同时包含 from 和 to 的目录用量不变，不做检查。
+func (s *MemoryStore) checkDirQuotaMove(from, to string, moved, displaced QuotaUsage) error {
+	delta := QuotaUsage{Bytes: moved.Bytes - displaced.Bytes, Files: moved.Files - displaced.Files}
+	if delta.Bytes <= 0 && delta.Files <= 0 {
+		return nil
+	}
+	for _, dirPath := range s.quotaDirs(to) {
+		if isWithin(from, dirPath) {
+			continue
+		}
+		if err := s.exceedsDirQuota(dirPath, delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryUsage 返回条目连同其全部后代的用量，调用方需持有锁
+func (s *MemoryStore) entryUsage(p string, m *Metadata) QuotaUsage {
+	usage := dirUsageOf(m)
+	if m.Type == TypeDirectory {
+		sub := s.subtreeUsage(p)
+		usage.Bytes += sub.Bytes
+		usage.Files += sub.Files
+	}
+	return usage
+}
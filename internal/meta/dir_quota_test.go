@@ -0,0 +1,119 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirQuotaEnforced(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/home", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/home/a", 0755))
+	_, err := store.Create(ctx, "/home/a/f", 0644)
+	require.NoError(t, err)
+
+	assert.Error(t, store.SetDirQuota(ctx, "/home/a/f", 10, 0))
+	assert.Error(t, store.SetDirQuota(ctx, "/home", -1, 0))
+	require.NoError(t, store.SetDirQuota(ctx, "/home", 100, 3))
+
+	usage, err := store.GetQuotaUsage(ctx, "/home")
+	require.NoError(t, err)
+	assert.Equal(t, DirQuotaUsage{Path: "/home", MaxBytes: 100, MaxFiles: 3, Usage: QuotaUsage{Files: 2}}, *usage)
+
+	// 条目数量
+	require.NoError(t, store.Mkdir(ctx, "/home/b", 0755))
+	err = store.Mkdir(ctx, "/home/c", 0755)
+	var exceeded *DirQuotaExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, "/home", exceeded.Path)
+	assert.Equal(t, "files", exceeded.Resource)
+	_, err = store.Create(ctx, "/home/a/g", 0644)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	require.NoError(t, store.Rmdir(ctx, "/home/b"))
+
+	// 文件大小
+	m, err := store.Get(ctx, "/home/a/f")
+	require.NoError(t, err)
+	m.Size = 80
+	require.NoError(t, store.Update(ctx, "/home/a/f", m))
+	m.Size = 120
+	assert.ErrorIs(t, store.Update(ctx, "/home/a/f", m), ErrQuotaExceeded)
+	assert.ErrorIs(t, store.Truncate(ctx, "/home/a/f", 101), ErrQuotaExceeded)
+	require.NoError(t, store.Truncate(ctx, "/home/a/f", 50))
+
+	usage, err = store.GetQuotaUsage(ctx, "/home")
+	require.NoError(t, err)
+	assert.Equal(t, QuotaUsage{Bytes: 50, Files: 2}, usage.Usage)
+
+	// 子目录可以查询用量，删除后用量减少
+	usage, err = store.GetQuotaUsage(ctx, "/home/a")
+	require.NoError(t, err)
+	assert.Equal(t, QuotaUsage{Bytes: 50, Files: 1}, usage.Usage)
+	require.NoError(t, store.Delete(ctx, "/home/a/f"))
+	usage, err = store.GetQuotaUsage(ctx, "/home")
+	require.NoError(t, err)
+	assert.Equal(t, QuotaUsage{Files: 1}, usage.Usage)
+
+	// 删除配额后不再限制
+	require.NoError(t, store.SetDirQuota(ctx, "/home", 0, 0))
+	for _, name := range []string{"/home/x", "/home/y", "/home/z"} {
+		require.NoError(t, store.Mkdir(ctx, name, 0755))
+	}
+}
+
+func TestDirQuotaRenameAndLinks(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/q", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/q/sub", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/tmp", 0755))
+	require.NoError(t, store.SetDirQuota(ctx, "/q", 0, 3))
+
+	// 移入配额目录的子树计入用量，目录内移动不变
+	require.NoError(t, store.Mkdir(ctx, "/tmp/d", 0755))
+	_, err := store.Create(ctx, "/tmp/d/f", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/tmp/d/f2", 0644)
+	require.NoError(t, err)
+	assert.ErrorIs(t, store.Rename(ctx, "/tmp/d", "/q/d"), ErrQuotaExceeded)
+	require.NoError(t, store.Rename(ctx, "/tmp/d/f", "/q/f"))
+	require.NoError(t, store.Rename(ctx, "/q/f", "/q/sub/f"))
+	usage, err := store.GetQuotaUsage(ctx, "/q")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Usage.Files)
+
+	// 硬链接在每个所在目录中各计一次
+	require.NoError(t, store.Link(ctx, "/q/sub/f", "/q/g"))
+	assert.ErrorIs(t, store.Link(ctx, "/q/sub/f", "/q/h"), ErrQuotaExceeded)
+	require.NoError(t, store.Link(ctx, "/q/sub/f", "/tmp/h"))
+	usage, err = store.GetQuotaUsage(ctx, "/q")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.Usage.Files)
+	require.NoError(t, store.Delete(ctx, "/q/g"))
+	usage, err = store.GetQuotaUsage(ctx, "/q")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Usage.Files)
+
+	// 交换时按净变化检查
+	require.NoError(t, store.Mkdir(ctx, "/tmp/e", 0755))
+	require.NoError(t, store.RenameExchange(ctx, "/q/sub", "/tmp/e"))
+	usage, err = store.GetQuotaUsage(ctx, "/q")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Usage.Files)
+	require.NoError(t, store.Mkdir(ctx, "/q/more", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/q/sub/more", 0755))
+	assert.ErrorIs(t, store.RenameExchange(ctx, "/q/more", "/tmp/e"), ErrQuotaExceeded)
+
+	// 配额随元数据保存
+	m, err := store.Get(ctx, "/q")
+	require.NoError(t, err)
+	data, err := m.MarshalBinary()
+	require.NoError(t, err)
+	var decoded Metadata
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, int64(3), decoded.QuotaFiles)
+}
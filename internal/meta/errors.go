@@ -0,0 +1,18 @@
+package meta
+
+import (
+	"errors"
+
+	api "cpfs/api/meta"
+)
+
+// ErrKeyNotFound 存储中不存在指定的键，可用 errors.Is 判断
+var ErrKeyNotFound = errors.New("key not found")
+
+// 以下错误定义在 cpfs/api/meta 中，客户端同样可以用 errors.Is 判断
+var (
+	ErrExist           = api.ErrExist
+	ErrNotEmpty        = api.ErrNotEmpty
+	ErrBusy            = api.ErrBusy
+	ErrVersionConflict = api.ErrVersionConflict
+)
@@ -0,0 +1,75 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameExchange(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	for _, dir := range []string{"/current", "/staging"} {
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+	}
+	_, err := store.Create(ctx, "/current/v1", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/staging/v2", 0644)
+	require.NoError(t, err)
+	current, err := store.Get(ctx, "/current")
+	require.NoError(t, err)
+	staging, err := store.Get(ctx, "/staging")
+	require.NoError(t, err)
+
+	require.NoError(t, store.RenameExchange(ctx, "/staging", "/current"))
+
+	m, err := store.Get(ctx, "/current")
+	require.NoError(t, err)
+	assert.Equal(t, staging.Inode, m.Inode)
+	assert.Equal(t, "current", m.Name)
+	m, err = store.Get(ctx, "/staging")
+	require.NoError(t, err)
+	assert.Equal(t, current.Inode, m.Inode)
+	assert.Equal(t, "staging", m.Name)
+
+	_, err = store.Get(ctx, "/current/v2")
+	assert.NoError(t, err)
+	_, err = store.Get(ctx, "/staging/v1")
+	assert.NoError(t, err)
+	_, err = store.Get(ctx, "/current/v1")
+	assert.Error(t, err)
+
+	// 只记录一条交换事件
+	events, _, err := store.Changelog().Since(store.Changelog().LastSeq())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, ChangeExchange, events[0].Type)
+	assert.Equal(t, "/staging", events[0].OldPath)
+	assert.Equal(t, "/current", events[0].Path)
+	assert.Equal(t, staging.Inode, events[0].Inode)
+}
+
+func TestRenameExchangeErrors(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/a", 0755))
+	_, err := store.Create(ctx, "/a/f", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/g", 0644)
+	require.NoError(t, err)
+
+	assert.Error(t, store.RenameExchange(ctx, "/a", "/missing"))
+	assert.Error(t, store.RenameExchange(ctx, "/a", "/a/f"))
+	assert.Error(t, store.RenameExchange(ctx, "/", "/g"))
+	assert.NoError(t, store.RenameExchange(ctx, "/g", "/g"))
+
+	// 文件与目录也可以交换
+	require.NoError(t, store.RenameExchange(ctx, "/a", "/g"))
+	m, err := store.Get(ctx, "/a")
+	require.NoError(t, err)
+	assert.Equal(t, TypeRegular, m.Type)
+	_, err = store.Get(ctx, "/g/f")
+	assert.NoError(t, err)
+}
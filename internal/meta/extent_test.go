@@ -0,0 +1,55 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	api "cpfs/api/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequentialBlocks 生成从 seq 开始、大小为 size 的 n 个连续块
+func sequentialBlocks(seq uint64, offset, size int64, n int, locations ...string) []Block {
+	blocks := make([]Block, n)
+	for i := range blocks {
+		blocks[i] = Block{
+			ID:        api.FormatBlockID(seq + uint64(i)),
+			Size:      size,
+			Offset:    offset + int64(i)*size,
+			Locations: locations,
+		}
+	}
+	return blocks
+}
+
+func TestMemoryStoreExtents(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	meta, err := store.Create(ctx, "/seq", 0644)
+	require.NoError(t, err)
+	meta.Blocks = sequentialBlocks(100, 0, 1<<20, InlineBlockLimit*4, "ds1", "ds2")
+	require.NoError(t, store.Update(ctx, "/seq", meta))
+
+	got, err := store.Get(ctx, "/seq")
+	require.NoError(t, err)
+	assert.Len(t, got.Extents, 1)
+	assert.Empty(t, got.Blocks)
+	assert.Equal(t, 0, got.BlockCount)
+
+	blocks, err := store.GetBlockRange(ctx, "/seq", 3<<20+5, 2<<20)
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, api.FormatBlockID(103), blocks[0].ID)
+	assert.Equal(t, api.FormatBlockID(105), blocks[2].ID)
+
+	compact := NewCompactStore()
+	_, err = compact.Create(ctx, "/seq", 0644)
+	require.NoError(t, err)
+	require.NoError(t, compact.Update(ctx, "/seq", &Metadata{Name: "seq", Blocks: sequentialBlocks(0, 0, 100, 3, "ds1")}))
+	got, err = compact.Get(ctx, "/seq")
+	require.NoError(t, err)
+	assert.Equal(t, []Extent{{Offset: 0, Length: 300, BlockSize: 100, Locations: []string{"ds1"}}}, got.Extents)
+}
@@ -0,0 +1,245 @@
+package meta
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WriteFence 子树写入栅栏，用于在创建快照前让子树静止
+//
+// 写入方用 Enter 登记进行中的写入；Quiesce 阻止子树下及其祖先上新的写入进入并等待已进入的写入全部结束，
+// 之后切出的快照与应用看到的状态一致。栅栏应尽量短：被阻止的写入一直等到恢复或各自的 ctx 取消。
+type WriteFence struct {
+	mu       sync.Mutex
+	quiesced map[string]int // 静止中的子树根及其重叠的 Quiesce 次数
+	inflight map[string]int // 进行中的写入路径及其数量
+	changed  chan struct{}  // 状态变化时关闭并替换，用于唤醒等待者
+}
+
+// NewWriteFence 创建写入栅栏
+func NewWriteFence() *WriteFence {
+	return &WriteFence{
+		quiesced: make(map[string]int),
+		inflight: make(map[string]int),
+		changed:  make(chan struct{}),
+	}
+}
+
+// notify 唤醒全部等待者，调用方需持有锁
+func (f *WriteFence) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// blocked 判断路径是否与静止中的子树重叠，调用方需持有锁
+//
+// 位于子树下的路径和子树的祖先都受阻：重命名或删除祖先会移走或删除正在切快照的子树。
+func (f *WriteFence) blocked(p string) bool {
+	for root := range f.quiesced {
+		if overlapsTree(p, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// busy 判断子树下或子树的祖先上是否还有进行中的写入，调用方需持有锁
+func (f *WriteFence) busy(root string) bool {
+	for p := range f.inflight {
+		if overlapsTree(p, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsTree 判断路径 p 位于子树 root 下或是 root 的祖先
+func overlapsTree(p, root string) bool {
+	return isWithin(p, root) || isWithin(root, p)
+}
+
+// Enter 登记对路径的一次写入，任一路径与静止中的子树重叠时等待恢复；成功时返回结束写入的函数，重复调用只生效一次
+//
+// 重命名等涉及多个路径的修改一次传入全部路径：只有全部路径都不受阻时才同时登记，
+// 否则一个也不登记。逐个登记会在持有前一个路径时等待后一个路径，与等待前一个路径结束的 Quiesce 互相等待。
+func (f *WriteFence) Enter(ctx context.Context, paths ...string) (exit func(), err error) {
+	normalized := make([]string, len(paths))
+	for i, p := range paths {
+		normalized[i] = normalizePath(p)
+	}
+	paths = normalized
+	f.mu.Lock()
+	for f.anyBlocked(paths) {
+		changed := f.changed
+		f.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		f.mu.Lock()
+	}
+	for _, p := range paths {
+		f.inflight[p]++
+	}
+	f.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			for _, p := range paths {
+				if f.inflight[p]--; f.inflight[p] == 0 {
+					delete(f.inflight, p)
+				}
+			}
+			f.notify()
+		})
+	}, nil
+}
+
+// anyBlocked 判断是否有路径与静止中的子树重叠，调用方需持有锁
+func (f *WriteFence) anyBlocked(paths []string) bool {
+	for _, p := range paths {
+		if f.blocked(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Quiesce 阻止子树 p 下及其祖先上新的写入并等待进行中的写入结束，返回恢复写入的函数，重复调用只生效一次
+//
+// ctx 在等待期间取消时撤销栅栏并返回错误。maxHold 大于 0 时栅栏在持有 maxHold 后自动恢复，
+// 防止调用方异常退出后子树一直不可写。
+func (f *WriteFence) Quiesce(ctx context.Context, p string, maxHold time.Duration) (resume func(), err error) {
+	root := normalizePath(p)
+	f.mu.Lock()
+	f.quiesced[root]++
+
+	var once sync.Once
+	resume = func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.quiesced[root]--; f.quiesced[root] == 0 {
+				delete(f.quiesced, root)
+			}
+			f.notify()
+		})
+	}
+
+	for f.busy(root) {
+		changed := f.changed
+		f.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			resume()
+			return nil, ctx.Err()
+		}
+		f.mu.Lock()
+	}
+	f.mu.Unlock()
+
+	if maxHold > 0 {
+		time.AfterFunc(maxHold, resume)
+	}
+	return resume, nil
+}
+
+// Quiesced 判断路径当前是否与静止中的子树重叠，即位于其下或是其祖先
+func (f *WriteFence) Quiesced(p string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.blocked(normalizePath(p))
+}
+
+// SetWriteFence 设置存储的写入栅栏，为 nil 时不做检查
+//
+// 设置后命名空间修改在加锁前先进入栅栏，静止中的子树下及其祖先上的修改等待恢复。修改有多个硬链接的文件
+// 会同步改写其他链接，这些链接的路径同样登记。栅栏按调用方给出的路径匹配，
+// 经过绑定点的路径不会被源目录上的栅栏阻止。
+func (s *MemoryStore) SetWriteFence(f *WriteFence) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fence = f
+}
+
+// lockFenced 在写入栅栏上登记对 paths 及其上文件的全部硬链接的写入并加写锁，返回解锁并结束写入的函数
+func (s *MemoryStore) lockFenced(ctx context.Context, paths ...string) (unlock func(), err error) {
+	return s.lockFencedWith(ctx, func() []string { return s.fencePaths(paths) })
+}
+
+// lockFencedWith 与 lockFenced 相同，需要登记的路径由 resolve 在持有写锁时给出
+//
+// 路径在进入栅栏前解析，加写锁后再解析一次：期间出现了未登记的路径（例如新建了硬链接）时
+// 释放写锁和栅栏后重试，保证持有写锁时受影响的路径都已登记。没有设置栅栏时直接加锁。
+func (s *MemoryStore) lockFencedWith(ctx context.Context, resolve func() []string) (unlock func(), err error) {
+	for {
+		s.mu.Lock()
+		fence := s.fence
+		if fence == nil {
+			return s.mu.Unlock, nil
+		}
+		paths := resolve()
+		s.mu.Unlock()
+
+		exit, err := fence.Enter(ctx, paths...)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if s.fence == fence && coversPaths(paths, resolve()) {
+			return func() {
+				s.mu.Unlock()
+				exit()
+			}, nil
+		}
+		s.mu.Unlock()
+		exit()
+	}
+}
+
+// fencePaths 返回修改 paths 时需要登记的路径，包括其上文件的其他硬链接，调用方需持有写锁
+func (s *MemoryStore) fencePaths(paths []string) []string {
+	all := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = normalizePath(p)
+		all = append(all, p)
+		if meta, exists := s.tree.get(s.locateEntry(p)); exists && len(s.links[meta.Inode]) > 1 {
+			all = append(all, s.linkPaths(p, meta.Inode)...)
+		}
+	}
+	return all
+}
+
+// subtreeLinkPaths 返回 root 下有多个硬链接的文件的全部链接路径，调用方需持有写锁
+func (s *MemoryStore) subtreeLinkPaths(root string) []string {
+	if len(s.links) == 0 {
+		return nil
+	}
+	var paths []string
+	for _, p := range s.tree.descendants(root) {
+		if meta, exists := s.tree.get(p); exists && len(s.links[meta.Inode]) > 1 {
+			paths = append(paths, s.linkPaths(p, meta.Inode)...)
+		}
+	}
+	return paths
+}
+
+// coversPaths 判断 entered 是否包含 required 中的全部路径
+func coversPaths(entered, required []string) bool {
+	set := make(map[string]bool, len(entered))
+	for _, p := range entered {
+		set[normalizePath(p)] = true
+	}
+	for _, p := range required {
+		if !set[normalizePath(p)] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,364 @@
+package meta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFenceQuiesce(t *testing.T) {
+	ctx := context.Background()
+	fence := NewWriteFence()
+
+	exit, err := fence.Enter(ctx, "/data/a")
+	require.NoError(t, err)
+
+	// 进行中的写入结束后 Quiesce 才返回
+	quiesced := make(chan func())
+	go func() {
+		resume, err := fence.Quiesce(ctx, "/data", 0)
+		assert.NoError(t, err)
+		quiesced <- resume
+	}()
+	select {
+	case <-quiesced:
+		t.Fatal("quiesce returned with a write in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	exit()
+	resume := <-quiesced
+	assert.True(t, fence.Quiesced("/data/b"))
+	assert.False(t, fence.Quiesced("/database"))
+
+	// 子树外的写入不受影响，子树内的写入等待恢复
+	exit, err = fence.Enter(ctx, "/other")
+	require.NoError(t, err)
+	exit()
+	entered := make(chan struct{})
+	go func() {
+		exit, err := fence.Enter(ctx, "/data/b")
+		assert.NoError(t, err)
+		exit()
+		close(entered)
+	}()
+	select {
+	case <-entered:
+		t.Fatal("write entered a quiesced subtree")
+	case <-time.After(20 * time.Millisecond):
+	}
+	resume()
+	resume()
+	<-entered
+	assert.False(t, fence.Quiesced("/data"))
+}
+
+func TestWriteFenceCancel(t *testing.T) {
+	fence := NewWriteFence()
+	exit, err := fence.Enter(context.Background(), "/a")
+	require.NoError(t, err)
+
+	// 等待期间取消时撤销栅栏
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = fence.Quiesce(ctx, "/", 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, fence.Quiesced("/a"))
+	exit()
+
+	// 超过 maxHold 自动恢复
+	_, err = fence.Quiesce(context.Background(), "/", 10*time.Millisecond)
+	require.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	exit, err = fence.Enter(ctx, "/a")
+	require.NoError(t, err)
+	exit()
+}
+
+func TestMemoryStoreWriteFence(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	fence := NewWriteFence()
+	store.SetWriteFence(fence)
+	require.NoError(t, store.Mkdir(ctx, "/data", 0755))
+
+	resume, err := fence.Quiesce(ctx, "/data", 0)
+	require.NoError(t, err)
+
+	// 静止期间读取正常，修改等待
+	_, err = store.Get(ctx, "/data")
+	assert.NoError(t, err)
+	_, err = store.Create(ctx, "/other", 0644)
+	assert.NoError(t, err)
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = store.Create(short, "/data/f", 0644)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, store.Rename(short, "/other", "/data/other"), context.DeadlineExceeded)
+
+	resume()
+	_, err = store.Create(ctx, "/data/f", 0644)
+	assert.NoError(t, err)
+}
+
+func TestMemoryStoreWriteFenceAncestors(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	fence := NewWriteFence()
+	store.SetWriteFence(fence)
+	require.NoError(t, store.Mkdir(ctx, "/a", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/a/b", 0755))
+	_, err := store.Create(ctx, "/a/b/f", 0644)
+	require.NoError(t, err)
+
+	resume, err := fence.Quiesce(ctx, "/a/b", 0)
+	require.NoError(t, err)
+	assert.True(t, fence.Quiesced("/a"))
+	assert.False(t, fence.Quiesced("/a/c"))
+
+	// 重命名或删除祖先会移走正在切快照的子树，同样等待恢复
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, store.Rename(short, "/a", "/z"), context.DeadlineExceeded)
+	assert.ErrorIs(t, store.DeleteAll(short, "/a"), context.DeadlineExceeded)
+	_, err = store.Get(ctx, "/a/b/f")
+	assert.NoError(t, err)
+
+	// 兄弟目录不受影响
+	require.NoError(t, store.Mkdir(ctx, "/a/c", 0755))
+	resume()
+	require.NoError(t, store.Rename(ctx, "/a", "/z"))
+
+	// 进行中的祖先写入结束后 Quiesce 才返回
+	exit, err := fence.Enter(ctx, "/z")
+	require.NoError(t, err)
+	quiesced := make(chan func())
+	go func() {
+		resume, err := fence.Quiesce(ctx, "/z/b", 0)
+		assert.NoError(t, err)
+		quiesced <- resume
+	}()
+	select {
+	case <-quiesced:
+		t.Fatal("quiesce returned with an ancestor write in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	exit()
+	(<-quiesced)()
+}
+
+func TestWriteFenceQuiesceRacesRename(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	fence := NewWriteFence()
+	store.SetWriteFence(fence)
+	require.NoError(t, store.Mkdir(ctx, "/data", 0755))
+	_, err := store.Create(ctx, "/data/a", 0644)
+	require.NoError(t, err)
+
+	// 重命名在子树内来回移动文件，同时反复静止子树；逐个登记路径时两者会互相等待到超时
+	stop := make(chan struct{})
+	renamed := make(chan error, 1)
+	go func() {
+		from, to := "/data/a", "/data/b"
+		for {
+			select {
+			case <-stop:
+				renamed <- nil
+				return
+			default:
+			}
+			if err := store.Rename(ctx, from, to); err != nil {
+				renamed <- err
+				return
+			}
+			from, to = to, from
+		}
+	}()
+	for i := 0; i < 2000; i++ {
+		wait, cancel := context.WithTimeout(ctx, time.Second)
+		resume, err := fence.Quiesce(wait, "/data", 0)
+		cancel()
+		require.NoError(t, err)
+		resume()
+	}
+	close(stop)
+	assert.NoError(t, <-renamed)
+}
+
+func TestWriteFenceEnterAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	fence := NewWriteFence()
+	resumeDst, err := fence.Quiesce(ctx, "/dst", 0)
+	require.NoError(t, err)
+
+	// 目标路径受阻时源路径也不登记，静止源子树不必等待这次重命名
+	entered := make(chan func())
+	go func() {
+		exit, err := fence.Enter(ctx, "/src/a", "/dst/a")
+		assert.NoError(t, err)
+		entered <- exit
+	}()
+	time.Sleep(10 * time.Millisecond)
+	short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	resumeSrc, err := fence.Quiesce(short, "/src", 0)
+	require.NoError(t, err)
+
+	// 两个子树都恢复后重命名才进入
+	resumeDst()
+	select {
+	case <-entered:
+		t.Fatal("write entered a quiesced subtree")
+	case <-time.After(20 * time.Millisecond):
+	}
+	resumeSrc()
+	exit := <-entered
+	short, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = fence.Quiesce(short, "/dst", 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	exit()
+}
+
+// newFencedStore 创建设置了写入栅栏的存储，/q 是将被静止的子树，/out 下有指向 /q 内文件的硬链接
+func newFencedStore(t *testing.T) (*MemoryStore, *WriteFence) {
+	t.Helper()
+	ctx := context.Background()
+	store := NewMemoryStore()
+	fence := NewWriteFence()
+	store.SetWriteFence(fence)
+	for _, dir := range []string{"/q", "/q/d", "/out", "/out/tree"} {
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+	}
+	for _, file := range []string{"/q/f", "/q/w", "/out/g", "/out/tree/x"} {
+		_, err := store.Create(ctx, file, 0644)
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Link(ctx, "/q/f", "/out/f-link"))
+	require.NoError(t, store.Link(ctx, "/out/tree/x", "/q/x-link"))
+	require.NoError(t, store.Bind(ctx, "/out", "/q/bound"))
+	require.NoError(t, store.OpenForWrite(ctx, "/q/w", "crashed"))
+	return store, fence
+}
+
+func TestMemoryStoreWriteFenceEntryPoints(t *testing.T) {
+	cases := []struct {
+		name string
+		op   func(ctx context.Context, s *MemoryStore) error
+	}{
+		{"CreateWithData", func(ctx context.Context, s *MemoryStore) error {
+			_, err := s.CreateWithData(ctx, "/q/inline", 0644, []byte("data"))
+			return err
+		}},
+		{"CreateWithOptions", func(ctx context.Context, s *MemoryStore) error {
+			_, _, err := s.CreateWithOptions(ctx, "/q/new", 0644, CreateOptions{})
+			return err
+		}},
+		{"MkdirWithOptions", func(ctx context.Context, s *MemoryStore) error {
+			_, err := s.MkdirWithOptions(ctx, "/q/newdir", 0755, MkdirOptions{})
+			return err
+		}},
+		{"RenameOnce", func(ctx context.Context, s *MemoryStore) error {
+			return s.RenameOnce(ctx, "op-1", "/out/g", "/q/g")
+		}},
+		{"CreateTemp", func(ctx context.Context, s *MemoryStore) error {
+			_, err := s.CreateTemp(ctx, "/q", 0644)
+			return err
+		}},
+		{"LinkTemp", func(ctx context.Context, s *MemoryStore) error {
+			tmp, err := s.CreateTemp(ctx, "/out", 0644)
+			if err != nil {
+				return err
+			}
+			return s.LinkTemp(ctx, tmp.Inode, "/q/tmp")
+		}},
+		{"DeleteTree", func(ctx context.Context, s *MemoryStore) error {
+			_, err := s.DeleteTree(ctx, "/q/d", DeleteTreeOptions{})
+			return err
+		}},
+		{"UpdateOpen", func(ctx context.Context, s *MemoryStore) error {
+			m, err := s.Open(ctx, "/q/f")
+			if err != nil {
+				return err
+			}
+			defer s.Close(context.Background(), m.Inode)
+			m.Mode = 0600
+			return s.UpdateOpen(ctx, m.Inode, m)
+		}},
+		{"SetLayout", func(ctx context.Context, s *MemoryStore) error {
+			return s.SetLayout(ctx, "/q/f", &StripeLayout{StripeCount: 2, StripeSize: 4096})
+		}},
+		{"SetDirDefaults", func(ctx context.Context, s *MemoryStore) error {
+			return s.SetDirDefaults(ctx, "/q/d", nil)
+		}},
+		{"SetPin", func(ctx context.Context, s *MemoryStore) error {
+			return s.SetPin(ctx, "/q/f", nil)
+		}},
+		{"SetProject", func(ctx context.Context, s *MemoryStore) error {
+			return s.SetProject(ctx, "/q/f", 7)
+		}},
+		{"SetDirQuota", func(ctx context.Context, s *MemoryStore) error {
+			return s.SetDirQuota(ctx, "/q/d", 0, 0)
+		}},
+		{"Bind", func(ctx context.Context, s *MemoryStore) error {
+			return s.Bind(ctx, "/out", "/q/mnt")
+		}},
+		{"Unbind", func(ctx context.Context, s *MemoryStore) error {
+			return s.Unbind(ctx, "/q/bound")
+		}},
+		{"OpenForWrite", func(ctx context.Context, s *MemoryStore) error {
+			return s.OpenForWrite(ctx, "/q/f", "client")
+		}},
+		{"CloseWrite", func(ctx context.Context, s *MemoryStore) error {
+			return s.CloseWrite(ctx, "/q/w", "crashed")
+		}},
+		{"RecoverWrites", func(ctx context.Context, s *MemoryStore) error {
+			_, err := s.RecoverWrites(ctx, RecoverWritesOptions{
+				Alive:  func(string) bool { return false },
+				Reader: replicaReader{},
+			})
+			return err
+		}},
+		{"ApplyEntries", func(ctx context.Context, s *MemoryStore) error {
+			return s.ApplyEntries(ctx, nil, []string{"/q/d"})
+		}},
+		// 硬链接的两端都被改写，任一端位于静止子树中都要等待
+		{"LinkFromQuiesced", func(ctx context.Context, s *MemoryStore) error {
+			return s.Link(ctx, "/q/f", "/out/f-link2")
+		}},
+		{"UpdateLinked", func(ctx context.Context, s *MemoryStore) error {
+			m, err := s.Get(ctx, "/out/f-link")
+			if err != nil {
+				return err
+			}
+			m.Mode = 0600
+			return s.Update(ctx, "/out/f-link", m)
+		}},
+		{"DeleteLinked", func(ctx context.Context, s *MemoryStore) error {
+			return s.Delete(ctx, "/out/f-link")
+		}},
+		{"DeleteAllLinked", func(ctx context.Context, s *MemoryStore) error {
+			return s.DeleteAll(ctx, "/out/tree")
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, fence := newFencedStore(t)
+			resume, err := fence.Quiesce(ctx, "/q", 0)
+			require.NoError(t, err)
+
+			short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			assert.ErrorIs(t, tc.op(short, store), context.DeadlineExceeded)
+
+			resume()
+			assert.NoError(t, tc.op(ctx, store))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,17 +11,22 @@ import (
 	"time"
 
 	"cpfs/internal/logger"
+	"cpfs/internal/metrics"
 
+	"github.com/fsnotify/fsnotify"
 	"go.uber.org/zap"
 )
 
 // FileStorage 实现基于文件的存储
 type FileStorage struct {
-	config *StorageConfig
-	mu     sync.RWMutex
-	cache  map[string][]byte
-	dirty  map[string]bool
-	stopCh chan struct{}
+	config  *StorageConfig
+	mu      sync.RWMutex
+	cache   map[string][]byte
+	dirty   map[string]bool
+	stopCh  chan struct{}
+	watcher *fsnotify.Watcher
+	metrics *storageMetrics
+	log     logger.Logger
 }
 
 // NewFileStorage 创建新的文件存储实例
@@ -39,8 +45,16 @@ func NewFileStorage(config *StorageConfig) (*FileStorage, error) {
 		cache:  make(map[string][]byte),
 		dirty:  make(map[string]bool),
 		stopCh: make(chan struct{}),
+		log:    logger.OrDefault(config.Logger),
 	}
 
+	// 注册运行指标
+	registry := config.Metrics
+	if registry == nil {
+		registry = metrics.NewRegistry()
+	}
+	fs.metrics = newStorageMetrics(fs, registry)
+
 	// 加载现有文件到缓存
 	if err := fs.loadExistingFiles(); err != nil {
 		return nil, fmt.Errorf("failed to load existing files: %v", err)
@@ -49,6 +63,19 @@ func NewFileStorage(config *StorageConfig) (*FileStorage, error) {
 	// 启动后台同步
 	go fs.syncLoop()
 
+	// 监听存储根目录的带外修改
+	if config.WatchRootDir {
+		if err := fs.startWatcher(); err != nil {
+			close(fs.stopCh)
+			return nil, fmt.Errorf("failed to watch storage directory: %v", err)
+		}
+	}
+
+	// 启动后台缓存一致性校验
+	if config.VerifyInterval > 0 {
+		go fs.verifyLoop()
+	}
+
 	return fs, nil
 }
 
@@ -123,6 +150,8 @@ func (fs *FileStorage) keyToPath(key string) (string, error) {
 
 // Save 保存数据
 func (fs *FileStorage) Save(ctx context.Context, key string, data []byte) error {
+	defer fs.metrics.observe(opSave, time.Now())
+
 	if key == "" {
 		return fmt.Errorf("empty key is not allowed")
 	}
@@ -154,7 +183,7 @@ func (fs *FileStorage) Save(ctx context.Context, key string, data []byte) error
 	fs.cache[key] = data
 	fs.dirty[key] = true
 
-	logger.Info("Saved data to storage",
+	fs.log.Debug("Saved data to storage",
 		zap.String("key", key),
 		zap.Int("size", len(data)),
 	)
@@ -164,6 +193,8 @@ func (fs *FileStorage) Save(ctx context.Context, key string, data []byte) error
 
 // Load 加载数据
 func (fs *FileStorage) Load(ctx context.Context, key string) ([]byte, error) {
+	defer fs.metrics.observe(opLoad, time.Now())
+
 	// 规范化key
 	key = normalizePath(key)
 
@@ -171,9 +202,11 @@ func (fs *FileStorage) Load(ctx context.Context, key string) ([]byte, error) {
 	// 检查缓存
 	if data, ok := fs.cache[key]; ok {
 		fs.mu.RUnlock()
-		return data, nil
+		fs.metrics.hits.Inc()
+		return fs.DecompressData(data)
 	}
 	fs.mu.RUnlock()
+	fs.metrics.misses.Inc()
 
 	// 验证并获取文件路径
 	path, err := fs.keyToPath(strings.TrimPrefix(key, "/"))
@@ -185,29 +218,24 @@ func (fs *FileStorage) Load(ctx context.Context, key string) ([]byte, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("key not found: %s", key)
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 		}
 		return nil, err
 	}
 
-	// 如果启用了压缩，解压数据
-	if fs.config.EnableCompression {
-		data, err = fs.DecompressData(data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decompress data: %v", err)
-		}
-	}
-
-	// 更新缓存
+	// 缓存保存磁盘上的原始内容，与一致性校验和目录监听保持一致
 	fs.mu.Lock()
 	fs.cache[key] = data
 	fs.mu.Unlock()
 
-	return data, nil
+	// 如果启用了压缩，解压数据
+	return fs.DecompressData(data)
 }
 
 // Delete 删除数据
 func (fs *FileStorage) Delete(ctx context.Context, key string) error {
+	defer fs.metrics.observe(opDelete, time.Now())
+
 	// 规范化key
 	key = normalizePath(key)
 
@@ -229,15 +257,123 @@ func (fs *FileStorage) Delete(ctx context.Context, key string) error {
 		return err
 	}
 
-	logger.Info("Deleted data from storage",
+	fs.log.Debug("Deleted data from storage",
+		zap.String("key", key),
+	)
+
+	return nil
+}
+
+// SecureDelete 安全删除数据
+//
+// 在删除文件前对磁盘内容进行 passes 次覆写（最后一次写零）并逐次落盘，
+// 删除前校验覆写结果，用于满足数据销毁合规要求。
+func (fs *FileStorage) SecureDelete(ctx context.Context, key string, passes int) error {
+	key = normalizePath(key)
+	if passes <= 0 {
+		passes = 1
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	// 从缓存中删除，避免后台同步重新写回
+	delete(fs.cache, key)
+	delete(fs.dirty, key)
+
+	path, err := fs.keyToPath(strings.TrimPrefix(key, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid key: %v", err)
+	}
+
+	for pass := 0; pass < passes; pass++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := overwriteFile(path, pass < passes-1); err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to overwrite %s: %v", path, err)
+		}
+	}
+
+	if err := verifyZeroed(path); err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+
+	fs.log.Info("Securely deleted data from storage",
 		zap.String("key", key),
+		zap.Int("passes", passes),
 	)
 
 	return nil
 }
 
+// overwriteFile 使用随机数据或零覆写整个文件并落盘
+func overwriteFile(path string, random bool) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	for remaining := info.Size(); remaining > 0; {
+		chunk := buf
+		if remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		if random {
+			if _, err := rand.Read(chunk); err != nil {
+				return err
+			}
+		} else {
+			clear(chunk)
+		}
+		if _, err := f.Write(chunk); err != nil {
+			return err
+		}
+		remaining -= int64(len(chunk))
+	}
+
+	return f.Sync()
+}
+
+// verifyZeroed 校验文件内容已全部被零覆写
+func verifyZeroed(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for i, b := range data {
+		if b != 0 {
+			return fmt.Errorf("secure delete verification failed for %s at offset %d", path, i)
+		}
+	}
+	return nil
+}
+
 // List 列出指定前缀的所有键
 func (fs *FileStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	defer fs.metrics.observe(opList, time.Now())
+
 	prefix = normalizePath(prefix)
 
 	fs.mu.RLock()
@@ -255,6 +391,8 @@ func (fs *FileStorage) List(ctx context.Context, prefix string) ([]string, error
 
 // Sync 同步数据到磁盘
 func (fs *FileStorage) Sync() error {
+	defer fs.metrics.syncDuration.ObserveSince(time.Now())
+
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
@@ -278,6 +416,7 @@ func (fs *FileStorage) Sync() error {
 			}
 
 			delete(fs.dirty, key)
+			fs.metrics.syncedKeys.Inc()
 		}
 	}
 
@@ -293,14 +432,14 @@ func (fs *FileStorage) syncLoop() {
 		select {
 		case <-ticker.C:
 			if err := fs.Sync(); err != nil {
-				logger.Error("Failed to sync storage",
+				fs.log.Error("Failed to sync storage",
 					zap.Error(err),
 				)
 			}
 		case <-fs.stopCh:
 			// 最后执行一次同步
 			if err := fs.Sync(); err != nil {
-				logger.Error("Failed to sync storage during shutdown",
+				fs.log.Error("Failed to sync storage during shutdown",
 					zap.Error(err),
 				)
 			}
@@ -312,6 +451,9 @@ func (fs *FileStorage) syncLoop() {
 // Close 关闭存储
 func (fs *FileStorage) Close() error {
 	close(fs.stopCh)
+	if fs.watcher != nil {
+		fs.watcher.Close()
+	}
 	return fs.Sync()
 }
 
@@ -320,8 +462,7 @@ func (fs *FileStorage) CompressData(data []byte) ([]byte, error) {
 	if !fs.config.EnableCompression {
 		return data, nil
 	}
-	// TODO: 实现数据压缩
-	return data, nil
+	return compressData(data)
 }
 
 // DecompressData 解压数据
@@ -329,6 +470,5 @@ func (fs *FileStorage) DecompressData(data []byte) ([]byte, error) {
 	if !fs.config.EnableCompression {
 		return data, nil
 	}
-	// TODO: 实现数据解压
-	return data, nil
+	return decompressData(data)
 }
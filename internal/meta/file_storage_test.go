@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"cpfs/internal/metrics"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -150,7 +153,7 @@ func TestFileStorage(t *testing.T) {
 	t.Run("Error Handling", func(t *testing.T) {
 		// 测试不存在的文件
 		_, err := storage.Load(ctx, "/nonexistent.txt")
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
 
 		// 测试空键
 		err = storage.Save(ctx, "", []byte("test"))
@@ -311,3 +314,193 @@ func BenchmarkStorageConcurrent(b *testing.B) {
 		}
 	})
 }
+
+// TestFileStorageSecureDelete 测试安全删除
+func TestFileStorageSecureDelete(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	storage, err := NewFileStorage(&StorageConfig{
+		RootDir:      tempDir,
+		SyncInterval: time.Second,
+		FileMode:     0644,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	key := "/secure/data.bin"
+	data := []byte(strings.Repeat("sensitive", 10000))
+
+	require.NoError(t, storage.Save(ctx, key, data))
+	require.NoError(t, storage.Sync())
+
+	path, err := storage.keyToPath(strings.TrimPrefix(key, "/"))
+	require.NoError(t, err)
+
+	// 覆写后文件内容全部为零
+	require.NoError(t, overwriteFile(path, true))
+	require.NoError(t, overwriteFile(path, false))
+	require.NoError(t, verifyZeroed(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), info.Size())
+
+	require.NoError(t, storage.SecureDelete(ctx, key, 3))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = storage.Load(ctx, key)
+	assert.Error(t, err)
+
+	// 删除不存在的键不报错
+	assert.NoError(t, storage.SecureDelete(ctx, "/secure/missing.bin", 1))
+}
+
+// TestFileStorageVerifyCache 测试缓存与磁盘一致性校验
+func TestFileStorageVerifyCache(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	storage, err := NewFileStorage(&StorageConfig{
+		RootDir:      tempDir,
+		SyncInterval: time.Hour,
+		FileMode:     0644,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"/v/a", "/v/b", "/v/c"} {
+		require.NoError(t, storage.Save(ctx, key, []byte("original")))
+	}
+	require.NoError(t, storage.Sync())
+
+	report, err := storage.VerifyCache(ctx, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Checked)
+	assert.True(t, report.Consistent())
+
+	// 模拟带外修改
+	pathA, _ := storage.keyToPath("v/a")
+	pathB, _ := storage.keyToPath("v/b")
+	require.NoError(t, os.WriteFile(pathA, []byte("tampered"), 0644))
+	require.NoError(t, os.Remove(pathB))
+
+	// 脏数据不参与校验
+	require.NoError(t, storage.Save(ctx, "/v/c", []byte("pending")))
+
+	report, err = storage.VerifyCache(ctx, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, []string{"/v/a"}, report.Divergent)
+	assert.Equal(t, []string{"/v/b"}, report.Missing)
+
+	// 仅告警时缓存保持不变
+	data, err := storage.Load(ctx, "/v/a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("original"), data)
+
+	report, err = storage.VerifyCache(ctx, 0, true)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+
+	data, err = storage.Load(ctx, "/v/a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("tampered"), data)
+	_, err = storage.Load(ctx, "/v/b")
+	assert.Error(t, err)
+}
+
+// TestFileStorageWatchRootDir 测试监听带外修改
+func TestFileStorageWatchRootDir(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	storage, err := NewFileStorage(&StorageConfig{
+		RootDir:      tempDir,
+		SyncInterval: time.Hour,
+		FileMode:     0644,
+		WatchRootDir: true,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	require.NoError(t, storage.Save(ctx, "/w/a", []byte("original")))
+	require.NoError(t, storage.Sync())
+	// 等待新建目录加入监听
+	time.Sleep(time.Millisecond * 100)
+
+	pathA, _ := storage.keyToPath("w/a")
+	require.NoError(t, os.WriteFile(pathA, []byte("edited"), 0644))
+	assert.Eventually(t, func() bool {
+		data, err := storage.Load(ctx, "/w/a")
+		return err == nil && string(data) == "edited"
+	}, time.Second*2, time.Millisecond*20)
+
+	// 新建子目录中的文件也能被发现
+	newDir := filepath.Join(tempDir, "w", "sub")
+	require.NoError(t, os.MkdirAll(newDir, 0755))
+	time.Sleep(time.Millisecond * 100)
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "b"), []byte("external"), 0644))
+	assert.Eventually(t, func() bool {
+		keys, _ := storage.List(ctx, "/w/sub")
+		return len(keys) == 1
+	}, time.Second*2, time.Millisecond*20)
+
+	require.NoError(t, os.Remove(pathA))
+	assert.Eventually(t, func() bool {
+		_, err := storage.Load(ctx, "/w/a")
+		return err != nil
+	}, time.Second*2, time.Millisecond*20)
+}
+
+// TestFileStorageStats 测试存储运行统计与指标
+func TestFileStorageStats(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	registry := metrics.NewRegistry()
+	storage, err := NewFileStorage(&StorageConfig{
+		RootDir:      tempDir,
+		SyncInterval: time.Hour,
+		FileMode:     0644,
+		Metrics:      registry,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	require.NoError(t, storage.Save(ctx, "/a", []byte("hello")))
+	require.NoError(t, storage.Save(ctx, "/b", []byte("world!")))
+
+	stats := storage.Stats()
+	assert.Equal(t, 2, stats.CachedKeys)
+	assert.Equal(t, int64(11), stats.CachedBytes)
+	assert.Equal(t, 2, stats.DirtyKeys)
+
+	require.NoError(t, storage.Sync())
+	_, err = storage.Load(ctx, "/a")
+	require.NoError(t, err)
+	_, err = storage.Load(ctx, "/missing")
+	assert.Error(t, err)
+	_, err = storage.List(ctx, "/")
+	require.NoError(t, err)
+
+	stats = storage.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRatio)
+	assert.Equal(t, 0, stats.DirtyKeys)
+	assert.Equal(t, uint64(2), stats.SyncedKeys)
+	assert.Equal(t, uint64(1), stats.SyncDuration.Count)
+	assert.Equal(t, uint64(2), stats.OpLatency[opSave].Count)
+	assert.Equal(t, uint64(2), stats.OpLatency[opLoad].Count)
+	assert.Equal(t, uint64(1), stats.OpLatency[opList].Count)
+
+	var buf strings.Builder
+	require.NoError(t, registry.WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), fmt.Sprintf("cpfs_storage_cache_hits_total{root=%q} 1", tempDir))
+	assert.Contains(t, buf.String(), fmt.Sprintf("cpfs_storage_cached_keys{root=%q} 2", tempDir))
+}
@@ -0,0 +1,136 @@
+package meta
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// startWatcher 监听存储根目录及其所有子目录
+func (fs *FileStorage) startWatcher() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = filepath.Walk(fs.config.RootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	fs.watcher = watcher
+	go fs.watchLoop()
+	return nil
+}
+
+// watchLoop 处理文件系统事件
+func (fs *FileStorage) watchLoop() {
+	for {
+		select {
+		case event, ok := <-fs.watcher.Events:
+			if !ok {
+				return
+			}
+			fs.handleFSEvent(event)
+		case err, ok := <-fs.watcher.Errors:
+			if !ok {
+				return
+			}
+			fs.log.Error("Storage directory watcher error",
+				zap.Error(err),
+			)
+		case <-fs.stopCh:
+			return
+		}
+	}
+}
+
+// handleFSEvent 根据文件系统事件修正缓存
+func (fs *FileStorage) handleFSEvent(event fsnotify.Event) {
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+
+	// 新建的子目录需要加入监听，并补偿加入监听前已写入的文件
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			fs.watchNewDir(event.Name)
+			return
+		}
+	}
+
+	fs.reconcileFile(event.Name)
+}
+
+// watchNewDir 监听新建目录并加载其中已存在的文件
+func (fs *FileStorage) watchNewDir(dir string) {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return fs.watcher.Add(path)
+		}
+		fs.reconcileFile(path)
+		return nil
+	})
+	if err != nil {
+		fs.log.Warn("Failed to watch new storage directory",
+			zap.String("dir", dir),
+			zap.Error(err),
+		)
+	}
+}
+
+// reconcileFile 比较文件与缓存内容并修正缓存
+//
+// 自身同步写入产生的事件因内容与缓存一致而被忽略；存在待同步写入的键以缓存为准。
+func (fs *FileStorage) reconcileFile(path string) {
+	key := fs.pathToKey(path)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.dirty[key] {
+		return
+	}
+
+	cached, cachedOK := fs.cache[key]
+	disk, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		if cachedOK {
+			delete(fs.cache, key)
+			fs.log.Warn("Storage file removed out of band, cache entry invalidated",
+				zap.String("key", key),
+			)
+		}
+	case err != nil:
+		fs.log.Warn("Failed to read storage file after modification",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	case !cachedOK:
+		fs.cache[key] = disk
+		fs.log.Warn("Storage file created out of band, loaded into cache",
+			zap.String("key", key),
+		)
+	case !bytes.Equal(cached, disk):
+		fs.cache[key] = disk
+		fs.log.Warn("Storage file modified out of band, cache entry reloaded",
+			zap.String("key", key),
+		)
+	}
+}
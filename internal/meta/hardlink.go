@@ -0,0 +1,145 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// Link 为已有文件创建硬链接 newPath
+//
+// 两个路径共享同一个 inode：通过任一路径的修改对另一路径可见，Links 记录链接数，
+// Delete 删除最后一个链接时才释放数据块。目录不支持硬链接。
+func (s *MemoryStore) Link(ctx context.Context, existingPath, newPath string) error {
+	unlock, err := s.lockFenced(ctx, existingPath, newPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	from, to := s.locateEntry(normalizePath(existingPath)), s.locateEntry(normalizePath(newPath))
+	current, exists := s.tree.get(from)
+	if !exists {
+		return fmt.Errorf("file not found: %s", from)
+	}
+	if current.Type == TypeDirectory || current.Type == TypeBind {
+		return fmt.Errorf("cannot hard link directory: %s", from)
+	}
+	if err := s.checkSameZone(from, to); err != nil {
+		return err
+	}
+	parent := path.Dir(to)
+	parentMeta, exists := s.tree.get(parent)
+	if !exists {
+		return fmt.Errorf("parent directory not found: %s", parent)
+	}
+	if parentMeta.Type != TypeDirectory {
+		return fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+	if _, exists := s.tree.get(to); exists {
+		return fmt.Errorf("file %w: %s", ErrExist, to)
+	}
+	if err := s.checkDirQuota([]string{to}, nil, current); err != nil {
+		return err
+	}
+	s.chargeDirQuota([]string{to}, nil, current)
+
+	// 新链接的节点先以占位节点挂入树中，由 put 写入条目
+	if len(s.links[current.Inode]) == 0 {
+		s.links[current.Inode] = []*treeNode{s.tree.lookup(from)}
+	}
+	s.links[current.Inode] = append(s.links[current.Inode], s.tree.ensure(to))
+
+	updated := current.Clone()
+	updated.Links++
+	updated.Version++
+	s.put(from, updated)
+	s.recordChange(ctx, ChangeCreate, to, updated)
+	return nil
+}
+
+// put 保存文件元数据，文件有多个硬链接时同步到其他链接的节点，调用方需持有写锁
+//
+// 块映射段按 inode 保存，各链接天然共享，无需同步。
+func (s *MemoryStore) put(filePath string, meta *Metadata) {
+	s.stamp(meta)
+	s.remember(filePath, meta)
+	node := s.tree.set(filePath, meta)
+	for _, other := range s.links[meta.Inode] {
+		if other == node {
+			continue
+		}
+		linked := meta.Clone()
+		linked.Name = s.interner.Intern(other.name)
+		s.tree.assign(other, linked)
+	}
+}
+
+// unlink 删除路径，文件还有其他硬链接时只减少链接数，返回 inode 的数据块是否可以回收，调用方需持有写锁
+//
+// 最后一个链接被删除但文件仍被打开时，数据块推迟到最后一次 Close 之后才回收。
+func (s *MemoryStore) unlink(ctx context.Context, filePath string, meta *Metadata) (bool, error) {
+	nodes := s.links[meta.Inode]
+	if len(nodes) < 2 {
+		orphaned, err := s.orphan(ctx, filePath, meta)
+		if err != nil {
+			return false, err
+		}
+		if err := s.dropBlockMap(ctx, meta.Inode); err != nil {
+			return false, err
+		}
+		s.accountQuota(filePath, meta, nil)
+		s.deleteEntry(filePath)
+		delete(s.dirUsage, meta.Inode)
+		s.forget(meta.Inode)
+		if meta.Type == TypeBind {
+			s.bindPoints--
+		}
+		return !orphaned, nil
+	}
+
+	// 块映射段按 inode 保存，由其余链接继续使用
+	s.chargeDirQuota([]string{filePath}, meta, nil)
+	removed := s.tree.remove(filePath)
+	remaining := make([]*treeNode, 0, len(nodes)-1)
+	for _, n := range nodes {
+		if n != removed {
+			remaining = append(remaining, n)
+		}
+	}
+	if len(remaining) == 1 {
+		delete(s.links, meta.Inode)
+	} else {
+		s.links[meta.Inode] = remaining
+	}
+
+	updated := remaining[0].meta.Clone()
+	updated.Links--
+	updated.Version++
+	s.put(s.tree.pathOf(remaining[0]), updated)
+	return false, nil
+}
+
+// linkPaths 返回 inode 的全部路径，没有多个硬链接时只有 filePath，调用方需持有锁
+func (s *MemoryStore) linkPaths(filePath string, inode uint64) []string {
+	nodes := s.links[inode]
+	if len(nodes) < 2 {
+		return []string{filePath}
+	}
+	paths := make([]string, len(nodes))
+	for i, n := range nodes {
+		paths[i] = s.tree.pathOf(n)
+	}
+	return paths
+}
+
+// buildLinks 根据条目的 inode 重建硬链接表
+func buildLinks(t *nsTree) map[uint64][]*treeNode {
+	links := make(map[uint64][]*treeNode)
+	for p, m := range t.all() {
+		if m.Links > 1 {
+			links[m.Inode] = append(links[m.Inode], t.lookup(p))
+		}
+	}
+	return links
+}
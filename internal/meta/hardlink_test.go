@@ -0,0 +1,124 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreLink(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/a", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/b", 0755))
+	file, err := store.Create(ctx, "/a/data.bin", 0644)
+	require.NoError(t, err)
+	file.Blocks = []Block{{ID: "blk-0", Size: 10}}
+	file.Size = 10
+	require.NoError(t, store.Update(ctx, "/a/data.bin", file))
+
+	require.NoError(t, store.Link(ctx, "/a/data.bin", "/b/alias.bin"))
+	original, err := store.Get(ctx, "/a/data.bin")
+	require.NoError(t, err)
+	alias, err := store.Get(ctx, "/b/alias.bin")
+	require.NoError(t, err)
+	assert.Equal(t, original.Inode, alias.Inode)
+	assert.Equal(t, 2, alias.Links)
+	assert.Equal(t, "alias.bin", alias.Name)
+	assert.Equal(t, "data.bin", original.Name)
+
+	// 通过一个路径的修改对另一路径可见
+	alias.Size = 20
+	alias.Blocks = append(alias.Blocks, Block{ID: "blk-1", Offset: 10, Size: 10})
+	require.NoError(t, store.Update(ctx, "/b/alias.bin", alias))
+	original, err = store.Get(ctx, "/a/data.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), original.Size)
+	assert.Len(t, original.Blocks, 2)
+	assert.Equal(t, alias.Version, original.Version)
+
+	// 删除一个链接只减少链接数
+	require.NoError(t, store.Delete(ctx, "/a/data.bin"))
+	alias, err = store.Get(ctx, "/b/alias.bin")
+	require.NoError(t, err)
+	assert.Equal(t, 1, alias.Links)
+	assert.Len(t, alias.Blocks, 2)
+	require.NoError(t, store.Delete(ctx, "/b/alias.bin"))
+	_, err = store.Get(ctx, "/b/alias.bin")
+	assert.Error(t, err)
+
+	require.NoError(t, store.Mkdir(ctx, "/a/dir", 0755))
+	assert.Error(t, store.Link(ctx, "/a/dir", "/b/dir"))
+	assert.Error(t, store.Link(ctx, "/missing", "/b/x"))
+	_, err = store.Create(ctx, "/a/x", 0644)
+	require.NoError(t, err)
+	assert.ErrorIs(t, store.Link(ctx, "/a/x", "/a/dir"), ErrExist)
+	assert.Error(t, store.Link(ctx, "/a/x", "/nowhere/x"))
+}
+
+func TestMemoryStoreLinkDeleteTree(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	buildTree(t, store, 1, 2)
+	require.NoError(t, store.Link(ctx, "/tree/d0/f0", "/keep"))
+
+	collector := &recordingCollector{}
+	_, err := store.DeleteTree(ctx, "/tree", DeleteTreeOptions{Collector: collector})
+	require.NoError(t, err)
+
+	// 仍被 /keep 引用的文件不回收数据块
+	assert.Equal(t, []Block{{ID: "/tree/d0/f1", Size: 10}}, collector.blocks)
+	kept, err := store.Get(ctx, "/keep")
+	require.NoError(t, err)
+	assert.Equal(t, 1, kept.Links)
+	assert.Len(t, kept.Blocks, 1)
+}
+
+func TestMemoryStoreLinkSegmentsAndRename(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	file, err := store.Create(ctx, "/big", 0644)
+	require.NoError(t, err)
+	blocks := make([]Block, InlineBlockLimit+1)
+	for i := range blocks {
+		blocks[i] = Block{ID: fmt.Sprintf("blk-%d", i), Offset: int64(i), Size: 1}
+	}
+	file.Blocks = blocks
+	file.Size = int64(len(blocks))
+	require.NoError(t, store.Update(ctx, "/big", file))
+	require.NoError(t, store.Link(ctx, "/big", "/big2"))
+
+	require.NoError(t, store.Rename(ctx, "/big", "/renamed"))
+	got, err := store.GetBlockRange(ctx, "/big2", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, got, len(blocks))
+	require.NoError(t, store.Delete(ctx, "/big2"))
+	got, err = store.GetBlockRange(ctx, "/renamed", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, got, len(blocks))
+	renamed, err := store.Get(ctx, "/renamed")
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed.Links)
+}
+
+func TestMemoryStoreLinkCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Link(ctx, "/f", "/g"))
+
+	var buf bytes.Buffer
+	require.NoError(t, store.WriteCheckpoint(&buf))
+	restored := NewMemoryStore()
+	require.NoError(t, restored.ReadCheckpoint(&buf))
+
+	require.NoError(t, restored.Delete(ctx, "/f"))
+	g, err := restored.Get(ctx, "/g")
+	require.NoError(t, err)
+	assert.Equal(t, 1, g.Links)
+}
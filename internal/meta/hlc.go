@@ -0,0 +1,111 @@
+package meta
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultHLCMaxOffset 混合逻辑时钟接受的远端时间戳领先本地物理时钟的最大值
+const DefaultHLCMaxOffset = 500 * time.Millisecond
+
+// HLC 混合逻辑时钟
+//
+// 物理时钟回拨或节点间存在偏差时，时间戳仍单调递增且与物理时间接近，用于日志排序。
+type HLC struct {
+	mu        sync.Mutex
+	last      HLCTimestamp
+	maxOffset time.Duration
+	now       func() time.Time
+}
+
+// NewHLC 创建混合逻辑时钟，maxOffset 为 0 时使用 DefaultHLCMaxOffset
+func NewHLC(maxOffset time.Duration) *HLC {
+	if maxOffset <= 0 {
+		maxOffset = DefaultHLCMaxOffset
+	}
+	return &HLC{maxOffset: maxOffset, now: time.Now}
+}
+
+// Now 返回新的本地时间戳，严格大于之前返回或观察到的所有时间戳
+func (c *HLC) Now() HLCTimestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	physical := c.now().UnixNano()
+	if physical > c.last.Wall {
+		c.last = HLCTimestamp{Wall: physical}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update 合并收到的远端时间戳并返回新的本地时间戳
+//
+// 远端时间戳领先本地物理时钟超过 maxOffset 时拒绝合并，避免一个时钟错误的节点把整个集群的时间戳推向未来。
+func (c *HLC) Update(remote HLCTimestamp) (HLCTimestamp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	physical := c.now().UnixNano()
+	if ahead := time.Duration(remote.Wall - physical); ahead > c.maxOffset {
+		return HLCTimestamp{}, fmt.Errorf("remote timestamp %s is %s ahead of the local clock, above the maximum offset %s",
+			remote, ahead, c.maxOffset)
+	}
+	switch {
+	case physical > c.last.Wall && physical > remote.Wall:
+		c.last = HLCTimestamp{Wall: physical}
+	case remote.Wall > c.last.Wall:
+		c.last = HLCTimestamp{Wall: remote.Wall, Logical: remote.Logical + 1}
+	case c.last.Wall > remote.Wall:
+		c.last.Logical++
+	default:
+		if remote.Logical > c.last.Logical {
+			c.last.Logical = remote.Logical
+		}
+		c.last.Logical++
+	}
+	return c.last, nil
+}
+
+// SetHLC 启用混合逻辑时钟：之后写入的每个元数据版本和变更日志事件都带有 HLC 时间戳，clock 为 nil 时停用
+//
+// 多个元数据分片或异地副本的时钟存在偏差，按 HLC 时间戳而非 ModifyTime 合并它们的事件和版本才能得到一致的顺序；
+// ApplyEntries 合并来源副本的时间戳，使本地之后的写入排在其后。
+func (s *MemoryStore) SetHLC(clock *HLC) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hlc = clock
+	s.changelog.SetHLC(clock)
+}
+
+// HLC 返回启用的混合逻辑时钟，未启用时为 nil
+func (s *MemoryStore) HLC() *HLC {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hlc
+}
+
+// stamp 为将要写入的元数据版本分配 HLC 时间戳，调用方需持有写锁
+func (s *MemoryStore) stamp(meta *Metadata) {
+	if s.hlc == nil {
+		return
+	}
+	ts := s.hlc.Now()
+	meta.HLC = &ts
+}
+
+// observe 合并副本同步带来的 HLC 时间戳，调用方需持有写锁
+func (s *MemoryStore) observe(entries map[string]*Metadata) error {
+	if s.hlc == nil {
+		return nil
+	}
+	for p, m := range entries {
+		if m.HLC == nil {
+			continue
+		}
+		if _, err := s.hlc.Update(*m.HLC); err != nil {
+			return fmt.Errorf("rejecting replicated entry %s: %v", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package meta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHLCMonotonicUnderClockRollback(t *testing.T) {
+	physical := time.Unix(1000, 0)
+	clock := NewHLC(0)
+	clock.now = func() time.Time { return physical }
+
+	a := clock.Now()
+	assert.Equal(t, HLCTimestamp{Wall: physical.UnixNano()}, a)
+	b := clock.Now()
+	assert.True(t, a.Before(b))
+	assert.Equal(t, uint32(1), b.Logical)
+
+	// 物理时钟回拨后时间戳仍递增
+	physical = physical.Add(-time.Second)
+	c := clock.Now()
+	assert.True(t, b.Before(c))
+	assert.Equal(t, b.Wall, c.Wall)
+
+	// 物理时钟追上后逻辑部分归零
+	physical = physical.Add(2 * time.Second)
+	d := clock.Now()
+	assert.Equal(t, HLCTimestamp{Wall: physical.UnixNano()}, d)
+	assert.Equal(t, 1, d.Compare(c))
+	assert.Equal(t, 0, d.Compare(d))
+}
+
+func TestHLCUpdate(t *testing.T) {
+	physical := time.Unix(1000, 0)
+	clock := NewHLC(time.Second)
+	clock.now = func() time.Time { return physical }
+	local := clock.Now()
+
+	// 远端领先但在允许范围内：采用远端时间戳并递增逻辑部分
+	remote := HLCTimestamp{Wall: physical.Add(300 * time.Millisecond).UnixNano(), Logical: 4}
+	got, err := clock.Update(remote)
+	require.NoError(t, err)
+	assert.Equal(t, HLCTimestamp{Wall: remote.Wall, Logical: 5}, got)
+	assert.True(t, remote.Before(clock.Now()))
+
+	// 远端落后：本地时间戳继续递增
+	got, err = clock.Update(local)
+	require.NoError(t, err)
+	assert.Equal(t, remote.Wall, got.Wall)
+	assert.Equal(t, uint32(7), got.Logical)
+
+	// 远端领先超过最大偏差时拒绝
+	_, err = clock.Update(HLCTimestamp{Wall: physical.Add(5 * time.Second).UnixNano()})
+	assert.Error(t, err)
+	assert.Equal(t, uint32(8), clock.Now().Logical, "rejected update leaves the clock unchanged")
+}
+
+func TestChangelogHLC(t *testing.T) {
+	changelog := NewChangelog(8)
+	changelog.Append(ChangeEvent{Type: ChangeCreate, Path: "/a"})
+
+	physical := time.Unix(1000, 0)
+	clock := NewHLC(0)
+	clock.now = func() time.Time { return physical }
+	changelog.SetHLC(clock)
+	changelog.Append(ChangeEvent{Type: ChangeCreate, Path: "/b"})
+	changelog.Append(ChangeEvent{Type: ChangeDelete, Path: "/b"})
+
+	events, _, err := changelog.Since(1)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Nil(t, events[0].HLC)
+	require.NotNil(t, events[1].HLC)
+	assert.True(t, events[1].HLC.Before(*events[2].HLC))
+	assert.Equal(t, physical, events[1].Time)
+}
+
+func TestMemoryStoreHLCVersions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.SetVersionHistory(8)
+	physical := time.Unix(1000, 0)
+	clock := NewHLC(time.Second)
+	clock.now = func() time.Time { return physical }
+	store.SetHLC(clock)
+
+	created, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	require.NotNil(t, created.HLC)
+	created.Size = 10
+	require.NoError(t, store.Update(ctx, "/f", created))
+	versions, err := store.ListVersions(ctx, "/f")
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.True(t, versions[0].HLC.Before(*versions[1].HLC))
+
+	events, _, err := store.Changelog().Since(1)
+	require.NoError(t, err)
+	for _, e := range events {
+		assert.NotNil(t, e.HLC, e.Path)
+	}
+
+	// 按 HLC 时间点读取
+	old, err := store.GetVersionAt(ctx, "/f", *versions[0].HLC)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), old.Size)
+	_, err = store.GetVersionAt(ctx, "/f", HLCTimestamp{Wall: 1})
+	assert.Error(t, err)
+
+	// 编码保留时间戳
+	data, err := versions[1].MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, versions[1].HLC, decoded.HLC)
+	compact := NewCompactStore()
+	entry, err := compact.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	entry.HLC = versions[1].HLC
+	require.NoError(t, compact.Update(ctx, "/f", entry))
+	got, err := compact.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, versions[1].HLC, got.HLC)
+
+	// 副本同步合并来源的时间戳，本地之后的写入排在其后
+	remote := HLCTimestamp{Wall: physical.Add(500 * time.Millisecond).UnixNano(), Logical: 3}
+	replicated := &Metadata{Inode: 99, Name: "r", Type: TypeRegular, Mode: 0644, Version: 1, HLC: &remote}
+	require.NoError(t, store.ApplyEntries(ctx, map[string]*Metadata{"/r": replicated}, nil))
+	local, err := store.Create(ctx, "/g", 0644)
+	require.NoError(t, err)
+	assert.True(t, remote.Before(*local.HLC))
+
+	ahead := HLCTimestamp{Wall: physical.Add(time.Hour).UnixNano()}
+	err = store.ApplyEntries(ctx, map[string]*Metadata{"/s": {Inode: 100, Name: "s", Version: 1, HLC: &ahead}}, nil)
+	assert.Error(t, err)
+	_, err = store.Get(ctx, "/s")
+	assert.Error(t, err, "rejected entries are not applied")
+}
@@ -0,0 +1,447 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Op 经过插件链的元数据修改操作
+type Op string
+
+const (
+	OpCreate    Op = "create"
+	OpUpdate    Op = "update"
+	OpSetAttr   Op = "setattr"
+	OpTruncate  Op = "truncate"
+	OpDelete    Op = "delete"
+	OpRename    Op = "rename"
+	OpSymlink   Op = "symlink"
+	OpMkdir     Op = "mkdir"
+	OpRmdir     Op = "rmdir"
+	OpDeleteAll Op = "delete_all"
+	OpLink      Op = "link"
+	OpExchange  Op = "exchange"
+)
+
+// OpInfo 一次元数据修改操作的描述，插件不应修改其内容
+type OpInfo struct {
+	Op      Op
+	Path    string
+	NewPath string      // 重命名或交换的另一个路径
+	Target  string      // 符号链接的目标，或硬链接指向的已有文件（发布临时文件时为空）
+	Mode    os.FileMode // 创建文件或目录的权限
+	Size    int64       // 截断后的大小
+	Meta    *Metadata   // Update 写入的元数据
+	Attrs   Attrs       // SetAttr 的属性值
+	Mask    SetAttrMask // SetAttr 修改的属性
+}
+
+// Hook 元数据操作插件，可用于配额、审计、触发病毒扫描或自定义策略
+//
+// Before 在操作执行前按注册顺序调用，任一插件返回错误时操作被拒绝，不再调用之后的插件；
+// After 在操作执行后按注册的逆序调用，只有 Before 通过的插件会收到，err 为操作结果。
+type Hook interface {
+	Before(ctx context.Context, op *OpInfo) error
+	After(ctx context.Context, op *OpInfo, err error)
+}
+
+// HookFuncs 用函数实现 Hook，为 nil 的函数被跳过
+type HookFuncs struct {
+	BeforeFunc func(ctx context.Context, op *OpInfo) error
+	AfterFunc  func(ctx context.Context, op *OpInfo, err error)
+}
+
+// Before 实现 Hook
+func (h HookFuncs) Before(ctx context.Context, op *OpInfo) error {
+	if h.BeforeFunc == nil {
+		return nil
+	}
+	return h.BeforeFunc(ctx, op)
+}
+
+// After 实现 Hook
+func (h HookFuncs) After(ctx context.Context, op *OpInfo, err error) {
+	if h.AfterFunc != nil {
+		h.AfterFunc(ctx, op, err)
+	}
+}
+
+// ErrDenied 操作被插件拒绝，可用 errors.Is 判断
+var ErrDenied = errors.New("operation denied")
+
+// DeniedError 操作被插件拒绝的详细错误
+type DeniedError struct {
+	Hook   string // 拒绝操作的插件名称
+	Op     Op
+	Path   string
+	Reason error // 插件返回的错误
+}
+
+// Error 实现 error
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%s %s denied by %s: %v", e.Op, e.Path, e.Hook, e.Reason)
+}
+
+// Is 使 errors.Is(err, ErrDenied) 成立
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// Unwrap 返回插件返回的错误
+func (e *DeniedError) Unwrap() error {
+	return e.Reason
+}
+
+// namedHook 带名称的插件
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+// HookedStore 在 NamespaceStore 外包装插件链，修改操作经过插件，读取操作直接转发
+//
+// NamespaceStore 之外的命名空间修改（Link、RenameExchange、RenameOnce、CreateWithData、CreateWithOptions、
+// MkdirWithOptions、DeleteTree、LinkTemp）在底层存储实现了对应方法时同样经过插件，否则返回 ErrUnsupported。
+// HookedStore 满足 MetaStore，Begin 和 RestoreSnapshot 在底层存储实现时直接转发，否则返回 ErrUnsupported。
+// SetLayout、Bind 等管理操作和按 inode 的 UpdateOpen 不通过 HookedStore 提供，
+// 持有底层存储的调用方不受插件约束，不应把底层存储交给需要受策略约束的调用方。
+type HookedStore struct {
+	NamespaceStore
+
+	mu    sync.RWMutex
+	hooks []namedHook
+}
+
+// NewHookedStore 在 store 外包装空的插件链，store 通常是 *MemoryStore
+func NewHookedStore(store NamespaceStore) *HookedStore {
+	return &HookedStore{NamespaceStore: store}
+}
+
+// Use 在插件链末尾注册插件，名称用于错误信息，不能重复
+func (s *HookedStore) Use(name string, h Hook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.hooks {
+		if existing.name == name {
+			return fmt.Errorf("hook %w: %s", ErrExist, name)
+		}
+	}
+	s.hooks = append(s.hooks, namedHook{name: name, hook: h})
+	return nil
+}
+
+// Hooks 返回已注册的插件名称，按调用顺序排列
+func (s *HookedStore) Hooks() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, len(s.hooks))
+	for i, h := range s.hooks {
+		names[i] = h.name
+	}
+	return names
+}
+
+// run 依次调用插件的 Before，全部通过后执行操作，再逆序调用 After
+func (s *HookedStore) run(ctx context.Context, op *OpInfo, fn func() error) error {
+	s.mu.RLock()
+	hooks := s.hooks
+	s.mu.RUnlock()
+
+	passed := 0
+	var err error
+	for _, h := range hooks {
+		if reason := h.hook.Before(ctx, op); reason != nil {
+			err = &DeniedError{Hook: h.name, Op: op.Op, Path: op.Path, Reason: reason}
+			break
+		}
+		passed++
+	}
+	if err == nil {
+		err = fn()
+	}
+	for i := passed - 1; i >= 0; i-- {
+		hooks[i].hook.After(ctx, op, err)
+	}
+	return err
+}
+
+// Create 实现 NamespaceStore
+func (s *HookedStore) Create(ctx context.Context, p string, mode os.FileMode) (*Metadata, error) {
+	var meta *Metadata
+	err := s.run(ctx, &OpInfo{Op: OpCreate, Path: p, Mode: mode}, func() error {
+		var err error
+		meta, err = s.NamespaceStore.Create(ctx, p, mode)
+		return err
+	})
+	return meta, err
+}
+
+// Update 实现 NamespaceStore
+func (s *HookedStore) Update(ctx context.Context, p string, meta *Metadata) error {
+	return s.run(ctx, &OpInfo{Op: OpUpdate, Path: p, Meta: meta}, func() error {
+		return s.NamespaceStore.Update(ctx, p, meta)
+	})
+}
+
+// SetAttr 实现 NamespaceStore
+func (s *HookedStore) SetAttr(ctx context.Context, p string, attrs Attrs, mask SetAttrMask) (*Metadata, error) {
+	var meta *Metadata
+	err := s.run(ctx, &OpInfo{Op: OpSetAttr, Path: p, Attrs: attrs, Mask: mask}, func() error {
+		var err error
+		meta, err = s.NamespaceStore.SetAttr(ctx, p, attrs, mask)
+		return err
+	})
+	return meta, err
+}
+
+// Truncate 实现 NamespaceStore
+func (s *HookedStore) Truncate(ctx context.Context, p string, size int64) error {
+	return s.run(ctx, &OpInfo{Op: OpTruncate, Path: p, Size: size}, func() error {
+		return s.NamespaceStore.Truncate(ctx, p, size)
+	})
+}
+
+// Delete 实现 NamespaceStore
+func (s *HookedStore) Delete(ctx context.Context, p string) error {
+	return s.run(ctx, &OpInfo{Op: OpDelete, Path: p}, func() error {
+		return s.NamespaceStore.Delete(ctx, p)
+	})
+}
+
+// Rename 实现 NamespaceStore
+func (s *HookedStore) Rename(ctx context.Context, oldPath, newPath string) error {
+	return s.run(ctx, &OpInfo{Op: OpRename, Path: oldPath, NewPath: newPath}, func() error {
+		return s.NamespaceStore.Rename(ctx, oldPath, newPath)
+	})
+}
+
+// Symlink 实现 NamespaceStore
+func (s *HookedStore) Symlink(ctx context.Context, target, linkPath string) error {
+	return s.run(ctx, &OpInfo{Op: OpSymlink, Path: linkPath, Target: target}, func() error {
+		return s.NamespaceStore.Symlink(ctx, target, linkPath)
+	})
+}
+
+// Mkdir 实现 NamespaceStore
+func (s *HookedStore) Mkdir(ctx context.Context, p string, mode os.FileMode) error {
+	return s.run(ctx, &OpInfo{Op: OpMkdir, Path: p, Mode: mode}, func() error {
+		return s.NamespaceStore.Mkdir(ctx, p, mode)
+	})
+}
+
+// Rmdir 实现 NamespaceStore
+func (s *HookedStore) Rmdir(ctx context.Context, p string) error {
+	return s.run(ctx, &OpInfo{Op: OpRmdir, Path: p}, func() error {
+		return s.NamespaceStore.Rmdir(ctx, p)
+	})
+}
+
+// DeleteAll 实现 NamespaceStore
+func (s *HookedStore) DeleteAll(ctx context.Context, p string) error {
+	return s.run(ctx, &OpInfo{Op: OpDeleteAll, Path: p}, func() error {
+		return s.NamespaceStore.DeleteAll(ctx, p)
+	})
+}
+
+// ErrUnsupported 底层存储没有实现该操作，可用 errors.Is 判断
+var ErrUnsupported = errors.New("operation not supported")
+
+// unsupported 返回底层存储不支持操作的错误
+func unsupported(op Op) error {
+	return fmt.Errorf("%w by underlying store: %s", ErrUnsupported, op)
+}
+
+// Begin 开始事务，不经过插件链
+func (s *HookedStore) Begin() (Transaction, error) {
+	store, ok := s.NamespaceStore.(interface {
+		Begin() (Transaction, error)
+	})
+	if !ok {
+		return nil, unsupported("begin")
+	}
+	return store.Begin()
+}
+
+// RestoreSnapshot 恢复快照，不经过插件链
+func (s *HookedStore) RestoreSnapshot(ctx context.Context, snapshotID string) error {
+	store, ok := s.NamespaceStore.(interface {
+		RestoreSnapshot(ctx context.Context, snapshotID string) error
+	})
+	if !ok {
+		return unsupported("restore_snapshot")
+	}
+	return store.RestoreSnapshot(ctx, snapshotID)
+}
+
+// Link 为已有文件创建硬链接，经过插件链，OpInfo 的 Path 为新路径，Target 为已有文件
+func (s *HookedStore) Link(ctx context.Context, existingPath, newPath string) error {
+	store, ok := s.NamespaceStore.(interface {
+		Link(ctx context.Context, existingPath, newPath string) error
+	})
+	if !ok {
+		return unsupported(OpLink)
+	}
+	return s.run(ctx, &OpInfo{Op: OpLink, Path: newPath, Target: existingPath}, func() error {
+		return store.Link(ctx, existingPath, newPath)
+	})
+}
+
+// RenameExchange 原子地交换两个路径，经过插件链
+func (s *HookedStore) RenameExchange(ctx context.Context, pathA, pathB string) error {
+	store, ok := s.NamespaceStore.(interface {
+		RenameExchange(ctx context.Context, pathA, pathB string) error
+	})
+	if !ok {
+		return unsupported(OpExchange)
+	}
+	return s.run(ctx, &OpInfo{Op: OpExchange, Path: pathA, NewPath: pathB}, func() error {
+		return store.RenameExchange(ctx, pathA, pathB)
+	})
+}
+
+// RenameOnce 以操作 ID 执行可安全重试的重命名，按 OpRename 经过插件链
+func (s *HookedStore) RenameOnce(ctx context.Context, opID, oldPath, newPath string) error {
+	store, ok := s.NamespaceStore.(interface {
+		RenameOnce(ctx context.Context, opID, oldPath, newPath string) error
+	})
+	if !ok {
+		return unsupported(OpRename)
+	}
+	return s.run(ctx, &OpInfo{Op: OpRename, Path: oldPath, NewPath: newPath}, func() error {
+		return store.RenameOnce(ctx, opID, oldPath, newPath)
+	})
+}
+
+// CreateWithData 创建文件并内联保存数据，按 OpCreate 经过插件链
+func (s *HookedStore) CreateWithData(ctx context.Context, p string, mode os.FileMode, data []byte) (*Metadata, error) {
+	store, ok := s.NamespaceStore.(interface {
+		CreateWithData(ctx context.Context, p string, mode os.FileMode, data []byte) (*Metadata, error)
+	})
+	if !ok {
+		return nil, unsupported(OpCreate)
+	}
+	var meta *Metadata
+	err := s.run(ctx, &OpInfo{Op: OpCreate, Path: p, Mode: mode}, func() error {
+		var err error
+		meta, err = store.CreateWithData(ctx, p, mode, data)
+		return err
+	})
+	return meta, err
+}
+
+// CreateWithOptions 按选项创建文件，按 OpCreate 经过插件链
+func (s *HookedStore) CreateWithOptions(ctx context.Context, p string, mode os.FileMode, opts CreateOptions) (*Metadata, bool, error) {
+	store, ok := s.NamespaceStore.(interface {
+		CreateWithOptions(ctx context.Context, p string, mode os.FileMode, opts CreateOptions) (*Metadata, bool, error)
+	})
+	if !ok {
+		return nil, false, unsupported(OpCreate)
+	}
+	var meta *Metadata
+	var created bool
+	err := s.run(ctx, &OpInfo{Op: OpCreate, Path: p, Mode: mode}, func() error {
+		var err error
+		meta, created, err = store.CreateWithOptions(ctx, p, mode, opts)
+		return err
+	})
+	return meta, created, err
+}
+
+// MkdirWithOptions 按选项创建目录，按 OpMkdir 经过插件链
+func (s *HookedStore) MkdirWithOptions(ctx context.Context, p string, mode os.FileMode, opts MkdirOptions) (*Metadata, error) {
+	store, ok := s.NamespaceStore.(interface {
+		MkdirWithOptions(ctx context.Context, p string, mode os.FileMode, opts MkdirOptions) (*Metadata, error)
+	})
+	if !ok {
+		return nil, unsupported(OpMkdir)
+	}
+	var meta *Metadata
+	err := s.run(ctx, &OpInfo{Op: OpMkdir, Path: p, Mode: mode}, func() error {
+		var err error
+		meta, err = store.MkdirWithOptions(ctx, p, mode, opts)
+		return err
+	})
+	return meta, err
+}
+
+// DeleteTree 分批删除子树，按 OpDeleteAll 经过插件链
+func (s *HookedStore) DeleteTree(ctx context.Context, p string, opts DeleteTreeOptions) (DeleteTreeProgress, error) {
+	store, ok := s.NamespaceStore.(interface {
+		DeleteTree(ctx context.Context, p string, opts DeleteTreeOptions) (DeleteTreeProgress, error)
+	})
+	if !ok {
+		return DeleteTreeProgress{}, unsupported(OpDeleteAll)
+	}
+	var progress DeleteTreeProgress
+	err := s.run(ctx, &OpInfo{Op: OpDeleteAll, Path: p}, func() error {
+		var err error
+		progress, err = store.DeleteTree(ctx, p, opts)
+		return err
+	})
+	return progress, err
+}
+
+// LinkTemp 将临时文件发布到 newPath，按 OpLink 经过插件链，Target 为空
+func (s *HookedStore) LinkTemp(ctx context.Context, inode uint64, newPath string) error {
+	store, ok := s.NamespaceStore.(interface {
+		LinkTemp(ctx context.Context, inode uint64, newPath string) error
+	})
+	if !ok {
+		return unsupported(OpLink)
+	}
+	return s.run(ctx, &OpInfo{Op: OpLink, Path: newPath}, func() error {
+		return store.LinkTemp(ctx, inode, newPath)
+	})
+}
+
+// HookFactory 创建插件，config 为该插件的配置项
+type HookFactory func(config map[string]string) (Hook, error)
+
+var (
+	hookFactoriesMu sync.RWMutex
+	hookFactories   = make(map[string]HookFactory)
+)
+
+// RegisterHook 按名称注册插件工厂，通常在插件包的 init 中调用
+//
+// 可选插件放在独立的包或带构建标签的文件中，编译进二进制后由 UseRegistered 按配置启用。
+// 名称重复时 panic。
+func RegisterHook(name string, factory HookFactory) {
+	hookFactoriesMu.Lock()
+	defer hookFactoriesMu.Unlock()
+	if _, ok := hookFactories[name]; ok {
+		panic("meta: duplicate hook registration: " + name)
+	}
+	hookFactories[name] = factory
+}
+
+// RegisteredHooks 返回已注册的插件名称，按字典序排列
+func RegisteredHooks() []string {
+	hookFactoriesMu.RLock()
+	defer hookFactoriesMu.RUnlock()
+	names := make([]string, 0, len(hookFactories))
+	for name := range hookFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UseRegistered 用名为 name 的已注册工厂和配置 config 创建插件，并注册到插件链末尾
+func (s *HookedStore) UseRegistered(name string, config map[string]string) error {
+	hookFactoriesMu.RLock()
+	factory, ok := hookFactories[name]
+	hookFactoriesMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("hook not registered: %s", name)
+	}
+	h, err := factory(config)
+	if err != nil {
+		return fmt.Errorf("failed to create hook %s: %v", name, err)
+	}
+	return s.Use(name, h)
+}
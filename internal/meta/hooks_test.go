@@ -0,0 +1,200 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ NamespaceStore = (*MemoryStore)(nil)
+	_ MetaStore      = (*HookedStore)(nil)
+)
+
+// restoringStore 在 MemoryStore 之上实现快照恢复，记录恢复的快照ID
+type restoringStore struct {
+	*MemoryStore
+	restored *string
+}
+
+func (s restoringStore) RestoreSnapshot(ctx context.Context, id string) error {
+	*s.restored = id
+	return nil
+}
+
+// recordingHook 记录调用顺序，deny 中的路径被拒绝
+type recordingHook struct {
+	name  string
+	calls *[]string
+	deny  string
+}
+
+func (h recordingHook) Before(ctx context.Context, op *OpInfo) error {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s before %s %s", h.name, op.Op, op.Path))
+	if op.Path == h.deny {
+		return errors.New("policy")
+	}
+	return nil
+}
+
+func (h recordingHook) After(ctx context.Context, op *OpInfo, err error) {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s after %s %s %v", h.name, op.Op, op.Path, err != nil))
+}
+
+func TestHookedStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewHookedStore(NewMemoryStore())
+	var calls []string
+	require.NoError(t, store.Use("audit", recordingHook{name: "audit", calls: &calls}))
+	require.NoError(t, store.Use("policy", recordingHook{name: "policy", calls: &calls, deny: "/secret"}))
+	assert.ErrorIs(t, store.Use("audit", HookFuncs{}), ErrExist)
+	assert.Equal(t, []string{"audit", "policy"}, store.Hooks())
+
+	_, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"audit before create /f",
+		"policy before create /f",
+		"policy after create /f false",
+		"audit after create /f false",
+	}, calls)
+
+	// 被拒绝的操作不执行，只有已通过的插件收到 After
+	calls = nil
+	err = store.Mkdir(ctx, "/secret", 0755)
+	assert.ErrorIs(t, err, ErrDenied)
+	var denied *DeniedError
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "policy", denied.Hook)
+	assert.Equal(t, OpMkdir, denied.Op)
+	_, err = store.Get(ctx, "/secret")
+	assert.Error(t, err)
+	assert.Equal(t, []string{
+		"audit before mkdir /secret",
+		"policy before mkdir /secret",
+		"audit after mkdir /secret true",
+	}, calls)
+
+	// 读取操作不经过插件
+	calls = nil
+	_, err = store.List(ctx, "/")
+	require.NoError(t, err)
+	assert.Empty(t, calls)
+
+	// 操作本身的错误传给 After
+	calls = nil
+	assert.Error(t, store.Rename(ctx, "/missing", "/g"))
+	assert.Equal(t, "audit after rename /missing true", calls[len(calls)-1])
+}
+
+func TestHookedStoreExtendedOps(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	store := NewHookedStore(inner)
+	var calls []string
+	require.NoError(t, store.Use("policy", recordingHook{name: "policy", calls: &calls, deny: "/secret"}))
+	require.NoError(t, inner.Mkdir(ctx, "/dir", 0755))
+	for _, p := range []string{"/a", "/b", "/secret"} {
+		_, err := inner.Create(ctx, p, 0644)
+		require.NoError(t, err)
+	}
+	tmp, err := inner.CreateTemp(ctx, "/", 0644)
+	require.NoError(t, err)
+
+	// NamespaceStore 之外的命名空间修改同样经过插件，被拒绝时不执行
+	denied := map[string]error{
+		"Link":           store.Link(ctx, "/a", "/secret"),
+		"RenameExchange": store.RenameExchange(ctx, "/secret", "/a"),
+		"RenameOnce":     store.RenameOnce(ctx, "op-1", "/secret", "/c"),
+		"LinkTemp":       store.LinkTemp(ctx, tmp.Inode, "/secret"),
+	}
+	_, denied["CreateWithData"] = store.CreateWithData(ctx, "/secret", 0644, []byte("x"))
+	_, _, denied["CreateWithOptions"] = store.CreateWithOptions(ctx, "/secret", 0644, CreateOptions{})
+	_, denied["MkdirWithOptions"] = store.MkdirWithOptions(ctx, "/secret", 0755, MkdirOptions{ExistOK: true})
+	_, denied["DeleteTree"] = store.DeleteTree(ctx, "/secret", DeleteTreeOptions{})
+	for name, err := range denied {
+		assert.ErrorIs(t, err, ErrDenied, name)
+	}
+	_, err = inner.Get(ctx, "/secret")
+	assert.NoError(t, err)
+
+	calls = nil
+	require.NoError(t, store.Link(ctx, "/a", "/dir/a"))
+	require.NoError(t, store.RenameExchange(ctx, "/a", "/b"))
+	_, err = store.CreateWithData(ctx, "/dir/inline", 0644, []byte("x"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"policy before link /dir/a",
+		"policy after link /dir/a false",
+		"policy before exchange /a",
+		"policy after exchange /a false",
+		"policy before create /dir/inline",
+		"policy after create /dir/inline false",
+	}, calls)
+
+	// 底层存储没有实现的操作返回 ErrUnsupported
+	plain := NewHookedStore(struct{ NamespaceStore }{store})
+	assert.ErrorIs(t, plain.Link(ctx, "/a", "/c"), ErrUnsupported)
+}
+
+func TestHookedStoreMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	inner := NewMemoryStore()
+	var store MetaStore = NewHookedStore(inner)
+
+	// 包装真实的 MemoryStore 时读取和快照直接转发，事务和快照恢复返回 ErrUnsupported
+	require.NoError(t, store.Mkdir(ctx, "/dir", 0755))
+	_, err := store.Create(ctx, "/dir/f", 0644)
+	require.NoError(t, err)
+	entries, err := store.List(ctx, "/dir")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	id, err := store.CreateSnapshot(ctx, "/dir")
+	require.NoError(t, err)
+	assert.Len(t, inner.ListSnapshots(ctx, "/dir"), 1)
+	_, err = store.Begin()
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.ErrorIs(t, store.RestoreSnapshot(ctx, id), ErrUnsupported)
+
+	// 底层存储实现了快照恢复时转发
+	var restored string
+	store = NewHookedStore(restoringStore{MemoryStore: inner, restored: &restored})
+	require.NoError(t, store.RestoreSnapshot(ctx, id))
+	assert.Equal(t, id, restored)
+}
+
+// scannedPaths 记录 test-scan 插件看到的新建文件
+var scannedPaths []string
+
+func init() {
+	RegisterHook("test-scan", func(config map[string]string) (Hook, error) {
+		suffix := config["suffix"]
+		return HookFuncs{AfterFunc: func(ctx context.Context, op *OpInfo, err error) {
+			if err == nil && op.Op == OpCreate && strings.HasSuffix(op.Path, suffix) {
+				scannedPaths = append(scannedPaths, op.Path)
+			}
+		}}, nil
+	})
+}
+
+func TestRegisteredHooks(t *testing.T) {
+	ctx := context.Background()
+	scannedPaths = nil
+	assert.Panics(t, func() { RegisterHook("test-scan", nil) })
+	assert.Contains(t, RegisteredHooks(), "test-scan")
+
+	store := NewHookedStore(NewMemoryStore())
+	require.NoError(t, store.UseRegistered("test-scan", map[string]string{"suffix": ".exe"}))
+	assert.Error(t, store.UseRegistered("missing", nil))
+
+	for _, p := range []string{"/a.exe", "/b.txt"} {
+		_, err := store.Create(ctx, p, 0644)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"/a.exe"}, scannedPaths)
+}
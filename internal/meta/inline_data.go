@@ -0,0 +1,54 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// InlineDataLimit 小文件数据不超过该大小时可随元数据内联保存
+const InlineDataLimit = 64 << 10
+
+// checkInlineData 检查内联数据大小
+func checkInlineData(filePath string, data []byte) error {
+	if len(data) > InlineDataLimit {
+		return fmt.Errorf("inline data too large for %s: %d > %d bytes", filePath, len(data), InlineDataLimit)
+	}
+	return nil
+}
+
+// CreateWithData 在一次加锁内创建文件并内联保存其数据
+//
+// 适用于编译产物等大量小文件的场景，省去先 Create 再写数据块并 Update 的额外往返。
+// 数据超过 InlineDataLimit 时返回错误，调用方应改为写入数据块。
+func (s *MemoryStore) CreateWithData(ctx context.Context, p string, mode os.FileMode, data []byte) (*Metadata, error) {
+	filePath := normalizePath(p)
+	if err := checkInlineData(filePath, data); err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockFenced(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	meta, err := s.create(ctx, s.locateEntry(filePath), mode, data)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Clone(), nil
+}
+
+// CreateWithData 在一次加锁内创建文件并内联保存其数据，语义同 MemoryStore.CreateWithData
+func (s *CompactStore) CreateWithData(ctx context.Context, p string, mode os.FileMode, data []byte) (*Metadata, error) {
+	filePath := normalizePath(p)
+	if err := checkInlineData(filePath, data); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(filePath, mode, data)
+}
@@ -0,0 +1,79 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWithData(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	data := []byte("package main\n")
+
+	meta, err := store.CreateWithData(ctx, "/main.go", 0644, data)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), meta.Size)
+	assert.Equal(t, data, meta.InlineData)
+
+	// 返回值和存储互不影响
+	meta.InlineData[0] = 'X'
+	got, err := store.Get(ctx, "/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, data, got.InlineData)
+
+	events, _, err := store.Changelog().Since(1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, ChangeCreate, events[0].Type)
+
+	_, err = store.CreateWithData(ctx, "/main.go", 0644, data)
+	assert.True(t, errors.Is(err, ErrExist))
+	_, err = store.CreateWithData(ctx, "/big", 0644, make([]byte, InlineDataLimit+1))
+	assert.Error(t, err)
+	_, err = store.Get(ctx, "/big")
+	assert.Error(t, err, "oversized data must not create the file")
+
+	empty, err := store.CreateWithData(ctx, "/empty", 0644, nil)
+	require.NoError(t, err)
+	assert.Zero(t, empty.Size)
+	assert.Nil(t, empty.InlineData)
+}
+
+func TestCompactStoreCreateWithData(t *testing.T) {
+	ctx := context.Background()
+	store := NewCompactStore()
+	data := []byte("inline contents")
+
+	_, err := store.CreateWithData(ctx, "/f", 0644, data)
+	require.NoError(t, err)
+	got, err := store.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, data, got.InlineData)
+	assert.Equal(t, int64(len(data)), got.Size)
+
+	// 反复更新触发整理后内联数据仍然完整
+	for i := 0; i < 200; i++ {
+		got.Owner = string(rune('a' + i%26))
+		got.Version = 0
+		require.NoError(t, store.Update(ctx, "/f", got))
+	}
+	got, err = store.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, data, got.InlineData)
+}
+
+func TestInlineDataProtoRoundTrip(t *testing.T) {
+	original := sampleMetadata()
+	original.Blocks = nil
+	original.InlineData = []byte{0, 1, 2, 0xff}
+
+	data, err := original.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, original.InlineData, decoded.InlineData)
+}
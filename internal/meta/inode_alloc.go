@@ -0,0 +1,98 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultInodeBatch 每次向存储预留的 inode 数量
+const DefaultInodeBatch = 1024
+
+// inodeAllocKey 持久化 inode 预留上限的存储键
+const inodeAllocKey = "/meta/inode_alloc"
+
+// InodeAllocator 通过 Storage 持久化的 inode 分配器
+//
+// 分配器按批预留：存储中记录已预留的上限，上限内的 inode 直接在内存中分配，用尽时才写入并同步新的上限。
+// 重启后从持久化的上限之后继续分配，崩溃时未用完的预留被跳过，因此已分配的 inode 不会被重复使用。
+type InodeAllocator struct {
+	mu      sync.Mutex
+	storage Storage
+	batch   uint64
+	next    uint64 // 最后分配的 inode
+	limit   uint64 // 已持久化的预留上限（含）
+}
+
+// NewInodeAllocator 从存储加载预留上限并创建分配器，batch 为 0 时使用 DefaultInodeBatch
+func NewInodeAllocator(ctx context.Context, storage Storage, batch int) (*InodeAllocator, error) {
+	if batch <= 0 {
+		batch = DefaultInodeBatch
+	}
+	a := &InodeAllocator{storage: storage, batch: uint64(batch)}
+
+	// 只在键确实不存在时从零开始，读取失败必须报错，否则重启后会重复分配
+	keys, err := storage.List(ctx, inodeAllocKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inode allocator state: %v", err)
+	}
+	for _, key := range keys {
+		if normalizePath(key) != inodeAllocKey {
+			continue
+		}
+		data, err := storage.Load(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load inode allocator state: %v", err)
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inode allocator state %q: %v", data, err)
+		}
+		a.limit = limit
+		a.next = limit
+	}
+	return a, nil
+}
+
+// Next 分配大于 floor 且大于之前所有分配结果的 inode
+//
+// floor 为调用方已知的最大 inode，例如从检查点或副本同步中加载的条目，使分配结果不与它们冲突。
+func (a *InodeAllocator) Next(ctx context.Context, floor uint64) (uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := a.next
+	if floor > n {
+		n = floor
+	}
+	n++
+	if n > a.limit {
+		limit := n + a.batch - 1
+		if err := a.storage.Save(ctx, inodeAllocKey, []byte(strconv.FormatUint(limit, 10))); err != nil {
+			return 0, fmt.Errorf("failed to reserve inodes: %v", err)
+		}
+		if err := a.storage.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync inode reservation: %v", err)
+		}
+		a.limit = limit
+	}
+	a.next = n
+	return n, nil
+}
+
+// Reserved 返回已持久化的预留上限
+func (a *InodeAllocator) Reserved() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.limit
+}
+
+// SetInodeAllocator 使用持久化分配器分配 inode，alloc 为 nil 时使用内存计数器
+//
+// 应在存储加载检查点之后、处理请求之前调用。
+func (s *MemoryStore) SetInodeAllocator(alloc *InodeAllocator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alloc = alloc
+}
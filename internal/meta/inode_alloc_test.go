@@ -0,0 +1,73 @@
+package meta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAllocStorage(t *testing.T) Storage {
+	storage, err := NewFileStorage(&StorageConfig{RootDir: t.TempDir(), SyncInterval: time.Second, FileMode: 0600})
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestInodeAllocatorBatchReservation(t *testing.T) {
+	ctx := context.Background()
+	storage := newAllocStorage(t)
+	alloc, err := NewInodeAllocator(ctx, storage, 4)
+	require.NoError(t, err)
+
+	var got []uint64
+	for i := 0; i < 5; i++ {
+		n, err := alloc.Next(ctx, 0)
+		require.NoError(t, err)
+		got = append(got, n)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, got)
+	assert.Equal(t, uint64(8), alloc.Reserved())
+
+	// 重启后跳过未用完的预留，不会重复分配
+	restarted, err := NewInodeAllocator(ctx, storage, 4)
+	require.NoError(t, err)
+	n, err := restarted.Next(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), n)
+
+	// floor 之下的 inode 不会被分配
+	n, err = restarted.Next(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(101), n)
+	assert.Equal(t, uint64(104), restarted.Reserved())
+
+	require.NoError(t, storage.Save(ctx, inodeAllocKey, []byte("garbage")))
+	_, err = NewInodeAllocator(ctx, storage, 4)
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreInodeAllocator(t *testing.T) {
+	ctx := context.Background()
+	storage := newAllocStorage(t)
+
+	store := NewMemoryStore()
+	alloc, err := NewInodeAllocator(ctx, storage, 16)
+	require.NoError(t, err)
+	store.SetInodeAllocator(alloc)
+	first, err := store.Create(ctx, "/a", 0644)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), first.Inode, "allocation starts above the root inode")
+	require.NoError(t, store.Mkdir(ctx, "/d", 0755))
+
+	// 重启后的存储不会复用崩溃前分配的 inode
+	restarted := NewMemoryStore()
+	alloc, err = NewInodeAllocator(ctx, storage, 16)
+	require.NoError(t, err)
+	restarted.SetInodeAllocator(alloc)
+	second, err := restarted.Create(ctx, "/b", 0644)
+	require.NoError(t, err)
+	assert.Greater(t, second.Inode, uint64(17))
+}
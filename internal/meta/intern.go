@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"strings"
+	"sync"
+)
+
+// Interner 字符串驻留表
+//
+// 宽命名空间中目录名、所有者、组、放置约束和数据服务器地址会重复出现数百万次，
+// 驻留后相同内容的字符串共享同一份底层存储。
+type Interner struct {
+	mu    sync.RWMutex
+	table map[string]string
+}
+
+// NewInterner 创建字符串驻留表
+func NewInterner() *Interner {
+	return &Interner{table: make(map[string]string)}
+}
+
+// Intern 返回与 v 内容相同的驻留字符串
+func (in *Interner) Intern(v string) string {
+	if v == "" {
+		return ""
+	}
+
+	in.mu.RLock()
+	interned, ok := in.table[v]
+	in.mu.RUnlock()
+	if ok {
+		return interned
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, ok := in.table[v]; ok {
+		return interned
+	}
+	// 拷贝一份，避免子串引用整条路径的底层数组
+	interned = strings.Clone(v)
+	in.table[interned] = interned
+	return interned
+}
+
+// Len 返回驻留的字符串数量
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.table)
+}
+
+// internMetadata 驻留元数据中重复率高的字符串字段
+func internMetadata(in *Interner, meta *Metadata) {
+	meta.Name = in.Intern(meta.Name)
+	meta.Owner = in.Intern(meta.Owner)
+	meta.Group = in.Intern(meta.Group)
+	meta.Placement = in.Intern(meta.Placement)
+	meta.StorageClass = in.Intern(meta.StorageClass)
+	for i := range meta.Blocks {
+		for j, location := range meta.Blocks[i].Locations {
+			meta.Blocks[i].Locations[j] = in.Intern(location)
+		}
+	}
+	for i := range meta.Extents {
+		for j, location := range meta.Extents[i].Locations {
+			meta.Extents[i].Locations[j] = in.Intern(location)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package meta
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterner(t *testing.T) {
+	in := NewInterner()
+
+	a := in.Intern(strings.Repeat("ab", 2))
+	b := in.Intern("abab")
+	assert.Equal(t, "abab", a)
+	assert.Same(t, unsafe.StringData(a), unsafe.StringData(b))
+	assert.Equal(t, "", in.Intern(""))
+	assert.Equal(t, 1, in.Len())
+
+	// 子串驻留时不引用原字符串
+	full := "/very/long/path/name"
+	base := in.Intern(full[len(full)-4:])
+	assert.NotSame(t, unsafe.StringData(full[len(full)-4:]), unsafe.StringData(base))
+}
+
+func TestMemoryStoreInterning(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	require.NoError(t, store.Mkdir(ctx, "/a", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/b", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/a/data", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/b/data", 0755))
+
+	for _, p := range []string{"/a/data", "/b/data"} {
+		meta, err := store.Get(ctx, p)
+		require.NoError(t, err)
+		meta.Owner = strings.Clone("alice")
+		meta.Blocks = []Block{{ID: "blk", Locations: []string{strings.Clone("ds1:9000")}}}
+		require.NoError(t, store.Update(ctx, p, meta))
+	}
+
+	a, err := store.Get(ctx, "/a/data")
+	require.NoError(t, err)
+	b, err := store.Get(ctx, "/b/data")
+	require.NoError(t, err)
+	assert.Same(t, unsafe.StringData(a.Name), unsafe.StringData(b.Name))
+	assert.Same(t, unsafe.StringData(a.Owner), unsafe.StringData(b.Owner))
+	assert.Same(t, unsafe.StringData(a.Blocks[0].Locations[0]), unsafe.StringData(b.Blocks[0].Locations[0]))
+}
+
+func TestCompactStoreSharedStrings(t *testing.T) {
+	store := NewCompactStore()
+	ctx := context.Background()
+
+	for _, p := range []string{"/a", "/b", "/a/data", "/b/data"} {
+		require.NoError(t, store.Mkdir(ctx, p, 0755))
+	}
+	for _, p := range []string{"/a/data", "/b/data"} {
+		meta, err := store.Get(ctx, p)
+		require.NoError(t, err)
+		meta.Owner = "alice"
+		require.NoError(t, store.Update(ctx, p, meta))
+	}
+
+	store.mu.RLock()
+	a, _ := store.lookup("/a/data")
+	b, _ := store.lookup("/b/data")
+	assert.Equal(t, store.entries[a].name, store.entries[b].name)
+	assert.Equal(t, store.entries[a].owner, store.entries[b].owner)
+	store.mu.RUnlock()
+
+	meta, err := store.Get(ctx, "/b/data")
+	require.NoError(t, err)
+	assert.Equal(t, "data", meta.Name)
+	assert.Equal(t, "alice", meta.Owner)
+}
@@ -0,0 +1,127 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLayoutViolation 写入的数据块不符合文件的条带布局，可用 errors.Is 判断
+var ErrLayoutViolation = errors.New("block violates file layout")
+
+// checkLayoutBlocks 检查数据块没有跨越布局 l 的条带单元边界，l 为 nil 时不检查
+func checkLayoutBlocks(l *StripeLayout, blocks []Block) error {
+	if l == nil {
+		return nil
+	}
+	for i := range blocks {
+		b := &blocks[i]
+		if b.Size > 0 && b.Offset/l.StripeSize != (b.End()-1)/l.StripeSize {
+			return fmt.Errorf("%w: block %s [%d, %d) crosses a %d byte stripe boundary",
+				ErrLayoutViolation, b.ID, b.Offset, b.End(), l.StripeSize)
+		}
+	}
+	return nil
+}
+
+// checkLayoutUpdate 检查更新保持文件布局不变且新的数据块符合布局，布局只能通过 SetLayout 修改
+func checkLayoutUpdate(filePath string, current, updated *Metadata) error {
+	if (current.Layout == nil) != (updated.Layout == nil) ||
+		(current.Layout != nil && *current.Layout != *updated.Layout) {
+		return fmt.Errorf("layout of %s can only be changed with SetLayout", filePath)
+	}
+	return checkLayoutBlocks(current.Layout, updated.Blocks)
+}
+
+// layoutFile 返回设置了布局的文件副本，文件已有数据时拒绝，调用方需持有写锁
+func layoutFile(current *Metadata, filePath string, layout *StripeLayout) (*Metadata, error) {
+	if current.Type != TypeRegular {
+		return nil, fmt.Errorf("path is not a file: %s", filePath)
+	}
+	if current.Size > 0 || len(current.Blocks) > 0 || len(current.Extents) > 0 || current.BlockCount > 0 {
+		return nil, fmt.Errorf("layout of %s must be set before the first write", filePath)
+	}
+	updated := current.Clone()
+	updated.Layout = layout.Clone()
+	updated.ModifyTime = time.Now()
+	updated.Version++
+	return updated, nil
+}
+
+// SetLayout 设置文件的条带布局，只能在第一次写入之前调用，layout 为 nil 时清除
+func (s *MemoryStore) SetLayout(ctx context.Context, p string, layout *StripeLayout) error {
+	if layout != nil {
+		if err := layout.Validate(); err != nil {
+			return err
+		}
+	}
+
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := normalizePath(p)
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	updated, err := layoutFile(current, filePath, layout)
+	if err != nil {
+		return err
+	}
+	s.put(filePath, updated)
+	s.recordChange(ctx, ChangeModify, filePath, updated)
+	return nil
+}
+
+// GetLayout 返回文件的条带布局，未设置时为 nil
+func (s *MemoryStore) GetLayout(ctx context.Context, p string) (*StripeLayout, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filePath := normalizePath(p)
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	return current.Layout.Clone(), nil
+}
+
+// SetLayout 设置文件的条带布局，只能在第一次写入之前调用，layout 为 nil 时清除
+func (s *CompactStore) SetLayout(ctx context.Context, p string, layout *StripeLayout) error {
+	if layout != nil {
+		if err := layout.Validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := normalizePath(p)
+	idx, exists := s.lookup(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	e := &s.entries[idx]
+	updated, err := layoutFile(s.decode(e), filePath, layout)
+	if err != nil {
+		return err
+	}
+	s.release(e)
+	s.encode(e, updated)
+	s.maybeCompact()
+	return nil
+}
+
+// GetLayout 返回文件的条带布局，未设置时为 nil
+func (s *CompactStore) GetLayout(ctx context.Context, p string) (*StripeLayout, error) {
+	m, err := s.Get(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return m.Layout, nil
+}
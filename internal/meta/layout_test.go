@@ -0,0 +1,54 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLayout(t *testing.T) {
+	for name, store := range map[string]interface {
+		dirDefaultsStore
+		SetLayout(ctx context.Context, path string, layout *StripeLayout) error
+		GetLayout(ctx context.Context, path string) (*StripeLayout, error)
+	}{
+		"memory":  NewMemoryStore(),
+		"compact": NewCompactStore(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := store.Create(ctx, "/f", 0644)
+			require.NoError(t, err)
+			layout := &StripeLayout{StripeCount: 4, StripeSize: 1 << 20, StartIndex: 1}
+			require.NoError(t, store.SetLayout(ctx, "/f", layout))
+			got, err := store.GetLayout(ctx, "/f")
+			require.NoError(t, err)
+			assert.Equal(t, layout, got)
+
+			f, err := store.Get(ctx, "/f")
+			require.NoError(t, err)
+			f.Size = 3 << 19
+			f.Blocks = []Block{{ID: "b1", Offset: 1 << 19, Size: 1 << 20, Locations: []string{"d1"}}}
+			assert.ErrorIs(t, store.Update(ctx, "/f", f), ErrLayoutViolation)
+
+			f.Blocks = []Block{
+				{ID: "b1", Offset: 0, Size: 1 << 20, Locations: []string{"d1"}},
+				{ID: "b2", Offset: 1 << 20, Size: 1 << 19, Locations: []string{"d2"}},
+			}
+			require.NoError(t, store.Update(ctx, "/f", f))
+			assert.Error(t, store.SetLayout(ctx, "/f", &StripeLayout{StripeCount: 1, StripeSize: 1 << 20}),
+				"layout is fixed after the first write")
+
+			f, err = store.Get(ctx, "/f")
+			require.NoError(t, err)
+			f.Layout = nil
+			assert.Error(t, store.Update(ctx, "/f", f), "updates cannot drop the layout")
+
+			require.NoError(t, store.Mkdir(ctx, "/d", 0755))
+			assert.Error(t, store.SetLayout(ctx, "/d", layout))
+			assert.Error(t, store.SetLayout(ctx, "/missing", layout))
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package meta
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultListBudget ListPage 和 Search 每次调用的默认时间预算
+	DefaultListBudget = 2 * time.Second
+
+	// listChunk 每次加读锁处理的条目数，两批之间释放锁让写操作进入
+	listChunk = 1024
+)
+
+// ErrInvalidPageToken 续传令牌无法解码或不属于查询的目录，可用 errors.Is 判断
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// encodePageToken 将最后处理的路径编码为续传令牌
+func encodePageToken(p string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(p))
+}
+
+// decodePageToken 解码续传令牌，令牌必须属于 root 子树
+func decodePageToken(token, root string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+	after := string(raw)
+	if !isWithin(after, root) {
+		return "", fmt.Errorf("%w: not within %s", ErrInvalidPageToken, root)
+	}
+	return after, nil
+}
+
+// pageDeadline 返回本次调用的截止时间
+func pageDeadline(ctx context.Context, budget time.Duration) time.Time {
+	if budget <= 0 {
+		budget = DefaultListBudget
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		budget = min(budget, time.Until(deadline)/2)
+	}
+	return time.Now().Add(budget)
+}
+
+// scanPage 按路径顺序处理续传位置之后的候选条目，match 返回 true 的条目计入结果
+//
+// 每批 listChunk 个条目加一次读锁；达到 Limit 或时间预算耗尽时返回，续传令牌为最后处理的路径，
+// 因此即使没有匹配的条目，后续调用也总能向前推进。
+func (s *MemoryStore) scanPage(ctx context.Context, candidates []string, opts PageOptions, match func(*Metadata) bool) (*Page, error) {
+	sort.Strings(candidates)
+	deadline := pageDeadline(ctx, opts.Budget)
+	page := &Page{}
+	for i := 0; i < len(candidates); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i > 0 && time.Now().After(deadline) {
+			page.NextToken = encodePageToken(candidates[i-1])
+			page.Truncated = true
+			return page, nil
+		}
+
+		end := min(i+listChunk, len(candidates))
+		s.mu.RLock()
+		for ; i < end; i++ {
+			m, exists := s.tree.get(candidates[i])
+			if !exists || (match != nil && !match(m)) {
+				continue
+			}
+			page.Entries = append(page.Entries, m.Clone())
+			page.Paths = append(page.Paths, candidates[i])
+			if opts.Limit > 0 && len(page.Entries) == opts.Limit {
+				i++
+				break
+			}
+		}
+		s.mu.RUnlock()
+
+		if opts.Limit > 0 && len(page.Entries) == opts.Limit {
+			if i < len(candidates) {
+				page.NextToken = encodePageToken(candidates[i-1])
+			}
+			return page, nil
+		}
+	}
+	return page, nil
+}
+
+// ListPage 分页列出目录内容，按名称排序
+//
+// 超大目录的单次调用受时间预算约束：预算耗尽时返回已得到的条目、续传令牌和 Truncated 标记，
+// 而不是让 RPC 超时；调用之间释放锁，不会长时间阻塞写操作。
+func (s *MemoryStore) ListPage(ctx context.Context, p string, opts PageOptions) (*Page, error) {
+	requested := normalizePath(p)
+	s.mu.RLock()
+	dirPath := s.locate(requested)
+	after, err := decodePageToken(opts.Token, dirPath)
+	if err != nil {
+		s.mu.RUnlock()
+		return nil, err
+	}
+
+	dirMeta, exists := s.tree.get(dirPath)
+	if !exists {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if dirMeta.Type != TypeDirectory {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+	var children []string
+	for _, child := range s.tree.childPaths(dirPath) {
+		if child > after {
+			children = append(children, child)
+		}
+	}
+	s.mu.RUnlock()
+
+	page, err := s.scanPage(ctx, children, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	unlocatePaths(page.Paths, requested, dirPath)
+	return page, nil
+}
+
+// ListStream 按名称顺序逐个回调目录的子项，fn 返回错误时停止并返回该错误
+//
+// 子项路径在开始时取一次快照，之后每 listChunk 个条目加一次读锁读取元数据，回调在锁外执行，
+// 因此服务端流式返回目录内容时不需要在内存中保存全部元数据。期间被删除的子项被跳过，新建的子项不出现。
+func (s *MemoryStore) ListStream(ctx context.Context, p string, fn func(*Metadata) error) error {
+	s.mu.RLock()
+	dirPath := s.locate(normalizePath(p))
+	dirMeta, exists := s.tree.get(dirPath)
+	if !exists {
+		s.mu.RUnlock()
+		return fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if dirMeta.Type != TypeDirectory {
+		s.mu.RUnlock()
+		return fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+	children := s.tree.childPaths(dirPath)
+	s.mu.RUnlock()
+	sort.Strings(children)
+
+	batch := make([]*Metadata, 0, min(len(children), listChunk))
+	for i := 0; i < len(children); i += listChunk {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		s.mu.RLock()
+		for _, child := range children[i:min(i+listChunk, len(children))] {
+			if m, exists := s.tree.get(child); exists {
+				batch = append(batch, m.Clone())
+			}
+		}
+		s.mu.RUnlock()
+
+		for _, m := range batch {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SearchOptions 子树搜索条件
+type SearchOptions struct {
+	PageOptions
+	// Name 名称的 path.Match 模式，为空时匹配全部
+	Name string
+	// Types 条目类型，为空时不限
+	Types []FileType
+	// MinSize 普通文件的最小字节数
+	MinSize int64
+}
+
+// Search 在 root 子树中分页搜索条目，按路径排序，时间预算与续传语义同 ListPage
+func (s *MemoryStore) Search(ctx context.Context, root string, opts SearchOptions) (*Page, error) {
+	if opts.Name != "" {
+		if _, err := path.Match(opts.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %v", opts.Name, err)
+		}
+	}
+	requested := normalizePath(root)
+	s.mu.RLock()
+	rootPath := s.locate(requested)
+	after, err := decodePageToken(opts.Token, rootPath)
+	if err != nil {
+		s.mu.RUnlock()
+		return nil, err
+	}
+
+	if _, exists := s.tree.get(rootPath); !exists {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("directory not found: %s", rootPath)
+	}
+	var candidates []string
+	for _, p := range append([]string{rootPath}, s.tree.descendants(rootPath)...) {
+		if p > after {
+			candidates = append(candidates, p)
+		}
+	}
+	s.mu.RUnlock()
+
+	page, err := s.scanPage(ctx, candidates, opts.PageOptions, func(m *Metadata) bool {
+		if len(opts.Types) > 0 && !slices.Contains(opts.Types, m.Type) {
+			return false
+		}
+		if opts.MinSize > 0 && m.Size < opts.MinSize {
+			return false
+		}
+		if opts.Name != "" {
+			ok, _ := path.Match(opts.Name, m.Name)
+			return ok
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	unlocatePaths(page.Paths, requested, rootPath)
+	return page, nil
+}
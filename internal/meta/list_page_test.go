@@ -0,0 +1,144 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreListPage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/dir", 0755))
+	for i := 0; i < 5; i++ {
+		_, err := store.Create(ctx, fmt.Sprintf("/dir/f%d", i), 0644)
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Mkdir(ctx, "/dir/sub", 0755))
+	_, err := store.Create(ctx, "/dir/sub/nested", 0644)
+	require.NoError(t, err)
+
+	page, err := store.ListPage(ctx, "/dir", PageOptions{Limit: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dir/f0", "/dir/f1", "/dir/f2", "/dir/f3"}, page.Paths)
+	assert.Equal(t, "f0", page.Entries[0].Name)
+	assert.False(t, page.Truncated)
+	require.NotEmpty(t, page.NextToken)
+
+	page, err = store.ListPage(ctx, "/dir", PageOptions{Token: page.NextToken, Limit: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dir/f4", "/dir/sub"}, page.Paths)
+	assert.Empty(t, page.NextToken)
+
+	_, err = store.ListPage(ctx, "/dir", PageOptions{Token: "!!"})
+	assert.Error(t, err)
+	_, err = store.ListPage(ctx, "/other", PageOptions{Token: encodePageToken("/dir/f1")})
+	assert.Error(t, err)
+	_, err = store.ListPage(ctx, "/dir/f0", PageOptions{})
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreListPageBudget(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/big", 0755))
+	const total = listChunk*2 + 10
+	for i := 0; i < total; i++ {
+		_, err := store.Create(ctx, fmt.Sprintf("/big/f%05d", i), 0644)
+		require.NoError(t, err)
+	}
+
+	// 预算耗尽时每次调用至少处理一批，返回部分结果和续传令牌
+	seen := make(map[string]bool)
+	opts := PageOptions{Budget: time.Nanosecond}
+	calls := 0
+	for {
+		page, err := store.ListPage(ctx, "/big", opts)
+		require.NoError(t, err)
+		calls++
+		for _, p := range page.Paths {
+			assert.False(t, seen[p], "duplicate entry %s", p)
+			seen[p] = true
+		}
+		if page.NextToken == "" {
+			assert.False(t, page.Truncated)
+			break
+		}
+		assert.True(t, page.Truncated)
+		opts.Token = page.NextToken
+	}
+	assert.Len(t, seen, total)
+	assert.Equal(t, 3, calls)
+}
+
+func TestMemoryStoreListStream(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/dir", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/dir/sub", 0755))
+	n := listChunk + 10
+	for i := 0; i < n; i++ {
+		_, err := store.Create(ctx, fmt.Sprintf("/dir/f%05d", i), 0644)
+		require.NoError(t, err)
+	}
+	_, err := store.Create(ctx, "/dir/sub/nested", 0644)
+	require.NoError(t, err)
+
+	var names []string
+	require.NoError(t, store.ListStream(ctx, "/dir", func(m *Metadata) error {
+		names = append(names, m.Name)
+		return nil
+	}))
+	require.Len(t, names, n+1)
+	assert.Equal(t, "f00000", names[0])
+	assert.Equal(t, "sub", names[n])
+
+	// 回调返回错误时停止
+	stop := errors.New("stop")
+	count := 0
+	err = store.ListStream(ctx, "/dir", func(m *Metadata) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 3, count)
+
+	assert.Error(t, store.ListStream(ctx, "/dir/f00000", func(*Metadata) error { return nil }))
+}
+
+func TestMemoryStoreSearch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/data", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/data/logs", 0755))
+	for _, p := range []string{"/data/a.csv", "/data/b.txt", "/data/logs/c.csv", "/other.csv"} {
+		meta, err := store.Create(ctx, p, 0644)
+		require.NoError(t, err)
+		meta.Size = int64(len(p))
+		require.NoError(t, store.Update(ctx, p, meta))
+	}
+
+	page, err := store.Search(ctx, "/data", SearchOptions{Name: "*.csv"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/data/a.csv", "/data/logs/c.csv"}, page.Paths)
+
+	page, err = store.Search(ctx, "/data", SearchOptions{Types: []FileType{TypeDirectory}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/data", "/data/logs"}, page.Paths)
+
+	page, err = store.Search(ctx, "/data", SearchOptions{MinSize: 12, PageOptions: PageOptions{Limit: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/data/logs/c.csv"}, page.Paths)
+	assert.Empty(t, page.NextToken)
+
+	_, err = store.Search(ctx, "/data", SearchOptions{Name: "["})
+	assert.Error(t, err)
+}
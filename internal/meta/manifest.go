@@ -0,0 +1,252 @@
+package meta
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestVersion 清单格式版本
+const ManifestVersion = 1
+
+// ManifestEntry 清单中的一个条目，路径相对于清单根目录
+type ManifestEntry struct {
+	Path     string   `json:"path"`
+	Type     FileType `json:"type"`
+	Size     int64    `json:"size,omitempty"`
+	Version  uint64   `json:"version"`
+	Checksum string   `json:"checksum,omitempty"` // 普通文件内容摘要，见 ContentDigest；符号链接为目标的摘要
+}
+
+// Manifest 目录树的清单，用于固定并复现数据集快照
+type Manifest struct {
+	Format  int             `json:"format"`
+	Root    string          `json:"root"`
+	Created time.Time       `json:"created"`
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ContentDigest 返回文件内容的摘要
+//
+// 由按偏移排序的数据块 ID、大小和校验和计算，内联文件直接对数据计算，不需要读取数据服务器；
+// 数据块不可变，因此摘要相同即内容相同。
+func ContentDigest(m *Metadata, blocks []Block) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "size|%d\n", m.Size)
+	if len(m.InlineData) > 0 {
+		fmt.Fprintf(h, "inline|%x\n", m.InlineData)
+	}
+	sorted := append([]Block(nil), blocks...)
+	sortBlocks(sorted)
+	for _, b := range sorted {
+		fmt.Fprintf(h, "%d|%d|%s|%s\n", b.Offset, b.Size, b.ID, b.Checksum)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// relativePath 返回 p 相对于 root 的路径，root 本身为 "."
+func relativePath(root, p string) string {
+	if p == root {
+		return "."
+	}
+	if root == "/" {
+		return p[1:]
+	}
+	return strings.TrimPrefix(p, root+"/")
+}
+
+// manifestEntries 在读锁内收集 root 子树的清单条目，按路径排序
+func (s *MemoryStore) manifestEntries(ctx context.Context, root string) ([]ManifestEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.tree.get(root); !exists {
+		return nil, fmt.Errorf("file not found: %s", root)
+	}
+	var entries []ManifestEntry
+	for p, m := range s.tree.entries(root) {
+		e := ManifestEntry{Path: relativePath(root, p), Type: m.Type, Version: m.Version}
+		if m.Type == TypeRegular {
+			blocks, err := s.fileBlocks(ctx, m)
+			if err != nil {
+				return nil, err
+			}
+			e.Size = m.Size
+			e.Checksum = ContentDigest(m, blocks)
+		}
+		if m.Type == TypeSymlink {
+			sum := sha256.Sum256([]byte(m.Target))
+			e.Checksum = hex.EncodeToString(sum[:])
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// BuildManifest 生成 p 子树的清单，在一次读锁内完成，反映同一时刻的状态
+func (s *MemoryStore) BuildManifest(ctx context.Context, p string) (*Manifest, error) {
+	root := normalizePath(p)
+	entries, err := s.manifestEntries(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return &Manifest{Format: ManifestVersion, Root: root, Created: time.Now(), Entries: entries}, nil
+}
+
+// WriteTo 以 JSON 格式写出清单
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// ReadManifest 读取 WriteTo 写出的清单
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %v", err)
+	}
+	if m.Format != ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest format: %d", m.Format)
+	}
+	return &m, nil
+}
+
+// ManifestMismatch 清单与当前目录树的一处差异
+type ManifestMismatch struct {
+	Path     string `json:"path"`
+	Field    string `json:"field"` // type、size、checksum 或 version
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// ManifestDiff 清单校验结果
+type ManifestDiff struct {
+	Missing []string           `json:"missing,omitempty"` // 清单中有、目录树中没有的路径
+	Extra   []string           `json:"extra,omitempty"`   // 目录树中有、清单中没有的路径
+	Changed []ManifestMismatch `json:"changed,omitempty"`
+}
+
+// OK 判断目录树与清单是否一致
+func (d *ManifestDiff) OK() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Changed) == 0
+}
+
+// VerifyManifestOptions VerifyManifest 的选项
+type VerifyManifestOptions struct {
+	// Root 校验的目录，为空时使用清单中的根目录，用于校验复制到其他位置的数据集
+	Root string
+	// CompareVersions 同时比较版本号，内容相同但被重写过的文件也视为不一致
+	CompareVersions bool
+}
+
+// VerifyManifest 将当前目录树与清单比较
+func (s *MemoryStore) VerifyManifest(ctx context.Context, m *Manifest, opts VerifyManifestOptions) (*ManifestDiff, error) {
+	root := m.Root
+	if opts.Root != "" {
+		root = opts.Root
+	}
+	live, err := s.manifestEntries(ctx, normalizePath(root))
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]ManifestEntry, len(live))
+	for _, e := range live {
+		current[e.Path] = e
+	}
+
+	diff := &ManifestDiff{}
+	changed := func(p, field string, expected, actual any) {
+		diff.Changed = append(diff.Changed, ManifestMismatch{
+			Path: p, Field: field, Expected: fmt.Sprint(expected), Actual: fmt.Sprint(actual),
+		})
+	}
+	for _, want := range m.Entries {
+		got, ok := current[want.Path]
+		if !ok {
+			diff.Missing = append(diff.Missing, want.Path)
+			continue
+		}
+		delete(current, want.Path)
+		switch {
+		case got.Type != want.Type:
+			changed(want.Path, "type", want.Type, got.Type)
+		case got.Size != want.Size:
+			changed(want.Path, "size", want.Size, got.Size)
+		// 旧清单中的符号链接没有摘要，不比较
+		case want.Checksum != "" && got.Checksum != want.Checksum:
+			changed(want.Path, "checksum", want.Checksum, got.Checksum)
+		case opts.CompareVersions && got.Version != want.Version:
+			changed(want.Path, "version", want.Version, got.Version)
+		}
+	}
+	for p := range current {
+		diff.Extra = append(diff.Extra, p)
+	}
+	sort.Strings(diff.Extra)
+	return diff, nil
+}
+
+// SnapshotDiff 两个快照之间的差异，路径相对于清单根目录并按字典序排列
+type SnapshotDiff struct {
+	Created  []string `json:"created,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+	Deleted  []string `json:"deleted,omitempty"`
+}
+
+// DiffSnapshots 比较用清单固定的两个快照，返回从 a 到 b 新建、修改和删除的路径
+//
+// 普通文件和符号链接按大小和摘要比较，重写为相同内容不算修改；没有摘要的条目按版本比较；
+// 目录的版本随子项变化，只在类型改变时报告。只读取两个清单，不遍历命名空间，
+// 增量备份只需复制 Created 和 Modified 中的文件并删除 Deleted 中的路径。
+func DiffSnapshots(a, b *Manifest) (*SnapshotDiff, error) {
+	for _, m := range []*Manifest{a, b} {
+		if m.Format != ManifestVersion {
+			return nil, fmt.Errorf("unsupported manifest format: %d", m.Format)
+		}
+	}
+	before := make(map[string]ManifestEntry, len(a.Entries))
+	for _, e := range a.Entries {
+		before[e.Path] = e
+	}
+
+	diff := &SnapshotDiff{}
+	for _, e := range b.Entries {
+		old, ok := before[e.Path]
+		if !ok {
+			diff.Created = append(diff.Created, e.Path)
+			continue
+		}
+		delete(before, e.Path)
+		switch {
+		case old.Type != e.Type:
+			diff.Modified = append(diff.Modified, e.Path)
+		case e.Type == TypeDirectory:
+		case old.Checksum != "" && e.Checksum != "":
+			if old.Size != e.Size || old.Checksum != e.Checksum {
+				diff.Modified = append(diff.Modified, e.Path)
+			}
+		case old.Version != e.Version:
+			diff.Modified = append(diff.Modified, e.Path)
+		}
+	}
+	for p := range before {
+		diff.Deleted = append(diff.Deleted, p)
+	}
+	sort.Strings(diff.Created)
+	sort.Strings(diff.Modified)
+	sort.Strings(diff.Deleted)
+	return diff, nil
+}
@@ -0,0 +1,115 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestBuildAndVerify(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/datasets", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/datasets/v1", 0755))
+	_, err := store.CreateWithData(ctx, "/datasets/v1/labels.csv", 0644, []byte("a,1\n"))
+	require.NoError(t, err)
+	f, err := store.Create(ctx, "/datasets/v1/train.bin", 0644)
+	require.NoError(t, err)
+	f.Size = 8
+	f.Blocks = []Block{{ID: "b1", Size: 8, Checksum: "c1", Locations: []string{"d1"}}}
+	require.NoError(t, store.Update(ctx, "/datasets/v1/train.bin", f))
+
+	m, err := store.BuildManifest(ctx, "/datasets/v1")
+	require.NoError(t, err)
+	paths := make([]string, len(m.Entries))
+	for i, e := range m.Entries {
+		paths[i] = e.Path
+	}
+	assert.Equal(t, []string{".", "labels.csv", "train.bin"}, paths)
+	assert.Equal(t, int64(8), m.Entries[2].Size)
+	assert.NotEmpty(t, m.Entries[2].Checksum)
+
+	var buf bytes.Buffer
+	_, err = m.WriteTo(&buf)
+	require.NoError(t, err)
+	loaded, err := ReadManifest(&buf)
+	require.NoError(t, err)
+
+	diff, err := store.VerifyManifest(ctx, loaded, VerifyManifestOptions{})
+	require.NoError(t, err)
+	assert.True(t, diff.OK(), "%+v", diff)
+
+	// 重写为相同内容只改变版本
+	f, err = store.Get(ctx, "/datasets/v1/train.bin")
+	require.NoError(t, err)
+	require.NoError(t, store.Update(ctx, "/datasets/v1/train.bin", f))
+	diff, err = store.VerifyManifest(ctx, loaded, VerifyManifestOptions{})
+	require.NoError(t, err)
+	assert.True(t, diff.OK())
+	diff, err = store.VerifyManifest(ctx, loaded, VerifyManifestOptions{CompareVersions: true})
+	require.NoError(t, err)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, "version", diff.Changed[0].Field)
+
+	f.Blocks = []Block{{ID: "b2", Size: 8, Checksum: "c2", Locations: []string{"d1"}}}
+	require.NoError(t, store.Update(ctx, "/datasets/v1/train.bin", f))
+	require.NoError(t, store.Delete(ctx, "/datasets/v1/labels.csv"))
+	_, err = store.Create(ctx, "/datasets/v1/new", 0644)
+	require.NoError(t, err)
+	diff, err = store.VerifyManifest(ctx, loaded, VerifyManifestOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"labels.csv"}, diff.Missing)
+	assert.Equal(t, []string{"new"}, diff.Extra)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, ManifestMismatch{Path: "train.bin", Field: "checksum",
+		Expected: m.Entries[2].Checksum, Actual: diff.Changed[0].Actual}, diff.Changed[0])
+
+	_, err = ReadManifest(bytes.NewBufferString(`{"format": 9}`))
+	assert.Error(t, err)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/ds", 0755))
+	for _, name := range []string{"keep", "rewrite", "change", "remove"} {
+		_, err := store.CreateWithData(ctx, "/ds/"+name, 0644, []byte(name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Symlink(ctx, "keep", "/ds/link"))
+	before, err := store.BuildManifest(ctx, "/ds")
+	require.NoError(t, err)
+
+	f, err := store.Get(ctx, "/ds/rewrite")
+	require.NoError(t, err)
+	require.NoError(t, store.Update(ctx, "/ds/rewrite", f))
+	f, err = store.Get(ctx, "/ds/change")
+	require.NoError(t, err)
+	f.InlineData = []byte("changed")
+	f.Size = 7
+	require.NoError(t, store.Update(ctx, "/ds/change", f))
+	require.NoError(t, store.Delete(ctx, "/ds/remove"))
+	require.NoError(t, store.Delete(ctx, "/ds/link"))
+	require.NoError(t, store.Symlink(ctx, "change", "/ds/link"))
+	require.NoError(t, store.Mkdir(ctx, "/ds/sub", 0755))
+	_, err = store.Create(ctx, "/ds/sub/new", 0644)
+	require.NoError(t, err)
+	after, err := store.BuildManifest(ctx, "/ds")
+	require.NoError(t, err)
+
+	diff, err := DiffSnapshots(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub", "sub/new"}, diff.Created)
+	assert.Equal(t, []string{"change", "link"}, diff.Modified)
+	assert.Equal(t, []string{"remove"}, diff.Deleted)
+
+	diff, err = DiffSnapshots(after, after)
+	require.NoError(t, err)
+	assert.Equal(t, &SnapshotDiff{}, diff)
+
+	_, err = DiffSnapshots(before, &Manifest{Format: 99})
+	assert.Error(t, err)
+}
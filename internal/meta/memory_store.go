@@ -0,0 +1,514 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	api "cpfs/api/meta"
+	"cpfs/internal/cluster"
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// MemoryStore 内存元数据存储实现
+type MemoryStore struct {
+	mu       sync.RWMutex
+	tree     *nsTree
+	inodes   uint64
+	root     *Metadata
+	interner *Interner
+
+	// 大文件拆分出的块映射段及其持久化存储，按 inode 保存
+	segments     map[uint64][]*blockSegment
+	blockStorage Storage
+
+	// 有多个硬链接的 inode 及其全部目录项节点
+	links map[uint64][]*treeNode
+
+	// 命名空间变更日志
+	changelog *Changelog
+
+	// 项目配额，为 nil 时不统计
+	quotas *ProjectQuotas
+
+	// 用户与组配额，为 nil 时不统计
+	owners *OwnerQuotas
+
+	// 持久化 inode 分配器，为 nil 时使用内存计数器 inodes
+	alloc *InodeAllocator
+
+	// 混合逻辑时钟，为 nil 时元数据版本不带 HLC 时间戳
+	hlc *HLC
+
+	// 最近完成的带操作 ID 的重命名，用于识别重试
+	renames *renameJournal
+
+	// 截断后不再被引用、等待回收的数据块
+	released []Block
+
+	// 客户端打开的 inode 及其引用计数
+	opens map[uint64]*openFile
+
+	// 路径解析缓存
+	dentries *dentryCache
+
+	// 绑定点数量，为 0 时跳过路径替换
+	bindPoints int
+
+	// 写入栅栏，为 nil 时不检查
+	fence *WriteFence
+
+	// 加密区域查询，为 nil 时不检查区域边界
+	zones ZoneLookup
+
+	// 设置了目录配额的目录的子树用量缓存，按目录 inode 保存，未缓存时按需统计
+	dirUsage map[uint64]QuotaUsage
+
+	// 按 inode 保留的历史版本，historyLimit 为 0 时不保留
+	history      map[uint64][]*Metadata
+	historyLimit int
+
+	// 按ID保存的目录快照
+	snapshots map[string]*storeSnapshot
+
+	log logger.Logger
+}
+
+// NewMemoryStore 创建新的内存存储
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		interner:  NewInterner(),
+		segments:  make(map[uint64][]*blockSegment),
+		links:     make(map[uint64][]*treeNode),
+		opens:     make(map[uint64]*openFile),
+		changelog: NewChangelog(DefaultChangelogCapacity),
+		renames:   newRenameJournal(DefaultRenameJournalSize, DefaultRenameJournalTTL),
+		dentries:  newDentryCache(DefaultDentryCacheSize),
+		log:       logger.Default(),
+	}
+
+	// 创建根目录，根目录的 inode 固定为 1
+	store.inodes = 1
+	root := &Metadata{
+		Inode:      1,
+		Name:       "/",
+		Type:       TypeDirectory,
+		Mode:       0755,
+		CreateTime: time.Now(),
+		ModifyTime: time.Now(),
+		AccessTime: time.Now(),
+		Version:    1,
+	}
+
+	store.root = root
+	store.tree = newTree(root)
+
+	return store
+}
+
+// SetLogger 设置日志，应在使用存储之前调用
+func (s *MemoryStore) SetLogger(l logger.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = logger.OrDefault(l)
+}
+
+// normalizePath 标准化路径
+func normalizePath(p string) string {
+	return api.CleanPath(p)
+}
+
+// nextInode 分配新的 inode，设置了持久化分配器时由其分配，调用方需持有写锁
+func (s *MemoryStore) nextInode(ctx context.Context) (uint64, error) {
+	if s.alloc == nil {
+		s.inodes++
+		return s.inodes, nil
+	}
+	inode, err := s.alloc.Next(ctx, s.inodes)
+	if err != nil {
+		return 0, err
+	}
+	s.inodes = inode
+	return inode, nil
+}
+
+// Create 创建新文件
+func (s *MemoryStore) Create(ctx context.Context, p string, mode os.FileMode) (*Metadata, error) {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	meta, err := s.create(ctx, s.locateEntry(normalizePath(p)), mode, nil)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Clone(), nil
+}
+
+// create 创建新文件并返回存储中的元数据，data 非空时内联保存，调用方需持有写锁
+func (s *MemoryStore) create(ctx context.Context, filePath string, mode os.FileMode, data []byte) (*Metadata, error) {
+	// 检查父目录是否存在
+	parent := path.Dir(filePath)
+	parentMeta, exists := s.tree.get(parent)
+	if !exists {
+		return nil, fmt.Errorf("parent directory not found: %s", parent)
+	}
+
+	// 确保父路径是目录
+	if parentMeta.Type != TypeDirectory {
+		return nil, fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+
+	// 检查文件是否已存在
+	if _, exists := s.tree.get(filePath); exists {
+		return nil, fmt.Errorf("file %w: %s", ErrExist, filePath)
+	}
+
+	// 创建新文件元数据
+	inode, err := s.nextInode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	meta := &Metadata{
+		Inode:      inode,
+		Name:       s.interner.Intern(path.Base(filePath)),
+		Type:       TypeRegular,
+		Size:       0,
+		Mode:       mode,
+		Links:      1,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+	}
+	if len(data) > 0 {
+		meta.InlineData = append([]byte(nil), data...)
+		meta.Size = int64(len(data))
+	}
+	s.applyParentDefaults(filePath, meta)
+	if err := s.chargeQuota(filePath, nil, meta); err != nil {
+		return nil, err
+	}
+
+	s.stamp(meta)
+	s.setEntry(filePath, meta)
+	s.recordChange(ctx, ChangeCreate, filePath, meta)
+	s.log.Info("Created new file",
+		zap.String("path", filePath),
+		zap.Uint64("inode", meta.Inode),
+	)
+
+	return meta, nil
+}
+
+// Get 获取文件元数据副本，修改后需调用 Update 写回
+func (s *MemoryStore) Get(ctx context.Context, p string) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := s.locate(normalizePath(p))
+	meta, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+
+	meta.AccessTime = time.Now()
+	return meta.Clone(), nil
+}
+
+// Update 更新文件元数据
+//
+// meta.Version 必须等于存储中的当前版本，否则返回 *VersionConflictError；
+// Version 为 0 表示不做版本检查，直接覆盖。成功后 meta.Version 递增为新版本。
+func (s *MemoryStore) Update(ctx context.Context, p string, meta *Metadata) error {
+	return s.update(ctx, p, meta, meta.Version, meta.Version != 0)
+}
+
+// UpdateIf 仅当存储中的当前版本等于 expectedVersion 时更新文件元数据
+func (s *MemoryStore) UpdateIf(ctx context.Context, p string, meta *Metadata, expectedVersion uint64) error {
+	return s.update(ctx, p, meta, expectedVersion, true)
+}
+
+// update 执行更新，check 为 true 时进行版本比较
+func (s *MemoryStore) update(ctx context.Context, p string, meta *Metadata, expectedVersion uint64, check bool) error {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	if check && current.Version != expectedVersion {
+		return &VersionConflictError{Path: filePath, Expected: expectedVersion, Actual: current.Version}
+	}
+	return s.replace(ctx, filePath, current, meta)
+}
+
+// replace 用 meta 替换文件的当前元数据，版本号在当前版本上递增，调用方需持有写锁
+func (s *MemoryStore) replace(ctx context.Context, filePath string, current, meta *Metadata) error {
+	if err := checkLayoutUpdate(filePath, current, meta); err != nil {
+		return err
+	}
+	if err := checkPlacement(filePath, meta); err != nil {
+		return err
+	}
+	if err := s.checkQuota(filePath, current, meta); err != nil {
+		return err
+	}
+	internMetadata(s.interner, meta)
+	if err := s.storeBlockMap(ctx, meta); err != nil {
+		return err
+	}
+	s.accountQuota(filePath, current, meta)
+
+	meta.ModifyTime = time.Now()
+	meta.Version = current.Version + 1
+	s.put(filePath, meta.Clone())
+	if filePath == "/" {
+		s.root = s.tree.entry(filePath)
+	}
+	s.recordChange(ctx, ChangeModify, filePath, meta)
+
+	return nil
+}
+
+// Delete 删除文件或空目录，文件有多个硬链接时只删除该路径并减少链接数，非空目录返回 ErrNotEmpty
+func (s *MemoryStore) Delete(ctx context.Context, p string) error {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := s.locateEntry(normalizePath(p))
+	meta, exists := s.tree.get(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	if meta.Type == TypeBind {
+		return fmt.Errorf("%w: %s is a bind point", ErrBusy, filePath)
+	}
+	if meta.Type == TypeDirectory && s.hasChildren(filePath) {
+		return fmt.Errorf("%w: %s", ErrNotEmpty, filePath)
+	}
+
+	if _, err := s.unlink(ctx, filePath, meta); err != nil {
+		return err
+	}
+	s.recordChange(ctx, ChangeDelete, filePath, meta)
+	return nil
+}
+
+// Rename 将文件或目录原子地移动到 newPath，目录的全部后代随之移动
+//
+// newPath 的父目录必须存在，newPath 本身不能已存在，目录不能移动到自身之下。
+func (s *MemoryStore) Rename(ctx context.Context, oldPath, newPath string) error {
+	unlock, err := s.lockFenced(ctx, oldPath, newPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.rename(ctx, s.locateEntry(normalizePath(oldPath)), s.locateEntry(normalizePath(newPath)))
+}
+
+// rename 执行重命名，调用方需持有写锁
+func (s *MemoryStore) rename(ctx context.Context, from, to string) error {
+	meta, exists := s.tree.get(from)
+	if !exists {
+		return fmt.Errorf("file not found: %s", from)
+	}
+	if from == to {
+		return nil
+	}
+	if from == "/" {
+		return fmt.Errorf("cannot rename root directory")
+	}
+	if meta.Type == TypeBind {
+		return fmt.Errorf("%w: %s is a bind point", ErrBusy, from)
+	}
+	if isWithin(to, from) {
+		return fmt.Errorf("cannot move %s into itself: %s", from, to)
+	}
+	if err := s.checkSameZone(from, to); err != nil {
+		return err
+	}
+	if err := s.checkNoZoneBelow(from); err != nil {
+		return err
+	}
+	parent := path.Dir(to)
+	parentMeta, exists := s.tree.get(parent)
+	if !exists {
+		return fmt.Errorf("parent directory not found: %s", parent)
+	}
+	if parentMeta.Type != TypeDirectory {
+		return fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+	if _, exists := s.tree.get(to); exists {
+		return fmt.Errorf("file %w: %s", ErrExist, to)
+	}
+	if err := s.checkDirQuotaMove(from, to, s.entryUsage(from, meta), QuotaUsage{}); err != nil {
+		return err
+	}
+
+	s.move(from, to)
+	renamed := s.renamed(to, meta)
+	s.recordRename(ctx, from, to, renamed)
+	return nil
+}
+
+// move 将 from 及其全部后代换到 to 下，不修改条目本身，调用方需持有写锁
+//
+// 只移动子树的根节点，后代、硬链接表和按 inode 保存的块映射段都不需要改写。
+func (s *MemoryStore) move(from, to string) {
+	s.tree.move(from, to)
+	s.retargetBinds(from, to)
+	s.invalidateDirUsage()
+}
+
+// renamed 更新移动到 to 的条目的名称和版本，返回新的元数据，调用方需持有写锁
+func (s *MemoryStore) renamed(to string, meta *Metadata) *Metadata {
+	renamed := meta.Clone()
+	renamed.Name = s.interner.Intern(path.Base(to))
+	renamed.ModifyTime = time.Now()
+	renamed.Version++
+	s.put(to, renamed)
+	return renamed
+}
+
+// List 列出目录内容
+//
+// 一次返回全部子项，超大目录应使用 ListPage 分页读取。
+func (s *MemoryStore) List(ctx context.Context, p string) ([]*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dirPath := s.locate(normalizePath(p))
+
+	// 检查目录是否存在
+	dirMeta, exists := s.tree.get(dirPath)
+	if !exists {
+		return nil, fmt.Errorf("directory not found: %s", dirPath)
+	}
+
+	// 确保是目录
+	if dirMeta.Type != TypeDirectory {
+		return nil, fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+
+	var results []*Metadata
+	for _, child := range s.tree.lookup(dirPath).children {
+		if child.meta != nil {
+			results = append(results, child.meta.Clone())
+		}
+	}
+
+	return results, nil
+}
+
+// Mkdir 创建目录
+func (s *MemoryStore) Mkdir(ctx context.Context, p string, mode os.FileMode) error {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, err = s.mkdir(ctx, s.locateEntry(normalizePath(p)), mode)
+	return err
+}
+
+// mkdir 创建目录并返回存储中的元数据，调用方需持有写锁
+func (s *MemoryStore) mkdir(ctx context.Context, dirPath string, mode os.FileMode) (*Metadata, error) {
+	// 检查父目录
+	parent := path.Dir(dirPath)
+	parentMeta, exists := s.tree.get(parent)
+	if !exists {
+		return nil, fmt.Errorf("parent directory not found: %s", parent)
+	}
+
+	// 确保父路径是目录
+	if parentMeta.Type != TypeDirectory {
+		return nil, fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+
+	// 检查目录是否已存在
+	if _, exists := s.tree.get(dirPath); exists {
+		return nil, fmt.Errorf("directory %w: %s", ErrExist, dirPath)
+	}
+
+	// 创建目录元数据
+	inode, err := s.nextInode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	meta := &Metadata{
+		Inode:      inode,
+		Name:       s.interner.Intern(path.Base(dirPath)),
+		Type:       TypeDirectory,
+		Mode:       mode | os.ModeDir,
+		Links:      1,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+	}
+	s.applyParentDefaults(dirPath, meta)
+	if err := s.chargeQuota(dirPath, nil, meta); err != nil {
+		return nil, err
+	}
+
+	s.stamp(meta)
+	s.setEntry(dirPath, meta)
+	s.recordChange(ctx, ChangeCreate, dirPath, meta)
+	return meta, nil
+}
+
+// EffectivePlacement 返回路径生效的放置约束表达式
+//
+// 从路径本身开始向上查找，返回最近一个设置了放置约束的目录的表达式。
+func (s *MemoryStore) EffectivePlacement(ctx context.Context, p string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current := normalizePath(p)
+	if _, exists := s.tree.get(current); !exists {
+		return "", fmt.Errorf("file not found: %s", current)
+	}
+
+	for {
+		if meta, exists := s.tree.get(current); exists && meta.Type == TypeDirectory && meta.Placement != "" {
+			return meta.Placement, nil
+		}
+		if current == "/" {
+			return "", nil
+		}
+		current = path.Dir(current)
+	}
+}
+
+// checkPlacement 检查放置约束只设置在目录上且表达式可以解析
+func checkPlacement(filePath string, meta *Metadata) error {
+	if meta.Placement == "" {
+		return nil
+	}
+	if meta.Type != TypeDirectory {
+		return fmt.Errorf("placement constraint can only be set on directories: %s", filePath)
+	}
+	if _, err := cluster.ParseConstraint(meta.Placement); err != nil {
+		return fmt.Errorf("invalid placement constraint on %s: %v", filePath, err)
+	}
+	return nil
+}
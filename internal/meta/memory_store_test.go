@@ -0,0 +1,437 @@
+// internal/meta/memory_store_test.go
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"cpfs/internal/cluster"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// createParentDirs 递归创建父目录
+func createParentDirs(store *MemoryStore, ctx context.Context, pathStr string) error {
+	pathStr = normalizePath(pathStr)
+	if pathStr == "/" {
+		return nil
+	}
+
+	parent := path.Dir(pathStr)
+	if parent != "/" {
+		if err := createParentDirs(store, ctx, parent); err != nil {
+			return err
+		}
+	}
+
+	// 检查目录是否已存在
+	if _, err := store.Get(ctx, pathStr); err == nil {
+		return nil // 目录已存在
+	}
+
+	// 创建目录
+	return store.Mkdir(ctx, pathStr, 0755)
+}
+
+func TestMemoryStoreCrossPlatform(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{
+			name:     "Windows style path",
+			path:     "dir1\\subdir1\\file1.txt",
+			expected: "/dir1/subdir1/file1.txt",
+		},
+		{
+			name:     "Linux style path",
+			path:     "dir2/subdir2/file2.txt",
+			expected: "/dir2/subdir2/file2.txt",
+		},
+		{
+			name:     "Mixed style path",
+			path:     "dir3\\subdir3/file3.txt",
+			expected: "/dir3/subdir3/file3.txt",
+		},
+		{
+			name:     "Path with double slashes",
+			path:     "dir4//subdir4//file4.txt",
+			expected: "/dir4/subdir4/file4.txt",
+		},
+		{
+			name:     "Path with dot",
+			path:     "./dir5/./subdir5/file5.txt",
+			expected: "/dir5/subdir5/file5.txt",
+		},
+		{
+			name:     "Path with double dot",
+			path:     "dir6/subdir6/../file6.txt",
+			expected: "/dir6/file6.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 创建父目录
+			parentPath := path.Dir(tt.expected)
+			err := createParentDirs(store, ctx, parentPath)
+			assert.NoError(t, err, "Failed to create parent directories for %s", tt.path)
+
+			// 创建文件
+			meta, err := store.Create(ctx, tt.path, 0644)
+			if err != nil {
+				t.Fatalf("Failed to create file %s: %v", tt.path, err)
+			}
+			assert.NotNil(t, meta)
+
+			// 验证文件
+			getMeta, err := store.Get(ctx, tt.expected)
+			if err != nil {
+				t.Fatalf("Failed to get file %s: %v", tt.expected, err)
+			}
+			assert.Equal(t, meta.Inode, getMeta.Inode)
+
+			// 验证目录内容
+			dirMeta, err := store.List(ctx, path.Dir(tt.expected))
+			if err != nil {
+				t.Fatalf("Failed to list directory %s: %v", path.Dir(tt.expected), err)
+			}
+
+			// 验证文件在目录中
+			found := false
+			expectedName := path.Base(tt.expected)
+			for _, m := range dirMeta {
+				if m.Name == expectedName {
+					found = true
+					break
+				}
+			}
+			assert.True(t, found, "File %s not found in directory listing", expectedName)
+		})
+	}
+}
+
+func TestMemoryStoreOSSpecific(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Simple path", "test1/path1"},
+		{"Nested path", "test2/path2/subpath2"},
+		{"Deep path", "test3/path3/subpath3/deep3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 分解路径并逐级创建目录
+			parts := strings.Split(strings.Trim(tt.path, "/"), "/")
+			currentPath := "/"
+
+			for _, part := range parts {
+				currentPath = path.Join(currentPath, part)
+				// 尝试创建目录
+				err := store.Mkdir(ctx, currentPath, 0755)
+				// 如果目录已存在，继续处理
+				if err != nil && !strings.Contains(err.Error(), "already exists") {
+					t.Fatalf("Failed to create directory %s: %v", currentPath, err)
+				}
+			}
+
+			// 验证使用不同的路径分隔符访问
+			forwardPath := "/" + strings.Join(parts, "/")
+			backPath := "/" + strings.Join(parts, "\\")
+
+			// 获取并验证元数据
+			meta1, err := store.Get(ctx, forwardPath)
+			if err != nil {
+				t.Fatalf("Failed to get with forward slashes (%s): %v", forwardPath, err)
+			}
+			assert.NotNil(t, meta1)
+
+			meta2, err := store.Get(ctx, backPath)
+			if err != nil {
+				t.Fatalf("Failed to get with backslashes (%s): %v", backPath, err)
+			}
+			assert.NotNil(t, meta2)
+
+			// 验证是否是同一个目录
+			assert.Equal(t, meta1.Inode, meta2.Inode,
+				"Different inodes for same path (%s vs %s)", forwardPath, backPath)
+
+			// 验证目录类型
+			assert.Equal(t, TypeDirectory, meta1.Type,
+				"Path %s is not a directory", forwardPath)
+
+			// 验证权限
+			assert.Equal(t, os.FileMode(0755)|os.ModeDir, meta1.Mode,
+				"Incorrect directory permissions for %s", forwardPath)
+
+			// 列出父目录内容并验证
+			parent := path.Dir(forwardPath)
+			dirContents, err := store.List(ctx, parent)
+			if err != nil {
+				t.Fatalf("Failed to list parent directory %s: %v", parent, err)
+			}
+
+			// 验证目录在父目录列表中
+			found := false
+			expectedName := path.Base(forwardPath)
+			for _, entry := range dirContents {
+				if entry.Name == expectedName {
+					found = true
+					break
+				}
+			}
+			assert.True(t, found, "Directory %s not found in parent directory listing", expectedName)
+		})
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "/"},
+		{".", "/"},
+		{"/", "/"},
+		{"a", "/a"},
+		{"a/", "/a"},
+		{"/a", "/a"},
+		{"/a/", "/a"},
+		{"a/b", "/a/b"},
+		{"a\\b", "/a/b"},
+		{"\\a\\b", "/a/b"},
+		{"a\\b/c", "/a/b/c"},
+		{"../a", "/a"},
+		{"a/../b", "/b"},
+		{"a/./b", "/a/b"},
+		{"./a/b", "/a/b"},
+		{"a//b", "/a/b"},
+		{"a\\/b", "/a/b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Clean(%q)", tt.input), func(t *testing.T) {
+			result := normalizePath(tt.input)
+			assert.Equal(t, tt.expected, result, "Path cleaning failed")
+		})
+	}
+}
+
+func TestMemoryStoreEffectivePlacement(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	assert.NoError(t, store.Mkdir(ctx, "/fast", 0755))
+	assert.NoError(t, store.Mkdir(ctx, "/fast/sub", 0755))
+	_, err := store.Create(ctx, "/fast/sub/file", 0644)
+	assert.NoError(t, err)
+
+	placement, err := store.EffectivePlacement(ctx, "/fast/sub/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "", placement)
+
+	dir, err := store.Get(ctx, "/fast")
+	assert.NoError(t, err)
+	dir.Placement = "ssd=true && zone!=z3"
+	assert.NoError(t, store.Update(ctx, "/fast", dir))
+
+	placement, err = store.EffectivePlacement(ctx, "/fast/sub/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssd=true && zone!=z3", placement)
+
+	_, err = store.EffectivePlacement(ctx, "/missing")
+	assert.Error(t, err)
+
+	// 无法解析的表达式和文件上的放置约束在写入时拒绝
+	dir, err = store.Get(ctx, "/fast")
+	assert.NoError(t, err)
+	dir.Placement = "ssd=true &&"
+	assert.Error(t, store.Update(ctx, "/fast", dir))
+	file, err := store.Get(ctx, "/fast/sub/file")
+	assert.NoError(t, err)
+	file.Placement = "ssd=true"
+	assert.Error(t, store.Update(ctx, "/fast/sub/file", file))
+
+	placement, err = store.EffectivePlacement(ctx, "/fast/sub/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssd=true && zone!=z3", placement)
+
+	// 放置器按文件生效的约束选择节点
+	members := []cluster.Member{
+		{ID: "d1", State: cluster.StateAlive, Labels: cluster.Labels{"ssd": "true", "zone": "z3"}},
+		{ID: "d2", State: cluster.StateAlive, Labels: cluster.Labels{"ssd": "true", "zone": "z1"}},
+		{ID: "d3", State: cluster.StateAlive, Labels: cluster.Labels{"zone": "z1"}},
+	}
+	selected, err := cluster.NewPlacer(store, nil).Select(ctx, members, "/fast/sub/file", 1)
+	assert.NoError(t, err)
+	if assert.Len(t, selected, 1) {
+		assert.Equal(t, "d2", selected[0].ID)
+	}
+	_, err = cluster.NewPlacer(store, nil).Select(ctx, members, "/fast/sub/file", 2)
+	assert.Error(t, err)
+}
+
+// versionedStore 支持版本检查更新的存储
+type versionedStore interface {
+	Create(ctx context.Context, path string, mode os.FileMode) (*Metadata, error)
+	Get(ctx context.Context, path string) (*Metadata, error)
+	Update(ctx context.Context, path string, meta *Metadata) error
+	UpdateIf(ctx context.Context, path string, meta *Metadata, expectedVersion uint64) error
+}
+
+func testVersionedUpdate(t *testing.T, store versionedStore) {
+	ctx := context.Background()
+	_, err := store.Create(ctx, "/counter", 0644)
+	assert.NoError(t, err)
+
+	a, err := store.Get(ctx, "/counter")
+	assert.NoError(t, err)
+	b, err := store.Get(ctx, "/counter")
+	assert.NoError(t, err)
+
+	a.Size = 1
+	assert.NoError(t, store.Update(ctx, "/counter", a))
+	assert.Equal(t, uint64(2), a.Version)
+
+	// 基于旧版本的更新被拒绝
+	b.Size = 2
+	err = store.Update(ctx, "/counter", b)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	var conflict *VersionConflictError
+	if assert.ErrorAs(t, err, &conflict) {
+		assert.Equal(t, uint64(1), conflict.Expected)
+		assert.Equal(t, uint64(2), conflict.Actual)
+	}
+
+	assert.ErrorIs(t, store.UpdateIf(ctx, "/counter", b, 1), ErrVersionConflict)
+	assert.NoError(t, store.UpdateIf(ctx, "/counter", b, 2))
+
+	got, err := store.Get(ctx, "/counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), got.Size)
+	assert.Equal(t, uint64(3), got.Version)
+
+	// 版本为 0 时无条件覆盖
+	got.Version = 0
+	assert.NoError(t, store.Update(ctx, "/counter", got))
+	assert.Equal(t, uint64(4), got.Version)
+
+	// 并发读改写在冲突时重试，不丢失更新
+	const workers, increments = 8, 50
+	done := make(chan struct{})
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < increments; i++ {
+				for {
+					meta, err := store.Get(ctx, "/counter")
+					if err != nil {
+						return
+					}
+					meta.Size++
+					if err := store.Update(ctx, "/counter", meta); err == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	for w := 0; w < workers; w++ {
+		<-done
+	}
+
+	got, err = store.Get(ctx, "/counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2+workers*increments), got.Size)
+}
+
+func TestMemoryStoreVersionedUpdate(t *testing.T) {
+	testVersionedUpdate(t, NewMemoryStore())
+}
+
+func TestCompactStoreVersionedUpdate(t *testing.T) {
+	testVersionedUpdate(t, NewCompactStore())
+}
+
+func TestMemoryStoreSetLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	store := NewMemoryStore()
+	store.SetLogger(zap.New(core))
+
+	_, err := store.Create(context.Background(), "/injected", 0644)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, logs.FilterMessage("Created new file").Len())
+}
+
+func TestMemoryStoreRename(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	assert.NoError(t, store.Mkdir(ctx, "/src", 0755))
+	assert.NoError(t, store.Mkdir(ctx, "/src/sub", 0755))
+	assert.NoError(t, store.Mkdir(ctx, "/dst", 0755))
+	file, err := store.Create(ctx, "/src/sub/data.bin", 0644)
+	assert.NoError(t, err)
+	blocks := make([]Block, InlineBlockLimit+1)
+	for i := range blocks {
+		blocks[i] = Block{ID: fmt.Sprintf("blk-%d", i), Offset: int64(i) << 20, Size: 1 << 20}
+	}
+	file.Blocks = blocks
+	file.Size = int64(len(blocks)) << 20
+	assert.NoError(t, store.Update(ctx, "/src/sub/data.bin", file))
+
+	// 文件重命名更新名称和版本，inode 不变
+	_, err = store.Create(ctx, "/src/a.txt", 0644)
+	assert.NoError(t, err)
+	before, err := store.Get(ctx, "/src/a.txt")
+	assert.NoError(t, err)
+	assert.NoError(t, store.Rename(ctx, "/src/a.txt", "/src/b.txt"))
+	_, err = store.Get(ctx, "/src/a.txt")
+	assert.Error(t, err)
+	after, err := store.Get(ctx, "/src/b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "b.txt", after.Name)
+	assert.Equal(t, before.Inode, after.Inode)
+	assert.Equal(t, before.Version+1, after.Version)
+
+	// 目录重命名移动全部后代，包括拆分保存的块映射
+	assert.NoError(t, store.Rename(ctx, "/src", "/dst/moved"))
+	entries, err := store.List(ctx, "/dst/moved")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	_, err = store.Get(ctx, "/src/sub/data.bin")
+	assert.Error(t, err)
+	got, err := store.GetBlockRange(ctx, "/dst/moved/sub/data.bin", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, got, len(blocks))
+
+	events := store.Changelog().History("/dst/moved", 1)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, ChangeRename, events[0].Type)
+		assert.Equal(t, "/src", events[0].OldPath)
+		assert.True(t, events[0].IsDir)
+	}
+
+	assert.ErrorIs(t, store.Rename(ctx, "/dst/moved/b.txt", "/dst/moved/sub"), ErrExist)
+	assert.Error(t, store.Rename(ctx, "/dst", "/dst/moved/inner"))
+	assert.Error(t, store.Rename(ctx, "/missing", "/other"))
+	assert.Error(t, store.Rename(ctx, "/dst/moved/b.txt", "/nowhere/b.txt"))
+	assert.Error(t, store.Rename(ctx, "/dst/moved/b.txt", "/dst/moved/b.txt/c"))
+	assert.Error(t, store.Rename(ctx, "/", "/root"))
+	assert.NoError(t, store.Rename(ctx, "/dst/moved/b.txt", "/dst/moved/b.txt"))
+}
@@ -0,0 +1,301 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"iter"
+	"sort"
+	"strings"
+	"time"
+
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// MerkleBuckets Merkle 树叶子桶数量
+const MerkleBuckets = 256
+
+// MerkleTree 元数据命名空间的 Merkle 摘要
+//
+// 路径按哈希分配到固定数量的桶中，每个桶的摘要由桶内条目按路径排序后计算，
+// 根摘要由所有桶摘要计算，比较两棵树即可定位发生分歧的桶。
+type MerkleTree struct {
+	Root    [sha256.Size]byte
+	Buckets [MerkleBuckets][sha256.Size]byte
+}
+
+// Replica 定义参与反熵修复的元数据副本
+type Replica interface {
+	// MerkleTree 计算副本的 Merkle 摘要
+	MerkleTree(ctx context.Context) (*MerkleTree, error)
+	// BucketEntries 返回指定桶内的全部条目
+	BucketEntries(ctx context.Context, bucket int) (map[string]*Metadata, error)
+	// ApplyEntries 写入或删除条目，用于从主副本修复
+	ApplyEntries(ctx context.Context, upserts map[string]*Metadata, deletes []string) error
+}
+
+// RepairReport 反熵修复报告
+type RepairReport struct {
+	Buckets []int    // 发生分歧的桶
+	Updated []string // 被修复（新增或覆盖）的路径
+	Deleted []string // 被删除的路径
+}
+
+// Diverged 判断是否检测到分歧
+func (r *RepairReport) Diverged() bool {
+	return len(r.Buckets) > 0
+}
+
+// bucketOf 返回路径所属的桶
+func bucketOf(p string) int {
+	h := fnv.New32a()
+	h.Write([]byte(p))
+	return int(h.Sum32() % MerkleBuckets)
+}
+
+// entryDigest 将条目的持久化字段写入摘要，不包含访问时间等易变字段
+func entryDigest(buf *bytes.Buffer, p string, m *Metadata) {
+	fmt.Fprintf(buf, "%s|%d|%d|%d|%d|%d|%s|%s|%d|%d|%s\n",
+		p, m.Inode, m.Type, m.Size, m.Mode, m.Links, m.Owner, m.Group,
+		m.ModifyTime.UnixNano(), m.Version, m.Placement)
+	for _, block := range m.Blocks {
+		fmt.Fprintf(buf, "  %s|%d|%d|%s\n", block.ID, block.Size, block.Offset, block.Checksum)
+	}
+	for _, extent := range m.Extents {
+		fmt.Fprintf(buf, "  extent|%d|%d|%d|%d\n", extent.Offset, extent.Length, extent.BlockSize, extent.StartID)
+	}
+	if len(m.InlineData) > 0 {
+		fmt.Fprintf(buf, "  inline|%x\n", m.InlineData)
+	}
+	if m.StorageClass != "" || m.Compression || m.ProjectID != 0 {
+		fmt.Fprintf(buf, "  class|%s|%t|%d\n", m.StorageClass, m.Compression, m.ProjectID)
+	}
+	if d := m.Defaults; d != nil {
+		fmt.Fprintf(buf, "  defaults|%d|%s|%s|%t|%s|%d|%d\n", d.Mode, d.Owner, d.Group, d.SetGID, d.StorageClass, d.Compression, d.ProjectID)
+	}
+	if m.Pin != nil {
+		fmt.Fprintf(buf, "  pin|%s|%s\n", strings.Join(m.Pin.Nodes, ","), m.Pin.Media)
+	}
+	if l := m.Layout; l != nil {
+		fmt.Fprintf(buf, "  layout|%d|%d|%d\n", l.StripeCount, l.StripeSize, l.StartIndex)
+	}
+	if m.Writer != "" {
+		fmt.Fprintf(buf, "  writer|%s\n", m.Writer)
+	}
+	if m.Target != "" {
+		fmt.Fprintf(buf, "  target|%s\n", m.Target)
+	}
+	if m.QuotaBytes != 0 || m.QuotaFiles != 0 {
+		fmt.Fprintf(buf, "  quota|%d|%d\n", m.QuotaBytes, m.QuotaFiles)
+	}
+}
+
+// buildMerkleTree 根据条目计算 Merkle 树
+func buildMerkleTree(entries iter.Seq2[string, *Metadata]) *MerkleTree {
+	var paths [MerkleBuckets][]string
+	metas := make(map[string]*Metadata)
+	for p, m := range entries {
+		b := bucketOf(p)
+		paths[b] = append(paths[b], p)
+		metas[p] = m
+	}
+
+	tree := &MerkleTree{}
+	root := sha256.New()
+	var buf bytes.Buffer
+	for b := 0; b < MerkleBuckets; b++ {
+		sort.Strings(paths[b])
+		buf.Reset()
+		for _, p := range paths[b] {
+			entryDigest(&buf, p, metas[p])
+		}
+		tree.Buckets[b] = sha256.Sum256(buf.Bytes())
+		root.Write(tree.Buckets[b][:])
+	}
+	copy(tree.Root[:], root.Sum(nil))
+
+	return tree
+}
+
+// DiffBuckets 返回两棵树中摘要不同的桶
+func (t *MerkleTree) DiffBuckets(other *MerkleTree) []int {
+	if t.Root == other.Root {
+		return nil
+	}
+
+	var diff []int
+	for b := 0; b < MerkleBuckets; b++ {
+		if t.Buckets[b] != other.Buckets[b] {
+			diff = append(diff, b)
+		}
+	}
+	return diff
+}
+
+// Repair 比较主副本与从副本，并以主副本为准修复从副本
+func Repair(ctx context.Context, leader, follower Replica) (*RepairReport, error) {
+	leaderTree, err := leader.MerkleTree(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build leader merkle tree: %v", err)
+	}
+	followerTree, err := follower.MerkleTree(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build follower merkle tree: %v", err)
+	}
+
+	report := &RepairReport{Buckets: leaderTree.DiffBuckets(followerTree)}
+	for _, b := range report.Buckets {
+		leaderEntries, err := leader.BucketEntries(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		followerEntries, err := follower.BucketEntries(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+
+		upserts := make(map[string]*Metadata)
+		var deletes []string
+		var lb, fb bytes.Buffer
+		for p, m := range leaderEntries {
+			fm, exists := followerEntries[p]
+			if exists {
+				lb.Reset()
+				fb.Reset()
+				entryDigest(&lb, p, m)
+				entryDigest(&fb, p, fm)
+				if bytes.Equal(lb.Bytes(), fb.Bytes()) {
+					continue
+				}
+			}
+			upserts[p] = m
+			report.Updated = append(report.Updated, p)
+		}
+		for p := range followerEntries {
+			if _, exists := leaderEntries[p]; !exists {
+				deletes = append(deletes, p)
+				report.Deleted = append(report.Deleted, p)
+			}
+		}
+
+		if err := follower.ApplyEntries(ctx, upserts, deletes); err != nil {
+			return nil, fmt.Errorf("failed to repair bucket %d: %v", b, err)
+		}
+	}
+
+	sort.Strings(report.Updated)
+	sort.Strings(report.Deleted)
+	return report, nil
+}
+
+// RunAntiEntropy 周期性执行反熵修复直到 ctx 被取消，检测到分歧时调用 onDivergence
+func RunAntiEntropy(ctx context.Context, interval time.Duration, leader, follower Replica, onDivergence func(*RepairReport)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := Repair(ctx, leader, follower)
+			if err != nil {
+				logger.Error("Anti-entropy repair failed",
+					zap.Error(err),
+				)
+				continue
+			}
+			if report.Diverged() {
+				logger.Warn("Metadata replica divergence repaired",
+					zap.Int("buckets", len(report.Buckets)),
+					zap.Int("updated", len(report.Updated)),
+					zap.Int("deleted", len(report.Deleted)),
+				)
+				if onDivergence != nil {
+					onDivergence(report)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// MerkleTree 实现 Replica
+func (s *MemoryStore) MerkleTree(ctx context.Context) (*MerkleTree, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return buildMerkleTree(s.tree.all()), nil
+}
+
+// BucketEntries 实现 Replica
+func (s *MemoryStore) BucketEntries(ctx context.Context, bucket int) (map[string]*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make(map[string]*Metadata)
+	for p, m := range s.tree.all() {
+		if bucketOf(p) == bucket {
+			entries[p] = m.Clone()
+		}
+	}
+	return entries, nil
+}
+
+// ApplyEntries 实现 Replica
+func (s *MemoryStore) ApplyEntries(ctx context.Context, upserts map[string]*Metadata, deletes []string) error {
+	paths := make([]string, 0, len(upserts)+len(deletes))
+	for p := range upserts {
+		paths = append(paths, p)
+	}
+	paths = append(paths, deletes...)
+	unlock, err := s.lockFenced(ctx, paths...)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.observe(upserts); err != nil {
+		return err
+	}
+	for p, m := range upserts {
+		typ := ChangeModify
+		current, exists := s.tree.get(p)
+		if !exists {
+			typ = ChangeCreate
+		}
+		// 副本同步以源副本为准，只统计用量不拒绝
+		s.quotas.charge(current, m, false)
+		s.owners.charge(current, m, false)
+		if exists && current.Type == TypeBind {
+			s.bindPoints--
+		}
+		if m.Type == TypeBind {
+			s.bindPoints++
+		}
+		s.setEntry(p, m.Clone())
+		internMetadata(s.interner, s.tree.entry(p))
+		if m.Inode > s.inodes {
+			s.inodes = m.Inode
+		}
+		if p == "/" {
+			s.root = s.tree.entry(p)
+		}
+		s.recordChange(ctx, typ, p, m)
+	}
+	for _, p := range deletes {
+		if m, exists := s.tree.get(p); exists && p != "/" {
+			s.quotas.charge(m, nil, false)
+			s.owners.charge(m, nil, false)
+			s.deleteEntry(p)
+			if m.Type == TypeBind {
+				s.bindPoints--
+			}
+			s.recordChange(ctx, ChangeDelete, p, m)
+		}
+	}
+	s.invalidateDirUsage()
+	return nil
+}
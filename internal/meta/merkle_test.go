@@ -0,0 +1,60 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAntiEntropyRepair(t *testing.T) {
+	ctx := context.Background()
+	leader := NewMemoryStore()
+	follower := NewMemoryStore()
+
+	require.NoError(t, leader.Mkdir(ctx, "/data", 0755))
+	for i := 0; i < 20; i++ {
+		_, err := leader.Create(ctx, fmt.Sprintf("/data/file-%d", i), 0644)
+		require.NoError(t, err)
+	}
+
+	// 首次修复复制全部条目
+	report, err := Repair(ctx, leader, follower)
+	require.NoError(t, err)
+	assert.True(t, report.Diverged())
+	assert.Len(t, report.Updated, 22)
+
+	leaderTree, _ := leader.MerkleTree(ctx)
+	followerTree, _ := follower.MerkleTree(ctx)
+	assert.Equal(t, leaderTree.Root, followerTree.Root)
+
+	// 无分歧时不做修改
+	report, err = Repair(ctx, leader, follower)
+	require.NoError(t, err)
+	assert.False(t, report.Diverged())
+
+	// 从副本出现分歧：内容被修改、多出条目、缺少条目
+	meta, err := follower.Get(ctx, "/data/file-3")
+	require.NoError(t, err)
+	meta.Size = 4096
+	require.NoError(t, follower.Update(ctx, "/data/file-3", meta))
+	_, err = follower.Create(ctx, "/data/orphan", 0644)
+	require.NoError(t, err)
+	require.NoError(t, follower.Delete(ctx, "/data/file-7"))
+
+	report, err = Repair(ctx, leader, follower)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/data/file-3", "/data/file-7"}, report.Updated)
+	assert.Equal(t, []string{"/data/orphan"}, report.Deleted)
+
+	restored, err := follower.Get(ctx, "/data/file-3")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), restored.Size)
+	_, err = follower.Get(ctx, "/data/orphan")
+	assert.Error(t, err)
+
+	followerTree, _ = follower.MerkleTree(ctx)
+	assert.Equal(t, leaderTree.Root, followerTree.Root)
+}
@@ -0,0 +1,160 @@
+package meta
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// api/proto/cpfs/meta/v1/metadata.proto 中 CheckpointEntry 的字段编号，元数据本身的编码见 cpfs/api/meta
+const (
+	fieldEntryPath     protowire.Number = 1
+	fieldEntryMetadata protowire.Number = 2
+)
+
+// maxCheckpointRecord 检查点单条记录的最大长度
+const maxCheckpointRecord = 64 << 20
+
+// consumeFields 逐个解析字段，varint 字段传入 v，长度前缀字段传入 raw，其他类型跳过
+func consumeFields(data []byte, fn func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var v uint64
+		var raw []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			raw, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid protobuf field %d: %v", num, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		if typ == protowire.VarintType || typ == protowire.BytesType {
+			if err := fn(num, typ, v, raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteCheckpoint 将全部元数据以 protobuf 检查点格式写入 w
+//
+// 检查点由按路径排序、以 varint 长度为前缀的 CheckpointEntry 记录组成。
+func (s *MemoryStore) WriteCheckpoint(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paths := make([]string, 0, s.tree.size)
+	for p := range s.tree.all() {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	bw := bufio.NewWriter(w)
+	var record, frame []byte
+	for _, p := range paths {
+		record = protowire.AppendTag(record[:0], fieldEntryPath, protowire.BytesType)
+		record = protowire.AppendString(record, p)
+		record = protowire.AppendTag(record, fieldEntryMetadata, protowire.BytesType)
+		record = protowire.AppendBytes(record, s.tree.entry(p).AppendProto(nil))
+
+		frame = protowire.AppendVarint(frame[:0], uint64(len(record)))
+		if _, err := bw.Write(frame); err != nil {
+			return fmt.Errorf("failed to write checkpoint: %v", err)
+		}
+		if _, err := bw.Write(record); err != nil {
+			return fmt.Errorf("failed to write checkpoint: %v", err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// ReadCheckpoint 从 protobuf 检查点恢复元数据，替换存储中的全部内容
+func (s *MemoryStore) ReadCheckpoint(r io.Reader) error {
+	br := bufio.NewReader(r)
+	data := make(map[string]*Metadata)
+	var inodes uint64
+
+	for {
+		size, err := binary.ReadUvarint(br)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read checkpoint: %v", err)
+		}
+		if size > maxCheckpointRecord {
+			return fmt.Errorf("checkpoint record too large: %d", size)
+		}
+
+		record := make([]byte, size)
+		if _, err := io.ReadFull(br, record); err != nil {
+			return fmt.Errorf("failed to read checkpoint: %v", err)
+		}
+
+		var entryPath string
+		meta := &Metadata{}
+		err = consumeFields(record, func(num protowire.Number, typ protowire.Type, v uint64, raw []byte) error {
+			switch {
+			case typ == protowire.BytesType && num == fieldEntryPath:
+				entryPath = string(raw)
+			case typ == protowire.BytesType && num == fieldEntryMetadata:
+				return meta.UnmarshalBinary(raw)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to decode checkpoint record: %v", err)
+		}
+		if entryPath == "" {
+			return fmt.Errorf("checkpoint record without path")
+		}
+
+		internMetadata(s.interner, meta)
+		data[normalizePath(entryPath)] = meta
+		if meta.Inode > inodes {
+			inodes = meta.Inode
+		}
+	}
+
+	root, ok := data["/"]
+	if !ok {
+		return fmt.Errorf("checkpoint has no root directory")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree = treeFrom(data)
+	s.root = root
+	s.inodes = inodes
+	s.links = buildLinks(s.tree)
+	s.bindPoints = countBindPoints(data)
+	s.invalidateDirUsage()
+	s.quotas.recompute(maps.All(data))
+	s.owners.recompute(maps.All(data))
+	// 日志中的重命名和缓存的解析结果属于被替换的命名空间
+	s.renames.reset()
+	s.dentries.reset()
+	return nil
+}
@@ -0,0 +1,89 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleMetadata() *Metadata {
+	now := time.Unix(1700000000, 123456789)
+	return &Metadata{
+		Inode:      42,
+		Name:       "file.dat",
+		Type:       TypeRegular,
+		Size:       8192,
+		Mode:       0644,
+		Links:      1,
+		Owner:      "alice",
+		Group:      "staff",
+		CreateTime: now,
+		ModifyTime: now.Add(time.Second),
+		AccessTime: now.Add(time.Minute),
+		Version:    3,
+		Placement:  "zone=a",
+
+		StorageClass: "archive",
+		Compression:  true,
+		Blocks: []Block{
+			{ID: "b1", Size: 4096, Offset: 0, Checksum: "c1", Locations: []string{"ds1", "ds2"}},
+			{ID: "b2", Size: 4096, Offset: 4096},
+		},
+	}
+}
+
+func TestMemoryStoreCheckpoint(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	require.NoError(t, store.Mkdir(ctx, "/dir", 0755))
+	meta, err := store.Create(ctx, "/dir/file", 0644)
+	require.NoError(t, err)
+	meta.Blocks = sampleMetadata().Blocks
+	require.NoError(t, store.Update(ctx, "/dir/file", meta))
+
+	var buf bytes.Buffer
+	require.NoError(t, store.WriteCheckpoint(&buf))
+
+	restored := NewMemoryStore()
+	require.NoError(t, restored.ReadCheckpoint(bytes.NewReader(buf.Bytes())))
+
+	got, err := restored.Get(ctx, "/dir/file")
+	require.NoError(t, err)
+	assert.Equal(t, meta.Inode, got.Inode)
+	assert.Equal(t, meta.Blocks, got.Blocks)
+	assert.Equal(t, meta.Version, got.Version)
+
+	// 恢复后继续分配的 inode 不与已有条目冲突
+	created, err := restored.Create(ctx, "/dir/new", 0644)
+	require.NoError(t, err)
+	assert.Greater(t, created.Inode, meta.Inode)
+
+	// 截断的检查点报错
+	assert.Error(t, NewMemoryStore().ReadCheckpoint(bytes.NewReader(buf.Bytes()[:buf.Len()-3])))
+}
+
+func BenchmarkMetadataMarshalProto(b *testing.B) {
+	meta := sampleMetadata()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := meta.MarshalBinary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMetadataMarshalJSON(b *testing.B) {
+	meta := sampleMetadata()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(meta); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// openFile 一个被客户端打开的 inode
+type openFile struct {
+	count int
+	// 最近一次找到该 inode 的目录项，重命名时随子树移动，条目被替换后按 inode 重新查找
+	node *treeNode
+	// 全部链接已被删除，等待最后一次关闭
+	orphaned bool
+	// CreateTemp 创建、尚未发布的临时文件，可以由 LinkTemp 链接到命名空间
+	temp bool
+	// 删除后的元数据，全部数据块展开保存在 Blocks 中
+	meta *Metadata
+}
+
+// Open 为客户端打开文件，增加 inode 的打开计数并返回元数据副本
+//
+// 文件被打开期间删除最后一个链接时，路径立即消失，但数据块保留到最后一次 Close，
+// 与 POSIX 删除后仍可通过已打开的句柄读写的语义一致。每次成功的 Open 必须对应一次 Close，
+// 客户端会话结束时由会话层关闭其全部句柄。
+func (s *MemoryStore) Open(ctx context.Context, p string) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := s.locate(normalizePath(p))
+	meta, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	if meta.Type != TypeRegular {
+		return nil, fmt.Errorf("path is not a regular file: %s", filePath)
+	}
+
+	f, ok := s.opens[meta.Inode]
+	if !ok {
+		f = &openFile{}
+		s.opens[meta.Inode] = f
+	}
+	f.count++
+	f.node = s.tree.lookup(filePath)
+	meta.AccessTime = time.Now()
+	return meta.Clone(), nil
+}
+
+// Close 减少 inode 的打开计数，已删除的文件最后一次关闭时数据块交给 TakeReleasedBlocks
+func (s *MemoryStore) Close(ctx context.Context, inode uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.opens[inode]
+	if !ok {
+		return fmt.Errorf("inode %d is not open", inode)
+	}
+	f.count--
+	if f.count > 0 {
+		return nil
+	}
+	delete(s.opens, inode)
+	if f.orphaned {
+		s.released = append(s.released, f.meta.Blocks...)
+	}
+	return nil
+}
+
+// OpenCount 返回 inode 当前的打开计数
+func (s *MemoryStore) OpenCount(inode uint64) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if f, ok := s.opens[inode]; ok {
+		return f.count
+	}
+	return 0
+}
+
+// Orphans 返回全部链接已被删除但仍被打开的 inode 数量
+func (s *MemoryStore) Orphans() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for _, f := range s.opens {
+		if f.orphaned {
+			n++
+		}
+	}
+	return n
+}
+
+// orphan 在删除文件最后一个链接前检查它是否仍被打开，是则保存数据块等待最后一次关闭，调用方需持有写锁
+func (s *MemoryStore) orphan(ctx context.Context, filePath string, meta *Metadata) (bool, error) {
+	f, ok := s.opens[meta.Inode]
+	if !ok || meta.Type != TypeRegular {
+		return false, nil
+	}
+	blocks, err := s.fileBlocks(ctx, meta)
+	if err != nil {
+		return false, err
+	}
+	orphan := meta.Clone()
+	orphan.Blocks = blocks
+	orphan.Extents = nil
+	orphan.BlockCount = 0
+	orphan.Links = 0
+	f.orphaned = true
+	f.meta = orphan
+	return true, nil
+}
+
+// openPath 返回打开的 inode 当前的路径，调用方需持有锁
+func (s *MemoryStore) openPath(inode uint64, f *openFile) (string, bool) {
+	if s.tree.attached(f.node) && f.node.meta.Inode == inode {
+		return s.tree.pathOf(f.node), true
+	}
+	if nodes := s.links[inode]; len(nodes) > 0 {
+		f.node = nodes[0]
+		return s.tree.pathOf(f.node), true
+	}
+	// 打开的目录项被删除或替换，例如副本同步改写了该路径，按 inode 查找
+	for p, m := range s.tree.all() {
+		if m.Inode == inode {
+			f.node = s.tree.lookup(p)
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// GetOpen 通过打开的 inode 获取元数据副本，文件被重命名或删除后仍然可用
+func (s *MemoryStore) GetOpen(ctx context.Context, inode uint64) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.opens[inode]
+	if !ok {
+		return nil, fmt.Errorf("inode %d is not open", inode)
+	}
+	if f.orphaned {
+		return f.meta.Clone(), nil
+	}
+	p, ok := s.openPath(inode, f)
+	if !ok {
+		return nil, fmt.Errorf("inode %d not found", inode)
+	}
+	meta := s.tree.entry(p)
+	meta.AccessTime = time.Now()
+	return meta.Clone(), nil
+}
+
+// UpdateOpen 通过打开的 inode 更新元数据，版本检查与 Update 相同
+//
+// 已删除的文件只在内存中更新，不再出现在变更日志和配额中；新元数据不再引用的数据块立即交给
+// TakeReleasedBlocks，其余的在最后一次关闭后回收。
+func (s *MemoryStore) UpdateOpen(ctx context.Context, inode uint64, meta *Metadata) error {
+	// 已删除的文件不在命名空间中，不需要登记
+	unlock, err := s.lockFencedWith(ctx, func() []string {
+		f, ok := s.opens[inode]
+		if !ok || f.orphaned {
+			return nil
+		}
+		p, ok := s.openPath(inode, f)
+		if !ok {
+			return nil
+		}
+		return s.fencePaths([]string{p})
+	})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, ok := s.opens[inode]
+	if !ok {
+		return fmt.Errorf("inode %d is not open", inode)
+	}
+	if !f.orphaned {
+		p, ok := s.openPath(inode, f)
+		if !ok {
+			return fmt.Errorf("inode %d not found", inode)
+		}
+		current := s.tree.entry(p)
+		if meta.Version != 0 && meta.Version != current.Version {
+			return &VersionConflictError{Path: p, Expected: meta.Version, Actual: current.Version}
+		}
+		return s.replace(ctx, p, current, meta)
+	}
+
+	current := f.meta
+	if meta.Version != 0 && meta.Version != current.Version {
+		// 已删除的节点仍指向原来的父节点，拼出的是删除前的路径
+		var last string
+		if f.node != nil {
+			last = s.tree.pathOf(f.node)
+		}
+		return &VersionConflictError{Path: last, Expected: meta.Version, Actual: current.Version}
+	}
+	updated := meta.Clone()
+	var blocks []Block
+	for i := range meta.Extents {
+		blocks = append(blocks, meta.Extents[i].Blocks()...)
+	}
+	updated.Blocks = append(blocks, meta.Blocks...)
+	updated.Extents = nil
+	updated.BlockCount = 0
+	updated.Inode = inode
+	updated.Links = 0
+	updated.ModifyTime = time.Now()
+	updated.Version = current.Version + 1
+
+	kept := make(map[string]bool, len(updated.Blocks))
+	for _, b := range updated.Blocks {
+		kept[b.ID] = true
+	}
+	for _, b := range current.Blocks {
+		if !kept[b.ID] {
+			s.released = append(s.released, b)
+		}
+	}
+	f.meta = updated
+	meta.Version = updated.Version
+	return nil
+}
@@ -0,0 +1,137 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenDeleteOnLastClose(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	file, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	file.Blocks = []Block{{ID: "blk-0", Size: 10}}
+	require.NoError(t, store.Update(ctx, "/f", file))
+
+	m, err := store.Open(ctx, "/f")
+	require.NoError(t, err)
+	_, err = store.Open(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.OpenCount(m.Inode))
+
+	// 删除后路径消失，数据块保留到最后一次关闭
+	require.NoError(t, store.Delete(ctx, "/f"))
+	_, err = store.Get(ctx, "/f")
+	assert.Error(t, err)
+	assert.Equal(t, 1, store.Orphans())
+
+	require.NoError(t, store.Close(ctx, m.Inode))
+	assert.Empty(t, store.TakeReleasedBlocks())
+	require.NoError(t, store.Close(ctx, m.Inode))
+	assert.Equal(t, []Block{{ID: "blk-0", Size: 10}}, store.TakeReleasedBlocks())
+	assert.Zero(t, store.Orphans())
+	assert.Zero(t, store.OpenCount(m.Inode))
+
+	assert.Error(t, store.Close(ctx, m.Inode))
+	_, err = store.Open(ctx, "/")
+	assert.Error(t, err)
+}
+
+func TestOpenCloseWithoutDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	file, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	file.Blocks = []Block{{ID: "blk-0", Size: 10}}
+	require.NoError(t, store.Update(ctx, "/f", file))
+
+	m, err := store.Open(ctx, "/f")
+	require.NoError(t, err)
+	require.NoError(t, store.Close(ctx, m.Inode))
+	assert.Empty(t, store.TakeReleasedBlocks())
+	_, err = store.Get(ctx, "/f")
+	assert.NoError(t, err)
+}
+
+func TestDeleteTreeSkipsOpenFiles(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/d", 0755))
+	for _, p := range []string{"/d/open", "/d/closed"} {
+		file, err := store.Create(ctx, p, 0644)
+		require.NoError(t, err)
+		file.Blocks = []Block{{ID: p, Size: 10}}
+		require.NoError(t, store.Update(ctx, p, file))
+	}
+	m, err := store.Open(ctx, "/d/open")
+	require.NoError(t, err)
+
+	collector := &recordingCollector{}
+	_, err = store.DeleteTree(ctx, "/d", DeleteTreeOptions{Collector: collector})
+	require.NoError(t, err)
+	assert.Equal(t, []Block{{ID: "/d/closed", Size: 10}}, collector.blocks)
+
+	require.NoError(t, store.Close(ctx, m.Inode))
+	assert.Equal(t, []Block{{ID: "/d/open", Size: 10}}, store.TakeReleasedBlocks())
+}
+
+func TestOpenHandleAfterUnlink(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	file, err := store.Create(ctx, "/tmp", 0600)
+	require.NoError(t, err)
+	m, err := store.Open(ctx, "/tmp")
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(ctx, "/tmp"))
+
+	// 删除后仍可通过句柄读写
+	m, err = store.GetOpen(ctx, m.Inode)
+	require.NoError(t, err)
+	assert.Equal(t, file.Inode, m.Inode)
+	assert.Zero(t, m.Links)
+	m.Blocks = []Block{{ID: "blk-0", Size: 10}, {ID: "blk-1", Offset: 10, Size: 10}}
+	m.Size = 20
+	require.NoError(t, store.UpdateOpen(ctx, m.Inode, m))
+
+	// 覆盖写替换掉的块立即回收
+	m.Blocks = []Block{{ID: "blk-0", Size: 10}, {ID: "blk-2", Offset: 10, Size: 10}}
+	require.NoError(t, store.UpdateOpen(ctx, m.Inode, m))
+	assert.Equal(t, []Block{{ID: "blk-1", Offset: 10, Size: 10}}, store.TakeReleasedBlocks())
+
+	stale := m.Clone()
+	stale.Version--
+	assert.ErrorIs(t, store.UpdateOpen(ctx, m.Inode, stale), ErrVersionConflict)
+	got, err := store.GetOpen(ctx, m.Inode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), got.Size)
+
+	_, err = store.Get(ctx, "/tmp")
+	assert.Error(t, err)
+	require.NoError(t, store.Close(ctx, m.Inode))
+	assert.Len(t, store.TakeReleasedBlocks(), 2)
+	_, err = store.GetOpen(ctx, m.Inode)
+	assert.Error(t, err)
+}
+
+func TestOpenHandleFollowsRename(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/a", 0755))
+	_, err := store.Create(ctx, "/a/f", 0644)
+	require.NoError(t, err)
+	m, err := store.Open(ctx, "/a/f")
+	require.NoError(t, err)
+
+	require.NoError(t, store.Rename(ctx, "/a", "/b"))
+	m, err = store.GetOpen(ctx, m.Inode)
+	require.NoError(t, err)
+	m.Size = 5
+	require.NoError(t, store.UpdateOpen(ctx, m.Inode, m))
+
+	got, err := store.Get(ctx, "/b/f")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), got.Size)
+}
@@ -0,0 +1,384 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// WhiteoutPrefix 可写层中白名单文件的名称前缀，.wh.name 表示基础层中的 name 已被删除
+	WhiteoutPrefix = ".wh."
+	// OpaqueMarker 可写层目录中存在该文件时，基础层中的同名目录内容被整体遮蔽
+	OpaqueMarker = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// ErrCopyUpRequired 修改基础层中带数据块的文件需要复制数据，但覆盖视图没有配置 BlockCopier
+var ErrCopyUpRequired = errors.New("copy-up requires a block copier")
+
+// BlockCopier 复制数据块，用于把基础层中的文件提升到可写层，返回与 blocks 一一对应的新数据块
+//
+// 基础层只读，可写层中的文件不能引用基础层的数据块，否则截断或删除后回收会破坏基础层。
+type BlockCopier interface {
+	CopyBlocks(ctx context.Context, blocks []Block) ([]Block, error)
+}
+
+// Overlay 将只读的基础层目录与可写层目录合并为一个命名空间视图，语义与 overlayfs 相同
+//
+// 读取时可写层中的条目遮蔽基础层中的同名条目；修改基础层中的条目时先将其复制到可写层；
+// 删除基础层中的条目时在可写层留下 WhiteoutPrefix 开头的白名单文件，被删除后重建的目录
+// 带有 OpaqueMarker，不再显示基础层的内容。基础层本身从不被修改。
+//
+// Overlay 实现 CacheBackend，可以像普通存储一样挂载。视图中的路径相对于覆盖视图的根，
+// 多步操作不是原子的，可写层应只由一个客户端使用。
+type Overlay struct {
+	store  *MemoryStore
+	base   string
+	upper  string
+	copier BlockCopier
+}
+
+// NewOverlay 以 base 为基础层、upper 为可写层创建覆盖视图，copier 为 nil 时不能修改基础层中带数据块的文件
+func NewOverlay(ctx context.Context, store *MemoryStore, base, upper string, copier BlockCopier) (*Overlay, error) {
+	base, upper = normalizePath(base), normalizePath(upper)
+	if isWithin(base, upper) || isWithin(upper, base) {
+		return nil, fmt.Errorf("overlay layers must not contain each other: %s, %s", base, upper)
+	}
+	for _, dir := range []string{base, upper} {
+		m, err := store.Get(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		if m.Type != TypeDirectory {
+			return nil, fmt.Errorf("overlay layer is not a directory: %s", dir)
+		}
+	}
+	return &Overlay{store: store, base: base, upper: upper, copier: copier}, nil
+}
+
+// upperPath 返回视图路径在可写层中的路径
+func (o *Overlay) upperPath(p string) string {
+	return path.Join(o.upper, p)
+}
+
+// basePath 返回视图路径在基础层中的路径
+func (o *Overlay) basePath(p string) string {
+	return path.Join(o.base, p)
+}
+
+// whiteoutPath 返回视图路径对应的白名单文件
+func (o *Overlay) whiteoutPath(p string) string {
+	return path.Join(o.upper, path.Dir(p), WhiteoutPrefix+path.Base(p))
+}
+
+// exists 判断存储中的路径是否存在
+func (o *Overlay) exists(ctx context.Context, p string) bool {
+	_, err := o.store.Get(ctx, p)
+	return err == nil
+}
+
+// baseVisible 判断视图路径在基础层中的条目是否可见，即路径上没有被白名单删除或被不透明目录遮蔽
+func (o *Overlay) baseVisible(ctx context.Context, p string) bool {
+	if p == "/" {
+		return true
+	}
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	current := "/"
+	for _, name := range parts {
+		if o.exists(ctx, path.Join(o.upperPath(current), OpaqueMarker)) {
+			return false
+		}
+		current = path.Join(current, name)
+		if o.exists(ctx, o.whiteoutPath(current)) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup 返回视图路径的元数据以及是否位于可写层
+func (o *Overlay) lookup(ctx context.Context, p string) (*Metadata, bool, error) {
+	if m, err := o.store.Get(ctx, o.upperPath(p)); err == nil {
+		return m, true, nil
+	}
+	if o.baseVisible(ctx, p) {
+		if m, err := o.store.Get(ctx, o.basePath(p)); err == nil {
+			return m, false, nil
+		}
+	}
+	return nil, false, fmt.Errorf("file not found: %s", p)
+}
+
+// checkName 拒绝与白名单文件冲突的名称
+func checkName(p string) error {
+	if strings.HasPrefix(path.Base(p), WhiteoutPrefix) {
+		return fmt.Errorf("name is reserved for overlay whiteouts: %s", path.Base(p))
+	}
+	return nil
+}
+
+// Get 获取视图中条目的元数据
+func (o *Overlay) Get(ctx context.Context, p string) (*Metadata, error) {
+	m, _, err := o.lookup(ctx, normalizePath(p))
+	return m, err
+}
+
+// List 列出视图中目录的内容，按名称排序
+func (o *Overlay) List(ctx context.Context, p string) ([]*Metadata, error) {
+	dirPath := normalizePath(p)
+	dir, _, err := o.lookup(ctx, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if dir.Type != TypeDirectory {
+		return nil, fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+
+	merged := make(map[string]*Metadata)
+	hidden := make(map[string]bool)
+	opaque := false
+	if upper, err := o.store.List(ctx, o.upperPath(dirPath)); err == nil {
+		for _, m := range upper {
+			switch {
+			case m.Name == OpaqueMarker:
+				opaque = true
+			case strings.HasPrefix(m.Name, WhiteoutPrefix):
+				hidden[strings.TrimPrefix(m.Name, WhiteoutPrefix)] = true
+			default:
+				merged[m.Name] = m
+			}
+		}
+	}
+	if !opaque && o.baseVisible(ctx, dirPath) {
+		if base, err := o.store.List(ctx, o.basePath(dirPath)); err == nil {
+			for _, m := range base {
+				if _, ok := merged[m.Name]; !ok && !hidden[m.Name] {
+					merged[m.Name] = m
+				}
+			}
+		}
+	}
+
+	entries := make([]*Metadata, 0, len(merged))
+	for _, m := range merged {
+		entries = append(entries, m)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// copyUpDirs 在可写层中按基础层的属性补齐目录 dir 及其祖先
+func (o *Overlay) copyUpDirs(ctx context.Context, dir string) error {
+	current := "/"
+	for _, name := range strings.Split(strings.TrimPrefix(dir, "/"), "/") {
+		current = path.Join(current, name)
+		if current == "/" || o.exists(ctx, o.upperPath(current)) {
+			continue
+		}
+		m, _, err := o.lookup(ctx, current)
+		if err != nil {
+			return err
+		}
+		if m.Type != TypeDirectory {
+			return fmt.Errorf("path is not a directory: %s", current)
+		}
+		if err := o.store.Mkdir(ctx, o.upperPath(current), m.Mode.Perm()); err != nil {
+			return err
+		}
+		if _, err := o.store.SetAttr(ctx, o.upperPath(current), Attrs{Mode: m.Mode, Owner: m.Owner, Group: m.Group},
+			SetAttrMode|SetAttrOwner|SetAttrGroup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareCreate 检查视图中可以在 p 新建条目，补齐可写层中的父目录并清除白名单，返回是否清除了白名单
+func (o *Overlay) prepareCreate(ctx context.Context, p string) (bool, error) {
+	if err := checkName(p); err != nil {
+		return false, err
+	}
+	if _, _, err := o.lookup(ctx, p); err == nil {
+		return false, fmt.Errorf("file %w: %s", ErrExist, p)
+	}
+	parent, _, err := o.lookup(ctx, path.Dir(p))
+	if err != nil {
+		return false, fmt.Errorf("parent directory not found: %s", path.Dir(p))
+	}
+	if parent.Type != TypeDirectory {
+		return false, fmt.Errorf("parent path is not a directory: %s", path.Dir(p))
+	}
+	if err := o.copyUpDirs(ctx, path.Dir(p)); err != nil {
+		return false, err
+	}
+	whiteout := o.whiteoutPath(p)
+	if !o.exists(ctx, whiteout) {
+		return false, nil
+	}
+	return true, o.store.Delete(ctx, whiteout)
+}
+
+// Create 在可写层中创建文件
+func (o *Overlay) Create(ctx context.Context, p string, mode os.FileMode) (*Metadata, error) {
+	filePath := normalizePath(p)
+	if _, err := o.prepareCreate(ctx, filePath); err != nil {
+		return nil, err
+	}
+	return o.store.Create(ctx, o.upperPath(filePath), mode)
+}
+
+// Mkdir 在可写层中创建目录，替换被删除的基础层目录时新目录是不透明的
+func (o *Overlay) Mkdir(ctx context.Context, p string, mode os.FileMode) error {
+	dirPath := normalizePath(p)
+	replaced, err := o.prepareCreate(ctx, dirPath)
+	if err != nil {
+		return err
+	}
+	if err := o.store.Mkdir(ctx, o.upperPath(dirPath), mode); err != nil {
+		return err
+	}
+	if replaced {
+		_, err = o.store.Create(ctx, path.Join(o.upperPath(dirPath), OpaqueMarker), 0)
+	}
+	return err
+}
+
+// copyUp 将基础层中的条目复制到可写层，返回可写层中的元数据以及基础层块ID到复制块的映射
+func (o *Overlay) copyUp(ctx context.Context, p string, m *Metadata) (*Metadata, map[string]Block, error) {
+	if m.Type == TypeDirectory {
+		if err := o.copyUpDirs(ctx, p); err != nil {
+			return nil, nil, err
+		}
+		upper, err := o.store.Get(ctx, o.upperPath(p))
+		return upper, nil, err
+	}
+	if m.Type != TypeRegular {
+		return nil, nil, fmt.Errorf("cannot copy up %s", p)
+	}
+
+	var blocks []Block
+	copies := make(map[string]Block)
+	if len(m.Blocks) > 0 || len(m.Extents) > 0 || m.BlockCount > 0 {
+		if o.copier == nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrCopyUpRequired, p)
+		}
+		baseBlocks, err := o.store.GetBlockRange(ctx, o.basePath(p), 0, -1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if blocks, err = o.copier.CopyBlocks(ctx, baseBlocks); err != nil {
+			return nil, nil, fmt.Errorf("failed to copy up %s: %v", p, err)
+		}
+		if len(blocks) != len(baseBlocks) {
+			return nil, nil, fmt.Errorf("failed to copy up %s: copied %d of %d blocks", p, len(blocks), len(baseBlocks))
+		}
+		for i, b := range baseBlocks {
+			copies[b.ID] = blocks[i]
+		}
+	}
+	if err := o.copyUpDirs(ctx, path.Dir(p)); err != nil {
+		return nil, nil, err
+	}
+	upper, err := o.store.Create(ctx, o.upperPath(p), m.Mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	copied := m.Clone()
+	copied.Inode = upper.Inode
+	copied.Version = upper.Version
+	copied.Blocks = blocks
+	copied.Extents = nil
+	copied.BlockCount = 0
+	if err := o.store.Update(ctx, o.upperPath(p), copied); err != nil {
+		return nil, nil, err
+	}
+	return copied, copies, nil
+}
+
+// Update 更新视图中条目的元数据，基础层中的条目先被复制到可写层
+//
+// 基础层中的条目第一次修改时，meta.Version 与基础层中的版本比较。
+func (o *Overlay) Update(ctx context.Context, p string, meta *Metadata) error {
+	filePath := normalizePath(p)
+	current, inUpper, err := o.lookup(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	if !inUpper {
+		if meta.Version != 0 && meta.Version != current.Version {
+			return &VersionConflictError{Path: filePath, Expected: meta.Version, Actual: current.Version}
+		}
+		upper, copies, err := o.copyUp(ctx, filePath, current)
+		if err != nil {
+			return err
+		}
+		updated := meta.Clone()
+		updated.Inode = upper.Inode
+		updated.Version = upper.Version
+		if meta.Blocks == nil && meta.BlockCount > 0 {
+			// 拆分保存的块映射没有随元数据返回，保留复制后的块映射
+			updated.Extents = upper.Extents
+		} else {
+			// 调用方的数据块中仍引用基础层的块换成复制后的块
+			var blocks []Block
+			for i := range meta.Extents {
+				blocks = append(blocks, meta.Extents[i].Blocks()...)
+			}
+			blocks = append(blocks, meta.Blocks...)
+			for i, b := range blocks {
+				if c, ok := copies[b.ID]; ok {
+					blocks[i] = c
+				}
+			}
+			updated.Blocks = append([]Block{}, blocks...)
+			updated.Extents = nil
+			updated.BlockCount = 0
+		}
+		if err := o.store.Update(ctx, o.upperPath(filePath), updated); err != nil {
+			return err
+		}
+		meta.Version = updated.Version
+		return nil
+	}
+	return o.store.Update(ctx, o.upperPath(filePath), meta)
+}
+
+// Delete 从视图中删除条目，基础层中的条目用白名单隐藏，目录必须为空
+func (o *Overlay) Delete(ctx context.Context, p string) error {
+	filePath := normalizePath(p)
+	if filePath == "/" {
+		return fmt.Errorf("cannot delete overlay root")
+	}
+	m, inUpper, err := o.lookup(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	if m.Type == TypeDirectory {
+		entries, err := o.List(ctx, filePath)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("%w: %s", ErrNotEmpty, filePath)
+		}
+	}
+	if inUpper {
+		// 可写层中的空目录只可能剩下白名单文件
+		if err := o.store.DeleteAll(ctx, o.upperPath(filePath)); err != nil {
+			return err
+		}
+	}
+	if o.baseVisible(ctx, filePath) && o.exists(ctx, o.basePath(filePath)) {
+		if err := o.copyUpDirs(ctx, path.Dir(filePath)); err != nil {
+			return err
+		}
+		if _, err := o.store.Create(ctx, o.whiteoutPath(filePath), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ CacheBackend = (*Overlay)(nil)
+
+// prefixCopier 复制数据块时给块ID加上前缀
+type prefixCopier struct{}
+
+func (prefixCopier) CopyBlocks(ctx context.Context, blocks []Block) ([]Block, error) {
+	copied := make([]Block, len(blocks))
+	for i, b := range blocks {
+		copied[i] = b
+		copied[i].ID = "copy-" + b.ID
+	}
+	return copied, nil
+}
+
+// newTestOverlay 创建基础层包含 /data/a、/data/b 和 /readme 的覆盖视图
+func newTestOverlay(t *testing.T, copier BlockCopier) (*MemoryStore, *Overlay) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	for _, dir := range []string{"/base", "/base/data", "/upper"} {
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+	}
+	for _, p := range []string{"/base/data/a", "/base/data/b", "/base/readme"} {
+		_, err := store.Create(ctx, p, 0644)
+		require.NoError(t, err)
+	}
+	o, err := NewOverlay(ctx, store, "/base", "/upper", copier)
+	require.NoError(t, err)
+	return store, o
+}
+
+func names(entries []*Metadata) []string {
+	var result []string
+	for _, m := range entries {
+		result = append(result, m.Name)
+	}
+	return result
+}
+
+func TestOverlayMergedView(t *testing.T) {
+	ctx := context.Background()
+	store, o := newTestOverlay(t, nil)
+
+	_, err := o.Create(ctx, "/data/c", 0644)
+	require.NoError(t, err)
+	entries, err := o.List(ctx, "/data")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, names(entries))
+
+	// 新文件只出现在可写层
+	_, err = store.Get(ctx, "/upper/data/c")
+	assert.NoError(t, err)
+	_, err = store.Get(ctx, "/base/data/c")
+	assert.Error(t, err)
+
+	_, err = o.Create(ctx, "/data/a", 0644)
+	assert.ErrorIs(t, err, ErrExist)
+	_, err = o.Create(ctx, "/data/.wh.x", 0644)
+	assert.Error(t, err)
+
+	_, err = NewOverlay(ctx, store, "/base", "/base/data", nil)
+	assert.Error(t, err)
+}
+
+func TestOverlayDeleteWhiteout(t *testing.T) {
+	ctx := context.Background()
+	store, o := newTestOverlay(t, nil)
+
+	require.NoError(t, o.Delete(ctx, "/data/a"))
+	_, err := o.Get(ctx, "/data/a")
+	assert.Error(t, err)
+	entries, err := o.List(ctx, "/data")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, names(entries))
+
+	// 基础层不变，可写层留下白名单文件
+	_, err = store.Get(ctx, "/base/data/a")
+	assert.NoError(t, err)
+	_, err = store.Get(ctx, "/upper/data/.wh.a")
+	assert.NoError(t, err)
+
+	// 重新创建时清除白名单
+	_, err = o.Create(ctx, "/data/a", 0600)
+	require.NoError(t, err)
+	m, err := o.Get(ctx, "/data/a")
+	require.NoError(t, err)
+	assert.Equal(t, 0600, int(m.Mode.Perm()))
+	_, err = store.Get(ctx, "/upper/data/.wh.a")
+	assert.Error(t, err)
+
+	// 非空目录不能删除
+	assert.ErrorIs(t, o.Delete(ctx, "/data"), ErrNotEmpty)
+}
+
+func TestOverlayOpaqueDirectory(t *testing.T) {
+	ctx := context.Background()
+	_, o := newTestOverlay(t, nil)
+
+	for _, p := range []string{"/data/a", "/data/b"} {
+		require.NoError(t, o.Delete(ctx, p))
+	}
+	require.NoError(t, o.Delete(ctx, "/data"))
+	_, err := o.Get(ctx, "/data")
+	assert.Error(t, err)
+
+	// 重建的目录不显示基础层的内容
+	require.NoError(t, o.Mkdir(ctx, "/data", 0755))
+	entries, err := o.List(ctx, "/data")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	_, err = o.Get(ctx, "/data/a")
+	assert.Error(t, err)
+
+	entries, err = o.List(ctx, "/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data", "readme"}, names(entries))
+}
+
+func TestOverlayCopyUp(t *testing.T) {
+	ctx := context.Background()
+	store, o := newTestOverlay(t, nil)
+
+	m, err := o.Get(ctx, "/data/a")
+	require.NoError(t, err)
+	m.Mode = 0600
+	require.NoError(t, o.Update(ctx, "/data/a", m))
+
+	upper, err := o.Get(ctx, "/data/a")
+	require.NoError(t, err)
+	assert.NotEqual(t, m.Inode, upper.Inode)
+	assert.Equal(t, 0600, int(upper.Mode.Perm()))
+	base, err := store.Get(ctx, "/base/data/a")
+	require.NoError(t, err)
+	assert.Equal(t, 0644, int(base.Mode.Perm()))
+
+	// 复制的父目录保留基础层属性
+	dir, err := store.Get(ctx, "/upper/data")
+	require.NoError(t, err)
+	assert.Equal(t, TypeDirectory, dir.Type)
+
+	// 过期版本被拒绝
+	stale := m.Clone()
+	stale.Version = 99
+	assert.ErrorIs(t, o.Update(ctx, "/data/b", stale), ErrVersionConflict)
+}
+
+func TestOverlayCopyUpBlocks(t *testing.T) {
+	ctx := context.Background()
+	store, o := newTestOverlay(t, nil)
+
+	base, err := store.Get(ctx, "/base/data/a")
+	require.NoError(t, err)
+	base.Blocks = []Block{{ID: "blk-0", Size: 10}}
+	base.Size = 10
+	require.NoError(t, store.Update(ctx, "/base/data/a", base))
+
+	// 没有 BlockCopier 时不能修改带数据块的基础层文件
+	m, err := o.Get(ctx, "/data/a")
+	require.NoError(t, err)
+	assert.ErrorIs(t, o.Update(ctx, "/data/a", m), ErrCopyUpRequired)
+
+	o, err = NewOverlay(ctx, store, "/base", "/upper", prefixCopier{})
+	require.NoError(t, err)
+	m, err = o.Get(ctx, "/data/a")
+	require.NoError(t, err)
+	m.Blocks = append(m.Blocks, Block{ID: "blk-new", Offset: 10, Size: 5})
+	m.Size = 15
+	require.NoError(t, o.Update(ctx, "/data/a", m))
+
+	blocks, err := store.GetBlockRange(ctx, "/upper/data/a", 0, -1)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "copy-blk-0", blocks[0].ID)
+	assert.Equal(t, "blk-new", blocks[1].ID)
+
+	blocks, err = store.GetBlockRange(ctx, "/base/data/a", 0, -1)
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "blk-0", blocks[0].ID)
+}
@@ -0,0 +1,278 @@
+package meta
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+	"sync"
+)
+
+// OwnerKind 用户配额的统计维度
+type OwnerKind string
+
+const (
+	OwnerUser  OwnerKind = "user"  // 按条目的 Owner 统计
+	OwnerGroup OwnerKind = "group" // 按条目的 Group 统计
+)
+
+// ParseOwnerKind 解析统计维度
+func ParseOwnerKind(s string) (OwnerKind, error) {
+	switch kind := OwnerKind(s); kind {
+	case OwnerUser, OwnerGroup:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("unknown quota owner kind: %q", s)
+	}
+}
+
+// ownerKey 配额主体
+type ownerKey struct {
+	kind OwnerKind
+	name string
+}
+
+// OwnerQuotaExceededError 操作会使用户或组的用量超过上限
+type OwnerQuotaExceededError struct {
+	Kind     OwnerKind // user 或 group
+	Name     string    // 用户名或组名
+	Resource string    // 超限的资源：bytes 或 files
+	Limit    int64     // 上限
+	Usage    int64     // 操作前的用量
+}
+
+// Error 实现 error
+func (e *OwnerQuotaExceededError) Error() string {
+	return fmt.Sprintf("%s %s quota exceeded: %s limit is %d, usage is %d", e.Kind, e.Name, e.Resource, e.Limit, e.Usage)
+}
+
+// Is 使 errors.Is(err, ErrQuotaExceeded) 成立
+func (e *OwnerQuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// OwnerQuota 单个用户或组的上限与用量
+type OwnerQuota struct {
+	Kind   OwnerKind   `json:"kind"`
+	Name   string      `json:"name"`
+	Limits QuotaLimits `json:"limits"`
+	Usage  QuotaUsage  `json:"usage"`
+}
+
+// OwnerQuotas 按条目的所有者和组统计整个命名空间的用量并执行配额
+//
+// 每个条目同时计入其 Owner 和 Group，Owner 或 Group 为空时不计入对应维度。
+// 与项目配额不同，用户配额只支持硬限制：软限制、宽限期和 Webhook 会被拒绝。
+// Get、List 和内部统计方法对 nil 接收者安全，表示未启用用户配额。
+type OwnerQuotas struct {
+	mu     sync.Mutex
+	limits map[ownerKey]QuotaLimits
+	usage  map[ownerKey]QuotaUsage
+}
+
+// NewOwnerQuotas 创建用户与组配额
+func NewOwnerQuotas() *OwnerQuotas {
+	return &OwnerQuotas{
+		limits: make(map[ownerKey]QuotaLimits),
+		usage:  make(map[ownerKey]QuotaUsage),
+	}
+}
+
+// SetLimits 设置用户或组的配额上限，上限均为 0 时删除配额但继续统计用量
+func (q *OwnerQuotas) SetLimits(kind OwnerKind, name string, limits QuotaLimits) error {
+	if _, err := ParseOwnerKind(string(kind)); err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("%s name is required", kind)
+	}
+	if err := limits.validate(); err != nil {
+		return err
+	}
+	if limits.SoftBytes != 0 || limits.SoftFiles != 0 || limits.Grace != 0 || limits.Webhook != "" {
+		return fmt.Errorf("%s quotas only support hard limits", kind)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := ownerKey{kind, name}
+	if limits == (QuotaLimits{}) {
+		delete(q.limits, key)
+	} else {
+		q.limits[key] = limits
+	}
+	return nil
+}
+
+// Get 返回用户或组的上限与用量
+func (q *OwnerQuotas) Get(kind OwnerKind, name string) OwnerQuota {
+	if q == nil {
+		return OwnerQuota{Kind: kind, Name: name}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := ownerKey{kind, name}
+	return OwnerQuota{Kind: kind, Name: name, Limits: q.limits[key], Usage: q.usage[key]}
+}
+
+// List 返回维度 kind 下所有设置了上限或有用量的主体，kind 为空时返回全部，按维度和名称排序
+func (q *OwnerQuotas) List(kind OwnerKind) []OwnerQuota {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seen := make(map[ownerKey]bool, len(q.limits)+len(q.usage))
+	for key := range q.limits {
+		seen[key] = true
+	}
+	for key := range q.usage {
+		seen[key] = true
+	}
+	var out []OwnerQuota
+	for key := range seen {
+		if kind == "" || key.kind == kind {
+			out = append(out, OwnerQuota{Kind: key.kind, Name: key.name, Limits: q.limits[key], Usage: q.usage[key]})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind > out[j].Kind // user 在 group 之前
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// ownerKeys 返回条目计入的主体
+func ownerKeys(m *Metadata) []ownerKey {
+	if m == nil {
+		return nil
+	}
+	var keys []ownerKey
+	if m.Owner != "" {
+		keys = append(keys, ownerKey{OwnerUser, m.Owner})
+	}
+	if m.Group != "" {
+		keys = append(keys, ownerKey{OwnerGroup, m.Group})
+	}
+	return keys
+}
+
+// ownerUsageOf 返回条目计入所有者和组的用量
+func ownerUsageOf(m *Metadata) QuotaUsage {
+	if m == nil {
+		return QuotaUsage{}
+	}
+	u := QuotaUsage{Files: 1}
+	if m.Type == TypeRegular {
+		u.Bytes = m.Size
+	}
+	return u
+}
+
+// check 检查将条目从 old 改为 new（nil 表示不存在）是否超出用户或组配额，只有用量增加时才会拒绝
+func (q *OwnerQuotas) check(old, new *Metadata) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.checkLocked(old, new)
+}
+
+// checkLocked 执行 check，调用方需持有锁
+func (q *OwnerQuotas) checkLocked(old, new *Metadata) error {
+	prev := ownerKeys(old)
+	for _, key := range ownerKeys(new) {
+		limits, ok := q.limits[key]
+		if !ok {
+			continue
+		}
+		delta := ownerUsageOf(new)
+		for _, k := range prev {
+			if k == key {
+				u := ownerUsageOf(old)
+				delta.Bytes -= u.Bytes
+				delta.Files -= u.Files
+			}
+		}
+		usage := q.usage[key]
+		if delta.Files > 0 && limits.Files > 0 && usage.Files+delta.Files > limits.Files {
+			return &OwnerQuotaExceededError{Kind: key.kind, Name: key.name, Resource: "files", Limit: limits.Files, Usage: usage.Files}
+		}
+		if delta.Bytes > 0 && limits.Bytes > 0 && usage.Bytes+delta.Bytes > limits.Bytes {
+			return &OwnerQuotaExceededError{Kind: key.kind, Name: key.name, Resource: "bytes", Limit: limits.Bytes, Usage: usage.Bytes}
+		}
+	}
+	return nil
+}
+
+// charge 将条目从 old 改为 new 的用量变化计入所有者和组，enforce 时先检查配额
+func (q *OwnerQuotas) charge(old, new *Metadata, enforce bool) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if enforce {
+		if err := q.checkLocked(old, new); err != nil {
+			return err
+		}
+	}
+	for _, key := range ownerKeys(old) {
+		q.addLocked(key, ownerUsageOf(old), -1)
+	}
+	for _, key := range ownerKeys(new) {
+		q.addLocked(key, ownerUsageOf(new), 1)
+	}
+	return nil
+}
+
+// addLocked 按 sign 增减主体用量，用量归零时删除记录，调用方需持有锁
+func (q *OwnerQuotas) addLocked(key ownerKey, u QuotaUsage, sign int64) {
+	usage := q.usage[key]
+	usage.Bytes += sign * u.Bytes
+	usage.Files += sign * u.Files
+	if usage == (QuotaUsage{}) {
+		delete(q.usage, key)
+	} else {
+		q.usage[key] = usage
+	}
+}
+
+// recompute 根据全部条目重新统计用量
+func (q *OwnerQuotas) recompute(entries iter.Seq2[string, *Metadata]) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.usage = make(map[ownerKey]QuotaUsage)
+	linked := make(map[uint64]bool)
+	for _, m := range entries {
+		// 硬链接共享 inode，只统计一次
+		if m.Links > 1 {
+			if linked[m.Inode] {
+				continue
+			}
+			linked[m.Inode] = true
+		}
+		for _, key := range ownerKeys(m) {
+			q.addLocked(key, ownerUsageOf(m), 1)
+		}
+	}
+}
+
+// SetOwnerQuotas 启用用户与组配额并按现有条目统计用量，q 为 nil 时停用
+func (s *MemoryStore) SetOwnerQuotas(q *OwnerQuotas) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owners = q
+	q.recompute(s.tree.all())
+}
+
+// OwnerQuotas 返回当前启用的用户与组配额，未启用时为 nil
+func (s *MemoryStore) OwnerQuotas() *OwnerQuotas {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.owners
+}
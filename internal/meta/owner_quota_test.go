@@ -0,0 +1,91 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwnerQuotaEnforcement(t *testing.T) {
+	store := NewMemoryStore()
+	quotas := NewOwnerQuotas()
+	require.NoError(t, quotas.SetLimits(OwnerUser, "alice", QuotaLimits{Bytes: 100}))
+	require.NoError(t, quotas.SetLimits(OwnerGroup, "eng", QuotaLimits{Files: 3}))
+	store.SetOwnerQuotas(quotas)
+	ctx := context.Background()
+
+	// 用户的数据分布在两棵目录树中
+	for _, dir := range []string{"/home/alice", "/scratch/alice"} {
+		_, err := store.MkdirWithOptions(ctx, dir, 0755, MkdirOptions{Parents: true})
+		require.NoError(t, err)
+		require.NoError(t, store.SetDirDefaults(ctx, dir, &DirDefaults{Owner: "alice", Group: "eng"}))
+	}
+	_, err := store.CreateWithData(ctx, "/home/alice/a", 0644, bytes.Repeat([]byte("x"), 60))
+	require.NoError(t, err)
+
+	_, err = store.CreateWithData(ctx, "/scratch/alice/b", 0644, bytes.Repeat([]byte("x"), 50))
+	var qerr *OwnerQuotaExceededError
+	require.True(t, errors.As(err, &qerr), "%v", err)
+	assert.Equal(t, OwnerUser, qerr.Kind)
+	assert.Equal(t, "bytes", qerr.Resource)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = store.CreateWithData(ctx, "/scratch/alice/b", 0644, bytes.Repeat([]byte("x"), 40))
+	require.NoError(t, err)
+	assert.Equal(t, QuotaUsage{Bytes: 100, Files: 2}, quotas.Get(OwnerUser, "alice").Usage)
+	assert.Equal(t, QuotaUsage{Bytes: 100, Files: 2}, quotas.Get(OwnerGroup, "eng").Usage)
+
+	_, err = store.Create(ctx, "/home/alice/c", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/home/alice/d", 0644)
+	require.True(t, errors.As(err, &qerr), "%v", err)
+	assert.Equal(t, OwnerGroup, qerr.Kind)
+	assert.Equal(t, "files", qerr.Resource)
+
+	// chown 把用量转到新的所有者，超出新所有者的配额时拒绝
+	require.NoError(t, quotas.SetLimits(OwnerUser, "bob", QuotaLimits{Bytes: 50}))
+	_, err = store.SetAttr(ctx, "/home/alice/a", Attrs{Owner: "bob"}, SetAttrOwner)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = store.SetAttr(ctx, "/scratch/alice/b", Attrs{Owner: "bob"}, SetAttrOwner)
+	require.NoError(t, err)
+	assert.Equal(t, QuotaUsage{Bytes: 60, Files: 2}, quotas.Get(OwnerUser, "alice").Usage)
+	assert.Equal(t, QuotaUsage{Bytes: 40, Files: 1}, quotas.Get(OwnerUser, "bob").Usage)
+
+	// 删除释放用量
+	require.NoError(t, store.Delete(ctx, "/home/alice/c"))
+	assert.Equal(t, QuotaUsage{Bytes: 100, Files: 2}, quotas.Get(OwnerGroup, "eng").Usage)
+
+	list := quotas.List("")
+	require.Len(t, list, 3)
+	assert.Equal(t, []string{"alice", "bob", "eng"}, []string{list[0].Name, list[1].Name, list[2].Name})
+	assert.Len(t, quotas.List(OwnerGroup), 1)
+
+	// 从检查点恢复时重新统计
+	var buf bytes.Buffer
+	require.NoError(t, store.WriteCheckpoint(&buf))
+	restored := NewMemoryStore()
+	recounted := NewOwnerQuotas()
+	restored.SetOwnerQuotas(recounted)
+	require.NoError(t, restored.ReadCheckpoint(&buf))
+	assert.Equal(t, quotas.Get(OwnerUser, "bob").Usage, recounted.Get(OwnerUser, "bob").Usage)
+}
+
+func TestOwnerQuotaLimitsValidation(t *testing.T) {
+	quotas := NewOwnerQuotas()
+	assert.Error(t, quotas.SetLimits("tenant", "a", QuotaLimits{Bytes: 1}))
+	assert.Error(t, quotas.SetLimits(OwnerUser, "", QuotaLimits{Bytes: 1}))
+	assert.Error(t, quotas.SetLimits(OwnerUser, "a", QuotaLimits{Files: -1}))
+	assert.Error(t, quotas.SetLimits(OwnerUser, "a", QuotaLimits{Files: 2, SoftFiles: 1}))
+	require.NoError(t, quotas.SetLimits(OwnerGroup, "g", QuotaLimits{Files: 2}))
+	require.NoError(t, quotas.SetLimits(OwnerGroup, "g", QuotaLimits{}))
+	assert.Empty(t, quotas.List(""))
+
+	_, err := ParseOwnerKind("group")
+	assert.NoError(t, err)
+	var disabled *OwnerQuotas
+	assert.Equal(t, OwnerQuota{Kind: OwnerUser, Name: "a"}, disabled.Get(OwnerUser, "a"))
+}
@@ -0,0 +1,76 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// pinFile 返回设置了固定位置的文件副本，调用方需持有写锁
+func pinFile(current *Metadata, filePath string, pin *FilePin) (*Metadata, error) {
+	if current.Type != TypeRegular {
+		return nil, fmt.Errorf("path is not a file: %s", filePath)
+	}
+	updated := current.Clone()
+	updated.Pin = pin.Clone()
+	updated.ModifyTime = time.Now()
+	updated.Version++
+	return updated, nil
+}
+
+// SetPin 将文件固定到指定节点或介质，pin 为 nil 时取消固定
+//
+// 只记录固定位置，已有数据块的迁移由调用方按 cluster.SelectPinnedNodes 的结果完成。
+func (s *MemoryStore) SetPin(ctx context.Context, p string, pin *FilePin) error {
+	if pin != nil {
+		if err := pin.Validate(); err != nil {
+			return err
+		}
+	}
+
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := normalizePath(p)
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	updated, err := pinFile(current, filePath, pin)
+	if err != nil {
+		return err
+	}
+	s.put(filePath, updated)
+	s.recordChange(ctx, ChangeModify, filePath, updated)
+	return nil
+}
+
+// SetPin 将文件固定到指定节点或介质，pin 为 nil 时取消固定
+func (s *CompactStore) SetPin(ctx context.Context, p string, pin *FilePin) error {
+	if pin != nil {
+		if err := pin.Validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath := normalizePath(p)
+	idx, exists := s.lookup(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	e := &s.entries[idx]
+	updated, err := pinFile(s.decode(e), filePath, pin)
+	if err != nil {
+		return err
+	}
+	s.release(e)
+	s.encode(e, updated)
+	s.maybeCompact()
+	return nil
+}
@@ -0,0 +1,51 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPin(t *testing.T) {
+	for name, store := range map[string]interface {
+		dirDefaultsStore
+		SetPin(ctx context.Context, path string, pin *FilePin) error
+	}{
+		"memory":  NewMemoryStore(),
+		"compact": NewCompactStore(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, store.Mkdir(ctx, "/models", 0755))
+			_, err := store.Create(ctx, "/models/weights", 0644)
+			require.NoError(t, err)
+
+			pin := &FilePin{Nodes: []string{"d1", "d2"}, Media: "nvme"}
+			require.NoError(t, store.SetPin(ctx, "/models/weights", pin))
+			pin.Nodes[0] = "changed"
+			f, err := store.Get(ctx, "/models/weights")
+			require.NoError(t, err)
+			assert.True(t, f.Pinned())
+			assert.Equal(t, &FilePin{Nodes: []string{"d1", "d2"}, Media: "nvme"}, f.Pin)
+			assert.Equal(t, uint64(2), f.Version)
+
+			// 普通更新保留固定位置
+			f.Size = 10
+			require.NoError(t, store.Update(ctx, "/models/weights", f))
+			f, err = store.Get(ctx, "/models/weights")
+			require.NoError(t, err)
+			assert.True(t, f.Pinned())
+
+			require.NoError(t, store.SetPin(ctx, "/models/weights", nil))
+			f, err = store.Get(ctx, "/models/weights")
+			require.NoError(t, err)
+			assert.False(t, f.Pinned())
+
+			assert.Error(t, store.SetPin(ctx, "/models", &FilePin{Media: "nvme"}), "directories cannot be pinned")
+			assert.Error(t, store.SetPin(ctx, "/models/weights", &FilePin{}))
+			assert.Error(t, store.SetPin(ctx, "/missing", &FilePin{Media: "nvme"}))
+		})
+	}
+}
@@ -0,0 +1,482 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultQuotaGrace 超过软限制后的默认宽限期
+const DefaultQuotaGrace = 7 * 24 * time.Hour
+
+// ErrQuotaExceeded 超出配额，可用 errors.Is 判断
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError 操作会使项目用量超过上限
+type QuotaExceededError struct {
+	Project  uint32 // 项目ID
+	Resource string // 超限的资源：bytes 或 files
+	Limit    int64  // 上限
+	Usage    int64  // 操作前的用量
+	Soft     bool   // 超过的是宽限期已过的软限制
+}
+
+// Error 实现 error
+func (e *QuotaExceededError) Error() string {
+	if e.Soft {
+		return fmt.Sprintf("project %d quota exceeded: %s soft limit is %d, usage is %d and the grace period has expired",
+			e.Project, e.Resource, e.Limit, e.Usage)
+	}
+	return fmt.Sprintf("project %d quota exceeded: %s limit is %d, usage is %d", e.Project, e.Resource, e.Limit, e.Usage)
+}
+
+// Is 使 errors.Is(err, ErrQuotaExceeded) 成立
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// QuotaLimits 配额上限，0 表示不限制
+//
+// 硬限制直接拒绝使用量增加的写入；超过软限制时只发出事件，宽限期过后软限制按硬限制执行，
+// 直到用量回落到软限制以下。
+type QuotaLimits struct {
+	Bytes     int64         `json:"bytes"`                // 普通文件大小之和的硬限制
+	Files     int64         `json:"files"`                // 文件与目录数量的硬限制
+	SoftBytes int64         `json:"soft_bytes,omitempty"` // 大小软限制
+	SoftFiles int64         `json:"soft_files,omitempty"` // 数量软限制
+	Grace     time.Duration `json:"grace,omitempty"`      // 软限制宽限期，为 0 时使用 DefaultQuotaGrace
+	Webhook   string        `json:"webhook,omitempty"`    // 接收该配额事件的 URL，为空时不通知
+}
+
+// validate 校验上限
+func (l QuotaLimits) validate() error {
+	if l.Bytes < 0 || l.Files < 0 || l.SoftBytes < 0 || l.SoftFiles < 0 || l.Grace < 0 {
+		return fmt.Errorf("quota limits must not be negative: %+v", l)
+	}
+	if l.Bytes > 0 && l.SoftBytes > l.Bytes {
+		return fmt.Errorf("soft byte limit %d exceeds hard limit %d", l.SoftBytes, l.Bytes)
+	}
+	if l.Files > 0 && l.SoftFiles > l.Files {
+		return fmt.Errorf("soft file limit %d exceeds hard limit %d", l.SoftFiles, l.Files)
+	}
+	return nil
+}
+
+// grace 返回生效的宽限期
+func (l QuotaLimits) grace() time.Duration {
+	if l.Grace <= 0 {
+		return DefaultQuotaGrace
+	}
+	return l.Grace
+}
+
+// overSoft 判断用量是否超过软限制
+func (l QuotaLimits) overSoft(u QuotaUsage) bool {
+	return (l.SoftBytes > 0 && u.Bytes > l.SoftBytes) || (l.SoftFiles > 0 && u.Files > l.SoftFiles)
+}
+
+// QuotaEventType 配额事件类型
+type QuotaEventType string
+
+const (
+	QuotaSoftExceeded QuotaEventType = "soft_exceeded" // 用量超过软限制，宽限期开始
+	QuotaSoftCleared  QuotaEventType = "soft_cleared"  // 用量回落到软限制以下
+	QuotaGraceExpired QuotaEventType = "grace_expired" // 宽限期结束，软限制开始按硬限制执行
+	QuotaRejected     QuotaEventType = "rejected"      // 写入因超出配额被拒绝
+)
+
+// QuotaEvent 配额状态变化事件
+type QuotaEvent struct {
+	Type    QuotaEventType `json:"type"`
+	Project uint32         `json:"project"`
+	Limits  QuotaLimits    `json:"limits"`
+	Usage   QuotaUsage     `json:"usage"`
+	Time    time.Time      `json:"time"`
+}
+
+// QuotaUsage 配额用量
+type QuotaUsage struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+}
+
+// ProjectQuota 单个项目的上限与用量
+type ProjectQuota struct {
+	Project      uint32      `json:"project"`
+	Limits       QuotaLimits `json:"limits"`
+	Usage        QuotaUsage  `json:"usage"`
+	GraceExpires time.Time   `json:"grace_expires,omitempty"` // 超过软限制时宽限期的结束时间
+}
+
+// ProjectQuotas 按项目ID统计用量并执行配额
+//
+// 项目ID记录在每个条目上，与目录结构无关：同一项目的数据可以分布在多棵目录树中，
+// 通过目录默认属性在创建时继承，或用 SetProject 为已有条目打标签。
+// 项目ID为 0 的条目不计入任何项目。Get、List 和内部统计方法对 nil 接收者安全，表示未启用配额。
+type ProjectQuotas struct {
+	mu     sync.Mutex
+	limits map[uint32]QuotaLimits
+	usage  map[uint32]QuotaUsage
+	// 超过软限制的项目及开始时间，以及已发出宽限期结束事件的项目
+	softSince    map[uint32]time.Time
+	graceEmitted map[uint32]bool
+	onEvent      func(QuotaEvent)
+	now          func() time.Time
+}
+
+// NewProjectQuotas 创建项目配额
+func NewProjectQuotas() *ProjectQuotas {
+	return &ProjectQuotas{
+		limits:       make(map[uint32]QuotaLimits),
+		usage:        make(map[uint32]QuotaUsage),
+		softSince:    make(map[uint32]time.Time),
+		graceEmitted: make(map[uint32]bool),
+		now:          time.Now,
+	}
+}
+
+// OnEvent 设置配额事件回调
+//
+// 回调在持有元数据存储锁时同步调用，不得阻塞或访问存储；需要网络通知时交给 QuotaWebhook 等异步队列。
+func (q *ProjectQuotas) OnEvent(fn func(QuotaEvent)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.onEvent = fn
+}
+
+// emitLocked 发出事件，调用方需持有锁
+func (q *ProjectQuotas) emitLocked(typ QuotaEventType, project uint32, now time.Time) {
+	if q.onEvent != nil {
+		q.onEvent(QuotaEvent{Type: typ, Project: project, Limits: q.limits[project], Usage: q.usage[project], Time: now})
+	}
+}
+
+// updateSoftLocked 根据当前用量更新项目的软限制状态，调用方需持有锁
+func (q *ProjectQuotas) updateSoftLocked(project uint32, now time.Time) {
+	limits, ok := q.limits[project]
+	over := ok && limits.overSoft(q.usage[project])
+	_, was := q.softSince[project]
+	switch {
+	case over && !was:
+		q.softSince[project] = now
+		q.emitLocked(QuotaSoftExceeded, project, now)
+	case !over && was:
+		delete(q.softSince, project)
+		delete(q.graceEmitted, project)
+		if ok {
+			q.emitLocked(QuotaSoftCleared, project, now)
+		}
+	}
+}
+
+// graceExpiredLocked 判断项目的软限制宽限期是否已过，调用方需持有锁
+func (q *ProjectQuotas) graceExpiredLocked(project uint32, now time.Time) bool {
+	since, ok := q.softSince[project]
+	return ok && !now.Before(since.Add(q.limits[project].grace()))
+}
+
+// Evaluate 为宽限期已经结束的项目发出 QuotaGraceExpired 事件，每个超限周期只发出一次，返回这些项目
+//
+// 没有写入时宽限期结束也需要通知，应周期性调用。
+func (q *ProjectQuotas) Evaluate() []uint32 {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	var expired []uint32
+	for project := range q.softSince {
+		if q.graceExpiredLocked(project, now) && !q.graceEmitted[project] {
+			q.graceEmitted[project] = true
+			q.emitLocked(QuotaGraceExpired, project, now)
+			expired = append(expired, project)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i] < expired[j] })
+	return expired
+}
+
+// SetLimits 设置项目配额上限，上限均为 0 时删除配额但继续统计用量
+func (q *ProjectQuotas) SetLimits(project uint32, limits QuotaLimits) error {
+	if project == 0 {
+		return fmt.Errorf("project id 0 cannot have a quota")
+	}
+	if err := limits.validate(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if limits == (QuotaLimits{}) {
+		delete(q.limits, project)
+	} else {
+		q.limits[project] = limits
+	}
+	q.updateSoftLocked(project, q.now())
+	return nil
+}
+
+// Get 返回项目的上限与用量
+func (q *ProjectQuotas) Get(project uint32) ProjectQuota {
+	if q == nil {
+		return ProjectQuota{Project: project}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.quotaLocked(project)
+}
+
+// quotaLocked 返回项目的上限、用量与宽限期，调用方需持有锁
+func (q *ProjectQuotas) quotaLocked(project uint32) ProjectQuota {
+	pq := ProjectQuota{Project: project, Limits: q.limits[project], Usage: q.usage[project]}
+	if since, ok := q.softSince[project]; ok {
+		pq.GraceExpires = since.Add(pq.Limits.grace())
+	}
+	return pq
+}
+
+// List 返回所有设置了上限或有用量的项目，按项目ID排序
+func (q *ProjectQuotas) List() []ProjectQuota {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seen := make(map[uint32]bool, len(q.limits)+len(q.usage))
+	var out []ProjectQuota
+	for project := range q.limits {
+		seen[project] = true
+	}
+	for project := range q.usage {
+		seen[project] = true
+	}
+	for project := range seen {
+		out = append(out, q.quotaLocked(project))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Project < out[j].Project
+	})
+	return out
+}
+
+// quotaUsageOf 返回条目计入其项目的用量
+func quotaUsageOf(m *Metadata) QuotaUsage {
+	if m == nil || m.ProjectID == 0 {
+		return QuotaUsage{}
+	}
+	u := QuotaUsage{Files: 1}
+	if m.Type == TypeRegular {
+		u.Bytes = m.Size
+	}
+	return u
+}
+
+// check 检查将条目从 old 改为 new（nil 表示不存在）是否超出配额，只有用量增加时才会拒绝
+func (q *ProjectQuotas) check(old, new *Metadata) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.checkLocked(old, new)
+}
+
+// checkLocked 执行 check，拒绝时发出 QuotaRejected 事件，调用方需持有锁
+func (q *ProjectQuotas) checkLocked(old, new *Metadata) error {
+	err := q.exceededLocked(old, new)
+	if err != nil {
+		q.emitLocked(QuotaRejected, new.ProjectID, q.now())
+	}
+	return err
+}
+
+// exceededLocked 返回操作超出的限制，调用方需持有锁
+func (q *ProjectQuotas) exceededLocked(old, new *Metadata) error {
+	if new == nil || new.ProjectID == 0 {
+		return nil
+	}
+	project := new.ProjectID
+	delta := quotaUsageOf(new)
+	if old != nil && old.ProjectID == project {
+		prev := quotaUsageOf(old)
+		delta.Bytes -= prev.Bytes
+		delta.Files -= prev.Files
+	}
+	limits, ok := q.limits[project]
+	if !ok {
+		return nil
+	}
+	usage := q.usage[project]
+	if delta.Files > 0 && limits.Files > 0 && usage.Files+delta.Files > limits.Files {
+		return &QuotaExceededError{Project: project, Resource: "files", Limit: limits.Files, Usage: usage.Files}
+	}
+	if delta.Bytes > 0 && limits.Bytes > 0 && usage.Bytes+delta.Bytes > limits.Bytes {
+		return &QuotaExceededError{Project: project, Resource: "bytes", Limit: limits.Bytes, Usage: usage.Bytes}
+	}
+	// 宽限期过后软限制按硬限制执行
+	if (delta.Files > 0 || delta.Bytes > 0) && q.graceExpiredLocked(project, q.now()) {
+		if delta.Files > 0 && limits.SoftFiles > 0 && usage.Files+delta.Files > limits.SoftFiles {
+			return &QuotaExceededError{Project: project, Resource: "files", Limit: limits.SoftFiles, Usage: usage.Files, Soft: true}
+		}
+		if delta.Bytes > 0 && limits.SoftBytes > 0 && usage.Bytes+delta.Bytes > limits.SoftBytes {
+			return &QuotaExceededError{Project: project, Resource: "bytes", Limit: limits.SoftBytes, Usage: usage.Bytes, Soft: true}
+		}
+	}
+	return nil
+}
+
+// charge 将条目从 old 改为 new 的用量变化计入项目，enforce 时先检查配额
+func (q *ProjectQuotas) charge(old, new *Metadata, enforce bool) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if enforce {
+		if err := q.checkLocked(old, new); err != nil {
+			return err
+		}
+	}
+	now := q.now()
+	if old != nil && old.ProjectID != 0 {
+		q.addLocked(old.ProjectID, quotaUsageOf(old), -1)
+		q.updateSoftLocked(old.ProjectID, now)
+	}
+	if new != nil && new.ProjectID != 0 {
+		q.addLocked(new.ProjectID, quotaUsageOf(new), 1)
+		if old == nil || old.ProjectID != new.ProjectID {
+			q.updateSoftLocked(new.ProjectID, now)
+		}
+	}
+	return nil
+}
+
+// addLocked 按 sign 增减项目用量，用量归零时删除记录，调用方需持有锁
+func (q *ProjectQuotas) addLocked(project uint32, u QuotaUsage, sign int64) {
+	usage := q.usage[project]
+	usage.Bytes += sign * u.Bytes
+	usage.Files += sign * u.Files
+	if usage == (QuotaUsage{}) {
+		delete(q.usage, project)
+	} else {
+		q.usage[project] = usage
+	}
+}
+
+// recompute 根据全部条目重新统计用量
+func (q *ProjectQuotas) recompute(entries iter.Seq2[string, *Metadata]) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.usage = make(map[uint32]QuotaUsage)
+	linked := make(map[uint64]bool)
+	for _, m := range entries {
+		// 硬链接共享 inode，只统计一次
+		if m.Links > 1 {
+			if linked[m.Inode] {
+				continue
+			}
+			linked[m.Inode] = true
+		}
+		if m.ProjectID != 0 {
+			q.addLocked(m.ProjectID, quotaUsageOf(m), 1)
+		}
+	}
+	now := q.now()
+	for project := range q.limits {
+		q.updateSoftLocked(project, now)
+	}
+}
+
+// SetProjectQuotas 启用项目配额并按现有条目统计用量，q 为 nil 时停用
+func (s *MemoryStore) SetProjectQuotas(q *ProjectQuotas) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotas = q
+	q.recompute(s.tree.all())
+}
+
+// ProjectQuotas 返回当前启用的项目配额，未启用时为 nil
+func (s *MemoryStore) ProjectQuotas() *ProjectQuotas {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.quotas
+}
+
+// chargeQuota 检查项目、用户与组以及目录配额并计入 filePath 上条目的用量，调用方需持有写锁
+func (s *MemoryStore) chargeQuota(filePath string, old, new *Metadata) error {
+	paths := []string{filePath}
+	if err := s.checkDirQuota(paths, old, new); err != nil {
+		return err
+	}
+	if err := s.owners.check(old, new); err != nil {
+		return err
+	}
+	if err := s.quotas.charge(old, new, true); err != nil {
+		return err
+	}
+	s.owners.charge(old, new, false)
+	s.chargeDirQuota(paths, old, new)
+	return nil
+}
+
+// checkQuota 检查更新是否超出项目、用户与组或目录配额，文件有多个硬链接时检查每个路径所在的目录，调用方需持有写锁
+func (s *MemoryStore) checkQuota(filePath string, old, new *Metadata) error {
+	if err := s.quotas.check(old, new); err != nil {
+		return err
+	}
+	if err := s.owners.check(old, new); err != nil {
+		return err
+	}
+	return s.checkDirQuota(s.linkPaths(filePath, new.Inode), old, new)
+}
+
+// accountQuota 不做检查地计入条目从 old 改为 new 的用量变化，调用方需持有写锁
+func (s *MemoryStore) accountQuota(filePath string, old, new *Metadata) {
+	s.quotas.charge(old, new, false)
+	s.owners.charge(old, new, false)
+	inode := new
+	if inode == nil {
+		inode = old
+	}
+	s.chargeDirQuota(s.linkPaths(filePath, inode.Inode), old, new)
+}
+
+// SetProject 为已有条目打上项目标签，用量随之从原项目转到新项目，project 为 0 时清除标签
+//
+// 只修改该条目本身；目录下之后新建的条目通过目录默认属性的 ProjectID 继承项目。
+func (s *MemoryStore) SetProject(ctx context.Context, p string, project uint32) error {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := normalizePath(p)
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	if current.ProjectID == project {
+		return nil
+	}
+
+	updated := current.Clone()
+	updated.ProjectID = project
+	if err := s.chargeQuota(filePath, current, updated); err != nil {
+		return err
+	}
+	updated.Version++
+	s.put(filePath, updated)
+	if filePath == "/" {
+		s.root = updated
+	}
+	s.recordChange(ctx, ChangeModify, filePath, updated)
+	return nil
+}
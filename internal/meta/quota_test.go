@@ -0,0 +1,188 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cpfs/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectQuotaInheritanceAndEnforcement(t *testing.T) {
+	store := NewMemoryStore()
+	quotas := NewProjectQuotas()
+	require.NoError(t, quotas.SetLimits(7, QuotaLimits{Bytes: 100, Files: 4}))
+	store.SetProjectQuotas(quotas)
+	ctx := context.Background()
+
+	// 同一项目的数据分布在两棵目录树中
+	for _, dir := range []string{"/teams/a", "/scratch/a"} {
+		_, err := store.MkdirWithOptions(ctx, dir, 0755, MkdirOptions{Parents: true})
+		require.NoError(t, err)
+		require.NoError(t, store.SetDirDefaults(ctx, dir, &DirDefaults{ProjectID: 7}))
+	}
+
+	f, err := store.Create(ctx, "/teams/a/x", 0644)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(7), f.ProjectID)
+	f.Size = 60
+	require.NoError(t, store.Update(ctx, "/teams/a/x", f))
+
+	_, err = store.CreateWithData(ctx, "/scratch/a/y", 0644, bytes.Repeat([]byte("x"), 50))
+	var qerr *QuotaExceededError
+	require.True(t, errors.As(err, &qerr), "%v", err)
+	assert.Equal(t, "bytes", qerr.Resource)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = store.Get(ctx, "/scratch/a/y")
+	assert.Error(t, err, "rejected create leaves nothing behind")
+
+	_, err = store.CreateWithData(ctx, "/scratch/a/y", 0644, bytes.Repeat([]byte("x"), 40))
+	require.NoError(t, err)
+	assert.Equal(t, QuotaUsage{Bytes: 100, Files: 2}, quotas.Get(7).Usage)
+
+	// 缩小文件总是允许
+	f, err = store.Get(ctx, "/teams/a/x")
+	require.NoError(t, err)
+	f.Size = 10
+	require.NoError(t, store.Update(ctx, "/teams/a/x", f))
+	assert.Equal(t, int64(50), quotas.Get(7).Usage.Bytes)
+
+	_, err = store.Create(ctx, "/teams/a/z", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Mkdir(ctx, "/teams/a/sub", 0755))
+	_, err = store.Create(ctx, "/teams/a/sub/w", 0644)
+	assert.ErrorIs(t, err, ErrQuotaExceeded, "subdirectory inherits the project and the file limit applies")
+
+	require.NoError(t, store.Delete(ctx, "/teams/a/z"))
+	assert.Equal(t, QuotaUsage{Bytes: 50, Files: 3}, quotas.Get(7).Usage)
+}
+
+func TestSetProjectMovesUsage(t *testing.T) {
+	store := NewMemoryStore()
+	quotas := NewProjectQuotas()
+	ctx := context.Background()
+
+	_, err := store.CreateWithData(ctx, "/report", 0644, []byte("hello"))
+	require.NoError(t, err)
+	// 启用前已有的条目在启用时统计
+	require.NoError(t, store.SetProject(ctx, "/report", 1))
+	store.SetProjectQuotas(quotas)
+	assert.Equal(t, QuotaUsage{Bytes: 5, Files: 1}, quotas.Get(1).Usage)
+
+	require.NoError(t, quotas.SetLimits(2, QuotaLimits{Bytes: 4}))
+	assert.ErrorIs(t, store.SetProject(ctx, "/report", 2), ErrQuotaExceeded)
+
+	require.NoError(t, quotas.SetLimits(2, QuotaLimits{}))
+	require.NoError(t, store.SetProject(ctx, "/report", 2))
+	assert.Equal(t, QuotaUsage{}, quotas.Get(1).Usage)
+	assert.Equal(t, QuotaUsage{Bytes: 5, Files: 1}, quotas.Get(2).Usage)
+
+	list := quotas.List()
+	require.Len(t, list, 1)
+	assert.Equal(t, uint32(2), list[0].Project)
+
+	require.NoError(t, store.Delete(ctx, "/report"))
+	assert.Empty(t, quotas.List())
+	assert.Error(t, store.SetProject(ctx, "/missing", 1))
+}
+
+func TestProjectQuotaCheckpointRecompute(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/p", 0755))
+	require.NoError(t, store.SetDirDefaults(ctx, "/p", &DirDefaults{ProjectID: 3}))
+	_, err := store.CreateWithData(ctx, "/p/a", 0644, []byte("abc"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, store.WriteCheckpoint(&buf))
+
+	restored := NewMemoryStore()
+	quotas := NewProjectQuotas()
+	restored.SetProjectQuotas(quotas)
+	require.NoError(t, restored.ReadCheckpoint(&buf))
+	assert.Equal(t, QuotaUsage{Bytes: 3, Files: 1}, quotas.Get(3).Usage)
+}
+
+func TestProjectQuotaLimitsValidation(t *testing.T) {
+	quotas := NewProjectQuotas()
+	assert.Error(t, quotas.SetLimits(0, QuotaLimits{Bytes: 1}))
+	assert.Error(t, quotas.SetLimits(1, QuotaLimits{Files: -1}))
+	assert.Error(t, quotas.SetLimits(1, QuotaLimits{Files: 1, SoftFiles: 2}))
+	assert.NoError(t, quotas.SetLimits(1, QuotaLimits{SoftFiles: 2}), "soft limit without a hard limit")
+	var disabled *ProjectQuotas
+	assert.Equal(t, ProjectQuota{Project: 5}, disabled.Get(5))
+}
+
+func TestProjectQuotaSoftLimitGrace(t *testing.T) {
+	store := NewMemoryStore()
+	quotas := NewProjectQuotas()
+	now := time.Unix(1000, 0)
+	quotas.now = func() time.Time { return now }
+	var events []QuotaEventType
+	quotas.OnEvent(func(e QuotaEvent) { events = append(events, e.Type) })
+	require.NoError(t, quotas.SetLimits(2, QuotaLimits{Files: 10, SoftFiles: 2, Grace: time.Hour}))
+	store.SetProjectQuotas(quotas)
+	ctx := context.Background()
+
+	require.NoError(t, store.Mkdir(ctx, "/p", 0755))
+	require.NoError(t, store.SetDirDefaults(ctx, "/p", &DirDefaults{ProjectID: 2}))
+	for _, name := range []string{"/p/a", "/p/b", "/p/c"} {
+		_, err := store.Create(ctx, name, 0644)
+		require.NoError(t, err, "soft limit only warns during the grace period")
+	}
+	assert.Equal(t, []QuotaEventType{QuotaSoftExceeded}, events)
+	assert.Equal(t, now.Add(time.Hour), quotas.Get(2).GraceExpires)
+	assert.Empty(t, quotas.Evaluate())
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, []uint32{2}, quotas.Evaluate())
+	assert.Empty(t, quotas.Evaluate(), "grace expiry is reported once")
+	_, err := store.Create(ctx, "/p/d", 0644)
+	var qerr *QuotaExceededError
+	require.True(t, errors.As(err, &qerr), "%v", err)
+	assert.True(t, qerr.Soft)
+	assert.Equal(t, int64(2), qerr.Limit)
+
+	require.NoError(t, store.Delete(ctx, "/p/c"))
+	require.NoError(t, store.Delete(ctx, "/p/b"))
+	assert.Equal(t, []QuotaEventType{QuotaSoftExceeded, QuotaGraceExpired, QuotaRejected, QuotaSoftCleared}, events)
+	assert.True(t, quotas.Get(2).GraceExpires.IsZero())
+	_, err = store.Create(ctx, "/p/d", 0644)
+	require.NoError(t, err, "dropping below the soft limit resets the grace period")
+}
+
+func TestQuotaWebhook(t *testing.T) {
+	received := make(chan QuotaEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e QuotaEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received <- e
+	}))
+	defer srv.Close()
+
+	hook := NewQuotaWebhook(1)
+	hook.SetLogger(logger.Nop())
+	hook.Notify(QuotaEvent{Type: QuotaSoftExceeded, Project: 1})
+	hook.Notify(QuotaEvent{Type: QuotaSoftExceeded, Project: 4, Limits: QuotaLimits{SoftBytes: 1, Webhook: srv.URL}})
+	hook.Notify(QuotaEvent{Type: QuotaSoftCleared, Project: 4, Limits: QuotaLimits{SoftBytes: 1, Webhook: srv.URL}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hook.Run(ctx)
+	select {
+	case e := <-received:
+		assert.Equal(t, uint32(4), e.Project)
+		assert.Equal(t, QuotaSoftExceeded, e.Type)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+}
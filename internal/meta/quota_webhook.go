@@ -0,0 +1,100 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// defaultQuotaWebhookQueue 待发送配额事件的默认队列长度
+const defaultQuotaWebhookQueue = 256
+
+// QuotaWebhook 将配额事件异步 POST 到配额配置的 Webhook 地址
+//
+// Notify 不阻塞，可直接作为 ProjectQuotas.OnEvent 的回调；队列满时丢弃事件并记录日志。
+type QuotaWebhook struct {
+	// HTTPClient 发送请求使用的客户端，为 nil 时使用 10 秒超时的客户端
+	HTTPClient *http.Client
+
+	queue chan QuotaEvent
+	log   logger.Logger
+}
+
+// NewQuotaWebhook 创建配额事件通知器，queue 为 0 时使用默认队列长度
+func NewQuotaWebhook(queue int) *QuotaWebhook {
+	if queue <= 0 {
+		queue = defaultQuotaWebhookQueue
+	}
+	return &QuotaWebhook{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan QuotaEvent, queue),
+		log:        logger.Default(),
+	}
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (w *QuotaWebhook) SetLogger(l logger.Logger) {
+	w.log = logger.OrDefault(l)
+}
+
+// Notify 将事件加入发送队列，配额未配置 Webhook 时忽略
+func (w *QuotaWebhook) Notify(e QuotaEvent) {
+	if e.Limits.Webhook == "" {
+		return
+	}
+	select {
+	case w.queue <- e:
+	default:
+		w.log.Warn("quota webhook queue full, dropping event",
+			zap.Uint32("project", e.Project), zap.String("type", string(e.Type)))
+	}
+}
+
+// Run 发送队列中的事件直到 ctx 被取消，发送失败只记录日志
+func (w *QuotaWebhook) Run(ctx context.Context) {
+	for {
+		select {
+		case e := <-w.queue:
+			if err := w.send(ctx, e); err != nil {
+				w.log.Warn("failed to deliver quota event",
+					zap.Uint32("project", e.Project), zap.String("type", string(e.Type)), zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send 以 JSON 格式 POST 单个事件
+func (w *QuotaWebhook) send(ctx context.Context, e QuotaEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode quota event: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Limits.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
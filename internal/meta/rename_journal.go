@@ -0,0 +1,109 @@
+package meta
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultRenameJournalSize 重命名日志保留的最大操作数
+	DefaultRenameJournalSize = 10000
+	// DefaultRenameJournalTTL 重命名日志中操作的保留时长，客户端应在此之前完成重试
+	DefaultRenameJournalTTL = 10 * time.Minute
+)
+
+// renameRecord 一次已完成的重命名
+type renameRecord struct {
+	id       string
+	from, to string
+	done     time.Time
+}
+
+// renameJournal 按操作 ID 记录最近完成的重命名，超过容量或保留时长的记录按完成顺序淘汰
+type renameJournal struct {
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	records map[string]*list.Element
+	order   *list.List // 最早完成的在前
+}
+
+// newRenameJournal 创建重命名日志
+func newRenameJournal(size int, ttl time.Duration) *renameJournal {
+	return &renameJournal{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		records: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// lookup 返回操作 ID 对应的未过期记录
+func (j *renameJournal) lookup(id string) (*renameRecord, bool) {
+	j.expire()
+	elem, ok := j.records[id]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*renameRecord), true
+}
+
+// add 记录一次完成的重命名
+func (j *renameJournal) add(id, from, to string) {
+	j.records[id] = j.order.PushBack(&renameRecord{id: id, from: from, to: to, done: j.now()})
+	for j.order.Len() > j.size {
+		j.remove(j.order.Front())
+	}
+}
+
+// expire 淘汰超过保留时长的记录
+func (j *renameJournal) expire() {
+	cutoff := j.now().Add(-j.ttl)
+	for elem := j.order.Front(); elem != nil && elem.Value.(*renameRecord).done.Before(cutoff); elem = j.order.Front() {
+		j.remove(elem)
+	}
+}
+
+// remove 删除一条记录
+func (j *renameJournal) remove(elem *list.Element) {
+	j.order.Remove(elem)
+	delete(j.records, elem.Value.(*renameRecord).id)
+}
+
+// reset 清空全部记录
+func (j *renameJournal) reset() {
+	j.records = make(map[string]*list.Element)
+	j.order.Init()
+}
+
+// RenameOnce 以操作 ID 执行可安全重试的重命名
+//
+// 客户端为每次逻辑上的重命名生成唯一的 opID，超时后用同一个 opID 重试。重命名完成后记录在日志中，
+// 保留期内的重试被识别为已执行并直接返回成功，而不是因为源路径已不存在而失败。
+// 同一个 opID 用于不同的路径时返回错误。opID 为空时等同于 Rename。
+func (s *MemoryStore) RenameOnce(ctx context.Context, opID, oldPath, newPath string) error {
+	unlock, err := s.lockFenced(ctx, oldPath, newPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	from, to := s.locateEntry(normalizePath(oldPath)), s.locateEntry(normalizePath(newPath))
+	if opID == "" {
+		return s.rename(ctx, from, to)
+	}
+	if record, ok := s.renames.lookup(opID); ok {
+		if record.from != from || record.to != to {
+			return fmt.Errorf("rename operation %s was used for %s -> %s", opID, record.from, record.to)
+		}
+		return nil
+	}
+	if err := s.rename(ctx, from, to); err != nil {
+		return err
+	}
+	s.renames.add(opID, from, to)
+	return nil
+}
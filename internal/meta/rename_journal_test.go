@@ -0,0 +1,54 @@
+package meta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameOnce(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_, err := store.Create(ctx, "/a", 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RenameOnce(ctx, "op-1", "/a", "/b"))
+	// 超时后的重试被识别为已执行
+	require.NoError(t, store.RenameOnce(ctx, "op-1", "/a", "/b"))
+	_, err = store.Get(ctx, "/b")
+	assert.NoError(t, err)
+
+	// 同一个操作 ID 不能用于其他路径
+	assert.Error(t, store.RenameOnce(ctx, "op-1", "/b", "/c"))
+	// 没有操作 ID 的重试照常失败
+	assert.Error(t, store.RenameOnce(ctx, "", "/a", "/b"))
+
+	// 失败的重命名不记录，修正后可以用同一个操作 ID 重试
+	assert.Error(t, store.RenameOnce(ctx, "op-2", "/missing", "/c"))
+	_, err = store.Create(ctx, "/missing", 0644)
+	require.NoError(t, err)
+	assert.NoError(t, store.RenameOnce(ctx, "op-2", "/missing", "/c"))
+}
+
+func TestRenameJournalEviction(t *testing.T) {
+	j := newRenameJournal(2, time.Minute)
+	now := time.Unix(1700000000, 0)
+	j.now = func() time.Time { return now }
+
+	j.add("op-1", "/a", "/b")
+	j.add("op-2", "/b", "/c")
+	j.add("op-3", "/c", "/d")
+	_, ok := j.lookup("op-1")
+	assert.False(t, ok)
+	record, ok := j.lookup("op-2")
+	require.True(t, ok)
+	assert.Equal(t, "/c", record.to)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = j.lookup("op-3")
+	assert.False(t, ok)
+	assert.Zero(t, j.order.Len())
+}
@@ -0,0 +1,86 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// hasChildren 判断目录是否有子条目，调用方需持有锁
+func (s *MemoryStore) hasChildren(dirPath string) bool {
+	return s.tree.hasChildren(dirPath)
+}
+
+// Rmdir 删除空目录，目录非空时返回 ErrNotEmpty
+func (s *MemoryStore) Rmdir(ctx context.Context, p string) error {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dirPath := s.locateEntry(normalizePath(p))
+	if dirPath == "/" {
+		return fmt.Errorf("cannot remove root directory")
+	}
+	meta, exists := s.tree.get(dirPath)
+	if !exists {
+		return fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if meta.Type == TypeBind {
+		return fmt.Errorf("%w: %s is a bind point", ErrBusy, dirPath)
+	}
+	if meta.Type != TypeDirectory {
+		return fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+	if s.hasChildren(dirPath) {
+		return fmt.Errorf("%w: %s", ErrNotEmpty, dirPath)
+	}
+
+	if _, err := s.unlink(ctx, dirPath, meta); err != nil {
+		return err
+	}
+	s.recordChange(ctx, ChangeDelete, dirPath, meta)
+	return nil
+}
+
+// DeleteAll 在一次加锁内删除路径及其全部后代，其他操作看不到删除了一半的子树
+//
+// 后代先于祖先删除，每个条目产生一条删除事件。超大子树会长时间持有写锁，
+// 应改用分批删除的 DeleteTree。
+func (s *MemoryStore) DeleteAll(ctx context.Context, p string) error {
+	// 删除子树中有硬链接的文件会改写它们在子树外的链接，一并登记
+	unlock, err := s.lockFencedWith(ctx, func() []string {
+		return append(s.fencePaths([]string{p}), s.subtreeLinkPaths(s.locateEntry(normalizePath(p)))...)
+	})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	root := s.locateEntry(normalizePath(p))
+	if root == "/" {
+		return fmt.Errorf("cannot delete root directory")
+	}
+	rootMeta, exists := s.tree.get(root)
+	if !exists {
+		return fmt.Errorf("file not found: %s", root)
+	}
+	if rootMeta.Type == TypeBind {
+		return fmt.Errorf("%w: %s is a bind point", ErrBusy, root)
+	}
+	if err := s.checkNoZoneBelow(root); err != nil {
+		return err
+	}
+	paths := append([]string{root}, s.tree.descendants(root)...)
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	for _, filePath := range paths {
+		meta := s.tree.entry(filePath)
+		if _, err := s.unlink(ctx, filePath, meta); err != nil {
+			return err
+		}
+		s.recordChange(ctx, ChangeDelete, filePath, meta)
+	}
+	return nil
+}
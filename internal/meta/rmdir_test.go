@@ -0,0 +1,55 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRmdir(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/d", 0755))
+	_, err := store.Create(ctx, "/d/f", 0644)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, store.Rmdir(ctx, "/d"), ErrNotEmpty)
+	assert.ErrorIs(t, store.Delete(ctx, "/d"), ErrNotEmpty)
+	assert.Error(t, store.Rmdir(ctx, "/d/f"))
+	assert.Error(t, store.Rmdir(ctx, "/"))
+
+	require.NoError(t, store.Delete(ctx, "/d/f"))
+	require.NoError(t, store.Rmdir(ctx, "/d"))
+	_, err = store.Get(ctx, "/d")
+	assert.Error(t, err)
+}
+
+func TestDeleteAll(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	for _, dir := range []string{"/d", "/d/sub", "/dx"} {
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+	}
+	for _, p := range []string{"/d/a", "/d/sub/b", "/dx/c"} {
+		_, err := store.Create(ctx, p, 0644)
+		require.NoError(t, err)
+	}
+	// 子树外的硬链接在删除后保留
+	require.NoError(t, store.Link(ctx, "/d/a", "/dx/a"))
+
+	require.NoError(t, store.DeleteAll(ctx, "/d"))
+	for _, p := range []string{"/d", "/d/a", "/d/sub", "/d/sub/b"} {
+		_, err := store.Get(ctx, p)
+		assert.Error(t, err, p)
+	}
+	m, err := store.Get(ctx, "/dx/a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.Links)
+	_, err = store.Get(ctx, "/dx/c")
+	assert.NoError(t, err)
+
+	assert.Error(t, store.DeleteAll(ctx, "/d"))
+	assert.Error(t, store.DeleteAll(ctx, "/"))
+}
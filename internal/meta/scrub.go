@@ -0,0 +1,202 @@
+package meta
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// BlockReader 从指定数据服务器读取数据块的一个副本
+type BlockReader interface {
+	ReadBlock(ctx context.Context, location string, block Block) ([]byte, error)
+}
+
+// ScrubProblemKind 校验发现的问题类型
+type ScrubProblemKind string
+
+const (
+	ScrubNoReplicas       ScrubProblemKind = "no_replicas"       // 数据块没有任何副本位置
+	ScrubReadError        ScrubProblemKind = "read_error"        // 副本读取失败
+	ScrubSizeMismatch     ScrubProblemKind = "size_mismatch"     // 副本长度与元数据不符
+	ScrubChecksumMismatch ScrubProblemKind = "checksum_mismatch" // 副本内容与元数据中的校验和不符
+	ScrubReplicaMismatch  ScrubProblemKind = "replica_mismatch"  // 没有校验和时副本内容与多数副本不一致
+)
+
+// ScrubProblem 单个副本或数据块的问题
+type ScrubProblem struct {
+	Path     string           `json:"path"`
+	BlockID  string           `json:"block_id"`
+	Location string           `json:"location,omitempty"` // 出问题的副本位置，整个数据块的问题为空
+	Kind     ScrubProblemKind `json:"kind"`
+	Detail   string           `json:"detail,omitempty"`
+}
+
+// ScrubReport 校验结果
+type ScrubReport struct {
+	Path     string         `json:"path"`
+	Files    int            `json:"files"`
+	Blocks   int            `json:"blocks"`
+	Replicas int            `json:"replicas"` // 成功读取的副本数
+	Bytes    int64          `json:"bytes"`    // 成功读取的字节数
+	Problems []ScrubProblem `json:"problems,omitempty"`
+	Elapsed  time.Duration  `json:"elapsed"`
+}
+
+// OK 判断是否所有数据块的全部副本都完好，可以安全删除原始副本
+func (r *ScrubReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// ScrubOptions VerifyPath 的选项
+type ScrubOptions struct {
+	// Checksum 计算数据块校验和，结果与 Block.Checksum 比较，为 nil 时使用 SHA-256 十六进制编码
+	Checksum func(data []byte) string
+}
+
+// sha256Checksum 默认的数据块校验和
+func sha256Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// scrubFile 待校验的文件及其数据块
+type scrubFile struct {
+	path   string
+	blocks []Block
+}
+
+// scrubTargets 收集路径下全部普通文件的数据块，按路径排序
+func (s *MemoryStore) scrubTargets(ctx context.Context, root string) ([]scrubFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var files []scrubFile
+	for p, meta := range s.tree.entries(root) {
+		if meta.Type != TypeRegular {
+			continue
+		}
+		blocks, err := s.fileBlocks(ctx, meta)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, scrubFile{path: p, blocks: blocks})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files, nil
+}
+
+// isWithin 判断 p 是否为 root 本身或其后代
+func isWithin(p, root string) bool {
+	if root == "/" || p == root {
+		return true
+	}
+	return len(p) > len(root) && p[:len(root)] == root && p[len(root)] == '/'
+}
+
+// VerifyPath 读取文件或子树中每个数据块的全部副本，校验长度、校验和以及副本之间的一致性
+//
+// 只读操作，不修复任何副本。元数据在开始时取快照，校验期间的写入不影响结果；
+// 适合在导入完成后、删除原始数据之前确认数据完整。
+func (s *MemoryStore) VerifyPath(ctx context.Context, p string, reader BlockReader, opts ScrubOptions) (*ScrubReport, error) {
+	root := normalizePath(p)
+	if _, err := s.Get(ctx, root); err != nil {
+		return nil, err
+	}
+	if opts.Checksum == nil {
+		opts.Checksum = sha256Checksum
+	}
+
+	files, err := s.scrubTargets(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	report := &ScrubReport{Path: root}
+	for _, f := range files {
+		report.Files++
+		for _, block := range f.blocks {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			report.Blocks++
+			scrubBlock(ctx, report, f.path, block, reader, opts.Checksum)
+		}
+	}
+	report.Elapsed = time.Since(start)
+
+	s.log.Info("Verified path",
+		zap.String("path", root),
+		zap.Int("files", report.Files),
+		zap.Int("blocks", report.Blocks),
+		zap.Int("problems", len(report.Problems)),
+		zap.Duration("elapsed", report.Elapsed),
+	)
+	return report, nil
+}
+
+// scrubBlock 读取并校验一个数据块的全部副本，问题追加到报告中
+func scrubBlock(ctx context.Context, report *ScrubReport, filePath string, block Block, reader BlockReader, checksum func([]byte) string) {
+	problem := func(location string, kind ScrubProblemKind, detail string) {
+		report.Problems = append(report.Problems, ScrubProblem{
+			Path: filePath, BlockID: block.ID, Location: location, Kind: kind, Detail: detail,
+		})
+	}
+	if len(block.Locations) == 0 {
+		problem("", ScrubNoReplicas, "")
+		return
+	}
+
+	// 读取成功的副本的校验和，用于没有记录校验和时的多数比较
+	sums := make(map[string]string, len(block.Locations))
+	votes := make(map[string]int)
+	for _, location := range block.Locations {
+		data, err := reader.ReadBlock(ctx, location, block)
+		if err != nil {
+			problem(location, ScrubReadError, err.Error())
+			continue
+		}
+		report.Replicas++
+		report.Bytes += int64(len(data))
+		if int64(len(data)) != block.Size {
+			problem(location, ScrubSizeMismatch, fmt.Sprintf("expected %d bytes, read %d", block.Size, len(data)))
+			continue
+		}
+		sum := checksum(data)
+		if block.Checksum != "" {
+			if sum != block.Checksum {
+				problem(location, ScrubChecksumMismatch, fmt.Sprintf("expected %s, got %s", block.Checksum, sum))
+			}
+			continue
+		}
+		sums[location] = sum
+		votes[sum]++
+	}
+
+	if len(votes) <= 1 {
+		return
+	}
+	// 得票最多的内容视为正确，票数相同时无法判断，所有副本都报告
+	best, tie := "", false
+	for sum, n := range votes {
+		switch {
+		case best == "" || n > votes[best]:
+			best, tie = sum, false
+		case n == votes[best]:
+			tie = true
+		}
+	}
+	for _, location := range block.Locations {
+		sum, ok := sums[location]
+		if ok && (tie || sum != best) {
+			problem(location, ScrubReplicaMismatch, fmt.Sprintf("replica checksum %s", sum))
+		}
+	}
+}
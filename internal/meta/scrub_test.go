@@ -0,0 +1,79 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replicaReader 按 位置/块ID 返回副本内容的测试读取器
+type replicaReader map[string][]byte
+
+func (r replicaReader) ReadBlock(ctx context.Context, location string, block Block) ([]byte, error) {
+	data, ok := r[location+"/"+block.ID]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return data, nil
+}
+
+func TestVerifyPath(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/ingest", 0755))
+
+	good := []byte("hello")
+	bad := []byte("hellO")
+	f, err := store.Create(ctx, "/ingest/a", 0644)
+	require.NoError(t, err)
+	f.Size = 10
+	f.Blocks = []Block{
+		{ID: "b1", Size: 5, Offset: 0, Checksum: sha256Checksum(good), Locations: []string{"d1", "d2"}},
+		{ID: "b2", Size: 5, Offset: 5, Locations: []string{"d1", "d2", "d3"}},
+	}
+	require.NoError(t, store.Update(ctx, "/ingest/a", f))
+	_, err = store.Create(ctx, "/other", 0644)
+	require.NoError(t, err)
+
+	reader := replicaReader{
+		"d1/b1": good, "d2/b1": good,
+		"d1/b2": good, "d2/b2": good, "d3/b2": good,
+	}
+	report, err := store.VerifyPath(ctx, "/ingest", reader, ScrubOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%+v", report.Problems)
+	assert.Equal(t, 1, report.Files)
+	assert.Equal(t, 2, report.Blocks)
+	assert.Equal(t, 5, report.Replicas)
+
+	reader["d2/b1"] = bad
+	reader["d3/b2"] = bad
+	delete(reader, "d1/b2")
+	report, err = store.VerifyPath(ctx, "/ingest/a", reader, ScrubOptions{})
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, []ScrubProblem{
+		{Path: "/ingest/a", BlockID: "b1", Location: "d2", Kind: ScrubChecksumMismatch,
+			Detail: "expected " + sha256Checksum(good) + ", got " + sha256Checksum(bad)},
+		{Path: "/ingest/a", BlockID: "b2", Location: "d1", Kind: ScrubReadError, Detail: "connection refused"},
+		{Path: "/ingest/a", BlockID: "b2", Location: "d2", Kind: ScrubReplicaMismatch, Detail: "replica checksum " + sha256Checksum(good)},
+		{Path: "/ingest/a", BlockID: "b2", Location: "d3", Kind: ScrubReplicaMismatch, Detail: "replica checksum " + sha256Checksum(bad)},
+	}, report.Problems, "two differing replicas cannot be resolved without a checksum")
+
+	reader["d1/b2"] = good
+	reader["d2/b1"] = good[:4]
+	report, err = store.VerifyPath(ctx, "/", reader, ScrubOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Files)
+	if assert.Len(t, report.Problems, 2) {
+		assert.Equal(t, ScrubSizeMismatch, report.Problems[0].Kind)
+		assert.Equal(t, ScrubProblem{Path: "/ingest/a", BlockID: "b2", Location: "d3", Kind: ScrubReplicaMismatch,
+			Detail: "replica checksum " + sha256Checksum(bad)}, report.Problems[1])
+	}
+
+	_, err = store.VerifyPath(ctx, "/missing", reader, ScrubOptions{})
+	assert.Error(t, err)
+}
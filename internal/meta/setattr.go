@@ -0,0 +1,65 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// settableModeBits chmod 可以修改的模式位，文件类型位保持不变
+const settableModeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// SetAttr 修改 mask 指定的属性并返回修改后的元数据副本
+//
+// 与 Update 不同，调用方不需要先读取完整的元数据，未指定的属性保持不变，
+// 因此不会覆盖并发修改的数据块或其他属性。修改时间只在 mask 包含 SetAttrModifyTime 时改变。
+// 符号链接的权限位固定，不能 chmod。
+func (s *MemoryStore) SetAttr(ctx context.Context, p string, attrs Attrs, mask SetAttrMask) (*Metadata, error) {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	filePath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	if mask == 0 {
+		return current.Clone(), nil
+	}
+	if mask&SetAttrMode != 0 && current.Type == TypeSymlink {
+		return nil, fmt.Errorf("cannot change mode of symlink: %s", filePath)
+	}
+
+	updated := current.Clone()
+	if mask&SetAttrMode != 0 {
+		updated.Mode = current.Mode&^settableModeBits | attrs.Mode&settableModeBits
+	}
+	if mask&SetAttrOwner != 0 {
+		updated.Owner = s.interner.Intern(attrs.Owner)
+	}
+	if mask&SetAttrGroup != 0 {
+		updated.Group = s.interner.Intern(attrs.Group)
+	}
+	if mask&SetAttrAccessTime != 0 {
+		updated.AccessTime = attrs.AccessTime
+	}
+	if mask&SetAttrModifyTime != 0 {
+		updated.ModifyTime = attrs.ModifyTime
+	}
+	// chown 把用量转到新的所有者和组，超出其配额时拒绝
+	if updated.Owner != current.Owner || updated.Group != current.Group {
+		if err := s.chargeQuota(filePath, current, updated); err != nil {
+			return nil, err
+		}
+	}
+	updated.Version++
+	s.put(filePath, updated)
+	if filePath == "/" {
+		s.root = updated
+	}
+	s.recordChange(ctx, ChangeAttr, filePath, updated)
+	return updated.Clone(), nil
+}
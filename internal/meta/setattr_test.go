@@ -0,0 +1,53 @@
+package meta
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAttr(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	created, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+
+	// chmod 只修改权限位，不改变修改时间
+	m, err := store.SetAttr(ctx, "/f", Attrs{Mode: 0600 | os.ModeSetuid | os.ModeDir}, SetAttrMode)
+	require.NoError(t, err)
+	assert.Equal(t, 0600|os.ModeSetuid, m.Mode)
+	assert.Equal(t, created.ModifyTime, m.ModifyTime)
+	assert.Equal(t, created.Version+1, m.Version)
+
+	m, err = store.SetAttr(ctx, "/f", Attrs{Owner: "alice", Group: "staff"}, SetAttrOwner|SetAttrGroup)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", m.Owner)
+	assert.Equal(t, "staff", m.Group)
+	assert.Equal(t, 0600|os.ModeSetuid, m.Mode)
+
+	mtime := time.Unix(1600000000, 0)
+	atime := time.Unix(1600000100, 0)
+	_, err = store.SetAttr(ctx, "/f", Attrs{AccessTime: atime, ModifyTime: mtime}, SetAttrAccessTime|SetAttrModifyTime)
+	require.NoError(t, err)
+	stored, err := store.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.True(t, stored.ModifyTime.Equal(mtime))
+	assert.Equal(t, created.Version+3, stored.Version)
+
+	// 目录保留类型位
+	require.NoError(t, store.Mkdir(ctx, "/d", 0755))
+	m, err = store.SetAttr(ctx, "/d", Attrs{Mode: 0700}, SetAttrMode)
+	require.NoError(t, err)
+	assert.True(t, m.Mode.IsDir())
+	assert.Equal(t, os.FileMode(0700), m.Mode.Perm())
+
+	require.NoError(t, store.Symlink(ctx, "/f", "/l"))
+	_, err = store.SetAttr(ctx, "/l", Attrs{Mode: 0600}, SetAttrMode)
+	assert.Error(t, err)
+	_, err = store.SetAttr(ctx, "/missing", Attrs{}, SetAttrMode)
+	assert.Error(t, err)
+}
@@ -0,0 +1,256 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrSharedHandleClosed 共享句柄已提交、中止或不存在，可用 errors.Is 判断
+var ErrSharedHandleClosed = errors.New("shared file handle is closed")
+
+// SharedWriteStore 集合写入使用的元数据操作
+type SharedWriteStore interface {
+	Get(ctx context.Context, path string) (*Metadata, error)
+	GetBlockRange(ctx context.Context, path string, offset, length int64) ([]Block, error)
+	UpdateIf(ctx context.Context, path string, meta *Metadata, expectedVersion uint64) error
+}
+
+// byteRange 文件内 [offset, end) 字节范围
+type byteRange struct {
+	rank   int
+	offset int64
+	end    int64
+}
+
+// commitResult 集合提交的结果，所有 rank 共享
+type commitResult struct {
+	meta *Metadata
+	err  error
+}
+
+// sharedHandle 一个文件的共享写入句柄
+type sharedHandle struct {
+	path      string
+	ranks     int
+	version   uint64 // 打开时文件的版本，提交时文件必须未被修改
+	ranges    []byteRange
+	staged    [][]Block
+	committed map[int]bool
+	done      chan struct{}
+	result    commitResult
+}
+
+// SharedFiles 面向 MPI-IO 集合 IO 的共享文件句柄
+//
+// 一个 rank 调用 OpenShared 得到句柄纪元并广播给其他 rank；各 rank 预留互不重叠的字节范围，
+// 把写入数据服务器的数据块暂存到句柄中，最后都调用 Commit。最后一个 rank 提交时，
+// 所有 rank 的数据块在一次元数据更新中发布，其他 rank 在此之前看不到任何部分写入。
+type SharedFiles struct {
+	store SharedWriteStore
+
+	mu      sync.Mutex
+	next    uint64
+	handles map[uint64]*sharedHandle
+}
+
+// NewSharedFiles 创建共享文件句柄表
+func NewSharedFiles(store SharedWriteStore) *SharedFiles {
+	return &SharedFiles{store: store, handles: make(map[uint64]*sharedHandle)}
+}
+
+// OpenShared 为 ranks 个进程打开文件的共享写入句柄，返回句柄纪元
+func (f *SharedFiles) OpenShared(ctx context.Context, p string, ranks int) (uint64, error) {
+	if ranks < 1 {
+		return 0, fmt.Errorf("shared handle needs at least one rank: %d", ranks)
+	}
+	filePath := normalizePath(p)
+	meta, err := f.store.Get(ctx, filePath)
+	if err != nil {
+		return 0, err
+	}
+	if meta.Type != TypeRegular {
+		return 0, fmt.Errorf("path is not a file: %s", filePath)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	f.handles[f.next] = &sharedHandle{
+		path:      filePath,
+		ranks:     ranks,
+		version:   meta.Version,
+		staged:    make([][]Block, ranks),
+		committed: make(map[int]bool),
+		done:      make(chan struct{}),
+	}
+	return f.next, nil
+}
+
+// handleLocked 返回未关闭的句柄并检查 rank，调用方需持有锁
+func (f *SharedFiles) handleLocked(epoch uint64, rank int) (*sharedHandle, error) {
+	h, ok := f.handles[epoch]
+	if !ok {
+		return nil, fmt.Errorf("%w: epoch %d", ErrSharedHandleClosed, epoch)
+	}
+	if rank < 0 || rank >= h.ranks {
+		return nil, fmt.Errorf("rank %d out of range [0, %d)", rank, h.ranks)
+	}
+	return h, nil
+}
+
+// Reserve 为 rank 预留 [offset, offset+length) 字节范围，与其他 rank 的预留重叠时返回错误
+//
+// 提交时与预留范围相交的原有数据块整体被替换，因此覆盖已有数据时范围应与块边界对齐。
+func (f *SharedFiles) Reserve(epoch uint64, rank int, offset, length int64) error {
+	if offset < 0 || length <= 0 {
+		return fmt.Errorf("invalid range: offset %d, length %d", offset, length)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, err := f.handleLocked(epoch, rank)
+	if err != nil {
+		return err
+	}
+	r := byteRange{rank: rank, offset: offset, end: offset + length}
+	for _, other := range h.ranges {
+		if other.offset < r.end && r.offset < other.end {
+			return fmt.Errorf("range [%d, %d) overlaps rank %d reservation [%d, %d)",
+				r.offset, r.end, other.rank, other.offset, other.end)
+		}
+	}
+	h.ranges = append(h.ranges, r)
+	return nil
+}
+
+// Stage 暂存 rank 已写入数据服务器的数据块，每个块必须完全位于该 rank 的某个预留范围内
+func (f *SharedFiles) Stage(epoch uint64, rank int, blocks []Block) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, err := f.handleLocked(epoch, rank)
+	if err != nil {
+		return err
+	}
+	if h.committed[rank] {
+		return fmt.Errorf("rank %d has already committed", rank)
+	}
+	for i := range blocks {
+		b := &blocks[i]
+		if !h.reserved(rank, b.Offset, b.End()) {
+			return fmt.Errorf("block %s [%d, %d) is outside rank %d reservations", b.ID, b.Offset, b.End(), rank)
+		}
+	}
+	for _, b := range blocks {
+		b.Locations = append([]string(nil), b.Locations...)
+		h.staged[rank] = append(h.staged[rank], b)
+	}
+	return nil
+}
+
+// reserved 判断 [offset, end) 是否完全位于 rank 的某个预留范围内
+func (h *sharedHandle) reserved(rank int, offset, end int64) bool {
+	for _, r := range h.ranges {
+		if r.rank == rank && r.offset <= offset && end <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// Commit rank 参与集合提交，阻塞到所有 rank 都提交后返回发布后的元数据
+//
+// 最后一个提交的 rank 执行发布：与预留范围重叠的原有数据块被替换，文件大小扩展到最远的写入位置。
+// 文件在打开后被其他写入修改时提交失败并返回 ErrVersionConflict，所有 rank 得到相同的结果。
+// ctx 取消只影响调用方的等待，不会撤销该 rank 的提交。
+func (f *SharedFiles) Commit(ctx context.Context, epoch uint64, rank int) (*Metadata, error) {
+	f.mu.Lock()
+	h, err := f.handleLocked(epoch, rank)
+	if err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
+	h.committed[rank] = true
+	last := len(h.committed) == h.ranks
+	if last {
+		delete(f.handles, epoch)
+	}
+	f.mu.Unlock()
+
+	if last {
+		meta, err := f.publish(ctx, h)
+		h.result = commitResult{meta: meta, err: err}
+		close(h.done)
+	}
+
+	select {
+	case <-h.done:
+		if h.result.err != nil {
+			return nil, h.result.err
+		}
+		return h.result.meta.Clone(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Abort 放弃句柄，等待提交的 rank 得到 ErrSharedHandleClosed，已写入的数据块由调用方回收
+func (f *SharedFiles) Abort(epoch uint64) {
+	f.mu.Lock()
+	h, ok := f.handles[epoch]
+	delete(f.handles, epoch)
+	f.mu.Unlock()
+	if ok {
+		h.result = commitResult{err: fmt.Errorf("%w: epoch %d aborted", ErrSharedHandleClosed, epoch)}
+		close(h.done)
+	}
+}
+
+// publish 将所有 rank 暂存的数据块在一次更新中写入元数据
+func (f *SharedFiles) publish(ctx context.Context, h *sharedHandle) (*Metadata, error) {
+	meta, err := f.store.Get(ctx, h.path)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Version != h.version {
+		return nil, &VersionConflictError{Path: h.path, Expected: h.version, Actual: meta.Version}
+	}
+	existing, err := f.store.GetBlockRange(ctx, h.path, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []Block
+	for _, b := range existing {
+		replaced := false
+		for _, r := range h.ranges {
+			if b.Overlaps(r.offset, r.end) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			blocks = append(blocks, b)
+		}
+	}
+	for _, staged := range h.staged {
+		for i := range staged {
+			blocks = append(blocks, staged[i])
+			meta.Size = max(meta.Size, staged[i].End())
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Offset < blocks[j].Offset
+	})
+
+	meta.Extents = nil
+	meta.Blocks = blocks
+	meta.BlockCount = 0
+	if err := f.store.UpdateIf(ctx, h.path, meta, h.version); err != nil {
+		return nil, err
+	}
+	return f.store.Get(ctx, h.path)
+}
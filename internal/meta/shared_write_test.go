@@ -0,0 +1,103 @@
+package meta
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedFilesCollectiveCommit(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	f, err := store.Create(ctx, "/out", 0644)
+	require.NoError(t, err)
+	f.Size = 4
+	f.Blocks = []Block{{ID: "old", Offset: 0, Size: 4, Locations: []string{"d1"}}}
+	require.NoError(t, store.Update(ctx, "/out", f))
+
+	shared := NewSharedFiles(store)
+	epoch, err := shared.OpenShared(ctx, "/out", 3)
+	require.NoError(t, err)
+
+	for rank := 0; rank < 3; rank++ {
+		require.NoError(t, shared.Reserve(epoch, rank, int64(rank)*4, 4))
+	}
+	assert.Error(t, shared.Reserve(epoch, 1, 6, 4), "ranges of different ranks must not overlap")
+	assert.Error(t, shared.Reserve(epoch, 3, 100, 4), "rank out of range")
+	assert.Error(t, shared.Stage(epoch, 0, []Block{{ID: "x", Offset: 2, Size: 4}}), "block outside reservation")
+
+	results := make([]*Metadata, 3)
+	var wg sync.WaitGroup
+	for rank := 0; rank < 3; rank++ {
+		require.NoError(t, shared.Stage(epoch, rank, []Block{
+			{ID: "r" + string(rune('0'+rank)), Offset: int64(rank) * 4, Size: 4, Locations: []string{"d2"}},
+		}))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meta, err := shared.Commit(ctx, epoch, rank)
+			assert.NoError(t, err)
+			results[rank] = meta
+		}()
+		if rank < 2 {
+			// 其他 rank 提交前什么都不发布
+			time.Sleep(5 * time.Millisecond)
+			current, err := store.Get(ctx, "/out")
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), current.Size)
+		}
+	}
+	wg.Wait()
+
+	for _, meta := range results {
+		require.NotNil(t, meta)
+		assert.Equal(t, int64(12), meta.Size)
+	}
+	blocks, err := store.GetBlockRange(ctx, "/out", 0, 0)
+	require.NoError(t, err)
+	ids := make([]string, len(blocks))
+	for i, b := range blocks {
+		ids[i] = b.ID
+	}
+	assert.Equal(t, []string{"r0", "r1", "r2"}, ids)
+
+	_, err = shared.Commit(ctx, epoch, 0)
+	assert.ErrorIs(t, err, ErrSharedHandleClosed)
+}
+
+func TestSharedFilesConflictAndAbort(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	_, err := store.Create(ctx, "/out", 0644)
+	require.NoError(t, err)
+	shared := NewSharedFiles(store)
+
+	epoch, err := shared.OpenShared(ctx, "/out", 1)
+	require.NoError(t, err)
+	f, err := store.Get(ctx, "/out")
+	require.NoError(t, err)
+	f.Size = 1
+	require.NoError(t, store.Update(ctx, "/out", f))
+	_, err = shared.Commit(ctx, epoch, 0)
+	assert.ErrorIs(t, err, ErrVersionConflict, "the file changed outside the collective write")
+
+	epoch, err = shared.OpenShared(ctx, "/out", 2)
+	require.NoError(t, err)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := shared.Commit(ctx, epoch, 0)
+		errs <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	shared.Abort(epoch)
+	assert.ErrorIs(t, <-errs, ErrSharedHandleClosed)
+
+	_, err = shared.OpenShared(ctx, "/out", 0)
+	assert.Error(t, err)
+	_, err = shared.OpenShared(ctx, "/missing", 1)
+	assert.Error(t, err)
+}
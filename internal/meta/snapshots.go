@@ -0,0 +1,134 @@
+package meta
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrSnapshotNotFound 快照不存在，可用 errors.Is 判断
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotInfo 目录快照的描述
+type SnapshotInfo struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"`
+	Entries int       `json:"entries"` // 快照包含的条目数量
+}
+
+// storeSnapshot 内存存储保存的快照，内容为创建时刻的清单
+type storeSnapshot struct {
+	info     SnapshotInfo
+	manifest *Manifest
+}
+
+// newSnapshotID 生成随机快照ID，进程重启后也不会与调用方保存的旧ID重复
+func newSnapshotID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate snapshot id: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// CreateSnapshot 为 p 子树创建快照，返回快照ID
+//
+// 快照以清单的形式固定子树在同一时刻的状态，可用 SnapshotManifest 取出并与其他快照比较。
+// 与历史版本一样只保存元数据，不阻止数据块被回收；快照只保存在内存中。
+func (s *MemoryStore) CreateSnapshot(ctx context.Context, p string) (string, error) {
+	manifest, err := s.BuildManifest(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	id, err := newSnapshotID()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	if s.snapshots == nil {
+		s.snapshots = make(map[string]*storeSnapshot)
+	}
+	s.snapshots[id] = &storeSnapshot{
+		info: SnapshotInfo{
+			ID:      id,
+			Path:    manifest.Root,
+			Created: manifest.Created,
+			Entries: len(manifest.Entries),
+		},
+		manifest: manifest,
+	}
+	s.mu.Unlock()
+
+	s.log.Info("Created snapshot",
+		zap.String("path", manifest.Root),
+		zap.String("id", id),
+		zap.Int("entries", len(manifest.Entries)),
+	)
+	return id, nil
+}
+
+// DeleteSnapshot 删除快照，快照不存在时返回 ErrSnapshotNotFound
+func (s *MemoryStore) DeleteSnapshot(ctx context.Context, snapshotID string) error {
+	s.mu.Lock()
+	snap, ok := s.snapshots[snapshotID]
+	if ok {
+		delete(s.snapshots, snapshotID)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, snapshotID)
+	}
+	s.log.Info("Deleted snapshot",
+		zap.String("path", snap.info.Path),
+		zap.String("id", snapshotID),
+	)
+	return nil
+}
+
+// ListSnapshots 返回 p 目录的快照，按创建时间排序；p 为空时返回全部快照
+func (s *MemoryStore) ListSnapshots(ctx context.Context, p string) []SnapshotInfo {
+	root := ""
+	if p != "" {
+		root = normalizePath(p)
+	}
+
+	s.mu.RLock()
+	var infos []SnapshotInfo
+	for _, snap := range s.snapshots {
+		if root == "" || snap.info.Path == root {
+			infos = append(infos, snap.info)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Created.Equal(infos[j].Created) {
+			return infos[i].Created.Before(infos[j].Created)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// SnapshotManifest 返回快照的清单，快照不存在时返回 ErrSnapshotNotFound
+func (s *MemoryStore) SnapshotManifest(ctx context.Context, snapshotID string) (*Manifest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap, ok := s.snapshots[snapshotID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, snapshotID)
+	}
+	manifest := *snap.manifest
+	manifest.Entries = append([]ManifestEntry(nil), snap.manifest.Entries...)
+	return &manifest, nil
+}
@@ -0,0 +1,53 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreSnapshots(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/data", 0755))
+	_, err := store.CreateWithData(ctx, "/data/a.txt", 0644, []byte("v1"))
+	require.NoError(t, err)
+
+	first, err := store.CreateSnapshot(ctx, "/data/")
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(ctx, "/data/a.txt"))
+	_, err = store.CreateWithData(ctx, "/data/b.txt", 0644, []byte("v2"))
+	require.NoError(t, err)
+	second, err := store.CreateSnapshot(ctx, "/data")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	_, err = store.CreateSnapshot(ctx, "/missing")
+	assert.Error(t, err)
+
+	// 快照固定创建时刻的状态，之后的修改不影响已有快照
+	a, err := store.SnapshotManifest(ctx, first)
+	require.NoError(t, err)
+	b, err := store.SnapshotManifest(ctx, second)
+	require.NoError(t, err)
+	diff, err := DiffSnapshots(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.txt"}, diff.Created)
+	assert.Equal(t, []string{"a.txt"}, diff.Deleted)
+
+	infos := store.ListSnapshots(ctx, "/data")
+	require.Len(t, infos, 2)
+	assert.Equal(t, first, infos[0].ID)
+	assert.Equal(t, "/data", infos[0].Path)
+	assert.Equal(t, 2, infos[0].Entries)
+	assert.Empty(t, store.ListSnapshots(ctx, "/other"))
+	assert.Len(t, store.ListSnapshots(ctx, ""), 2)
+
+	require.NoError(t, store.DeleteSnapshot(ctx, first))
+	assert.ErrorIs(t, store.DeleteSnapshot(ctx, first), ErrSnapshotNotFound)
+	_, err = store.SnapshotManifest(ctx, first)
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+	assert.Len(t, store.ListSnapshots(ctx, "/data"), 1)
+}
@@ -4,13 +4,16 @@ import (
 	"context"
 	"os"
 	"time"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/metrics"
 )
 
 // Storage 定义存储接口
 type Storage interface {
 	// Save 保存数据
 	Save(ctx context.Context, key string, data []byte) error
-	// Load 加载数据
+	// Load 加载数据，键不存在时返回 ErrKeyNotFound
 	Load(ctx context.Context, key string) ([]byte, error)
 	// Delete 删除数据
 	Delete(ctx context.Context, key string) error
@@ -30,6 +33,18 @@ type StorageConfig struct {
 	FileMode os.FileMode
 	// 是否启用压缩
 	EnableCompression bool
+	// 缓存一致性校验间隔，为 0 时不启用后台校验
+	VerifyInterval time.Duration
+	// 每次校验抽样的键数量，为 0 时校验全部缓存键
+	VerifySampleSize int
+	// 发现缓存与磁盘不一致时是否以磁盘内容重新加载，否则仅告警
+	VerifyReload bool
+	// 是否监听存储根目录的带外修改并重新加载受影响的缓存
+	WatchRootDir bool
+	// 运行指标注册表，为 nil 时使用实例私有的注册表
+	Metrics *metrics.Registry
+	// 日志，为 nil 时使用全局日志
+	Logger logger.Logger
 }
 
 // DefaultStorageConfig 返回默认配置
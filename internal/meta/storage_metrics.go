@@ -0,0 +1,117 @@
+package meta
+
+import (
+	"time"
+
+	"cpfs/internal/metrics"
+)
+
+// 存储操作名称，用作延迟指标的 op 标签
+const (
+	opSave   = "save"
+	opLoad   = "load"
+	opDelete = "delete"
+	opList   = "list"
+)
+
+// storageOps 记录延迟的存储操作
+var storageOps = []string{opSave, opLoad, opDelete, opList}
+
+// StorageStats FileStorage 运行统计
+type StorageStats struct {
+	Hits         uint64                               // 缓存命中次数
+	Misses       uint64                               // 缓存未命中次数
+	HitRatio     float64                              // 缓存命中率
+	CachedKeys   int                                  // 缓存键数量
+	CachedBytes  int64                                // 缓存数据字节数
+	DirtyKeys    int                                  // 待同步键数量
+	SyncedKeys   uint64                               // 累计同步到磁盘的键数量
+	SyncDuration metrics.HistogramSnapshot            // 同步耗时分布
+	OpLatency    map[string]metrics.HistogramSnapshot // 各操作耗时分布
+}
+
+// storageMetrics FileStorage 的指标集合
+type storageMetrics struct {
+	hits         *metrics.Counter
+	misses       *metrics.Counter
+	syncedKeys   *metrics.Counter
+	syncDuration *metrics.Histogram
+	ops          map[string]*metrics.Histogram
+}
+
+// newStorageMetrics 在注册表中注册 FileStorage 的指标，以存储根目录作为 root 标签区分实例
+func newStorageMetrics(fs *FileStorage, registry *metrics.Registry) *storageMetrics {
+	labels := metrics.Labels{"root": fs.config.RootDir}
+
+	m := &storageMetrics{
+		hits:         registry.Counter("cpfs_storage_cache_hits_total", "Number of loads served from the cache.", labels),
+		misses:       registry.Counter("cpfs_storage_cache_misses_total", "Number of loads that read from disk.", labels),
+		syncedKeys:   registry.Counter("cpfs_storage_synced_keys_total", "Number of keys written to disk by sync.", labels),
+		syncDuration: registry.Histogram("cpfs_storage_sync_duration_seconds", "Duration of storage syncs.", labels, nil),
+		ops:          make(map[string]*metrics.Histogram),
+	}
+	for _, op := range storageOps {
+		m.ops[op] = registry.Histogram("cpfs_storage_op_duration_seconds", "Duration of storage operations.",
+			metrics.Labels{"root": fs.config.RootDir, "op": op}, nil)
+	}
+
+	registry.GaugeFunc("cpfs_storage_cached_keys", "Number of keys held in the cache.", labels, func() float64 {
+		fs.mu.RLock()
+		defer fs.mu.RUnlock()
+		return float64(len(fs.cache))
+	})
+	registry.GaugeFunc("cpfs_storage_cached_bytes", "Bytes held in the cache.", labels, func() float64 {
+		return float64(fs.cachedBytes())
+	})
+	registry.GaugeFunc("cpfs_storage_dirty_keys", "Number of keys waiting to be synced.", labels, func() float64 {
+		fs.mu.RLock()
+		defer fs.mu.RUnlock()
+		return float64(len(fs.dirty))
+	})
+
+	storageBuffers.RegisterMetrics(registry, metrics.Labels{"pool": "storage"})
+
+	return m
+}
+
+// observe 记录操作耗时
+func (m *storageMetrics) observe(op string, start time.Time) {
+	m.ops[op].ObserveSince(start)
+}
+
+// cachedBytes 统计缓存数据字节数
+func (fs *FileStorage) cachedBytes() int64 {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	var total int64
+	for _, data := range fs.cache {
+		total += int64(len(data))
+	}
+	return total
+}
+
+// Stats 返回存储运行统计
+func (fs *FileStorage) Stats() StorageStats {
+	stats := StorageStats{
+		Hits:         fs.metrics.hits.Value(),
+		Misses:       fs.metrics.misses.Value(),
+		SyncedKeys:   fs.metrics.syncedKeys.Value(),
+		SyncDuration: fs.metrics.syncDuration.Snapshot(),
+		OpLatency:    make(map[string]metrics.HistogramSnapshot, len(fs.metrics.ops)),
+		CachedBytes:  fs.cachedBytes(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	for op, h := range fs.metrics.ops {
+		stats.OpLatency[op] = h.Snapshot()
+	}
+
+	fs.mu.RLock()
+	stats.CachedKeys = len(fs.cache)
+	stats.DirtyKeys = len(fs.dirty)
+	fs.mu.RUnlock()
+
+	return stats
+}
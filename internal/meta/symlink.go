@@ -0,0 +1,208 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxSymlinkDepth 解析一个路径时最多跟随的符号链接数，与 Linux 的 MAXSYMLINKS 相同
+const MaxSymlinkDepth = 40
+
+// ErrSymlinkLoop 解析路径时遇到符号链接循环或跟随次数超过 MaxSymlinkDepth，可用 errors.Is 判断
+var ErrSymlinkLoop = errors.New("too many levels of symbolic links")
+
+// SymlinkLoopError 路径解析因符号链接循环失败
+//
+// errors.Is 对 ErrSymlinkLoop 和 syscall.ELOOP 都成立，FUSE 和 NFS 前端可直接映射为 ELOOP。
+type SymlinkLoopError struct {
+	Path     string // 被解析的路径
+	Followed int    // 失败前跟随的符号链接数
+	Cycle    bool   // 检测到确定的循环，而不是超过深度限制
+}
+
+// Error 实现 error
+func (e *SymlinkLoopError) Error() string {
+	if e.Cycle {
+		return fmt.Sprintf("symbolic link cycle resolving %s after %d links", e.Path, e.Followed)
+	}
+	return fmt.Sprintf("too many levels of symbolic links resolving %s: more than %d", e.Path, MaxSymlinkDepth)
+}
+
+// Is 使 errors.Is(err, ErrSymlinkLoop) 和 errors.Is(err, syscall.ELOOP) 成立
+func (e *SymlinkLoopError) Is(target error) bool {
+	return target == ErrSymlinkLoop || target == syscall.ELOOP
+}
+
+// Symlink 创建指向 target 的符号链接 linkPath
+//
+// target 原样保存，不要求存在；相对路径在解析时相对于链接所在的目录。
+func (s *MemoryStore) Symlink(ctx context.Context, target, linkPath string) error {
+	if target == "" {
+		return fmt.Errorf("symlink target is required")
+	}
+
+	unlock, err := s.lockFenced(ctx, linkPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := s.locateEntry(normalizePath(linkPath))
+	parent := path.Dir(filePath)
+	parentMeta, exists := s.tree.get(parent)
+	if !exists {
+		return fmt.Errorf("parent directory not found: %s", parent)
+	}
+	if parentMeta.Type != TypeDirectory {
+		return fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+	if _, exists := s.tree.get(filePath); exists {
+		return fmt.Errorf("file %w: %s", ErrExist, filePath)
+	}
+
+	inode, err := s.nextInode(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	meta := &Metadata{
+		Inode:      inode,
+		Name:       s.interner.Intern(path.Base(filePath)),
+		Type:       TypeSymlink,
+		Size:       int64(len(target)),
+		Mode:       os.ModeSymlink | 0777,
+		Links:      1,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+		Target:     target,
+	}
+	s.applyParentDefaults(filePath, meta)
+	// 符号链接的权限位不起作用，固定为 0777
+	meta.Mode = os.ModeSymlink | 0777
+	if err := s.chargeQuota(filePath, nil, meta); err != nil {
+		return err
+	}
+
+	s.stamp(meta)
+	s.setEntry(filePath, meta)
+	s.recordChange(ctx, ChangeCreate, filePath, meta)
+	return nil
+}
+
+// Readlink 返回符号链接指向的路径
+func (s *MemoryStore) Readlink(ctx context.Context, p string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filePath := s.locateEntry(normalizePath(p))
+	meta, exists := s.tree.get(filePath)
+	if !exists {
+		return "", fmt.Errorf("file not found: %s", filePath)
+	}
+	if meta.Type != TypeSymlink {
+		return "", fmt.Errorf("path is not a symlink: %s", filePath)
+	}
+	return meta.Target, nil
+}
+
+// ResolvePath 跟随路径中的全部符号链接，返回最终条目的路径
+//
+// 中间目录和最后一个分量上的符号链接都会被解析。遇到循环或跟随次数超过 MaxSymlinkDepth 时
+// 返回 *SymlinkLoopError。成功的解析结果缓存在路径解析缓存中。
+func (s *MemoryStore) ResolvePath(ctx context.Context, p string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resolve(normalizePath(p))
+}
+
+// resolve 优先从路径解析缓存返回结果，未命中时解析并缓存，调用方需持有锁
+//
+// 缓存只在写锁下清空，持有读锁时写入的结果不会与并发修改交错。
+func (s *MemoryStore) resolve(filePath string) (string, error) {
+	if resolved, ok := s.dentries.get(filePath); ok {
+		return resolved, nil
+	}
+	resolved, err := s.resolveLocked(filePath)
+	if err != nil {
+		return "", err
+	}
+	s.dentries.put(filePath, resolved)
+	return resolved, nil
+}
+
+// resolveLocked 逐个分量解析路径，调用方需持有锁
+//
+// 跟随符号链接时记录（链接，剩余路径）状态，同一状态再次出现即为确定的循环，不必等到深度耗尽。
+func (s *MemoryStore) resolveLocked(filePath string) (string, error) {
+	resolved := "/"
+	remaining := strings.Split(strings.TrimPrefix(filePath, "/"), "/")
+	followed := 0
+	var seen map[string]bool
+	for len(remaining) > 0 {
+		name := remaining[0]
+		remaining = remaining[1:]
+		if name == "" {
+			continue
+		}
+		next := path.Join(resolved, name)
+		meta, exists := s.tree.get(next)
+		if !exists {
+			return "", fmt.Errorf("file not found: %s", next)
+		}
+		if meta.Type == TypeBind {
+			// 绑定点透明替换为源目录，不计入符号链接次数
+			resolved = meta.Target
+			continue
+		}
+		if meta.Type != TypeSymlink {
+			if len(remaining) > 0 && meta.Type != TypeDirectory {
+				return "", fmt.Errorf("path is not a directory: %s", next)
+			}
+			resolved = next
+			continue
+		}
+
+		followed++
+		if followed > MaxSymlinkDepth {
+			return "", &SymlinkLoopError{Path: filePath, Followed: followed - 1}
+		}
+		state := next + "\x00" + strings.Join(remaining, "/")
+		if seen[state] {
+			return "", &SymlinkLoopError{Path: filePath, Followed: followed - 1, Cycle: true}
+		}
+		if seen == nil {
+			seen = make(map[string]bool)
+		}
+		seen[state] = true
+		target := meta.Target
+		if !path.IsAbs(target) {
+			target = path.Join(resolved, target)
+		}
+		// 链接目标替换已解析的部分，从根目录重新解析
+		remaining = append(strings.Split(strings.TrimPrefix(path.Clean(target), "/"), "/"), remaining...)
+		resolved = "/"
+	}
+	return resolved, nil
+}
+
+// GetFollow 跟随符号链接后获取元数据副本，语义同 stat(2)；Get 不跟随，语义同 lstat(2)
+func (s *MemoryStore) GetFollow(ctx context.Context, p string) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filePath, err := s.resolve(normalizePath(p))
+	if err != nil {
+		return nil, err
+	}
+	meta := s.tree.entry(filePath)
+	meta.AccessTime = time.Now()
+	return meta.Clone(), nil
+}
@@ -0,0 +1,88 @@
+package meta
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreSymlink(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/data", 0755))
+	require.NoError(t, store.Mkdir(ctx, "/data/v2", 0755))
+	file, err := store.Create(ctx, "/data/v2/model.bin", 0644)
+	require.NoError(t, err)
+	file.Size = 100
+	require.NoError(t, store.Update(ctx, "/data/v2/model.bin", file))
+
+	require.NoError(t, store.Symlink(ctx, "v2", "/data/current"))
+	require.NoError(t, store.Symlink(ctx, "/data/current/model.bin", "/latest"))
+
+	target, err := store.Readlink(ctx, "/data/current")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", target)
+	link, err := store.Get(ctx, "/latest")
+	require.NoError(t, err)
+	assert.Equal(t, TypeSymlink, link.Type)
+	assert.Equal(t, os.ModeSymlink|0777, link.Mode)
+	assert.Equal(t, int64(len("/data/current/model.bin")), link.Size)
+
+	// 中间目录和最后一个分量上的链接都被解析
+	resolved, err := store.ResolvePath(ctx, "/latest")
+	require.NoError(t, err)
+	assert.Equal(t, "/data/v2/model.bin", resolved)
+	followed, err := store.GetFollow(ctx, "/data/current/model.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), followed.Size)
+	assert.Equal(t, "model.bin", followed.Name)
+
+	_, err = store.Readlink(ctx, "/data/v2/model.bin")
+	assert.Error(t, err)
+	assert.ErrorIs(t, store.Symlink(ctx, "x", "/latest"), ErrExist)
+	assert.Error(t, store.Symlink(ctx, "", "/empty"))
+	assert.Error(t, store.Symlink(ctx, "x", "/missing/link"))
+
+	// 悬空链接可以创建，解析时报告目标不存在
+	require.NoError(t, store.Symlink(ctx, "../nowhere", "/data/dangling"))
+	_, err = store.GetFollow(ctx, "/data/dangling")
+	assert.Error(t, err)
+	_, err = store.ResolvePath(ctx, "/data/v2/model.bin/child")
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreSymlinkLoop(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Symlink(ctx, "/b", "/a"))
+	require.NoError(t, store.Symlink(ctx, "a", "/b"))
+
+	_, err := store.GetFollow(ctx, "/a")
+	assert.ErrorIs(t, err, ErrSymlinkLoop)
+	_, err = store.ResolvePath(ctx, "/b/child")
+	assert.ErrorIs(t, err, ErrSymlinkLoop)
+}
+
+func TestSymlinkPersistence(t *testing.T) {
+	f := &Metadata{Name: "l", Type: TypeSymlink, Target: "../target"}
+	data, err := f.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, "../target", decoded.Target)
+
+	ctx := context.Background()
+	store := NewCompactStore()
+	_, err = store.Create(ctx, "/l", 0644)
+	require.NoError(t, err)
+	m, err := store.Get(ctx, "/l")
+	require.NoError(t, err)
+	m.Target = "../target"
+	require.NoError(t, store.Update(ctx, "/l", m))
+	m, err = store.Get(ctx, "/l")
+	require.NoError(t, err)
+	assert.Equal(t, "../target", m.Target)
+}
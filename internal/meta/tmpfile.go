@@ -0,0 +1,115 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CreateTemp 在目录 dir 中创建没有名称的临时文件，对应 open(2) 的 O_TMPFILE
+//
+// 文件创建后处于打开状态，返回的元数据中 Links 为 0，通过 GetOpen 和 UpdateOpen 读写。
+// LinkTemp 把它发布到命名空间中，发布之前不出现在目录列表和变更日志中，也不计入配额；
+// 未发布就 Close 时数据块交给 TakeReleasedBlocks 回收。写完再发布可以实现原子出现的文件。
+func (s *MemoryStore) CreateTemp(ctx context.Context, dir string, mode os.FileMode) (*Metadata, error) {
+	unlock, err := s.lockFenced(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	dirPath := s.locate(normalizePath(dir))
+	parent, exists := s.tree.get(dirPath)
+	if !exists {
+		return nil, fmt.Errorf("directory not found: %s", dirPath)
+	}
+	if parent.Type != TypeDirectory {
+		return nil, fmt.Errorf("path is not a directory: %s", dirPath)
+	}
+
+	inode, err := s.nextInode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	meta := &Metadata{
+		Inode:      inode,
+		Type:       TypeRegular,
+		Mode:       mode,
+		CreateTime: now,
+		ModifyTime: now,
+		AccessTime: now,
+		Version:    1,
+	}
+	applyDirDefaults(parent.Defaults, parent, meta)
+	internMetadata(s.interner, meta)
+
+	s.opens[meta.Inode] = &openFile{count: 1, node: s.tree.lookup(dirPath), orphaned: true, temp: true, meta: meta}
+	return meta.Clone(), nil
+}
+
+// LinkTemp 将 CreateTemp 创建的临时文件发布到 newPath，对应 linkat(2) 的 AT_EMPTY_PATH
+//
+// newPath 不能已存在。发布后文件成为普通文件，句柄仍然有效，之后的 Close 不再回收数据块。
+// 被删除后仍打开的普通文件不能重新链接。
+func (s *MemoryStore) LinkTemp(ctx context.Context, inode uint64, newPath string) error {
+	unlock, err := s.lockFenced(ctx, newPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, ok := s.opens[inode]
+	if !ok {
+		return fmt.Errorf("inode %d is not open", inode)
+	}
+	if !f.temp {
+		return fmt.Errorf("inode %d is not a temporary file", inode)
+	}
+	filePath := s.locateEntry(normalizePath(newPath))
+	// 临时文件的数据块按创建目录所在的区域加密
+	if s.tree.attached(f.node) {
+		if err := s.checkSameZone(s.tree.pathOf(f.node), filePath); err != nil {
+			return err
+		}
+	}
+	parent := path.Dir(filePath)
+	parentMeta, exists := s.tree.get(parent)
+	if !exists {
+		return fmt.Errorf("parent directory not found: %s", parent)
+	}
+	if parentMeta.Type != TypeDirectory {
+		return fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+	if _, exists := s.tree.get(filePath); exists {
+		return fmt.Errorf("file %w: %s", ErrExist, filePath)
+	}
+
+	meta := f.meta.Clone()
+	meta.Name = s.interner.Intern(path.Base(filePath))
+	meta.Links = 1
+	meta.Version++
+	if err := s.chargeQuota(filePath, nil, meta); err != nil {
+		return err
+	}
+	if err := s.storeBlockMap(ctx, meta); err != nil {
+		s.accountQuota(filePath, meta, nil)
+		return err
+	}
+
+	s.stamp(meta)
+	f.node = s.tree.set(filePath, meta)
+	f.orphaned = false
+	f.temp = false
+	f.meta = nil
+	s.recordChange(ctx, ChangeCreate, filePath, meta)
+	s.log.Info("Published temporary file",
+		zap.String("path", filePath),
+		zap.Uint64("inode", meta.Inode),
+	)
+	return nil
+}
@@ -0,0 +1,72 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTempPublish(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/out", 0755))
+
+	tmp, err := store.CreateTemp(ctx, "/out", 0644)
+	require.NoError(t, err)
+	assert.Zero(t, tmp.Links)
+	entries, err := store.List(ctx, "/out")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// 写完之后再发布
+	tmp.Blocks = []Block{{ID: "blk-0", Size: 10}}
+	tmp.Size = 10
+	require.NoError(t, store.UpdateOpen(ctx, tmp.Inode, tmp))
+
+	_, err = store.Create(ctx, "/out/exists", 0644)
+	require.NoError(t, err)
+	assert.ErrorIs(t, store.LinkTemp(ctx, tmp.Inode, "/out/exists"), ErrExist)
+	require.NoError(t, store.LinkTemp(ctx, tmp.Inode, "/out/result"))
+	assert.Error(t, store.LinkTemp(ctx, tmp.Inode, "/out/again"))
+
+	m, err := store.Get(ctx, "/out/result")
+	require.NoError(t, err)
+	assert.Equal(t, tmp.Inode, m.Inode)
+	assert.Equal(t, "result", m.Name)
+	assert.Equal(t, 1, m.Links)
+	assert.Equal(t, int64(10), m.Size)
+
+	// 发布后句柄仍然有效，关闭不回收数据块
+	m.Size = 20
+	require.NoError(t, store.UpdateOpen(ctx, tmp.Inode, m))
+	require.NoError(t, store.Close(ctx, tmp.Inode))
+	assert.Empty(t, store.TakeReleasedBlocks())
+	m, err = store.Get(ctx, "/out/result")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), m.Size)
+}
+
+func TestCreateTempDiscard(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	tmp, err := store.CreateTemp(ctx, "/", 0600)
+	require.NoError(t, err)
+	tmp.Blocks = []Block{{ID: "blk-0", Size: 10}}
+	require.NoError(t, store.UpdateOpen(ctx, tmp.Inode, tmp))
+	require.NoError(t, store.Close(ctx, tmp.Inode))
+	assert.Equal(t, []Block{{ID: "blk-0", Size: 10}}, store.TakeReleasedBlocks())
+
+	_, err = store.CreateTemp(ctx, "/missing", 0600)
+	assert.Error(t, err)
+
+	// 删除后仍打开的普通文件不能重新链接
+	_, err = store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	m, err := store.Open(ctx, "/f")
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(ctx, "/f"))
+	assert.Error(t, store.LinkTemp(ctx, m.Inode, "/f"))
+}
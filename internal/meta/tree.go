@@ -0,0 +1,271 @@
+package meta
+
+import (
+	"iter"
+	"path"
+	"sort"
+	"strings"
+)
+
+// treeNode 命名空间树中的目录项，通过父子指针相连
+//
+// 节点只记录自己的名称，完整路径沿父指针向上拼出，因此移动子树只需把子树根节点挂到新的父节点下。
+// 同一 inode 的多个硬链接是指向各自节点的多个目录项，元数据按链接分别保存，名称不同、其余字段相同。
+type treeNode struct {
+	name     string
+	meta     *Metadata // 为 nil 时是占位节点：自身没有条目，但有条目的后代（例如副本同步先于父目录到达）
+	parent   *treeNode
+	children map[string]*treeNode
+}
+
+// nsTree 以根目录为根、inode 相连的命名空间树
+//
+// 条目按路径逐级查找，代价与路径深度成正比；列目录、判断目录是否为空和遍历子树只访问相关节点，
+// 重命名目录只移动一个节点，与子树大小无关。
+type nsTree struct {
+	root *treeNode
+	size int // 有条目的节点数
+}
+
+// newTree 创建只有根目录的命名空间树
+func newTree(root *Metadata) *nsTree {
+	return &nsTree{root: &treeNode{name: "/", meta: root}, size: 1}
+}
+
+// pathNames 依次返回标准化路径的各级名称
+func pathNames(p string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for rest := strings.TrimPrefix(p, "/"); rest != ""; {
+			name, next, _ := strings.Cut(rest, "/")
+			if !yield(name) {
+				return
+			}
+			rest = next
+		}
+	}
+}
+
+// lookup 返回路径对应的节点，包括占位节点，不存在时返回 nil
+func (t *nsTree) lookup(p string) *treeNode {
+	n := t.root
+	for name := range pathNames(p) {
+		if n = n.children[name]; n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// ensure 返回路径对应的节点，沿途缺少的节点以占位节点补齐
+func (t *nsTree) ensure(p string) *treeNode {
+	n := t.root
+	for name := range pathNames(p) {
+		child, ok := n.children[name]
+		if !ok {
+			child = &treeNode{name: name, parent: n}
+			if n.children == nil {
+				n.children = make(map[string]*treeNode)
+			}
+			n.children[name] = child
+		}
+		n = child
+	}
+	return n
+}
+
+// get 返回路径上的条目
+func (t *nsTree) get(p string) (*Metadata, bool) {
+	if n := t.lookup(p); n != nil && n.meta != nil {
+		return n.meta, true
+	}
+	return nil, false
+}
+
+// entry 返回路径上的条目，不存在时返回 nil
+func (t *nsTree) entry(p string) *Metadata {
+	m, _ := t.get(p)
+	return m
+}
+
+// set 写入路径上的条目并返回其节点
+func (t *nsTree) set(p string, m *Metadata) *treeNode {
+	n := t.ensure(p)
+	t.assign(n, m)
+	return n
+}
+
+// assign 写入节点上的条目
+func (t *nsTree) assign(n *treeNode, m *Metadata) {
+	if n.meta == nil {
+		t.size++
+	}
+	n.meta = m
+}
+
+// remove 删除路径上的条目并返回其原节点，不存在时返回 nil；仍有后代的节点保留为占位节点
+func (t *nsTree) remove(p string) *treeNode {
+	n := t.lookup(p)
+	if n == nil || n.meta == nil || n == t.root {
+		return nil
+	}
+	n.meta = nil
+	t.size--
+	t.prune(n)
+	return n
+}
+
+// prune 从 n 开始向上摘除没有条目也没有后代的节点
+func (t *nsTree) prune(n *treeNode) {
+	for n != t.root && n.meta == nil && len(n.children) == 0 {
+		delete(n.parent.children, n.name)
+		n = n.parent
+	}
+}
+
+// move 把 from 的节点连同整个子树挂到 to 下，to 上已有的占位节点的后代并入移动的节点
+func (t *nsTree) move(from, to string) {
+	n := t.lookup(from)
+	if n == nil || n == t.root {
+		return
+	}
+	oldParent := n.parent
+	delete(oldParent.children, n.name)
+	t.prune(oldParent)
+
+	parent := t.ensure(path.Dir(to))
+	n.name = path.Base(to)
+	if existing, ok := parent.children[n.name]; ok {
+		for name, child := range existing.children {
+			child.parent = n
+			if n.children == nil {
+				n.children = make(map[string]*treeNode)
+			}
+			n.children[name] = child
+		}
+	}
+	n.parent = parent
+	if parent.children == nil {
+		parent.children = make(map[string]*treeNode)
+	}
+	parent.children[n.name] = n
+}
+
+// pathOf 沿父指针拼出节点的完整路径
+func (t *nsTree) pathOf(n *treeNode) string {
+	if n == t.root {
+		return "/"
+	}
+	var names []string
+	for ; n != t.root; n = n.parent {
+		names = append(names, n.name)
+	}
+	var b strings.Builder
+	for i := len(names) - 1; i >= 0; i-- {
+		b.WriteByte('/')
+		b.WriteString(names[i])
+	}
+	return b.String()
+}
+
+// attached 判断节点仍在树中且有条目，被删除或所在子树被摘除的节点返回 false
+func (t *nsTree) attached(n *treeNode) bool {
+	if n == nil || n.meta == nil {
+		return false
+	}
+	for ; n != t.root; n = n.parent {
+		if n.parent == nil || n.parent.children[n.name] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// hasChildren 判断目录下是否有节点
+func (t *nsTree) hasChildren(dirPath string) bool {
+	n := t.lookup(dirPath)
+	return n != nil && len(n.children) > 0
+}
+
+// childPaths 返回目录的直接子条目路径，顺序不固定
+func (t *nsTree) childPaths(dirPath string) []string {
+	n := t.lookup(dirPath)
+	if n == nil {
+		return nil
+	}
+	paths := make([]string, 0, len(n.children))
+	for name, child := range n.children {
+		if child.meta != nil {
+			paths = append(paths, path.Join(dirPath, name))
+		}
+	}
+	return paths
+}
+
+// walk 先序遍历 n 的后代（不含 n），p 为 n 的路径，yield 返回 false 时停止
+func walk(n *treeNode, p string, yield func(string, *Metadata) bool) bool {
+	for name, child := range n.children {
+		childPath := path.Join(p, name)
+		if child.meta != nil && !yield(childPath, child.meta) {
+			return false
+		}
+		if !walk(child, childPath, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// entries 遍历 root 及其全部后代的条目，顺序不固定；root 不存在时为空
+//
+// 与遍历 map 一样，遍历期间删除尚未访问的条目后不会再访问到它，新写入的条目可能访问不到。
+func (t *nsTree) entries(root string) iter.Seq2[string, *Metadata] {
+	return func(yield func(string, *Metadata) bool) {
+		n := t.lookup(root)
+		if n == nil {
+			return
+		}
+		if n.meta != nil && !yield(root, n.meta) {
+			return
+		}
+		walk(n, root, yield)
+	}
+}
+
+// all 遍历全部条目，顺序不固定
+func (t *nsTree) all() iter.Seq2[string, *Metadata] {
+	return t.entries("/")
+}
+
+// descendants 返回 root 的全部后代路径（不含 root），按字典序排列
+func (t *nsTree) descendants(root string) []string {
+	var paths []string
+	if n := t.lookup(root); n != nil {
+		walk(n, root, func(p string, _ *Metadata) bool {
+			paths = append(paths, p)
+			return true
+		})
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// treeFrom 根据全部条目建立命名空间树，条目中必须有根目录
+func treeFrom(data map[string]*Metadata) *nsTree {
+	t := newTree(data["/"])
+	for p, m := range data {
+		if p != "/" {
+			t.set(p, m)
+		}
+	}
+	return t
+}
+
+// setEntry 写入路径上的条目，调用方需持有写锁
+func (s *MemoryStore) setEntry(p string, m *Metadata) {
+	s.tree.set(p, m)
+}
+
+// deleteEntry 删除路径上的条目，调用方需持有写锁
+func (s *MemoryStore) deleteEntry(p string) {
+	s.tree.remove(p)
+}
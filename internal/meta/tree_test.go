@@ -0,0 +1,138 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listNames 返回目录子项的名称，按字典序排列
+func listNames(t *testing.T, store *MemoryStore, dir string) []string {
+	entries, err := store.List(context.Background(), dir)
+	require.NoError(t, err)
+	var names []string
+	for _, m := range entries {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestTreeTracksNamespace(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	_, err := store.MkdirWithOptions(ctx, "/a/b", 0755, MkdirOptions{Parents: true})
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/a/b/f", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/a/g", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Link(ctx, "/a/g", "/a/b/g2"))
+
+	assert.Equal(t, []string{"b", "g"}, listNames(t, store, "/a"))
+	assert.Equal(t, []string{"f", "g2"}, listNames(t, store, "/a/b"))
+
+	require.NoError(t, store.Rename(ctx, "/a/b", "/c"))
+	assert.Equal(t, []string{"g"}, listNames(t, store, "/a"))
+	assert.Equal(t, []string{"a", "c"}, listNames(t, store, "/"))
+	assert.Equal(t, []string{"f", "g2"}, listNames(t, store, "/c"))
+	assert.Equal(t, []string{"/c/f", "/c/g2"}, store.tree.descendants("/c"))
+	assert.Equal(t, 6, store.tree.size)
+
+	assert.ErrorIs(t, store.Rmdir(ctx, "/c"), ErrNotEmpty)
+	require.NoError(t, store.DeleteAll(ctx, "/c"))
+	require.NoError(t, store.Delete(ctx, "/a/g"))
+	require.NoError(t, store.Rmdir(ctx, "/a"))
+	assert.Empty(t, listNames(t, store, "/"))
+	assert.Empty(t, store.tree.root.children)
+	assert.Equal(t, 1, store.tree.size)
+
+	// 从检查点恢复时重建树
+	_, err = store.Create(ctx, "/x", 0644)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, store.WriteCheckpoint(&buf))
+	restored := NewMemoryStore()
+	require.NoError(t, restored.ReadCheckpoint(&buf))
+	assert.Equal(t, []string{"x"}, listNames(t, restored, "/"))
+	assert.Equal(t, 2, restored.tree.size)
+}
+
+func TestTreeRenameMovesSubtree(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	_, err := store.MkdirWithOptions(ctx, "/src/deep/er", 0755, MkdirOptions{Parents: true})
+	require.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		_, err := store.Create(ctx, fmt.Sprintf("/src/deep/er/f%d", i), 0644)
+		require.NoError(t, err)
+	}
+	node := store.tree.lookup("/src/deep/er/f7")
+	require.NotNil(t, node)
+
+	require.NoError(t, store.Mkdir(ctx, "/dst", 0755))
+	require.NoError(t, store.Rename(ctx, "/src/deep", "/dst/moved"))
+
+	// 后代节点原样随子树移动，路径由父指针得出
+	assert.Same(t, node, store.tree.lookup("/dst/moved/er/f7"))
+	assert.Equal(t, "/dst/moved/er/f7", store.tree.pathOf(node))
+	assert.Nil(t, store.tree.lookup("/src/deep"))
+	assert.Len(t, store.tree.descendants("/dst/moved"), 51)
+	_, err = store.Get(ctx, "/src/deep/er/f7")
+	assert.Error(t, err)
+	meta, err := store.Get(ctx, "/dst/moved/er/f7")
+	require.NoError(t, err)
+	assert.Equal(t, "f7", meta.Name)
+}
+
+func TestTreeHardLinksFollowRenames(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	require.NoError(t, store.Mkdir(ctx, "/d", 0755))
+	_, err := store.Create(ctx, "/d/f", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Link(ctx, "/d/f", "/g"))
+
+	// 重命名链接所在目录后，通过另一链接的修改仍同步到移动后的节点
+	require.NoError(t, store.Rename(ctx, "/d", "/e"))
+	meta, err := store.Get(ctx, "/g")
+	require.NoError(t, err)
+	meta.Size = 42
+	require.NoError(t, store.Update(ctx, "/g", meta))
+	moved, err := store.Get(ctx, "/e/f")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), moved.Size)
+	assert.Equal(t, "f", moved.Name)
+	assert.Equal(t, 2, moved.Links)
+	assert.ElementsMatch(t, []string{"/e/f", "/g"}, store.linkPaths("/g", meta.Inode))
+
+	require.NoError(t, store.Delete(ctx, "/g"))
+	moved, err = store.Get(ctx, "/e/f")
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved.Links)
+	assert.Empty(t, store.links)
+}
+
+func TestTreePlaceholderNodes(t *testing.T) {
+	tree := newTree(&Metadata{Inode: 1, Name: "/", Type: TypeDirectory})
+	// 子条目先于父目录写入时，父目录以占位节点存在，不计入条目
+	tree.set("/a/b", &Metadata{Inode: 3, Name: "b"})
+	_, exists := tree.get("/a")
+	assert.False(t, exists)
+	assert.Equal(t, 2, tree.size)
+	assert.Empty(t, tree.childPaths("/"))
+	assert.Equal(t, []string{"/a/b"}, tree.descendants("/"))
+
+	tree.set("/a", &Metadata{Inode: 2, Name: "a", Type: TypeDirectory})
+	assert.Equal(t, []string{"/a"}, tree.childPaths("/"))
+	tree.remove("/a")
+	assert.NotNil(t, tree.lookup("/a"))
+	tree.remove("/a/b")
+	assert.Nil(t, tree.lookup("/a"))
+	assert.Equal(t, 1, tree.size)
+}
@@ -0,0 +1,86 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+)
+
+// Truncate 将文件大小设置为 size
+//
+// 缩小时完全位于 size 之后的数据块不再被引用，记录下来等待 TakeReleasedBlocks 交给块回收器；
+// 跨越 size 的块保留在块映射中但长度截短，它原有的校验和覆盖旧长度，因此被清除。
+// 扩大时不分配数据块，新增部分是读出为零的空洞。内联数据的文件直接截断或补零，不能超过 InlineDataLimit。
+// 硬链接共享同一个 inode，截断对全部链接可见。
+func (s *MemoryStore) Truncate(ctx context.Context, p string, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("invalid size: %d", size)
+	}
+
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	if current.Type != TypeRegular {
+		return fmt.Errorf("path is not a file: %s", filePath)
+	}
+	if size == current.Size {
+		return nil
+	}
+
+	updated := current.Clone()
+	updated.Size = size
+	var released []Block
+	if len(current.InlineData) > 0 {
+		if size > InlineDataLimit {
+			return fmt.Errorf("cannot extend inline file %s beyond %d bytes", filePath, InlineDataLimit)
+		}
+		inline := make([]byte, size)
+		copy(inline, current.InlineData)
+		updated.InlineData = inline
+	} else if size < current.Size {
+		blocks, err := s.fileBlocks(ctx, current)
+		if err != nil {
+			return err
+		}
+		kept := make([]Block, 0, len(blocks))
+		for _, b := range blocks {
+			switch {
+			case b.Offset >= size:
+				released = append(released, b)
+			case b.End() > size:
+				b.Size = size - b.Offset
+				b.Checksum = ""
+				kept = append(kept, b)
+			default:
+				kept = append(kept, b)
+			}
+		}
+		updated.Blocks = kept
+		updated.Extents = nil
+		updated.BlockCount = 0
+	}
+
+	if err := s.replace(ctx, filePath, current, updated); err != nil {
+		return err
+	}
+	s.released = append(s.released, released...)
+	return nil
+}
+
+// TakeReleasedBlocks 取出截断后不再被引用的数据块以及删除后最后一次关闭的文件的数据块，由块回收器调度删除
+//
+// 记录只保存在内存中，取出后即清空；进程重启前未取出的块需要由一致性检查发现。
+func (s *MemoryStore) TakeReleasedBlocks() []Block {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	released := s.released
+	s.released = nil
+	return released
+}
@@ -0,0 +1,76 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	api "cpfs/api/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	file, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	for i := range 4 {
+		file.Blocks = append(file.Blocks, Block{ID: api.FormatBlockID(uint64(i + 1)), Size: 10, Offset: int64(i) * 10, Locations: []string{"ds1"}})
+	}
+	file.Blocks[2].Checksum = "sum"
+	file.Size = 40
+	require.NoError(t, store.Update(ctx, "/f", file))
+
+	// 缩小后尾部的块等待回收，跨越新大小的块被截短
+	require.NoError(t, store.Truncate(ctx, "/f", 25))
+	m, err := store.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, int64(25), m.Size)
+	blocks, err := store.GetBlockRange(ctx, "/f", 0, -1)
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, int64(5), blocks[2].Size)
+	assert.Empty(t, blocks[2].Checksum)
+
+	released := store.TakeReleasedBlocks()
+	require.Len(t, released, 1)
+	assert.Equal(t, int64(30), released[0].Offset)
+	assert.Empty(t, store.TakeReleasedBlocks())
+
+	// 扩大不分配数据块
+	require.NoError(t, store.Truncate(ctx, "/f", 100))
+	m, err = store.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), m.Size)
+	assert.Empty(t, store.TakeReleasedBlocks())
+
+	require.NoError(t, store.Truncate(ctx, "/f", 0))
+	blocks, err = store.GetBlockRange(ctx, "/f", 0, -1)
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+	assert.Len(t, store.TakeReleasedBlocks(), 3)
+
+	require.NoError(t, store.Mkdir(ctx, "/d", 0755))
+	assert.Error(t, store.Truncate(ctx, "/d", 0))
+	assert.Error(t, store.Truncate(ctx, "/f", -1))
+}
+
+func TestTruncateInline(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	_, err := store.CreateWithData(ctx, "/small", 0644, []byte("hello world"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Truncate(ctx, "/small", 5))
+	m, err := store.Get(ctx, "/small")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), m.InlineData)
+
+	require.NoError(t, store.Truncate(ctx, "/small", 7))
+	m, err = store.Get(ctx, "/small")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello\x00\x00"), m.InlineData)
+
+	assert.Error(t, store.Truncate(ctx, "/small", InlineDataLimit+1))
+}
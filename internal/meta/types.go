@@ -0,0 +1,99 @@
+package meta
+
+import (
+	"context"
+	"os"
+
+	api "cpfs/api/meta"
+)
+
+// 元数据的公开类型定义在独立发布的 cpfs/api/meta 中，这里以别名引用，服务端代码沿用 meta.Metadata 等名称
+type (
+	FileType           = api.FileType
+	Metadata           = api.Metadata
+	Block              = api.Block
+	Extent             = api.Extent
+	CompressionDefault = api.CompressionDefault
+	DirDefaults        = api.DirDefaults
+	FilePin            = api.FilePin
+	StripeLayout       = api.StripeLayout
+	StripeChunk        = api.StripeChunk
+	HLCTimestamp       = api.HLCTimestamp
+	SetAttrMask        = api.SetAttrMask
+	Attrs              = api.Attrs
+	PageOptions        = api.PageOptions
+	Page               = api.Page
+	ChangeType         = api.ChangeType
+	ChangeEvent        = api.ChangeEvent
+
+	VersionConflictError = api.VersionConflictError
+)
+
+const (
+	TypeRegular   = api.TypeRegular
+	TypeDirectory = api.TypeDirectory
+	TypeSymlink   = api.TypeSymlink
+	TypeBind      = api.TypeBind
+
+	CompressionUnset = api.CompressionUnset
+	CompressionOn    = api.CompressionOn
+	CompressionOff   = api.CompressionOff
+
+	SetAttrMode       = api.SetAttrMode
+	SetAttrOwner      = api.SetAttrOwner
+	SetAttrGroup      = api.SetAttrGroup
+	SetAttrAccessTime = api.SetAttrAccessTime
+	SetAttrModifyTime = api.SetAttrModifyTime
+
+	ChangeCreate   = api.ChangeCreate
+	ChangeModify   = api.ChangeModify
+	ChangeDelete   = api.ChangeDelete
+	ChangeRename   = api.ChangeRename
+	ChangeAttr     = api.ChangeAttr
+	ChangeExchange = api.ChangeExchange
+)
+
+// NamespaceStore 元数据存储的命名空间操作，*MemoryStore 满足该接口
+type NamespaceStore interface {
+	// 文件操作
+	Create(ctx context.Context, path string, mode os.FileMode) (*Metadata, error)
+	Get(ctx context.Context, path string) (*Metadata, error)
+	Update(ctx context.Context, path string, meta *Metadata) error
+	SetAttr(ctx context.Context, path string, attrs Attrs, mask SetAttrMask) (*Metadata, error)
+	Truncate(ctx context.Context, path string, size int64) error
+	Delete(ctx context.Context, path string) error
+	Rename(ctx context.Context, oldPath, newPath string) error
+	Symlink(ctx context.Context, target, linkPath string) error
+	Readlink(ctx context.Context, path string) (string, error)
+
+	// 目录操作
+	List(ctx context.Context, path string) ([]*Metadata, error)
+	ListPage(ctx context.Context, path string, opts PageOptions) (*Page, error)
+	ListStream(ctx context.Context, path string, fn func(*Metadata) error) error
+	Mkdir(ctx context.Context, path string, mode os.FileMode) error
+	Rmdir(ctx context.Context, path string) error
+	DeleteAll(ctx context.Context, path string) error
+
+	// 变更订阅
+	Watch(ctx context.Context, pathPrefix string) (*Watcher, error)
+
+	// 快照操作
+	CreateSnapshot(ctx context.Context, path string) (string, error)
+}
+
+// MetaStore 元数据存储接口，在命名空间操作之外支持事务和快照恢复
+type MetaStore interface {
+	NamespaceStore
+
+	// 事务操作
+	Begin() (Transaction, error)
+
+	// 快照恢复
+	RestoreSnapshot(ctx context.Context, snapshotID string) error
+}
+
+// Transaction 事务接口
+type Transaction interface {
+	Commit() error
+	Rollback() error
+}
@@ -0,0 +1,44 @@
+package meta
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionToken 返回元数据的乐观并发令牌
+//
+// 令牌由 inode 和版本号组成，文件被删除后重建时即使版本号相同令牌也不同。
+// 客户端在读取时获得令牌，在修改时原样回传，网关可直接将其用作 ETag。
+func VersionToken(m *Metadata) string {
+	return strconv.FormatUint(m.Inode, 16) + "-" + strconv.FormatUint(m.Version, 16)
+}
+
+// ParseVersionToken 解析乐观并发令牌
+func ParseVersionToken(token string) (inode, version uint64, err error) {
+	inodePart, versionPart, ok := strings.Cut(token, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid version token: %q", token)
+	}
+	if inode, err = strconv.ParseUint(inodePart, 16, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid version token: %q", token)
+	}
+	if version, err = strconv.ParseUint(versionPart, 16, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid version token: %q", token)
+	}
+	return inode, version, nil
+}
+
+// ExpectedVersion 校验令牌属于当前文件并返回令牌中的版本，用于 UpdateIf
+//
+// 令牌中的 inode 与当前文件不一致时返回 *VersionConflictError。
+func ExpectedVersion(current *Metadata, p, token string) (uint64, error) {
+	inode, version, err := ParseVersionToken(token)
+	if err != nil {
+		return 0, err
+	}
+	if inode != current.Inode {
+		return 0, &VersionConflictError{Path: p, Expected: version, Actual: current.Version}
+	}
+	return version, nil
+}
@@ -0,0 +1,41 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionToken(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	meta, err := store.Create(ctx, "/file", 0644)
+	require.NoError(t, err)
+	token := VersionToken(meta)
+
+	inode, version, err := ParseVersionToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, meta.Inode, inode)
+	assert.Equal(t, meta.Version, version)
+
+	for _, bad := range []string{"", "abc", "x-1", "1-y"} {
+		_, _, err := ParseVersionToken(bad)
+		assert.Error(t, err, bad)
+	}
+
+	// 令牌驱动的条件更新
+	expected, err := ExpectedVersion(meta, "/file", token)
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateIf(ctx, "/file", meta, expected))
+	assert.ErrorIs(t, store.UpdateIf(ctx, "/file", meta, expected), ErrVersionConflict)
+
+	// 删除后重建的文件不接受旧令牌
+	require.NoError(t, store.Delete(ctx, "/file"))
+	recreated, err := store.Create(ctx, "/file", 0644)
+	require.NoError(t, err)
+	_, err = ExpectedVersion(recreated, "/file", token)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+}
@@ -0,0 +1,167 @@
+package meta
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// SetVersionHistory 设置每个文件保留的历史版本数量，为 0 时不保留并丢弃已有的历史
+//
+// 历史按 inode 保存，重命名和硬链接之后仍然可以查询，文件的最后一个链接被删除时随之丢弃。
+// 历史只保存元数据，数据块可能已被回收；块映射拆分保存的大文件的历史版本只有 BlockCount 没有 Blocks。
+func (s *MemoryStore) SetVersionHistory(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	s.historyLimit = n
+	if n == 0 {
+		s.history = nil
+		return
+	}
+	if s.history == nil {
+		s.history = make(map[uint64][]*Metadata)
+	}
+	for inode, versions := range s.history {
+		if len(versions) > n {
+			s.history[inode] = append([]*Metadata(nil), versions[len(versions)-n:]...)
+		}
+	}
+}
+
+// remember 在 filePath 的元数据被 meta 替换前保存旧版本，调用方需持有写锁
+func (s *MemoryStore) remember(filePath string, meta *Metadata) {
+	if s.historyLimit == 0 {
+		return
+	}
+	prev, ok := s.tree.get(filePath)
+	if !ok || prev == meta || prev.Inode != meta.Inode || prev.Version == meta.Version {
+		return
+	}
+	versions := append(s.history[meta.Inode], prev)
+	if len(versions) > s.historyLimit {
+		versions = versions[len(versions)-s.historyLimit:]
+	}
+	s.history[meta.Inode] = versions
+}
+
+// forget 丢弃 inode 的历史版本，调用方需持有写锁
+func (s *MemoryStore) forget(inode uint64) {
+	delete(s.history, inode)
+}
+
+// ListVersions 返回文件保留的历史版本和当前版本的副本，按版本号从旧到新排列
+func (s *MemoryStore) ListVersions(ctx context.Context, p string) ([]*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filePath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	history := s.history[current.Inode]
+	versions := make([]*Metadata, 0, len(history)+1)
+	for _, m := range history {
+		versions = append(versions, m.Clone())
+	}
+	return append(versions, current.Clone()), nil
+}
+
+// GetVersion 返回文件指定版本的元数据副本，版本不是当前版本且未被保留时返回错误
+func (s *MemoryStore) GetVersion(ctx context.Context, p string, version uint64) (*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filePath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	if m := s.findVersion(current, version); m != nil {
+		return m.Clone(), nil
+	}
+	return nil, fmt.Errorf("version %d of %s is not retained", version, filePath)
+}
+
+// GetVersionAt 返回 HLC 时间戳不晚于 ts 的最新版本的副本，用于按跨节点一致的时间点读取
+//
+// 只有启用 HLC 后写入的版本带有时间戳；ts 早于保留的所有带时间戳版本时返回错误。
+func (s *MemoryStore) GetVersionAt(ctx context.Context, p string, ts HLCTimestamp) (*Metadata, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	filePath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	versions := append(append([]*Metadata(nil), s.history[current.Inode]...), current)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if m := versions[i]; m.HLC != nil && m.HLC.Compare(ts) <= 0 {
+			return m.Clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("no retained version of %s at or before %s", filePath, ts)
+}
+
+// findVersion 查找 current 所属 inode 的指定版本，调用方需持有锁
+func (s *MemoryStore) findVersion(current *Metadata, version uint64) *Metadata {
+	if current.Version == version {
+		return current
+	}
+	for _, m := range s.history[current.Inode] {
+		if m.Version == version {
+			return m
+		}
+	}
+	return nil
+}
+
+// RestoreVersion 把文件的属性恢复为指定版本，作为一个新版本写入并返回其副本
+//
+// 只恢复元数据属性，数据（大小、数据块、内联数据）、链接数和写入者保持当前状态，
+// 因为历史版本引用的数据块可能已被回收。恢复会检查配额和条带布局，与 Update 一样记录变更。
+func (s *MemoryStore) RestoreVersion(ctx context.Context, p string, version uint64) (*Metadata, error) {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	filePath := s.locate(normalizePath(p))
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	old := s.findVersion(current, version)
+	if old == nil {
+		return nil, fmt.Errorf("version %d of %s is not retained", version, filePath)
+	}
+	if old == current {
+		return current.Clone(), nil
+	}
+
+	data := current.Clone()
+	restored := old.Clone()
+	restored.Name = data.Name
+	restored.Size = data.Size
+	restored.Blocks = data.Blocks
+	restored.Extents = data.Extents
+	restored.BlockCount = data.BlockCount
+	restored.InlineData = data.InlineData
+	restored.Links = data.Links
+	restored.Writer = data.Writer
+	if err := s.replace(ctx, filePath, current, restored); err != nil {
+		return nil, err
+	}
+	s.log.Info("Restored metadata version",
+		zap.String("path", filePath),
+		zap.Uint64("from_version", version),
+		zap.Uint64("version", restored.Version),
+	)
+	return restored.Clone(), nil
+}
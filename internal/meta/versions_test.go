@@ -0,0 +1,98 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionHistory(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.SetVersionHistory(2)
+
+	created, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	_, err = store.SetAttr(ctx, "/f", Attrs{Mode: 0600}, SetAttrMode)
+	require.NoError(t, err)
+	_, err = store.SetAttr(ctx, "/f", Attrs{Owner: "alice"}, SetAttrOwner)
+	require.NoError(t, err)
+	require.NoError(t, store.Rename(ctx, "/f", "/g"))
+
+	// 只保留最近 2 个历史版本，重命名后仍可查询
+	versions, err := store.ListVersions(ctx, "/g")
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	for i, v := range []uint64{2, 3, 4} {
+		assert.Equal(t, v, versions[i].Version)
+		assert.Equal(t, created.Inode, versions[i].Inode)
+	}
+	assert.Equal(t, "f", versions[1].Name)
+	assert.Equal(t, "g", versions[2].Name)
+
+	m, err := store.GetVersion(ctx, "/g", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "", m.Owner)
+	assert.Equal(t, 0600, int(m.Mode.Perm()))
+	_, err = store.GetVersion(ctx, "/g", 1)
+	assert.Error(t, err)
+
+	// 返回的是副本
+	m.Owner = "mallory"
+	m, err = store.GetVersion(ctx, "/g", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "", m.Owner)
+
+	// 删除后历史随之丢弃
+	require.NoError(t, store.Delete(ctx, "/g"))
+	_, err = store.Create(ctx, "/g", 0644)
+	require.NoError(t, err)
+	versions, err = store.ListVersions(ctx, "/g")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestRestoreVersion(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.SetVersionHistory(10)
+
+	_, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+	_, err = store.SetAttr(ctx, "/f", Attrs{Mode: 0600, Owner: "alice"}, SetAttrMode|SetAttrOwner)
+	require.NoError(t, err)
+	m, err := store.Get(ctx, "/f")
+	require.NoError(t, err)
+	m.Blocks = []Block{{ID: "blk-0", Size: 10}}
+	m.Size = 10
+	require.NoError(t, store.Update(ctx, "/f", m))
+	_, err = store.SetAttr(ctx, "/f", Attrs{Mode: 0640}, SetAttrMode)
+	require.NoError(t, err)
+
+	// 恢复属性，保留当前数据
+	restored, err := store.RestoreVersion(ctx, "/f", 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), restored.Version)
+	assert.Equal(t, 0644, int(restored.Mode.Perm()))
+	assert.Equal(t, "", restored.Owner)
+	assert.Equal(t, int64(10), restored.Size)
+	assert.Equal(t, []Block{{ID: "blk-0", Size: 10}}, restored.Blocks)
+
+	current, err := store.Get(ctx, "/f")
+	require.NoError(t, err)
+	assert.Equal(t, restored.Mode, current.Mode)
+	versions, err := store.ListVersions(ctx, "/f")
+	require.NoError(t, err)
+	assert.Len(t, versions, 5)
+
+	_, err = store.RestoreVersion(ctx, "/f", 42)
+	assert.Error(t, err)
+
+	// 关闭后不再保留
+	store.SetVersionHistory(0)
+	versions, err = store.ListVersions(ctx, "/f")
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
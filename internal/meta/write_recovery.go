@@ -0,0 +1,219 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// ErrFileBusy 文件正被其他客户端写入，可用 errors.Is 判断
+var ErrFileBusy = errors.New("file is open for writing by another client")
+
+// WriteRecovery 一个崩溃客户端遗留文件的恢复结果
+type WriteRecovery struct {
+	Path    string  `json:"path"`
+	Client  string  `json:"client"`   // 崩溃的写入客户端
+	OldSize int64   `json:"old_size"` // 恢复前记录的文件大小
+	NewSize int64   `json:"new_size"` // 截断到的一致偏移
+	Dropped []Block `json:"dropped,omitempty"`
+}
+
+// RecoverWritesOptions RecoverWrites 的选项
+type RecoverWritesOptions struct {
+	// Alive 判断写入客户端是否仍然存活，存活客户端持有的文件不做处理
+	Alive func(client string) bool
+	// Reader 读取数据块副本用于校验
+	Reader BlockReader
+	// Checksum 计算数据块校验和，为 nil 时使用 SHA-256 十六进制编码
+	Checksum func(data []byte) string
+	// Collector 接收被丢弃的数据块，为 nil 时不调度回收
+	Collector BlockCollector
+}
+
+// OpenForWrite 将文件标记为正被 client 写入
+//
+// 标记随元数据持久化，客户端崩溃后仍然保留，由 RecoverWrites 处理。
+// 文件已被其他客户端标记时返回 ErrFileBusy。
+func (s *MemoryStore) OpenForWrite(ctx context.Context, p, client string) error {
+	if client == "" {
+		return fmt.Errorf("writer client id is required")
+	}
+	return s.setWriter(ctx, p, client, "")
+}
+
+// CloseWrite 清除 client 对文件的写入标记，写入的数据块全部确认后调用
+func (s *MemoryStore) CloseWrite(ctx context.Context, p, client string) error {
+	return s.setWriter(ctx, p, "", client)
+}
+
+// setWriter 将写入标记从 from 改为 to，to 为空时要求当前持有者为 from
+func (s *MemoryStore) setWriter(ctx context.Context, p, to, from string) error {
+	unlock, err := s.lockFenced(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	filePath := normalizePath(p)
+	current, exists := s.tree.get(filePath)
+	if !exists {
+		return fmt.Errorf("file not found: %s", filePath)
+	}
+	if current.Type != TypeRegular {
+		return fmt.Errorf("path is not a file: %s", filePath)
+	}
+	holder := from
+	if to != "" {
+		holder = to
+	}
+	if current.Writer != "" && current.Writer != holder {
+		return fmt.Errorf("%w: %s held by %s", ErrFileBusy, filePath, current.Writer)
+	}
+	if current.Writer == to {
+		return nil
+	}
+
+	updated := current.Clone()
+	updated.Writer = to
+	updated.Version++
+	s.put(filePath, updated)
+	s.recordChange(ctx, ChangeModify, filePath, updated)
+	return nil
+}
+
+// OpenWrites 返回仍带有写入标记的文件及其写入客户端
+func (s *MemoryStore) OpenWrites(ctx context.Context) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	open := make(map[string]string)
+	for p, m := range s.tree.all() {
+		if m.Writer != "" {
+			open[p] = m.Writer
+		}
+	}
+	return open
+}
+
+// blockIntact 判断数据块是否至少有一个长度和校验和都正确的副本，返回完好的副本位置
+func blockIntact(ctx context.Context, reader BlockReader, block Block, checksum func([]byte) string) []string {
+	var intact []string
+	for _, location := range block.Locations {
+		data, err := reader.ReadBlock(ctx, location, block)
+		if err != nil || int64(len(data)) != block.Size {
+			continue
+		}
+		if block.Checksum != "" && checksum(data) != block.Checksum {
+			continue
+		}
+		intact = append(intact, location)
+	}
+	return intact
+}
+
+// recoverFile 从末尾开始校验文件的数据块，直到遇到完好的块，返回恢复后的元数据
+//
+// 崩溃客户端最后写入的块可能只到达了部分数据服务器或根本没有写完，这些块之前的数据
+// 已经被确认，因此只需要向前校验到第一个完好的块。
+func recoverFile(ctx context.Context, current *Metadata, blocks []Block, opts RecoverWritesOptions) (*Metadata, []Block) {
+	sortBlocks(blocks)
+	kept := len(blocks)
+	var dropped []Block
+	for kept > 0 {
+		last := blocks[kept-1]
+		if intact := blockIntact(ctx, opts.Reader, last, opts.Checksum); len(intact) > 0 {
+			blocks[kept-1].Locations = intact
+			break
+		}
+		dropped = append(dropped, last)
+		kept--
+	}
+
+	updated := current.Clone()
+	updated.Writer = ""
+	updated.Extents = nil
+	updated.Blocks = append([]Block{}, blocks[:kept]...)
+	updated.BlockCount = 0
+	end := int64(0)
+	if kept > 0 {
+		end = blocks[kept-1].End()
+	}
+	// 内联数据的文件没有数据块，保持原大小
+	if len(current.InlineData) == 0 {
+		updated.Size = min(current.Size, end)
+	}
+	return updated, dropped
+}
+
+// RecoverWrites 处理写入客户端已经不存活的文件
+//
+// 对每个这样的文件，从末尾开始读取未确认的数据块：没有任何完好副本的块被丢弃，
+// 文件截断到最后一个完好块的结尾，损坏的副本从块位置中移除，然后清除写入标记。
+// 校验期间文件被修改时跳过该文件，下次调用重试。
+func (s *MemoryStore) RecoverWrites(ctx context.Context, opts RecoverWritesOptions) ([]WriteRecovery, error) {
+	if opts.Alive == nil || opts.Reader == nil {
+		return nil, fmt.Errorf("alive check and block reader are required")
+	}
+	if opts.Checksum == nil {
+		opts.Checksum = sha256Checksum
+	}
+
+	open := s.OpenWrites(ctx)
+	paths := make([]string, 0, len(open))
+	for p, client := range open {
+		if !opts.Alive(client) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var recovered []WriteRecovery
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return recovered, err
+		}
+
+		s.mu.RLock()
+		current, exists := s.tree.get(p)
+		var blocks []Block
+		var err error
+		if exists {
+			current = current.Clone()
+			blocks, err = s.fileBlocks(ctx, current)
+		}
+		s.mu.RUnlock()
+		if !exists || current.Writer != open[p] {
+			continue
+		}
+		if err != nil {
+			return recovered, err
+		}
+
+		updated, dropped := recoverFile(ctx, current, blocks, opts)
+		if err := s.update(ctx, p, updated, current.Version, true); err != nil {
+			if errors.Is(err, ErrVersionConflict) {
+				continue
+			}
+			return recovered, err
+		}
+		if len(dropped) > 0 && opts.Collector != nil {
+			if err := opts.Collector.ScheduleBlocks(ctx, dropped); err != nil {
+				return recovered, fmt.Errorf("failed to schedule block collection: %v", err)
+			}
+		}
+
+		r := WriteRecovery{Path: p, Client: current.Writer, OldSize: current.Size, NewSize: updated.Size, Dropped: dropped}
+		recovered = append(recovered, r)
+		s.log.Warn("Recovered file left open by crashed client",
+			zap.String("path", p),
+			zap.String("client", r.Client),
+			zap.Int64("old_size", r.OldSize),
+			zap.Int64("new_size", r.NewSize),
+			zap.Int("dropped_blocks", len(dropped)),
+		)
+	}
+	return recovered, nil
+}
@@ -0,0 +1,107 @@
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockSink 记录被调度回收的数据块
+type blockSink struct{ blocks []Block }
+
+func (c *blockSink) ScheduleBlocks(ctx context.Context, blocks []Block) error {
+	c.blocks = append(c.blocks, blocks...)
+	return nil
+}
+
+func TestOpenForWrite(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	_, err := store.Create(ctx, "/f", 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, store.OpenForWrite(ctx, "/f", "c1"))
+	require.NoError(t, store.OpenForWrite(ctx, "/f", "c1"), "reopening by the same client is allowed")
+	assert.ErrorIs(t, store.OpenForWrite(ctx, "/f", "c2"), ErrFileBusy)
+	assert.ErrorIs(t, store.CloseWrite(ctx, "/f", "c2"), ErrFileBusy)
+	assert.Equal(t, map[string]string{"/f": "c1"}, store.OpenWrites(ctx))
+
+	require.NoError(t, store.CloseWrite(ctx, "/f", "c1"))
+	assert.Empty(t, store.OpenWrites(ctx))
+	require.NoError(t, store.OpenForWrite(ctx, "/f", "c2"))
+}
+
+func TestRecoverWrites(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	data := []byte("0123")
+
+	newFile := func(p, client string, blocks []Block) {
+		f, err := store.Create(ctx, p, 0644)
+		require.NoError(t, err)
+		f.Size = int64(4 * len(blocks))
+		f.Blocks = blocks
+		require.NoError(t, store.Update(ctx, p, f))
+		require.NoError(t, store.OpenForWrite(ctx, p, client))
+	}
+	newFile("/crashed", "dead", []Block{
+		{ID: "b1", Offset: 0, Size: 4, Checksum: sha256Checksum(data), Locations: []string{"d1"}},
+		{ID: "b2", Offset: 4, Size: 4, Locations: []string{"d1", "d2"}},
+		{ID: "b3", Offset: 8, Size: 4, Locations: []string{"d1", "d2"}},
+	})
+	newFile("/running", "live", []Block{{ID: "r1", Size: 4, Locations: []string{"d1"}}})
+
+	reader := replicaReader{
+		"d1/b1": data,
+		"d1/b2": data[:2], "d2/b2": data,
+		"d1/b3": data[:1],
+	}
+	sink := &blockSink{}
+	recovered, err := store.RecoverWrites(ctx, RecoverWritesOptions{
+		Alive:     func(client string) bool { return client == "live" },
+		Reader:    reader,
+		Collector: sink,
+	})
+	require.NoError(t, err)
+	require.Len(t, recovered, 1)
+	assert.Equal(t, "/crashed", recovered[0].Path)
+	assert.Equal(t, "dead", recovered[0].Client)
+	assert.Equal(t, int64(12), recovered[0].OldSize)
+	assert.Equal(t, int64(8), recovered[0].NewSize)
+	require.Len(t, sink.blocks, 1)
+	assert.Equal(t, "b3", sink.blocks[0].ID)
+
+	f, err := store.Get(ctx, "/crashed")
+	require.NoError(t, err)
+	assert.Empty(t, f.Writer)
+	assert.Equal(t, int64(8), f.Size)
+	blocks, err := store.GetBlockRange(ctx, "/crashed", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, []string{"d2"}, blocks[1].Locations, "the torn replica is removed")
+	assert.Equal(t, map[string]string{"/running": "live"}, store.OpenWrites(ctx))
+
+	// 没有任何完好数据块时截断为空文件
+	newFile("/empty", "dead", []Block{{ID: "e1", Size: 4, Locations: []string{"d9"}}})
+	recovered, err = store.RecoverWrites(ctx, RecoverWritesOptions{
+		Alive:  func(client string) bool { return client == "live" },
+		Reader: reader,
+	})
+	require.NoError(t, err)
+	require.Len(t, recovered, 1)
+	assert.Equal(t, int64(0), recovered[0].NewSize)
+
+	_, err = store.RecoverWrites(ctx, RecoverWritesOptions{})
+	assert.Error(t, err)
+}
+
+func TestWriterProtoRoundTrip(t *testing.T) {
+	f := &Metadata{Name: "f", Writer: "client-1"}
+	data, err := f.MarshalBinary()
+	require.NoError(t, err)
+	decoded := &Metadata{}
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, "client-1", decoded.Writer)
+}
@@ -0,0 +1,55 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCrossZone 源路径和目标路径位于不同的加密区域，对应 EXDEV，可用 errors.Is 判断
+var ErrCrossZone = errors.New("cross-zone link or rename")
+
+// ErrContainsZone 目录的子树中有加密区域的根目录，不能整体移动或删除，可用 errors.Is 判断
+var ErrContainsZone = errors.New("directory contains an encryption zone")
+
+// ZoneLookup 加密区域查询，encryption.ZoneManager 满足该接口
+//
+// 区域按根目录的路径记录，移动或删除包含区域根目录的目录后，原区域内的文件会脱离区域。
+type ZoneLookup interface {
+	// ZoneRoot 返回路径所属加密区域的根目录，路径不在任何区域内时返回空字符串
+	ZoneRoot(p string) string
+	// ContainsZone 判断目录 dir 的子树中（不含 dir 本身）是否有加密区域的根目录
+	ContainsZone(dir string) bool
+}
+
+// SetZoneLookup 设置加密区域查询，为 nil 时不检查区域边界
+//
+// 区域内的数据块用区域的数据密钥加密，设置后跨越区域边界的重命名、交换和硬链接返回 ErrCrossZone，
+// 调用方需要像处理 EXDEV 一样复制数据；重命名、交换或删除包含区域根目录的目录返回 ErrContainsZone。
+// 查询按源目录中的路径进行。
+func (s *MemoryStore) SetZoneLookup(lookup ZoneLookup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zones = lookup
+}
+
+// checkSameZone 检查 from 与 to 位于同一个加密区域，调用方需持有锁
+func (s *MemoryStore) checkSameZone(from, to string) error {
+	if s.zones == nil {
+		return nil
+	}
+	if zoneFrom, zoneTo := s.zones.ZoneRoot(from), s.zones.ZoneRoot(to); zoneFrom != zoneTo {
+		return fmt.Errorf("%w: %s (zone %q) -> %s (zone %q)", ErrCrossZone, from, zoneFrom, to, zoneTo)
+	}
+	return nil
+}
+
+// checkNoZoneBelow 检查目录 p 的子树中没有加密区域的根目录，调用方需持有锁
+func (s *MemoryStore) checkNoZoneBelow(p string) error {
+	if s.zones == nil {
+		return nil
+	}
+	if s.zones.ContainsZone(p) {
+		return fmt.Errorf("%w: %s", ErrContainsZone, p)
+	}
+	return nil
+}
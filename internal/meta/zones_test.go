@@ -0,0 +1,58 @@
+package meta
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// secureZone 以 /secure 为唯一加密区域的 ZoneLookup
+type secureZone struct{}
+
+func (secureZone) ZoneRoot(p string) string {
+	if p == "/secure" || strings.HasPrefix(p, "/secure/") {
+		return "/secure"
+	}
+	return ""
+}
+
+func (secureZone) ContainsZone(dir string) bool {
+	return dir == "/"
+}
+
+func TestZoneBoundary(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.SetZoneLookup(secureZone{})
+	for _, dir := range []string{"/secure", "/secure/sub", "/plain"} {
+		require.NoError(t, store.Mkdir(ctx, dir, 0755))
+	}
+	_, err := store.Create(ctx, "/secure/a", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/plain/b", 0644)
+	require.NoError(t, err)
+
+	// 区域内的重命名和硬链接不受影响
+	require.NoError(t, store.Rename(ctx, "/secure/a", "/secure/sub/a"))
+	require.NoError(t, store.Link(ctx, "/secure/sub/a", "/secure/a"))
+
+	// 跨越区域边界的修改被拒绝，命名空间保持不变
+	assert.ErrorIs(t, store.Rename(ctx, "/secure/a", "/plain/a"), ErrCrossZone)
+	assert.ErrorIs(t, store.Rename(ctx, "/plain/b", "/secure/b"), ErrCrossZone)
+	assert.ErrorIs(t, store.Rename(ctx, "/secure/sub", "/plain/sub"), ErrCrossZone)
+	assert.ErrorIs(t, store.RenameOnce(ctx, "op-1", "/secure/a", "/plain/a"), ErrCrossZone)
+	assert.ErrorIs(t, store.Link(ctx, "/secure/a", "/plain/a"), ErrCrossZone)
+	assert.ErrorIs(t, store.RenameExchange(ctx, "/secure/a", "/plain/b"), ErrCrossZone)
+	_, err = store.Get(ctx, "/secure/a")
+	assert.NoError(t, err)
+	_, err = store.Get(ctx, "/plain/a")
+	assert.Error(t, err)
+
+	tmp, err := store.CreateTemp(ctx, "/secure", 0644)
+	require.NoError(t, err)
+	assert.ErrorIs(t, store.LinkTemp(ctx, tmp.Inode, "/plain/tmp"), ErrCrossZone)
+	require.NoError(t, store.LinkTemp(ctx, tmp.Inode, "/secure/tmp"))
+}
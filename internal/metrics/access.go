@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAccessWindow 访问统计的默认滚动窗口
+	DefaultAccessWindow = time.Hour
+	// DefaultAccessMaxPaths 默认最多跟踪的路径数
+	DefaultAccessMaxPaths = 100000
+
+	// accessSlots 每个路径的时间片数，窗口按此等分
+	accessSlots = 60
+)
+
+// AccessOrder 热点排序依据
+type AccessOrder string
+
+const (
+	AccessByReads   AccessOrder = "reads"   // 按读取次数
+	AccessByBytes   AccessOrder = "bytes"   // 按读取字节数
+	AccessByClients AccessOrder = "clients" // 按不同客户端数
+)
+
+// ParseAccessOrder 解析排序依据，空字符串为 AccessByReads
+func ParseAccessOrder(s string) (AccessOrder, error) {
+	switch o := AccessOrder(s); o {
+	case "":
+		return AccessByReads, nil
+	case AccessByReads, AccessByBytes, AccessByClients:
+		return o, nil
+	default:
+		return "", fmt.Errorf("unknown access order: %q", s)
+	}
+}
+
+// AccessOptions 访问统计选项
+type AccessOptions struct {
+	// Window 统计窗口，为 0 时使用 DefaultAccessWindow
+	Window time.Duration
+	// MaxPaths 最多跟踪的路径数，超过时淘汰最久未访问的路径，为 0 时使用 DefaultAccessMaxPaths
+	MaxPaths int
+}
+
+// AccessStat 一个文件或目录在窗口内的访问统计
+type AccessStat struct {
+	Path    string `json:"path"`
+	Reads   uint64 `json:"reads"`
+	Bytes   uint64 `json:"bytes"`
+	Clients int    `json:"clients"` // 不同客户端数
+}
+
+// accessCounter 单个路径按时间片滚动的计数
+type accessCounter struct {
+	ids     []int64
+	reads   []uint64
+	bytes   []uint64
+	clients map[string]int64 // 客户端 -> 最近一次访问的时间片
+	last    int64
+}
+
+// AccessTracker 滚动统计文件和目录的访问热度
+//
+// 每次读取按路径计入分钟级时间片，查询时汇总窗口内的读取次数、字节数和不同客户端数，
+// 目录的统计为其直接子文件之和。结果用于指导分层和缓存容量规划，不要求精确：
+// 跟踪的路径数有上限，超过时淘汰最久未访问的路径。
+type AccessTracker struct {
+	width    time.Duration
+	maxPaths int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	files map[string]*accessCounter
+}
+
+// NewAccessTracker 创建访问统计
+func NewAccessTracker(opts AccessOptions) *AccessTracker {
+	if opts.Window <= 0 {
+		opts.Window = DefaultAccessWindow
+	}
+	if opts.MaxPaths <= 0 {
+		opts.MaxPaths = DefaultAccessMaxPaths
+	}
+	return &AccessTracker{
+		width:    max(opts.Window/accessSlots, time.Nanosecond),
+		maxPaths: opts.MaxPaths,
+		now:      time.Now,
+		files:    make(map[string]*accessCounter),
+	}
+}
+
+// Record 记录 client 对文件 p 的一次读取
+func (t *AccessTracker) Record(p, client string, bytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.now().UnixNano() / int64(t.width)
+	c, ok := t.files[p]
+	if !ok {
+		if len(t.files) >= t.maxPaths {
+			t.evictLocked(id)
+		}
+		c = &accessCounter{
+			ids:     make([]int64, accessSlots),
+			reads:   make([]uint64, accessSlots),
+			bytes:   make([]uint64, accessSlots),
+			clients: make(map[string]int64),
+		}
+		t.files[p] = c
+	}
+	slot := int(id % accessSlots)
+	if c.ids[slot] != id {
+		c.ids[slot], c.reads[slot], c.bytes[slot] = id, 0, 0
+	}
+	c.reads[slot]++
+	if bytes > 0 {
+		c.bytes[slot] += uint64(bytes)
+	}
+	if client != "" {
+		c.clients[client] = id
+	}
+	c.last = id
+}
+
+// evictLocked 删除窗口外的路径，仍超过上限时删除最久未访问的十分之一，调用方需持有锁
+func (t *AccessTracker) evictLocked(id int64) {
+	for p, c := range t.files {
+		if c.last <= id-accessSlots {
+			delete(t.files, p)
+		}
+	}
+	if len(t.files) < t.maxPaths {
+		return
+	}
+	paths := make([]string, 0, len(t.files))
+	for p := range t.files {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return t.files[paths[i]].last < t.files[paths[j]].last
+	})
+	for _, p := range paths[:max(len(paths)/10, 1)] {
+		delete(t.files, p)
+	}
+}
+
+// statLocked 汇总路径在截至 id 的窗口内的计数，同时清理窗口外的客户端，调用方需持有锁
+func (c *accessCounter) statLocked(id int64) (reads, bytes uint64) {
+	for slot, slotID := range c.ids {
+		if slotID > id-accessSlots {
+			reads += c.reads[slot]
+			bytes += c.bytes[slot]
+		}
+	}
+	for client, last := range c.clients {
+		if last <= id-accessSlots {
+			delete(c.clients, client)
+		}
+	}
+	return reads, bytes
+}
+
+// TopFiles 返回窗口内访问最多的 n 个文件，n 为 0 时返回全部
+func (t *AccessTracker) TopFiles(n int, order AccessOrder) []AccessStat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.now().UnixNano() / int64(t.width)
+	var stats []AccessStat
+	for p, c := range t.files {
+		reads, bytes := c.statLocked(id)
+		if reads == 0 {
+			continue
+		}
+		stats = append(stats, AccessStat{Path: p, Reads: reads, Bytes: bytes, Clients: len(c.clients)})
+	}
+	return topAccess(stats, n, order)
+}
+
+// TopDirectories 返回窗口内访问最多的 n 个目录，按直接子文件汇总，n 为 0 时返回全部
+func (t *AccessTracker) TopDirectories(n int, order AccessOrder) []AccessStat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.now().UnixNano() / int64(t.width)
+	dirs := make(map[string]*AccessStat)
+	clients := make(map[string]map[string]bool)
+	for p, c := range t.files {
+		reads, bytes := c.statLocked(id)
+		if reads == 0 {
+			continue
+		}
+		dir := path.Dir(p)
+		s, ok := dirs[dir]
+		if !ok {
+			s = &AccessStat{Path: dir}
+			dirs[dir] = s
+			clients[dir] = make(map[string]bool)
+		}
+		s.Reads += reads
+		s.Bytes += bytes
+		for client := range c.clients {
+			clients[dir][client] = true
+		}
+	}
+	stats := make([]AccessStat, 0, len(dirs))
+	for dir, s := range dirs {
+		s.Clients = len(clients[dir])
+		stats = append(stats, *s)
+	}
+	return topAccess(stats, n, order)
+}
+
+// topAccess 按排序依据降序排列并截取前 n 个，相同时按路径排序
+func topAccess(stats []AccessStat, n int, order AccessOrder) []AccessStat {
+	key := func(s *AccessStat) uint64 {
+		switch order {
+		case AccessByBytes:
+			return s.Bytes
+		case AccessByClients:
+			return uint64(s.Clients)
+		default:
+			return s.Reads
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		ki, kj := key(&stats[i]), key(&stats[j])
+		if ki != kj {
+			return ki > kj
+		}
+		return stats[i].Path < stats[j].Path
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessTracker(t *testing.T) {
+	tracker := NewAccessTracker(AccessOptions{Window: time.Hour})
+	now := time.Unix(1700000000, 0)
+	tracker.now = func() time.Time { return now }
+
+	tracker.Record("/data/a.bin", "alice", 100)
+	tracker.Record("/data/a.bin", "bob", 100)
+	tracker.Record("/data/a.bin", "alice", 100)
+	tracker.Record("/data/b.bin", "alice", 1000)
+	tracker.Record("/logs/c.log", "carol", 10)
+
+	files := tracker.TopFiles(0, AccessByReads)
+	require.Len(t, files, 3)
+	assert.Equal(t, AccessStat{Path: "/data/a.bin", Reads: 3, Bytes: 300, Clients: 2}, files[0])
+
+	files = tracker.TopFiles(1, AccessByBytes)
+	require.Len(t, files, 1)
+	assert.Equal(t, "/data/b.bin", files[0].Path)
+
+	dirs := tracker.TopDirectories(0, AccessByClients)
+	require.Len(t, dirs, 2)
+	assert.Equal(t, AccessStat{Path: "/data", Reads: 4, Bytes: 1300, Clients: 2}, dirs[0])
+	assert.Equal(t, AccessStat{Path: "/logs", Reads: 1, Bytes: 10, Clients: 1}, dirs[1])
+
+	// 半个窗口后的访问仍与之前的计数合并，超过窗口的计数被丢弃
+	now = now.Add(30 * time.Minute)
+	tracker.Record("/logs/c.log", "dave", 10)
+	files = tracker.TopFiles(0, AccessByReads)
+	assert.Equal(t, uint64(3), files[0].Reads)
+	assert.Equal(t, AccessStat{Path: "/logs/c.log", Reads: 2, Bytes: 20, Clients: 2}, files[1])
+
+	now = now.Add(45 * time.Minute)
+	files = tracker.TopFiles(0, AccessByReads)
+	assert.Equal(t, []AccessStat{{Path: "/logs/c.log", Reads: 1, Bytes: 10, Clients: 1}}, files)
+
+	now = now.Add(time.Hour)
+	assert.Empty(t, tracker.TopFiles(0, AccessByReads))
+	assert.Empty(t, tracker.TopDirectories(0, AccessByReads))
+}
+
+func TestAccessTrackerEviction(t *testing.T) {
+	tracker := NewAccessTracker(AccessOptions{MaxPaths: 3})
+	now := time.Unix(1700000000, 0)
+	tracker.now = func() time.Time { return now }
+
+	for _, p := range []string{"/a", "/b", "/c"} {
+		tracker.Record(p, "", 1)
+		now = now.Add(time.Minute)
+	}
+	tracker.Record("/d", "", 1)
+
+	files := tracker.TopFiles(0, AccessByReads)
+	require.Len(t, files, 3)
+	for _, s := range files {
+		assert.NotEqual(t, "/a", s.Path, "least recently accessed path should be evicted")
+	}
+}
+
+func TestParseAccessOrder(t *testing.T) {
+	order, err := ParseAccessOrder("")
+	require.NoError(t, err)
+	assert.Equal(t, AccessByReads, order)
+	order, err = ParseAccessOrder("bytes")
+	require.NoError(t, err)
+	assert.Equal(t, AccessByBytes, order)
+	_, err = ParseAccessOrder("latency")
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Labels 指标标签
+type Labels map[string]string
+
+// DefaultBuckets 默认的延迟直方图桶边界（秒）
+var DefaultBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// Counter 单调递增计数器
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc 计数加一
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add 增加计数
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value 返回当前计数
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Gauge 可增可减的瞬时值
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set 设置当前值
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Add 增加当前值，delta 可以为负数
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if g.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// Value 返回当前值
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// Histogram 固定桶边界的直方图
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// HistogramSnapshot 直方图快照，Counts[i] 为落入 (Buckets[i-1], Buckets[i]] 的样本数，
+// 最后一个元素为超过最大边界的样本数
+type HistogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Sum     float64
+	Count   uint64
+}
+
+// NewHistogram 创建直方图，buckets 为空时使用默认桶边界
+func NewHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{
+		buckets: sorted,
+		counts:  make([]uint64, len(sorted)+1),
+	}
+}
+
+// Observe 记录一个样本
+func (h *Histogram) Observe(v float64) {
+	idx := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	h.counts[idx]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// ObserveDuration 记录一个耗时样本（秒）
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// ObserveSince 记录从 start 到现在的耗时
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.ObserveDuration(time.Since(start))
+}
+
+// Snapshot 返回直方图快照
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  append([]uint64(nil), h.counts...),
+		Sum:     h.sum,
+		Count:   h.count,
+	}
+}
+
+// Mean 返回样本平均值
+func (s HistogramSnapshot) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// Quantile 根据桶分布估算分位数，返回样本所在桶的上边界
+func (s HistogramSnapshot) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	var cumulative uint64
+	for i, c := range s.Counts {
+		cumulative += c
+		if cumulative >= rank {
+			if i < len(s.Buckets) {
+				return s.Buckets[i]
+			}
+			return math.Inf(1)
+		}
+	}
+	return math.Inf(1)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterAndGauge(t *testing.T) {
+	var c Counter
+	c.Inc()
+	c.Add(4)
+	assert.Equal(t, uint64(5), c.Value())
+
+	var g Gauge
+	g.Set(1.5)
+	g.Add(-0.5)
+	assert.Equal(t, 1.0, g.Value())
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 5, 10})
+	for _, v := range []float64{0.5, 1, 3, 7, 20} {
+		h.Observe(v)
+	}
+	h.ObserveDuration(2 * time.Second)
+
+	snap := h.Snapshot()
+	assert.Equal(t, []uint64{2, 2, 1, 1}, snap.Counts)
+	assert.Equal(t, uint64(6), snap.Count)
+	assert.InDelta(t, 33.5, snap.Sum, 1e-9)
+	assert.InDelta(t, 33.5/6, snap.Mean(), 1e-9)
+	assert.Equal(t, 5.0, snap.Quantile(0.5))
+	assert.True(t, math.IsInf(snap.Quantile(1), 1))
+
+	assert.Equal(t, 0.0, NewHistogram(nil).Snapshot().Quantile(0.99))
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.Counter("requests_total", "Requests.", Labels{"op": "get"})
+	c.Add(3)
+	// 同名同标签返回同一实例
+	assert.Same(t, c, r.Counter("requests_total", "Requests.", Labels{"op": "get"}))
+	r.Counter("requests_total", "Requests.", Labels{"op": "put"}).Inc()
+
+	r.Gauge("temperature", "Temperature.", nil).Set(21.5)
+	r.GaugeFunc("queue_depth", "Queue depth.", nil, func() float64 { return 7 })
+	r.CounterFunc("evictions_total", "Evictions.", nil, func() float64 { return 2 })
+	r.Histogram("latency_seconds", "Latency.", nil, []float64{0.1, 1}).Observe(0.5)
+
+	// 类型冲突时 panic
+	assert.Panics(t, func() { r.Gauge("requests_total", "Requests.", nil) })
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WritePrometheus(&buf))
+	out := buf.String()
+
+	assert.Contains(t, out, "# TYPE requests_total counter\n")
+	assert.Contains(t, out, "requests_total{op=\"get\"} 3\n")
+	assert.Contains(t, out, "requests_total{op=\"put\"} 1\n")
+	assert.Contains(t, out, "temperature 21.5\n")
+	assert.Contains(t, out, "queue_depth 7\n")
+	assert.Contains(t, out, "# TYPE evictions_total counter\nevictions_total 2\n")
+	assert.Contains(t, out, "latency_seconds_bucket{le=\"0.1\"} 0\n")
+	assert.Contains(t, out, "latency_seconds_bucket{le=\"1\"} 1\n")
+	assert.Contains(t, out, "latency_seconds_bucket{le=\"+Inf\"} 1\n")
+	assert.Contains(t, out, "latency_seconds_count 1\n")
+	assert.Equal(t, 1, strings.Count(out, "# HELP requests_total"))
+
+	descs := r.Descs()
+	require.Len(t, descs, 5)
+	assert.Equal(t, "evictions_total", descs[0].Name)
+	assert.Equal(t, "latency_seconds", descs[1].Name)
+	assert.Equal(t, TypeHistogram, descs[1].Type)
+}
@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DashboardFile Export 写出的 Grafana 仪表盘文件名
+	DashboardFile = "cpfs-dashboard.json"
+	// AlertRulesFile Export 写出的 Prometheus 告警规则文件名
+	AlertRulesFile = "cpfs-alerts.yml"
+)
+
+// rateWindow 计数器和直方图面板使用的速率窗口
+const rateWindow = "5m"
+
+// subsystem 返回指标所属的子系统，即 cpfs_ 之后的第一段，例如 cpfs_meta_cache_hits_total 属于 meta
+func subsystem(name string) string {
+	name = strings.TrimPrefix(name, "cpfs_")
+	if i := strings.IndexByte(name, '_'); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// byClause 返回按标签聚合的 by 子句，没有标签时为空
+func byClause(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return " by (" + strings.Join(labels, ", ") + ")"
+}
+
+// PanelQuery 返回指标在仪表盘上展示的 PromQL 查询
+//
+// 计数器按标签聚合每秒速率，直方图按标签聚合 p99，瞬时值按标签求和。
+func PanelQuery(desc Desc, labels []string) string {
+	switch desc.Type {
+	case TypeCounter:
+		return fmt.Sprintf("sum%s (rate(%s[%s]))", byClause(labels), desc.Name, rateWindow)
+	case TypeHistogram:
+		return fmt.Sprintf("histogram_quantile(0.99, sum%s (rate(%s_bucket[%s])))",
+			byClause(append([]string{"le"}, labels...)), desc.Name, rateWindow)
+	default:
+		return fmt.Sprintf("sum%s (%s)", byClause(labels), desc.Name)
+	}
+}
+
+// legendFormat 返回按标签区分序列的图例格式
+func legendFormat(labels []string) string {
+	if len(labels) == 0 {
+		return "{{instance}}"
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = "{{" + l + "}}"
+	}
+	return strings.Join(parts, " ")
+}
+
+// panelUnit 根据指标名称推断 Grafana 单位
+func panelUnit(desc Desc) string {
+	switch {
+	case strings.HasSuffix(desc.Name, "_seconds"):
+		return "s"
+	case strings.HasSuffix(desc.Name, "_bytes") && desc.Type != TypeCounter:
+		return "bytes"
+	case strings.HasSuffix(desc.Name, "_bytes_total"):
+		return "Bps"
+	case desc.Type == TypeCounter:
+		return "ops"
+	default:
+		return "short"
+	}
+}
+
+// GenerateDashboard 按注册表中的指标生成 Grafana 仪表盘 JSON
+//
+// 每个指标一个时间序列面板，按子系统分行，查询使用注册时的指标名与标签名，
+// 因此应在服务启动、各子系统注册完指标之后用其注册表生成。
+func GenerateDashboard(r *Registry, title string) ([]byte, error) {
+	type target struct {
+		Expr         string `json:"expr"`
+		LegendFormat string `json:"legendFormat"`
+		RefID        string `json:"refId"`
+	}
+	type gridPos struct {
+		H int `json:"h"`
+		W int `json:"w"`
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	type panel struct {
+		ID          int            `json:"id"`
+		Type        string         `json:"type"`
+		Title       string         `json:"title"`
+		Description string         `json:"description,omitempty"`
+		GridPos     gridPos        `json:"gridPos"`
+		Datasource  map[string]any `json:"datasource,omitempty"`
+		Targets     []target       `json:"targets,omitempty"`
+		FieldConfig map[string]any `json:"fieldConfig,omitempty"`
+	}
+
+	datasource := map[string]any{"type": "prometheus", "uid": "${datasource}"}
+	var panels []panel
+	y, current := 0, ""
+	col := 0
+	for _, desc := range r.Descs() {
+		if sub := subsystem(desc.Name); sub != current {
+			if col != 0 {
+				y += 8
+				col = 0
+			}
+			current = sub
+			panels = append(panels, panel{ID: len(panels) + 1, Type: "row", Title: sub, GridPos: gridPos{H: 1, W: 24, Y: y}})
+			y++
+		}
+		labels := r.LabelNames(desc.Name)
+		panels = append(panels, panel{
+			ID:          len(panels) + 1,
+			Type:        "timeseries",
+			Title:       desc.Name,
+			Description: desc.Help,
+			GridPos:     gridPos{H: 8, W: 12, X: col * 12, Y: y},
+			Datasource:  datasource,
+			Targets:     []target{{Expr: PanelQuery(desc, labels), LegendFormat: legendFormat(labels), RefID: "A"}},
+			FieldConfig: map[string]any{"defaults": map[string]any{"unit": panelUnit(desc)}},
+		})
+		if col++; col == 2 {
+			y += 8
+			col = 0
+		}
+	}
+
+	dashboard := map[string]any{
+		"title":         title,
+		"uid":           "cpfs",
+		"schemaVersion": 39,
+		"tags":          []string{"cpfs"},
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"refresh":       "30s",
+		"templating": map[string]any{"list": []any{map[string]any{
+			"name":  "datasource",
+			"type":  "datasource",
+			"query": "prometheus",
+		}}},
+		"panels": panels,
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
+
+// AlertRule 一条 Prometheus 告警规则
+type AlertRule struct {
+	Alert       string
+	Expr        string
+	For         string
+	Severity    string
+	Summary     string
+	Description string
+}
+
+// alertName 把指标名转换为告警名，例如 cpfs_snapshot_failures_total 转换为 CpfsSnapshotFailures
+func alertName(name string) string {
+	name = strings.TrimSuffix(name, "_total")
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// AlertRules 按注册表中的指标生成告警规则
+//
+// 失败计数器（_failures_total、_errors_total）持续增长、积压指标（_lag）持续上升、
+// SLO 燃烧率告警触发以及内存用量接近预算时告警；其他指标只出现在仪表盘中。
+func AlertRules(r *Registry) []AlertRule {
+	descs := r.Descs()
+	names := make(map[string]bool, len(descs))
+	for _, desc := range descs {
+		names[desc.Name] = true
+	}
+
+	var rules []AlertRule
+	for _, desc := range descs {
+		by := byClause(r.LabelNames(desc.Name))
+		switch {
+		case desc.Type == TypeCounter && (strings.HasSuffix(desc.Name, "_failures_total") || strings.HasSuffix(desc.Name, "_errors_total")):
+			rules = append(rules, AlertRule{
+				Alert:       alertName(desc.Name),
+				Expr:        fmt.Sprintf("sum%s (rate(%s[10m])) > 0", by, desc.Name),
+				For:         "15m",
+				Severity:    "warning",
+				Summary:     desc.Name + " is increasing",
+				Description: desc.Help,
+			})
+		case desc.Type == TypeGauge && strings.HasSuffix(desc.Name, "_lag"):
+			rules = append(rules, AlertRule{
+				Alert:       alertName(desc.Name) + "Growing",
+				Expr:        fmt.Sprintf("sum%s (deriv(%s[15m])) > 0", by, desc.Name),
+				For:         "30m",
+				Severity:    "warning",
+				Summary:     desc.Name + " keeps growing",
+				Description: desc.Help,
+			})
+		case desc.Name == "cpfs_slo_alert":
+			for _, severity := range []Severity{SeverityPage, SeverityTicket} {
+				rules = append(rules, AlertRule{
+					Alert:       "CpfsSLOBurnRate" + alertName(string(severity)),
+					Expr:        fmt.Sprintf("%s{severity=%q} == 1", desc.Name, severity),
+					For:         "2m",
+					Severity:    string(severity),
+					Summary:     "SLO {{ $labels.slo }} is burning its error budget",
+					Description: desc.Help,
+				})
+			}
+		}
+	}
+	if names["cpfs_memory_used_bytes"] && names["cpfs_memory_budget_bytes"] {
+		rules = append(rules, AlertRule{
+			Alert:       "CpfsMemoryBudgetNearlyExhausted",
+			Expr:        "cpfs_memory_used_bytes / cpfs_memory_budget_bytes > 0.95 and cpfs_memory_budget_bytes > 0",
+			For:         "10m",
+			Severity:    "warning",
+			Summary:     "cache memory is above 95% of the budget",
+			Description: "Caches are close to the shared memory budget and are evicting aggressively.",
+		})
+	}
+	return rules
+}
+
+// GenerateAlertRules 生成 Prometheus 告警规则文件（YAML）
+func GenerateAlertRules(r *Registry) []byte {
+	var b bytes.Buffer
+	b.WriteString("groups:\n  - name: cpfs\n    rules:\n")
+	for _, rule := range AlertRules(r) {
+		fmt.Fprintf(&b, "      - alert: %s\n", rule.Alert)
+		fmt.Fprintf(&b, "        expr: %s\n", strconv.Quote(rule.Expr))
+		fmt.Fprintf(&b, "        for: %s\n", rule.For)
+		fmt.Fprintf(&b, "        labels:\n          severity: %s\n", rule.Severity)
+		fmt.Fprintf(&b, "        annotations:\n          summary: %s\n          description: %s\n",
+			strconv.Quote(rule.Summary), strconv.Quote(rule.Description))
+	}
+	return b.Bytes()
+}
+
+// Export 把仪表盘和告警规则写入目录 dir，返回写出的文件路径
+func Export(r *Registry, dir string) ([]string, error) {
+	dashboard, err := GenerateDashboard(r, "CPFS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate dashboard: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %v", err)
+	}
+	files := map[string][]byte{
+		DashboardFile:  dashboard,
+		AlertRulesFile: GenerateAlertRules(r),
+	}
+	paths := make([]string, 0, len(files))
+	for _, name := range []string{DashboardFile, AlertRulesFile} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, files[name], 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
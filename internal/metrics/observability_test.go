@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// observedRegistry 注册几个不同子系统和类型的指标
+func observedRegistry() *Registry {
+	r := NewRegistry()
+	r.Counter("cpfs_meta_cache_hits_total", "Cache hits.", nil)
+	r.Counter("cpfs_snapshot_failures_total", "Failed snapshots.", Labels{"path": "/a"})
+	r.Histogram("cpfs_storage_op_duration_seconds", "Storage latency.", Labels{"op": "get"}, DefaultBuckets)
+	r.Histogram("cpfs_storage_op_duration_seconds", "Storage latency.", Labels{"op": "put"}, DefaultBuckets)
+	r.GaugeFunc("cpfs_event_sink_lag", "Unpublished events.", Labels{"sink": "hook"}, func() float64 { return 0 })
+	r.Gauge("cpfs_memory_used_bytes", "Used.", nil)
+	r.Gauge("cpfs_memory_budget_bytes", "Budget.", nil)
+	r.GaugeFunc("cpfs_slo_alert", "Firing.", Labels{"slo": "read", "severity": "page"}, func() float64 { return 0 })
+	return r
+}
+
+func TestGenerateDashboard(t *testing.T) {
+	r := observedRegistry()
+	assert.Equal(t, []string{"op"}, r.LabelNames("cpfs_storage_op_duration_seconds"))
+	assert.Nil(t, r.LabelNames("missing"))
+
+	out, err := GenerateDashboard(r, "CPFS")
+	require.NoError(t, err)
+	var dashboard struct {
+		Title  string `json:"title"`
+		Panels []struct {
+			Type    string `json:"type"`
+			Title   string `json:"title"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	require.NoError(t, json.Unmarshal(out, &dashboard))
+	assert.Equal(t, "CPFS", dashboard.Title)
+
+	exprs := make(map[string]string)
+	var rows []string
+	for _, p := range dashboard.Panels {
+		if p.Type == "row" {
+			rows = append(rows, p.Title)
+			continue
+		}
+		require.Len(t, p.Targets, 1)
+		exprs[p.Title] = p.Targets[0].Expr
+	}
+	// 每个注册的指标都有面板
+	for _, desc := range r.Descs() {
+		assert.Contains(t, exprs, desc.Name)
+	}
+	assert.Equal(t, []string{"event", "memory", "meta", "slo", "snapshot", "storage"}, rows)
+	assert.Equal(t, "sum (rate(cpfs_meta_cache_hits_total[5m]))", exprs["cpfs_meta_cache_hits_total"])
+	assert.Equal(t, "histogram_quantile(0.99, sum by (le, op) (rate(cpfs_storage_op_duration_seconds_bucket[5m])))",
+		exprs["cpfs_storage_op_duration_seconds"])
+	assert.Equal(t, "sum by (sink) (cpfs_event_sink_lag)", exprs["cpfs_event_sink_lag"])
+}
+
+func TestGenerateAlertRules(t *testing.T) {
+	r := observedRegistry()
+	rules := AlertRules(r)
+	names := make(map[string]string)
+	for _, rule := range rules {
+		names[rule.Alert] = rule.Expr
+	}
+	assert.Equal(t, "sum by (path) (rate(cpfs_snapshot_failures_total[10m])) > 0", names["CpfsSnapshotFailures"])
+	assert.Equal(t, "sum by (sink) (deriv(cpfs_event_sink_lag[15m])) > 0", names["CpfsEventSinkLagGrowing"])
+	assert.Equal(t, `cpfs_slo_alert{severity="page"} == 1`, names["CpfsSLOBurnRatePage"])
+	assert.Contains(t, names, "CpfsMemoryBudgetNearlyExhausted")
+	assert.NotContains(t, names, "CpfsMetaCacheHits")
+
+	dir := t.TempDir()
+	paths, err := Export(r, filepath.Join(dir, "out"))
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	data, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	yaml := string(data)
+	assert.True(t, strings.HasPrefix(yaml, "groups:\n  - name: cpfs\n    rules:\n"))
+	assert.Contains(t, yaml, "      - alert: CpfsSnapshotFailures\n        expr: \"sum by (path) (rate(cpfs_snapshot_failures_total[10m])) > 0\"\n")
+	assert.Contains(t, yaml, "expr: \"cpfs_slo_alert{severity=\\\"page\\\"} == 1\"")
+}
@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type 指标类型
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// Desc 指标描述
+type Desc struct {
+	Name string
+	Help string
+	Type Type
+}
+
+// series 带标签的单条时间序列
+type series struct {
+	labels      Labels
+	counter     *Counter
+	counterFunc func() float64
+	gauge       *Gauge
+	gaugeFunc   func() float64
+	histogram   *Histogram
+}
+
+// family 同名指标族
+type family struct {
+	desc   Desc
+	series map[string]*series
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// Default 默认的全局注册表
+var Default = NewRegistry()
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// labelKey 返回标签的规范化键
+func labelKey(labels Labels) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// getOrCreate 获取或创建时间序列，同名指标类型不一致时 panic
+func (r *Registry) getOrCreate(desc Desc, labels Labels, create func() *series) *series {
+	key := labelKey(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[desc.Name]
+	if !ok {
+		f = &family{desc: desc, series: make(map[string]*series)}
+		r.families[desc.Name] = f
+	} else if f.desc.Type != desc.Type {
+		panic(fmt.Sprintf("metric %s registered as %s, requested as %s", desc.Name, f.desc.Type, desc.Type))
+	}
+
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		s.labels = labels
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter 获取或创建计数器
+func (r *Registry) Counter(name, help string, labels Labels) *Counter {
+	s := r.getOrCreate(Desc{Name: name, Help: help, Type: TypeCounter}, labels, func() *series {
+		return &series{counter: &Counter{}}
+	})
+	return s.counter
+}
+
+// CounterFunc 注册在采集时读取的计数器，同名同标签重复注册时替换回调
+func (r *Registry) CounterFunc(name, help string, labels Labels, fn func() float64) {
+	s := r.getOrCreate(Desc{Name: name, Help: help, Type: TypeCounter}, labels, func() *series {
+		return &series{}
+	})
+	r.mu.Lock()
+	s.counterFunc = fn
+	r.mu.Unlock()
+}
+
+// Gauge 获取或创建瞬时值指标
+func (r *Registry) Gauge(name, help string, labels Labels) *Gauge {
+	s := r.getOrCreate(Desc{Name: name, Help: help, Type: TypeGauge}, labels, func() *series {
+		return &series{gauge: &Gauge{}}
+	})
+	return s.gauge
+}
+
+// GaugeFunc 注册在采集时计算的瞬时值指标，同名同标签重复注册时替换回调
+func (r *Registry) GaugeFunc(name, help string, labels Labels, fn func() float64) {
+	s := r.getOrCreate(Desc{Name: name, Help: help, Type: TypeGauge}, labels, func() *series {
+		return &series{}
+	})
+	r.mu.Lock()
+	s.gaugeFunc = fn
+	r.mu.Unlock()
+}
+
+// Histogram 获取或创建直方图
+func (r *Registry) Histogram(name, help string, labels Labels, buckets []float64) *Histogram {
+	s := r.getOrCreate(Desc{Name: name, Help: help, Type: TypeHistogram}, labels, func() *series {
+		return &series{histogram: NewHistogram(buckets)}
+	})
+	return s.histogram
+}
+
+// Descs 返回所有已注册指标的描述，按名称排序
+func (r *Registry) Descs() []Desc {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	descs := make([]Desc, 0, len(r.families))
+	for _, f := range r.families {
+		descs = append(descs, f.desc)
+	}
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].Name < descs[j].Name
+	})
+	return descs
+}
+
+// LabelNames 返回指标族全部时间序列使用的标签名，按字典序排列，指标不存在时返回 nil
+func (r *Registry) LabelNames(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		return nil
+	}
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, s := range f.series {
+		for k := range s.labels {
+			if !seen[k] {
+				seen[k] = true
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatLabels 以 Prometheus 文本格式输出标签
+func formatLabels(labels Labels, extra ...string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys)+len(extra)/2)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatFloat 格式化数值
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WritePrometheus 以 Prometheus 文本格式输出所有指标
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.desc.Help, name, f.desc.Type); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s := f.series[key]
+			var err error
+			switch {
+			case s.counter != nil:
+				_, err = fmt.Fprintf(w, "%s%s %d\n", name, formatLabels(s.labels), s.counter.Value())
+			case s.counterFunc != nil:
+				_, err = fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(s.labels), formatFloat(s.counterFunc()))
+			case s.gauge != nil:
+				_, err = fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(s.labels), formatFloat(s.gauge.Value()))
+			case s.gaugeFunc != nil:
+				_, err = fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(s.labels), formatFloat(s.gaugeFunc()))
+			case s.histogram != nil:
+				err = writeHistogram(w, name, s.labels, s.histogram.Snapshot())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeHistogram 输出直方图的累积桶、总和与计数
+func writeHistogram(w io.Writer, name string, labels Labels, snap HistogramSnapshot) error {
+	var cumulative uint64
+	for i, c := range snap.Counts {
+		cumulative += c
+		le := math.Inf(1)
+		if i < len(snap.Buckets) {
+			le = snap.Buckets[i]
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(labels, "le", formatFloat(le)), cumulative); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n",
+		name, formatLabels(labels), formatFloat(snap.Sum),
+		name, formatLabels(labels), snap.Count)
+	return err
+}
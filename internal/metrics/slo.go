@@ -0,0 +1,351 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSLOWindow 错误预算的默认统计周期
+	DefaultSLOWindow = 30 * 24 * time.Hour
+
+	// 多窗口燃烧率告警阈值：1 小时内消耗 2% 预算时告警（page），6 小时内消耗 5% 时提醒（ticket）
+	pageBurnRate   = 14.4
+	ticketBurnRate = 6
+
+	// 短窗口使用分钟粒度，覆盖最长的 6 小时告警窗口
+	minuteSlots = 6 * 60
+)
+
+// burnWindows 导出燃烧率的窗口
+var burnWindows = []struct {
+	name   string
+	window time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// Severity 燃烧率告警级别
+type Severity string
+
+const (
+	SeverityNone   Severity = ""       // 未告警
+	SeverityTicket Severity = "ticket" // 慢速消耗，需要在工作时间处理
+	SeverityPage   Severity = "page"   // 快速消耗，需要立即处理
+)
+
+// SLO 服务等级目标
+type SLO struct {
+	Name string
+	// Methods 计入的 gRPC 方法名后缀，为空表示全部方法
+	Methods []string
+	// Objective 达标请求的目标比例，例如 0.999
+	Objective float64
+	// LatencyThreshold 延迟阈值，超过即不达标；为 0 时按请求是否出错判断可用性
+	LatencyThreshold time.Duration
+	// Window 错误预算的统计周期，为 0 时使用 DefaultSLOWindow
+	Window time.Duration
+}
+
+// matches 判断方法是否计入该目标
+func (s *SLO) matches(method string) bool {
+	if len(s.Methods) == 0 {
+		return true
+	}
+	for _, m := range s.Methods {
+		if strings.HasSuffix(method, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// SLOAlert 告警级别变化事件
+type SLOAlert struct {
+	SLO      string
+	Severity Severity // 新的告警级别，SeverityNone 表示恢复
+	BurnRate float64  // 触发判断时长窗口的燃烧率
+	Time     time.Time
+}
+
+// eventRing 按固定时间粒度滚动的达标/不达标计数
+type eventRing struct {
+	width time.Duration
+	ids   []int64
+	good  []uint64
+	bad   []uint64
+}
+
+// newEventRing 创建覆盖 slots*width 时长的计数环
+func newEventRing(width time.Duration, slots int) *eventRing {
+	return &eventRing{
+		width: width,
+		ids:   make([]int64, slots),
+		good:  make([]uint64, slots),
+		bad:   make([]uint64, slots),
+	}
+}
+
+// add 在 now 所在的时间片计数
+func (r *eventRing) add(now time.Time, good bool) {
+	id := now.UnixNano() / int64(r.width)
+	slot := int(id % int64(len(r.ids)))
+	if r.ids[slot] != id {
+		r.ids[slot], r.good[slot], r.bad[slot] = id, 0, 0
+	}
+	if good {
+		r.good[slot]++
+	} else {
+		r.bad[slot]++
+	}
+}
+
+// sum 返回截至 now 的最近 window 内的计数
+func (r *eventRing) sum(now time.Time, window time.Duration) (good, bad uint64) {
+	id := now.UnixNano() / int64(r.width)
+	n := min(int((window+r.width-1)/r.width), len(r.ids))
+	for k := 0; k < n; k++ {
+		slot := int((id - int64(k)) % int64(len(r.ids)))
+		if r.ids[slot] == id-int64(k) {
+			good += r.good[slot]
+			bad += r.bad[slot]
+		}
+	}
+	return good, bad
+}
+
+// sloState 单个目标的计数与告警状态
+type sloState struct {
+	slo      SLO
+	minutes  *eventRing
+	hours    *eventRing
+	severity Severity
+}
+
+// errorRatio 返回窗口内不达标请求比例，没有请求时为 0
+func errorRatio(good, bad uint64) float64 {
+	if good+bad == 0 {
+		return 0
+	}
+	return float64(bad) / float64(good+bad)
+}
+
+// burnRate 返回窗口内的错误预算燃烧率，1 表示恰好在统计周期末耗尽预算
+func (s *sloState) burnRate(now time.Time, window time.Duration) float64 {
+	budget := 1 - s.slo.Objective
+	if budget <= 0 {
+		return 0
+	}
+	return errorRatio(s.minutes.sum(now, window)) / budget
+}
+
+// budgetRemaining 返回统计周期内剩余的错误预算比例，可能为负
+func (s *sloState) budgetRemaining(now time.Time) float64 {
+	budget := 1 - s.slo.Objective
+	if budget <= 0 {
+		return 0
+	}
+	return 1 - errorRatio(s.hours.sum(now, s.slo.Window))/budget
+}
+
+// severityAt 按多窗口燃烧率判断告警级别，长短窗口同时超过阈值才告警
+func (s *sloState) severityAt(now time.Time) (Severity, float64) {
+	if long := s.burnRate(now, time.Hour); long > pageBurnRate && s.burnRate(now, 5*time.Minute) > pageBurnRate {
+		return SeverityPage, long
+	}
+	if long := s.burnRate(now, 6*time.Hour); long > ticketBurnRate && s.burnRate(now, 30*time.Minute) > ticketBurnRate {
+		return SeverityTicket, long
+	}
+	return SeverityNone, s.burnRate(now, time.Hour)
+}
+
+// SLOTracker 在服务端统计服务等级目标的达成情况
+//
+// 每个目标按分钟和小时粒度滚动计数，导出多窗口燃烧率、剩余错误预算和告警状态指标，
+// 运维无需在外部配置录制规则即可得到可执行的告警信号。
+type SLOTracker struct {
+	mu      sync.Mutex
+	slos    []*sloState
+	now     func() time.Time
+	onAlert func(SLOAlert)
+	log     logger.Logger
+}
+
+// NewSLOTracker 创建目标跟踪器并在 registry 中注册指标，registry 为 nil 时使用 Default
+func NewSLOTracker(slos []SLO, registry *Registry) (*SLOTracker, error) {
+	if registry == nil {
+		registry = Default
+	}
+	t := &SLOTracker{now: time.Now, log: logger.Default()}
+	seen := make(map[string]bool, len(slos))
+	for _, slo := range slos {
+		if slo.Name == "" {
+			return nil, fmt.Errorf("slo name is required")
+		}
+		if seen[slo.Name] {
+			return nil, fmt.Errorf("duplicate slo: %s", slo.Name)
+		}
+		seen[slo.Name] = true
+		if slo.Objective <= 0 || slo.Objective >= 1 {
+			return nil, fmt.Errorf("slo %s: objective must be between 0 and 1: %v", slo.Name, slo.Objective)
+		}
+		if slo.Window <= 0 {
+			slo.Window = DefaultSLOWindow
+		}
+		t.slos = append(t.slos, &sloState{
+			slo:     slo,
+			minutes: newEventRing(time.Minute, minuteSlots),
+			hours:   newEventRing(time.Hour, int(slo.Window/time.Hour)+1),
+		})
+	}
+
+	for _, s := range t.slos {
+		labels := Labels{"slo": s.slo.Name}
+		registry.GaugeFunc("cpfs_slo_objective", "Target ratio of good requests.", labels, func() float64 {
+			return s.slo.Objective
+		})
+		registry.GaugeFunc("cpfs_slo_error_budget_remaining", "Fraction of the error budget left in the SLO window.", labels, func() float64 {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			return s.budgetRemaining(t.now())
+		})
+		for _, w := range burnWindows {
+			registry.GaugeFunc("cpfs_slo_burn_rate", "Error budget burn rate over the window; 1 exhausts the budget exactly at the end of the SLO window.",
+				Labels{"slo": s.slo.Name, "window": w.name}, func() float64 {
+					t.mu.Lock()
+					defer t.mu.Unlock()
+					return s.burnRate(t.now(), w.window)
+				})
+		}
+		for _, severity := range []Severity{SeverityTicket, SeverityPage} {
+			registry.GaugeFunc("cpfs_slo_alert", "Whether the multi-window burn rate alert is firing.",
+				Labels{"slo": s.slo.Name, "severity": string(severity)}, func() float64 {
+					t.mu.Lock()
+					defer t.mu.Unlock()
+					if current, _ := s.severityAt(t.now()); current == severity {
+						return 1
+					}
+					return 0
+				})
+		}
+	}
+	return t, nil
+}
+
+// NewSLOTrackerFromConfig 根据服务器配置创建目标跟踪器
+func NewSLOTrackerFromConfig(cfg *config.ServerConfig, registry *Registry) (*SLOTracker, error) {
+	slos := make([]SLO, 0, len(cfg.SLOs))
+	for _, c := range cfg.SLOs {
+		slos = append(slos, SLO{
+			Name:             c.Name,
+			Methods:          c.Methods,
+			Objective:        c.Objective,
+			LatencyThreshold: time.Duration(c.LatencyThreshold * float64(time.Millisecond)),
+			Window:           time.Duration(c.WindowDays) * 24 * time.Hour,
+		})
+	}
+	return NewSLOTracker(slos, registry)
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (t *SLOTracker) SetLogger(l logger.Logger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.log = logger.OrDefault(l)
+}
+
+// OnAlert 设置告警级别变化时的回调，由 Evaluate 触发
+func (t *SLOTracker) OnAlert(fn func(SLOAlert)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onAlert = fn
+}
+
+// Observe 记录一次请求，计入所有匹配该方法的目标
+func (t *SLOTracker) Observe(method string, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, s := range t.slos {
+		if !s.slo.matches(method) {
+			continue
+		}
+		good := err == nil
+		if s.slo.LatencyThreshold > 0 {
+			good = latency <= s.slo.LatencyThreshold
+		}
+		s.minutes.add(now, good)
+		s.hours.add(now, good)
+	}
+}
+
+// BurnRate 返回目标在窗口内的燃烧率
+func (t *SLOTracker) BurnRate(name string, window time.Duration) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range t.slos {
+		if s.slo.Name == name {
+			return s.burnRate(t.now(), window), true
+		}
+	}
+	return 0, false
+}
+
+// Evaluate 重新计算各目标的告警级别，级别变化时记录日志并回调，返回变化事件
+func (t *SLOTracker) Evaluate() []SLOAlert {
+	t.mu.Lock()
+	now := t.now()
+	var alerts []SLOAlert
+	for _, s := range t.slos {
+		severity, burn := s.severityAt(now)
+		if severity == s.severity {
+			continue
+		}
+		s.severity = severity
+		alerts = append(alerts, SLOAlert{SLO: s.slo.Name, Severity: severity, BurnRate: burn, Time: now})
+	}
+	onAlert, log := t.onAlert, t.log
+	t.mu.Unlock()
+
+	for _, alert := range alerts {
+		if alert.Severity == SeverityNone {
+			log.Info("SLO burn rate recovered", zap.String("slo", alert.SLO), zap.Float64("burn_rate", alert.BurnRate))
+		} else {
+			log.Warn("SLO error budget burning too fast",
+				zap.String("slo", alert.SLO),
+				zap.String("severity", string(alert.Severity)),
+				zap.Float64("burn_rate", alert.BurnRate),
+			)
+		}
+		if onAlert != nil {
+			onAlert(alert)
+		}
+	}
+	return alerts
+}
+
+// Run 周期性执行 Evaluate 直到 ctx 被取消
+func (t *SLOTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.Evaluate()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"cpfs/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRing(t *testing.T) {
+	ring := newEventRing(time.Minute, 10)
+	now := time.Unix(1700000000, 0)
+	ring.add(now, true)
+	ring.add(now, false)
+	ring.add(now.Add(-5*time.Minute), false)
+
+	good, bad := ring.sum(now, time.Minute)
+	assert.Equal(t, [2]uint64{1, 1}, [2]uint64{good, bad})
+	good, bad = ring.sum(now, 10*time.Minute)
+	assert.Equal(t, [2]uint64{1, 2}, [2]uint64{good, bad})
+	// 槽位被复用前的旧数据不计入
+	ring.add(now.Add(10*time.Minute), true)
+	good, bad = ring.sum(now.Add(10*time.Minute), 5*time.Minute)
+	assert.Equal(t, [2]uint64{1, 0}, [2]uint64{good, bad})
+	good, bad = ring.sum(now.Add(time.Hour), 10*time.Minute)
+	assert.Equal(t, [2]uint64{0, 0}, [2]uint64{good, bad})
+}
+
+func TestSLOTrackerBurnRate(t *testing.T) {
+	registry := NewRegistry()
+	tracker, err := NewSLOTrackerFromConfig(&config.ServerConfig{SLOs: []config.SLOConfig{
+		{Name: "stat-latency", Methods: []string{"/Stat"}, Objective: 0.99, LatencyThreshold: 5},
+		{Name: "availability", Objective: 0.999},
+	}}, registry)
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	tracker.now = func() time.Time { return now }
+
+	var alerts []SLOAlert
+	tracker.OnAlert(func(a SLOAlert) { alerts = append(alerts, a) })
+
+	// 10% 的 Stat 请求超过 5ms：燃烧率 10，超过 ticket 阈值但低于 page 阈值
+	for i := 0; i < 100; i++ {
+		latency := time.Millisecond
+		if i%10 == 0 {
+			latency = 20 * time.Millisecond
+		}
+		tracker.Observe("/cpfs.Meta/Stat", latency, nil)
+	}
+	tracker.Observe("/cpfs.Meta/List", time.Second, errors.New("unavailable"))
+
+	burn, ok := tracker.BurnRate("stat-latency", time.Hour)
+	require.True(t, ok)
+	assert.InDelta(t, 10, burn, 1e-6)
+	burn, _ = tracker.BurnRate("availability", time.Hour)
+	assert.InDelta(t, 1.0/101/0.001, burn, 1e-9)
+
+	changed := tracker.Evaluate()
+	require.Len(t, changed, 2)
+	assert.Equal(t, alerts, changed)
+	for _, a := range changed {
+		assert.Equal(t, SeverityTicket, a.Severity, a.SLO)
+	}
+	assert.Empty(t, tracker.Evaluate(), "unchanged severity does not re-fire")
+
+	var buf bytes.Buffer
+	require.NoError(t, registry.WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), `cpfs_slo_burn_rate{slo="stat-latency",window="1h"} 9.99`)
+	assert.Contains(t, buf.String(), `cpfs_slo_alert{severity="ticket",slo="stat-latency"} 1`)
+	assert.Contains(t, buf.String(), `cpfs_slo_alert{severity="page",slo="stat-latency"} 0`)
+	assert.Contains(t, buf.String(), `cpfs_slo_error_budget_remaining{slo="stat-latency"} -8.99`)
+
+	// 窗口滑过后恢复
+	now = now.Add(7 * time.Hour)
+	changed = tracker.Evaluate()
+	require.Len(t, changed, 2)
+	assert.Equal(t, SeverityNone, changed[0].Severity)
+}
+
+func TestSLOTrackerInvalid(t *testing.T) {
+	for _, slos := range [][]SLO{
+		{{Objective: 0.99}},
+		{{Name: "a", Objective: 1}},
+		{{Name: "a", Objective: 0.9}, {Name: "a", Objective: 0.99}},
+	} {
+		_, err := NewSLOTracker(slos, NewRegistry())
+		assert.Error(t, err)
+	}
+}
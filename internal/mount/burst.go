@@ -0,0 +1,298 @@
+package mount
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBurstFlushers 默认的并行刷写数
+	DefaultBurstFlushers = 4
+	// DefaultBurstRetryInterval 刷写失败后的默认重试间隔
+	DefaultBurstRetryInterval = time.Second
+
+	// burstTempPrefix 尚未关闭的暂存文件前缀，恢复时忽略
+	burstTempPrefix = ".tmp-"
+)
+
+// FlushTarget 暂存文件的最终写入目标，即客户端写入 cpfs 的路径
+type FlushTarget interface {
+	Upload(ctx context.Context, path string, r io.Reader, size int64) error
+}
+
+// BurstOptions 突发缓冲选项
+type BurstOptions struct {
+	// Flushers 并行刷写数，为 0 时使用 DefaultBurstFlushers
+	Flushers int
+	// RetryInterval 刷写失败后的重试间隔，为 0 时使用 DefaultBurstRetryInterval
+	RetryInterval time.Duration
+}
+
+// BurstBuffer 突发缓冲模式：写入先落到节点本地的暂存目录，由后台刷写到 cpfs
+//
+// 计算作业以本地磁盘速度写完即可继续，作业结束前调用 WaitForDrain 等待数据全部落到共享存储。
+// 暂存文件以目标路径命名，客户端重启后 Recover 重新排队未刷写的文件。
+type BurstBuffer struct {
+	dir    string
+	target FlushTarget
+	opts   BurstOptions
+	log    logger.Logger
+
+	mu      sync.Mutex
+	pending map[string]bool // 已关闭、尚未刷写完成的目标路径
+	queue   chan string
+	drained *sync.Cond
+	failed  map[string]error // 最近一次刷写失败的原因
+}
+
+// NewBurstBuffer 创建突发缓冲，暂存目录不存在时创建
+func NewBurstBuffer(dir string, target FlushTarget, opts BurstOptions) (*BurstBuffer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	if opts.Flushers <= 0 {
+		opts.Flushers = DefaultBurstFlushers
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultBurstRetryInterval
+	}
+	b := &BurstBuffer{
+		dir:     dir,
+		target:  target,
+		opts:    opts,
+		log:     logger.Default(),
+		pending: make(map[string]bool),
+		queue:   make(chan string, 1024),
+		failed:  make(map[string]error),
+	}
+	b.drained = sync.NewCond(&b.mu)
+	return b, nil
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (b *BurstBuffer) SetLogger(l logger.Logger) {
+	b.log = logger.OrDefault(l)
+}
+
+// stagedPath 返回目标路径对应的暂存文件
+func (b *BurstBuffer) stagedPath(p string) string {
+	return filepath.Join(b.dir, url.PathEscape(p))
+}
+
+// stagedFile 暂存中的文件，Close 后排队刷写
+type stagedFile struct {
+	*os.File
+	b    *BurstBuffer
+	path string
+	once sync.Once
+}
+
+// Close 关闭暂存文件并排队刷写
+func (f *stagedFile) Close() error {
+	err := f.File.Close()
+	f.once.Do(func() {
+		if err != nil {
+			os.Remove(f.File.Name())
+			return
+		}
+		if err = f.b.commit(f.File.Name(), f.path); err != nil {
+			os.Remove(f.File.Name())
+		}
+	})
+	return err
+}
+
+// Create 在暂存目录中创建写往 p 的文件，关闭后才会刷写；同一路径再次写入时覆盖尚未刷写的内容
+func (b *BurstBuffer) Create(p string) (io.WriteCloser, error) {
+	f, err := os.CreateTemp(b.dir, burstTempPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staged file: %v", err)
+	}
+	return &stagedFile{File: f, b: b, path: p}, nil
+}
+
+// commit 用关闭的临时文件替换 p 的暂存文件并排队刷写
+//
+// 替换与刷写完成后的删除都在锁内进行，刷写不会删除上传期间写入的新内容。
+func (b *BurstBuffer) commit(tmp, p string) error {
+	b.mu.Lock()
+	if err := os.Rename(tmp, b.stagedPath(p)); err != nil {
+		b.mu.Unlock()
+		return fmt.Errorf("failed to commit staged file: %v", err)
+	}
+	queued := b.pending[p]
+	b.pending[p] = true
+	b.mu.Unlock()
+	if !queued {
+		b.queue <- p
+	}
+	return nil
+}
+
+// enqueue 将目标路径加入刷写队列，已在队列中的路径不重复加入
+func (b *BurstBuffer) enqueue(p string) {
+	b.mu.Lock()
+	queued := b.pending[p]
+	b.pending[p] = true
+	b.mu.Unlock()
+	if !queued {
+		b.queue <- p
+	}
+}
+
+// Recover 重新排队暂存目录中上次未刷写完成的文件，删除未关闭的临时文件，返回排队的文件数
+func (b *BurstBuffer) Recover() (int, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read staging directory: %v", err)
+	}
+	n := 0
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), burstTempPrefix) {
+			os.Remove(filepath.Join(b.dir, e.Name()))
+			continue
+		}
+		p, err := url.PathUnescape(e.Name())
+		if err != nil {
+			b.log.Warn("Ignoring unknown file in staging directory", zap.String("file", e.Name()))
+			continue
+		}
+		b.enqueue(p)
+		n++
+	}
+	return n, nil
+}
+
+// Pending 返回尚未刷写完成的文件数
+func (b *BurstBuffer) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// Failures 返回最近刷写失败且仍在重试的文件及原因
+func (b *BurstBuffer) Failures() map[string]error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	failures := make(map[string]error, len(b.failed))
+	for p, err := range b.failed {
+		failures[p] = err
+	}
+	return failures
+}
+
+// WaitForDrain 作业完成屏障：阻塞到此前关闭的所有文件都已刷写到 cpfs
+func (b *BurstBuffer) WaitForDrain(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.drained.Broadcast()
+	})
+	defer stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		b.drained.Wait()
+	}
+	return nil
+}
+
+// Run 启动刷写并阻塞到 ctx 被取消，刷写失败的文件按 RetryInterval 重试
+func (b *BurstBuffer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < b.opts.Flushers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case p := <-b.queue:
+					b.flushOne(ctx, p)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// flushOne 刷写一个文件，成功后删除暂存文件，失败时延迟后重新排队
+func (b *BurstBuffer) flushOne(ctx context.Context, p string) {
+	err := b.upload(ctx, p)
+	if err == nil {
+		b.mu.Lock()
+		delete(b.failed, p)
+		if _, statErr := os.Stat(b.stagedPath(p)); os.IsNotExist(statErr) {
+			delete(b.pending, p)
+			b.drained.Broadcast()
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+		// 上传期间写入了新内容，继续刷写新版本
+		go b.requeue(ctx, p)
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	b.log.Warn("Failed to flush staged file", zap.String("path", p), zap.Error(err))
+	b.mu.Lock()
+	b.failed[p] = err
+	b.mu.Unlock()
+	time.AfterFunc(b.opts.RetryInterval, func() { b.requeue(ctx, p) })
+}
+
+// upload 上传暂存文件并删除本地副本
+//
+// 上传期间同一路径被再次写入时，新的暂存文件替换了旧文件，这里只删除已上传的那个版本。
+func (b *BurstBuffer) upload(ctx context.Context, p string) error {
+	staged := b.stagedPath(p)
+	f, err := os.Open(staged)
+	if err != nil {
+		return fmt.Errorf("failed to open staged file: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat staged file: %v", err)
+	}
+	if err := b.target.Upload(ctx, p, f, info.Size()); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if current, err := os.Stat(staged); err == nil && !os.SameFile(info, current) {
+		return nil
+	}
+	if err := os.Remove(staged); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove staged file: %v", err)
+	}
+	return nil
+}
+
+// requeue 将仍待刷写的路径重新放入队列
+func (b *BurstBuffer) requeue(ctx context.Context, p string) {
+	select {
+	case b.queue <- p:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,120 @@
+package mount
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"cpfs/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTarget 记录上传内容，failures 次上传失败后才成功
+type memoryTarget struct {
+	mu       sync.Mutex
+	files    map[string]string
+	failures int
+}
+
+func (t *memoryTarget) Upload(ctx context.Context, path string, r io.Reader, size int64) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failures > 0 {
+		t.failures--
+		return errors.New("data server unavailable")
+	}
+	if int64(len(data)) != size {
+		return errors.New("size mismatch")
+	}
+	t.files[path] = string(data)
+	return nil
+}
+
+func (t *memoryTarget) get(path string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.files[path]
+}
+
+func writeStaged(t *testing.T, b *BurstBuffer, path, content string) {
+	w, err := b.Create(path)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func TestBurstBufferDrain(t *testing.T) {
+	dir := t.TempDir()
+	target := &memoryTarget{files: map[string]string{}, failures: 1}
+	b, err := NewBurstBuffer(dir, target, BurstOptions{Flushers: 2, RetryInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	b.SetLogger(logger.Nop())
+
+	writeStaged(t, b, "/job/out-0", "rank 0")
+	writeStaged(t, b, "/job/out-1", "rank 1")
+	assert.Equal(t, 2, b.Pending(), "nothing is flushed before the stager runs")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer drainCancel()
+	require.NoError(t, b.WaitForDrain(drainCtx))
+	assert.Equal(t, "rank 0", target.get("/job/out-0"))
+	assert.Equal(t, "rank 1", target.get("/job/out-1"))
+	assert.Empty(t, b.Failures())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "flushed files are removed from the staging directory")
+}
+
+func TestBurstBufferRecover(t *testing.T) {
+	dir := t.TempDir()
+	target := &memoryTarget{files: map[string]string{}}
+	b, err := NewBurstBuffer(dir, target, BurstOptions{})
+	require.NoError(t, err)
+	writeStaged(t, b, "/a/b", "left over")
+	w, err := b.Create("/unfinished")
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "partial")
+	require.NoError(t, err)
+
+	// 模拟客户端重启
+	restarted, err := NewBurstBuffer(dir, target, BurstOptions{})
+	require.NoError(t, err)
+	n, err := restarted.Recover()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go restarted.Run(ctx)
+	drainCtx, drainCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer drainCancel()
+	require.NoError(t, restarted.WaitForDrain(drainCtx))
+	assert.Equal(t, "left over", target.get("/a/b"))
+	assert.Empty(t, target.get("/unfinished"))
+}
+
+func TestBurstBufferWaitCanceled(t *testing.T) {
+	b, err := NewBurstBuffer(t.TempDir(), &memoryTarget{files: map[string]string{}}, BurstOptions{})
+	require.NoError(t, err)
+	writeStaged(t, b, "/x", "data")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.WaitForDrain(ctx), context.DeadlineExceeded, "no stager is running")
+}
@@ -0,0 +1,100 @@
+package mount
+
+import (
+	"context"
+	"path"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+
+	"go.uber.org/zap"
+)
+
+// Notifier 向内核发送 FUSE 缓存失效通知，对应 fuse_lowlevel_notify_inval_entry/inval_inode
+type Notifier interface {
+	// InvalidateEntry 使父目录中名为 name 的目录项缓存失效
+	InvalidateEntry(parent uint64, name string) error
+	// InvalidateInode 使 inode 的属性和 [offset, offset+length) 范围的页缓存失效，length 为 0 表示直到文件末尾，
+	// offset 为负时只使属性失效
+	InvalidateInode(inode uint64, offset, length int64) error
+}
+
+// InodeResolver 返回路径在本地挂载中对应的 inode，内核未缓存该路径时返回 false
+type InodeResolver func(p string) (uint64, bool)
+
+// Invalidator 将其他客户端产生的元数据变更转换为内核缓存失效通知
+type Invalidator struct {
+	notifier Notifier
+	resolve  InodeResolver
+	log      logger.Logger
+}
+
+// NewInvalidator 创建缓存失效器
+func NewInvalidator(notifier Notifier, resolve InodeResolver) *Invalidator {
+	return &Invalidator{
+		notifier: notifier,
+		resolve:  resolve,
+		log:      logger.Default(),
+	}
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (i *Invalidator) SetLogger(l logger.Logger) {
+	i.log = logger.OrDefault(l)
+}
+
+// Handle 处理一条变更事件
+//
+// 创建、删除、重命名和交换使父目录中的目录项失效，修改使文件属性和页缓存失效，属性修改只使属性失效。
+// 内核未缓存的路径直接跳过，通知失败只记录日志，不影响后续事件。
+func (i *Invalidator) Handle(e meta.ChangeEvent) {
+	switch e.Type {
+	case meta.ChangeCreate, meta.ChangeDelete:
+		i.invalidateEntry(e.Path)
+	case meta.ChangeRename, meta.ChangeExchange:
+		i.invalidateEntry(e.OldPath)
+		i.invalidateEntry(e.Path)
+	case meta.ChangeModify, meta.ChangeAttr:
+		offset := int64(0)
+		if e.Type == meta.ChangeAttr {
+			offset = -1
+		}
+		if err := i.notifier.InvalidateInode(e.Inode, offset, 0); err != nil {
+			i.log.Debug("Failed to invalidate inode",
+				zap.String("path", e.Path),
+				zap.Uint64("inode", e.Inode),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// invalidateEntry 使路径对应的目录项失效
+func (i *Invalidator) invalidateEntry(p string) {
+	if p == "" || p == "/" {
+		return
+	}
+	parent, ok := i.resolve(path.Dir(p))
+	if !ok {
+		return
+	}
+	if err := i.notifier.InvalidateEntry(parent, path.Base(p)); err != nil {
+		i.log.Debug("Failed to invalidate entry",
+			zap.String("path", p),
+			zap.Uint64("parent", parent),
+			zap.Error(err),
+		)
+	}
+}
+
+// Run 处理订阅中的事件直到订阅结束，返回订阅结束的原因
+func (i *Invalidator) Run(ctx context.Context, watcher *meta.Watcher) error {
+	for e := range watcher.Events {
+		i.Handle(e)
+	}
+	if err := watcher.Err(); err != nil && ctx.Err() == nil {
+		i.log.Warn("Invalidation watch ended", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package mount
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier 记录失效通知的测试实现
+type recordingNotifier struct {
+	calls []string
+}
+
+func (n *recordingNotifier) InvalidateEntry(parent uint64, name string) error {
+	n.calls = append(n.calls, fmt.Sprintf("entry %d %s", parent, name))
+	return nil
+}
+
+func (n *recordingNotifier) InvalidateInode(inode uint64, offset, length int64) error {
+	n.calls = append(n.calls, fmt.Sprintf("inode %d %d", inode, offset))
+	return nil
+}
+
+func TestInvalidatorHandle(t *testing.T) {
+	notifier := &recordingNotifier{}
+	inodes := map[string]uint64{"/": 1, "/dir": 2}
+	inv := NewInvalidator(notifier, func(p string) (uint64, bool) {
+		inode, ok := inodes[p]
+		return inode, ok
+	})
+
+	inv.Handle(meta.ChangeEvent{Type: meta.ChangeCreate, Path: "/dir/a"})
+	inv.Handle(meta.ChangeEvent{Type: meta.ChangeModify, Path: "/dir/a", Inode: 7})
+	inv.Handle(meta.ChangeEvent{Type: meta.ChangeAttr, Path: "/dir/a", Inode: 7})
+	inv.Handle(meta.ChangeEvent{Type: meta.ChangeRename, OldPath: "/dir/a", Path: "/b"})
+	inv.Handle(meta.ChangeEvent{Type: meta.ChangeDelete, Path: "/uncached/c"})
+	inv.Handle(meta.ChangeEvent{Type: meta.ChangeDelete, Path: "/b"})
+	inv.Handle(meta.ChangeEvent{Type: meta.ChangeExchange, OldPath: "/dir/x", Path: "/y"})
+
+	assert.Equal(t, []string{
+		"entry 2 a",
+		"inode 7 0",
+		"inode 7 -1",
+		"entry 2 a",
+		"entry 1 b",
+		"entry 1 b",
+		"entry 2 x",
+		"entry 1 y",
+	}, notifier.calls)
+}
+
+func TestInvalidatorRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := meta.NewMemoryStore()
+
+	watcher, err := store.Changelog().Watch(ctx, "/", true, 0)
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	inv := NewInvalidator(notifier, func(p string) (uint64, bool) { return 1, p == "/" })
+	done := make(chan error, 1)
+	go func() { done <- inv.Run(ctx, watcher) }()
+
+	_, err = store.Create(ctx, "/file", 0644)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"entry 1 file"}, notifier.calls)
+}
@@ -0,0 +1,222 @@
+package mount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"cpfs/internal/bufpool"
+	"cpfs/internal/encryption"
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+
+	"go.uber.org/zap"
+)
+
+// ErrHandlePoisoned 文件句柄上的写入被中途打断，已写出的数据状态不确定，可用 errors.Is 判断
+var ErrHandlePoisoned = errors.New("file handle poisoned by interrupted write")
+
+// ChunkWriter 把一个条带单元的数据写入条带目标，通常是到数据服务器的流式 RPC
+//
+// ctx 被取消时实现必须中止传输并尽快返回；返回后不得再引用 data。
+type ChunkWriter interface {
+	WriteChunk(ctx context.Context, target int, offset int64, data []byte) error
+}
+
+// DataKeys 返回路径所属加密区域的数据密钥，路径未加密时返回 nil，通常是 *encryption.ZoneManager
+type DataKeys interface {
+	DataKey(p string) ([]byte, error)
+}
+
+// StripedHandle 按条带布局并行写入的文件句柄
+//
+// 一次写入按条带单元拆分，每个条带目标一个传输顺序发送自己的单元，各目标之间并行。
+// 任一单元失败或 ctx 被取消时，其余传输随之取消，WriteAt 等待全部传输退出、归还缓冲区后才返回。
+// 取消发生在任何单元开始发送之前时句柄不受影响，可以重试；已有单元开始发送时，
+// 目标上的数据可能只写了一部分，句柄被标记为不可用，之后的写入返回 ErrHandlePoisoned，需重新打开文件。
+type StripedHandle struct {
+	path   string
+	layout meta.StripeLayout
+	writer ChunkWriter
+	pool   *bufpool.Pool
+	log    logger.Logger
+	fence  *meta.WriteFence
+	keys   DataKeys
+
+	mu       sync.Mutex
+	size     int64
+	poisoned error
+}
+
+// NewStripedHandle 创建条带写入句柄，pool 为 nil 时使用 bufpool.Default
+func NewStripedHandle(p string, layout meta.StripeLayout, writer ChunkWriter, pool *bufpool.Pool) *StripedHandle {
+	if pool == nil {
+		pool = bufpool.Default
+	}
+	return &StripedHandle{
+		path:   p,
+		layout: layout,
+		writer: writer,
+		pool:   pool,
+		log:    logger.Default(),
+	}
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (h *StripedHandle) SetLogger(l logger.Logger) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.log = logger.OrDefault(l)
+}
+
+// SetFence 设置写入栅栏，每次写入在栅栏上登记，文件所在子树静止时等待恢复
+func (h *StripedHandle) SetFence(f *meta.WriteFence) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fence = f
+}
+
+// SetDataKeys 设置加密区域的数据密钥来源，为 nil 时不加密
+//
+// 设置后每次写入先查询文件所在区域的密钥，区域内的条带单元用 encryption.EncryptBlock 加密后发送，
+// 单元的文件偏移作为块ID。所在区域已被销毁时写入返回 encryption.ErrZoneDestroyed，不会以明文写出。
+func (h *StripedHandle) SetDataKeys(keys DataKeys) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.keys = keys
+}
+
+// chunkBlockID 条带单元加密使用的块ID
+func chunkBlockID(offset int64) string {
+	return strconv.FormatInt(offset, 10)
+}
+
+// Size 返回已成功写入的最大文件偏移
+func (h *StripedHandle) Size() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.size
+}
+
+// Err 返回句柄被标记为不可用的原因，句柄可用时返回 nil
+func (h *StripedHandle) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.poisoned
+}
+
+// chunkResult 一个条带单元的发送结果
+type chunkResult struct {
+	started bool
+	err     error
+}
+
+// WriteAt 将 data 写入文件偏移 off，返回从 off 开始连续写成功的字节数
+func (h *StripedHandle) WriteAt(ctx context.Context, data []byte, off int64) (int, error) {
+	if err := h.Err(); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	h.mu.Lock()
+	fence, keys := h.fence, h.keys
+	h.mu.Unlock()
+	if fence != nil {
+		exit, err := fence.Enter(ctx, h.path)
+		if err != nil {
+			return 0, err
+		}
+		defer exit()
+	}
+	var dataKey []byte
+	if keys != nil {
+		key, err := keys.DataKey(h.path)
+		if err != nil {
+			return 0, err
+		}
+		dataKey = key
+	}
+
+	chunks := h.layout.Chunks(off, int64(len(data)))
+	byTarget := make(map[int][]int)
+	for i, c := range chunks {
+		byTarget[c.Target] = append(byTarget[c.Target], i)
+	}
+
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]chunkResult, len(chunks))
+	// 最先失败的单元的错误，之后被取消的传输返回的错误只是它的结果
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+	for target, indexes := range byTarget {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, i := range indexes {
+				if writeCtx.Err() != nil {
+					return
+				}
+				c := chunks[i]
+				buf := h.pool.Get()
+				buf.Write(data[c.Offset-off : c.Offset-off+c.Length])
+				chunk := buf.Bytes()
+				var err error
+				if dataKey != nil {
+					chunk, err = encryption.EncryptBlock(dataKey, chunkBlockID(c.Offset), chunk)
+				}
+				if err == nil {
+					results[i].started = true
+					err = h.writer.WriteChunk(writeCtx, target, c.Offset, chunk)
+				}
+				h.pool.Put(buf)
+				if err != nil {
+					results[i].err = err
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 连续写成功的前缀
+	n := 0
+	started := false
+	for i, c := range chunks {
+		started = started || results[i].started
+		if results[i].started && results[i].err == nil && n == int(c.Offset-off) {
+			n += int(c.Length)
+		}
+	}
+	cause := firstErr
+	if cause == nil && n < len(data) {
+		// 没有单元失败但有单元未发送，只能是 ctx 被取消
+		cause = ctx.Err()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cause == nil {
+		h.size = max(h.size, off+int64(n))
+		return n, nil
+	}
+	if started {
+		h.poisoned = fmt.Errorf("%w: %s at offset %d: %v", ErrHandlePoisoned, h.path, off, cause)
+		h.log.Warn("Striped write interrupted",
+			zap.String("path", h.path),
+			zap.Int64("offset", off),
+			zap.Int("length", len(data)),
+			zap.Int("written", n),
+			zap.Error(cause),
+		)
+	}
+	return n, cause
+}
@@ -0,0 +1,193 @@
+package mount
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cpfs/internal/bufpool"
+	"cpfs/internal/encryption"
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChunkWriter 记录写入的条带单元，block 中的目标在 ctx 取消前一直阻塞
+type fakeChunkWriter struct {
+	mu      sync.Mutex
+	data    map[int64][]byte
+	block   map[int]bool
+	fail    map[int]error
+	started chan int
+}
+
+func newFakeChunkWriter() *fakeChunkWriter {
+	return &fakeChunkWriter{
+		data:    make(map[int64][]byte),
+		block:   make(map[int]bool),
+		fail:    make(map[int]error),
+		started: make(chan int, 64),
+	}
+}
+
+func (w *fakeChunkWriter) WriteChunk(ctx context.Context, target int, offset int64, data []byte) error {
+	w.started <- target
+	if w.block[target] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := w.fail[target]; err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.data[offset] = bytes.Clone(data)
+	return nil
+}
+
+var testLayout = meta.StripeLayout{StripeCount: 4, StripeSize: 4}
+
+func TestStripedHandleWrite(t *testing.T) {
+	writer := newFakeChunkWriter()
+	h := NewStripedHandle("/f", testLayout, writer, bufpool.New(0))
+
+	n, err := h.WriteAt(context.Background(), []byte("0123456789abcdefgh"), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 18, n)
+	assert.Equal(t, int64(20), h.Size())
+	assert.Equal(t, []byte("01"), writer.data[2])
+	assert.Equal(t, []byte("2345"), writer.data[4])
+	assert.Equal(t, []byte("efgh"), writer.data[16])
+	assert.NoError(t, h.Err())
+}
+
+func TestStripedHandleCancelDuringWrite(t *testing.T) {
+	writer := newFakeChunkWriter()
+	writer.block[2] = true
+	pool := bufpool.New(0)
+	h := NewStripedHandle("/f", testLayout, writer, pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for target := range writer.started {
+			if target == 2 {
+				cancel()
+				return
+			}
+		}
+	}()
+	n, err := h.WriteAt(ctx, make([]byte, 32), 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, n, 32)
+
+	// 传输全部退出后才返回，缓冲区全部归还
+	stats := pool.Stats()
+	assert.Equal(t, stats.Gets, stats.Puts)
+
+	assert.ErrorIs(t, h.Err(), ErrHandlePoisoned)
+	_, err = h.WriteAt(context.Background(), []byte("x"), 0)
+	assert.ErrorIs(t, err, ErrHandlePoisoned)
+	assert.Zero(t, h.Size())
+}
+
+func TestStripedHandleTargetFailure(t *testing.T) {
+	writer := newFakeChunkWriter()
+	writer.block[1] = true
+	writer.fail[3] = errors.New("connection reset")
+	h := NewStripedHandle("/f", testLayout, writer, nil)
+
+	// 一个目标失败时其余目标的传输被取消
+	n, err := h.WriteAt(context.Background(), make([]byte, 16), 0)
+	assert.EqualError(t, err, "connection reset")
+	assert.Less(t, n, 4*2)
+	assert.ErrorIs(t, h.Err(), ErrHandlePoisoned)
+}
+
+func TestStripedHandleCancelBeforeWrite(t *testing.T) {
+	writer := newFakeChunkWriter()
+	h := NewStripedHandle("/f", testLayout, writer, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := h.WriteAt(ctx, []byte("data"), 0)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 还没有数据发出，句柄仍然可用
+	assert.NoError(t, h.Err())
+	n, err := h.WriteAt(context.Background(), []byte("data"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+}
+
+// fakeDataKeys 为 /secure 下的路径返回固定的数据密钥
+type fakeDataKeys struct {
+	key []byte
+	err error
+}
+
+func (k *fakeDataKeys) DataKey(p string) ([]byte, error) {
+	if !strings.HasPrefix(p, "/secure/") {
+		return nil, nil
+	}
+	return k.key, k.err
+}
+
+func TestStripedHandleEncryption(t *testing.T) {
+	key, err := encryption.GenerateKey()
+	require.NoError(t, err)
+	keys := &fakeDataKeys{key: key}
+
+	writer := newFakeChunkWriter()
+	h := NewStripedHandle("/secure/f", testLayout, writer, nil)
+	h.SetDataKeys(keys)
+	n, err := h.WriteAt(context.Background(), []byte("0123456789"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	// 条带单元以密文发出，以文件偏移为块ID解密
+	assert.NotContains(t, string(writer.data[4]), "4567")
+	plaintext, err := encryption.DecryptBlock(key, "4", writer.data[4])
+	require.NoError(t, err)
+	assert.Equal(t, []byte("4567"), plaintext)
+	_, err = encryption.DecryptBlock(key, "8", writer.data[4])
+	assert.Error(t, err)
+
+	// 区域外的文件不加密
+	plain := NewStripedHandle("/plain/f", testLayout, writer, nil)
+	plain.SetDataKeys(keys)
+	_, err = plain.WriteAt(context.Background(), []byte("abcd"), 32)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("abcd"), writer.data[32])
+
+	// 区域被销毁后拒绝写入，不会以明文写出，句柄仍然可用
+	keys.key, keys.err = nil, encryption.ErrZoneDestroyed
+	_, err = h.WriteAt(context.Background(), []byte("zzzz"), 64)
+	assert.ErrorIs(t, err, encryption.ErrZoneDestroyed)
+	assert.NotContains(t, writer.data, int64(64))
+	assert.NoError(t, h.Err())
+}
+
+func TestStripedHandleFence(t *testing.T) {
+	writer := newFakeChunkWriter()
+	h := NewStripedHandle("/data/f", testLayout, writer, nil)
+	fence := meta.NewWriteFence()
+	h.SetFence(fence)
+
+	resume, err := fence.Quiesce(context.Background(), "/data", 0)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = h.WriteAt(ctx, []byte("data"), 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, h.Err())
+
+	resume()
+	n, err := h.WriteAt(context.Background(), []byte("data"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+}
@@ -0,0 +1,207 @@
+package mount
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cpfs/internal/meta"
+)
+
+// TraceFormat 操作跟踪的输出格式
+type TraceFormat int
+
+const (
+	TraceText TraceFormat = iota // 类似 strace 的单行文本
+	TraceJSON                    // 每行一个 JSON 对象
+)
+
+// OpRecord 一次文件系统操作的跟踪记录
+type OpRecord struct {
+	Time    time.Time     `json:"time"`
+	Op      string        `json:"op"`
+	Path    string        `json:"path"`
+	Offset  int64         `json:"offset,omitempty"`
+	Size    int64         `json:"size,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// String 按类似 strace 的格式输出：时间 操作("路径", off=, size=) = 结果 <耗时秒数>
+func (r *OpRecord) String() string {
+	args := fmt.Sprintf("%q", r.Path)
+	if r.Offset != 0 || r.Size != 0 {
+		args += fmt.Sprintf(", off=%d, size=%d", r.Offset, r.Size)
+	}
+	result := "ok"
+	if r.Error != "" {
+		result = "error (" + r.Error + ")"
+	}
+	return fmt.Sprintf("%s %s(%s) = %s <%.6f>",
+		r.Time.Format("15:04:05.000000"), r.Op, args, result, r.Latency.Seconds())
+}
+
+// OpTracer 客户端操作跟踪，可在运行时开关，用于诊断应用的慢 IO 模式
+//
+// 关闭时 Trace 只有一次原子读取的开销；开启后每个操作完成时写出一行记录，写入串行化，
+// 写入失败时自动关闭跟踪，避免影响文件系统操作本身。
+type OpTracer struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	format  TraceFormat
+	now     func() time.Time
+}
+
+// NewOpTracer 创建处于关闭状态的操作跟踪
+func NewOpTracer() *OpTracer {
+	return &OpTracer{now: time.Now}
+}
+
+// Enable 开始将记录写入 w，替换之前的输出目标
+func (t *OpTracer) Enable(w io.Writer, format TraceFormat) {
+	t.enable(w, nil, format)
+}
+
+// enable 切换输出目标，closer 在关闭跟踪时关闭
+func (t *OpTracer) enable(w io.Writer, closer io.Closer, format TraceFormat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeLocked()
+	t.w, t.closer, t.format = w, closer, format
+	t.enabled.Store(true)
+}
+
+// EnableFile 开始将记录追加写入本地文件，关闭跟踪时关闭文件
+func (t *OpTracer) EnableFile(path string, format TraceFormat) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create trace directory: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open trace file: %v", err)
+	}
+	t.enable(f, f, format)
+	return nil
+}
+
+// Disable 停止跟踪并关闭由 EnableFile 打开的文件
+func (t *OpTracer) Disable() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closeLocked()
+}
+
+// closeLocked 停止跟踪，调用方需持有锁
+func (t *OpTracer) closeLocked() error {
+	t.enabled.Store(false)
+	t.w = nil
+	if t.closer == nil {
+		return nil
+	}
+	err := t.closer.Close()
+	t.closer = nil
+	return err
+}
+
+// Enabled 返回跟踪是否开启
+func (t *OpTracer) Enabled() bool {
+	return t.enabled.Load()
+}
+
+// Trace 开始跟踪一个操作，返回在操作完成时以其结果调用的函数
+//
+//	done := tracer.Trace("read", p, off, int64(len(buf)))
+//	n, err := ...
+//	done(err)
+func (t *OpTracer) Trace(op, path string, offset, size int64) func(err error) {
+	if !t.enabled.Load() {
+		return func(error) {}
+	}
+	start := t.now()
+	return func(err error) {
+		r := OpRecord{Time: start, Op: op, Path: path, Offset: offset, Size: size, Latency: t.now().Sub(start)}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		t.write(&r)
+	}
+}
+
+// write 写出一条记录
+func (t *OpTracer) write(r *OpRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.w == nil {
+		return
+	}
+	var line []byte
+	if t.format == TraceJSON {
+		line, _ = json.Marshal(r)
+	} else {
+		line = []byte(r.String())
+	}
+	if _, err := t.w.Write(append(line, '\n')); err != nil {
+		t.closeLocked()
+	}
+}
+
+// TracingBackend 跟踪每个元数据操作的后端包装
+type TracingBackend struct {
+	backend meta.CacheBackend
+	tracer  *OpTracer
+}
+
+// NewTracingBackend 用 tracer 包装元数据后端
+func NewTracingBackend(backend meta.CacheBackend, tracer *OpTracer) *TracingBackend {
+	return &TracingBackend{backend: backend, tracer: tracer}
+}
+
+// Create 实现 meta.CacheBackend
+func (b *TracingBackend) Create(ctx context.Context, path string, mode os.FileMode) (md *meta.Metadata, err error) {
+	defer b.trace("create", path)(&err)
+	return b.backend.Create(ctx, path, mode)
+}
+
+// Get 实现 meta.CacheBackend
+func (b *TracingBackend) Get(ctx context.Context, path string) (md *meta.Metadata, err error) {
+	defer b.trace("getattr", path)(&err)
+	return b.backend.Get(ctx, path)
+}
+
+// Update 实现 meta.CacheBackend
+func (b *TracingBackend) Update(ctx context.Context, path string, md *meta.Metadata) (err error) {
+	defer b.trace("setattr", path)(&err)
+	return b.backend.Update(ctx, path, md)
+}
+
+// Delete 实现 meta.CacheBackend
+func (b *TracingBackend) Delete(ctx context.Context, path string) (err error) {
+	defer b.trace("unlink", path)(&err)
+	return b.backend.Delete(ctx, path)
+}
+
+// List 实现 meta.CacheBackend
+func (b *TracingBackend) List(ctx context.Context, path string) (entries []*meta.Metadata, err error) {
+	defer b.trace("readdir", path)(&err)
+	return b.backend.List(ctx, path)
+}
+
+// Mkdir 实现 meta.CacheBackend
+func (b *TracingBackend) Mkdir(ctx context.Context, path string, mode os.FileMode) (err error) {
+	defer b.trace("mkdir", path)(&err)
+	return b.backend.Mkdir(ctx, path, mode)
+}
+
+// trace 开始跟踪元数据操作，返回的函数在 defer 中读取命名返回的错误
+func (b *TracingBackend) trace(op, path string) func(*error) {
+	done := b.tracer.Trace(op, path, 0, 0)
+	return func(err *error) { done(*err) }
+}
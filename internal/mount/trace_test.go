@@ -0,0 +1,103 @@
+package mount
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock 每次读取前进固定步长的时钟
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestOpTracerText(t *testing.T) {
+	tracer := NewOpTracer()
+	tracer.now = fakeClock(1500 * time.Microsecond)
+
+	tracer.Trace("read", "/a", 0, 4096)(nil)
+	assert.False(t, tracer.Enabled(), "disabled tracer records nothing")
+
+	var buf bytes.Buffer
+	tracer.Enable(&buf, TraceText)
+	tracer.Trace("read", "/data/x", 4096, 4096)(nil)
+	tracer.Trace("open", "/missing", 0, 0)(errors.New("no such file"))
+	require.NoError(t, tracer.Disable())
+	tracer.Trace("read", "/after", 0, 1)(nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, `12:00:00.001500 read("/data/x", off=4096, size=4096) = ok <0.001500>`, lines[0])
+	assert.Equal(t, `12:00:00.004500 open("/missing") = error (no such file) <0.001500>`, lines[1])
+}
+
+func TestOpTracerJSONFile(t *testing.T) {
+	tracer := NewOpTracer()
+	file := filepath.Join(t.TempDir(), "trace", "ops.jsonl")
+	require.NoError(t, tracer.EnableFile(file, TraceJSON))
+	tracer.Trace("write", "/f", 10, 20)(nil)
+	require.NoError(t, tracer.Disable())
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var r OpRecord
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(data), &r))
+	assert.Equal(t, "write", r.Op)
+	assert.Equal(t, int64(20), r.Size)
+}
+
+// failingWriter 总是写入失败
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestOpTracerDisablesOnWriteError(t *testing.T) {
+	tracer := NewOpTracer()
+	tracer.Enable(failingWriter{}, TraceText)
+	tracer.Trace("read", "/a", 0, 1)(nil)
+	assert.False(t, tracer.Enabled())
+}
+
+func TestTracingBackend(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewOpTracer()
+	tracer.Enable(&buf, TraceJSON)
+	backend := NewTracingBackend(meta.NewMemoryStore(), tracer)
+
+	ctx := context.Background()
+	require.NoError(t, backend.Mkdir(ctx, "/dir", 0755))
+	_, err := backend.Create(ctx, "/dir/f", 0644)
+	require.NoError(t, err)
+	_, err = backend.Get(ctx, "/dir/missing")
+	require.Error(t, err)
+	_, err = backend.List(ctx, "/dir")
+	require.NoError(t, err)
+
+	var ops []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r OpRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		ops = append(ops, r.Op)
+		if r.Op == "getattr" {
+			assert.NotEmpty(t, r.Error)
+		}
+	}
+	assert.Equal(t, []string{"mkdir", "create", "getattr", "readdir"}, ops)
+}
@@ -0,0 +1,111 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cpfs/internal/meta"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeKeyPrefix ACME 账户与证书在存储中的键前缀
+const acmeKeyPrefix = "/acme/"
+
+// ACMEOptions 定义 ACME 自动证书选项
+type ACMEOptions struct {
+	// Hosts 允许自动签发证书的域名
+	Hosts []string
+	// Email 账户联系邮箱
+	Email string
+	// DirectoryURL ACME 服务目录地址，为空时使用 Let's Encrypt 生产环境
+	DirectoryURL string
+}
+
+// StorageCertCache 基于 Storage 接口实现 autocert.Cache，用于保存账户密钥和证书
+type StorageCertCache struct {
+	storage meta.Storage
+}
+
+// NewStorageCertCache 创建基于存储的证书缓存
+func NewStorageCertCache(storage meta.Storage) *StorageCertCache {
+	return &StorageCertCache{storage: storage}
+}
+
+// cacheKey 将 autocert 的键转换为存储键
+func (c *StorageCertCache) cacheKey(name string) string {
+	// 替换路径分隔符，避免键在存储中产生子目录
+	return acmeKeyPrefix + strings.ReplaceAll(name, "/", "_")
+}
+
+// Get 实现 autocert.Cache
+func (c *StorageCertCache) Get(ctx context.Context, name string) ([]byte, error) {
+	data, err := c.storage.Load(ctx, c.cacheKey(name))
+	if err != nil {
+		if errors.Is(err, meta.ErrKeyNotFound) {
+			return nil, autocert.ErrCacheMiss
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Put 实现 autocert.Cache
+func (c *StorageCertCache) Put(ctx context.Context, name string, data []byte) error {
+	if err := c.storage.Save(ctx, c.cacheKey(name), data); err != nil {
+		return err
+	}
+	// 证书与账户密钥丢失需要重新申请，立即持久化
+	return c.storage.Sync()
+}
+
+// Delete 实现 autocert.Cache
+func (c *StorageCertCache) Delete(ctx context.Context, name string) error {
+	return c.storage.Delete(ctx, c.cacheKey(name))
+}
+
+// NewACMEManager 创建 ACME 自动证书管理器
+func NewACMEManager(opts ACMEOptions, storage meta.Storage) (*autocert.Manager, error) {
+	if len(opts.Hosts) == 0 {
+		return nil, fmt.Errorf("at least one ACME host is required")
+	}
+	if storage == nil {
+		return nil, fmt.Errorf("storage is required for ACME certificate cache")
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(opts.Hosts...),
+		Cache:      NewStorageCertCache(storage),
+		Email:      opts.Email,
+	}
+	if opts.DirectoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: opts.DirectoryURL}
+	}
+
+	return manager, nil
+}
+
+// ACMETLSConfig 返回使用 ACME 管理器签发证书的 TLS 配置
+//
+// 若同时配置了 SNI 路由器，已静态配置证书的域名优先使用静态证书，
+// 其余域名由 ACME 自动签发。
+func ACMETLSConfig(manager *autocert.Manager, router *SNIRouter) *tls.Config {
+	config := manager.TLSConfig()
+	config.MinVersion = tls.VersionTLS12
+	if router == nil {
+		return config
+	}
+
+	config.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if entry, ok := router.lookupExact(hello.ServerName); ok {
+			return entry.cert, nil
+		}
+		return manager.GetCertificate(hello)
+	}
+	return config
+}
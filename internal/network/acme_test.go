@@ -0,0 +1,68 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestStorageCertCache(t *testing.T) {
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{
+		RootDir:      t.TempDir(),
+		SyncInterval: time.Second,
+		FileMode:     0600,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	ctx := context.Background()
+	cache := NewStorageCertCache(storage)
+
+	_, err = cache.Get(ctx, "example.com")
+	assert.Equal(t, autocert.ErrCacheMiss, err)
+
+	require.NoError(t, cache.Put(ctx, "example.com", []byte("cert")))
+	data, err := cache.Get(ctx, "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("cert"), data)
+
+	require.NoError(t, cache.Delete(ctx, "example.com"))
+	_, err = cache.Get(ctx, "example.com")
+	assert.Equal(t, autocert.ErrCacheMiss, err)
+}
+
+func TestNewACMEManager(t *testing.T) {
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{
+		RootDir:      t.TempDir(),
+		SyncInterval: time.Second,
+		FileMode:     0600,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	_, err = NewACMEManager(ACMEOptions{}, storage)
+	assert.Error(t, err)
+
+	_, err = NewACMEManager(ACMEOptions{Hosts: []string{"gw.example.com"}}, nil)
+	assert.Error(t, err)
+
+	manager, err := NewACMEManager(ACMEOptions{
+		Hosts:        []string{"gw.example.com"},
+		DirectoryURL: "https://acme.invalid/directory",
+	}, storage)
+	require.NoError(t, err)
+
+	// 非白名单域名拒绝签发
+	assert.Error(t, manager.HostPolicy(context.Background(), "other.example.com"))
+	assert.NoError(t, manager.HostPolicy(context.Background(), "gw.example.com"))
+
+	server, err := NewGRPCServer(ServerOptions{Address: "127.0.0.1:0", ACME: manager})
+	assert.NoError(t, err)
+	assert.NotNil(t, server)
+}
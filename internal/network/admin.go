@@ -0,0 +1,159 @@
+package network
+
+import (
+	"context"
+	"errors"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/jobs"
+	"cpfs/internal/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdminGroup 允许执行管理查询的组
+const AdminGroup = "cpfs-admin"
+
+// DefaultHistoryLimit 操作历史查询默认返回的事件数量
+const DefaultHistoryLimit = 100
+
+// HistoryRequest 路径操作历史查询请求
+type HistoryRequest struct {
+	Path  string
+	Limit int // 最多返回的事件数量，为 0 时使用 DefaultHistoryLimit
+}
+
+// requireAdmin 要求请求主体属于 AdminGroup
+func requireAdmin(ctx context.Context) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing principal")
+	}
+	if !principal.InGroup(AdminGroup) {
+		return status.Errorf(codes.PermissionDenied, "%s is not in %s", principal.User, AdminGroup)
+	}
+	return nil
+}
+
+// PathHistory 管理查询：返回路径最近的创建、修改、重命名和删除记录，按时间倒序
+//
+// 历史来自变更日志，只覆盖日志仍保留的范围；路径已被删除时仍可查询。
+func PathHistory(ctx context.Context, store WatchSource, req *HistoryRequest) ([]meta.ChangeEvent, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if req.Path == "" {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	return store.Changelog().History(req.Path, limit), nil
+}
+
+// jobStatus 将作业管理器的错误转换为 gRPC 状态
+func jobStatus(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, jobs.ErrJobFinished):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// ListJobs 管理查询：返回全部作业记录，包括已结束作业的历史
+func ListJobs(ctx context.Context, manager *jobs.Manager) ([]*jobs.Job, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	list, err := manager.List(ctx)
+	if err != nil {
+		return nil, jobStatus(err)
+	}
+	return list, nil
+}
+
+// GetJob 管理查询：返回作业状态和进度
+func GetJob(ctx context.Context, manager *jobs.Manager, id string) (*jobs.Job, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	job, err := manager.Get(ctx, id)
+	if err != nil {
+		return nil, jobStatus(err)
+	}
+	return job, nil
+}
+
+// CancelJob 管理操作：取消运行中的作业
+func CancelJob(ctx context.Context, manager *jobs.Manager, id string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := manager.Cancel(ctx, id); err != nil {
+		return jobStatus(err)
+	}
+	return nil
+}
+
+// OwnerQuotaRequest 设置用户或组配额的请求
+type OwnerQuotaRequest struct {
+	Kind   string // user 或 group
+	Name   string
+	Limits meta.QuotaLimits // 上限均为 0 时删除配额
+}
+
+// ownerKind 解析配额维度，非法时返回 InvalidArgument
+func ownerKind(kind string) (meta.OwnerKind, error) {
+	k, err := meta.ParseOwnerKind(kind)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return k, nil
+}
+
+// ListOwnerQuotas 管理查询：返回维度 kind 下所有设置了上限或有用量的用户或组，kind 为空时返回全部
+func ListOwnerQuotas(ctx context.Context, quotas *meta.OwnerQuotas, kind string) ([]meta.OwnerQuota, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	var k meta.OwnerKind
+	if kind != "" {
+		var err error
+		if k, err = ownerKind(kind); err != nil {
+			return nil, err
+		}
+	}
+	return quotas.List(k), nil
+}
+
+// GetOwnerQuota 管理查询：返回用户或组的上限与用量
+func GetOwnerQuota(ctx context.Context, quotas *meta.OwnerQuotas, kind, name string) (meta.OwnerQuota, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return meta.OwnerQuota{}, err
+	}
+	k, err := ownerKind(kind)
+	if err != nil {
+		return meta.OwnerQuota{}, err
+	}
+	return quotas.Get(k, name), nil
+}
+
+// SetOwnerQuota 管理操作：设置用户或组的配额上限，返回设置后的上限与用量
+func SetOwnerQuota(ctx context.Context, quotas *meta.OwnerQuotas, req *OwnerQuotaRequest) (meta.OwnerQuota, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return meta.OwnerQuota{}, err
+	}
+	k, err := ownerKind(req.Kind)
+	if err != nil {
+		return meta.OwnerQuota{}, err
+	}
+	if err := quotas.SetLimits(k, req.Name, req.Limits); err != nil {
+		return meta.OwnerQuota{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return quotas.Get(k, req.Name), nil
+}
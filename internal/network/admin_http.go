@@ -0,0 +1,196 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/jobs"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdminHTTPOptions 管理接口 HTTP/JSON 转码配置
+type AdminHTTPOptions struct {
+	// Store 元数据存储，提供路径查询与操作历史
+	Store WatchSource
+	// Jobs 作业管理器，为 nil 时不提供作业接口
+	Jobs *jobs.Manager
+	// Topology 集群视图，为 nil 时不提供拓扑接口
+	Topology TopologySource
+	// Access 访问统计，为 nil 时不提供热点查询接口
+	Access *metrics.AccessTracker
+	// Quotas 用户与组配额，为 nil 时不提供配额接口
+	Quotas *meta.OwnerQuotas
+	// Verifier 校验 Authorization 头中的 Bearer 令牌
+	Verifier auth.TokenVerifier
+}
+
+// NewAdminHTTPHandler 返回元数据服务器管理与元数据查询的 HTTP/JSON 接口
+//
+// 接口与同名的 gRPC 管理调用一一对应，复用相同的认证和权限检查，
+// gRPC 状态码按 gRPC-Gateway 的约定转换为 HTTP 状态码，内嵌的 Web 界面和浏览器工具无需额外代理即可调用：
+//
+//	GET  /v1/meta/stat?path=/a/b         路径元数据
+//	GET  /v1/cluster/topology            集群视图
+//	GET  /v1/admin/history?path=&limit=  路径操作历史
+//	GET  /v1/admin/hot?scope=&order=&limit=  热点文件或目录（scope=dirs）
+//	GET  /v1/admin/jobs                  作业列表
+//	GET  /v1/admin/jobs/{id}             作业状态
+//	POST /v1/admin/jobs/{id}/cancel      取消作业
+//	GET  /v1/admin/quotas?kind=          用户与组配额列表（kind=user 或 group）
+//	GET  /v1/admin/quotas/{kind}/{name}  用户或组的上限与用量
+//	PUT  /v1/admin/quotas/{kind}/{name}  设置上限，请求体为 {"bytes":..,"files":..}
+func NewAdminHTTPHandler(opts AdminHTTPOptions) http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, fn func(ctx context.Context, r *http.Request) (interface{}, error)) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := auth.AuthenticateHTTP(r, opts.Verifier)
+			if err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+			resp, err := fn(ctx, r)
+			if err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, resp)
+		})
+	}
+
+	handle("GET /v1/meta/stat", func(ctx context.Context, r *http.Request) (interface{}, error) {
+		path := r.URL.Query().Get("path")
+		if path == "" {
+			return nil, status.Error(codes.InvalidArgument, "path is required")
+		}
+		md, err := opts.Store.Get(ctx, path)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return md, nil
+	})
+	handle("GET /v1/admin/history", func(ctx context.Context, r *http.Request) (interface{}, error) {
+		req := &HistoryRequest{Path: r.URL.Query().Get("path")}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid limit: %q", limit)
+			}
+			req.Limit = n
+		}
+		return PathHistory(ctx, opts.Store, req)
+	})
+	if opts.Topology != nil {
+		handle("GET /v1/cluster/topology", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			return GetTopology(ctx, opts.Topology)
+		})
+	}
+	if opts.Access != nil {
+		handle("GET /v1/admin/hot", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			query := r.URL.Query()
+			req := &HotPathsRequest{Order: query.Get("order")}
+			switch scope := query.Get("scope"); scope {
+			case "", "files":
+			case "dirs":
+				req.Directories = true
+			default:
+				return nil, status.Errorf(codes.InvalidArgument, "invalid scope: %q", scope)
+			}
+			if limit := query.Get("limit"); limit != "" {
+				n, err := strconv.Atoi(limit)
+				if err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "invalid limit: %q", limit)
+				}
+				req.Limit = n
+			}
+			return HotPaths(ctx, opts.Access, req)
+		})
+	}
+	if opts.Jobs != nil {
+		handle("GET /v1/admin/jobs", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			return ListJobs(ctx, opts.Jobs)
+		})
+		handle("GET /v1/admin/jobs/{id}", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			return GetJob(ctx, opts.Jobs, r.PathValue("id"))
+		})
+		handle("POST /v1/admin/jobs/{id}/cancel", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			if err := CancelJob(ctx, opts.Jobs, r.PathValue("id")); err != nil {
+				return nil, err
+			}
+			return struct{}{}, nil
+		})
+	}
+	if opts.Quotas != nil {
+		handle("GET /v1/admin/quotas", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			return ListOwnerQuotas(ctx, opts.Quotas, r.URL.Query().Get("kind"))
+		})
+		handle("GET /v1/admin/quotas/{kind}/{name}", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			return GetOwnerQuota(ctx, opts.Quotas, r.PathValue("kind"), r.PathValue("name"))
+		})
+		handle("PUT /v1/admin/quotas/{kind}/{name}", func(ctx context.Context, r *http.Request) (interface{}, error) {
+			req := &OwnerQuotaRequest{Kind: r.PathValue("kind"), Name: r.PathValue("name")}
+			if err := json.NewDecoder(r.Body).Decode(&req.Limits); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid quota limits: %v", err)
+			}
+			return SetOwnerQuota(ctx, opts.Quotas, req)
+		})
+	}
+	return mux
+}
+
+// httpError HTTP/JSON 接口的错误响应，与 gRPC-Gateway 的错误格式一致
+type httpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeHTTPError 将 gRPC 状态写为 JSON 错误响应
+func writeHTTPError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	writeJSON(w, httpStatusFromCode(st.Code()), httpError{Code: int(st.Code()), Message: st.Message()})
+}
+
+// writeJSON 写出 JSON 响应
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// httpStatusFromCode 将 gRPC 状态码转换为 HTTP 状态码
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
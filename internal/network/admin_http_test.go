@@ -0,0 +1,140 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/cluster"
+	"cpfs/internal/jobs"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+// staticVerifier 按令牌查表的测试校验器
+type staticVerifier map[string]*auth.Principal
+
+func (v staticVerifier) Verify(ctx context.Context, token string) (*auth.Principal, error) {
+	if p, ok := v[token]; ok {
+		return p, nil
+	}
+	return nil, errors.New("unknown token")
+}
+
+func TestAdminHTTPHandler(t *testing.T) {
+	store := meta.NewMemoryStore()
+	owner := auth.WithPrincipal(context.Background(), &auth.Principal{User: "alice"})
+	_, err := store.Create(owner, "/report.txt", 0644)
+	require.NoError(t, err)
+
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{RootDir: t.TempDir(), SyncInterval: time.Second, FileMode: 0600})
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	manager := jobs.NewManager(storage)
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{User: "root", Groups: []string{AdminGroup}})
+	job, err := manager.Submit(admin, "scrub", nil, func(ctx context.Context, report func(jobs.Progress)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+
+	membership, err := cluster.NewMembership(cluster.MembershipConfig{NodeID: "meta-1", Role: cluster.RoleMeta}, nil)
+	require.NoError(t, err)
+
+	quotas := meta.NewOwnerQuotas()
+	store.SetOwnerQuotas(quotas)
+
+	access := metrics.NewAccessTracker(metrics.AccessOptions{})
+	access.Record("/report.txt", "alice", 42)
+
+	server := httptest.NewServer(NewAdminHTTPHandler(AdminHTTPOptions{
+		Store:    store,
+		Jobs:     manager,
+		Topology: membership,
+		Access:   access,
+		Quotas:   quotas,
+		Verifier: staticVerifier{
+			"root-token":  {User: "root", Groups: []string{AdminGroup}},
+			"alice-token": {User: "alice"},
+		},
+	}))
+	defer server.Close()
+
+	send := func(method, path, token, body string, out interface{}) int {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+	call := func(method, path, token string, out interface{}) int {
+		return send(method, path, token, "", out)
+	}
+
+	var md meta.Metadata
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/meta/stat?path=/report.txt", "alice-token", &md))
+	assert.Equal(t, "report.txt", md.Name)
+
+	var topo cluster.Topology
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/cluster/topology", "alice-token", &topo))
+	assert.Equal(t, "meta-1", topo.MetaServers[0].ID)
+
+	var events []meta.ChangeEvent
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/admin/history?path=/report.txt&limit=5", "root-token", &events))
+	require.Len(t, events, 1)
+	assert.Equal(t, meta.ChangeCreate, events[0].Type)
+
+	var hot []metrics.AccessStat
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/admin/hot?scope=dirs&order=bytes", "root-token", &hot))
+	assert.Equal(t, []metrics.AccessStat{{Path: "/", Reads: 1, Bytes: 42, Clients: 1}}, hot)
+	assert.Equal(t, http.StatusBadRequest, call("GET", "/v1/admin/hot?scope=volumes", "root-token", nil))
+
+	var herr httpError
+	assert.Equal(t, http.StatusForbidden, call("GET", "/v1/admin/jobs", "alice-token", &herr))
+	assert.Equal(t, int(codes.PermissionDenied), herr.Code)
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/v1/admin/jobs", "", nil))
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/v1/admin/jobs", "bogus", nil))
+	assert.Equal(t, http.StatusBadRequest, call("GET", "/v1/admin/history?path=/report.txt&limit=x", "root-token", nil))
+
+	var list []*jobs.Job
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/admin/jobs", "root-token", &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, http.StatusOK, call("POST", "/v1/admin/jobs/"+job.ID+"/cancel", "root-token", nil))
+	_, err = manager.Wait(admin, job.ID)
+	require.NoError(t, err)
+
+	var got jobs.Job
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/admin/jobs/"+job.ID, "root-token", &got))
+	assert.Equal(t, jobs.StateCanceled, got.State)
+	assert.Equal(t, http.StatusNotFound, call("GET", "/v1/admin/jobs/missing", "root-token", nil))
+
+	var quota meta.OwnerQuota
+	assert.Equal(t, http.StatusOK, send("PUT", "/v1/admin/quotas/user/alice", "root-token", `{"bytes":1024,"files":10}`, &quota))
+	assert.Equal(t, meta.QuotaLimits{Bytes: 1024, Files: 10}, quota.Limits)
+	assert.Equal(t, http.StatusForbidden, send("PUT", "/v1/admin/quotas/user/alice", "alice-token", `{"bytes":1}`, nil))
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/v1/admin/quotas/user/alice", "root-token", `{`, nil))
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/admin/quotas/user/alice", "root-token", &quota))
+	assert.Equal(t, int64(10), quota.Limits.Files)
+	var quotaList []meta.OwnerQuota
+	assert.Equal(t, http.StatusOK, call("GET", "/v1/admin/quotas?kind=user", "root-token", &quotaList))
+	require.Len(t, quotaList, 1)
+	assert.Equal(t, http.StatusBadRequest, call("GET", "/v1/admin/quotas?kind=tenant", "root-token", nil))
+}
@@ -0,0 +1,121 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/jobs"
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPathHistory(t *testing.T) {
+	store := meta.NewMemoryStore()
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{User: "alice"})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{User: "bob"})
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{User: "root", Groups: []string{AdminGroup}})
+
+	file, err := store.Create(alice, "/report.txt", 0644)
+	require.NoError(t, err)
+	file.Size = 42
+	require.NoError(t, store.Update(alice, "/report.txt", file))
+	_, err = store.Create(alice, "/other.txt", 0644)
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(bob, "/report.txt"))
+
+	events, err := PathHistory(admin, store, &HistoryRequest{Path: "/report.txt"})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, meta.ChangeDelete, events[0].Type)
+	assert.Equal(t, "bob", events[0].User)
+	assert.Equal(t, meta.ChangeModify, events[1].Type)
+	assert.Equal(t, meta.ChangeCreate, events[2].Type)
+	assert.Equal(t, "alice", events[2].User)
+
+	events, err = PathHistory(admin, store, &HistoryRequest{Path: "report.txt", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, meta.ChangeDelete, events[0].Type)
+
+	_, err = PathHistory(alice, store, &HistoryRequest{Path: "/report.txt"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = PathHistory(context.Background(), store, &HistoryRequest{Path: "/report.txt"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = PathHistory(admin, store, &HistoryRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestJobAdmin(t *testing.T) {
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{RootDir: t.TempDir(), SyncInterval: time.Second, FileMode: 0600})
+	require.NoError(t, err)
+	t.Cleanup(func() { storage.Close() })
+	manager := jobs.NewManager(storage)
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{User: "alice"})
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{User: "root", Groups: []string{AdminGroup}})
+
+	job, err := manager.Submit(admin, "scrub", nil, func(ctx context.Context, report func(jobs.Progress)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+
+	list, err := ListJobs(admin, manager)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	_, err = ListJobs(alice, manager)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	require.NoError(t, CancelJob(admin, manager, job.ID))
+	_, err = manager.Wait(admin, job.ID)
+	require.NoError(t, err)
+	got, err := GetJob(admin, manager, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StateCanceled, got.State)
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(CancelJob(admin, manager, job.ID)))
+	_, err = GetJob(admin, manager, "missing")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestOwnerQuotaAdmin(t *testing.T) {
+	store := meta.NewMemoryStore()
+	quotas := meta.NewOwnerQuotas()
+	store.SetOwnerQuotas(quotas)
+	ctx := context.Background()
+	require.NoError(t, store.SetDirDefaults(ctx, "/", &meta.DirDefaults{Owner: "alice"}))
+	_, err := store.CreateWithData(ctx, "/a", 0644, []byte("abc"))
+	require.NoError(t, err)
+
+	alice := auth.WithPrincipal(ctx, &auth.Principal{User: "alice"})
+	admin := auth.WithPrincipal(ctx, &auth.Principal{User: "root", Groups: []string{AdminGroup}})
+
+	_, err = SetOwnerQuota(alice, quotas, &OwnerQuotaRequest{Kind: "user", Name: "alice", Limits: meta.QuotaLimits{Bytes: 1}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	got, err := SetOwnerQuota(admin, quotas, &OwnerQuotaRequest{Kind: "user", Name: "alice", Limits: meta.QuotaLimits{Bytes: 10}})
+	require.NoError(t, err)
+	assert.Equal(t, meta.QuotaUsage{Bytes: 3, Files: 1}, got.Usage)
+	_, err = SetOwnerQuota(admin, quotas, &OwnerQuotaRequest{Kind: "user", Name: "alice", Limits: meta.QuotaLimits{Bytes: 10, SoftBytes: 5}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	got, err = GetOwnerQuota(admin, quotas, "user", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), got.Limits.Bytes)
+	_, err = GetOwnerQuota(admin, quotas, "tenant", "alice")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := ListOwnerQuotas(admin, quotas, "user")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	list, err = ListOwnerQuotas(admin, quotas, "group")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = store.CreateWithData(ctx, "/b", 0644, []byte("12345678"))
+	assert.ErrorIs(t, err, meta.ErrQuotaExceeded)
+}
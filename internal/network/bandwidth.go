@@ -0,0 +1,247 @@
+package network
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/config"
+	"cpfs/internal/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// BandwidthOptions 按客户端的流带宽限制配置
+type BandwidthOptions struct {
+	// Rate 每个客户端所有流合计的默认带宽上限（字节/秒），为 0 时不限制
+	Rate int64
+	// Burst 令牌桶容量（字节），为 0 时等于 Rate
+	Burst int64
+	// Overrides 按客户端覆盖带宽上限，键为用户名或未认证客户端的地址
+	Overrides map[string]int64
+	// Metrics 限速次数的指标注册表，为 nil 时使用 metrics.Default
+	Metrics *metrics.Registry
+}
+
+// BandwidthOptionsFromConfig 根据服务器配置创建带宽限制，未配置任何上限时返回 nil
+func BandwidthOptionsFromConfig(cfg *config.ServerConfig) *BandwidthOptions {
+	if cfg.ClientBandwidthLimit <= 0 && len(cfg.ClientBandwidthOverrides) == 0 {
+		return nil
+	}
+	return &BandwidthOptions{
+		Rate:      cfg.ClientBandwidthLimit,
+		Burst:     cfg.ClientBandwidthBurst,
+		Overrides: cfg.ClientBandwidthOverrides,
+	}
+}
+
+// tokenBucket 字节令牌桶
+//
+// 令牌余额可以为负：每次预留立即扣除并按欠额计算等待时间，后到的预留排在之前所有预留之后，
+// 因此同一客户端的多个流按到达顺序轮流获得带宽，任何一个流都不会被饿死。
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket 创建装满令牌的令牌桶
+func newTokenBucket(rate, burst int64, now time.Time) *tokenBucket {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &tokenBucket{rate: float64(rate), burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// reserve 预留 n 个字节的令牌，返回需要等待的时间
+func (b *tokenBucket) reserve(now time.Time, n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel 归还未使用的预留
+func (b *tokenBucket) cancel(n int) {
+	b.mu.Lock()
+	b.tokens = min(b.burst, b.tokens+float64(n))
+	b.mu.Unlock()
+}
+
+// clientBandwidth 单个客户端的令牌桶与活跃流数
+type clientBandwidth struct {
+	bucket  *tokenBucket
+	streams int
+}
+
+// BandwidthLimiter 数据服务器上按客户端限制读写流带宽
+//
+// 同一客户端的所有流共享一个令牌桶，避免单个批处理作业开多个流占满集群网络；
+// 客户端的最后一个流结束后释放其令牌桶。
+type BandwidthLimiter struct {
+	mu       sync.Mutex
+	opts     BandwidthOptions
+	clients  map[string]*clientBandwidth
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+	throttle *metrics.Counter
+}
+
+// NewBandwidthLimiter 创建带宽限制器
+func NewBandwidthLimiter(opts BandwidthOptions) *BandwidthLimiter {
+	registry := opts.Metrics
+	if registry == nil {
+		registry = metrics.Default
+	}
+	return &BandwidthLimiter{
+		opts:    opts,
+		clients: make(map[string]*clientBandwidth),
+		now:     time.Now,
+		sleep:   sleepContext,
+		throttle: registry.Counter("cpfs_client_bandwidth_throttled_total",
+			"Number of data stream messages delayed by per-client bandwidth limits.", nil),
+	}
+}
+
+// sleepContext 等待 d 或 ctx 结束
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rate 返回客户端的带宽上限
+func (l *BandwidthLimiter) rate(client string) int64 {
+	if r, ok := l.opts.Overrides[client]; ok {
+		return r
+	}
+	return l.opts.Rate
+}
+
+// acquire 登记客户端的一个流，不限速时返回 nil
+func (l *BandwidthLimiter) acquire(client string) *tokenBucket {
+	rate := l.rate(client)
+	if rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientBandwidth{bucket: newTokenBucket(rate, l.opts.Burst, l.now())}
+		l.clients[client] = c
+	}
+	c.streams++
+	return c.bucket
+}
+
+// release 注销客户端的一个流
+func (l *BandwidthLimiter) release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.clients[client]; ok {
+		if c.streams--; c.streams <= 0 {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// wait 为 n 个字节等待令牌，ctx 结束时归还预留
+func (l *BandwidthLimiter) wait(ctx context.Context, bucket *tokenBucket, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	delay := bucket.reserve(l.now(), n)
+	if delay <= 0 {
+		return nil
+	}
+	l.throttle.Inc()
+	if err := l.sleep(ctx, delay); err != nil {
+		bucket.cancel(n)
+		return err
+	}
+	return nil
+}
+
+// StreamInterceptor 返回按客户端限制流收发字节数的流拦截器
+func (l *BandwidthLimiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		client := bandwidthClient(ss.Context())
+		bucket := l.acquire(client)
+		if bucket == nil {
+			return handler(srv, ss)
+		}
+		defer l.release(client)
+		return handler(srv, &limitedStream{ServerStream: ss, limiter: l, bucket: bucket})
+	}
+}
+
+// bandwidthClient 返回限速使用的客户端标识：已认证用户名，否则为客户端 IP
+func bandwidthClient(ctx context.Context) string {
+	if p, ok := auth.PrincipalFromContext(ctx); ok && p.User != "" {
+		return p.User
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// messageSize 返回消息的字节数，无法计算时为 0
+func messageSize(m interface{}) int {
+	switch v := m.(type) {
+	case proto.Message:
+		return proto.Size(v)
+	case []byte:
+		return len(v)
+	case interface{ Size() int }:
+		return v.Size()
+	default:
+		return 0
+	}
+}
+
+// limitedStream 收发消息前等待令牌的服务端流
+type limitedStream struct {
+	grpc.ServerStream
+	limiter *BandwidthLimiter
+	bucket  *tokenBucket
+}
+
+func (s *limitedStream) SendMsg(m interface{}) error {
+	if err := s.limiter.wait(s.Context(), s.bucket, messageSize(m)); err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// RecvMsg 在消息到达后按其大小扣除令牌，限速体现在下一次接收之前，
+// 客户端随之受 HTTP/2 流控阻塞
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.limiter.wait(s.Context(), s.bucket, messageSize(m))
+}
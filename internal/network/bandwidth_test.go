@@ -0,0 +1,129 @@
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/config"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// countingStream 记录发送消息数的服务端流
+type countingStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent int
+}
+
+func (s *countingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	s.sent++
+	return nil
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	start := time.Unix(1000, 0)
+	b := newTokenBucket(1000, 0, start)
+
+	assert.Zero(t, b.reserve(start, 1000), "full bucket covers the burst")
+	assert.Equal(t, 500*time.Millisecond, b.reserve(start, 500))
+	// 后到的预留排在前一个之后
+	assert.Equal(t, time.Second, b.reserve(start, 500))
+	b.cancel(500)
+	assert.Equal(t, 250*time.Millisecond, b.reserve(start.Add(time.Second), 750))
+	assert.Zero(t, b.reserve(start.Add(10*time.Second), 1000), "refill is capped at the burst")
+}
+
+func TestBandwidthLimiterSharesClientBucket(t *testing.T) {
+	l := NewBandwidthLimiter(BandwidthOptions{Rate: 100, Overrides: map[string]int64{"batch": 10, "admin": 0}, Metrics: metrics.NewRegistry()})
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+	var slept []time.Duration
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	a, b := l.acquire("alice"), l.acquire("alice")
+	require.Same(t, a, b, "streams of one client share a bucket")
+	require.Nil(t, l.acquire("admin"), "zero override disables the limit")
+
+	ctx := context.Background()
+	// 两个流交替发送，各自等待时间按到达顺序线性增长
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.wait(ctx, a, 50))
+		require.NoError(t, l.wait(ctx, b, 50))
+	}
+	assert.Equal(t, []time.Duration{
+		500 * time.Millisecond, time.Second, 1500 * time.Millisecond,
+		2 * time.Second, 2500 * time.Millisecond, 3 * time.Second,
+	}, slept)
+
+	batch := l.acquire("batch")
+	slept = nil
+	require.NoError(t, l.wait(ctx, batch, 20))
+	assert.Equal(t, []time.Duration{time.Second}, slept)
+
+	l.release("alice")
+	assert.Contains(t, l.clients, "alice")
+	l.release("alice")
+	assert.NotContains(t, l.clients, "alice")
+}
+
+func TestBandwidthLimiterCancel(t *testing.T) {
+	l := NewBandwidthLimiter(BandwidthOptions{Rate: 10, Metrics: metrics.NewRegistry()})
+	bucket := l.acquire("alice")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.wait(ctx, bucket, 20), context.Canceled)
+	assert.InDelta(t, 10, bucket.tokens, 1e-6, "cancelled reservation is returned")
+}
+
+func TestBandwidthStreamInterceptor(t *testing.T) {
+	registry := metrics.NewRegistry()
+	l := NewBandwidthLimiter(BandwidthOptions{Rate: 1 << 20, Metrics: registry})
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 4242}})
+	ss := &countingStream{ctx: ctx}
+
+	err := l.StreamInterceptor()(nil, ss, &grpc.StreamServerInfo{FullMethod: "/cpfs.Data/Read"}, func(srv interface{}, stream grpc.ServerStream) error {
+		assert.Contains(t, l.clients, "10.0.0.7")
+		for i := 0; i < 3; i++ {
+			if err := stream.SendMsg(make([]byte, 1024)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, ss.sent)
+	assert.Empty(t, l.clients)
+}
+
+func TestBandwidthClient(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 4242}})
+	assert.Equal(t, "10.0.0.7", bandwidthClient(ctx))
+	assert.Equal(t, "alice", bandwidthClient(auth.WithPrincipal(ctx, &auth.Principal{User: "alice"})))
+	assert.Equal(t, "", bandwidthClient(context.Background()))
+}
+
+func TestBandwidthOptionsFromConfig(t *testing.T) {
+	assert.Nil(t, BandwidthOptionsFromConfig(&config.ServerConfig{}))
+	opts := BandwidthOptionsFromConfig(&config.ServerConfig{
+		ClientBandwidthLimit:     100 << 20,
+		ClientBandwidthOverrides: map[string]int64{"batch": 10 << 20},
+	})
+	require.NotNil(t, opts)
+	assert.Equal(t, int64(100<<20), opts.Rate)
+	assert.Equal(t, int64(10<<20), opts.Overrides["batch"])
+}
@@ -0,0 +1,162 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"cpfs/internal/cluster"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	// gossipCodecName gossip 消息的 gRPC content-subtype
+	gossipCodecName = "cpfs-gossip-json"
+	// gossipPushPullMethod PushPull RPC 的完整方法名
+	gossipPushPullMethod = "/cpfs.cluster.Gossip/PushPull"
+	// DefaultGossipTimeout 单次 gossip 交换的默认超时
+	DefaultGossipTimeout = 2 * time.Second
+)
+
+func init() {
+	encoding.RegisterCodec(gossipCodec{})
+}
+
+// gossipCodec 以 JSON 编码 gossip 消息，成员记录无需生成代码即可在节点间交换
+type gossipCodec struct{}
+
+func (gossipCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (gossipCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (gossipCodec) Name() string                       { return gossipCodecName }
+
+// GossipMessage PushPull 请求与响应，携带发送方的成员摘要
+type GossipMessage struct {
+	Members []cluster.Member `json:"members"`
+}
+
+// GossipHandler 处理对端发来的 gossip 交换，由 cluster.Membership 实现
+type GossipHandler interface {
+	// Merge 合并对端的成员摘要
+	Merge(digest []cluster.Member)
+	// Digest 返回本地成员摘要
+	Digest() []cluster.Member
+}
+
+// gossipServiceDesc 手工声明的 gossip 服务，仅包含 PushPull 一个一元方法
+var gossipServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cpfs.cluster.Gossip",
+	HandlerType: (*GossipHandler)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "PushPull", Handler: gossipPushPullHandler},
+	},
+	Metadata: "internal/network/gossip.go",
+}
+
+// gossipPushPullHandler 合并对端摘要并返回合并后的本地摘要；成功的交换同时充当探活
+func gossipPushPullHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(GossipMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	handle := func(ctx context.Context, req any) (any, error) {
+		h := srv.(GossipHandler)
+		h.Merge(req.(*GossipMessage).Members)
+		return &GossipMessage{Members: h.Digest()}, nil
+	}
+	if interceptor == nil {
+		return handle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: gossipPushPullMethod}
+	return interceptor(ctx, in, info, handle)
+}
+
+// RegisterGossip 在服务器上注册 gossip 服务，须在 Start 之前调用
+func (s *GRPCServer) RegisterGossip(h GossipHandler) {
+	s.server.RegisterService(&gossipServiceDesc, h)
+}
+
+// GossipTransport 基于 gRPC 的 cluster.Transport 实现，按对端地址复用连接
+type GossipTransport struct {
+	timeout time.Duration
+	opts    []grpc.DialOption
+
+	mu     sync.Mutex
+	conns  map[string]*grpc.ClientConn
+	closed bool
+}
+
+// NewGossipTransport 创建 gossip 传输层
+//
+// timeout 为 0 时使用 DefaultGossipTimeout；未提供拨号选项时使用明文连接，
+// 启用 TLS 的集群应传入 grpc.WithTransportCredentials。
+func NewGossipTransport(timeout time.Duration, opts ...grpc.DialOption) *GossipTransport {
+	if timeout <= 0 {
+		timeout = DefaultGossipTimeout
+	}
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	return &GossipTransport{
+		timeout: timeout,
+		opts:    opts,
+		conns:   make(map[string]*grpc.ClientConn),
+	}
+}
+
+// PushPull 将本地成员摘要发送给对端并返回对端的摘要
+func (t *GossipTransport) PushPull(ctx context.Context, address string, digest []cluster.Member) ([]cluster.Member, error) {
+	conn, err := t.conn(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	out := new(GossipMessage)
+	if err := conn.Invoke(ctx, gossipPushPullMethod, &GossipMessage{Members: digest}, out,
+		grpc.CallContentSubtype(gossipCodecName)); err != nil {
+		return nil, err
+	}
+	return out.Members, nil
+}
+
+// conn 返回到对端的连接，首次使用时创建
+func (t *GossipTransport) conn(address string) (*grpc.ClientConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, fmt.Errorf("gossip transport is closed")
+	}
+	if conn, ok := t.conns[address]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(address, t.opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.conns[address] = conn
+	return conn, nil
+}
+
+// Close 关闭所有对端连接
+func (t *GossipTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	var firstErr error
+	for address, conn := range t.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(t.conns, address)
+	}
+	return firstErr
+}
@@ -0,0 +1,67 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/cluster"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startGossipServer 启动注册了 gossip 服务的服务器，返回实际监听地址
+func startGossipServer(t *testing.T, h GossipHandler) string {
+	server, err := NewGRPCServer(ServerOptions{Address: "127.0.0.1:0"})
+	require.NoError(t, err)
+	server.RegisterGossip(h)
+
+	go server.Start()
+	t.Cleanup(server.Stop)
+	require.Eventually(t, func() bool {
+		return server.GetAddress() != "127.0.0.1:0"
+	}, time.Second*5, time.Millisecond*10)
+	return server.GetAddress()
+}
+
+func newGossipMembership(t *testing.T, id string, transport cluster.Transport, seeds ...string) *cluster.Membership {
+	m, err := cluster.NewMembership(cluster.MembershipConfig{NodeID: id, Seeds: seeds}, transport)
+	require.NoError(t, err)
+	return m
+}
+
+func TestGossipTransport(t *testing.T) {
+	var _ cluster.Transport = (*GossipTransport)(nil)
+
+	transport := NewGossipTransport(time.Second)
+	defer transport.Close()
+
+	b := newGossipMembership(t, "data-2", transport)
+	addrB := startGossipServer(t, b)
+	a := newGossipMembership(t, "data-1", transport, addrB)
+	a.UpdateLoad(cluster.NodeLoad{Capacity: 100, Used: 30})
+
+	// 通过网络交换摘要：a 获知 b，b 收到 a 的负载
+	remote, err := transport.PushPull(context.Background(), addrB, a.Digest())
+	require.NoError(t, err)
+	a.Merge(remote)
+
+	member, ok := a.Get("data-2")
+	require.True(t, ok)
+	assert.Equal(t, cluster.StateAlive, member.State)
+	member, ok = b.Get("data-1")
+	require.True(t, ok)
+	assert.Equal(t, int64(30), member.Load.Used)
+
+	// 不可达的对端在超时内返回错误
+	unreachable := NewGossipTransport(time.Millisecond * 200)
+	defer unreachable.Close()
+	_, err = unreachable.PushPull(context.Background(), "127.0.0.1:1", a.Digest())
+	assert.Error(t, err)
+
+	// 关闭后不再建立连接
+	require.NoError(t, transport.Close())
+	_, err = transport.PushPull(context.Background(), addrB, a.Digest())
+	assert.Error(t, err)
+}
@@ -2,7 +2,9 @@ package network
 
 import (
 	"cpfs/internal/logger"
+	"fmt"
 	"net"
+	"strings"
 	"sync"
 
 	"go.uber.org/zap"
@@ -17,12 +19,26 @@ type GRPCServer struct {
 	listener net.Listener
 	mu       sync.Mutex
 	running  bool
+	log      logger.Logger
 }
 
 // NewGRPCServer 创建新的 gRPC 服务器
 func NewGRPCServer(opts ServerOptions) (*GRPCServer, error) {
 	var serverOpts []grpc.ServerOption
 
+	// 校验监听网络与地址，IPv6 字面量需使用 [::1]:port 形式
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	switch opts.Network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network: %s", opts.Network)
+	}
+	if _, _, err := net.SplitHostPort(opts.Address); err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %v", opts.Address, err)
+	}
+
 	// 设置消息大小限制
 	if opts.MaxMsgSize > 0 {
 		serverOpts = append(serverOpts,
@@ -32,7 +48,11 @@ func NewGRPCServer(opts ServerOptions) (*GRPCServer, error) {
 	}
 
 	// 配置 TLS
-	if opts.TLS {
+	if opts.ACME != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(ACMETLSConfig(opts.ACME, opts.SNI))))
+	} else if opts.SNI != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(opts.SNI.TLSConfig())))
+	} else if opts.TLS {
 		creds, err := credentials.NewServerTLSFromFile(opts.CertFile, opts.KeyFile)
 		if err != nil {
 			return nil, err
@@ -40,12 +60,34 @@ func NewGRPCServer(opts ServerOptions) (*GRPCServer, error) {
 		serverOpts = append(serverOpts, grpc.Creds(creds))
 	}
 
+	// 配置拦截器
+	if len(opts.UnaryInterceptors) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(opts.UnaryInterceptors...))
+	}
+	streamInterceptors := opts.StreamInterceptors
+	if opts.SlowClient != nil {
+		slow := *opts.SlowClient
+		if slow.Logger == nil {
+			slow.Logger = opts.Logger
+		}
+		streamInterceptors = append(streamInterceptors[:len(streamInterceptors):len(streamInterceptors)],
+			SlowClientInterceptor(slow))
+	}
+	if opts.Bandwidth != nil {
+		streamInterceptors = append(streamInterceptors[:len(streamInterceptors):len(streamInterceptors)],
+			NewBandwidthLimiter(*opts.Bandwidth).StreamInterceptor())
+	}
+	if len(streamInterceptors) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainStreamInterceptor(streamInterceptors...))
+	}
+
 	// 创建 gRPC 服务器
 	server := grpc.NewServer(serverOpts...)
 
 	return &GRPCServer{
 		opts:   opts,
 		server: server,
+		log:    logger.OrDefault(opts.Logger),
 	}, nil
 }
 
@@ -56,19 +98,23 @@ func (s *GRPCServer) Start() error {
 		s.mu.Unlock()
 		return nil
 	}
-	s.mu.Unlock()
 
-	lis, err := net.Listen("tcp", s.opts.Address)
+	lis, err := net.Listen(s.opts.Network, s.opts.Address)
 	if err != nil {
+		s.mu.Unlock()
 		return err
 	}
 
+	// 监听器与运行状态在锁内设置，Stop 和 GetAddress 会并发读取
 	s.listener = lis
 	s.running = true
+	s.mu.Unlock()
 
-	logger.Info("Starting gRPC server",
+	s.log.Info("Starting gRPC server",
 		zap.String("address", s.opts.Address),
-		zap.Bool("tls", s.opts.TLS),
+		zap.String("network", s.opts.Network),
+		zap.String("advertise", s.GetAdvertiseAddress()),
+		zap.Bool("tls", s.opts.TLS || s.opts.SNI != nil || s.opts.ACME != nil),
 	)
 
 	return s.server.Serve(lis)
@@ -83,7 +129,7 @@ func (s *GRPCServer) Stop() {
 		return
 	}
 
-	logger.Info("Stopping gRPC server",
+	s.log.Info("Stopping gRPC server",
 		zap.String("address", s.opts.Address))
 
 	s.server.GracefulStop()
@@ -92,8 +138,30 @@ func (s *GRPCServer) Stop() {
 
 // GetAddress 获取服务器地址
 func (s *GRPCServer) GetAddress() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.listener != nil {
 		return s.listener.Addr().String()
 	}
 	return s.opts.Address
 }
+
+// GetAdvertiseAddress 获取对外通告地址
+func (s *GRPCServer) GetAdvertiseAddress() string {
+	bound := s.GetAddress()
+	if s.opts.AdvertiseAddress == "" {
+		return bound
+	}
+	if _, _, err := net.SplitHostPort(s.opts.AdvertiseAddress); err == nil {
+		return s.opts.AdvertiseAddress
+	}
+
+	// 通告地址未包含端口，沿用实际监听端口
+	_, port, err := net.SplitHostPort(bound)
+	if err != nil {
+		return s.opts.AdvertiseAddress
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(s.opts.AdvertiseAddress, "["), "]")
+	return net.JoinHostPort(host, port)
+}
@@ -3,6 +3,7 @@ package network
 import (
 	"context"
 	"cpfs/internal/logger"
+	"net"
 	"testing"
 	"time"
 
@@ -66,3 +67,57 @@ func TestGRPCServerTLSFailure(t *testing.T) {
 	_, err := NewGRPCServer(opts)
 	assert.Error(t, err)
 }
+
+func TestGRPCServerInvalidAddress(t *testing.T) {
+	_, err := NewGRPCServer(ServerOptions{Address: "::1:50051"})
+	assert.Error(t, err)
+
+	_, err = NewGRPCServer(ServerOptions{Address: "127.0.0.1:0", Network: "udp"})
+	assert.Error(t, err)
+}
+
+func TestGRPCServerIPv6(t *testing.T) {
+	lis, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback not available")
+	}
+	lis.Close()
+
+	server, err := NewGRPCServer(ServerOptions{Address: "[::1]:0", Network: "tcp6"})
+	assert.NoError(t, err)
+
+	go func() {
+		err := server.Start()
+		assert.NoError(t, err)
+	}()
+	time.Sleep(time.Millisecond * 200)
+
+	host, _, err := net.SplitHostPort(server.GetAddress())
+	assert.NoError(t, err)
+	assert.Equal(t, "::1", host)
+
+	server.Stop()
+}
+
+func TestGRPCServerAdvertiseAddress(t *testing.T) {
+	tests := []struct {
+		advertise string
+		expected  string
+	}{
+		{"", "[::]:50051"},
+		{"node1.example.com:6000", "node1.example.com:6000"},
+		{"node1.example.com", "node1.example.com:50051"},
+		{"fd00::10", "[fd00::10]:50051"},
+		{"[fd00::10]", "[fd00::10]:50051"},
+		{"[fd00::10]:7000", "[fd00::10]:7000"},
+	}
+
+	for _, tt := range tests {
+		server, err := NewGRPCServer(ServerOptions{
+			Address:          "[::]:50051",
+			AdvertiseAddress: tt.advertise,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, server.GetAdvertiseAddress(), tt.advertise)
+	}
+}
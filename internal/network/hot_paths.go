@@ -0,0 +1,44 @@
+package network
+
+import (
+	"context"
+
+	"cpfs/internal/metrics"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultHotPathsLimit 热点查询默认返回的路径数量
+const DefaultHotPathsLimit = 20
+
+// HotPathsRequest 热点文件/目录查询请求
+type HotPathsRequest struct {
+	Directories bool   // 查询热点目录，否则查询热点文件
+	Order       string // reads、bytes 或 clients，为空时按读取次数
+	Limit       int    // 最多返回的路径数量，为 0 时使用 DefaultHotPathsLimit
+}
+
+// RecordRead 将一次读取计入访问统计，客户端标识与带宽限制相同：已认证用户名，否则为客户端 IP
+func RecordRead(ctx context.Context, tracker *metrics.AccessTracker, path string, bytes int64) {
+	tracker.Record(path, bandwidthClient(ctx), bytes)
+}
+
+// HotPaths 管理查询：返回统计窗口内访问最多的文件或目录，用于分层和缓存容量规划
+func HotPaths(ctx context.Context, tracker *metrics.AccessTracker, req *HotPathsRequest) ([]metrics.AccessStat, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	order, err := metrics.ParseAccessOrder(req.Order)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultHotPathsLimit
+	}
+	if req.Directories {
+		return tracker.TopDirectories(limit, order), nil
+	}
+	return tracker.TopFiles(limit, order), nil
+}
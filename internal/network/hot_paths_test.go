@@ -0,0 +1,44 @@
+package network
+
+import (
+	"context"
+	"testing"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHotPaths(t *testing.T) {
+	tracker := metrics.NewAccessTracker(metrics.AccessOptions{})
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{User: "alice"})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{User: "bob"})
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{User: "root", Groups: []string{AdminGroup}})
+
+	RecordRead(alice, tracker, "/data/a.bin", 4096)
+	RecordRead(bob, tracker, "/data/a.bin", 4096)
+	RecordRead(alice, tracker, "/data/b.bin", 1<<20)
+
+	files, err := HotPaths(admin, tracker, &HotPathsRequest{})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, metrics.AccessStat{Path: "/data/a.bin", Reads: 2, Bytes: 8192, Clients: 2}, files[0])
+
+	files, err = HotPaths(admin, tracker, &HotPathsRequest{Order: "bytes", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "/data/b.bin", files[0].Path)
+
+	dirs, err := HotPaths(admin, tracker, &HotPathsRequest{Directories: true})
+	require.NoError(t, err)
+	assert.Equal(t, []metrics.AccessStat{{Path: "/data", Reads: 3, Bytes: 8192 + 1<<20, Clients: 2}}, dirs)
+
+	_, err = HotPaths(alice, tracker, &HotPathsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = HotPaths(admin, tracker, &HotPathsRequest{Order: "latency"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,179 @@
+package network
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ServingTimeTrailer 数据服务器在响应 trailer 中返回的服务端处理耗时（微秒）
+const ServingTimeTrailer = "x-cpfs-serving-time-us"
+
+// latencyDecay 延迟滑动平均中新样本的权重
+const latencyDecay = 0.2
+
+// servingTrailer 构造携带处理耗时的 trailer
+func servingTrailer(start time.Time) metadata.MD {
+	return metadata.Pairs(ServingTimeTrailer, strconv.FormatInt(time.Since(start).Microseconds(), 10))
+}
+
+// ServingTimeInterceptor 返回在一元响应 trailer 中附加服务端处理耗时的拦截器
+func ServingTimeInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		// 不在 gRPC 服务端上下文中（例如直接调用）时设置失败，忽略即可
+		_ = grpc.SetTrailer(ctx, servingTrailer(start))
+		return resp, err
+	}
+}
+
+// ServingTimeStreamInterceptor 返回在流结束时的 trailer 中附加服务端处理耗时的拦截器
+func ServingTimeStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		ss.SetTrailer(servingTrailer(start))
+		return err
+	}
+}
+
+// ServerLatency 单个数据服务器的延迟统计
+type ServerLatency struct {
+	Server  string
+	Total   metrics.HistogramSnapshot // 客户端观测到的端到端耗时（秒）
+	Serving metrics.HistogramSnapshot // 服务端返回的处理耗时（秒），总耗时减去它即为网络与排队耗时
+	Average time.Duration             // 端到端耗时的滑动平均
+}
+
+// serverLatency 单个服务器的延迟直方图
+type serverLatency struct {
+	total   *metrics.Histogram
+	serving *metrics.Histogram
+	average float64
+}
+
+// LatencyTracker 客户端按数据服务器聚合的延迟统计，用于副本选择并可上报给元数据服务器
+type LatencyTracker struct {
+	mu      sync.RWMutex
+	servers map[string]*serverLatency
+}
+
+// NewLatencyTracker 创建延迟统计
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{servers: make(map[string]*serverLatency)}
+}
+
+// Observe 记录一次对 server 的调用，serving 小于 0 表示服务端未返回处理耗时
+func (t *LatencyTracker) Observe(server string, total, serving time.Duration) {
+	t.mu.Lock()
+	s, ok := t.servers[server]
+	if !ok {
+		s = &serverLatency{
+			total:   metrics.NewHistogram(nil),
+			serving: metrics.NewHistogram(nil),
+			average: total.Seconds(),
+		}
+		t.servers[server] = s
+	}
+	s.average += latencyDecay * (total.Seconds() - s.average)
+	t.mu.Unlock()
+
+	s.total.ObserveDuration(total)
+	if serving >= 0 {
+		s.serving.ObserveDuration(serving)
+	}
+}
+
+// UnaryClientInterceptor 返回记录每次调用延迟和服务端处理耗时的客户端拦截器，按连接目标地址聚合
+func (t *LatencyTracker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var trailer metadata.MD
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		t.Observe(serverAddr(cc.Target()), time.Since(start), parseServingTime(trailer))
+		return err
+	}
+}
+
+// serverAddr 去掉连接目标中的解析器前缀（例如 passthrough:///），与块位置中的地址保持一致
+func serverAddr(target string) string {
+	if i := strings.Index(target, ":///"); i >= 0 {
+		return target[i+len(":///"):]
+	}
+	return target
+}
+
+// parseServingTime 解析 trailer 中的处理耗时，缺失或非法时返回 -1
+func parseServingTime(md metadata.MD) time.Duration {
+	values := md.Get(ServingTimeTrailer)
+	if len(values) == 0 {
+		return -1
+	}
+	us, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil || us < 0 {
+		return -1
+	}
+	return time.Duration(us) * time.Microsecond
+}
+
+// Rank 按延迟滑动平均从低到高排序副本位置，用于选择读取的副本
+//
+// 尚无样本的服务器按已知服务器的平均值参与排序，既不会被一直跳过也不会被优先挤满。
+func (t *LatencyTracker) Rank(locations []string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var known float64
+	var n int
+	for _, loc := range locations {
+		if s, ok := t.servers[loc]; ok {
+			known += s.average
+			n++
+		}
+	}
+	fallback := 0.0
+	if n > 0 {
+		fallback = known / float64(n)
+	}
+	score := func(loc string) float64 {
+		if s, ok := t.servers[loc]; ok {
+			return s.average
+		}
+		return fallback
+	}
+
+	ranked := append([]string(nil), locations...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return score(ranked[i]) < score(ranked[j])
+	})
+	return ranked
+}
+
+// Snapshot 返回所有服务器的延迟统计，按服务器地址排序，可上报给元数据服务器用于放置决策
+func (t *LatencyTracker) Snapshot() []ServerLatency {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make([]ServerLatency, 0, len(t.servers))
+	for server, s := range t.servers {
+		out = append(out, ServerLatency{
+			Server:  server,
+			Total:   s.total.Snapshot(),
+			Serving: s.serving.Snapshot(),
+			Average: time.Duration(s.average * float64(time.Second)),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Server < out[j].Server
+	})
+	return out
+}
@@ -0,0 +1,73 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestLatencyTrackerRank(t *testing.T) {
+	tracker := NewLatencyTracker()
+	for i := 0; i < 5; i++ {
+		tracker.Observe("ds-1:9000", 40*time.Millisecond, 30*time.Millisecond)
+		tracker.Observe("ds-2:9000", 5*time.Millisecond, 2*time.Millisecond)
+		tracker.Observe("ds-3:9000", 20*time.Millisecond, -1)
+	}
+
+	assert.Equal(t, []string{"ds-2:9000", "ds-3:9000", "ds-1:9000"},
+		tracker.Rank([]string{"ds-1:9000", "ds-2:9000", "ds-3:9000"}))
+	// 未知服务器按已知服务器平均值（约 21.7ms）排序
+	assert.Equal(t, []string{"ds-2:9000", "ds-4:9000", "ds-1:9000"},
+		tracker.Rank([]string{"ds-1:9000", "ds-4:9000", "ds-2:9000"}))
+	assert.Equal(t, []string{"a", "b"}, tracker.Rank([]string{"a", "b"}))
+
+	snap := tracker.Snapshot()
+	require.Len(t, snap, 3)
+	assert.Equal(t, "ds-1:9000", snap[0].Server)
+	assert.Equal(t, uint64(5), snap[0].Total.Count)
+	assert.InDelta(t, 0.03, snap[0].Serving.Mean(), 1e-9)
+	assert.Equal(t, 40*time.Millisecond, snap[0].Average)
+	assert.Equal(t, uint64(0), snap[2].Serving.Count, "no serving time reported by ds-3")
+}
+
+func TestLatencyClientInterceptor(t *testing.T) {
+	cc, err := grpc.NewClient("passthrough:///ds-1:9000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+
+	tracker := NewLatencyTracker()
+	interceptor := tracker.UnaryClientInterceptor()
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			if trailer, ok := opt.(grpc.TrailerCallOption); ok {
+				*trailer.TrailerAddr = metadata.Pairs(ServingTimeTrailer, "1500")
+			}
+		}
+		return nil
+	}
+	require.NoError(t, interceptor(context.Background(), "/cpfs.Data/Read", nil, nil, cc, invoker))
+
+	snap := tracker.Snapshot()
+	require.Len(t, snap, 1)
+	assert.Equal(t, "ds-1:9000", snap[0].Server)
+	assert.InDelta(t, 0.0015, snap[0].Serving.Sum, 1e-9)
+}
+
+func TestParseServingTime(t *testing.T) {
+	assert.Equal(t, 250*time.Microsecond, parseServingTime(metadata.Pairs(ServingTimeTrailer, "250")))
+	assert.Equal(t, time.Duration(-1), parseServingTime(nil))
+	assert.Equal(t, time.Duration(-1), parseServingTime(metadata.Pairs(ServingTimeTrailer, "soon")))
+}
+
+func TestServingTimeInterceptorPassesThrough(t *testing.T) {
+	resp, err := ServingTimeInterceptor()(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/cpfs.Data/Read"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
@@ -0,0 +1,44 @@
+package network
+
+import (
+	"context"
+
+	"cpfs/internal/meta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ReadConsistencyHeader 读请求的一致性级别：serializable（默认）或 linearizable
+const ReadConsistencyHeader = "x-cpfs-read-consistency"
+
+// WithReadConsistency 在客户端请求上下文中附加读一致性级别
+func WithReadConsistency(ctx context.Context, c meta.ReadConsistency) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, ReadConsistencyHeader, c.String())
+}
+
+// incomingReadConsistency 解析请求中的读一致性级别
+func incomingReadConsistency(ctx context.Context) (meta.ReadConsistency, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return meta.ReadSerializable, nil
+	}
+	values := md.Get(ReadConsistencyHeader)
+	if len(values) == 0 {
+		return meta.ReadSerializable, nil
+	}
+	return meta.ParseReadConsistency(values[0])
+}
+
+// ReadConsistencyInterceptor 返回将请求头中的读一致性级别写入上下文的一元拦截器
+func ReadConsistencyInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		c, err := incomingReadConsistency(ctx)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return handler(meta.WithReadConsistency(ctx, c), req)
+	}
+}
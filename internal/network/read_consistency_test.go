@@ -0,0 +1,40 @@
+package network
+
+import (
+	"context"
+	"testing"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadConsistencyInterceptor(t *testing.T) {
+	interceptor := ReadConsistencyInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/cpfs.Meta/Get"}
+
+	var got meta.ReadConsistency
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = meta.ReadConsistencyFromContext(ctx)
+		return nil, nil
+	}
+
+	outgoing := WithReadConsistency(context.Background(), meta.ReadLinearizable)
+	md, _ := metadata.FromOutgoingContext(outgoing)
+	_, err := interceptor(metadata.NewIncomingContext(context.Background(), md), nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, meta.ReadLinearizable, got)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, meta.ReadSerializable, got)
+
+	bad := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ReadConsistencyHeader, "eventual"))
+	_, err = interceptor(bad, nil, info, handler)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
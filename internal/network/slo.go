@@ -0,0 +1,30 @@
+package network
+
+import (
+	"context"
+	"time"
+
+	"cpfs/internal/metrics"
+
+	"google.golang.org/grpc"
+)
+
+// SLOUnaryInterceptor 记录一元调用的延迟与结果，计入匹配的服务等级目标
+func SLOUnaryInterceptor(tracker *metrics.SLOTracker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		tracker.Observe(info.FullMethod, time.Since(start), err)
+		return resp, err
+	}
+}
+
+// SLOStreamInterceptor 记录流调用的总耗时与结果，计入匹配的服务等级目标
+func SLOStreamInterceptor(tracker *metrics.SLOTracker) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		tracker.Observe(info.FullMethod, time.Since(start), err)
+		return err
+	}
+}
@@ -0,0 +1,37 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSLOUnaryInterceptor(t *testing.T) {
+	tracker, err := metrics.NewSLOTracker([]metrics.SLO{{Name: "meta", Methods: []string{"/Get"}, Objective: 0.9}}, metrics.NewRegistry())
+	require.NoError(t, err)
+	interceptor := SLOUnaryInterceptor(tracker)
+
+	failing := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/cpfs.Meta/Get"}, failing)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/cpfs.Meta/Get"}, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/cpfs.Meta/List"}, failing)
+
+	burn, _ := tracker.BurnRate("meta", time.Hour)
+	assert.InDelta(t, 5, burn, 1e-6, "one of two matching calls failed against a 10% budget")
+}
@@ -0,0 +1,177 @@
+package network
+
+import (
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/metrics"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// SlowClientOptions 慢客户端检测配置
+type SlowClientOptions struct {
+	// MaxSendStall 单次发送阻塞的最长时间，超过即认为客户端停止读取，为 0 时不检查
+	MaxSendStall time.Duration
+	// Window 统计阻塞比例的窗口，为 0 时使用 DefaultSlowClientWindow
+	Window time.Duration
+	// MaxBlockedRatio 窗口内阻塞在发送上的时间占比上限（0~1），为 0 时不检查
+	MaxBlockedRatio float64
+	// CheckInterval 检查周期，为 0 时使用 DefaultSlowClientCheckInterval
+	CheckInterval time.Duration
+	// OnEvict 驱逐慢客户端时的回调
+	OnEvict func(SlowClientEvent)
+	// Metrics 驱逐计数的指标注册表，为 nil 时使用 metrics.Default
+	Metrics *metrics.Registry
+	// Logger 日志，为 nil 时使用全局日志
+	Logger logger.Logger
+}
+
+const (
+	// DefaultSlowClientWindow 默认阻塞比例统计窗口
+	DefaultSlowClientWindow = 30 * time.Second
+	// DefaultSlowClientCheckInterval 默认检查周期
+	DefaultSlowClientCheckInterval = time.Second
+)
+
+// SlowClientEvent 慢客户端驱逐事件
+type SlowClientEvent struct {
+	Method       string        // 流方法
+	Peer         string        // 客户端地址
+	Stall        time.Duration // 驱逐时当前发送已阻塞的时间
+	BlockedRatio float64       // 最近窗口内的阻塞比例
+	Time         time.Time
+}
+
+// sendTracker 记录流的发送阻塞情况
+type sendTracker struct {
+	mu          sync.Mutex
+	sendStart   time.Time     // 当前发送开始时间，未在发送时为零值
+	blocked     time.Duration // 当前窗口内已完成发送的阻塞时间
+	windowStart time.Time
+}
+
+// begin 记录发送开始
+func (t *sendTracker) begin(now time.Time) {
+	t.mu.Lock()
+	t.sendStart = now
+	t.mu.Unlock()
+}
+
+// end 记录发送结束
+func (t *sendTracker) end(now time.Time) {
+	t.mu.Lock()
+	t.blocked += now.Sub(later(t.sendStart, t.windowStart))
+	t.sendStart = time.Time{}
+	t.mu.Unlock()
+}
+
+// check 返回当前发送已阻塞的时间，窗口结束时同时返回窗口内的阻塞比例并开始新窗口
+func (t *sendTracker) check(now time.Time, window time.Duration) (stall time.Duration, ratio float64, full bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	blocked := t.blocked
+	if !t.sendStart.IsZero() {
+		stall = now.Sub(t.sendStart)
+		blocked += now.Sub(later(t.sendStart, t.windowStart))
+	}
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < window {
+		return stall, 0, false
+	}
+	t.windowStart = now
+	t.blocked = 0
+	return stall, float64(blocked) / float64(elapsed), true
+}
+
+// later 返回较晚的时间
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// trackedStream 记录发送阻塞时间的服务端流
+type trackedStream struct {
+	grpc.ServerStream
+	tracker *sendTracker
+}
+
+func (s *trackedStream) SendMsg(m interface{}) error {
+	s.tracker.begin(time.Now())
+	err := s.ServerStream.SendMsg(m)
+	s.tracker.end(time.Now())
+	return err
+}
+
+// SlowClientInterceptor 返回检测并驱逐慢客户端的流拦截器
+//
+// 处理函数在独立的 goroutine 中运行；检测到客户端停止读取或读取过慢时拦截器立即以
+// codes.ResourceExhausted 结束 RPC，gRPC 随之重置流并释放服务端缓冲区，阻塞中的发送随即返回。
+func SlowClientInterceptor(opts SlowClientOptions) grpc.StreamServerInterceptor {
+	if opts.Window <= 0 {
+		opts.Window = DefaultSlowClientWindow
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = DefaultSlowClientCheckInterval
+	}
+	registry := opts.Metrics
+	if registry == nil {
+		registry = metrics.Default
+	}
+	log := logger.OrDefault(opts.Logger)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		tracker := &sendTracker{windowStart: time.Now()}
+		done := make(chan error, 1)
+		go func() {
+			done <- handler(srv, &trackedStream{ServerStream: ss, tracker: tracker})
+		}()
+
+		ticker := time.NewTicker(opts.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case err := <-done:
+				return err
+			case now := <-ticker.C:
+				stall, ratio, full := tracker.check(now, opts.Window)
+				stalled := opts.MaxSendStall > 0 && stall > opts.MaxSendStall
+				slow := full && opts.MaxBlockedRatio > 0 && ratio > opts.MaxBlockedRatio
+				if !stalled && !slow {
+					continue
+				}
+
+				event := SlowClientEvent{
+					Method:       info.FullMethod,
+					Stall:        stall,
+					BlockedRatio: ratio,
+					Time:         now,
+				}
+				if p, ok := peer.FromContext(ss.Context()); ok {
+					event.Peer = p.Addr.String()
+				}
+				registry.Counter("cpfs_slow_client_evictions_total", "Number of streams cancelled because the client consumed too slowly.",
+					metrics.Labels{"method": info.FullMethod}).Inc()
+				log.Warn("Evicting slow client",
+					zap.String("method", event.Method),
+					zap.String("peer", event.Peer),
+					zap.Duration("stall", event.Stall),
+					zap.Float64("blocked_ratio", event.BlockedRatio),
+				)
+				if opts.OnEvict != nil {
+					opts.OnEvict(event)
+				}
+				return status.Errorf(codes.ResourceExhausted, "client is consuming too slowly: stalled %v, blocked %.0f%%",
+					stall.Round(time.Millisecond), ratio*100)
+			}
+		}
+	}
+}
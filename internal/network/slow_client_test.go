@@ -0,0 +1,110 @@
+package network
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockingStream 模拟客户端读取速度的服务端流，每次发送阻塞 delay，ctx 取消后立即返回
+type blockingStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	delay time.Duration
+	sent  atomic.Int64
+}
+
+func (s *blockingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *blockingStream) SendMsg(m interface{}) error {
+	select {
+	case <-time.After(s.delay):
+		s.sent.Add(1)
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// sendLoop 持续发送消息直到出错
+func sendLoop(srv interface{}, ss grpc.ServerStream) error {
+	for {
+		if err := ss.SendMsg(struct{}{}); err != nil {
+			return err
+		}
+	}
+}
+
+func TestSlowClientInterceptorStall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var evicted SlowClientEvent
+	registry := metrics.NewRegistry()
+	interceptor := SlowClientInterceptor(SlowClientOptions{
+		MaxSendStall:  50 * time.Millisecond,
+		CheckInterval: 10 * time.Millisecond,
+		OnEvict:       func(e SlowClientEvent) { evicted = e },
+		Metrics:       registry,
+	})
+
+	stream := &blockingStream{ctx: ctx, delay: time.Hour}
+	info := &grpc.StreamServerInfo{FullMethod: "/cpfs.Data/Read"}
+	err := interceptor(nil, stream, info, sendLoop)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "/cpfs.Data/Read", evicted.Method)
+	assert.Greater(t, evicted.Stall, 50*time.Millisecond)
+	assert.Equal(t, uint64(1), registry.Counter("cpfs_slow_client_evictions_total", "",
+		metrics.Labels{"method": "/cpfs.Data/Read"}).Value())
+}
+
+func TestSlowClientInterceptorBlockedRatio(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interceptor := SlowClientInterceptor(SlowClientOptions{
+		Window:          100 * time.Millisecond,
+		MaxBlockedRatio: 0.8,
+		CheckInterval:   10 * time.Millisecond,
+		Metrics:         metrics.NewRegistry(),
+	})
+
+	stream := &blockingStream{ctx: ctx, delay: 5 * time.Millisecond}
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/m"}, sendLoop)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Greater(t, stream.sent.Load(), int64(0))
+}
+
+func TestSlowClientInterceptorHealthy(t *testing.T) {
+	interceptor := SlowClientInterceptor(SlowClientOptions{
+		MaxSendStall:    50 * time.Millisecond,
+		Window:          20 * time.Millisecond,
+		MaxBlockedRatio: 0.8,
+		CheckInterval:   5 * time.Millisecond,
+		Metrics:         metrics.NewRegistry(),
+	})
+
+	stream := &blockingStream{ctx: context.Background(), delay: time.Millisecond}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		for i := 0; i < 10; i++ {
+			if err := ss.SendMsg(struct{}{}); err != nil {
+				return err
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return nil
+	}
+	require.NoError(t, interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/m"}, handler))
+	assert.Equal(t, int64(10), stream.sent.Load())
+}
@@ -0,0 +1,235 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// TenantCert 定义租户域名对应的证书与命名空间
+type TenantCert struct {
+	// ServerName 域名，支持 *.example.com 形式的通配符
+	ServerName string
+	CertFile   string
+	KeyFile    string
+	// Namespace 租户命名空间根路径
+	Namespace string
+}
+
+// tenantEntry 已加载的租户证书
+type tenantEntry struct {
+	config  TenantCert
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// SNIRouter 基于 TLS SNI 选择证书和租户命名空间
+type SNIRouter struct {
+	mu       sync.RWMutex
+	tenants  map[string]*tenantEntry
+	fallback string
+	log      logger.Logger
+}
+
+// NewSNIRouter 创建 SNI 路由器，fallback 为客户端未携带 SNI 或无匹配时使用的域名
+func NewSNIRouter(tenants []TenantCert, fallback string) (*SNIRouter, error) {
+	r := &SNIRouter{
+		tenants:  make(map[string]*tenantEntry),
+		fallback: strings.ToLower(fallback),
+		log:      logger.Default(),
+	}
+
+	for _, tenant := range tenants {
+		if tenant.ServerName == "" {
+			return nil, fmt.Errorf("tenant server name is required")
+		}
+		entry := &tenantEntry{config: tenant}
+		if err := entry.load(); err != nil {
+			return nil, err
+		}
+		r.tenants[strings.ToLower(tenant.ServerName)] = entry
+	}
+
+	if r.fallback != "" {
+		if _, ok := r.tenants[r.fallback]; !ok {
+			return nil, fmt.Errorf("fallback server name not configured: %s", fallback)
+		}
+	}
+
+	return r, nil
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (r *SNIRouter) SetLogger(l logger.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = logger.OrDefault(l)
+}
+
+// modTimes 返回证书文件和私钥文件的修改时间
+func (c TenantCert) modTimes() (certMod, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(c.CertFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat certificate for %s: %v", c.ServerName, err)
+	}
+	keyInfo, err := os.Stat(c.KeyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat private key for %s: %v", c.ServerName, err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// load 从文件加载证书
+func (e *tenantEntry) load() error {
+	certMod, keyMod, err := e.config.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(e.config.CertFile, e.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate for %s: %v", e.config.ServerName, err)
+	}
+
+	e.cert = &cert
+	e.certMod = certMod
+	e.keyMod = keyMod
+	return nil
+}
+
+// lookup 按 SNI 查找租户，依次尝试精确匹配、通配符匹配和默认租户
+func (r *SNIRouter) lookup(serverName string) (*tenantEntry, bool) {
+	if entry, ok := r.lookupExact(serverName); ok {
+		return entry, true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.tenants[r.fallback]
+	return entry, ok
+}
+
+// lookupExact 按 SNI 查找租户，仅进行精确匹配和通配符匹配
+func (r *SNIRouter) lookupExact(serverName string) (*tenantEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if entry, ok := r.tenants[name]; ok {
+		return entry, true
+	}
+
+	if idx := strings.Index(name, "."); idx > 0 {
+		if entry, ok := r.tenants["*"+name[idx:]]; ok {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate
+func (r *SNIRouter) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	entry, ok := r.lookup(hello.ServerName)
+	if !ok {
+		return nil, fmt.Errorf("no certificate for server name: %s", hello.ServerName)
+	}
+	return entry.cert, nil
+}
+
+// Namespace 返回 SNI 对应的租户命名空间
+func (r *SNIRouter) Namespace(serverName string) (string, bool) {
+	entry, ok := r.lookup(serverName)
+	if !ok {
+		return "", false
+	}
+	return entry.config.Namespace, true
+}
+
+// TLSConfig 返回按 SNI 选择证书的 TLS 配置
+func (r *SNIRouter) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Reload 重新加载证书文件或私钥文件有变化的证书，加载失败时保留旧证书
+//
+// 修改时间与上次加载时不同即视为变化，因此只轮换私钥文件、或替换文件的
+// 修改时间更早（cp -p、符号链接切换）时同样会触发重新加载。
+func (r *SNIRouter) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var firstErr error
+	for name, entry := range r.tenants {
+		certMod, keyMod, err := entry.config.modTimes()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if certMod.Equal(entry.certMod) && keyMod.Equal(entry.keyMod) {
+			continue
+		}
+
+		updated := &tenantEntry{config: entry.config}
+		if err := updated.load(); err != nil {
+			r.log.Warn("Failed to reload tenant certificate",
+				zap.String("server_name", name),
+				zap.Error(err),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		r.tenants[name] = updated
+		r.log.Info("Reloaded tenant certificate",
+			zap.String("server_name", name),
+		)
+	}
+
+	return firstErr
+}
+
+// WatchReload 周期性检查证书文件并自动重新加载，直到 ctx 被取消
+func (r *SNIRouter) WatchReload(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = r.Reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// TenantFromContext 从 gRPC 请求上下文中获取连接 SNI 对应的租户命名空间
+func (r *SNIRouter) TenantFromContext(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", false
+	}
+	return r.Namespace(info.State.ServerName)
+}
@@ -0,0 +1,133 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert 生成自签名证书并写入文件
+func writeTestCert(t *testing.T, dir, name string, serial int64) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+// leafSerial 解析证书序列号
+func leafSerial(t *testing.T, cert *tls.Certificate) int64 {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.SerialNumber.Int64()
+}
+
+func TestSNIRouter(t *testing.T) {
+	dir := t.TempDir()
+	certA, keyA := writeTestCert(t, dir, "a.example.com", 1)
+	certB, keyB := writeTestCert(t, dir, "wild.example.org", 2)
+
+	router, err := NewSNIRouter([]TenantCert{
+		{ServerName: "a.example.com", CertFile: certA, KeyFile: keyA, Namespace: "/tenants/a"},
+		{ServerName: "*.example.org", CertFile: certB, KeyFile: keyB, Namespace: "/tenants/b"},
+	}, "a.example.com")
+	require.NoError(t, err)
+
+	cert, err := router.GetCertificate(&tls.ClientHelloInfo{ServerName: "A.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), leafSerial(t, cert))
+
+	cert, err = router.GetCertificate(&tls.ClientHelloInfo{ServerName: "x.example.org"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), leafSerial(t, cert))
+
+	ns, ok := router.Namespace("x.example.org")
+	assert.True(t, ok)
+	assert.Equal(t, "/tenants/b", ns)
+
+	// 无 SNI 时使用默认租户
+	ns, ok = router.Namespace("")
+	assert.True(t, ok)
+	assert.Equal(t, "/tenants/a", ns)
+
+	// 证书文件更新后自动重新加载
+	certA, keyA = writeTestCert(t, dir, "a.example.com", 3)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certA, future, future))
+	require.NoError(t, router.Reload())
+
+	cert, err = router.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), leafSerial(t, cert))
+
+	// 证书文件的修改时间不变、只有私钥文件变化时同样重新加载
+	certA, keyA = writeTestCert(t, dir, "a.example.com", 4)
+	require.NoError(t, os.Chtimes(certA, future, future))
+	require.NoError(t, os.Chtimes(keyA, future.Add(time.Minute), future.Add(time.Minute)))
+	require.NoError(t, router.Reload())
+
+	cert, err = router.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), leafSerial(t, cert))
+
+	// 替换文件的修改时间早于旧文件（例如 cp -p）时同样重新加载
+	certA, keyA = writeTestCert(t, dir, "a.example.com", 5)
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(certA, past, past))
+	require.NoError(t, os.Chtimes(keyA, past, past))
+	require.NoError(t, router.Reload())
+
+	cert, err = router.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), leafSerial(t, cert))
+}
+
+func TestSNIRouterErrors(t *testing.T) {
+	dir := t.TempDir()
+	certA, keyA := writeTestCert(t, dir, "a.example.com", 1)
+
+	_, err := NewSNIRouter([]TenantCert{{ServerName: "a.example.com", CertFile: "missing.crt", KeyFile: keyA}}, "")
+	assert.Error(t, err)
+
+	_, err = NewSNIRouter([]TenantCert{{ServerName: "a.example.com", CertFile: certA, KeyFile: keyA}}, "b.example.com")
+	assert.Error(t, err)
+
+	router, err := NewSNIRouter([]TenantCert{{ServerName: "a.example.com", CertFile: certA, KeyFile: keyA}}, "")
+	require.NoError(t, err)
+	_, err = router.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"})
+	assert.Error(t, err)
+
+	server, err := NewGRPCServer(ServerOptions{Address: "127.0.0.1:0", SNI: router})
+	assert.NoError(t, err)
+	assert.NotNil(t, server)
+}
@@ -0,0 +1,58 @@
+package network
+
+import (
+	"context"
+
+	"cpfs/internal/cluster"
+
+	"google.golang.org/grpc/status"
+)
+
+// TopologySource 提供带版本号的集群视图，由 cluster.Membership 实现
+type TopologySource interface {
+	Topology() (cluster.Topology, <-chan struct{})
+}
+
+// TopologyRequest Topology 请求
+type TopologyRequest struct {
+	// Version 调用方已持有的视图版本，WatchTopology 在版本不同时推送，0 表示立即推送当前视图
+	Version uint64
+}
+
+// TopologyStream WatchTopology 服务端流，与生成代码中的 ServerStream 形状一致
+type TopologyStream interface {
+	Context() context.Context
+	Send(*cluster.Topology) error
+}
+
+// GetTopology 实现 Topology RPC，返回当前集群视图
+func GetTopology(ctx context.Context, source TopologySource) (*cluster.Topology, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	topo, _ := source.Topology()
+	return &topo, nil
+}
+
+// WatchTopology 实现服务端流式 RPC，集群视图每次变化时推送完整视图
+//
+// 短时间内的多次变化可能合并为一次推送；服务器重启后版本号重新计数，
+// 因此请求版本与当前版本不同即推送。客户端断开时正常返回。
+func WatchTopology(source TopologySource, req *TopologyRequest, stream TopologyStream) error {
+	ctx := stream.Context()
+	last := req.Version
+	for {
+		topo, changed := source.Topology()
+		if topo.Version != last {
+			if err := stream.Send(&topo); err != nil {
+				return err
+			}
+			last = topo.Version
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return watchStatus(ctx.Err())
+		}
+	}
+}
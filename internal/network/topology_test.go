@@ -0,0 +1,64 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/cluster"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTopologyStream 记录推送视图的测试流
+type fakeTopologyStream struct {
+	ctx  context.Context
+	sent chan *cluster.Topology
+}
+
+func (s *fakeTopologyStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeTopologyStream) Send(topo *cluster.Topology) error {
+	s.sent <- topo
+	return nil
+}
+
+func TestGetTopology(t *testing.T) {
+	m, err := cluster.NewMembership(cluster.MembershipConfig{NodeID: "meta-1", Role: cluster.RoleMeta}, nil)
+	require.NoError(t, err)
+
+	topo, err := GetTopology(context.Background(), m)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), topo.Version)
+	assert.Equal(t, "meta-1", topo.MetaServers[0].ID)
+}
+
+func TestWatchTopology(t *testing.T) {
+	m, err := cluster.NewMembership(cluster.MembershipConfig{NodeID: "meta-1", Role: cluster.RoleMeta}, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeTopologyStream{ctx: ctx, sent: make(chan *cluster.Topology, 4)}
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchTopology(m, &TopologyRequest{}, stream)
+	}()
+
+	first := <-stream.sent
+	assert.Equal(t, uint64(1), first.Version)
+
+	m.Merge([]cluster.Member{{ID: "data-1", Role: cluster.RoleData, State: cluster.StateAlive, Incarnation: 1}})
+	select {
+	case next := <-stream.sent:
+		assert.Equal(t, uint64(2), next.Version)
+		require.Len(t, next.DataServers, 1)
+	case <-time.After(time.Second):
+		t.Fatal("topology change not pushed")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
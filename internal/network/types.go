@@ -2,6 +2,11 @@ package network
 
 import (
 	"context"
+
+	"cpfs/internal/logger"
+
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
 )
 
 // ServerOptions 定义服务器选项
@@ -11,6 +16,24 @@ type ServerOptions struct {
 	TLS        bool
 	CertFile   string
 	KeyFile    string
+	// Network 监听网络类型：tcp（默认，通配地址时双栈）、tcp4 或 tcp6
+	Network string
+	// AdvertiseAddress 对外通告地址，为空时使用实际监听地址；
+	// 未包含端口时使用实际监听端口（适用于 NAT/Kubernetes 场景）
+	AdvertiseAddress string
+	// SNI 多租户证书路由，设置后按 SNI 选择证书并忽略 CertFile/KeyFile
+	SNI *SNIRouter
+	// ACME 自动证书管理器，设置后未在 SNI 中静态配置的域名自动签发证书
+	ACME *autocert.Manager
+	// 一元与流拦截器，按顺序链式执行（例如认证、限流）
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+	// SlowClient 慢客户端检测，设置后在流拦截器链末尾驱逐读取过慢的客户端
+	SlowClient *SlowClientOptions
+	// Bandwidth 按客户端的流带宽限制，设置后在流拦截器链末尾限速
+	Bandwidth *BandwidthOptions
+	// Logger 日志，为 nil 时使用全局日志；同时作为未单独设置日志的 SlowClient 的日志
+	Logger logger.Logger
 }
 
 // Server 定义网络服务器接口
@@ -21,6 +44,8 @@ type Server interface {
 	Stop()
 	// GetAddress 获取服务器地址
 	GetAddress() string
+	// GetAdvertiseAddress 获取对外通告地址
+	GetAdvertiseAddress() string
 }
 
 // Connection 定义网络连接接口
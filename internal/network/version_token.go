@@ -0,0 +1,78 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"cpfs/internal/meta"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// VersionTokenHeader 携带元数据版本令牌的 gRPC 元数据键，读取响应中返回、修改请求中回传
+	VersionTokenHeader = "x-cpfs-version"
+	// ForceHeader 修改请求设置为 true 时跳过版本令牌检查，无条件覆盖
+	ForceHeader = "x-cpfs-force"
+)
+
+// IncomingVersionToken 从请求中读取版本令牌和强制覆盖标志
+func IncomingVersionToken(ctx context.Context) (token string, force bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	if values := md.Get(VersionTokenHeader); len(values) > 0 {
+		token = values[0]
+	}
+	if values := md.Get(ForceHeader); len(values) > 0 {
+		force, _ = strconv.ParseBool(values[0])
+	}
+	return token, force
+}
+
+// SendVersionToken 在响应头中返回元数据的版本令牌，List 等多条目响应应在消息体中逐条携带
+func SendVersionToken(ctx context.Context, m *meta.Metadata) error {
+	return grpc.SetHeader(ctx, metadata.Pairs(VersionTokenHeader, meta.VersionToken(m)))
+}
+
+// WithVersionToken 在客户端请求上下文中附加版本令牌
+func WithVersionToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, VersionTokenHeader, token)
+}
+
+// WithForce 在客户端请求上下文中附加强制覆盖标志
+func WithForce(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, ForceHeader, "true")
+}
+
+// RequireVersionToken 返回一元拦截器，要求 mutating 判定为修改操作的请求携带版本令牌或强制覆盖标志
+func RequireVersionToken(mutating func(fullMethod string) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if mutating(info.FullMethod) {
+			token, force := IncomingVersionToken(ctx)
+			if token == "" && !force {
+				return nil, status.Errorf(codes.FailedPrecondition,
+					"%s requires %s header or %s=true", info.FullMethod, VersionTokenHeader, ForceHeader)
+			}
+			if token != "" {
+				if _, _, err := meta.ParseVersionToken(token); err != nil {
+					return nil, status.Error(codes.InvalidArgument, err.Error())
+				}
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// VersionStatus 将版本冲突错误转换为 codes.Aborted 状态，其他错误原样返回
+func VersionStatus(err error) error {
+	if errors.Is(err, meta.ErrVersionConflict) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return err
+}
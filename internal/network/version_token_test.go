@@ -0,0 +1,65 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// incoming 将客户端上下文中的元数据转换为服务端收到的上下文
+func incoming(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestRequireVersionToken(t *testing.T) {
+	interceptor := RequireVersionToken(func(method string) bool {
+		return strings.HasSuffix(method, "/Update")
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	update := &grpc.UnaryServerInfo{FullMethod: "/cpfs.Meta/Update"}
+	get := &grpc.UnaryServerInfo{FullMethod: "/cpfs.Meta/Get"}
+
+	_, err := interceptor(context.Background(), nil, get, handler)
+	assert.NoError(t, err)
+
+	_, err = interceptor(context.Background(), nil, update, handler)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	token := meta.VersionToken(&meta.Metadata{Inode: 7, Version: 3})
+	ctx := incoming(WithVersionToken(context.Background(), token))
+	_, err = interceptor(ctx, nil, update, handler)
+	assert.NoError(t, err)
+	got, force := IncomingVersionToken(ctx)
+	assert.Equal(t, token, got)
+	assert.False(t, force)
+
+	_, err = interceptor(incoming(WithVersionToken(context.Background(), "bogus")), nil, update, handler)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	ctx = incoming(WithForce(context.Background()))
+	_, err = interceptor(ctx, nil, update, handler)
+	assert.NoError(t, err)
+	_, force = IncomingVersionToken(ctx)
+	assert.True(t, force)
+}
+
+func TestVersionStatus(t *testing.T) {
+	conflict := fmt.Errorf("update failed: %w", &meta.VersionConflictError{Path: "/a", Expected: 1, Actual: 2})
+	assert.Equal(t, codes.Aborted, status.Code(VersionStatus(conflict)))
+
+	other := fmt.Errorf("boom")
+	require.Equal(t, other, VersionStatus(other))
+}
@@ -0,0 +1,80 @@
+package network
+
+import (
+	"context"
+	"errors"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/meta"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WatchRequest WatchDirectory 请求
+type WatchRequest struct {
+	Path      string // 监听的目录
+	Recursive bool   // 是否包含所有子孙路径
+	FromSeq   uint64 // 从该序号开始回放，0 表示只接收新事件
+}
+
+// WatchStream WatchDirectory 服务端流，与生成代码中的 ServerStream 形状一致
+type WatchStream interface {
+	Context() context.Context
+	Send(*meta.ChangeEvent) error
+}
+
+// WatchSource 提供目录元数据和变更日志的元数据存储
+type WatchSource interface {
+	Get(ctx context.Context, path string) (*meta.Metadata, error)
+	Changelog() *meta.Changelog
+}
+
+// WatchDirectory 实现服务端流式 RPC，将目录下的变更事件推送给客户端
+//
+// 客户端落后超过变更日志容量时返回 codes.OutOfRange，应重新 List 后以新的序号订阅；
+// 客户端断开时正常返回。
+func WatchDirectory(store WatchSource, req *WatchRequest, stream WatchStream) error {
+	ctx := stream.Context()
+
+	if session, ok := auth.SessionFromContext(ctx); ok {
+		release, err := session.Acquire(auth.ResourceWatches)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	dir, err := store.Get(ctx, req.Path)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if dir.Type != meta.TypeDirectory {
+		return status.Errorf(codes.InvalidArgument, "not a directory: %s", req.Path)
+	}
+
+	watcher, err := store.Changelog().Watch(ctx, req.Path, req.Recursive, req.FromSeq)
+	if err != nil {
+		return watchStatus(err)
+	}
+	for event := range watcher.Events {
+		if err := stream.Send(&event); err != nil {
+			return err
+		}
+	}
+	return watchStatus(watcher.Err())
+}
+
+// watchStatus 将订阅结束原因转换为 gRPC 状态
+func watchStatus(err error) error {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled):
+		return nil
+	case errors.Is(err, meta.ErrChangelogTruncated):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,94 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/auth"
+	"cpfs/internal/meta"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeWatchStream 记录发送事件的测试流
+type fakeWatchStream struct {
+	ctx    context.Context
+	events chan *meta.ChangeEvent
+}
+
+func (s *fakeWatchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeWatchStream) Send(e *meta.ChangeEvent) error {
+	s.events <- e
+	return nil
+}
+
+func TestWatchDirectory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := meta.NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/dir", 0755))
+
+	stream := &fakeWatchStream{ctx: ctx, events: make(chan *meta.ChangeEvent, 8)}
+	req := &WatchRequest{Path: "/dir", FromSeq: store.Changelog().LastSeq() + 1}
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchDirectory(store, req, stream)
+	}()
+
+	_, err := store.Create(ctx, "/dir/file", 0644)
+	require.NoError(t, err)
+
+	select {
+	case e := <-stream.events:
+		assert.Equal(t, meta.ChangeCreate, e.Type)
+		assert.Equal(t, "/dir/file", e.Path)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestWatchDirectoryErrors(t *testing.T) {
+	ctx := context.Background()
+	store := meta.NewMemoryStore()
+	_, err := store.Create(ctx, "/file", 0644)
+	require.NoError(t, err)
+
+	stream := &fakeWatchStream{ctx: ctx}
+
+	err = WatchDirectory(store, &WatchRequest{Path: "/missing"}, stream)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = WatchDirectory(store, &WatchRequest{Path: "/file"}, stream)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	for i := 0; i < meta.DefaultChangelogCapacity; i++ {
+		store.Changelog().Append(meta.ChangeEvent{Type: meta.ChangeModify, Path: "/file"})
+	}
+	err = WatchDirectory(store, &WatchRequest{Path: "/", FromSeq: 1}, stream)
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+}
+
+func TestWatchDirectorySessionLimit(t *testing.T) {
+	table, err := auth.NewExportTable(nil)
+	require.NoError(t, err)
+	table.SetLimits(auth.SessionLimits{MaxWatches: 1})
+	session, err := table.Setup(&auth.Principal{User: "alice"}, "/")
+	require.NoError(t, err)
+
+	release, err := session.Acquire(auth.ResourceWatches)
+	require.NoError(t, err)
+
+	ctx := auth.WithSession(context.Background(), session)
+	err = WatchDirectory(meta.NewMemoryStore(), &WatchRequest{Path: "/"}, &fakeWatchStream{ctx: ctx})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	release()
+}
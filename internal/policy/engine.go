@@ -0,0 +1,300 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// DefaultTimeout 单个模块一次求值的默认超时
+const DefaultTimeout = 100 * time.Millisecond
+
+// Request 提交给策略模块的操作描述，以 JSON 编码作为模块输入
+type Request struct {
+	Op      string `json:"op"`
+	Path    string `json:"path"`
+	NewPath string `json:"new_path,omitempty"` // 重命名的目标路径或交换的另一个路径
+	Target  string `json:"target,omitempty"`   // 符号链接的目标或硬链接指向的已有文件
+	Mode    uint32 `json:"mode,omitempty"`     // 创建文件或目录的权限
+	Size    int64  `json:"size,omitempty"`     // 写入或截断后的文件大小
+}
+
+// Decision 策略模块以 JSON 编码返回的结果
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Module 已编译的策略模块
+//
+// Evaluate 以 JSON 编码的 Request 调用模块导出的 evaluate 函数，返回 JSON 编码的 Decision。
+// 输入输出在模块线性内存中的传递方式由 Runtime 的实现约定。
+type Module interface {
+	Evaluate(ctx context.Context, input []byte) ([]byte, error)
+	Close(ctx context.Context) error
+}
+
+// Runtime 编译 WebAssembly 模块的运行时，默认实现为 WazeroRuntime
+type Runtime interface {
+	Compile(ctx context.Context, name string, wasm []byte) (Module, error)
+}
+
+// Options 策略引擎选项
+type Options struct {
+	// Timeout 单个模块一次求值的超时，为 0 时使用 DefaultTimeout
+	Timeout time.Duration
+	// FailOpen 为 true 时模块出错或超时放行操作，否则拒绝
+	FailOpen bool
+}
+
+// namedModule 带名称的已加载模块
+type namedModule struct {
+	name   string
+	module Module
+}
+
+// Engine 用 WebAssembly 策略模块对元数据操作做准入检查，实现 meta.Hook
+//
+// 运维人员把模块放在策略目录中，无需重新编译 cpfs 即可实现命名规范、禁止特定扩展名、
+// 按目录限制文件大小等规则。创建、删除、重命名以及改变文件大小的操作按模块名称顺序求值，
+// 任一模块拒绝即拒绝操作；其他操作不经过模块。
+type Engine struct {
+	runtime Runtime
+	opts    Options
+	results map[string]*metrics.Counter
+
+	mu      sync.RWMutex
+	modules []namedModule // 按名称排序
+	log     logger.Logger
+}
+
+// NewEngine 创建没有加载模块的策略引擎，registry 为 nil 时使用 metrics.Default
+func NewEngine(runtime Runtime, opts Options, registry *metrics.Registry) *Engine {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if registry == nil {
+		registry = metrics.Default
+	}
+	e := &Engine{
+		runtime: runtime,
+		opts:    opts,
+		results: make(map[string]*metrics.Counter),
+		log:     logger.Default(),
+	}
+	for _, result := range []string{"allow", "deny", "error"} {
+		e.results[result] = registry.Counter("cpfs_policy_evaluations_total", "Admission policy module evaluations.", metrics.Labels{"result": result})
+	}
+	return e
+}
+
+// NewEngineFromConfig 按 ServerConfig 的策略配置创建引擎并加载 PolicyDir 中的模块
+func NewEngineFromConfig(ctx context.Context, cfg *config.ServerConfig, runtime Runtime, registry *metrics.Registry) (*Engine, error) {
+	e := NewEngine(runtime, Options{
+		Timeout:  time.Duration(cfg.PolicyTimeout) * time.Millisecond,
+		FailOpen: cfg.PolicyFailOpen,
+	}, registry)
+	if cfg.PolicyDir != "" {
+		if err := e.LoadDir(ctx, cfg.PolicyDir); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (e *Engine) SetLogger(l logger.Logger) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.log = logger.OrDefault(l)
+}
+
+// Load 编译并加载名为 name 的模块，替换同名的已加载模块
+func (e *Engine) Load(ctx context.Context, name string, wasm []byte) error {
+	module, err := e.runtime.Compile(ctx, name, wasm)
+	if err != nil {
+		return fmt.Errorf("failed to compile policy module %s: %v", name, err)
+	}
+
+	e.mu.Lock()
+	var old Module
+	i := sort.Search(len(e.modules), func(i int) bool { return e.modules[i].name >= name })
+	if i < len(e.modules) && e.modules[i].name == name {
+		old = e.modules[i].module
+		e.modules[i].module = module
+	} else {
+		e.modules = append(e.modules, namedModule{})
+		copy(e.modules[i+1:], e.modules[i:])
+		e.modules[i] = namedModule{name: name, module: module}
+	}
+	log := e.log
+	e.mu.Unlock()
+
+	if old != nil {
+		if err := old.Close(ctx); err != nil {
+			log.Warn("Failed to close replaced policy module", zap.String("module", name), zap.Error(err))
+		}
+	}
+	log.Info("Loaded policy module", zap.String("module", name))
+	return nil
+}
+
+// LoadDir 加载目录中全部 .wasm 文件，模块名称为去掉扩展名的文件名
+func (e *Engine) LoadDir(ctx context.Context, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return fmt.Errorf("failed to list policy modules: %v", err)
+	}
+	for _, file := range files {
+		wasm, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read policy module: %v", err)
+		}
+		if err := e.Load(ctx, strings.TrimSuffix(filepath.Base(file), ".wasm"), wasm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unload 卸载名为 name 的模块
+func (e *Engine) Unload(ctx context.Context, name string) error {
+	e.mu.Lock()
+	i := sort.Search(len(e.modules), func(i int) bool { return e.modules[i].name >= name })
+	if i == len(e.modules) || e.modules[i].name != name {
+		e.mu.Unlock()
+		return fmt.Errorf("policy module not loaded: %s", name)
+	}
+	module := e.modules[i].module
+	e.modules = append(e.modules[:i:i], e.modules[i+1:]...)
+	e.mu.Unlock()
+	return module.Close(ctx)
+}
+
+// Modules 返回已加载的模块名称，按求值顺序排列
+func (e *Engine) Modules() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, len(e.modules))
+	for i, m := range e.modules {
+		names[i] = m.name
+	}
+	return names
+}
+
+// Close 卸载全部模块
+func (e *Engine) Close(ctx context.Context) error {
+	e.mu.Lock()
+	modules := e.modules
+	e.modules = nil
+	e.mu.Unlock()
+
+	var errs []error
+	for _, m := range modules {
+		if err := m.module.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", m.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// request 把需要检查的操作转换为模块输入，其他操作返回 false
+func request(op *meta.OpInfo) (Request, bool) {
+	req := Request{Op: string(op.Op), Path: op.Path}
+	switch op.Op {
+	case meta.OpCreate, meta.OpMkdir:
+		req.Mode = uint32(op.Mode)
+	case meta.OpSymlink, meta.OpLink:
+		req.Target = op.Target
+	case meta.OpRename, meta.OpExchange:
+		req.NewPath = op.NewPath
+	case meta.OpDelete, meta.OpRmdir, meta.OpDeleteAll:
+	case meta.OpTruncate:
+		req.Size = op.Size
+	case meta.OpUpdate:
+		if op.Meta == nil {
+			return req, false
+		}
+		req.Size = op.Meta.Size
+	default:
+		return req, false
+	}
+	return req, true
+}
+
+// Evaluate 依次用已加载的模块检查请求，任一模块拒绝时返回包含其原因的错误
+func (e *Engine) Evaluate(ctx context.Context, req Request) error {
+	e.mu.RLock()
+	modules, log := e.modules, e.log
+	e.mu.RUnlock()
+	if len(modules) == 0 {
+		return nil
+	}
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode policy request: %v", err)
+	}
+	for _, m := range modules {
+		decision, err := e.evaluate(ctx, m.module, input)
+		if err != nil {
+			e.results["error"].Inc()
+			log.Warn("Policy module failed", zap.String("module", m.name), zap.String("op", req.Op),
+				zap.String("path", req.Path), zap.Error(err))
+			if e.opts.FailOpen {
+				continue
+			}
+			return fmt.Errorf("policy %s failed: %v", m.name, err)
+		}
+		if !decision.Allow {
+			e.results["deny"].Inc()
+			if decision.Reason == "" {
+				return fmt.Errorf("policy %s", m.name)
+			}
+			return fmt.Errorf("policy %s: %s", m.name, decision.Reason)
+		}
+		e.results["allow"].Inc()
+	}
+	return nil
+}
+
+// evaluate 在超时内调用一个模块并解码结果
+func (e *Engine) evaluate(ctx context.Context, module Module, input []byte) (Decision, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+	output, err := module.Evaluate(ctx, input)
+	if err != nil {
+		return Decision{}, err
+	}
+	var decision Decision
+	if err := json.Unmarshal(output, &decision); err != nil {
+		return Decision{}, fmt.Errorf("invalid decision: %v", err)
+	}
+	return decision, nil
+}
+
+// Before 实现 meta.Hook
+func (e *Engine) Before(ctx context.Context, op *meta.OpInfo) error {
+	req, ok := request(op)
+	if !ok {
+		return nil
+	}
+	return e.Evaluate(ctx, req)
+}
+
+// After 实现 meta.Hook
+func (e *Engine) After(ctx context.Context, op *meta.OpInfo, err error) {}
@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// suffixModule 拒绝以指定后缀结尾的路径，用来代替真正的 WebAssembly 模块
+type suffixModule struct {
+	suffix string
+	closed bool
+}
+
+func (m *suffixModule) Evaluate(ctx context.Context, input []byte) ([]byte, error) {
+	var req Request
+	if err := json.Unmarshal(input, &req); err != nil {
+		return nil, err
+	}
+	switch m.suffix {
+	case "error":
+		return nil, errors.New("trap")
+	case "slow":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if strings.HasSuffix(req.Path, m.suffix) || strings.HasSuffix(req.NewPath, m.suffix) {
+		return json.Marshal(Decision{Reason: "extension " + m.suffix + " is not allowed"})
+	}
+	return json.Marshal(Decision{Allow: true})
+}
+
+func (m *suffixModule) Close(ctx context.Context) error {
+	m.closed = true
+	return nil
+}
+
+// suffixRuntime 把模块内容当作要拒绝的后缀
+type suffixRuntime struct {
+	compiled map[string]*suffixModule
+}
+
+func (r *suffixRuntime) Compile(ctx context.Context, name string, wasm []byte) (Module, error) {
+	if len(wasm) == 0 {
+		return nil, errors.New("empty module")
+	}
+	m := &suffixModule{suffix: string(wasm)}
+	if r.compiled == nil {
+		r.compiled = make(map[string]*suffixModule)
+	}
+	r.compiled[name] = m
+	return m, nil
+}
+
+func TestEngineAdmission(t *testing.T) {
+	ctx := context.Background()
+	rt := &suffixRuntime{}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "no-exe.wasm"), []byte(".exe"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "no-tmp.wasm"), []byte(".tmp"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0644))
+
+	e, err := NewEngineFromConfig(ctx, &config.ServerConfig{PolicyDir: dir}, rt, metrics.NewRegistry())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"no-exe", "no-tmp"}, e.Modules())
+
+	assert.NoError(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a.txt"}))
+	err = e.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a.exe"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no-exe")
+	assert.Error(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpRename, Path: "/a.txt", NewPath: "/a.tmp"}))
+	assert.Error(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpExchange, Path: "/a.txt", NewPath: "/a.tmp"}))
+	assert.Error(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpLink, Path: "/b.exe", Target: "/a.txt"}))
+
+	// 不检查的操作直接放行
+	assert.NoError(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpSetAttr, Path: "/a.exe"}))
+
+	// 替换和卸载模块
+	require.NoError(t, e.Load(ctx, "no-exe", []byte(".bin")))
+	assert.NoError(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a.exe"}))
+	require.NoError(t, e.Unload(ctx, "no-tmp"))
+	assert.True(t, rt.compiled["no-tmp"].closed)
+	assert.NoError(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpDelete, Path: "/a.tmp"}))
+	assert.Error(t, e.Unload(ctx, "no-tmp"))
+
+	assert.Error(t, e.Load(ctx, "empty", nil))
+	require.NoError(t, e.Close(ctx))
+	assert.Empty(t, e.Modules())
+}
+
+func TestEngineFailures(t *testing.T) {
+	ctx := context.Background()
+	rt := &suffixRuntime{}
+
+	closed := NewEngine(rt, Options{Timeout: 10 * time.Millisecond}, metrics.NewRegistry())
+	require.NoError(t, closed.Load(ctx, "broken", []byte("error")))
+	assert.Error(t, closed.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a"}))
+	require.NoError(t, closed.Load(ctx, "broken", []byte("slow")))
+	assert.Error(t, closed.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a"}))
+
+	open := NewEngine(rt, Options{Timeout: 10 * time.Millisecond, FailOpen: true}, metrics.NewRegistry())
+	require.NoError(t, open.Load(ctx, "broken", []byte("slow")))
+	require.NoError(t, open.Load(ctx, "strict", []byte(".exe")))
+	assert.NoError(t, open.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a"}))
+	assert.Error(t, open.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a.exe"}))
+}
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// DefaultMemoryLimitPages 策略模块线性内存的默认上限（64 KiB 页），即 16 MiB
+const DefaultMemoryLimitPages = 256
+
+// WazeroRuntime 基于 wazero 的 Runtime 实现，纯 Go 编写，不依赖 cgo
+//
+// 模块须导出：
+//   - memory：线性内存；
+//   - alloc(size i32) i32：返回可写入 size 字节输入的地址；
+//   - evaluate(ptr i32, len i32) i64：输入为 JSON 编码的 Request，
+//     返回值高 32 位为 JSON 编码的 Decision 的地址，低 32 位为其长度。
+//
+// 每次求值使用新的模块实例，实例之间不共享状态，求值结束即释放内存；ctx 取消或超时时中止执行。
+// 运行时提供不带文件系统和标准输出的 WASI 导入，以 reactor 方式构建的模块在实例化时调用 _initialize。
+type WazeroRuntime struct {
+	runtime wazero.Runtime
+}
+
+// NewWazeroRuntime 创建 wazero 运行时，memoryLimitPages 为 0 时使用 DefaultMemoryLimitPages
+func NewWazeroRuntime(ctx context.Context, memoryLimitPages uint32) (*WazeroRuntime, error) {
+	if memoryLimitPages == 0 {
+		memoryLimitPages = DefaultMemoryLimitPages
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(memoryLimitPages))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %v", err)
+	}
+	return &WazeroRuntime{runtime: runtime}, nil
+}
+
+// Compile 实现 Runtime，检查模块导出了约定的内存和函数
+func (r *WazeroRuntime) Compile(ctx context.Context, name string, wasm []byte) (Module, error) {
+	compiled, err := r.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, err
+	}
+	functions := compiled.ExportedFunctions()
+	for _, export := range []string{"alloc", "evaluate"} {
+		if _, ok := functions[export]; !ok {
+			compiled.Close(ctx)
+			return nil, fmt.Errorf("module does not export %s", export)
+		}
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		compiled.Close(ctx)
+		return nil, fmt.Errorf("module does not export memory")
+	}
+	return &wazeroModule{runtime: r.runtime, compiled: compiled}, nil
+}
+
+// Close 关闭运行时及其编译的全部模块
+func (r *WazeroRuntime) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+// wazeroModule wazero 编译的策略模块
+type wazeroModule struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// Evaluate 实现 Module
+func (m *wazeroModule) Evaluate(ctx context.Context, input []byte) ([]byte, error) {
+	instance, err := m.runtime.InstantiateModule(ctx, m.compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, err
+	}
+	defer instance.Close(ctx)
+
+	results, err := instance.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc: %v", err)
+	}
+	ptr := uint32(results[0])
+	memory := instance.Memory()
+	if !memory.Write(ptr, input) {
+		return nil, fmt.Errorf("alloc returned out of range address %d", ptr)
+	}
+
+	results, err = instance.ExportedFunction("evaluate").Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("evaluate: %v", err)
+	}
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	output, ok := memory.Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("evaluate returned out of range result %d+%d", outPtr, outLen)
+	}
+	// Read 返回的切片引用模块内存，实例关闭前复制
+	return append([]byte(nil), output...), nil
+}
+
+// Close 实现 Module
+func (m *wazeroModule) Close(ctx context.Context) error {
+	return m.compiled.Close(ctx)
+}
@@ -0,0 +1,149 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wasmVec 编码 WebAssembly 二进制格式中的向量：元素数量后接各元素
+func wasmVec(items ...[]byte) []byte {
+	out := wasmU32(uint32(len(items)))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// wasmU32 无符号 LEB128 编码
+func wasmU32(v uint32) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// wasmS64 有符号 LEB128 编码
+func wasmS64(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func wasmName(s string) []byte {
+	return append(wasmU32(uint32(len(s))), s...)
+}
+
+func wasmSection(id byte, content []byte) []byte {
+	return append(append([]byte{id}, wasmU32(uint32(len(content)))...), content...)
+}
+
+func wasmBody(code []byte) []byte {
+	return append(wasmU32(uint32(len(code))), code...)
+}
+
+// policyWasm 构造符合 WazeroRuntime 约定的模块，evaluate 的函数体由 evaluate 给出
+//
+//	(memory (export "memory") 1)
+//	(global $heap (mut i32) (i32.const 1024))
+//	(func (export "alloc") (param $n i32) (result i32) (local $p i32)
+//	  global.get $heap  local.set $p
+//	  global.get $heap  local.get $n  i32.add  global.set $heap
+//	  local.get $p)
+//	(data (i32.const 0) "{\"allow\":true}")
+//	(data (i32.const 16) "{\"allow\":false,\"reason\":\"deletes are not allowed\"}")
+func policyWasm(evaluate []byte) []byte {
+	const allow, deny = `{"allow":true}`, `{"allow":false,"reason":"deletes are not allowed"}`
+	i32, i64 := byte(0x7f), byte(0x7e)
+
+	out := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	out = append(out, wasmSection(1, wasmVec(
+		[]byte{0x60, 1, i32, 1, i32},
+		[]byte{0x60, 2, i32, i32, 1, i64},
+	))...)
+	out = append(out, wasmSection(3, wasmVec([]byte{0}, []byte{1}))...)
+	out = append(out, wasmSection(5, wasmVec([]byte{0x00, 1}))...)
+	out = append(out, wasmSection(6, wasmVec(append([]byte{i32, 1, 0x41}, append(wasmS64(1024), 0x0b)...)))...)
+	out = append(out, wasmSection(7, wasmVec(
+		append(wasmName("memory"), 2, 0),
+		append(wasmName("alloc"), 0, 0),
+		append(wasmName("evaluate"), 0, 1),
+	))...)
+	alloc := []byte{1, 1, i32, 0x23, 0, 0x21, 1, 0x23, 0, 0x20, 0, 0x6a, 0x24, 0, 0x20, 1, 0x0b}
+	out = append(out, wasmSection(10, wasmVec(wasmBody(alloc), wasmBody(append([]byte{0}, evaluate...))))...)
+	out = append(out, wasmSection(11, wasmVec(
+		append([]byte{0, 0x41, 0, 0x0b}, wasmName(allow)...),
+		append([]byte{0, 0x41, 16, 0x0b}, wasmName(deny)...),
+	))...)
+	return out
+}
+
+// denyDeletesWasm 输入第 8 个字节为 'd'（{"op":"delete"...）时返回拒绝结果，否则放行
+//
+//	local.get $ptr  i32.load8_u offset=7  i32.const 'd'  i32.eq
+//	if (result i64) i64.const (16<<32 | len(deny)) else i64.const len(allow) end
+func denyDeletesWasm() []byte {
+	code := []byte{0x20, 0, 0x2d, 0, 7, 0x41}
+	code = append(code, wasmS64('d')...)
+	code = append(code, 0x46, 0x04, 0x7e, 0x42)
+	code = append(code, wasmS64(16<<32|int64(len(`{"allow":false,"reason":"deletes are not allowed"}`)))...)
+	code = append(code, 0x05, 0x42)
+	code = append(code, wasmS64(int64(len(`{"allow":true}`)))...)
+	return policyWasm(append(code, 0x0b, 0x0b))
+}
+
+// spinWasm evaluate 永不返回：loop br 0 end unreachable
+func spinWasm() []byte {
+	return policyWasm([]byte{0x03, 0x40, 0x0c, 0, 0x0b, 0x00, 0x0b})
+}
+
+func TestWazeroRuntime(t *testing.T) {
+	ctx := context.Background()
+	rt, err := NewWazeroRuntime(ctx, 0)
+	require.NoError(t, err)
+	defer rt.Close(ctx)
+
+	e := NewEngine(rt, Options{Timeout: 200 * time.Millisecond}, metrics.NewRegistry())
+	require.NoError(t, e.Load(ctx, "no-delete", denyDeletesWasm()))
+
+	assert.NoError(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/a.txt"}))
+	err = e.Before(ctx, &meta.OpInfo{Op: meta.OpDelete, Path: "/a.txt"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deletes are not allowed")
+
+	// 每次求值使用新实例，alloc 的地址不会随调用累积耗尽内存
+	for i := 0; i < 100; i++ {
+		require.NoError(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpMkdir, Path: "/dir"}))
+	}
+
+	// 不返回的模块在超时后被中止，按 FailOpen 为 false 拒绝操作
+	require.NoError(t, e.Load(ctx, "spin", spinWasm()))
+	start := time.Now()
+	assert.Error(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/b.txt"}))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	require.NoError(t, e.Unload(ctx, "spin"))
+	assert.NoError(t, e.Before(ctx, &meta.OpInfo{Op: meta.OpCreate, Path: "/b.txt"}))
+
+	// 无效的模块和缺少约定导出的模块无法加载
+	assert.Error(t, e.Load(ctx, "garbage", []byte("not wasm")))
+	_, err = rt.Compile(ctx, "empty", []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+	assert.ErrorContains(t, err, "alloc")
+	require.NoError(t, e.Close(ctx))
+}
@@ -0,0 +1,243 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cpfs/internal/config"
+)
+
+const (
+	// DefaultMaxFsyncLatency fsync 耗时超过该值时给出警告
+	DefaultMaxFsyncLatency = 100 * time.Millisecond
+	// DefaultMaxClockSkew 与集群其他节点的时钟偏差上限
+	DefaultMaxClockSkew = 500 * time.Millisecond
+)
+
+// minSaneTime 早于该时间的本地时钟视为未同步，例如未接 RTC 的机器启动后时钟停在 1970 年
+var minSaneTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// probeFile 数据目录检查写入的探测文件名
+const probeFile = ".cpfs-preflight"
+
+// DataDir 检查数据目录存在（不存在时创建）且可写，探测文件写入后 fsync 文件和目录并读回校验
+//
+// fsync 超过 maxFsync 时给出警告：通常意味着磁盘或虚拟化层在同步写入上异常缓慢。
+func DataDir(dir string, maxFsync time.Duration) Check {
+	return Check{Name: "data_dir", Run: func(ctx context.Context) error {
+		if dir == "" {
+			return fmt.Errorf("data_dir is not configured")
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create data directory: %v", err)
+		}
+		p := filepath.Join(dir, probeFile)
+		defer os.Remove(p)
+		payload := []byte(fmt.Sprintf("cpfs preflight %d\n", time.Now().UnixNano()))
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("data directory %s is not writable: %v", dir, err)
+		}
+		if _, err := f.Write(payload); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write probe file: %v", err)
+		}
+		start := time.Now()
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("fsync failed on %s: %v", dir, err)
+		}
+		if err := syncDir(dir); err != nil {
+			f.Close()
+			return fmt.Errorf("fsync of directory %s failed: %v", dir, err)
+		}
+		elapsed := time.Since(start)
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close probe file: %v", err)
+		}
+		got, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read back probe file: %v", err)
+		}
+		if string(got) != string(payload) {
+			return fmt.Errorf("probe file read back %d bytes, wrote %d", len(got), len(payload))
+		}
+		if maxFsync > 0 && elapsed > maxFsync {
+			return Warnf("fsync took %s, above %s", elapsed.Round(time.Millisecond), maxFsync)
+		}
+		return nil
+	}}
+}
+
+// syncDir fsync 目录，使新建文件的目录项持久化
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Port 检查监听地址可用：地址格式正确且端口没有被其他进程占用
+func Port(name, addr string) Check {
+	return Check{Name: "port_" + name, Run: func(ctx context.Context) error {
+		if addr == "" {
+			return fmt.Errorf("%s address is not configured", name)
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid %s address %q: %v", name, addr, err)
+		}
+		var lc net.ListenConfig
+		ln, err := lc.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("cannot listen on %s: %v", addr, err)
+		}
+		return ln.Close()
+	}}
+}
+
+// PeerState 集群中另一个节点报告的时钟与需要全局一致的配置
+type PeerState struct {
+	Addr     string
+	Time     time.Time
+	Settings ClusterSettings
+}
+
+// PeerSource 查询集群中其他节点的状态，无法联系的节点不返回
+type PeerSource interface {
+	PeerStates(ctx context.Context) ([]PeerState, error)
+}
+
+// Clock 检查本地时钟：不早于合理的下限，与各节点的偏差不超过 maxSkew
+//
+// 租约、写入栅栏和快照调度都依赖墙钟，时钟明显错误的节点不应加入集群。peers 为 nil 时只检查本地时钟。
+func Clock(peers PeerSource, maxSkew time.Duration, now func() time.Time) Check {
+	if now == nil {
+		now = time.Now
+	}
+	return Check{Name: "clock", Run: func(ctx context.Context) error {
+		local := now()
+		if local.Before(minSaneTime) {
+			return fmt.Errorf("system clock %s is before %s, the clock is probably not synchronized",
+				local.UTC().Format(time.RFC3339), minSaneTime.Format(time.RFC3339))
+		}
+		if peers == nil {
+			return nil
+		}
+		states, err := peers.PeerStates(ctx)
+		if err != nil {
+			return Warnf("failed to query peer clocks: %v", err)
+		}
+		var skewed []string
+		for _, peer := range states {
+			skew := peer.Time.Sub(now())
+			if skew < 0 {
+				skew = -skew
+			}
+			if skew > maxSkew {
+				skewed = append(skewed, fmt.Sprintf("%s (%s)", peer.Addr, skew.Round(time.Millisecond)))
+			}
+		}
+		if len(skewed) > 0 {
+			return fmt.Errorf("clock skew above %s with %s", maxSkew, strings.Join(skewed, ", "))
+		}
+		return nil
+	}}
+}
+
+// ClusterSettings 必须在集群所有节点上一致的配置
+type ClusterSettings struct {
+	RaidLevel  int
+	StripeSize int64
+}
+
+// SettingsOf 返回配置中需要全局一致的部分
+func SettingsOf(cfg *config.ServerConfig) ClusterSettings {
+	return ClusterSettings{RaidLevel: cfg.RaidLevel, StripeSize: cfg.StripeSize}
+}
+
+// diff 返回与 other 不一致的字段说明
+func (s ClusterSettings) diff(other ClusterSettings) []string {
+	var out []string
+	if s.RaidLevel != other.RaidLevel {
+		out = append(out, fmt.Sprintf("raid_level %d != %d", s.RaidLevel, other.RaidLevel))
+	}
+	if s.StripeSize != other.StripeSize {
+		out = append(out, fmt.Sprintf("stripe_size %d != %d", s.StripeSize, other.StripeSize))
+	}
+	return out
+}
+
+// validateConfig 检查本地配置自洽
+func validateConfig(cfg *config.ServerConfig) []string {
+	var problems []string
+	if cfg.ServerID == "" {
+		problems = append(problems, "server_id is required")
+	}
+	switch cfg.ServerType {
+	case "meta", "data":
+	default:
+		problems = append(problems, fmt.Sprintf("server_type must be meta or data, got %q", cfg.ServerType))
+	}
+	if cfg.StripeSize < 0 || (cfg.StripeSize > 0 && cfg.StripeSize&(cfg.StripeSize-1) != 0) {
+		problems = append(problems, fmt.Sprintf("stripe_size must be a power of two, got %d", cfg.StripeSize))
+	}
+	if cfg.RaidLevel < 0 {
+		problems = append(problems, fmt.Sprintf("raid_level must not be negative, got %d", cfg.RaidLevel))
+	}
+	if cfg.HeartbeatInterval > 0 && cfg.FailureTimeout > 0 && cfg.FailureTimeout <= cfg.HeartbeatInterval {
+		problems = append(problems, fmt.Sprintf("failure_timeout %d must exceed heartbeat_interval %d",
+			cfg.FailureTimeout, cfg.HeartbeatInterval))
+	}
+	if cfg.ServerType == "data" && len(cfg.MetaServers) == 0 {
+		problems = append(problems, "data servers require meta_servers")
+	}
+	return problems
+}
+
+// Config 检查本地配置自洽，并与集群中其他节点的 RAID 级别、条带大小等全局配置一致
+//
+// 条带大小不一致的节点会按不同的布局读写同一文件，必须在启动前拒绝。peers 为 nil 时只检查本地配置。
+func Config(cfg *config.ServerConfig, peers PeerSource) Check {
+	return Check{Name: "config", Run: func(ctx context.Context) error {
+		if problems := validateConfig(cfg); len(problems) > 0 {
+			return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+		}
+		if peers == nil {
+			return nil
+		}
+		states, err := peers.PeerStates(ctx)
+		if err != nil {
+			return Warnf("failed to query peer configuration: %v", err)
+		}
+		local := SettingsOf(cfg)
+		var mismatched []string
+		for _, peer := range states {
+			if d := local.diff(peer.Settings); len(d) > 0 {
+				mismatched = append(mismatched, fmt.Sprintf("%s: %s", peer.Addr, strings.Join(d, ", ")))
+			}
+		}
+		if len(mismatched) > 0 {
+			sort.Strings(mismatched)
+			return fmt.Errorf("configuration differs from the cluster: %s", strings.Join(mismatched, "; "))
+		}
+		return nil
+	}}
+}
+
+// ChecksFromConfig 返回服务器启动前应运行的检查
+func ChecksFromConfig(cfg *config.ServerConfig, peers PeerSource) []Check {
+	return []Check{
+		Config(cfg, peers),
+		DataDir(cfg.DataDir, DefaultMaxFsyncLatency),
+		Clock(peers, DefaultMaxClockSkew, nil),
+		Port("listen", cfg.ListenAddress),
+	}
+}
@@ -0,0 +1,85 @@
+package preflight
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cpfs/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataDirCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	require.NoError(t, DataDir(dir, time.Minute).Run(context.Background()))
+	_, err := os.Stat(filepath.Join(dir, probeFile))
+	assert.True(t, os.IsNotExist(err), "probe file is removed")
+
+	assert.Error(t, DataDir("", time.Minute).Run(context.Background()))
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	assert.Error(t, DataDir(filepath.Join(file, "data"), time.Minute).Run(context.Background()))
+}
+
+func TestPortCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	assert.Error(t, Port("listen", ln.Addr().String()).Run(context.Background()))
+	assert.Error(t, Port("listen", "localhost").Run(context.Background()))
+	assert.NoError(t, Port("listen", "127.0.0.1:0").Run(context.Background()))
+}
+
+// staticPeers 返回固定的节点状态
+type staticPeers []PeerState
+
+func (p staticPeers) PeerStates(ctx context.Context) ([]PeerState, error) {
+	return p, nil
+}
+
+func TestClockCheck(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	peers := staticPeers{
+		{Addr: "meta-2", Time: now.Add(100 * time.Millisecond)},
+		{Addr: "data-1", Time: now.Add(-2 * time.Second)},
+	}
+	err := Clock(peers, DefaultMaxClockSkew, clock).Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "data-1 (2s)")
+	assert.NotContains(t, err.Error(), "meta-2")
+	assert.NoError(t, Clock(peers[:1], DefaultMaxClockSkew, clock).Run(context.Background()))
+
+	epoch := func() time.Time { return time.Unix(0, 0) }
+	assert.Error(t, Clock(nil, DefaultMaxClockSkew, epoch).Run(context.Background()))
+}
+
+func TestConfigCheck(t *testing.T) {
+	cfg := &config.ServerConfig{ServerID: "meta-1", ServerType: "meta", StripeSize: 1 << 20, RaidLevel: 5}
+	peers := staticPeers{{Addr: "data-1", Settings: ClusterSettings{RaidLevel: 5, StripeSize: 1 << 20}}}
+	assert.NoError(t, Config(cfg, peers).Run(context.Background()))
+
+	peers = append(peers, PeerState{Addr: "data-2", Settings: ClusterSettings{RaidLevel: 5, StripeSize: 64 << 10}})
+	err := Config(cfg, peers).Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "data-2: stripe_size 1048576 != 65536")
+
+	bad := &config.ServerConfig{ServerType: "data", StripeSize: 1000, HeartbeatInterval: 5, FailureTimeout: 5}
+	err = Config(bad, nil).Run(context.Background())
+	require.Error(t, err)
+	for _, want := range []string{"server_id", "power of two", "failure_timeout", "meta_servers"} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	checks := ChecksFromConfig(&config.ServerConfig{
+		ServerID: "meta-1", ServerType: "meta", DataDir: t.TempDir(), ListenAddress: "127.0.0.1:0",
+	}, nil)
+	report := Run(context.Background(), time.Second, checks...)
+	assert.True(t, report.OK(), report.String())
+	assert.Len(t, report.Results, 4)
+}
@@ -0,0 +1,149 @@
+// Package preflight 实现服务启动前的自检
+//
+// 服务器在监听端口、加载元数据之前运行一组检查：数据目录可写且 fsync 生效、时钟合理、
+// 监听端口可用、本地配置自洽并与集群中其他节点一致。任一检查失败时拒绝启动，
+// 并输出逐项的检查报告，而不是在运行中以难以定位的方式出错。
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultCheckTimeout 单项检查的默认超时
+const DefaultCheckTimeout = 10 * time.Second
+
+// Status 检查结果
+type Status string
+
+const (
+	StatusPass Status = "pass" // 检查通过
+	StatusWarn Status = "warn" // 不影响启动，但需要关注
+	StatusFail Status = "fail" // 拒绝启动
+)
+
+// Warning 检查发现的问题不足以拒绝启动，检查函数返回它时结果记为 StatusWarn
+type Warning struct {
+	Msg string
+}
+
+// Error 实现 error
+func (w *Warning) Error() string {
+	return w.Msg
+}
+
+// Warnf 返回格式化的 Warning
+func Warnf(format string, args ...interface{}) error {
+	return &Warning{Msg: fmt.Sprintf(format, args...)}
+}
+
+// Check 一项启动检查
+type Check struct {
+	Name string
+	// Run 执行检查，返回 nil 表示通过，返回 *Warning 表示警告，其他错误表示失败
+	Run func(ctx context.Context) error
+}
+
+// Result 单项检查的结果
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report 全部检查的结果，顺序与检查顺序一致
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// OK 没有失败的检查时返回 true
+func (r *Report) OK() bool {
+	for _, res := range r.Results {
+		if res.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Err 有失败的检查时返回列出全部失败项的错误
+func (r *Report) Err() error {
+	var failed []string
+	for _, res := range r.Results {
+		if res.Status == StatusFail {
+			failed = append(failed, res.Name+": "+res.Detail)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight failed: %s", strings.Join(failed, "; "))
+}
+
+// String 返回逐项的文本报告
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "[%s] %s (%s)", strings.ToUpper(string(res.Status)), res.Name, res.Duration.Round(time.Millisecond))
+		if res.Detail != "" {
+			fmt.Fprintf(&b, ": %s", res.Detail)
+		}
+		b.WriteByte('\n')
+	}
+	if r.OK() {
+		b.WriteString("preflight passed\n")
+	} else {
+		b.WriteString("preflight failed, refusing to start\n")
+	}
+	return b.String()
+}
+
+// Run 依次执行检查并汇总结果，每项检查受 timeout 限制，为 0 时使用 DefaultCheckTimeout
+//
+// 一项失败不会跳过后续检查，报告因此包含全部问题。
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) *Report {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	report := &Report{}
+	for _, check := range checks {
+		start := time.Now()
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := runCheck(checkCtx, check)
+		cancel()
+		res := Result{Name: check.Name, Status: StatusPass, Duration: time.Since(start)}
+		var warning *Warning
+		switch {
+		case err == nil:
+		case errors.As(err, &warning):
+			res.Status, res.Detail = StatusWarn, warning.Msg
+		default:
+			res.Status, res.Detail = StatusFail, err.Error()
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+// runCheck 执行检查，检查超时未返回时按失败处理
+func runCheck(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,39 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReport(t *testing.T) {
+	report := Run(context.Background(), 50*time.Millisecond,
+		Check{Name: "ok", Run: func(ctx context.Context) error { return nil }},
+		Check{Name: "slow_disk", Run: func(ctx context.Context) error { return Warnf("fsync took %s", time.Second) }},
+		Check{Name: "broken", Run: func(ctx context.Context) error { return errors.New("boom") }},
+		Check{Name: "hang", Run: func(ctx context.Context) error { select {} }},
+		Check{Name: "panic", Run: func(ctx context.Context) error { panic("bad") }},
+	)
+	var statuses []Status
+	for _, res := range report.Results {
+		statuses = append(statuses, res.Status)
+	}
+	assert.Equal(t, []Status{StatusPass, StatusWarn, StatusFail, StatusFail, StatusFail}, statuses)
+	assert.False(t, report.OK())
+	err := report.Err()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken: boom")
+	assert.Contains(t, err.Error(), "hang: check timed out")
+	text := report.String()
+	assert.Contains(t, text, "[WARN] slow_disk")
+	assert.True(t, strings.HasSuffix(text, "refusing to start\n"), text)
+
+	ok := Run(context.Background(), 0, Check{Name: "ok", Run: func(ctx context.Context) error { return nil }})
+	assert.True(t, ok.OK())
+	assert.NoError(t, ok.Err())
+}
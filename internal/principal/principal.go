@@ -0,0 +1,37 @@
+// Package principal 定义已认证主体及其在上下文中的传递方式
+//
+// 该包不依赖 gRPC 等传输层，元数据等核心包可以直接读取发起请求的主体。
+package principal
+
+import (
+	"context"
+)
+
+// Principal 已认证的 cpfs 主体
+type Principal struct {
+	User   string   // 用户名
+	Groups []string // 所属组
+}
+
+// InGroup 判断主体是否属于指定组
+func (p *Principal) InGroup(group string) bool {
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithPrincipal 将主体写入上下文
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext 从上下文获取主体
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,379 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultCheckInterval Run 检查到期策略的默认间隔
+	DefaultCheckInterval = time.Minute
+	// recordKeyPrefix 调度器快照记录在存储中的键前缀
+	recordKeyPrefix = "/snapshots/scheduled/"
+)
+
+// Snapshotter 创建和删除目录快照，由 meta.MemoryStore 实现
+//
+// DeleteSnapshot 返回 meta.ErrSnapshotNotFound 时视为快照已被删除。
+type Snapshotter interface {
+	CreateSnapshot(ctx context.Context, path string) (string, error)
+	DeleteSnapshot(ctx context.Context, snapshotID string) error
+}
+
+// namedIntervals 快照间隔的别名
+var namedIntervals = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// Policy 一个目录的定时快照策略
+type Policy struct {
+	Path     string
+	Interval time.Duration
+	Keep     int // 保留的最近快照数量，为 0 时不自动删除
+}
+
+// ParsePolicy 解析配置中的快照策略，间隔可以是 hourly、daily、weekly 或 Go 时长
+func ParsePolicy(cfg config.SnapshotPolicyConfig) (Policy, error) {
+	if cfg.Path == "" {
+		return Policy{}, fmt.Errorf("snapshot policy path is required")
+	}
+	interval, ok := namedIntervals[strings.ToLower(strings.TrimSpace(cfg.Interval))]
+	if !ok {
+		var err error
+		if interval, err = time.ParseDuration(cfg.Interval); err != nil {
+			return Policy{}, fmt.Errorf("invalid snapshot interval for %s: %v", cfg.Path, err)
+		}
+	}
+	if interval <= 0 {
+		return Policy{}, fmt.Errorf("snapshot interval for %s must be positive", cfg.Path)
+	}
+	if cfg.Keep < 0 {
+		return Policy{}, fmt.Errorf("snapshot keep for %s must not be negative", cfg.Path)
+	}
+	return Policy{Path: cfg.Path, Interval: interval, Keep: cfg.Keep}, nil
+}
+
+// Snapshot 调度器创建的一个快照
+type Snapshot struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"`
+}
+
+// Scheduler 按策略定时为目录创建快照，并删除超出保留数量的旧快照
+//
+// 距上次快照满 Interval 的策略在下一次检查时创建快照，之后按创建时间删除最旧的快照直到只剩 Keep 个。
+// 设置存储后快照记录随创建和删除持久化，重启后由 Recover 恢复，重启前创建的快照仍按保留数量删除；
+// 未设置存储时记录只保存在内存中。
+type Scheduler struct {
+	store    Snapshotter
+	storage  meta.Storage
+	policies []Policy
+	created  *metrics.Counter
+	pruned   *metrics.Counter
+	failed   *metrics.Counter
+	now      func() time.Time
+
+	mu    sync.Mutex
+	taken map[string][]Snapshot // 按目录记录的快照，按创建时间排序
+	log   logger.Logger
+}
+
+// New 创建快照调度器，同一目录只能有一个策略；registry 为 nil 时使用 metrics.Default
+func New(store Snapshotter, policies []Policy, registry *metrics.Registry) (*Scheduler, error) {
+	seen := make(map[string]bool, len(policies))
+	for _, p := range policies {
+		if p.Interval <= 0 {
+			return nil, fmt.Errorf("snapshot interval for %s must be positive", p.Path)
+		}
+		if seen[p.Path] {
+			return nil, fmt.Errorf("duplicate snapshot policy: %s", p.Path)
+		}
+		seen[p.Path] = true
+	}
+	if registry == nil {
+		registry = metrics.Default
+	}
+
+	s := &Scheduler{
+		store:    store,
+		policies: policies,
+		created:  registry.Counter("cpfs_snapshot_created_total", "Scheduled snapshots created.", nil),
+		pruned:   registry.Counter("cpfs_snapshot_pruned_total", "Expired snapshots deleted.", nil),
+		failed:   registry.Counter("cpfs_snapshot_failures_total", "Scheduled snapshot operations that failed.", nil),
+		now:      time.Now,
+		taken:    make(map[string][]Snapshot),
+		log:      logger.Default(),
+	}
+	registry.GaugeFunc("cpfs_snapshot_retained", "Scheduled snapshots currently retained.", nil, func() float64 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		n := 0
+		for _, snaps := range s.taken {
+			n += len(snaps)
+		}
+		return float64(n)
+	})
+	return s, nil
+}
+
+// NewFromConfig 按 ServerConfig.SnapshotPolicies 创建快照调度器
+func NewFromConfig(cfgs []config.SnapshotPolicyConfig, store Snapshotter, registry *metrics.Registry) (*Scheduler, error) {
+	policies := make([]Policy, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		p, err := ParsePolicy(cfg)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return New(store, policies, registry)
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (s *Scheduler) SetLogger(l logger.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = logger.OrDefault(l)
+}
+
+// SetStorage 设置持久化快照记录的存储，为 nil 时记录只保存在内存中
+func (s *Scheduler) SetStorage(storage meta.Storage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storage = storage
+}
+
+// recordKey 返回快照记录在存储中的键
+func recordKey(id string) string {
+	return recordKeyPrefix + id
+}
+
+// Recover 从存储加载重启前保存的快照记录，返回新加载的记录数量，应在 Run 之前调用
+//
+// 已删除策略的目录的记录也会加载，但不会被自动删除。
+func (s *Scheduler) Recover(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	storage := s.storage
+	s.mu.Unlock()
+	if storage == nil {
+		return 0, nil
+	}
+
+	keys, err := storage.List(ctx, recordKeyPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list snapshot records: %v", err)
+	}
+	var records []Snapshot
+	for _, key := range keys {
+		data, err := storage.Load(ctx, key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load snapshot record %s: %v", key, err)
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return 0, fmt.Errorf("failed to decode snapshot record %s: %v", key, err)
+		}
+		records = append(records, snap)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known := make(map[string]bool)
+	for _, snaps := range s.taken {
+		for _, snap := range snaps {
+			known[snap.ID] = true
+		}
+	}
+	recovered := 0
+	touched := make(map[string]bool)
+	for _, snap := range records {
+		if known[snap.ID] {
+			continue
+		}
+		s.taken[snap.Path] = append(s.taken[snap.Path], snap)
+		touched[snap.Path] = true
+		recovered++
+	}
+	for path := range touched {
+		snaps := s.taken[path]
+		sort.SliceStable(snaps, func(i, j int) bool {
+			return snaps[i].Created.Before(snaps[j].Created)
+		})
+	}
+	if recovered > 0 {
+		s.log.Info("Recovered scheduled snapshot records", zap.Int("snapshots", recovered))
+	}
+	return recovered, nil
+}
+
+// Snapshots 返回调度器为目录保留的快照，按创建时间排序
+func (s *Scheduler) Snapshots(path string) []Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Snapshot(nil), s.taken[path]...)
+}
+
+// Tick 为到期的策略创建快照并删除过期快照，返回本轮创建的快照
+func (s *Scheduler) Tick(ctx context.Context) []Snapshot {
+	var created []Snapshot
+	for _, p := range s.policies {
+		if ctx.Err() != nil {
+			break
+		}
+		if !s.due(p) {
+			continue
+		}
+		snap, err := s.take(ctx, p)
+		if err != nil {
+			continue
+		}
+		created = append(created, snap)
+		s.prune(ctx, p)
+	}
+	return created
+}
+
+// due 判断策略是否到期
+func (s *Scheduler) due(p Policy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snaps := s.taken[p.Path]
+	if len(snaps) == 0 {
+		return true
+	}
+	return !s.now().Before(snaps[len(snaps)-1].Created.Add(p.Interval))
+}
+
+// Oldest 返回保留的最早快照的创建时间，没有快照时 ok 为 false
+//
+// 可作为 meta.TimeHold 注册到变更日志压缩器，使最早的快照之后的事件不被压缩。
+func (s *Scheduler) Oldest() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var oldest time.Time
+	found := false
+	for _, snaps := range s.taken {
+		if len(snaps) > 0 && (!found || snaps[0].Created.Before(oldest)) {
+			oldest, found = snaps[0].Created, true
+		}
+	}
+	return oldest, found
+}
+
+// take 为策略的目录创建快照并记录
+func (s *Scheduler) take(ctx context.Context, p Policy) (Snapshot, error) {
+	id, err := s.store.CreateSnapshot(ctx, p.Path)
+	s.mu.Lock()
+	log := s.log
+	if err != nil {
+		s.mu.Unlock()
+		s.failed.Inc()
+		log.Warn("Failed to create scheduled snapshot", zap.String("path", p.Path), zap.Error(err))
+		return Snapshot{}, err
+	}
+	snap := Snapshot{ID: id, Path: p.Path, Created: s.now()}
+	s.taken[p.Path] = append(s.taken[p.Path], snap)
+	storage := s.storage
+	s.mu.Unlock()
+
+	s.created.Inc()
+	log.Info("Created scheduled snapshot", zap.String("path", p.Path), zap.String("id", id))
+
+	// 记录保存失败时快照仍在内存中保留，本次运行内照常删除，但重启后不再受调度器管理
+	if storage != nil {
+		if err := saveRecord(ctx, storage, snap); err != nil {
+			s.failed.Inc()
+			log.Warn("Failed to save scheduled snapshot record", zap.String("path", p.Path), zap.String("id", id), zap.Error(err))
+		}
+	}
+	return snap, nil
+}
+
+// saveRecord 持久化快照记录
+func saveRecord(ctx context.Context, storage meta.Storage, snap Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return storage.Save(ctx, recordKey(snap.ID), data)
+}
+
+// prune 删除超出保留数量的最旧快照，删除失败的快照保留到下一轮重试
+func (s *Scheduler) prune(ctx context.Context, p Policy) {
+	if p.Keep <= 0 {
+		return
+	}
+	s.mu.Lock()
+	snaps := s.taken[p.Path]
+	var expired []Snapshot
+	if len(snaps) > p.Keep {
+		expired = append(expired, snaps[:len(snaps)-p.Keep]...)
+	}
+	log := s.log
+	storage := s.storage
+	s.mu.Unlock()
+
+	deleted := make(map[string]bool, len(expired))
+	for _, snap := range expired {
+		if err := s.store.DeleteSnapshot(ctx, snap.ID); err != nil && !errors.Is(err, meta.ErrSnapshotNotFound) {
+			s.failed.Inc()
+			log.Warn("Failed to delete expired snapshot", zap.String("path", p.Path), zap.String("id", snap.ID), zap.Error(err))
+			continue
+		}
+		deleted[snap.ID] = true
+		s.pruned.Inc()
+		log.Info("Deleted expired snapshot", zap.String("path", p.Path), zap.String("id", snap.ID))
+		if storage != nil {
+			if err := storage.Delete(ctx, recordKey(snap.ID)); err != nil {
+				log.Warn("Failed to delete scheduled snapshot record", zap.String("id", snap.ID), zap.Error(err))
+			}
+		}
+	}
+	if len(deleted) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.taken[p.Path][:0]
+	for _, snap := range s.taken[p.Path] {
+		if !deleted[snap.ID] {
+			kept = append(kept, snap)
+		}
+	}
+	s.taken[p.Path] = kept
+}
+
+// Run 每隔 interval 检查一次到期策略，为 0 时使用 DefaultCheckInterval；启动时立即检查一次，阻塞到 ctx 被取消
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	s.Tick(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Tick(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
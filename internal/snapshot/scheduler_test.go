@@ -0,0 +1,211 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cpfs/internal/config"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSnapshotter 记录创建和删除的快照
+type fakeSnapshotter struct {
+	next      int
+	live      map[string]string // 快照 ID 到目录
+	failPath  string
+	failID    string
+	deleteErr error
+}
+
+func newFakeSnapshotter() *fakeSnapshotter {
+	return &fakeSnapshotter{live: make(map[string]string)}
+}
+
+func (f *fakeSnapshotter) CreateSnapshot(ctx context.Context, path string) (string, error) {
+	if path == f.failPath {
+		return "", errors.New("create failed")
+	}
+	f.next++
+	id := fmt.Sprintf("snap-%d", f.next)
+	f.live[id] = path
+	return id, nil
+}
+
+func (f *fakeSnapshotter) DeleteSnapshot(ctx context.Context, id string) error {
+	if id == f.failID {
+		return errors.New("delete failed")
+	}
+	delete(f.live, id)
+	return nil
+}
+
+func ids(snaps []Snapshot) []string {
+	out := make([]string, len(snaps))
+	for i, s := range snaps {
+		out[i] = s.ID
+	}
+	return out
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy(config.SnapshotPolicyConfig{Path: "/home", Interval: "hourly", Keep: 24})
+	require.NoError(t, err)
+	assert.Equal(t, Policy{Path: "/home", Interval: time.Hour, Keep: 24}, p)
+
+	p, err = ParsePolicy(config.SnapshotPolicyConfig{Path: "/db", Interval: "15m"})
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, p.Interval)
+
+	for _, cfg := range []config.SnapshotPolicyConfig{
+		{Interval: "hourly"},
+		{Path: "/a", Interval: "often"},
+		{Path: "/a", Interval: "-1h"},
+		{Path: "/a", Interval: "daily", Keep: -1},
+	} {
+		_, err := ParsePolicy(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+
+	_, err = NewFromConfig([]config.SnapshotPolicyConfig{
+		{Path: "/a", Interval: "daily"},
+		{Path: "/a", Interval: "hourly"},
+	}, newFakeSnapshotter(), metrics.NewRegistry())
+	assert.Error(t, err)
+}
+
+func TestSchedulerRetention(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeSnapshotter()
+	s, err := New(store, []Policy{
+		{Path: "/home", Interval: time.Hour, Keep: 2},
+		{Path: "/logs", Interval: 24 * time.Hour},
+	}, metrics.NewRegistry())
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	_, ok := s.Oldest()
+	assert.False(t, ok)
+
+	// 首次检查为全部策略创建快照
+	start := now
+	assert.Len(t, s.Tick(ctx), 2)
+	assert.Empty(t, s.Tick(ctx))
+
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Hour)
+		created := s.Tick(ctx)
+		require.Len(t, created, 1)
+		assert.Equal(t, "/home", created[0].Path)
+	}
+	assert.Equal(t, []string{"snap-4", "snap-5"}, ids(s.Snapshots("/home")))
+	assert.Len(t, s.Snapshots("/logs"), 1)
+	assert.Len(t, store.live, 3)
+	oldest, ok := s.Oldest()
+	require.True(t, ok)
+	assert.Equal(t, start, oldest)
+
+	// Keep 为 0 时不删除
+	now = now.Add(24 * time.Hour)
+	s.Tick(ctx)
+	assert.Len(t, s.Snapshots("/logs"), 2)
+}
+
+func TestSchedulerFailures(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeSnapshotter()
+	registry := metrics.NewRegistry()
+	s, err := New(store, []Policy{
+		{Path: "/a", Interval: time.Hour, Keep: 1},
+		{Path: "/b", Interval: time.Hour, Keep: 1},
+	}, registry)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	// 创建失败的策略在下一轮重试
+	store.failPath = "/b"
+	assert.Len(t, s.Tick(ctx), 1)
+	store.failPath = ""
+	assert.Len(t, s.Tick(ctx), 1)
+	assert.Equal(t, []string{"snap-2"}, ids(s.Snapshots("/b")))
+
+	// 删除失败的快照保留到下一轮
+	store.failID = "snap-1"
+	now = now.Add(time.Hour)
+	s.Tick(ctx)
+	assert.Equal(t, []string{"snap-1", "snap-3"}, ids(s.Snapshots("/a")))
+	store.failID = ""
+	now = now.Add(time.Hour)
+	s.Tick(ctx)
+	assert.Equal(t, []string{"snap-5"}, ids(s.Snapshots("/a")))
+	assert.Equal(t, uint64(2), s.failed.Value())
+}
+
+func TestSchedulerRecoverAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	storage, err := meta.NewFileStorage(&meta.StorageConfig{
+		RootDir:      t.TempDir(),
+		SyncInterval: time.Second,
+		FileMode:     0600,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	store := meta.NewMemoryStore()
+	require.NoError(t, store.Mkdir(ctx, "/home", 0755))
+	var _ Snapshotter = store
+
+	policies := []Policy{{Path: "/home", Interval: time.Hour, Keep: 2}}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newScheduler := func() *Scheduler {
+		s, err := New(store, policies, metrics.NewRegistry())
+		require.NoError(t, err)
+		s.now = func() time.Time { return now }
+		s.SetStorage(storage)
+		return s
+	}
+
+	s := newScheduler()
+	for i := 0; i < 2; i++ {
+		require.Len(t, s.Tick(ctx), 1)
+		now = now.Add(time.Hour)
+	}
+	before := ids(s.Snapshots("/home"))
+	require.Len(t, before, 2)
+
+	// 重启后恢复记录，到期判断和保留数量都计入重启前的快照
+	s = newScheduler()
+	recovered, err := s.Recover(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, recovered)
+	assert.Equal(t, before, ids(s.Snapshots("/home")))
+	recovered, err = s.Recover(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, recovered)
+
+	require.Len(t, s.Tick(ctx), 1)
+	kept := ids(s.Snapshots("/home"))
+	assert.Equal(t, before[1], kept[0])
+	assert.Len(t, store.ListSnapshots(ctx, "/home"), 2)
+	keys, err := storage.List(ctx, recordKeyPrefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	// 已在带外删除的快照视为删除成功，记录随之清除
+	require.NoError(t, store.DeleteSnapshot(ctx, kept[0]))
+	now = now.Add(time.Hour)
+	require.Len(t, s.Tick(ctx), 1)
+	assert.NotContains(t, ids(s.Snapshots("/home")), kept[0])
+	assert.Len(t, store.ListSnapshots(ctx, "/home"), 2)
+	keys, err = storage.List(ctx, recordKeyPrefix)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Zero(t, s.failed.Value())
+}
@@ -0,0 +1,308 @@
+package tiering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cpfs/internal/logger"
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultHotClass 热文件提升到的默认存储类别
+	DefaultHotClass = "ssd"
+	// DefaultMinResidency 提升后至少保留在热存储上的默认时长
+	DefaultMinResidency = time.Hour
+	// DefaultInterval 默认的评估间隔
+	DefaultInterval = time.Minute
+)
+
+// Store 分层迁移使用的元数据操作
+type Store interface {
+	Get(ctx context.Context, path string) (*meta.Metadata, error)
+	UpdateIf(ctx context.Context, path string, meta *meta.Metadata, expectedVersion uint64) error
+}
+
+// Mover 把文件的数据块迁移到指定存储类别的节点上，并更新块位置
+type Mover interface {
+	Migrate(ctx context.Context, path string, class string) error
+}
+
+// Options 自动分层策略
+type Options struct {
+	// HotClass 提升到的存储类别，为空时使用 DefaultHotClass
+	HotClass string
+	// PromoteReads 访问统计窗口内读取次数达到该值的文件被提升
+	PromoteReads uint64
+	// DemoteReads 已提升的文件读取次数降到该值及以下时降级，必须小于 PromoteReads，两者之差即滞回区间
+	DemoteReads uint64
+	// MinResidency 提升后至少保留的时长，期间不降级，为 0 时使用 DefaultMinResidency
+	MinResidency time.Duration
+	// Interval Run 的评估间隔，为 0 时使用 DefaultInterval
+	Interval time.Duration
+}
+
+// Direction 迁移方向
+type Direction string
+
+const (
+	Promote Direction = "promote"
+	Demote  Direction = "demote"
+)
+
+// MoveEvent 一次迁移的结果，每次迁移都会产生，失败时 Err 非空
+type MoveEvent struct {
+	Path      string
+	Direction Direction
+	From      string // 原存储类别
+	To        string // 目标存储类别
+	Reads     uint64 // 触发迁移时窗口内的读取次数
+	Time      time.Time
+	Err       error
+}
+
+// promotion 一个已提升文件的原存储类别和提升时间
+type promotion struct {
+	from  string
+	since time.Time
+}
+
+// Tierer 按访问热度在存储类别之间自动迁移文件
+//
+// 每轮评估读取访问统计，读取次数达到 PromoteReads 的文件迁移到 HotClass，
+// 已提升的文件在保留 MinResidency 之后且读取次数不超过 DemoteReads 时迁回原存储类别。
+// 阈值之间的滞回区间和最短保留时间避免文件在两个类别之间反复迁移。
+// 提升记录保存在内存中，重启后已提升的文件不会被自动降级。
+type Tierer struct {
+	store   Store
+	tracker *metrics.AccessTracker
+	mover   Mover
+	opts    Options
+	moves   map[Direction]*metrics.Counter
+	failed  *metrics.Counter
+	now     func() time.Time
+
+	mu       sync.Mutex
+	promoted map[string]promotion
+	onEvent  func(MoveEvent)
+	log      logger.Logger
+}
+
+// New 创建自动分层器，registry 为 nil 时使用 metrics.Default
+func New(store Store, tracker *metrics.AccessTracker, mover Mover, opts Options, registry *metrics.Registry) (*Tierer, error) {
+	if opts.PromoteReads == 0 {
+		return nil, fmt.Errorf("promote threshold is required")
+	}
+	if opts.DemoteReads >= opts.PromoteReads {
+		return nil, fmt.Errorf("demote threshold %d must be below promote threshold %d", opts.DemoteReads, opts.PromoteReads)
+	}
+	if opts.HotClass == "" {
+		opts.HotClass = DefaultHotClass
+	}
+	if opts.MinResidency <= 0 {
+		opts.MinResidency = DefaultMinResidency
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if registry == nil {
+		registry = metrics.Default
+	}
+
+	t := &Tierer{
+		store:    store,
+		tracker:  tracker,
+		mover:    mover,
+		opts:     opts,
+		moves:    make(map[Direction]*metrics.Counter),
+		failed:   registry.Counter("cpfs_tiering_failures_total", "Tiering migrations that failed.", nil),
+		now:      time.Now,
+		promoted: make(map[string]promotion),
+		log:      logger.Default(),
+	}
+	for _, d := range []Direction{Promote, Demote} {
+		t.moves[d] = registry.Counter("cpfs_tiering_moves_total", "Files migrated between storage classes.", metrics.Labels{"direction": string(d)})
+	}
+	registry.GaugeFunc("cpfs_tiering_promoted_files", "Files currently promoted to the hot storage class.", nil, func() float64 {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return float64(len(t.promoted))
+	})
+	return t, nil
+}
+
+// SetLogger 设置日志，为 nil 时使用全局日志
+func (t *Tierer) SetLogger(l logger.Logger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.log = logger.OrDefault(l)
+}
+
+// OnEvent 设置每次迁移后的回调
+func (t *Tierer) OnEvent(fn func(MoveEvent)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEvent = fn
+}
+
+// Promoted 返回当前已提升的文件，按路径排序
+func (t *Tierer) Promoted() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	paths := make([]string, 0, len(t.promoted))
+	for p := range t.promoted {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Evaluate 执行一轮评估并完成需要的迁移，返回本轮的迁移事件
+func (t *Tierer) Evaluate(ctx context.Context) []MoveEvent {
+	reads := make(map[string]uint64)
+	for _, s := range t.tracker.TopFiles(0, metrics.AccessByReads) {
+		reads[s.Path] = s.Reads
+	}
+
+	now := t.now()
+	t.mu.Lock()
+	var demote []string
+	for p, promo := range t.promoted {
+		if reads[p] <= t.opts.DemoteReads && now.Sub(promo.since) >= t.opts.MinResidency {
+			demote = append(demote, p)
+		}
+	}
+	var promote []string
+	for p, n := range reads {
+		if _, ok := t.promoted[p]; !ok && n >= t.opts.PromoteReads {
+			promote = append(promote, p)
+		}
+	}
+	t.mu.Unlock()
+	sort.Strings(demote)
+	sort.Strings(promote)
+
+	var events []MoveEvent
+	for _, p := range demote {
+		if ctx.Err() != nil {
+			return events
+		}
+		if e, ok := t.demote(ctx, p, reads[p]); ok {
+			events = append(events, e)
+		}
+	}
+	for _, p := range promote {
+		if ctx.Err() != nil {
+			return events
+		}
+		if e, ok := t.promote(ctx, p, reads[p]); ok {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// promote 将文件迁移到热存储类别，已经位于该类别的文件不迁移也不记录
+func (t *Tierer) promote(ctx context.Context, p string, reads uint64) (MoveEvent, bool) {
+	md, err := t.store.Get(ctx, p)
+	if err != nil || md.Type != meta.TypeRegular || md.StorageClass == t.opts.HotClass {
+		return MoveEvent{}, false
+	}
+	e := t.move(ctx, p, Promote, md.StorageClass, t.opts.HotClass, reads)
+	if e.Err == nil {
+		t.mu.Lock()
+		t.promoted[p] = promotion{from: md.StorageClass, since: e.Time}
+		t.mu.Unlock()
+	}
+	return e, true
+}
+
+// demote 将已提升的文件迁回原存储类别，文件已被删除时只清除记录
+func (t *Tierer) demote(ctx context.Context, p string, reads uint64) (MoveEvent, bool) {
+	t.mu.Lock()
+	promo := t.promoted[p]
+	t.mu.Unlock()
+
+	if _, err := t.store.Get(ctx, p); err != nil {
+		if ctx.Err() == nil {
+			t.mu.Lock()
+			delete(t.promoted, p)
+			t.mu.Unlock()
+		}
+		return MoveEvent{}, false
+	}
+	e := t.move(ctx, p, Demote, t.opts.HotClass, promo.from, reads)
+	if e.Err == nil {
+		t.mu.Lock()
+		delete(t.promoted, p)
+		t.mu.Unlock()
+	}
+	return e, true
+}
+
+// move 迁移数据并更新文件的存储类别，然后发出事件
+func (t *Tierer) move(ctx context.Context, p string, dir Direction, from, to string, reads uint64) MoveEvent {
+	e := MoveEvent{Path: p, Direction: dir, From: from, To: to, Reads: reads}
+	e.Err = t.mover.Migrate(ctx, p, to)
+	if e.Err == nil {
+		e.Err = t.setClass(ctx, p, to)
+	}
+	e.Time = t.now()
+
+	if e.Err != nil {
+		t.failed.Inc()
+	} else {
+		t.moves[dir].Inc()
+	}
+	t.mu.Lock()
+	log, onEvent := t.log, t.onEvent
+	t.mu.Unlock()
+	if e.Err != nil {
+		log.Warn("Failed to migrate file", zap.String("path", p), zap.String("direction", string(dir)),
+			zap.String("to", to), zap.Error(e.Err))
+	} else {
+		log.Info("Migrated file", zap.String("path", p), zap.String("direction", string(dir)),
+			zap.String("from", from), zap.String("to", to), zap.Uint64("reads", reads))
+	}
+	if onEvent != nil {
+		onEvent(e)
+	}
+	return e
+}
+
+// setClass 更新文件的存储类别，与并发更新冲突时重新读取后重试
+func (t *Tierer) setClass(ctx context.Context, p, class string) error {
+	for {
+		md, err := t.store.Get(ctx, p)
+		if err != nil {
+			return err
+		}
+		version := md.Version
+		md.StorageClass = class
+		err = t.store.UpdateIf(ctx, p, md, version)
+		if !errors.Is(err, meta.ErrVersionConflict) {
+			return err
+		}
+	}
+}
+
+// Run 按 Interval 周期评估，阻塞到 ctx 被取消
+func (t *Tierer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.Evaluate(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
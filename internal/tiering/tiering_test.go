@@ -0,0 +1,129 @@
+package tiering
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cpfs/internal/meta"
+	"cpfs/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMover 记录迁移请求的测试迁移器
+type recordingMover struct {
+	mu    sync.Mutex
+	moves []string
+	fail  error
+}
+
+func (m *recordingMover) Migrate(ctx context.Context, path, class string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail != nil {
+		return m.fail
+	}
+	m.moves = append(m.moves, path+"->"+class)
+	return nil
+}
+
+func TestTiererPromoteAndDemote(t *testing.T) {
+	ctx := context.Background()
+	store := meta.NewMemoryStore()
+	_, err := store.Create(ctx, "/hot.bin", 0644)
+	require.NoError(t, err)
+	_, err = store.Create(ctx, "/cold.bin", 0644)
+	require.NoError(t, err)
+	md, err := store.Get(ctx, "/hot.bin")
+	require.NoError(t, err)
+	md.StorageClass = "hdd"
+	require.NoError(t, store.Update(ctx, "/hot.bin", md))
+
+	tracker := metrics.NewAccessTracker(metrics.AccessOptions{Window: time.Hour})
+	mover := &recordingMover{}
+	tierer, err := New(store, tracker, mover, Options{PromoteReads: 3, DemoteReads: 1, MinResidency: time.Hour}, metrics.NewRegistry())
+	require.NoError(t, err)
+	now := time.Now()
+	tierer.now = func() time.Time { return now }
+	var events []MoveEvent
+	tierer.OnEvent(func(e MoveEvent) { events = append(events, e) })
+
+	for i := 0; i < 3; i++ {
+		tracker.Record("/hot.bin", "alice", 4096)
+	}
+	tracker.Record("/cold.bin", "alice", 4096)
+
+	moved := tierer.Evaluate(ctx)
+	require.Len(t, moved, 1)
+	assert.Equal(t, MoveEvent{Path: "/hot.bin", Direction: Promote, From: "hdd", To: "ssd", Reads: 3, Time: now}, moved[0])
+	assert.Equal(t, moved, events)
+	assert.Equal(t, []string{"/hot.bin"}, tierer.Promoted())
+	md, err = store.Get(ctx, "/hot.bin")
+	require.NoError(t, err)
+	assert.Equal(t, "ssd", md.StorageClass)
+
+	// 已提升的文件不重复迁移；保留时间未到时即使变冷也不降级
+	assert.Empty(t, tierer.Evaluate(ctx))
+	now = now.Add(30 * time.Minute)
+	tracker = metrics.NewAccessTracker(metrics.AccessOptions{})
+	tierer.tracker = tracker
+	assert.Empty(t, tierer.Evaluate(ctx))
+
+	// 读取次数处于滞回区间内时保持提升
+	now = now.Add(time.Hour)
+	tracker.Record("/hot.bin", "alice", 4096)
+	tracker.Record("/hot.bin", "alice", 4096)
+	assert.Empty(t, tierer.Evaluate(ctx))
+
+	tierer.tracker = metrics.NewAccessTracker(metrics.AccessOptions{})
+	moved = tierer.Evaluate(ctx)
+	require.Len(t, moved, 1)
+	assert.Equal(t, Demote, moved[0].Direction)
+	assert.Equal(t, "hdd", moved[0].To)
+	assert.Empty(t, tierer.Promoted())
+	md, err = store.Get(ctx, "/hot.bin")
+	require.NoError(t, err)
+	assert.Equal(t, "hdd", md.StorageClass)
+	assert.Equal(t, []string{"/hot.bin->ssd", "/hot.bin->hdd"}, mover.moves)
+}
+
+func TestTiererFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	store := meta.NewMemoryStore()
+	_, err := store.Create(ctx, "/hot.bin", 0644)
+	require.NoError(t, err)
+
+	tracker := metrics.NewAccessTracker(metrics.AccessOptions{})
+	tracker.Record("/hot.bin", "alice", 1)
+	tracker.Record("/missing.bin", "alice", 1)
+	mover := &recordingMover{fail: errors.New("no ssd capacity")}
+	tierer, err := New(store, tracker, mover, Options{PromoteReads: 1}, metrics.NewRegistry())
+	require.NoError(t, err)
+
+	moved := tierer.Evaluate(ctx)
+	require.Len(t, moved, 1)
+	assert.EqualError(t, moved[0].Err, "no ssd capacity")
+	assert.Empty(t, tierer.Promoted())
+	md, err := store.Get(ctx, "/hot.bin")
+	require.NoError(t, err)
+	assert.Empty(t, md.StorageClass)
+
+	// 失败的文件在下一轮重试
+	mover.fail = nil
+	moved = tierer.Evaluate(ctx)
+	require.Len(t, moved, 1)
+	assert.NoError(t, moved[0].Err)
+	assert.Equal(t, []string{"/hot.bin"}, tierer.Promoted())
+}
+
+func TestNewTiererValidation(t *testing.T) {
+	tracker := metrics.NewAccessTracker(metrics.AccessOptions{})
+	_, err := New(meta.NewMemoryStore(), tracker, &recordingMover{}, Options{}, metrics.NewRegistry())
+	assert.Error(t, err)
+	_, err = New(meta.NewMemoryStore(), tracker, &recordingMover{}, Options{PromoteReads: 2, DemoteReads: 2}, metrics.NewRegistry())
+	assert.Error(t, err)
+}